@@ -17,6 +17,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"strings"
 
@@ -98,7 +99,7 @@ var help []string = []string{
 	"     -ha ant      rinex header: antenna number and type separated by /",
 	"     -hp pos      rinex header: approx position x/y/z separated by /",
 	"     -hd delta    rinex header: antenna delta h/e/n separated by /",
-	"     -v ver       rinex version [3.04]",
+	"     -v ver       rinex version (2.10-3.05) [3.04]",
 	"     -od          include doppler frequency in rinex obs [on]",
 	"     -os          include snr in rinex obs [on]",
 	"     -oi          include iono correction in rinex nav header [off]",
@@ -200,8 +201,8 @@ func cmdopts(opt *gnssgo.RnxOpt, ifile *string, ofile []string, dir *string, tra
 	opt.ObsType = gnssgo.OBSTYPE_PR | gnssgo.OBSTYPE_CP
 	opt.NavSys = gnssgo.SYS_GPS | gnssgo.SYS_GLO | gnssgo.SYS_GAL | gnssgo.SYS_QZS | gnssgo.SYS_SBS | gnssgo.SYS_CMP | gnssgo.SYS_IRN
 
-	for i = 0; i < 6; i++ {
-		for j = 0; j < 64; j++ {
+	for i = 0; i < len(opt.Mask); i++ {
+		for j = 0; j < len(opt.Mask[i]); j++ {
 			opt.Mask[i][j] = '1'
 		}
 	}
@@ -303,7 +304,7 @@ func cmdopts(opt *gnssgo.RnxOpt, ifile *string, ofile []string, dir *string, tra
 	}
 
 	if ver > 0 {
-		opt.RnxVer = int(ver * 100.0)
+		opt.RnxVer = int(math.Round(ver * 100.0))
 	}
 
 	if bod {
@@ -334,8 +335,8 @@ func cmdopts(opt *gnssgo.RnxOpt, ifile *string, ofile []string, dir *string, tra
 	}
 
 	if len(mask) > 0 {
-		for j = 0; j < 6; j++ {
-			for k = 0; k < 64; k++ {
+		for j = 0; j < len(opt.Mask); j++ {
+			for k = 0; k < len(opt.Mask[j]); k++ {
 				opt.Mask[j][k] = '0'
 			}
 		}
@@ -24,8 +24,8 @@ func main() {
 	}
 
 	// Initialize mask
-	for i := 0; i < 6; i++ {
-		for j := 0; j < 64; j++ {
+	for i := 0; i < len(opt.Mask); i++ {
+		for j := 0; j < len(opt.Mask[i]); j++ {
 			opt.Mask[i][j] = '1'
 		}
 	}
@@ -21,6 +21,7 @@ require (
 
 replace (
 	github.com/bramburn/gnssgo/hardware/topgnss/top708 => ../../hardware/topgnss/top708
+	github.com/bramburn/gnssgo/pkg/caster => ../../pkg/caster
 	github.com/bramburn/gnssgo/pkg/gnssgo => ../../pkg/gnssgo
 	github.com/bramburn/gnssgo/pkg/ntrip => ../../pkg/ntrip
 )
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -39,6 +40,7 @@ const (
 	ModeRTCM = "rtcm"
 	ModeUBX  = "ubx"
 	ModeRTK  = "rtk"
+	ModeAuto = "auto"
 )
 
 // RTK fix quality indicators
@@ -76,7 +78,7 @@ func init() {
 	flag.StringVar(&portName, "port", "", "Serial port name (e.g., COM1, /dev/ttyUSB0)")
 	flag.IntVar(&baudRate, "baud", 38400, "Baud rate (default: 38400)")
 	flag.DurationVar(&timeout, "timeout", 5*time.Second, "Connection verification timeout")
-	flag.StringVar(&mode, "mode", ModeRaw, "Data mode: raw, nmea, rtcm, ubx, rtk")
+	flag.StringVar(&mode, "mode", ModeRaw, "Data mode: raw, nmea, rtcm, ubx, rtk, auto")
 	flag.BoolVar(&showPorts, "list", false, "List available ports and exit")
 
 	// RTK-related flags
@@ -127,9 +129,24 @@ func main() {
 	fmt.Println("Port opened successfully. Waiting for device to initialize...")
 	time.Sleep(2 * time.Second) // Give the device time to initialize
 
+	// Detect the protocol sent by the device in auto mode
+	autoDetected := false
+	if strings.ToLower(mode) == ModeAuto {
+		fmt.Println("Detecting protocol...")
+		protocols, err := device.DetectProtocol(timeout)
+		if err != nil {
+			log.Fatalf("Failed to detect protocol: %v", err)
+		}
+		mode = protocolMode(protocols[0])
+		autoDetected = true
+		fmt.Printf("Detected %s, using %s mode.\n", strings.Join(protocols, ", "), mode)
+	}
+
 	// Verify connection
 	fmt.Println("Verifying connection...")
-	if !device.VerifyConnection(timeout) {
+	if autoDetected {
+		fmt.Println("Connection verified by protocol detection.")
+	} else if !device.VerifyConnection(timeout) {
 		fmt.Println("Unable to verify GNSS data. The device may not be sending data.")
 		fmt.Println("Do you want to continue anyway? (y/n)")
 		reader := bufio.NewReader(os.Stdin)
@@ -184,6 +201,20 @@ func main() {
 	}
 }
 
+// protocolMode returns the monitoring mode of a detected protocol
+func protocolMode(protocol string) string {
+	switch protocol {
+	case top708.ProtocolNMEA:
+		return ModeNMEA
+	case top708.ProtocolRTCM:
+		return ModeRTCM
+	case top708.ProtocolUBX:
+		return ModeUBX
+	default:
+		return ModeRaw
+	}
+}
+
 // getFixQualityDescription returns a human-readable description of the fix quality
 func getFixQualityDescription(quality string) string {
 	switch quality {
@@ -230,7 +261,6 @@ func updateRTKStatus(quality, satellites, hdop string) {
 // RTKHandler implements the DataHandler interface for RTK data
 type RTKHandler struct {
 	ntripClient *ntrip.Client
-	processor   *ntrip.RTKProcessor
 }
 
 // HandleNMEA handles NMEA sentences in RTK mode
@@ -294,42 +324,16 @@ func monitorRTK(device *top708.TOP708Device, sigChan chan os.Signal) {
 	}()
 	fmt.Println("Connected to NTRIP server successfully.")
 
-	// Create GNSS receiver
-	gnssReceiver, err := ntrip.NewGNSSReceiver(portName)
-	if err != nil {
-		log.Fatalf("Failed to create GNSS receiver: %v", err)
-	}
-	// Use defer with a function to handle potential nil pointer
-	defer func() {
-		if gnssReceiver != nil {
-			gnssReceiver.Close()
-		}
-	}()
-
-	// Create RTK processor
-	fmt.Println("Starting RTK processor...")
-	processor, err := ntrip.NewRTKProcessor(gnssReceiver, ntripClient)
-	if err != nil {
-		log.Fatalf("Failed to create RTK processor: %v", err)
-	}
-
-	// Start RTK processing
-	err = processor.Start()
-	if err != nil {
-		log.Fatalf("Failed to start RTK processing: %v", err)
-	}
-	// Use defer with a function to handle potential nil pointer
-	defer func() {
-		if processor != nil {
-			processor.Stop()
-		}
-	}()
-	fmt.Println("RTK processor started successfully.")
+	// Forward the corrections to the receiver, its internal RTK engine
+	// computes the solutions reported in the GGA sentences
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go device.ForwardCorrections(ctx, ntripClient)
+	fmt.Println("Forwarding RTCM corrections to the receiver.")
 
 	// Create RTK handler
 	handler := &RTKHandler{
 		ntripClient: ntripClient,
-		processor:   processor,
 	}
 
 	// Start NMEA monitoring to get position updates
@@ -519,57 +523,19 @@ func (h *RTCMHandler) HandleRTCM(message top708.RTCMMessage) {
 // HandleUBX handles UBX messages (not used in RTCM mode)
 func (h *RTCMHandler) HandleUBX(message top708.UBXMessage) {}
 
-// monitorRTCM monitors RTCM messages
+// monitorRTCM monitors and parses RTCM messages
 func monitorRTCM(device *top708.TOP708Device, sigChan chan os.Signal) {
-	// Since MonitorRTCM is not implemented yet, we'll use a manual approach
-	buffer := make([]byte, 2048) // RTCM messages can be larger
-	done := make(chan bool)
+	handler := &RTCMHandler{}
+	config := top708.DefaultMonitorConfig(top708.ProtocolRTCM, handler)
 
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				n, err := device.ReadRaw(buffer)
-				if err != nil {
-					fmt.Printf("Error reading data: %v\n", err)
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-
-				if n > 0 {
-					// Look for RTCM message preamble (0xD3)
-					data := buffer[:n]
-					for i := 0; i < len(data); i++ {
-						if data[i] == 0xD3 && i+2 < len(data) {
-							// Potential RTCM message
-							// In a real implementation, we would parse the RTCM message here
-							fmt.Printf("Potential RTCM data detected at offset %d\n", i)
-							// Skip ahead to avoid duplicate detections
-							i += 2
-						}
-					}
-
-					// Also print raw data in hex format for debugging
-					fmt.Printf("Raw data (%d bytes): ", n)
-					for i := 0; i < n && i < 20; i++ { // Show first 20 bytes max
-						fmt.Printf("%02X ", data[i])
-					}
-					if n > 20 {
-						fmt.Print("...")
-					}
-					fmt.Println()
-				}
-
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-	}()
+	err := device.MonitorRTCM(config)
+	if err != nil {
+		log.Fatalf("Failed to start RTCM monitoring: %v", err)
+	}
 
 	// Wait for signal
 	<-sigChan
-	done <- true
+	device.StopMonitoring()
 	fmt.Println("\nStopped monitoring.")
 }
 
@@ -586,102 +552,30 @@ func (h *UBXHandler) HandleRTCM(message top708.RTCMMessage) {}
 func (h *UBXHandler) HandleUBX(message top708.UBXMessage) {
 	fmt.Printf("UBX Message - Class: 0x%02X, ID: 0x%02X, Length: %d bytes\n",
 		message.Class, message.ID, len(message.Payload))
+
+	// Print payload in hex format (first 20 bytes max)
+	fmt.Print("  Payload: ")
+	for i := 0; i < len(message.Payload) && i < 20; i++ {
+		fmt.Printf("%02X ", message.Payload[i])
+	}
+	if len(message.Payload) > 20 {
+		fmt.Print("...")
+	}
+	fmt.Println()
 }
 
 // monitorUBX monitors UBX protocol messages
 func monitorUBX(device *top708.TOP708Device, sigChan chan os.Signal) {
-	// Since MonitorUBX is not implemented yet, we'll use a manual approach
-	buffer := make([]byte, 1024)
-	done := make(chan bool)
-
-	go func() {
-		// UBX message starts with 0xB5 0x62
-		ubxHeader := []byte{0xB5, 0x62}
-		ubxBuffer := make([]byte, 0)
-
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				n, err := device.ReadRaw(buffer)
-				if err != nil {
-					fmt.Printf("Error reading data: %v\n", err)
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
+	handler := &UBXHandler{}
+	config := top708.DefaultMonitorConfig(top708.ProtocolUBX, handler)
 
-				if n > 0 {
-					// Add new data to buffer
-					ubxBuffer = append(ubxBuffer, buffer[:n]...)
-
-					// Look for UBX message header
-					for len(ubxBuffer) >= 2 {
-						// Find UBX header
-						headerIndex := -1
-						for i := 0; i < len(ubxBuffer)-1; i++ {
-							if ubxBuffer[i] == ubxHeader[0] && ubxBuffer[i+1] == ubxHeader[1] {
-								headerIndex = i
-								break
-							}
-						}
-
-						if headerIndex == -1 {
-							// No header found, keep the last byte in case it's the first byte of a header
-							if len(ubxBuffer) > 1 {
-								ubxBuffer = ubxBuffer[len(ubxBuffer)-1:]
-							} else {
-								ubxBuffer = make([]byte, 0)
-							}
-							break
-						}
-
-						// Remove data before header
-						ubxBuffer = ubxBuffer[headerIndex:]
-
-						// Check if we have enough data for a complete message
-						if len(ubxBuffer) < 8 {
-							// Not enough data for header + class + id + length, wait for more
-							break
-						}
-
-						// Extract class, id, and length
-						class := ubxBuffer[2]
-						id := ubxBuffer[3]
-						length := int(ubxBuffer[4]) | (int(ubxBuffer[5]) << 8)
-
-						// Check if we have the complete message
-						if len(ubxBuffer) < 8+length {
-							// Not enough data for complete message, wait for more
-							break
-						}
-
-						// We have a complete message
-						fmt.Printf("UBX Message - Class: 0x%02X, ID: 0x%02X, Length: %d bytes\n",
-							class, id, length)
-
-						// Print payload in hex format (first 20 bytes max)
-						fmt.Print("  Payload: ")
-						for i := 0; i < length && i < 20; i++ {
-							fmt.Printf("%02X ", ubxBuffer[6+i])
-						}
-						if length > 20 {
-							fmt.Print("...")
-						}
-						fmt.Println()
-
-						// Remove processed message from buffer
-						ubxBuffer = ubxBuffer[8+length:]
-					}
-				}
-
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-	}()
+	err := device.MonitorUBX(config)
+	if err != nil {
+		log.Fatalf("Failed to start UBX monitoring: %v", err)
+	}
 
 	// Wait for signal
 	<-sigChan
-	done <- true
+	device.StopMonitoring()
 	fmt.Println("\nStopped monitoring.")
 }
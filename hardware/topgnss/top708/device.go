@@ -2,6 +2,8 @@ package top708
 
 import (
 	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
 )
 
 // Protocol constants
@@ -25,12 +27,25 @@ type GNSSDevice interface {
 	// VerifyConnection checks if the device is sending valid GNSS data
 	VerifyConnection(timeout time.Duration) bool
 
+	// DetectBaudRate finds the baud rate at which the device sends NMEA data
+	DetectBaudRate(portName string, candidates []int, timeout time.Duration) (int, error)
+
+	// DetectProtocol reports the protocols sent by the device
+	DetectProtocol(timeout time.Duration) ([]string, error)
+
 	// ReadRaw reads raw data from the device
 	ReadRaw(buffer []byte) (int, error)
 
+	// ReadObservations reads an epoch of raw observations with the
+	// navigation data decoded so far
+	ReadObservations() ([]gnssgo.ObsD, gnssgo.Nav, error)
+
 	// WriteRaw writes raw data to the device
 	WriteRaw(data []byte) (int, error)
 
+	// FeedCorrections writes RTCM corrections to the device
+	FeedCorrections(data []byte) error
+
 	// WriteCommand sends a command to the device
 	WriteCommand(command string) error
 
@@ -90,11 +105,44 @@ func DefaultMonitorConfig(protocol string, handler DataHandler) MonitorConfig {
 
 // NMEASentence represents a parsed NMEA sentence
 type NMEASentence struct {
-	Raw      string
-	Type     string
-	Fields   []string
-	Valid    bool
-	Checksum string
+	Raw           string
+	Type          string
+	Fields        []string
+	Valid         bool
+	Checksum      string
+	ChecksumError bool  // Checksum present but not matching the sentence
+	NoChecksum    bool  // Sentence has no checksum
+	Err           error // Reason the sentence is invalid or suspect
+}
+
+// GGAData contains the fields of a GGA (fix data) sentence.
+// Coordinates are in decimal degrees, negative for the southern and western
+// hemispheres. Empty fields are returned as zero values.
+type GGAData struct {
+	Time       string  // UTC time (hhmmss.ss)
+	Latitude   float64 // Latitude (deg)
+	Longitude  float64 // Longitude (deg)
+	FixQuality int     // Fix quality (0: invalid, 1: GPS, 2: DGPS, 4: RTK fixed, 5: RTK float)
+	NumSats    int     // Number of satellites in use
+	HDOP       float64 // Horizontal dilution of precision
+	Altitude   float64 // Altitude above mean sea level (m)
+	GeoidSep   float64 // Geoid separation (m)
+	AgeOfDiff  float64 // Age of differential corrections (s)
+	StationID  string  // Differential reference station ID
+}
+
+// RMCData contains the fields of an RMC (recommended minimum) sentence.
+// Coordinates are in decimal degrees, negative for the southern and western
+// hemispheres. Empty fields are returned as zero values.
+type RMCData struct {
+	Time      string  // UTC time (hhmmss.ss)
+	Status    string  // Status (A: valid, V: warning)
+	Latitude  float64 // Latitude (deg)
+	Longitude float64 // Longitude (deg)
+	Speed     float64 // Speed over ground (knots)
+	Course    float64 // Course over ground (deg true)
+	Date      string  // UTC date (ddmmyy)
+	MagVar    float64 // Magnetic variation (deg, negative west)
 }
 
 // RTCMMessage represents a parsed RTCM message
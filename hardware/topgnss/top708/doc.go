@@ -42,6 +42,46 @@ Example usage:
     // Process the data
     fmt.Printf("Read %d bytes: %s\n", n, string(buffer[:n]))
 
+If the baud rate of the device is unknown, DetectBaudRate tries the
+DefaultBaudRates (4800 to 921600) before connecting:
+
+    baudRate, err := device.DetectBaudRate("COM1", nil, 2*time.Second)
+    if err == nil {
+        err = device.Connect("COM1", baudRate)
+    }
+
+GetFirmwareVersion queries the firmware release. Settings made by the
+Configure methods are lost on a power cycle unless saved to flash by
+SaveConfiguration:
+
+    err := device.ConfigureUpdateRate(200)
+    if err == nil {
+        err = device.SaveConfiguration()
+    }
+
+HotStart, WarmStart, ColdStart and FactoryReset restart the receiver and wait
+for its startup message. Running monitors are paused meanwhile:
+
+    if err := device.ColdStart(); err != nil {
+        log.Printf("Cold start failed: %v", err)
+    }
+
+For a position from the raw measurements instead of the receiver solution,
+ReadObservations decodes an epoch of UBX RXM-RAWX observations with the
+ephemerides of the UBX RXM-SFRBX subframes received so far, as input to
+gnssgo.PntPos:
+
+    obs, nav, err := device.ReadObservations()
+    if err == nil {
+        var sol gnssgo.Sol
+        var msg string
+        opt := gnssgo.DefaultProcOpt()
+        ssat := make([]gnssgo.SSat, gnssgo.MAXSAT)
+        if gnssgo.PntPos(obs, len(obs), &nav, &opt, &sol, nil, ssat, &msg) == 0 {
+            log.Printf("No solution: %s", msg)
+        }
+    }
+
 ## SerialPort
 
 The SerialPort interface provides a generic interface for serial port operations.
@@ -88,6 +128,9 @@ Example usage:
         fmt.Printf("Fields: %v\n", sentence.Fields)
     }
 
+Sentences whose checksum does not match are rejected with ChecksumError set.
+Set StrictChecksum to false on the parser to keep them, flagged, instead.
+
 # Device Monitoring
 
 The package provides functionality for monitoring the device and processing data in real-time.
@@ -109,6 +152,12 @@ Example usage:
     // Stop monitoring when done
     device.StopMonitoring()
 
+MonitorNMEAContext stops the NMEA monitoring when the context is canceled:
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+    defer cancel()
+    err := device.MonitorNMEAContext(ctx, config)
+
 Where MyNMEAHandler implements the DataHandler interface:
 
     type MyNMEAHandler struct{}
@@ -124,5 +173,29 @@ Where MyNMEAHandler implements the DataHandler interface:
     func (h *MyNMEAHandler) HandleUBX(message top708.UBXMessage) {
         // Not used for NMEA monitoring
     }
+
+# RTK Corrections
+
+For RTK by the internal engine of the receiver, the RTCM corrections of an
+NTRIP caster must be written to the receiver. ForwardCorrections feeds them
+from a source such as an ntrip.Client until the context is canceled,
+throttled to the baud rate. A failed write drops the corrections read but does
+not stop the forwarding:
+
+    client, err := ntrip.NewClient("caster.example.com", "2101", "user", "password", "MOUNT")
+    if err == nil {
+        err = client.Connect()
+    }
+    if err != nil {
+        log.Fatalf("Failed to connect to NTRIP caster: %v", err)
+    }
+    defer client.Disconnect()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go device.ForwardCorrections(ctx, client)
+
+The fix quality of the GGA sentences then reports the RTK solution (4: fixed,
+5: float). FeedCorrections writes corrections received otherwise.
 */
 package top708
@@ -3,6 +3,7 @@ module github.com/bramburn/gnssgo/hardware/topgnss/top708
 go 1.21
 
 require (
+	github.com/bramburn/gnssgo/pkg/gnssgo v0.0.0
 	github.com/stretchr/testify v1.10.0
 	go.bug.st/serial v1.6.1
 )
@@ -14,3 +15,5 @@ require (
 	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/bramburn/gnssgo/pkg/gnssgo => ../../../pkg/gnssgo
@@ -1,19 +1,37 @@
 package top708
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo/rtcm"
+)
+
+// Errors reported in NMEASentence.Err
+var (
+	ErrNMEAFormat   = errors.New("malformed NMEA sentence")
+	ErrNMEAChecksum = errors.New("NMEA checksum mismatch")
 )
 
 // NMEAParser parses NMEA sentences
-type NMEAParser struct{}
+type NMEAParser struct {
+	// StrictChecksum rejects sentences whose checksum does not match.
+	// When false, such sentences are still returned as valid with
+	// ChecksumError set, which is useful when processing clean files.
+	StrictChecksum bool
+}
 
-// NewNMEAParser creates a new NMEA parser
+// NewNMEAParser creates a new NMEA parser with strict checksum validation
 func NewNMEAParser() *NMEAParser {
-	return &NMEAParser{}
+	return &NMEAParser{StrictChecksum: true}
 }
 
-// Parse parses an NMEA sentence
+// Parse parses an NMEA sentence.
+// The checksum is the XOR of all bytes between '$' and '*'. Sentences without
+// a checksum (e.g. some proprietary ones) are accepted with NoChecksum set.
 func (p *NMEAParser) Parse(sentence string) NMEASentence {
 	result := NMEASentence{
 		Raw:   sentence,
@@ -22,28 +40,33 @@ func (p *NMEAParser) Parse(sentence string) NMEASentence {
 
 	// Check if the sentence starts with $
 	if !strings.HasPrefix(sentence, "$") {
+		result.Err = fmt.Errorf("%w: missing '$' prefix", ErrNMEAFormat)
 		return result
 	}
-
-	// Check if the sentence has a checksum
-	parts := strings.Split(sentence, "*")
-	if len(parts) != 2 {
-		return result
-	}
-
-	// Extract the checksum
-	result.Checksum = parts[1]
-
-	// Calculate the checksum
-	data := parts[0][1:] // Remove the $ prefix
-	calculatedChecksum := p.calculateChecksum(data)
-	if calculatedChecksum != result.Checksum {
-		return result
+	body := strings.TrimRight(sentence, "\r\n")
+
+	// Split off and verify the checksum
+	star := strings.LastIndexByte(body, '*')
+	if star < 0 {
+		result.NoChecksum = true
+	} else {
+		result.Checksum = body[star+1:]
+		body = body[:star]
+		calculatedChecksum := p.calculateChecksum(body[1:]) // Remove the $ prefix
+		if !strings.EqualFold(calculatedChecksum, result.Checksum) {
+			result.ChecksumError = true
+			result.Err = fmt.Errorf("%w: got %q, expected %s", ErrNMEAChecksum,
+				result.Checksum, calculatedChecksum)
+			if p.StrictChecksum {
+				return result
+			}
+		}
 	}
 
 	// Split the sentence into fields
-	fields := strings.Split(parts[0], ",")
-	if len(fields) < 1 {
+	fields := strings.Split(body, ",")
+	if len(fields[0]) < 2 {
+		result.Err = fmt.Errorf("%w: missing sentence type", ErrNMEAFormat)
 		return result
 	}
 
@@ -64,6 +87,162 @@ func (p *NMEAParser) calculateChecksum(data string) string {
 	return fmt.Sprintf("%02X", checksum)
 }
 
+// ParseGGA extracts the fix data from a GGA sentence
+func (p *NMEAParser) ParseGGA(s NMEASentence) (GGAData, error) {
+	var data GGAData
+
+	if err := checkSentence(s, "GGA", 14); err != nil {
+		return data, err
+	}
+	f := s.Fields
+
+	var err error
+	data.Time = f[0]
+	if data.Latitude, err = parseCoordinate(f[1], f[2], 2, "N", "S"); err != nil {
+		return data, fmt.Errorf("GGA latitude: %w", err)
+	}
+	if data.Longitude, err = parseCoordinate(f[3], f[4], 3, "E", "W"); err != nil {
+		return data, fmt.Errorf("GGA longitude: %w", err)
+	}
+	if data.FixQuality, err = parseInt(f[5]); err != nil {
+		return data, fmt.Errorf("GGA fix quality: %w", err)
+	}
+	if data.NumSats, err = parseInt(f[6]); err != nil {
+		return data, fmt.Errorf("GGA satellites: %w", err)
+	}
+	if data.HDOP, err = parseFloat(f[7]); err != nil {
+		return data, fmt.Errorf("GGA HDOP: %w", err)
+	}
+	if data.Altitude, err = parseFloat(f[8]); err != nil {
+		return data, fmt.Errorf("GGA altitude: %w", err)
+	}
+	if data.GeoidSep, err = parseFloat(f[10]); err != nil {
+		return data, fmt.Errorf("GGA geoid separation: %w", err)
+	}
+	if data.AgeOfDiff, err = parseFloat(f[12]); err != nil {
+		return data, fmt.Errorf("GGA age of differential: %w", err)
+	}
+	data.StationID = f[13]
+
+	return data, nil
+}
+
+// ParseRMC extracts the navigation data from an RMC sentence
+func (p *NMEAParser) ParseRMC(s NMEASentence) (RMCData, error) {
+	var data RMCData
+
+	if err := checkSentence(s, "RMC", 11); err != nil {
+		return data, err
+	}
+	f := s.Fields
+
+	var err error
+	data.Time = f[0]
+	data.Status = f[1]
+	if data.Latitude, err = parseCoordinate(f[2], f[3], 2, "N", "S"); err != nil {
+		return data, fmt.Errorf("RMC latitude: %w", err)
+	}
+	if data.Longitude, err = parseCoordinate(f[4], f[5], 3, "E", "W"); err != nil {
+		return data, fmt.Errorf("RMC longitude: %w", err)
+	}
+	if data.Speed, err = parseFloat(f[6]); err != nil {
+		return data, fmt.Errorf("RMC speed: %w", err)
+	}
+	if data.Course, err = parseFloat(f[7]); err != nil {
+		return data, fmt.Errorf("RMC course: %w", err)
+	}
+	if f[8] != "" && !isDigits(f[8], 6) {
+		return data, fmt.Errorf("RMC date: invalid value %q", f[8])
+	}
+	data.Date = f[8]
+	if data.MagVar, err = parseFloat(f[9]); err != nil {
+		return data, fmt.Errorf("RMC magnetic variation: %w", err)
+	}
+	if f[10] == "W" {
+		data.MagVar = -data.MagVar
+	}
+
+	return data, nil
+}
+
+// checkSentence checks that s is a valid sentence of the given type (any
+// talker) with at least n fields
+func checkSentence(s NMEASentence, sentenceType string, n int) error {
+	if !s.Valid {
+		return fmt.Errorf("invalid NMEA sentence: %q", s.Raw)
+	}
+	if len(s.Type) != 5 || s.Type[2:] != sentenceType {
+		return fmt.Errorf("not a %s sentence: %s", sentenceType, s.Type)
+	}
+	if len(s.Fields) < n {
+		return fmt.Errorf("%s sentence has %d fields, expected %d", sentenceType, len(s.Fields), n)
+	}
+	return nil
+}
+
+// parseCoordinate converts an NMEA (d)ddmm.mmmm coordinate with its hemisphere
+// indicator to decimal degrees. degDigits is the number of degree digits (2 for
+// latitude, 3 for longitude); neg is the hemisphere that gives a negative value.
+// An empty value returns 0.
+func parseCoordinate(value, hemi string, degDigits int, pos, neg string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	dot := strings.IndexByte(value, '.')
+	if dot < 0 {
+		dot = len(value)
+	}
+	if dot < 3 || dot > degDigits+2 || !isDigits(value[:dot], dot) {
+		return 0, fmt.Errorf("malformed coordinate %q", value)
+	}
+	deg, _ := strconv.Atoi(value[:dot-2])
+	minutes, err := strconv.ParseFloat(value[dot-2:], 64)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("malformed coordinate %q", value)
+	}
+	result := float64(deg) + minutes/60.0
+	if (degDigits == 2 && result > 90) || result > 180 {
+		return 0, fmt.Errorf("coordinate %q out of range", value)
+	}
+	switch hemi {
+	case pos:
+	case neg:
+		result = -result
+	default:
+		return 0, fmt.Errorf("invalid hemisphere %q for coordinate %q", hemi, value)
+	}
+	return result, nil
+}
+
+// parseInt parses an integer field, returning 0 for an empty field
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseFloat parses a decimal field, returning 0 for an empty field
+func parseFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// isDigits reports whether value consists of exactly n decimal digits
+func isDigits(value string, n int) bool {
+	if len(value) != n {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // RTCMParser parses RTCM messages
 type RTCMParser struct{}
 
@@ -170,3 +349,87 @@ func (p *UBXParser) calculateChecksum(data []byte) uint16 {
 	}
 	return uint16(ck_a) | (uint16(ck_b) << 8)
 }
+
+// maxNMEALength is the longest NMEA sentence accepted by DetectProtocols and
+// MonitorNMEA, NMEA 0183 allows 82 characters but proprietary sentences are
+// often longer
+const maxNMEALength = 256
+
+// DetectProtocols reports the protocols found in a sample of a raw receiver
+// stream, most frequent first. Only complete frames count: NMEA sentences
+// with a matching checksum, UBX frames with a valid checksum and RTCM 3
+// frames with a valid CRC-24Q. The bytes of a frame are skipped once it is
+// recognized, so a '$' inside a binary payload is not taken as NMEA.
+// An empty result means no complete frame was found in the sample.
+func DetectProtocols(data []byte) []string {
+	counts := map[string]int{}
+	nmeaParser := NewNMEAParser()
+	ubxParser := NewUBXParser()
+
+	for i := 0; i < len(data); {
+		n := 0
+		switch data[i] {
+		case '$':
+			if n = nmeaFrameLength(data[i:]); n > 0 {
+				s := nmeaParser.Parse(string(data[i : i+n]))
+				if s.Valid && !s.NoChecksum {
+					counts[ProtocolNMEA]++
+				} else {
+					n = 0
+				}
+			}
+		case 0xB5:
+			if len(data)-i >= 8 && data[i+1] == 0x62 {
+				n = int(data[i+4]) | int(data[i+5])<<8 + 8
+				if n <= len(data)-i && ubxParser.Parse(data[i:i+n]).Valid {
+					counts[ProtocolUBX]++
+				} else {
+					n = 0
+				}
+			}
+		case 0xD3:
+			if len(data)-i >= 6 && data[i+1]&0xFC == 0 {
+				n = (int(data[i+1])<<8 | int(data[i+2])) + 6
+				if n <= len(data)-i && rtcm.ValidateCRC(&rtcm.RTCMMessage{Data: data[i : i+n]}) {
+					counts[ProtocolRTCM]++
+				} else {
+					n = 0
+				}
+			}
+		}
+		if n == 0 {
+			n = 1
+		}
+		i += n
+	}
+
+	var protocols []string
+	for _, p := range []string{ProtocolNMEA, ProtocolUBX, ProtocolRTCM} {
+		if counts[p] > 0 {
+			protocols = append(protocols, p)
+		}
+	}
+	sort.SliceStable(protocols, func(i, j int) bool {
+		return counts[protocols[i]] > counts[protocols[j]]
+	})
+	return protocols
+}
+
+// nmeaFrameLength returns the length of the printable line starting at data[0]
+// including its line end, or 0 if there is no complete line
+func nmeaFrameLength(data []byte) int {
+	for i := 1; i < len(data) && i < maxNMEALength; i++ {
+		switch {
+		case data[i] == '\n':
+			return i + 1
+		case data[i] == '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2
+			}
+			return i + 1
+		case data[i] < 0x20 || data[i] > 0x7E:
+			return 0
+		}
+	}
+	return 0
+}
@@ -28,23 +28,64 @@ func TestNMEAParserParse(t *testing.T) {
 
 	// Verify the result
 	assert.False(t, result.Valid)
+	assert.ErrorIs(t, result.Err, ErrNMEAFormat)
 	assert.Equal(t, sentence, result.Raw)
 
-	// Test with an invalid NMEA sentence (no checksum)
-	sentence = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"
+	// Test with a sentence without checksum (kept, but flagged)
+	sentence = "$PUBX,00,123519.00,4807.038,N,01131.000,E"
 	result = parser.Parse(sentence)
 
 	// Verify the result
-	assert.False(t, result.Valid)
-	assert.Equal(t, sentence, result.Raw)
+	assert.True(t, result.Valid)
+	assert.True(t, result.NoChecksum)
+	assert.False(t, result.ChecksumError)
+	assert.Equal(t, "PUBX", result.Type)
 
-	// Test with an invalid NMEA sentence (invalid checksum)
+	// Test with an invalid NMEA sentence (corrupted checksum)
 	sentence = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48"
 	result = parser.Parse(sentence)
 
 	// Verify the result
 	assert.False(t, result.Valid)
+	assert.True(t, result.ChecksumError)
+	assert.ErrorIs(t, result.Err, ErrNMEAChecksum)
 	assert.Equal(t, sentence, result.Raw)
+
+	// Test with a corrupted payload
+	sentence = "$GPGGA,123519,4807.038,N,01131.000,E,1,09,0.9,545.4,M,46.9,M,,*47"
+	result = parser.Parse(sentence)
+
+	// Verify the result
+	assert.False(t, result.Valid)
+	assert.True(t, result.ChecksumError)
+
+	// Test with a lower case checksum and line terminator
+	sentence = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6a\r\n"
+	result = parser.Parse(sentence)
+
+	// Verify the result
+	assert.True(t, result.Valid)
+	assert.NoError(t, result.Err)
+	assert.Equal(t, "GPRMC", result.Type)
+	assert.Equal(t, "W", result.Fields[len(result.Fields)-1])
+}
+
+// TestNMEAParserLenientChecksum tests Parse with StrictChecksum disabled
+func TestNMEAParserLenientChecksum(t *testing.T) {
+	parser := &NMEAParser{StrictChecksum: false}
+
+	// A checksum mismatch is reported but the sentence is kept
+	result := parser.Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48")
+	assert.True(t, result.Valid)
+	assert.True(t, result.ChecksumError)
+	assert.ErrorIs(t, result.Err, ErrNMEAChecksum)
+	assert.Equal(t, "GPGGA", result.Type)
+
+	// A correct checksum is not flagged
+	result = parser.Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47")
+	assert.True(t, result.Valid)
+	assert.False(t, result.ChecksumError)
+	assert.NoError(t, result.Err)
 }
 
 // TestNMEAParserCalculateChecksum tests the calculateChecksum method of NMEAParser
@@ -60,6 +101,149 @@ func TestNMEAParserCalculateChecksum(t *testing.T) {
 	assert.Equal(t, "47", checksum)
 }
 
+// nmeaSentence appends the checksum to body and parses it
+func nmeaSentence(p *NMEAParser, body string) NMEASentence {
+	return p.Parse("$" + body + "*" + p.calculateChecksum(body))
+}
+
+// TestNMEAParserParseGGA tests the ParseGGA method of NMEAParser
+func TestNMEAParserParseGGA(t *testing.T) {
+	parser := NewNMEAParser()
+
+	tests := []struct {
+		name     string
+		sentence string
+		want     GGAData
+		wantErr  string
+	}{
+		{
+			name:     "fix north east",
+			sentence: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+			want: GGAData{Time: "123519", Latitude: 48.1173, Longitude: 11.516666666666667,
+				FixQuality: 1, NumSats: 8, HDOP: 0.9, Altitude: 545.4, GeoidSep: 46.9},
+		},
+		{
+			name:     "rtk fixed south west",
+			sentence: "GNGGA,001043.00,3352.12800,S,15112.62600,W,4,12,0.66,55.2,M,22.1,M,1.0,0031",
+			want: GGAData{Time: "001043.00", Latitude: -33.868800, Longitude: -151.2104333333333,
+				FixQuality: 4, NumSats: 12, HDOP: 0.66, Altitude: 55.2, GeoidSep: 22.1,
+				AgeOfDiff: 1.0, StationID: "0031"},
+		},
+		{
+			name:     "no fix",
+			sentence: "GPGGA,235947.00,,,,,0,00,99.99,,,,,,",
+			want:     GGAData{Time: "235947.00", HDOP: 99.99},
+		},
+		{
+			name:     "malformed latitude",
+			sentence: "GPGGA,123519,48A7.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+			wantErr:  "malformed coordinate",
+		},
+		{
+			name:     "minutes out of range",
+			sentence: "GPGGA,123519,4867.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+			wantErr:  "malformed coordinate",
+		},
+		{
+			name:     "missing hemisphere",
+			sentence: "GPGGA,123519,4807.038,N,01131.000,,1,08,0.9,545.4,M,46.9,M,,",
+			wantErr:  "invalid hemisphere",
+		},
+		{
+			name:     "wrong sentence type",
+			sentence: "GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W",
+			wantErr:  "not a GGA sentence",
+		},
+		{
+			name:     "too few fields",
+			sentence: "GPGGA,123519,4807.038,N",
+			wantErr:  "fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parser.Parse(tt.sentence)
+			if !s.Valid {
+				s = nmeaSentence(parser, tt.sentence)
+			}
+			got, err := parser.ParseGGA(s)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.Time, got.Time)
+			assert.InDelta(t, tt.want.Latitude, got.Latitude, 1e-9)
+			assert.InDelta(t, tt.want.Longitude, got.Longitude, 1e-9)
+			got.Latitude, got.Longitude = tt.want.Latitude, tt.want.Longitude
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// An invalid sentence is rejected
+	_, err := parser.ParseGGA(parser.Parse("$GPGGA,123519*00"))
+	assert.ErrorContains(t, err, "invalid NMEA sentence")
+}
+
+// TestNMEAParserParseRMC tests the ParseRMC method of NMEAParser
+func TestNMEAParserParseRMC(t *testing.T) {
+	parser := NewNMEAParser()
+
+	tests := []struct {
+		name     string
+		sentence string
+		want     RMCData
+		wantErr  string
+	}{
+		{
+			name:     "valid fix",
+			sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+			want: RMCData{Time: "123519", Status: "A", Latitude: 48.1173, Longitude: 11.516666666666667,
+				Speed: 22.4, Course: 84.4, Date: "230394", MagVar: -3.1},
+		},
+		{
+			name:     "no fix",
+			sentence: "GNRMC,235947.00,V,,,,,,,170126,,,N",
+			want:     RMCData{Time: "235947.00", Status: "V", Date: "170126"},
+		},
+		{
+			name:     "malformed longitude",
+			sentence: "GPRMC,123519,A,4807.038,N,0113-.000,E,022.4,084.4,230394,003.1,W",
+			wantErr:  "RMC longitude: malformed coordinate",
+		},
+		{
+			name:     "latitude out of range",
+			sentence: "GPRMC,123519,A,9107.038,N,01131.000,E,022.4,084.4,230394,003.1,W",
+			wantErr:  "out of range",
+		},
+		{
+			name:     "invalid date",
+			sentence: "GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,2303,003.1,W",
+			wantErr:  "RMC date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parser.Parse(tt.sentence)
+			if !s.Valid {
+				s = nmeaSentence(parser, tt.sentence)
+			}
+			got, err := parser.ParseRMC(s)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want.Latitude, got.Latitude, 1e-9)
+			assert.InDelta(t, tt.want.Longitude, got.Longitude, 1e-9)
+			got.Latitude, got.Longitude = tt.want.Latitude, tt.want.Longitude
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 // TestRTCMParserParse tests the Parse method of RTCMParser
 func TestRTCMParserParse(t *testing.T) {
 	// Create a new RTCM parser
@@ -154,3 +338,50 @@ func TestUBXParserCalculateChecksum(t *testing.T) {
 	// Verify the result
 	assert.Equal(t, expected, checksum)
 }
+
+// ubxFrame returns a UBX frame of the given class, id and payload
+func ubxFrame(class, id byte, payload []byte) []byte {
+	frame := []byte{0xB5, 0x62, class, id, byte(len(payload)), byte(len(payload) >> 8)}
+	frame = append(frame, payload...)
+	ck := NewUBXParser().calculateChecksum(frame[2:])
+	return append(frame, byte(ck), byte(ck>>8))
+}
+
+// TestDetectProtocols tests the protocol detection of raw stream samples
+func TestDetectProtocols(t *testing.T) {
+	gga := []byte("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	badGGA := []byte("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48\r\n")
+	// UBX-INF-NOTICE carrying a complete NMEA sentence as text
+	inf := ubxFrame(0x04, 0x02, gga)
+	pvt := ubxFrame(0x01, 0x07, make([]byte, 92))
+	// RTCM 1005 frame from the RTCM 3 standard, with a valid CRC
+	rtcm1005 := []byte{
+		0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF,
+		0x34, 0xB4, 0xBD, 0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33, 0x36, 0x0B, 0x98,
+	}
+	concat := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, p := range parts {
+			data = append(data, p...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"nmea", concat(gga, gga), []string{ProtocolNMEA}},
+		{"ubx with nmea text", concat(pvt[50:], inf, pvt, inf, pvt[:30]), []string{ProtocolUBX}},
+		{"rtcm", concat([]byte{0x00, 0xD3}, rtcm1005, rtcm1005), []string{ProtocolRTCM}},
+		{"mixed", concat(gga, pvt, rtcm1005, pvt, gga, pvt), []string{ProtocolUBX, ProtocolNMEA, ProtocolRTCM}},
+		{"bad checksums", concat(badGGA, pvt[:len(pvt)-1], []byte{0x00}), nil},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectProtocols(tt.data))
+		})
+	}
+}
@@ -1,13 +1,18 @@
 package top708
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/bramburn/gnssgo/pkg/gnssgo/rtcm"
 )
 
 // Logger defines a simple logging interface
@@ -52,7 +57,12 @@ type TOP708Device struct {
 	serialPort SerialPort
 	connected  bool
 	mutex      sync.Mutex
-	stopChan   chan bool
+	monitors   *monitorGroup // Running monitors, nil if none
+	readLock   sync.RWMutex  // Read lock of monitors, write locked to pause them
+	raw        *gnssgo.Raw   // Raw observation decoder, nil until first used
+	rawPending []byte        // Data read after the last decoded observations
+	feedMutex  sync.Mutex    // Serializes the corrections fed
+	feedReady  time.Time     // Time the corrections fed are transmitted
 	logger     Logger
 	portName   string
 	baudRate   int
@@ -65,7 +75,6 @@ func NewTOP708Device(serialPort SerialPort) *TOP708Device {
 	return &TOP708Device{
 		serialPort: serialPort,
 		connected:  false,
-		stopChan:   make(chan bool),
 		logger:     &DefaultLogger{},
 		retryCount: 3,
 		retryDelay: 1 * time.Second,
@@ -154,31 +163,36 @@ func (d *TOP708Device) ConnectWithContext(ctx context.Context, portName string,
 
 // Disconnect closes the connection to the device
 func (d *TOP708Device) Disconnect() error {
+	// No monitor can start once the device is marked disconnected
+	d.mutex.Lock()
+	connected := d.connected
+	d.connected = false
+	group := d.monitors
+	d.monitors = nil
+	d.mutex.Unlock()
+
+	// Stop any ongoing monitoring before the port is closed
+	if group.stop() {
+		d.logger.Debugf("Stopped monitoring\n")
+	}
+
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	if !d.connected {
+	if !connected {
 		d.logger.Debugf("Device already disconnected\n")
 		return nil
 	}
 
 	d.logger.Infof("Disconnecting from device...\n")
 
-	// Stop any ongoing monitoring
-	select {
-	case d.stopChan <- true:
-		d.logger.Debugf("Stopped monitoring\n")
-	default:
-		// No monitoring is active
-	}
-
 	err := d.serialPort.Close()
 	if err != nil {
+		d.connected = true
 		d.logger.Errorf("Error disconnecting device: %v\n", err)
 		return fmt.Errorf("error disconnecting device: %w", err)
 	}
 
-	d.connected = false
 	d.logger.Infof("Successfully disconnected from device\n")
 	return nil
 }
@@ -217,7 +231,7 @@ func (d *TOP708Device) VerifyConnection(timeout time.Duration) bool {
 			d.logger.Debugf("Read %d bytes\n", n)
 
 			// Check for NMEA sentences
-			if strings.Contains(data, "$GN") || strings.Contains(data, "$GP") {
+			if containsNMEA(data) {
 				d.logger.Infof("Connection verified: valid NMEA data received\n")
 				return true
 			}
@@ -232,6 +246,73 @@ func (d *TOP708Device) VerifyConnection(timeout time.Duration) bool {
 	return false
 }
 
+// containsNMEA reports whether data contain the start of a GPS or
+// multi-GNSS NMEA sentence
+func containsNMEA(data string) bool {
+	return strings.Contains(data, "$GN") || strings.Contains(data, "$GP")
+}
+
+// DefaultBaudRates are the baud rates tried by DetectBaudRate, the default
+// rate of the TOPGNSS TOP708 first
+var DefaultBaudRates = []int{38400, 9600, 115200, 4800, 19200, 57600, 230400, 460800, 921600}
+
+// DetectBaudRate opens the port at each candidate baud rate in turn (default:
+// DefaultBaudRates) and returns the first rate at which NMEA data are received
+// within the timeout. The port is closed afterwards, connect with the rate
+// returned. The device must not be connected.
+func (d *TOP708Device) DetectBaudRate(portName string, candidates []int, timeout time.Duration) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.connected {
+		err := errors.New("device already connected")
+		d.logger.Errorf("DetectBaudRate failed: %v\n", err)
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		candidates = DefaultBaudRates
+	}
+
+	d.logger.Infof("Detecting baud rate of %s...\n", portName)
+
+	buffer := make([]byte, 1024)
+	for _, baudRate := range candidates {
+		if err := d.serialPort.Open(portName, baudRate); err != nil {
+			d.logger.Debugf("Failed to open %s at %d baud: %v\n", portName, baudRate, err)
+			continue
+		}
+
+		// Sentences may be split across reads
+		data := ""
+		found := false
+		for endTime := time.Now().Add(timeout); time.Now().Before(endTime); {
+			n, err := d.serialPort.Read(buffer)
+			if err != nil || n == 0 {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			data += string(buffer[:n])
+			if found = containsNMEA(data); found {
+				break
+			}
+			// Keep the tail for a sentence start split across reads
+			data = data[max(len(data)-2, 0):]
+		}
+
+		if err := d.serialPort.Close(); err != nil {
+			d.logger.Warnf("Failed to close %s: %v\n", portName, err)
+		}
+		if found {
+			d.logger.Infof("Detected baud rate: %d\n", baudRate)
+			return baudRate, nil
+		}
+		d.logger.Debugf("No NMEA data at %d baud\n", baudRate)
+	}
+
+	d.logger.Warnf("Baud rate detection failed: no NMEA data received\n")
+	return 0, fmt.Errorf("no NMEA data received at baud rates %v", candidates)
+}
+
 // VerifyConnectionWithContext checks if the device is sending valid GNSS data with context for cancellation
 func (d *TOP708Device) VerifyConnectionWithContext(ctx context.Context, timeout time.Duration) bool {
 	// Create a channel to communicate the result
@@ -252,6 +333,48 @@ func (d *TOP708Device) VerifyConnectionWithContext(ctx context.Context, timeout
 	}
 }
 
+// detectSampleSize is the number of bytes sampled by DetectProtocol, enough
+// for several epochs of NMEA or UBX output at the default rate
+const detectSampleSize = 4096
+
+// DetectProtocol samples the data sent by the device and reports the
+// protocols found, most frequent first (see DetectProtocols). Sampling stops
+// after detectSampleSize bytes or when the timeout expires. An error is
+// returned if no complete NMEA, UBX or RTCM frame was received.
+func (d *TOP708Device) DetectProtocol(timeout time.Duration) ([]string, error) {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("DetectProtocol failed: %v\n", err)
+		return nil, err
+	}
+
+	d.logger.Infof("Detecting protocol with timeout of %v...\n", timeout)
+
+	buffer := make([]byte, 1024)
+	sample := make([]byte, 0, detectSampleSize)
+	endTime := time.Now().Add(timeout)
+
+	for len(sample) < detectSampleSize && time.Now().Before(endTime) {
+		n, err := d.serialPort.Read(buffer)
+		if err != nil {
+			d.logger.Debugf("Read failed: %v\n", err)
+		}
+		if n > 0 {
+			sample = append(sample, buffer[:min(n, detectSampleSize-len(sample))]...)
+			continue
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	protocols := DetectProtocols(sample)
+	if len(protocols) == 0 {
+		d.logger.Warnf("Protocol detection failed: no complete frame in %d bytes\n", len(sample))
+		return nil, fmt.Errorf("no NMEA, UBX or RTCM data detected in %d bytes", len(sample))
+	}
+	d.logger.Infof("Detected protocols: %s\n", strings.Join(protocols, ", "))
+	return protocols, nil
+}
+
 // ReadRaw reads raw data from the device
 func (d *TOP708Device) ReadRaw(buffer []byte) (int, error) {
 	if !d.IsConnected() {
@@ -269,6 +392,66 @@ func (d *TOP708Device) ReadRaw(buffer []byte) (int, error) {
 	return n, err
 }
 
+// ReadObservations reads the device output until an epoch of raw
+// observations is decoded from UBX RXM-RAWX and returns the observations with
+// the navigation data decoded so far from UBX RXM-SFRBX, as input to
+// gnssgo.PntPos. The navigation data are kept across calls, so the first
+// epochs may come without ephemerides. Other output, e.g. NMEA, is skipped as
+// it carries no raw measurements. Running monitors are paused meanwhile.
+func (d *TOP708Device) ReadObservations() ([]gnssgo.ObsD, gnssgo.Nav, error) {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("ReadObservations failed: %v\n", err)
+		return nil, gnssgo.Nav{}, err
+	}
+
+	d.readLock.Lock()
+	defer d.readLock.Unlock()
+
+	if d.raw == nil {
+		d.raw = new(gnssgo.Raw)
+		d.raw.InitRaw(gnssgo.STRFMT_UBX)
+	}
+	buffer := make([]byte, 1024)
+	for endTime := time.Now().Add(observationTimeout); ; {
+		// Data left from the last call first
+		data := d.rawPending
+		d.rawPending = nil
+		if len(data) == 0 {
+			if !time.Now().Before(endTime) {
+				break
+			}
+			n, err := d.serialPort.Read(buffer)
+			if err != nil || n == 0 {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			data = buffer[:n]
+		}
+		for i := range data {
+			if d.raw.InputRaw(gnssgo.STRFMT_UBX, data[i]) != 1 {
+				continue
+			}
+			d.rawPending = append([]byte(nil), data[i+1:]...)
+			obs := append([]gnssgo.ObsD(nil), d.raw.ObsData.Data[:d.raw.ObsData.N()]...)
+			d.logger.Debugf("ReadObservations: %d observations\n", len(obs))
+			return obs, copyNav(&d.raw.NavData), nil
+		}
+	}
+	return nil, gnssgo.Nav{}, fmt.Errorf("%w: no UBX RXM-RAWX within %v", ErrNoResponse, observationTimeout)
+}
+
+// copyNav returns a copy of the navigation data not sharing the ephemerides
+// with nav
+func copyNav(nav *gnssgo.Nav) gnssgo.Nav {
+	c := *nav
+	c.Ephs = append([]gnssgo.Eph(nil), nav.Ephs...)
+	c.Geph = append([]gnssgo.GEph(nil), nav.Geph...)
+	c.Seph = append([]gnssgo.SEph(nil), nav.Seph...)
+	c.Alm = append([]gnssgo.Alm(nil), nav.Alm...)
+	return c
+}
+
 // ReadRawWithTimeout reads raw data from the device with a timeout
 func (d *TOP708Device) ReadRawWithTimeout(buffer []byte, timeout time.Duration) (int, error) {
 	if !d.IsConnected() {
@@ -324,6 +507,56 @@ func (d *TOP708Device) WriteRaw(data []byte) (int, error) {
 	return n, err
 }
 
+// FeedCorrections writes RTCM corrections to the receiver for its internal
+// RTK engine. The writes are throttled to the transmission rate of the port
+// (10 bits per byte at the baud rate), so that bursts of corrections do not
+// overrun the input of the receiver.
+func (d *TOP708Device) FeedCorrections(data []byte) error {
+	d.feedMutex.Lock()
+	defer d.feedMutex.Unlock()
+
+	if wait := time.Until(d.feedReady); wait > 0 {
+		time.Sleep(wait)
+	}
+	d.mutex.Lock()
+	baudRate := d.baudRate
+	d.mutex.Unlock()
+
+	if _, err := d.WriteRaw(data); err != nil {
+		return fmt.Errorf("failed to feed corrections: %w", err)
+	}
+	if baudRate > 0 {
+		d.feedReady = time.Now().Add(time.Duration(len(data)) * 10 * time.Second / time.Duration(baudRate))
+	}
+	return nil
+}
+
+// ForwardCorrections feeds the RTCM corrections read from source, e.g. an
+// ntrip.Client, to the receiver by FeedCorrections until ctx is canceled.
+// Read errors and empty reads are taken as no data available yet, like the
+// NTRIP client returns them. Corrections failed to write are logged and
+// dropped, the forwarding goes on.
+func (d *TOP708Device) ForwardCorrections(ctx context.Context, source io.Reader) error {
+	buffer := make([]byte, 4096)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := source.Read(buffer)
+		if err != nil || n == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(correctionPollInterval):
+			}
+			continue
+		}
+		if err := d.FeedCorrections(buffer[:n]); err != nil {
+			d.logger.Warnf("Dropped %d bytes of corrections: %v\n", n, err)
+		}
+	}
+}
+
 // WriteCommand sends a command to the device
 func (d *TOP708Device) WriteCommand(command string) error {
 	if !d.IsConnected() {
@@ -375,6 +608,176 @@ func (d *TOP708Device) WriteCommandWithResponse(command string, timeout time.Dur
 	return response, nil
 }
 
+// ErrNoResponse is returned (wrapped) when the device does not answer a
+// query within the timeout
+var ErrNoResponse = errors.New("no response from device")
+
+// queryTimeout is the time to wait for the response to a query
+var queryTimeout = 2 * time.Second
+
+// observationTimeout is the time to wait for an epoch of raw observations
+var observationTimeout = 2 * time.Second
+
+// correctionPollInterval is the interval of polling the correction source
+// while no data are available
+var correctionPollInterval = 10 * time.Millisecond
+
+// restartTimeout is the time to wait for the startup message after a restart
+var restartTimeout = 5 * time.Second
+
+// nmeaCommand appends the checksum to an NMEA command sentence "$..."
+func nmeaCommand(cmd string) string {
+	var checksum byte
+	for i := 1; i < len(cmd); i++ {
+		checksum ^= cmd[i]
+	}
+	return fmt.Sprintf("%s*%02X", cmd, checksum)
+}
+
+// queryResponse sends a command and reads the device output until a valid
+// sentence of the response type (e.g. "PMTK705") is received, for which
+// match returns true (nil: any). Other sentences, e.g. the periodic NMEA
+// output, are skipped. Running monitors are paused meanwhile, so that they do
+// not consume the response.
+func (d *TOP708Device) queryResponse(command, responseType string, match func(NMEASentence) bool,
+	timeout time.Duration) (NMEASentence, error) {
+	d.readLock.Lock()
+	defer d.readLock.Unlock()
+
+	if err := d.WriteCommand(command); err != nil {
+		return NMEASentence{}, err
+	}
+
+	parser := NewNMEAParser()
+	buffer := make([]byte, 1024)
+	data := ""
+	for endTime := time.Now().Add(timeout); time.Now().Before(endTime); {
+		n, err := d.serialPort.Read(buffer)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		data += string(buffer[:n])
+
+		// Complete sentences, the trailing partial one is kept
+		lines := strings.Split(data, "\r\n")
+		data = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			start := strings.LastIndex(line, "$")
+			if start < 0 {
+				continue
+			}
+			sentence := parser.Parse(line[start:])
+			if sentence.Valid && sentence.Type == responseType && (match == nil || match(sentence)) {
+				d.logger.Debugf("Received response: %s\n", sentence.Raw)
+				return sentence, nil
+			}
+		}
+		if len(data) > maxNMEALength {
+			data = ""
+		}
+	}
+	return NMEASentence{}, fmt.Errorf("%w: no %s within %v", ErrNoResponse, responseType, timeout)
+}
+
+// GetFirmwareVersion queries the firmware release of the device, e.g.
+// "AXN_5.1.7_3333_19020118", by PMTK605. The response is
+// $PMTK705,<release>,<build>,<product>,...
+func (d *TOP708Device) GetFirmwareVersion() (string, error) {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("GetFirmwareVersion failed: %v\n", err)
+		return "", err
+	}
+
+	d.logger.Infof("Querying firmware version...\n")
+
+	response, err := d.queryResponse(nmeaCommand("$PMTK605"), "PMTK705", nil, queryTimeout)
+	if err != nil {
+		d.logger.Errorf("Failed to query firmware version: %v\n", err)
+		return "", fmt.Errorf("failed to query firmware version: %w", err)
+	}
+	if len(response.Fields) == 0 || response.Fields[0] == "" {
+		err := fmt.Errorf("unexpected response: %s", response.Raw)
+		d.logger.Errorf("Failed to query firmware version: %v\n", err)
+		return "", err
+	}
+
+	d.logger.Infof("Firmware version: %s\n", response.Fields[0])
+	return response.Fields[0], nil
+}
+
+// SaveConfiguration saves the current configuration of the device to flash,
+// so that it is kept over a power cycle, by PQTMSAVEPAR. The device answers
+// $PQTMSAVEPAR,OK or $PQTMSAVEPAR,ERROR,<code>.
+func (d *TOP708Device) SaveConfiguration() error {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("SaveConfiguration failed: %v\n", err)
+		return err
+	}
+
+	d.logger.Infof("Saving configuration...\n")
+
+	response, err := d.queryResponse(nmeaCommand("$PQTMSAVEPAR"), "PQTMSAVEPAR", nil, queryTimeout)
+	if err != nil {
+		d.logger.Errorf("Failed to save configuration: %v\n", err)
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	if len(response.Fields) == 0 || response.Fields[0] != "OK" {
+		err := fmt.Errorf("configuration not saved: %s", response.Raw)
+		d.logger.Errorf("Failed to save configuration: %v\n", err)
+		return err
+	}
+
+	d.logger.Infof("Configuration saved successfully\n")
+	return nil
+}
+
+// HotStart restarts the receiver using all data kept (PMTK101)
+func (d *TOP708Device) HotStart() error {
+	return d.restart("hot start", 101)
+}
+
+// WarmStart restarts the receiver without the ephemeris (PMTK102)
+func (d *TOP708Device) WarmStart() error {
+	return d.restart("warm start", 102)
+}
+
+// ColdStart restarts the receiver without time, position, almanac and
+// ephemeris (PMTK103)
+func (d *TOP708Device) ColdStart() error {
+	return d.restart("cold start", 103)
+}
+
+// FactoryReset restarts the receiver with all data and the configuration
+// cleared to the factory defaults (PMTK104, full cold start)
+func (d *TOP708Device) FactoryReset() error {
+	return d.restart("factory reset", 104)
+}
+
+// restart sends a PMTK restart command and waits for the startup message
+// $PMTK010,001 of the receiver. Monitors keep running and resume after it.
+func (d *TOP708Device) restart(name string, code int) error {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("Restart (%s) failed: %v\n", name, err)
+		return err
+	}
+
+	d.logger.Infof("Restarting receiver (%s)...\n", name)
+
+	startup := func(s NMEASentence) bool { return len(s.Fields) > 0 && s.Fields[0] == "001" }
+	_, err := d.queryResponse(nmeaCommand(fmt.Sprintf("$PMTK%d", code)), "PMTK010", startup, restartTimeout)
+	if err != nil {
+		d.logger.Errorf("Failed to restart receiver (%s): %v\n", name, err)
+		return fmt.Errorf("failed to restart receiver (%s): %w", name, err)
+	}
+
+	d.logger.Infof("Receiver restarted (%s)\n", name)
+	return nil
+}
+
 // ChangeBaudRate changes the baud rate of the connection
 func (d *TOP708Device) ChangeBaudRate(baudRate int) error {
 	if !d.IsConnected() {
@@ -493,13 +896,29 @@ func (d *TOP708Device) GetCurrentPortName() string {
 	return d.portName
 }
 
-// MonitorNMEA starts monitoring NMEA data
+// MonitorNMEA starts monitoring NMEA data until StopMonitoring or Disconnect
 func (d *TOP708Device) MonitorNMEA(config MonitorConfig) error {
+	return d.MonitorNMEAContext(context.Background(), config)
+}
+
+// MonitorNMEAContext starts monitoring NMEA data until ctx is canceled or
+// StopMonitoring or Disconnect is called. The monitoring goroutine exits at
+// the latest after the read in progress.
+func (d *TOP708Device) MonitorNMEAContext(ctx context.Context, config MonitorConfig) error {
 	if !d.IsConnected() {
 		err := errors.New("device not connected")
 		d.logger.Errorf("MonitorNMEA failed: %v\n", err)
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		d.logger.Errorf("MonitorNMEA failed: %v\n", err)
+		return err
+	}
+
+	// Read buffer size, a sentence may span any number of reads
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultMonitorConfig(ProtocolNMEA, nil).BufferSize
+	}
 
 	d.logger.Infof("Starting NMEA monitoring with poll interval %v...\n", config.PollInterval)
 
@@ -512,74 +931,273 @@ func (d *TOP708Device) MonitorNMEA(config MonitorConfig) error {
 	lastErrorTime := time.Time{}
 
 	// Start monitoring in a goroutine
+	group, err := d.startMonitor()
+	if err != nil {
+		d.logger.Errorf("MonitorNMEA failed: %v\n", err)
+		return err
+	}
 	go func() {
+		defer group.wg.Done()
+		defer d.logger.Infof("NMEA monitoring stopped\n")
 		d.logger.Debugf("NMEA monitoring goroutine started\n")
 
+		// Wait for the poll interval between reads, stop on cancel or stop
+		for pause := time.Duration(0); group.sleep(ctx, pause); pause = config.PollInterval {
+			n, err := d.monitorRead(buffer)
+			if err != nil {
+				// Only log errors if they're not too frequent (avoid flooding logs)
+				if time.Since(lastErrorTime) > 5*time.Second {
+					d.logger.Debugf("Read error: %v (suppressing similar errors for 5s)\n", err)
+					lastErrorTime = time.Now()
+					errorCount++
+				}
+				continue
+			}
+
+			if n > 0 {
+				// Add new data to buffer
+				dataBuffer += string(buffer[:n])
+
+				// Process complete NMEA sentences
+				for {
+					// Find start and end of NMEA sentence
+					startIdx := strings.Index(dataBuffer, "$")
+					if startIdx == -1 {
+						break
+					}
+
+					endIdx := strings.Index(dataBuffer[startIdx:], "\r\n")
+					if endIdx == -1 {
+						break
+					}
+					endIdx += startIdx
+
+					// Extract and parse the sentence
+					sentence := dataBuffer[startIdx:endIdx]
+					parsedSentence := nmeaParser.Parse(sentence)
+
+					// Handle parsed data
+					if parsedSentence.Valid && config.Handler != nil {
+						sentenceCount++
+						if sentenceCount%100 == 0 {
+							d.logger.Debugf("Processed %d NMEA sentences, last type: %s\n",
+								sentenceCount, parsedSentence.Type)
+						}
+						config.Handler.HandleNMEA(parsedSentence)
+					} else if !parsedSentence.Valid {
+						d.logger.Debugf("Invalid NMEA sentence: %s (%v)\n", sentence, parsedSentence.Err)
+					}
+
+					// Remove processed data from buffer
+					dataBuffer = dataBuffer[endIdx+2:]
+				}
+
+				// Carry over the trailing partial sentence from its '$', which
+				// can not appear inside a sentence, and drop the data before it
+				if startIdx := strings.LastIndex(dataBuffer, "$"); startIdx >= 0 {
+					dataBuffer = dataBuffer[startIdx:]
+				} else {
+					dataBuffer = ""
+				}
+
+				// A partial sentence that grows beyond any NMEA sentence is garbage
+				if len(dataBuffer) > maxNMEALength {
+					d.logger.Warnf("Discarding %d bytes of unterminated NMEA data\n", len(dataBuffer))
+					dataBuffer = ""
+				}
+			}
+		}
+	}()
+
+	d.logger.Infof("NMEA monitoring started successfully\n")
+	return nil
+}
+
+// MonitorRTCM starts monitoring RTCM 3 data
+func (d *TOP708Device) MonitorRTCM(config MonitorConfig) error {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("MonitorRTCM failed: %v\n", err)
+		return err
+	}
+
+	d.logger.Infof("Starting RTCM monitoring with poll interval %v...\n", config.PollInterval)
+
+	// Create RTCM parser, it keeps incomplete frames between reads
+	rtcmParser := rtcm.NewRTCMParser()
+	buffer := make([]byte, config.BufferSize)
+	messageCount := 0
+	lastErrorTime := time.Time{}
+
+	// Start monitoring in a goroutine
+	group, err := d.startMonitor()
+	if err != nil {
+		d.logger.Errorf("MonitorRTCM failed: %v\n", err)
+		return err
+	}
+	go func() {
+		defer group.wg.Done()
+		d.logger.Debugf("RTCM monitoring goroutine started\n")
+
 		for {
 			select {
-			case <-d.stopChan:
-				d.logger.Infof("NMEA monitoring stopped\n")
+			case <-group.done:
+				d.logger.Infof("RTCM monitoring stopped\n")
 				return
 			default:
-				n, err := d.serialPort.Read(buffer)
+				n, err := d.monitorRead(buffer)
 				if err != nil {
 					// Only log errors if they're not too frequent (avoid flooding logs)
 					if time.Since(lastErrorTime) > 5*time.Second {
 						d.logger.Debugf("Read error: %v (suppressing similar errors for 5s)\n", err)
 						lastErrorTime = time.Now()
-						errorCount++
 					}
 					time.Sleep(config.PollInterval)
 					continue
 				}
 
 				if n > 0 {
-					// Add new data to buffer
-					dataBuffer += string(buffer[:n])
+					// Frame complete messages, garbage before a preamble is skipped
+					messages, _, err := rtcmParser.ParseRTCMMessage(buffer[:n])
+					if err != nil {
+						d.logger.Debugf("RTCM parse error: %v\n", err)
+					}
 
-					// Process complete NMEA sentences
+					for i := range messages {
+						raw := make([]byte, len(messages[i].Data))
+						copy(raw, messages[i].Data)
+						message := RTCMMessage{
+							Raw:       raw,
+							MessageID: messages[i].Type,
+							Length:    messages[i].Length - 3, // payload without header
+							Valid:     rtcm.ValidateCRC(&messages[i]),
+						}
+						if !message.Valid {
+							d.logger.Debugf("RTCM message %d failed CRC check\n", message.MessageID)
+						}
+
+						messageCount++
+						if messageCount%100 == 0 {
+							d.logger.Debugf("Processed %d RTCM messages, last type: %d\n",
+								messageCount, message.MessageID)
+						}
+						if config.Handler != nil {
+							config.Handler.HandleRTCM(message)
+						}
+					}
+				}
+
+				time.Sleep(config.PollInterval)
+			}
+		}
+	}()
+
+	d.logger.Infof("RTCM monitoring started successfully\n")
+	return nil
+}
+
+// MonitorUBX starts monitoring UBX data.
+// Frames are reassembled across reads and only delivered to the handler if
+// their checksum is valid. Partial frames are discarded once the pending data
+// grows beyond twice the buffer size.
+func (d *TOP708Device) MonitorUBX(config MonitorConfig) error {
+	if !d.IsConnected() {
+		err := errors.New("device not connected")
+		d.logger.Errorf("MonitorUBX failed: %v\n", err)
+		return err
+	}
+
+	d.logger.Infof("Starting UBX monitoring with poll interval %v...\n", config.PollInterval)
+
+	// Create UBX parser
+	ubxParser := NewUBXParser()
+	buffer := make([]byte, config.BufferSize)
+	var dataBuffer []byte
+	messageCount := 0
+	checksumErrors := 0
+	lastErrorTime := time.Time{}
+
+	// Start monitoring in a goroutine
+	group, err := d.startMonitor()
+	if err != nil {
+		d.logger.Errorf("MonitorUBX failed: %v\n", err)
+		return err
+	}
+	go func() {
+		defer group.wg.Done()
+		d.logger.Debugf("UBX monitoring goroutine started\n")
+
+		for {
+			select {
+			case <-group.done:
+				d.logger.Infof("UBX monitoring stopped\n")
+				return
+			default:
+				n, err := d.monitorRead(buffer)
+				if err != nil {
+					// Only log errors if they're not too frequent (avoid flooding logs)
+					if time.Since(lastErrorTime) > 5*time.Second {
+						d.logger.Debugf("Read error: %v (suppressing similar errors for 5s)\n", err)
+						lastErrorTime = time.Now()
+					}
+					time.Sleep(config.PollInterval)
+					continue
+				}
+
+				if n > 0 {
+					dataBuffer = append(dataBuffer, buffer[:n]...)
+
+					// Process complete UBX frames
 					for {
-						// Find start and end of NMEA sentence
-						startIdx := strings.Index(dataBuffer, "$")
+						// Find the sync characters, keep a trailing 0xB5 for the next read
+						startIdx := bytes.Index(dataBuffer, []byte{0xB5, 0x62})
 						if startIdx == -1 {
+							if len(dataBuffer) > 0 && dataBuffer[len(dataBuffer)-1] == 0xB5 {
+								dataBuffer = dataBuffer[len(dataBuffer)-1:]
+							} else {
+								dataBuffer = dataBuffer[:0]
+							}
 							break
 						}
+						dataBuffer = dataBuffer[startIdx:]
 
-						endIdx := strings.Index(dataBuffer[startIdx:], "\r\n")
-						if endIdx == -1 {
+						// Wait for the header and the complete frame
+						if len(dataBuffer) < 8 {
 							break
 						}
-						endIdx += startIdx
-
-						// Extract and parse the sentence
-						sentence := dataBuffer[startIdx:endIdx]
-						parsedSentence := nmeaParser.Parse(sentence)
-
-						// Handle parsed data
-						if parsedSentence.Valid && config.Handler != nil {
-							sentenceCount++
-							if sentenceCount%100 == 0 {
-								d.logger.Debugf("Processed %d NMEA sentences, last type: %s\n",
-									sentenceCount, parsedSentence.Type)
-							}
-							config.Handler.HandleNMEA(parsedSentence)
-						} else if !parsedSentence.Valid {
-							d.logger.Debugf("Invalid NMEA sentence: %s\n", sentence)
+						length := int(dataBuffer[4]) | int(dataBuffer[5])<<8
+						if len(dataBuffer) < length+8 {
+							break
 						}
 
-						// Remove processed data from buffer
-						if endIdx+2 <= len(dataBuffer) {
-							dataBuffer = dataBuffer[endIdx+2:]
-						} else {
-							dataBuffer = ""
+						raw := make([]byte, length+8)
+						copy(raw, dataBuffer[:length+8])
+						message := ubxParser.Parse(raw)
+						if !message.Valid {
+							// Resync on the next sync characters
+							checksumErrors++
+							d.logger.Debugf("UBX message 0x%02X 0x%02X failed checksum check (%d errors)\n",
+								message.Class, message.ID, checksumErrors)
+							dataBuffer = dataBuffer[2:]
+							continue
+						}
+						dataBuffer = dataBuffer[length+8:]
+
+						messageCount++
+						if messageCount%100 == 0 {
+							d.logger.Debugf("Processed %d UBX messages, last class/id: 0x%02X 0x%02X\n",
+								messageCount, message.Class, message.ID)
+						}
+						if config.Handler != nil {
+							config.Handler.HandleUBX(message)
 						}
 					}
-				}
 
-				// If the buffer gets too large without finding complete sentences, trim it
-				if len(dataBuffer) > config.BufferSize*2 {
-					d.logger.Warnf("NMEA buffer overflow, trimming %d bytes\n", len(dataBuffer)-config.BufferSize)
-					dataBuffer = dataBuffer[len(dataBuffer)-config.BufferSize:]
+					// Discard a partial frame that can not complete within the buffer limit
+					if len(dataBuffer) > config.BufferSize*2 {
+						d.logger.Debugf("Discarding %d bytes of incomplete UBX data\n", len(dataBuffer))
+						dataBuffer = dataBuffer[:0]
+					}
 				}
 
 				time.Sleep(config.PollInterval)
@@ -587,21 +1205,97 @@ func (d *TOP708Device) MonitorNMEA(config MonitorConfig) error {
 		}
 	}()
 
-	d.logger.Infof("NMEA monitoring started successfully\n")
+	d.logger.Infof("UBX monitoring started successfully\n")
 	return nil
 }
 
-// StopMonitoring stops all monitoring activities
+// StopMonitoring stops all monitoring activities and waits for the monitor
+// goroutines to exit. It may be called any number of times.
 func (d *TOP708Device) StopMonitoring() {
 	d.logger.Infof("Stopping monitoring...\n")
 
-	// Send stop signal with timeout to avoid blocking
+	if !d.stopMonitors() {
+		d.logger.Debugf("No monitoring active\n")
+	}
+}
+
+// monitorGroup tracks the monitor goroutines started until the next stop
+type monitorGroup struct {
+	done chan struct{}  // Closed to stop the monitors
+	wg   sync.WaitGroup // Running monitor goroutines
+}
+
+// startMonitor registers a monitor goroutine, which must call wg.Done on the
+// returned group when it exits and stop when its done channel is closed.
+// The check of the connection and the registration are atomic, so a monitor
+// can not start on a port being closed by Disconnect.
+func (d *TOP708Device) startMonitor() (*monitorGroup, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.connected {
+		return nil, errors.New("device not connected")
+	}
+	if d.monitors == nil {
+		d.monitors = &monitorGroup{done: make(chan struct{})}
+	}
+	d.monitors.wg.Add(1)
+	return d.monitors, nil
+}
+
+// monitorRead reads from the port for a monitor, it blocks while the
+// monitors are paused for a query
+func (d *TOP708Device) monitorRead(buffer []byte) (int, error) {
+	d.readLock.RLock()
+	defer d.readLock.RUnlock()
+	return d.serialPort.Read(buffer)
+}
+
+// sleep waits for d and reports whether the monitor should go on, false if
+// ctx is canceled or the monitors are stopped
+func (g *monitorGroup) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-g.done:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 	select {
-	case d.stopChan <- true:
-		d.logger.Debugf("Stop signal sent\n")
-	case <-time.After(500 * time.Millisecond):
-		d.logger.Warnf("Timed out sending stop signal, monitoring may already be stopped\n")
+	case <-ctx.Done():
+		return false
+	case <-g.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// stopMonitors signals the running monitors to stop and waits for them.
+// It reports whether any monitor was running.
+func (d *TOP708Device) stopMonitors() bool {
+	d.mutex.Lock()
+	group := d.monitors
+	d.monitors = nil
+	d.mutex.Unlock()
+
+	return group.stop()
+}
+
+// stop signals the monitors of the group to stop and waits for them. It
+// reports whether the group was running, false for a nil group.
+func (g *monitorGroup) stop() bool {
+	if g == nil {
+		return false
 	}
+	close(g.done)
+	g.wg.Wait()
+	return true
 }
 
 // ConfigureOutputMessages configures which NMEA messages are output by the device
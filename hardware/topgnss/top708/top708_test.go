@@ -1,10 +1,19 @@
 package top708
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.bug.st/serial/enumerator"
@@ -229,6 +238,91 @@ func TestTOP708DeviceVerifyConnectionNotConnected(t *testing.T) {
 	assert.False(t, result)
 }
 
+// TestTOP708DeviceDetectBaudRate tests detecting the baud rate of a device
+// sending NMEA at 115200 baud, garbage at the other rates
+func TestTOP708DeviceDetectBaudRate(t *testing.T) {
+	sentence := []byte("$GNGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*59\r\n")
+	garbage := make([]byte, len(sentence))
+	for i := range garbage {
+		garbage[i] = byte(0x80 + i%64)
+	}
+
+	serialPort := new(MockSerialPort)
+	serialPort.data = sentence
+	var rates []int
+	serialPort.On("Open", "/dev/ttyUSB0", mock.Anything).Run(func(args mock.Arguments) {
+		rates = append(rates, args.Int(1))
+	}).Return(nil)
+	serialPort.On("Close").Return(nil)
+	serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+		if rates[len(rates)-1] == 115200 {
+			copy(args.Get(0).([]byte), sentence)
+		} else {
+			copy(args.Get(0).([]byte), garbage)
+		}
+	}).Return(len(sentence), nil)
+
+	device := NewTOP708Device(serialPort)
+	rate, err := device.DetectBaudRate("/dev/ttyUSB0", nil, 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 115200, rate)
+	assert.Equal(t, []int{38400, 9600, 115200}, rates)
+	serialPort.AssertNumberOfCalls(t, "Close", 3)
+	assert.False(t, device.IsConnected())
+
+	// No NMEA at any candidate
+	rates = nil
+	rate, err = device.DetectBaudRate("/dev/ttyUSB0", []int{4800, 9600}, 20*time.Millisecond)
+	assert.Error(t, err)
+	assert.Zero(t, rate)
+	assert.Equal(t, []int{4800, 9600}, rates)
+
+	// Connected devices are not probed
+	device.connected = true
+	_, err = device.DetectBaudRate("/dev/ttyUSB0", nil, 20*time.Millisecond)
+	assert.Error(t, err)
+}
+
+// TestTOP708DeviceDetectProtocol tests detecting a UBX only stream whose
+// messages carry NMEA text
+func TestTOP708DeviceDetectProtocol(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	text := []byte("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	serialPort.data = append(ubxFrame(0x01, 0x07, make([]byte, 92)), ubxFrame(0x04, 0x02, text)...)
+	serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+		copy(args.Get(0).([]byte), serialPort.data)
+	}).Return(len(serialPort.data), nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+
+	protocols, err := device.DetectProtocol(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ProtocolUBX}, protocols)
+	assert.NotContains(t, protocols, ProtocolNMEA)
+}
+
+// TestTOP708DeviceDetectProtocolNoData tests DetectProtocol without valid frames
+func TestTOP708DeviceDetectProtocolNoData(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte("$GPGGA,noise")
+	serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+		copy(args.Get(0).([]byte), serialPort.data)
+	}).Return(len(serialPort.data), nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+
+	protocols, err := device.DetectProtocol(time.Second)
+	assert.Error(t, err)
+	assert.Empty(t, protocols)
+
+	_, err = NewTOP708Device(new(MockSerialPort)).DetectProtocol(time.Second)
+	assert.Error(t, err)
+}
+
 // TestTOP708DeviceReadRaw tests the ReadRaw method
 func TestTOP708DeviceReadRaw(t *testing.T) {
 	// Create a mock serial port
@@ -436,6 +530,457 @@ func TestTOP708DeviceConfigureOutputMessages(t *testing.T) {
 	serialPort.AssertCalled(t, "Write", mock.Anything)
 }
 
+// queryPort returns a mock serial port reading the chunks in turn, then
+// nothing, and recording the data written
+func queryPort(chunks ...string) *MockSerialPort {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	serialPort.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		serialPort.written = append(serialPort.written, args.Get(0).([]byte)...)
+	}).Return(0, nil)
+	for _, chunk := range chunks {
+		chunk := chunk
+		serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+			copy(args.Get(0).([]byte), chunk)
+		}).Return(len(chunk), nil).Once()
+	}
+	serialPort.On("Read", mock.Anything).Return(0, nil)
+	return serialPort
+}
+
+// nmeaLine returns an NMEA sentence with checksum and CR/LF
+func nmeaLine(body string) string {
+	return fmt.Sprintf("$%s*%s\r\n", body, NewNMEAParser().calculateChecksum(body))
+}
+
+// TestTOP708DeviceGetFirmwareVersion tests the version query with the
+// response split across reads among the periodic NMEA output
+func TestTOP708DeviceGetFirmwareVersion(t *testing.T) {
+	gga := nmeaLine("GNGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	version := nmeaLine("PMTK705,AXN_5.1.7_3333_19020118,0027,TOP708,1.0")
+	serialPort := queryPort(gga+version[:12], version[12:]+gga)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	got, err := device.GetFirmwareVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "AXN_5.1.7_3333_19020118", got)
+	assert.Equal(t, "$PMTK605*31\r\n", string(serialPort.written))
+
+	// No response, only NMEA output
+	defer func(timeout time.Duration) { queryTimeout = timeout }(queryTimeout)
+	queryTimeout = 50 * time.Millisecond
+	device = NewTOP708Device(queryPort(gga, gga))
+	device.connected = true
+	_, err = device.GetFirmwareVersion()
+	assert.ErrorIs(t, err, ErrNoResponse)
+
+	// Response with a bad checksum is ignored
+	device = NewTOP708Device(queryPort("$PMTK705,AXN_5.1.7_3333_19020118,0027,TOP708,1.0*00\r\n"))
+	device.connected = true
+	_, err = device.GetFirmwareVersion()
+	assert.ErrorIs(t, err, ErrNoResponse)
+
+	_, err = NewTOP708Device(new(MockSerialPort)).GetFirmwareVersion()
+	assert.Error(t, err)
+}
+
+// TestTOP708DeviceSaveConfiguration tests saving the configuration with an
+// OK and an ERROR response
+func TestTOP708DeviceSaveConfiguration(t *testing.T) {
+	serialPort := queryPort(nmeaLine("PQTMSAVEPAR,OK"))
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	assert.NoError(t, device.SaveConfiguration())
+	assert.Equal(t, "$PQTMSAVEPAR*5A\r\n", string(serialPort.written))
+
+	device = NewTOP708Device(queryPort(nmeaLine("PQTMSAVEPAR,ERROR,1")))
+	device.connected = true
+	err := device.SaveConfiguration()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "configuration not saved")
+
+	defer func(timeout time.Duration) { queryTimeout = timeout }(queryTimeout)
+	queryTimeout = 50 * time.Millisecond
+	device = NewTOP708Device(queryPort())
+	device.connected = true
+	assert.ErrorIs(t, device.SaveConfiguration(), ErrNoResponse)
+}
+
+// TestTOP708DeviceRestart tests the restart commands and the wait for the
+// startup message
+func TestTOP708DeviceRestart(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart func(d *TOP708Device) error
+		command string
+	}{
+		{"hot start", (*TOP708Device).HotStart, "$PMTK101*32\r\n"},
+		{"warm start", (*TOP708Device).WarmStart, "$PMTK102*31\r\n"},
+		{"cold start", (*TOP708Device).ColdStart, "$PMTK103*30\r\n"},
+		{"factory reset", (*TOP708Device).FactoryReset, "$PMTK104*37\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Startup message after the output before the restart and a
+			// system message of another type
+			serialPort := queryPort(nmeaLine("GPGSA,A,1,,,"), nmeaLine("PMTK010,002"), nmeaLine("PMTK010,001"))
+			device := NewTOP708Device(serialPort)
+			device.connected = true
+			assert.NoError(t, tt.restart(device))
+			assert.Equal(t, tt.command, string(serialPort.written))
+		})
+	}
+
+	device := NewTOP708Device(queryPort())
+	assert.Error(t, device.ColdStart())
+
+	defer func(timeout time.Duration) { restartTimeout = timeout }(restartTimeout)
+	restartTimeout = 50 * time.Millisecond
+	device = NewTOP708Device(queryPort(nmeaLine("PMTK010,002")))
+	device.connected = true
+	err := device.HotStart()
+	assert.ErrorIs(t, err, ErrNoResponse)
+	assert.Contains(t, err.Error(), "hot start")
+}
+
+// TestTOP708DeviceRestartMonitoring tests a restart while the NMEA monitor
+// reads the port, the startup message must not be taken by the monitor
+func TestTOP708DeviceRestartMonitoring(t *testing.T) {
+	// Sentences of the same length for the fixed return of the mock
+	gsa, startup := nmeaLine("GPGSA,A,1,,"), nmeaLine("PMTK010,001")
+	var restarted atomic.Bool
+
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	serialPort.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		serialPort.written = append(serialPort.written, args.Get(0).([]byte)...)
+		restarted.Store(true)
+	}).Return(0, nil)
+	serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+		if restarted.CompareAndSwap(true, false) {
+			copy(args.Get(0).([]byte), startup)
+		} else {
+			copy(args.Get(0).([]byte), gsa)
+		}
+	}).Return(len(gsa), nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	handler := &nmeaRecorder{}
+	config := DefaultMonitorConfig(ProtocolNMEA, handler)
+	config.PollInterval = time.Millisecond
+	assert.NoError(t, device.MonitorNMEA(config))
+	defer device.StopMonitoring()
+
+	count := func() int {
+		handler.mutex.Lock()
+		defer handler.mutex.Unlock()
+		return len(handler.sentences)
+	}
+	assert.Eventually(t, func() bool { return count() > 0 }, time.Second, time.Millisecond)
+
+	assert.NoError(t, device.WarmStart())
+	assert.Equal(t, "$PMTK102*31\r\n", string(serialPort.written))
+
+	// Monitor resumed with the output after the restart
+	n := count()
+	assert.Eventually(t, func() bool { return count() > n }, time.Second, time.Millisecond)
+	handler.mutex.Lock()
+	for _, sentence := range handler.sentences {
+		assert.Equal(t, "GPGSA", sentence.Type)
+	}
+	handler.mutex.Unlock()
+}
+
+// gpsSubframes encodes the ephemeris into the GPS LNAV subframes 1-3 with
+// 24-bit words without parity, as decoded by gnssgo.DecodeFrameEph
+func gpsSubframes(eph *gnssgo.Eph) [3][30]byte {
+	var sf [3][30]byte
+	q := func(v, scale float64) int32 { return int32(math.Round(v / scale)) }
+	qu := func(v, scale float64) uint32 { return uint32(math.Round(v / scale)) }
+	tow := uint32(math.Floor(eph.Toes/6.0)) - 2
+	for k := range sf {
+		b := sf[k][:]
+		gnssgo.SetBitU(b, 0, 8, 0x8B)
+		gnssgo.SetBitU(b, 24, 17, tow+uint32(k))
+		gnssgo.SetBitU(b, 43, 3, uint32(k+1))
+	}
+	b := sf[0][:]
+	gnssgo.SetBitU(b, 48, 10, uint32(eph.Week%1024))
+	gnssgo.SetBitU(b, 64, 6, uint32(eph.Svh))
+	gnssgo.SetBitU(b, 70, 2, uint32(eph.Iodc>>8))
+	gnssgo.SetBits(b, 160, 8, q(eph.Tgd[0], gnssgo.P2_31))
+	gnssgo.SetBitU(b, 168, 8, uint32(eph.Iodc&0xFF))
+	gnssgo.SetBitU(b, 176, 16, qu(eph.Toes, 16.0))
+	gnssgo.SetBits(b, 192, 8, q(eph.F2, gnssgo.P2_55))
+	gnssgo.SetBits(b, 200, 16, q(eph.F1, gnssgo.P2_43))
+	gnssgo.SetBits(b, 216, 22, q(eph.F0, gnssgo.P2_31))
+
+	b = sf[1][:]
+	gnssgo.SetBitU(b, 48, 8, uint32(eph.Iode))
+	gnssgo.SetBits(b, 56, 16, q(eph.Crs, gnssgo.P2_5))
+	gnssgo.SetBits(b, 72, 16, q(eph.Deln, gnssgo.P2_43*gnssgo.SC2RAD))
+	gnssgo.SetBits(b, 88, 32, q(eph.M0, gnssgo.P2_31*gnssgo.SC2RAD))
+	gnssgo.SetBits(b, 120, 16, q(eph.Cuc, gnssgo.P2_29))
+	gnssgo.SetBitU(b, 136, 32, qu(eph.E, gnssgo.P2_33))
+	gnssgo.SetBits(b, 168, 16, q(eph.Cus, gnssgo.P2_29))
+	gnssgo.SetBitU(b, 184, 32, qu(math.Sqrt(eph.A), gnssgo.P2_19))
+	gnssgo.SetBitU(b, 216, 16, qu(eph.Toes, 16.0))
+
+	b = sf[2][:]
+	gnssgo.SetBits(b, 48, 16, q(eph.Cic, gnssgo.P2_29))
+	gnssgo.SetBits(b, 64, 32, q(eph.OMG0, gnssgo.P2_31*gnssgo.SC2RAD))
+	gnssgo.SetBits(b, 96, 16, q(eph.Cis, gnssgo.P2_29))
+	gnssgo.SetBits(b, 112, 32, q(eph.I0, gnssgo.P2_31*gnssgo.SC2RAD))
+	gnssgo.SetBits(b, 144, 16, q(eph.Crc, gnssgo.P2_5))
+	gnssgo.SetBits(b, 160, 32, q(eph.Omg, gnssgo.P2_31*gnssgo.SC2RAD))
+	gnssgo.SetBits(b, 192, 24, q(eph.OMGd, gnssgo.P2_43*gnssgo.SC2RAD))
+	gnssgo.SetBitU(b, 216, 8, uint32(eph.Iode))
+	gnssgo.SetBits(b, 224, 14, q(eph.Idot, gnssgo.P2_43*gnssgo.SC2RAD))
+	return sf
+}
+
+// ubxSFRBX returns a UBX RXM-SFRBX message of a GPS LNAV subframe
+func ubxSFRBX(prn int, subframe [30]byte) []byte {
+	payload := []byte{0, byte(prn), 0, 0, 10, 0, 2, 0}
+	for i := 0; i < 10; i++ {
+		word := gnssgo.GetBitU(subframe[:], 24*i, 24) << 6
+		payload = binary.LittleEndian.AppendUint32(payload, word)
+	}
+	return ubxFrame(0x02, 0x13, payload)
+}
+
+// ubxRAWX returns a UBX RXM-RAWX message with GPS L1C/A pseudoranges
+func ubxRAWX(week int, tow float64, prns []int, ranges []float64) []byte {
+	payload := binary.LittleEndian.AppendUint64(nil, math.Float64bits(tow))
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(week))
+	payload = append(payload, 18, byte(len(prns)), 1, 1, 0, 0)
+	for i, prn := range prns {
+		meas := make([]byte, 32)
+		binary.LittleEndian.PutUint64(meas, math.Float64bits(ranges[i]))
+		meas[21] = byte(prn)
+		binary.LittleEndian.PutUint16(meas[24:], 10000) // locktime (ms)
+		meas[26] = 45                                   // C/N0 (dBHz)
+		meas[30] = 1                                    // pseudorange valid
+		payload = append(payload, meas...)
+	}
+	return ubxFrame(0x02, 0x15, payload)
+}
+
+// simulateRange returns the pseudorange of the satellite observed at time t
+// from rr with receiver clock bias dtr (m) and the elevation (rad)
+func simulateRange(eph *gnssgo.Eph, t gnssgo.Gtime, rr []float64, dtr float64) (float64, float64) {
+	var (
+		rs             [6]float64
+		e, pos, azel   [3]float64
+		dts, vari, rng float64
+	)
+	P := 0.075 * gnssgo.CLIGHT
+	for iter := 0; iter < 5; iter++ {
+		ts := gnssgo.TimeAdd(t, -P/gnssgo.CLIGHT)
+		ts = gnssgo.TimeAdd(ts, -gnssgo.Eph2Clk(ts, eph))
+		gnssgo.Eph2Pos(ts, eph, rs[:], &dts, &vari)
+		rng = gnssgo.GeoDist(rs[:], rr, e[:])
+		P = rng + dtr - gnssgo.CLIGHT*dts
+	}
+	gnssgo.Ecef2Pos(rr, pos[:])
+	return P, gnssgo.SatAzel(pos[:], e[:], azel[:])
+}
+
+// TestTOP708DeviceReadObservations feeds UBX RXM-SFRBX subframes and RXM-RAWX
+// pseudoranges of a simulated GPS constellation among NMEA output and checks
+// the single point position computed from the decoded observations
+func TestTOP708DeviceReadObservations(t *testing.T) {
+	const week, toes, dtr = 2300, 345600.0, 3000.0
+	rr := []float64{-3961904.9, 3348993.8, 3698211.8}
+
+	var (
+		stream []byte
+		prns   []int
+		ephs   []gnssgo.Eph
+	)
+	stream = append(stream, nmeaLine("GNGGA,000000.00,,,,,0,00,99.99,,,,,,")...)
+	for k := 0; k < 24; k++ {
+		eph := gnssgo.Eph{
+			Sat: gnssgo.SatNo(gnssgo.SYS_GPS, k+1), Iode: k + 1, Iodc: k + 1,
+			Week: week, Toes: toes,
+			A: 26559710.0, E: 0.005, I0: 55.0 * gnssgo.D2R,
+			OMG0: math.Remainder(float64(k%6)*60.0*gnssgo.D2R, 2.0*math.Pi),
+			M0:   math.Remainder(float64(k/6)*65.0*gnssgo.D2R+float64(k%6)*15.0*gnssgo.D2R, 2.0*math.Pi),
+			F0:   1e-5 * float64(k%3),
+		}
+		sf := gpsSubframes(&eph)
+		for i := range sf {
+			stream = append(stream, ubxSFRBX(k+1, sf[i])...)
+		}
+		// Ephemeris as decoded, with the quantization of the subframes
+		buff := append(append(sf[0][:], sf[1][:]...), sf[2][:]...)
+		if gnssgo.DecodeFrameEph(buff, &eph) == 0 {
+			t.Fatalf("PRN %d: subframes not decoded", k+1)
+		}
+		eph.Sat = gnssgo.SatNo(gnssgo.SYS_GPS, k+1)
+		ephs = append(ephs, eph)
+	}
+	for _, tow := range []float64{toes + 600.0, toes + 601.0} {
+		var ranges []float64
+		prns = prns[:0]
+		for k := range ephs {
+			P, el := simulateRange(&ephs[k], gnssgo.GpsT2Time(week, tow), rr, dtr)
+			if el >= 15.0*gnssgo.D2R {
+				prns = append(prns, k+1)
+				ranges = append(ranges, P)
+			}
+		}
+		stream = append(stream, ubxRAWX(week, tow, prns, ranges)...)
+	}
+	if len(prns) < 5 {
+		t.Fatalf("visible satellites = %d", len(prns))
+	}
+
+	// Output in reads of varying size
+	var chunks []string
+	for i, n := 0, 100; i < len(stream); i, n = i+n, n%700+150 {
+		chunks = append(chunks, string(stream[i:min(i+n, len(stream))]))
+	}
+	device := NewTOP708Device(queryPort(chunks...))
+	device.connected = true
+
+	for epoch, tow := range []float64{toes + 600.0, toes + 601.0} {
+		obs, nav, err := device.ReadObservations()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, obs, len(prns))
+		assert.Equal(t, 0.0, gnssgo.TimeDiff(obs[0].Time, gnssgo.GpsT2Time(week, tow)), "epoch %d", epoch)
+
+		var (
+			sol gnssgo.Sol
+			msg string
+		)
+		opt := gnssgo.DefaultProcOpt()
+		ssat := make([]gnssgo.SSat, gnssgo.MAXSAT)
+		if gnssgo.PntPos(obs, len(obs), &nav, &opt, &sol, nil, ssat, &msg) == 0 {
+			t.Fatalf("epoch %d: pntpos failed: %s", epoch, msg)
+		}
+		assert.Equal(t, gnssgo.SOLQ_SINGLE, int(sol.Stat))
+		for i := 0; i < 3; i++ {
+			assert.InDelta(t, rr[i], sol.Rr[i], 1e-3, "epoch %d: position %d", epoch, i)
+		}
+		assert.InDelta(t, dtr/gnssgo.CLIGHT, sol.Dtr[0], 1e-11)
+
+		// Navigation data returned are a copy
+		assert.Equal(t, ephs[0].A, nav.Ephs[0].A)
+		nav.Ephs[0].A = 0.0
+	}
+
+	defer func(timeout time.Duration) { observationTimeout = timeout }(observationTimeout)
+	observationTimeout = 50 * time.Millisecond
+	_, _, err := device.ReadObservations()
+	assert.ErrorIs(t, err, ErrNoResponse)
+
+	device = NewTOP708Device(queryPort())
+	_, _, err = device.ReadObservations()
+	assert.Error(t, err)
+}
+
+// stubNTRIPClient returns the queued corrections in reads and io.EOF while
+// no data are queued, like ntrip.Client
+type stubNTRIPClient struct {
+	mutex sync.Mutex
+	data  [][]byte
+}
+
+func (c *stubNTRIPClient) Read(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.data[0])
+	c.data = c.data[1:]
+	return n, nil
+}
+
+func (c *stubNTRIPClient) queue(data []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.data = append(c.data, data)
+}
+
+// TestTOP708DeviceForwardCorrections tests that the RTCM received from the
+// NTRIP client is written to the receiver, also after a failed write
+func TestTOP708DeviceForwardCorrections(t *testing.T) {
+	// RTCM 1005 frame from the RTCM 3 standard
+	rtcm1005 := []byte{
+		0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF,
+		0x34, 0xB4, 0xBD, 0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33, 0x36, 0x0B, 0x98,
+	}
+
+	var mutex sync.Mutex
+	var written [][]byte
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.On("Write", mock.Anything).Return(0, errors.New("write failed")).Once()
+	serialPort.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		written = append(written, append([]byte(nil), args.Get(0).([]byte)...))
+	}).Return(0, nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	client := &stubNTRIPClient{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- device.ForwardCorrections(ctx, client) }()
+
+	// The first write fails, the forwarding goes on with a frame split
+	// across reads passed through as received
+	client.queue(rtcm1005)
+	client.queue(rtcm1005[:10])
+	time.Sleep(50 * time.Millisecond)
+	client.queue(rtcm1005[10:])
+
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(written) == 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, [][]byte{rtcm1005[:10], rtcm1005[10:]}, written)
+}
+
+// TestTOP708DeviceFeedCorrections tests the throttling of the corrections to
+// the baud rate
+func TestTOP708DeviceFeedCorrections(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.On("Write", mock.Anything).Run(func(args mock.Arguments) {
+		serialPort.written = append(serialPort.written, args.Get(0).([]byte)...)
+	}).Return(0, nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	device.baudRate = 9600
+
+	// 96 bytes take 100 ms at 9600 baud
+	data := make([]byte, 96)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, device.FeedCorrections(data))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Len(t, serialPort.written, 3*len(data))
+
+	device = NewTOP708Device(serialPort)
+	assert.Error(t, device.FeedCorrections(data))
+}
+
 // TestTOP708DeviceConfigureUpdateRate tests the ConfigureUpdateRate method
 func TestTOP708DeviceConfigureUpdateRate(t *testing.T) {
 	// Create a mock serial port
@@ -540,3 +1085,421 @@ func TestTOP708DeviceConfigureSatelliteSystems(t *testing.T) {
 	assert.NoError(t, err)
 	serialPort.AssertCalled(t, "Write", mock.Anything)
 }
+
+// rtcmRecorder is a DataHandler recording the RTCM messages it receives
+type rtcmRecorder struct {
+	mutex    sync.Mutex
+	messages []RTCMMessage
+}
+
+func (h *rtcmRecorder) HandleNMEA(sentence NMEASentence) {}
+func (h *rtcmRecorder) HandleUBX(message UBXMessage)     {}
+
+func (h *rtcmRecorder) HandleRTCM(message RTCMMessage) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.messages = append(h.messages, message)
+}
+
+func (h *rtcmRecorder) received() []RTCMMessage {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]RTCMMessage(nil), h.messages...)
+}
+
+// TestTOP708DeviceMonitorRTCM tests framing RTCM messages across read boundaries
+func TestTOP708DeviceMonitorRTCM(t *testing.T) {
+	// RTCM 1005 frame from the RTCM 3 standard, with a valid CRC
+	frame := []byte{
+		0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF,
+		0x34, 0xB4, 0xBD, 0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33, 0x36, 0x0B, 0x98,
+	}
+	concat := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, p := range parts {
+			data = append(data, p...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   int
+	}{
+		{"split frame", [][]byte{frame[:10], frame[10:]}, 1},
+		{"garbage before preamble", [][]byte{concat([]byte("$GP\x01\x02"), frame[:5]), frame[5:]}, 1},
+		{"split header", [][]byte{frame[:2], frame[2:]}, 1},
+		{"two frames", [][]byte{concat(frame, frame[:20]), frame[20:]}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialPort := new(MockSerialPort)
+			serialPort.connected = true
+			serialPort.data = []byte{0}
+			for _, chunk := range tt.chunks {
+				chunk := chunk
+				serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+					copy(args.Get(0).([]byte), chunk)
+				}).Return(len(chunk), nil).Once()
+			}
+			serialPort.On("Read", mock.Anything).Return(0, nil)
+
+			device := NewTOP708Device(serialPort)
+			device.connected = true
+			handler := &rtcmRecorder{}
+			config := DefaultMonitorConfig(ProtocolRTCM, handler)
+			config.PollInterval = time.Millisecond
+
+			assert.NoError(t, device.MonitorRTCM(config))
+			time.Sleep(50 * time.Millisecond)
+			device.StopMonitoring()
+
+			messages := handler.received()
+			if assert.Len(t, messages, tt.want) {
+				for _, m := range messages {
+					assert.Equal(t, 1005, m.MessageID)
+					assert.Equal(t, 19, m.Length)
+					assert.Equal(t, frame, m.Raw)
+					assert.True(t, m.Valid)
+				}
+			}
+		})
+	}
+}
+
+// TestTOP708DeviceMonitorRTCMNotConnected tests MonitorRTCM when not connected
+func TestTOP708DeviceMonitorRTCMNotConnected(t *testing.T) {
+	device := NewTOP708Device(new(MockSerialPort))
+	err := device.MonitorRTCM(DefaultMonitorConfig(ProtocolRTCM, &rtcmRecorder{}))
+	assert.Error(t, err)
+}
+
+// nmeaRecorder is a DataHandler recording the NMEA sentences it receives
+type nmeaRecorder struct {
+	mutex     sync.Mutex
+	sentences []NMEASentence
+}
+
+func (h *nmeaRecorder) HandleRTCM(message RTCMMessage) {}
+func (h *nmeaRecorder) HandleUBX(message UBXMessage)   {}
+
+func (h *nmeaRecorder) HandleNMEA(sentence NMEASentence) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.sentences = append(h.sentences, sentence)
+}
+
+func (h *nmeaRecorder) received() []NMEASentence {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]NMEASentence(nil), h.sentences...)
+}
+
+// TestTOP708DeviceMonitorNMEA tests framing a high rate NMEA stream read in
+// chunks smaller than a sentence and split at awkward boundaries
+func TestTOP708DeviceMonitorNMEA(t *testing.T) {
+	parser := NewNMEAParser()
+	var stream []byte
+	var want []string
+	for i := 0; i < 100; i++ {
+		body := fmt.Sprintf("GPGGA,%06d.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,", 120000+i)
+		sentence := fmt.Sprintf("$%s*%s", body, parser.calculateChecksum(body))
+		want = append(want, sentence)
+		stream = append(stream, sentence+"\r\n"...)
+		if i%25 == 10 {
+			stream = append(stream, 0x00, 0xB5, 0x62) // binary noise between sentences
+		}
+	}
+
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	const bufferSize = 32 // less than half a sentence
+	sizes := []int{1, 5, 32, 17, 31, 2, 20, 13}
+	for i, k := 0, 0; i < len(stream); k++ {
+		chunk := stream[i:min(i+sizes[k%len(sizes)], len(stream))]
+		i += len(chunk)
+		serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+			copy(args.Get(0).([]byte), chunk)
+		}).Return(len(chunk), nil).Once()
+	}
+	serialPort.On("Read", mock.Anything).Return(0, nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	handler := &nmeaRecorder{}
+	config := DefaultMonitorConfig(ProtocolNMEA, handler)
+	config.BufferSize = bufferSize
+	config.PollInterval = 0
+
+	assert.NoError(t, device.MonitorNMEA(config))
+	deadline := time.Now().Add(10 * time.Second)
+	for len(handler.received()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	device.StopMonitoring()
+
+	sentences := handler.received()
+	if assert.Len(t, sentences, len(want)) {
+		for i, s := range sentences {
+			assert.Equal(t, want[i], s.Raw)
+			assert.True(t, s.Valid)
+		}
+	}
+}
+
+// TestTOP708DeviceMonitorNMEAContext tests that canceling the context stops
+// the monitoring goroutine promptly, also while it waits for the next poll
+func TestTOP708DeviceMonitorNMEAContext(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	serialPort.On("Read", mock.Anything).Return(0, nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	config := DefaultMonitorConfig(ProtocolNMEA, &nmeaRecorder{})
+
+	for _, poll := range []time.Duration{0, time.Hour} {
+		before := runtime.NumGoroutine()
+		config.PollInterval = poll
+		ctx, cancel := context.WithCancel(context.Background())
+		assert.NoError(t, device.MonitorNMEAContext(ctx, config))
+		assert.Greater(t, runtime.NumGoroutine(), before, "no monitor goroutine")
+
+		cancel()
+		deadline := time.Now().Add(100 * time.Millisecond)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), before, "monitor goroutine not stopped (poll %v)", poll)
+
+		// Nothing left to wait for
+		start := time.Now()
+		device.StopMonitoring()
+		assert.Less(t, time.Since(start), 100*time.Millisecond, "stop after cancel blocked")
+	}
+
+	// A canceled context does not start monitoring
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, device.MonitorNMEAContext(ctx, config), context.Canceled)
+}
+
+// ubxRecorder is a DataHandler recording the UBX messages it receives
+type ubxRecorder struct {
+	mutex    sync.Mutex
+	messages []UBXMessage
+}
+
+func (h *ubxRecorder) HandleNMEA(sentence NMEASentence) {}
+func (h *ubxRecorder) HandleRTCM(message RTCMMessage)   {}
+
+func (h *ubxRecorder) HandleUBX(message UBXMessage) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.messages = append(h.messages, message)
+}
+
+func (h *ubxRecorder) received() []UBXMessage {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]UBXMessage(nil), h.messages...)
+}
+
+// TestTOP708DeviceMonitorUBX tests framing UBX messages across read boundaries
+func TestTOP708DeviceMonitorUBX(t *testing.T) {
+	// UBX-NAV-PVT frame (class 0x01, id 0x07, 92 byte payload)
+	frame := []byte{0xB5, 0x62, 0x01, 0x07, 92, 0x00}
+	for i := 0; i < 92; i++ {
+		frame = append(frame, byte(i*7))
+	}
+	ck := NewUBXParser().calculateChecksum(frame[2:])
+	frame = append(frame, byte(ck), byte(ck>>8))
+
+	corrupted := append([]byte(nil), frame...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+
+	// Header of a frame that never completes within the buffer limit
+	stalled := []byte{0xB5, 0x62, 0x01, 0x07, 0x60, 0xEA}
+	filler := make([]byte, 1000)
+
+	concat := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, p := range parts {
+			data = append(data, p...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   int
+	}{
+		{"split frame", [][]byte{frame[:40], frame[40:]}, 1},
+		{"corrupted checksum", [][]byte{corrupted[:40], corrupted[40:]}, 0},
+		{"corrupted then valid", [][]byte{concat(corrupted, frame[:3]), frame[3:]}, 1},
+		{"garbage before sync", [][]byte{concat([]byte{0x00, 0xB5, 0x13, 0xB5}), frame}, 1},
+		{"split sync", [][]byte{concat([]byte("$GP"), frame[:1]), frame[1:]}, 1},
+		{"stalled partial frame", [][]byte{concat(stalled, filler), filler, filler, frame}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialPort := new(MockSerialPort)
+			serialPort.connected = true
+			serialPort.data = []byte{0}
+			for _, chunk := range tt.chunks {
+				chunk := chunk
+				serialPort.On("Read", mock.Anything).Run(func(args mock.Arguments) {
+					copy(args.Get(0).([]byte), chunk)
+				}).Return(len(chunk), nil).Once()
+			}
+			serialPort.On("Read", mock.Anything).Return(0, nil)
+
+			device := NewTOP708Device(serialPort)
+			device.connected = true
+			handler := &ubxRecorder{}
+			config := DefaultMonitorConfig(ProtocolUBX, handler)
+			config.PollInterval = time.Millisecond
+
+			assert.NoError(t, device.MonitorUBX(config))
+			time.Sleep(50 * time.Millisecond)
+			device.StopMonitoring()
+
+			messages := handler.received()
+			if assert.Len(t, messages, tt.want) {
+				for _, m := range messages {
+					assert.Equal(t, byte(0x01), m.Class)
+					assert.Equal(t, byte(0x07), m.ID)
+					assert.Equal(t, 92, m.Length)
+					assert.Equal(t, frame, m.Raw)
+					assert.True(t, m.Valid)
+				}
+			}
+		})
+	}
+}
+
+// TestTOP708DeviceMonitorUBXNotConnected tests MonitorUBX when not connected
+func TestTOP708DeviceMonitorUBXNotConnected(t *testing.T) {
+	device := NewTOP708Device(new(MockSerialPort))
+	err := device.MonitorUBX(DefaultMonitorConfig(ProtocolUBX, &ubxRecorder{}))
+	assert.Error(t, err)
+}
+
+// TestTOP708DeviceMonitorLifecycle tests starting and stopping the monitors
+// repeatedly leaves no goroutine running and that stopping is idempotent
+func TestTOP708DeviceMonitorLifecycle(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	serialPort.On("Read", mock.Anything).Return(0, nil)
+	serialPort.On("Close").Return(nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	handler := &ubxRecorder{}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		for _, protocol := range []string{ProtocolNMEA, ProtocolRTCM, ProtocolUBX} {
+			config := DefaultMonitorConfig(protocol, handler)
+			config.PollInterval = time.Millisecond
+			switch protocol {
+			case ProtocolNMEA:
+				assert.NoError(t, device.MonitorNMEA(config))
+			case ProtocolRTCM:
+				assert.NoError(t, device.MonitorRTCM(config))
+			case ProtocolUBX:
+				assert.NoError(t, device.MonitorUBX(config))
+			}
+		}
+		device.StopMonitoring()
+		start := time.Now()
+		device.StopMonitoring()
+		assert.Less(t, time.Since(start), 100*time.Millisecond, "stop without monitors blocked")
+	}
+
+	// Disconnect stops the monitors as well
+	assert.NoError(t, device.MonitorNMEA(DefaultMonitorConfig(ProtocolNMEA, handler)))
+	assert.NoError(t, device.Disconnect())
+	device.StopMonitoring()
+
+	// Goroutines that called Done may still be exiting
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before, "monitor goroutines leaked")
+}
+
+// TestTOP708DeviceMonitorConcurrent starts and stops the monitors from
+// several goroutines while the device is disconnected and reconnected, to be
+// run with -race
+func TestTOP708DeviceMonitorConcurrent(t *testing.T) {
+	serialPort := new(MockSerialPort)
+	serialPort.connected = true
+	serialPort.data = []byte{0}
+	serialPort.On("Read", mock.Anything).Return(0, nil)
+	serialPort.On("Open", "/dev/ttyUSB0", 38400).Return(nil)
+	serialPort.On("Close").Return(nil)
+
+	device := NewTOP708Device(serialPort)
+	device.connected = true
+	handler := &ubxRecorder{}
+	before := runtime.NumGoroutine()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					config := DefaultMonitorConfig(ProtocolNMEA, handler)
+					config.PollInterval = time.Millisecond
+					// Monitors fail to start while the device is disconnected
+					switch (g + i) % 3 {
+					case 0:
+						device.MonitorNMEA(config)
+					case 1:
+						device.MonitorRTCM(config)
+					case 2:
+						device.MonitorUBX(config)
+					}
+					if i%2 == 1 {
+						device.StopMonitoring()
+					}
+				}
+			}(g)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				device.Disconnect()
+				device.Connect("/dev/ttyUSB0", 38400)
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		wg.Wait()
+		device.StopMonitoring()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(20 * time.Second):
+		t.Fatal("start/stop of monitors deadlocked")
+	}
+	assert.True(t, device.IsConnected())
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before, "monitor goroutines leaked")
+}
@@ -0,0 +1,162 @@
+package caster
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authenticator validates the credentials of NTRIP clients (subscribers) and
+// servers (publishers) before the SourceService is asked for the mountpoint.
+// A false result is answered with 401, an error with 500.
+type Authenticator interface {
+	AuthenticateClient(mount, user, pass string) (bool, error)
+	AuthenticateServer(mount, user, pass string) (bool, error)
+}
+
+// AllowAll is an Authenticator accepting every connection, the default of
+// NewCaster
+type AllowAll struct{}
+
+// AuthenticateClient accepts every client
+func (AllowAll) AuthenticateClient(mount, user, pass string) (bool, error) {
+	return true, nil
+}
+
+// AuthenticateServer accepts every server
+func (AllowAll) AuthenticateServer(mount, user, pass string) (bool, error) {
+	return true, nil
+}
+
+// HtpasswdAuthenticator authenticates clients and servers against the users
+// of an Apache htpasswd file, on all mountpoints. bcrypt ($2a$, $2b$, $2y$)
+// and Apache MD5 ($apr1$) entries are supported.
+type HtpasswdAuthenticator struct {
+	users map[string]string // Password hash per user
+}
+
+// NewHtpasswdAuthenticator reads the htpasswd file at path
+func NewHtpasswdAuthenticator(path string) (*HtpasswdAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHtpasswd(f)
+}
+
+// ParseHtpasswd reads htpasswd entries (user:hash per line) from r. Empty
+// lines and lines starting with # are ignored, an entry with an unsupported
+// hash is an error.
+func ParseHtpasswd(r io.Reader) (*HtpasswdAuthenticator, error) {
+	a := &HtpasswdAuthenticator{users: make(map[string]string)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("htpasswd line %d: missing user or password", n)
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "$apr1$") {
+			return nil, fmt.Errorf("htpasswd line %d: unsupported hash for user %s", n, user)
+		}
+		a.users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AuthenticateClient checks the password of a client
+func (a *HtpasswdAuthenticator) AuthenticateClient(mount, user, pass string) (bool, error) {
+	return a.check(user, pass), nil
+}
+
+// AuthenticateServer checks the password of a server
+func (a *HtpasswdAuthenticator) AuthenticateServer(mount, user, pass string) (bool, error) {
+	return a.check(user, pass), nil
+}
+
+// check returns whether pass matches the hash of user
+func (a *HtpasswdAuthenticator) check(user, pass string) bool {
+	hash, ok := a.users[user]
+	if !ok {
+		return false
+	}
+	if strings.HasPrefix(hash, "$apr1$") {
+		salt, _, _ := strings.Cut(strings.TrimPrefix(hash, "$apr1$"), "$")
+		return subtle.ConstantTimeCompare([]byte(apr1(pass, salt)), []byte(hash)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+}
+
+// apr1 returns the Apache MD5 hash of password with salt ($apr1$salt$hash)
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alt := md5.Sum([]byte(password + salt + password))
+	h := md5.New()
+	h.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= 16 {
+		h.Write(alt[:min(i, 16)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+		if i&1 != 0 {
+			h.Write(pw)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(pw)
+		}
+		if i&1 != 0 {
+			h.Write(final)
+		} else {
+			h.Write(pw)
+		}
+		final = h.Sum(nil)
+	}
+
+	// Custom base64 of the bytes in the order of the crypt(3) MD5 scheme
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var sb strings.Builder
+	sb.WriteString(magic + salt + "$")
+	to64 := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			sb.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, g := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		to64(uint32(final[g[0]])<<16|uint32(final[g[1]])<<8|uint32(final[g[2]]), 4)
+	}
+	to64(uint32(final[11]), 2)
+	return sb.String()
+}
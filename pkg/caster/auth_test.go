@@ -0,0 +1,139 @@
+package caster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHtpasswdAuthenticator(t *testing.T) {
+	// Reference hash of openssl passwd -apr1 -salt r31..... secret
+	assert.Equal(t, "$apr1$r31.....$G/cElGhD0cboYkZN5h5Ne/", apr1("secret", "r31....."))
+
+	auth, err := NewHtpasswdAuthenticator("testdata/htpasswd")
+	require.NoError(t, err)
+
+	tests := []struct {
+		user, pass string
+		want       bool
+	}{
+		{"rover", "rover-secret", true}, // Apache MD5
+		{"base", "base-secret", true},   // bcrypt
+		{"rover", "base-secret", false},
+		{"base", "", false},
+		{"unknown", "rover-secret", false},
+	}
+	for _, tt := range tests {
+		ok, err := auth.AuthenticateClient("TEST", tt.user, tt.pass)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, ok, "client %s:%s", tt.user, tt.pass)
+		ok, err = auth.AuthenticateServer("TEST", tt.user, tt.pass)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, ok, "server %s:%s", tt.user, tt.pass)
+	}
+
+	_, err = ParseHtpasswd(strings.NewReader("user:{SHA}qUqP5cyxm6YcTAhz05Hph5gvu9M=\n"))
+	assert.Error(t, err)
+	_, err = ParseHtpasswd(strings.NewReader("user\n"))
+	assert.Error(t, err)
+	_, err = NewHtpasswdAuthenticator("testdata/missing")
+	assert.Error(t, err)
+}
+
+// authFunc is an Authenticator for tests, checking clients and servers alike
+type authFunc func(mount, user, pass string) (bool, error)
+
+func (f authFunc) AuthenticateClient(mount, user, pass string) (bool, error) {
+	return f(mount, user, pass)
+}
+
+func (f authFunc) AuthenticateServer(mount, user, pass string) (bool, error) {
+	return f(mount, user, pass)
+}
+
+// casterRequest sends an NTRIP request to the caster and returns the status
+func casterRequest(t *testing.T, url, method, mount, user, pass string, v2 bool) int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var body *strings.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("data")
+	} else {
+		body = strings.NewReader("")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url+"/"+mount, body)
+	require.NoError(t, err)
+	if v2 {
+		req.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCasterAuthenticator(t *testing.T) {
+	auth, err := NewHtpasswdAuthenticator("testdata/htpasswd")
+	require.NoError(t, err)
+
+	svc := NewInMemorySourceService()
+	svc.Sourcetable = Sourcetable{Mounts: []StreamEntry{{Name: "TEST", Identifier: "TEST", Format: "RTCM 3.3"}}}
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	ts := httptest.NewServer(NewCaster("N/A", svc, logger, auth).Handler)
+	defer ts.Close()
+
+	// Rejected server, then accepted server creating the mountpoint
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodPost, "TEST", "base", "wrong", true))
+	assert.Equal(t, http.StatusOK, casterRequest(t, ts.URL, http.MethodPost, "TEST", "base", "base-secret", true))
+
+	// Accepted and rejected clients
+	assert.Equal(t, http.StatusOK, casterRequest(t, ts.URL, http.MethodGet, "TEST", "rover", "rover-secret", true))
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodGet, "TEST", "rover", "wrong", true))
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodGet, "TEST", "", "", true))
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodGet, "TEST", "", "", false))
+
+	// Unknown mountpoint: credentials are checked before the mountpoint
+	assert.Equal(t, http.StatusNotFound, casterRequest(t, ts.URL, http.MethodGet, "NONE", "rover", "rover-secret", true))
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodGet, "NONE", "rover", "wrong", true))
+
+	// The sourcetable needs no credentials
+	assert.Equal(t, http.StatusOK, casterRequest(t, ts.URL, http.MethodGet, "", "", "", true))
+}
+
+func TestCasterAuthenticatorMount(t *testing.T) {
+	var mounts []string
+	auth := authFunc(func(mount, user, pass string) (bool, error) {
+		mounts = append(mounts, mount)
+		if mount == "FAIL" {
+			return false, errors.New("backend unavailable")
+		}
+		return mount == "OPEN", nil
+	})
+	svc := NewInMemorySourceService()
+	ts := httptest.NewServer(NewCaster("N/A", svc, logrus.New(), auth).Handler)
+	defer ts.Close()
+
+	assert.Equal(t, http.StatusOK, casterRequest(t, ts.URL, http.MethodPost, "OPEN", "", "", true))
+	assert.Equal(t, http.StatusUnauthorized, casterRequest(t, ts.URL, http.MethodPost, "CLOSED", "", "", true))
+	assert.Equal(t, http.StatusInternalServerError, casterRequest(t, ts.URL, http.MethodGet, "FAIL", "", "", true))
+	assert.Equal(t, []string{"OPEN", "CLOSED", "FAIL"}, mounts)
+
+	// Without an authenticator every connection is accepted
+	ts2 := httptest.NewServer(NewCaster("N/A", svc, logrus.New()).Handler)
+	defer ts2.Close()
+	assert.Equal(t, http.StatusOK, casterRequest(t, ts2.URL, http.MethodPost, "CLOSED", "", "", true))
+}
@@ -38,17 +38,32 @@ type SourceService interface {
 	Subscriber(ctx context.Context, mount, username, password string) (chan []byte, error)
 }
 
+// MountStatsProvider is implemented by a SourceService reporting the
+// subscriber counts per mountpoint
+type MountStatsProvider interface {
+	MountStats() map[string]MountStat
+}
+
 // Caster wraps http.Server, providing an NTRIP caster implementation
 type Caster struct {
 	http.Server
+	svc       SourceService
+	metrics   *metrics
+	validator *formatValidator
 }
 
-// NewCaster constructs a Caster, setting up the Handler and timeouts
-func NewCaster(addr string, svc SourceService, logger logrus.FieldLogger) *Caster {
+// NewCaster constructs a Caster, setting up the Handler and timeouts. Clients
+// and servers are checked by auth if given, otherwise all are accepted.
+func NewCaster(addr string, svc SourceService, logger logrus.FieldLogger, auth ...Authenticator) *Caster {
+	var a Authenticator = AllowAll{}
+	if len(auth) > 0 && auth[0] != nil {
+		a = auth[0]
+	}
+	m, v := newMetrics(), newFormatValidator()
 	return &Caster{
-		http.Server{
+		Server: http.Server{
 			Addr:        addr,
-			Handler:     getHandler(svc, logger),
+			Handler:     getHandler(svc, a, m, v, logger),
 			IdleTimeout: 10 * time.Second,
 			// Read timeout kills publishing connections because they don't necessarily read from
 			// the response body
@@ -57,11 +72,56 @@ func NewCaster(addr string, svc SourceService, logger logrus.FieldLogger) *Caste
 			// body
 			//WriteTimeout: 10 * time.Second,
 		},
+		svc:       svc,
+		metrics:   m,
+		validator: v,
+	}
+}
+
+// MountStats returns the subscriber counts per mountpoint, nil if the
+// SourceService does not implement MountStatsProvider
+func (c *Caster) MountStats() map[string]MountStat {
+	if p, ok := c.svc.(MountStatsProvider); ok {
+		return p.MountStats()
+	}
+	return nil
+}
+
+// MetricsHandler returns a handler serving the connected clients, active
+// publishers and bytes in and out per mountpoint and the requests per status
+// code in the Prometheus text format. Serve it on a separate address (or
+// path of another server), the caster itself routes every path to a
+// mountpoint.
+func (c *Caster) MetricsHandler() http.Handler {
+	return c.metrics
+}
+
+// EnableFormatValidation samples the first bytes (0: DefaultFormatSample) of
+// every publisher's stream and checks them against the format of the
+// mountpoint in the sourcetable (RTCM 3, RTCM 2, UBX or NMEA). Mismatches are
+// logged and reported by FormatChecks, the stream is not interrupted.
+func (c *Caster) EnableFormatValidation(sample int) {
+	if sample <= 0 {
+		sample = DefaultFormatSample
+	}
+	c.validator.mutex.Lock()
+	c.validator.sample = sample
+	c.validator.mutex.Unlock()
+}
+
+// FormatChecks returns the last format check per mountpoint
+func (c *Caster) FormatChecks() map[string]FormatCheck {
+	c.validator.mutex.Lock()
+	defer c.validator.mutex.Unlock()
+	checks := make(map[string]FormatCheck, len(c.validator.checks))
+	for mount, check := range c.validator.checks {
+		checks[mount] = check
 	}
+	return checks
 }
 
 // getHandler creates a new HTTP handler for the caster
-func getHandler(svc SourceService, logger logrus.FieldLogger) http.Handler {
+func getHandler(svc SourceService, auth Authenticator, m *metrics, v *formatValidator, logger logrus.FieldLogger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestVersion := 1
 		if strings.ToUpper(r.Header.Get(NTRIPVersionHeaderKey)) == strings.ToUpper(NTRIPVersionHeaderValueV2) {
@@ -83,7 +143,7 @@ func getHandler(svc SourceService, logger logrus.FieldLogger) http.Handler {
 			"user_agent":      r.UserAgent(),
 		})
 
-		h := &handler{svc, l}
-		h.handleRequest(w, r.WithContext(ctx))
+		h := &handler{svc, auth, m, v, l}
+		h.handleRequest(&statusRecorder{ResponseWriter: w, metrics: m}, r.WithContext(ctx))
 	})
 }
@@ -160,3 +160,75 @@ func TestCasterNotFound(t *testing.T) {
 	assert.Contains(t, string(body), "SOURCETABLE 200 OK")
 	assert.Contains(t, string(body), "ENDSOURCETABLE")
 }
+
+func TestCasterSourcetableUpdate(t *testing.T) {
+	// Create a new source service
+	svc := NewInMemorySourceService()
+	svc.Sourcetable = Sourcetable{
+		Casters: []CasterEntry{
+			{Host: "localhost", Port: 2101, Identifier: "Test Caster", Operator: "Test"},
+		},
+		Networks: []NetworkEntry{
+			{Identifier: "TEST", Operator: "Test", Authentication: "N"},
+		},
+	}
+
+	// Create a new caster
+	logger := logrus.New()
+	caster := NewCaster("N/A", svc, logger)
+
+	// Create a test server
+	ts := httptest.NewServer(caster.Handler)
+	defer ts.Close()
+
+	getSourcetable := func() string {
+		resp, err := http.Get(ts.URL)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+	assert.Contains(t, getSourcetable(), "CAS;localhost;2101;Test Caster;Test;0;")
+
+	// A sourcetable fetched before the update is not modified
+	before := svc.GetSourcetable()
+
+	// Update the caster and network entries
+	assert.NoError(t, svc.UpdateCaster("Test Caster", func(cas *CasterEntry) {
+		cas.Identifier = "Renamed Caster"
+		cas.Latitude = 52.5
+		cas.Longitude = 13.4
+	}))
+	assert.NoError(t, svc.UpdateNetwork("TEST", func(net *NetworkEntry) {
+		net.Operator = "New Operator"
+		net.Authentication = "B"
+		net.Fee = true
+	}))
+	assert.Equal(t, ErrorNotFound, svc.UpdateCaster("Unknown", func(cas *CasterEntry) {}))
+	assert.Equal(t, "Test Caster", before.Casters[0].Identifier)
+
+	body := getSourcetable()
+	assert.Contains(t, body, "CAS;localhost;2101;Renamed Caster;Test;0;;52.5000;13.4000")
+	assert.Contains(t, body, "NET;TEST;New Operator;B;Y;")
+	assert.NotContains(t, body, "Test Caster")
+
+	// Replace the network entries while clients fetch the sourcetable
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			svc.SetNetworks([]NetworkEntry{{Identifier: "NET1"}, {Identifier: "NET2"}})
+			svc.SetCasters(svc.GetSourcetable().Casters)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		body = getSourcetable()
+		assert.Contains(t, body, "ENDSOURCETABLE")
+	}
+	<-done
+	body = getSourcetable()
+	assert.Contains(t, body, "NET;NET1;")
+	assert.Contains(t, body, "NET;NET2;")
+	assert.NotContains(t, body, "NET;TEST;")
+}
@@ -0,0 +1,81 @@
+package caster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSourcetable reads a sourcetable from a YAML (.yaml, .yml) or JSON
+// (.json) file with the lists casters, networks and mounts. The keys of the
+// entries are the field names in lower camel case (name, format,
+// formatDetails, navSystem, ...). Unknown keys and entries missing required
+// fields are errors.
+func LoadSourcetable(path string) (Sourcetable, error) {
+	var st Sourcetable
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return st, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&st)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&st)
+	default:
+		return st, fmt.Errorf("sourcetable %s: unsupported file type, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return st, fmt.Errorf("sourcetable %s: %w", path, err)
+	}
+	if err := st.Validate(); err != nil {
+		return st, fmt.Errorf("sourcetable %s: %w", path, err)
+	}
+	return st, nil
+}
+
+// Validate checks the required fields of the entries: the host and port of
+// casters, the identifier of networks and the unique name and format of
+// mounts
+func (st Sourcetable) Validate() error {
+	for i, cas := range st.Casters {
+		if cas.Host == "" {
+			return fmt.Errorf("casters[%d]: missing host", i)
+		}
+		if cas.Port <= 0 || cas.Port > 65535 {
+			return fmt.Errorf("casters[%d] (%s): invalid port %d", i, cas.Host, cas.Port)
+		}
+	}
+	for i, net := range st.Networks {
+		if net.Identifier == "" {
+			return fmt.Errorf("networks[%d]: missing identifier", i)
+		}
+	}
+	names := make(map[string]bool, len(st.Mounts))
+	for i, m := range st.Mounts {
+		if m.Name == "" {
+			return fmt.Errorf("mounts[%d]: missing name", i)
+		}
+		if strings.ContainsAny(m.Name, "/; \t\r\n") {
+			return fmt.Errorf("mounts[%d] (%s): invalid character in name", i, m.Name)
+		}
+		if names[m.Name] {
+			return fmt.Errorf("mounts[%d] (%s): duplicate name", i, m.Name)
+		}
+		names[m.Name] = true
+		if m.Format == "" {
+			return fmt.Errorf("mounts[%d] (%s): missing format", i, m.Name)
+		}
+	}
+	return nil
+}
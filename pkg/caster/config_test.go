@@ -0,0 +1,89 @@
+package caster
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSourcetable(t *testing.T) {
+	want := Sourcetable{
+		Casters: []CasterEntry{{
+			Host: "caster.example.com", Port: 2101, Identifier: "Example", Operator: "Example Ops",
+			Country: "NLD", Latitude: 52.37, Longitude: 4.89, FallbackHostAddress: "0.0.0.0",
+		}},
+		Networks: []NetworkEntry{{
+			Identifier: "EXNET", Operator: "Example Ops", Authentication: "B",
+			NetworkInfoURL: "http://example.com/network", StreamInfoURL: "http://example.com/streams",
+			RegistrationAddress: "ops@example.com",
+		}},
+		Mounts: []StreamEntry{{
+			Name: "AMS", Identifier: "Amsterdam", Format: "RTCM 3.3", FormatDetails: "1005(10),1077(1),1087(1)",
+			Carrier: "2", NavSystem: "GPS+GLO", Network: "EXNET", CountryCode: "NLD",
+			Latitude: 52.37, Longitude: 4.89, NMEA: true, Generator: "gnssgo", Compression: "none",
+			Authentication: "B", Bitrate: 9600,
+		}, {
+			Name: "RAW", Format: "UBX",
+		}},
+	}
+	for _, path := range []string{"testdata/sourcetable.yaml", "testdata/sourcetable.json"} {
+		st, err := LoadSourcetable(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want.Casters, st.Casters, path)
+		assert.Equal(t, want.Networks, st.Networks, path)
+		assert.Equal(t, want.Mounts, st.Mounts, path)
+	}
+}
+
+func TestLoadSourcetableErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file, data, err string
+	}{
+		{"format.yaml", "mounts:\n  - name: AMS\n", "mounts[0] (AMS): missing format"},
+		{"name.json", `{"mounts": [{"format": "RTCM 3.3"}]}`, "mounts[0]: missing name"},
+		{"duplicate.yml", "mounts:\n  - {name: AMS, format: RTCM 3}\n  - {name: AMS, format: RTCM 3}\n", "mounts[1] (AMS): duplicate name"},
+		{"port.yaml", "casters:\n  - {host: example.com}\n", "casters[0] (example.com): invalid port 0"},
+		{"network.json", `{"networks": [{"operator": "Ops"}]}`, "networks[0]: missing identifier"},
+		{"unknown.yaml", "mounts:\n  - {name: AMS, format: RTCM 3, formt: x}\n", "field formt not found"},
+		{"unknown.json", `{"mounts": [{"name": "AMS", "format": "RTCM 3", "formt": "x"}]}`, `unknown field "formt"`},
+		{"type.yaml", "mounts:\n  - {name: AMS, format: RTCM 3, bitrate: fast}\n", "cannot unmarshal"},
+		{"sourcetable.txt", "", "unsupported file type"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+		_, err := LoadSourcetable(path)
+		assert.ErrorContains(t, err, tt.err, tt.file)
+		assert.ErrorContains(t, err, path, tt.file)
+	}
+	_, err := LoadSourcetable(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReloadSourcetable(t *testing.T) {
+	svc := NewInMemorySourceService()
+	ts := httptest.NewServer(NewCaster("N/A", svc, logrus.New()).Handler)
+	defer ts.Close()
+
+	st, err := LoadSourcetable("testdata/sourcetable.yaml")
+	require.NoError(t, err)
+	svc.ReloadSourcetable(st)
+	st.Mounts[0].Name = "CHANGED"
+	assert.Equal(t, "AMS", svc.GetSourcetable().Mounts[0].Name)
+
+	resp, err := http.Get(ts.URL + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "STR;AMS;Amsterdam;RTCM 3.3;")
+	assert.Contains(t, string(body), "STR;RAW;;UBX;")
+}
@@ -40,10 +40,52 @@ The SourceService interface defines the methods that a source service must imple
 A source service is responsible for managing the sourcetable and handling publisher and
 subscriber connections.
 
+## Authenticator
+
+An Authenticator passed to NewCaster checks the credentials of clients and servers
+before the SourceService is called; rejected connections get 401. Without one all
+connections are accepted. HtpasswdAuthenticator reads the users of an Apache htpasswd
+file with bcrypt or MD5 ($apr1$) entries:
+
+    auth, err := caster.NewHtpasswdAuthenticator("/etc/ntrip/htpasswd")
+    if err != nil {
+        log.Fatal(err)
+    }
+    caster := caster.NewCaster(":2101", svc, logger, auth)
+
+## Client limits
+
+InMemorySourceService.MaxClientsPerMount limits the subscribers of a mountpoint; further
+clients get 503. Each subscriber has a queue of SubscriberQueue messages, a subscriber
+whose queue is full is disconnected so a slow client cannot stall the others.
+Caster.MountStats reports the subscribers, rejected and evicted clients per mountpoint.
+
+## Metrics
+
+Caster.MetricsHandler serves the connected clients, active publishers and bytes in and
+out per mountpoint and the requests per status code in the Prometheus text format. The
+caster routes every path to a mountpoint, so serve the metrics on another address:
+
+    go http.ListenAndServe(":9100", caster.MetricsHandler())
+
+## Format validation
+
+Caster.EnableFormatValidation samples the start of every published stream and checks it
+against the format of its sourcetable entry (RTCM 3, RTCM 2, UBX or NMEA). A mountpoint
+declared as RTCM 3.3 but sending UBX is logged and marked in Caster.FormatChecks, which
+also lists the RTCM 3 message types found.
+
 ## Sourcetable
 
 The Sourcetable type represents the NTRIP sourcetable, which contains information about
-the available mountpoints, networks, and the caster itself.
+the available mountpoints, networks, and the caster itself. LoadSourcetable reads it from
+a YAML or JSON file, InMemorySourceService.ReloadSourcetable replaces it while running:
+
+    st, err := caster.LoadSourcetable("sourcetable.yaml")
+    if err != nil {
+        log.Fatal(err)
+    }
+    svc.ReloadSourcetable(st)
 
 # SOLID Principles
 
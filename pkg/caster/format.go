@@ -0,0 +1,237 @@
+package caster
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultFormatSample is the default number of bytes of a publisher's stream
+// sampled by the format validation
+const DefaultFormatSample = 4096
+
+// Stream formats recognized by DetectFormat
+const (
+	FormatRTCM2 = "RTCM 2"
+	FormatRTCM3 = "RTCM 3"
+	FormatUBX   = "UBX"
+	FormatNMEA  = "NMEA"
+)
+
+// FormatCheck is the result of the format validation of a mountpoint
+type FormatCheck struct {
+	Declared string // Format of the sourcetable entry
+	Detected string // Format found in the sample, "" if none
+	Messages []int  // RTCM 3 message types found in the sample
+	Mismatch bool   // Sample does not match the declared format
+}
+
+// DetectFormat returns the format with the most valid frames (CRC or
+// checksum checked) in data and the RTCM 3 message types found, "" if no
+// frame is found. RTCM 3, UBX and NMEA are recognized.
+func DetectFormat(data []byte) (string, []int) {
+	counts := make(map[string]int)
+	types := make(map[int]bool)
+	for i := 0; i < len(data); {
+		if n, typ := rtcm3Frame(data[i:]); n > 0 {
+			counts[FormatRTCM3]++
+			types[typ] = true
+			i += n
+		} else if n := ubxFrame(data[i:]); n > 0 {
+			counts[FormatUBX]++
+			i += n
+		} else if n := nmeaSentence(data[i:]); n > 0 {
+			counts[FormatNMEA]++
+			i += n
+		} else {
+			i++
+		}
+	}
+
+	format := ""
+	for _, f := range []string{FormatRTCM3, FormatUBX, FormatNMEA} {
+		if counts[f] > 0 && counts[f] > counts[format] {
+			format = f
+		}
+	}
+	var messages []int
+	for typ := range types {
+		messages = append(messages, typ)
+	}
+	sort.Ints(messages)
+	return format, messages
+}
+
+// rtcm3Frame returns the length and message type of the RTCM 3 frame at the
+// start of data, 0 if there is no valid frame
+func rtcm3Frame(data []byte) (int, int) {
+	if len(data) < 6 || data[0] != 0xD3 {
+		return 0, 0
+	}
+	length := int(data[1]&0x03)<<8 | int(data[2])
+	n := length + 6
+	if n > len(data) {
+		return 0, 0
+	}
+	crc := uint32(data[n-3])<<16 | uint32(data[n-2])<<8 | uint32(data[n-1])
+	if gnssgo.Rtk_CRC24q(data, n-3) != crc {
+		return 0, 0
+	}
+	typ := 0
+	if length >= 2 {
+		typ = int(data[3])<<4 | int(data[4])>>4
+	}
+	return n, typ
+}
+
+// ubxFrame returns the length of the UBX frame at the start of data, 0 if
+// there is no valid frame
+func ubxFrame(data []byte) int {
+	if len(data) < 8 || data[0] != 0xB5 || data[1] != 0x62 {
+		return 0
+	}
+	n := (int(data[4]) | int(data[5])<<8) + 8
+	if n > len(data) {
+		return 0
+	}
+	var a, b byte
+	for _, c := range data[2 : n-2] {
+		a += c
+		b += a
+	}
+	if a != data[n-2] || b != data[n-1] {
+		return 0
+	}
+	return n
+}
+
+// nmeaSentence returns the length of the NMEA sentence at the start of data,
+// 0 if there is no valid sentence
+func nmeaSentence(data []byte) int {
+	if len(data) == 0 || data[0] != '$' {
+		return 0
+	}
+	end := bytes.IndexByte(data[:min(len(data), 100)], '\n')
+	if end < 0 {
+		return 0
+	}
+	star := bytes.LastIndexByte(data[:end], '*')
+	if star < 0 || star+3 > end {
+		return 0
+	}
+	var sum byte
+	for _, c := range data[1:star] {
+		sum ^= c
+	}
+	if fmt.Sprintf("%02X", sum) != strings.ToUpper(string(data[star+1:star+3])) {
+		return 0
+	}
+	return end + 1
+}
+
+// formatFamily returns the format of a sourcetable format field, "" if it is
+// not recognized
+func formatFamily(format string) string {
+	f := strings.ToUpper(strings.ReplaceAll(format, " ", ""))
+	switch {
+	case strings.HasPrefix(f, "RTCM3"):
+		return FormatRTCM3
+	case strings.HasPrefix(f, "RTCM2"):
+		return FormatRTCM2
+	case strings.HasPrefix(f, "UBX"), strings.HasPrefix(f, "U-BLOX"):
+		return FormatUBX
+	case strings.HasPrefix(f, "NMEA"):
+		return FormatNMEA
+	}
+	return ""
+}
+
+// formatValidator samples the streams of publishers and checks them against
+// the formats of the sourcetable
+type formatValidator struct {
+	mutex  sync.Mutex
+	sample int // Bytes sampled per publisher (0: disabled)
+	checks map[string]FormatCheck
+}
+
+func newFormatValidator() *formatValidator {
+	return &formatValidator{checks: make(map[string]FormatCheck)}
+}
+
+// writer returns a writer sampling the stream published to mount, nil if
+// validation is disabled or the declared format is not recognized
+func (v *formatValidator) writer(w io.Writer, mount string, st Sourcetable, logger logrus.FieldLogger) *sampleWriter {
+	v.mutex.Lock()
+	sample := v.sample
+	v.mutex.Unlock()
+	if sample <= 0 {
+		return nil
+	}
+	for _, m := range st.Mounts {
+		if m.Name == mount && formatFamily(m.Format) != "" {
+			return &sampleWriter{w: w, v: v, logger: logger, mount: mount, declared: m.Format, size: sample}
+		}
+	}
+	return nil
+}
+
+// check checks the sample of mount against the declared format
+func (v *formatValidator) check(mount, declared string, sample []byte) FormatCheck {
+	detected, messages := DetectFormat(sample)
+	family := formatFamily(declared)
+	c := FormatCheck{
+		Declared: declared,
+		Detected: detected,
+		Messages: messages,
+		// RTCM 2 is not detected, only other formats are a mismatch
+		Mismatch: detected != family && (detected != "" || family != FormatRTCM2),
+	}
+	v.mutex.Lock()
+	v.checks[mount] = c
+	v.mutex.Unlock()
+	return c
+}
+
+// sampleWriter keeps the first bytes written to a publisher and checks them
+// once the sample is complete
+type sampleWriter struct {
+	w        io.Writer
+	v        *formatValidator
+	logger   logrus.FieldLogger
+	mount    string
+	declared string
+	size     int
+	buff     []byte
+	done     bool
+}
+
+// Write samples data and writes it to the publisher
+func (s *sampleWriter) Write(data []byte) (int, error) {
+	if !s.done {
+		s.buff = append(s.buff, data[:min(len(data), s.size-len(s.buff))]...)
+		if len(s.buff) >= s.size {
+			s.finish()
+		}
+	}
+	return s.w.Write(data)
+}
+
+// finish checks the sample, also a partial one when the publisher
+// disconnects
+func (s *sampleWriter) finish() {
+	if s.done || len(s.buff) == 0 {
+		return
+	}
+	s.done = true
+	c := s.v.check(s.mount, s.declared, s.buff)
+	s.buff = nil
+	if c.Mismatch {
+		s.logger.Warnf("mountpoint %s declared as %s, data detected as %q", s.mount, c.Declared, c.Detected)
+	}
+}
@@ -0,0 +1,106 @@
+package caster
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rtcm3Message returns an RTCM 3 frame of message type typ with a payload of
+// n bytes
+func rtcm3Message(typ, n int) []byte {
+	frame := make([]byte, n+6)
+	frame[0], frame[1], frame[2] = 0xD3, byte(n>>8), byte(n)
+	frame[3], frame[4] = byte(typ>>4), byte(typ<<4)
+	crc := gnssgo.Rtk_CRC24q(frame, n+3)
+	frame[n+3], frame[n+4], frame[n+5] = byte(crc>>16), byte(crc>>8), byte(crc)
+	return frame
+}
+
+// ubxMessage returns a UBX frame with a payload of n bytes
+func ubxMessage(class, id byte, n int) []byte {
+	frame := append([]byte{0xB5, 0x62, class, id, byte(n), byte(n >> 8)}, make([]byte, n+2)...)
+	var a, b byte
+	for _, c := range frame[2 : n+6] {
+		a += c
+		b += a
+	}
+	frame[n+6], frame[n+7] = a, b
+	return frame
+}
+
+func TestDetectFormat(t *testing.T) {
+	rtcm := append(rtcm3Message(1005, 19), rtcm3Message(1077, 40)...)
+	rtcm = append(rtcm, rtcm3Message(1005, 19)...)
+	ubx := append(ubxMessage(0x01, 0x07, 92), ubxMessage(0x02, 0x15, 16)...)
+	nmea := []byte("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		messages []int
+	}{
+		{"rtcm3", append([]byte{0x00, 0xD3}, rtcm...), FormatRTCM3, []int{1005, 1077}},
+		{"ubx", ubx, FormatUBX, nil},
+		{"nmea", nmea, FormatNMEA, nil},
+		{"mostly ubx", append(append([]byte{}, ubx...), rtcm3Message(1005, 19)...), FormatUBX, []int{1005}},
+		{"garbage", []byte("\xD3\x00\x13 not a valid frame $GPGGA*00\r\n"), "", nil},
+	}
+	for _, tt := range tests {
+		format, messages := DetectFormat(tt.data)
+		assert.Equal(t, tt.format, format, tt.name)
+		assert.Equal(t, tt.messages, messages, tt.name)
+	}
+	// A corrupted CRC is not a frame
+	bad := rtcm3Message(1005, 19)
+	bad[10] ^= 1
+	format, _ := DetectFormat(bad)
+	assert.Equal(t, "", format)
+}
+
+func TestCasterFormatValidation(t *testing.T) {
+	svc := NewInMemorySourceService()
+	svc.Sourcetable = Sourcetable{Mounts: []StreamEntry{
+		{Name: "GOOD", Format: "RTCM 3.3"},
+		{Name: "BAD", Format: "RTCM 3.3"},
+		{Name: "RAW", Format: "RAW"},
+	}}
+	c := NewCaster("N/A", svc, logrus.New())
+	c.EnableFormatValidation(128)
+	ts := httptest.NewServer(c.Handler)
+	defer ts.Close()
+
+	publish := func(mount string, data []byte) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/"+mount, bytes.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	// The good stream is shorter than the sample, checked on disconnect
+	publish("GOOD", append(rtcm3Message(1005, 19), rtcm3Message(1077, 40)...))
+	publish("BAD", bytes.Repeat(ubxMessage(0x01, 0x07, 92), 3))
+	publish("RAW", []byte("not checked"))
+
+	var checks map[string]FormatCheck
+	require.Eventually(t, func() bool {
+		checks = c.FormatChecks()
+		return len(checks) == 2
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, FormatCheck{Declared: "RTCM 3.3", Detected: FormatRTCM3, Messages: []int{1005, 1077}}, checks["GOOD"])
+	assert.Equal(t, FormatCheck{Declared: "RTCM 3.3", Detected: FormatUBX, Mismatch: true}, checks["BAD"])
+	assert.NotContains(t, checks, "RAW")
+}
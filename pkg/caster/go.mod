@@ -7,6 +7,10 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/crypto v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
+require golang.org/x/sys v0.28.0 // indirect
+
 replace github.com/bramburn/gnssgo/pkg/gnssgo => ../gnssgo
@@ -11,8 +11,11 @@ import (
 
 // handler is used by Caster to handle HTTP requests
 type handler struct {
-	svc    SourceService
-	logger logrus.FieldLogger
+	svc       SourceService
+	auth      Authenticator
+	metrics   *metrics
+	validator *formatValidator
+	logger    logrus.FieldLogger
 }
 
 // handleRequest handles both NTRIP v1 and v2 requests
@@ -61,7 +64,11 @@ func (h *handler) handleRequestV1(w http.ResponseWriter, r *http.Request) {
 	username, password, _ := r.BasicAuth()
 
 	// Get subscriber
-	sub, err := h.svc.Subscriber(r.Context(), mount, username, password)
+	err := h.authenticate(h.auth.AuthenticateClient, mount, username, password)
+	var sub chan []byte
+	if err == nil {
+		sub, err = h.svc.Subscriber(r.Context(), mount, username, password)
+	}
 	if err != nil {
 		h.logger.Infof("connection refused with reason: %s", err)
 		// NTRIP v1 says to return 401 for unauthorized, but sourcetable for any other error - this goes against that
@@ -69,6 +76,8 @@ func (h *handler) handleRequestV1(w http.ResponseWriter, r *http.Request) {
 			writeStatusV1(w, r, http.StatusUnauthorized)
 		} else if err == ErrorNotFound {
 			writeStatusV1(w, r, http.StatusNotFound)
+		} else if err == ErrorTooManyClients {
+			writeStatusV1(w, r, http.StatusServiceUnavailable)
 		} else {
 			writeStatusV1(w, r, http.StatusInternalServerError)
 		}
@@ -78,6 +87,9 @@ func (h *handler) handleRequestV1(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.metrics.addClients(mount, 1)
+	defer h.metrics.addClients(mount, -1)
+
 	// Write the NTRIP v1 response header
 	_, err = w.Write([]byte("ICY 200 OK\r\n"))
 	if err != nil {
@@ -108,6 +120,7 @@ func (h *handler) handleRequestV1(w http.ResponseWriter, r *http.Request) {
 			h.logger.WithError(err).Error("failed to flush to client")
 			return
 		}
+		h.metrics.addBytesOut(mount, len(data))
 	}
 }
 
@@ -161,7 +174,11 @@ func (h *handler) handleSourcetable(w http.ResponseWriter, r *http.Request) {
 // handlePublisher handles publisher requests
 func (h *handler) handlePublisher(w http.ResponseWriter, r *http.Request, mount, username, password string) {
 	// Get publisher
-	pub, err := h.svc.Publisher(r.Context(), mount, username, password)
+	err := h.authenticate(h.auth.AuthenticateServer, mount, username, password)
+	var pub io.WriteCloser
+	if err == nil {
+		pub, err = h.svc.Publisher(r.Context(), mount, username, password)
+	}
 	if err != nil {
 		h.logger.Infof("publisher connection refused with reason: %s", err)
 		if err == ErrorNotAuthorized {
@@ -174,13 +191,24 @@ func (h *handler) handlePublisher(w http.ResponseWriter, r *http.Request, mount,
 		return
 	}
 	defer pub.Close()
+	h.metrics.addPublishers(mount, 1)
+	defer h.metrics.addPublishers(mount, -1)
 
-	// Write success response
+	// Write success response. The HTTP/1 server would otherwise read the
+	// whole streamed body before sending the response.
+	if err := http.NewResponseController(w).EnableFullDuplex(); err != nil {
+		h.logger.WithError(err).Debug("full duplex not supported")
+	}
 	w.WriteHeader(http.StatusOK)
 	w.(http.Flusher).Flush()
 
 	// Copy data from the request body to the publisher
-	_, err = io.Copy(pub, r.Body)
+	var dst io.Writer = &countingWriter{pub, h.metrics, mount}
+	if sw := h.validator.writer(dst, mount, h.svc.GetSourcetable(), h.logger); sw != nil {
+		defer sw.finish()
+		dst = sw
+	}
+	_, err = io.Copy(dst, r.Body)
 	if err != nil {
 		h.logger.WithError(err).Error("failed to copy data from publisher")
 	}
@@ -189,19 +217,28 @@ func (h *handler) handlePublisher(w http.ResponseWriter, r *http.Request, mount,
 // handleSubscriber handles subscriber requests
 func (h *handler) handleSubscriber(w http.ResponseWriter, r *http.Request, mount, username, password string) {
 	// Get subscriber
-	sub, err := h.svc.Subscriber(r.Context(), mount, username, password)
+	err := h.authenticate(h.auth.AuthenticateClient, mount, username, password)
+	var sub chan []byte
+	if err == nil {
+		sub, err = h.svc.Subscriber(r.Context(), mount, username, password)
+	}
 	if err != nil {
 		h.logger.Infof("subscriber connection refused with reason: %s", err)
 		if err == ErrorNotAuthorized {
 			w.WriteHeader(http.StatusUnauthorized)
 		} else if err == ErrorNotFound {
 			w.WriteHeader(http.StatusNotFound)
+		} else if err == ErrorTooManyClients {
+			w.WriteHeader(http.StatusServiceUnavailable)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		return
 	}
 
+	h.metrics.addClients(mount, 1)
+	defer h.metrics.addClients(mount, -1)
+
 	// Set headers for chunked transfer encoding
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Transfer-Encoding", "chunked")
@@ -216,7 +253,22 @@ func (h *handler) handleSubscriber(w http.ResponseWriter, r *http.Request, mount
 			return
 		}
 		w.(http.Flusher).Flush()
+		h.metrics.addBytesOut(mount, len(data))
+	}
+}
+
+// authenticate checks the credentials with check, returning ErrorNotAuthorized
+// if they are rejected and ErrorInternalServerError if check fails
+func (h *handler) authenticate(check func(mount, user, pass string) (bool, error), mount, username, password string) error {
+	ok, err := check(mount, username, password)
+	if err != nil {
+		h.logger.WithError(err).Error("authentication failed")
+		return ErrorInternalServerError
+	}
+	if !ok {
+		return ErrorNotAuthorized
 	}
+	return nil
 }
 
 // writeStatusV1 writes an NTRIP v1 status response
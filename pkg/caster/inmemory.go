@@ -6,20 +6,63 @@ import (
 	"sync"
 )
 
-// InMemorySourceService is a simple in-memory implementation of SourceService
+// DefaultSubscriberQueue is the default number of messages queued per
+// subscriber of InMemorySourceService
+const DefaultSubscriberQueue = 10
+
+// ErrorTooManyClients is returned by Subscriber if a mountpoint has reached
+// MaxClientsPerMount subscribers
+const ErrorTooManyClients = Error("too many clients")
+
+// InMemorySourceService is a simple in-memory implementation of SourceService.
+// Sourcetable may be set before the caster is started, afterwards use the
+// Reload, Set and Update methods so clients always see a consistent sourcetable.
+// Publishers never block: a subscriber whose queue is full is evicted, its
+// channel is closed. MaxClientsPerMount and SubscriberQueue must be set
+// before the caster is started.
 type InMemorySourceService struct {
-	Sourcetable Sourcetable
-	mutex       sync.RWMutex
-	mounts      map[string]*mountPoint
+	Sourcetable        Sourcetable
+	MaxClientsPerMount int // Maximum subscribers per mountpoint (0: no limit)
+	SubscriberQueue    int // Messages queued per subscriber (0: DefaultSubscriberQueue)
+	mutex              sync.RWMutex
+	mounts             map[string]*mountPoint
+}
+
+// MountStat contains the subscriber counts of a mountpoint
+type MountStat struct {
+	Subscribers int // Current subscribers
+	Rejected    int // Subscribers rejected by MaxClientsPerMount
+	Evicted     int // Subscribers evicted because their queue was full
 }
 
 // mountPoint represents a mount point in the in-memory source service
 type mountPoint struct {
 	name        string
-	subscribers []chan []byte
+	subscribers []*subscriber
+	rejected    int
+	evicted     int
 	mutex       sync.RWMutex
 }
 
+// subscriber is the queue of a subscriber, closed once on eviction or when
+// its context is done
+type subscriber struct {
+	ch   chan []byte
+	once sync.Once
+}
+
+// remove removes sub from the subscribers and closes its queue. The caller
+// must hold the mount mutex.
+func (mp *mountPoint) remove(sub *subscriber) {
+	for i, s := range mp.subscribers {
+		if s == sub {
+			mp.subscribers = append(mp.subscribers[:i:i], mp.subscribers[i+1:]...)
+			break
+		}
+	}
+	sub.once.Do(func() { close(sub.ch) })
+}
+
 // NewInMemorySourceService creates a new in-memory source service
 func NewInMemorySourceService() *InMemorySourceService {
 	return &InMemorySourceService{
@@ -34,6 +77,67 @@ func (s *InMemorySourceService) GetSourcetable() Sourcetable {
 	return s.Sourcetable
 }
 
+// ReloadSourcetable replaces the sourcetable, e.g. after LoadSourcetable.
+// Connected publishers and subscribers are not affected.
+func (s *InMemorySourceService) ReloadSourcetable(st Sourcetable) {
+	st = Sourcetable{
+		Casters:  append([]CasterEntry(nil), st.Casters...),
+		Networks: append([]NetworkEntry(nil), st.Networks...),
+		Mounts:   append([]StreamEntry(nil), st.Mounts...),
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Sourcetable = st
+}
+
+// SetCasters replaces the CAS entries of the sourcetable
+func (s *InMemorySourceService) SetCasters(casters []CasterEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Sourcetable.Casters = append([]CasterEntry(nil), casters...)
+}
+
+// SetNetworks replaces the NET entries of the sourcetable
+func (s *InMemorySourceService) SetNetworks(networks []NetworkEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Sourcetable.Networks = append([]NetworkEntry(nil), networks...)
+}
+
+// UpdateCaster applies update to the CAS entry with the given identifier.
+// Entries are copied before being modified, so sourcetables already returned
+// by GetSourcetable are not affected.
+func (s *InMemorySourceService) UpdateCaster(identifier string, update func(*CasterEntry)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, cas := range s.Sourcetable.Casters {
+		if cas.Identifier == identifier {
+			casters := append([]CasterEntry(nil), s.Sourcetable.Casters...)
+			update(&casters[i])
+			s.Sourcetable.Casters = casters
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+// UpdateNetwork applies update to the NET entry with the given identifier.
+// Entries are copied before being modified, so sourcetables already returned
+// by GetSourcetable are not affected.
+func (s *InMemorySourceService) UpdateNetwork(identifier string, update func(*NetworkEntry)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, net := range s.Sourcetable.Networks {
+		if net.Identifier == identifier {
+			networks := append([]NetworkEntry(nil), s.Sourcetable.Networks...)
+			update(&networks[i])
+			s.Sourcetable.Networks = networks
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
 // Publisher creates a new publisher for the given mountpoint
 func (s *InMemorySourceService) Publisher(ctx context.Context, mount, username, password string) (io.WriteCloser, error) {
 	s.mutex.Lock()
@@ -41,7 +145,7 @@ func (s *InMemorySourceService) Publisher(ctx context.Context, mount, username,
 	if !ok {
 		mp = &mountPoint{
 			name:        mount,
-			subscribers: make([]chan []byte, 0),
+			subscribers: make([]*subscriber, 0),
 		}
 		s.mounts[mount] = mp
 	}
@@ -65,26 +169,47 @@ func (s *InMemorySourceService) Subscriber(ctx context.Context, mount, username,
 	}
 	s.mutex.RUnlock()
 
+	queue := s.SubscriberQueue
+	if queue <= 0 {
+		queue = DefaultSubscriberQueue
+	}
 	mp.mutex.Lock()
-	ch := make(chan []byte, 10)
-	mp.subscribers = append(mp.subscribers, ch)
+	if s.MaxClientsPerMount > 0 && len(mp.subscribers) >= s.MaxClientsPerMount {
+		mp.rejected++
+		mp.mutex.Unlock()
+		return nil, ErrorTooManyClients
+	}
+	sub := &subscriber{ch: make(chan []byte, queue)}
+	mp.subscribers = append(mp.subscribers, sub)
 	mp.mutex.Unlock()
 
 	// Remove the subscriber when the context is done
 	go func() {
 		<-ctx.Done()
 		mp.mutex.Lock()
-		for i, sub := range mp.subscribers {
-			if sub == ch {
-				mp.subscribers = append(mp.subscribers[:i], mp.subscribers[i+1:]...)
-				break
-			}
-		}
+		mp.remove(sub)
 		mp.mutex.Unlock()
-		close(ch)
 	}()
 
-	return ch, nil
+	return sub.ch, nil
+}
+
+// MountStats returns the subscriber counts per mountpoint
+func (s *InMemorySourceService) MountStats() map[string]MountStat {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stats := make(map[string]MountStat, len(s.mounts))
+	for name, mp := range s.mounts {
+		mp.mutex.RLock()
+		stats[name] = MountStat{
+			Subscribers: len(mp.subscribers),
+			Rejected:    mp.rejected,
+			Evicted:     mp.evicted,
+		}
+		mp.mutex.RUnlock()
+	}
+	return stats
 }
 
 // publisher implements io.WriteCloser for publishing data to subscribers
@@ -116,16 +241,19 @@ func (p *publisher) Write(data []byte) (int, error) {
 	dataCopy := make([]byte, len(data))
 	copy(dataCopy, data)
 
-	// Send the data to all subscribers
-	p.mount.mutex.RLock()
-	for _, sub := range p.mount.subscribers {
+	// Send the data to all subscribers, evicting those with a full queue
+	p.mount.mutex.Lock()
+	for i := 0; i < len(p.mount.subscribers); {
+		sub := p.mount.subscribers[i]
 		select {
-		case sub <- dataCopy:
+		case sub.ch <- dataCopy:
+			i++
 		default:
-			// Skip if the channel is full
+			p.mount.remove(sub)
+			p.mount.evicted++
 		}
 	}
-	p.mount.mutex.RUnlock()
+	p.mount.mutex.Unlock()
 
 	return len(data), nil
 }
@@ -0,0 +1,154 @@
+package caster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemorySubscriberEviction(t *testing.T) {
+	svc := NewInMemorySourceService()
+	svc.SubscriberQueue = 4
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pub, err := svc.Publisher(ctx, "TEST", "", "")
+	require.NoError(t, err)
+	stalled, err := svc.Subscriber(ctx, "TEST", "", "")
+	require.NoError(t, err)
+	active, err := svc.Subscriber(ctx, "TEST", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, MountStat{Subscribers: 2}, svc.MountStats()["TEST"])
+
+	// The active subscriber reads every message, the stalled one none
+	for i := 0; i < 20; i++ {
+		_, err := pub.Write([]byte{byte(i)})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{byte(i)}, <-active)
+	}
+	assert.Equal(t, MountStat{Subscribers: 1, Evicted: 1}, svc.MountStats()["TEST"])
+
+	// The queue of the evicted subscriber is closed after the queued messages
+	var got []byte
+	for data := range stalled {
+		got = append(got, data...)
+	}
+	assert.Equal(t, []byte{0, 1, 2, 3}, got)
+
+	// Cancelling an evicted subscriber is harmless
+	cancel()
+	_, ok := <-active
+	assert.False(t, ok)
+}
+
+func TestInMemoryMaxClientsPerMount(t *testing.T) {
+	svc := NewInMemorySourceService()
+	svc.MaxClientsPerMount = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := svc.Publisher(ctx, "TEST", "", "")
+	require.NoError(t, err)
+	subCtx, subCancel := context.WithCancel(ctx)
+	sub, err := svc.Subscriber(subCtx, "TEST", "", "")
+	require.NoError(t, err)
+	_, err = svc.Subscriber(ctx, "TEST", "", "")
+	require.NoError(t, err)
+	_, err = svc.Subscriber(ctx, "TEST", "", "")
+	assert.Equal(t, ErrorTooManyClients, err)
+	assert.Equal(t, MountStat{Subscribers: 2, Rejected: 1}, svc.MountStats()["TEST"])
+
+	// A client leaving frees its slot
+	subCancel()
+	for range sub {
+	}
+	_, err = svc.Subscriber(ctx, "TEST", "", "")
+	assert.NoError(t, err)
+}
+
+// TestCasterSlowClient tests that a client not reading its connection is
+// dropped while another client keeps receiving from a fast publisher
+func TestCasterSlowClient(t *testing.T) {
+	svc := NewInMemorySourceService()
+	svc.MaxClientsPerMount = 2
+	svc.SubscriberQueue = 16
+	c := NewCaster("N/A", svc, logrus.New())
+	ts := httptest.NewServer(c.Handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pub, err := svc.Publisher(ctx, "TEST", "", "")
+	require.NoError(t, err)
+
+	// Stalled client: sends the request and never reads
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /TEST HTTP/1.1\r\nHost: caster\r\nNtrip-Version: Ntrip/2.0\r\n\r\n")
+
+	// Active client
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/TEST", nil)
+	require.NoError(t, err)
+	req.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for deadline := time.Now().Add(5 * time.Second); c.MountStats()["TEST"].Subscribers < 2; {
+		require.True(t, time.Now().Before(deadline), "stalled client not subscribed")
+		time.Sleep(time.Millisecond)
+	}
+	// A third client is refused
+	req3, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/TEST", nil)
+	require.NoError(t, err)
+	req3.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+	resp3, err := http.DefaultClient.Do(req3)
+	require.NoError(t, err)
+	resp3.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp3.StatusCode)
+
+	// Publish until the socket buffers and the queue of the stalled client
+	// are full, the active client reads every chunk
+	chunk := make([]byte, 64*1024)
+	buff := make([]byte, len(chunk))
+	for i := 0; i < 2000 && c.MountStats()["TEST"].Evicted == 0; i++ {
+		for j := range chunk {
+			chunk[j] = byte(i)
+		}
+		_, err := pub.Write(chunk)
+		require.NoError(t, err)
+		_, err = io.ReadFull(resp.Body, buff)
+		require.NoError(t, err, "chunk %d", i)
+		require.True(t, bytes.Equal(chunk, buff), "chunk %d", i)
+	}
+	assert.Equal(t, MountStat{Subscribers: 1, Rejected: 1, Evicted: 1}, c.MountStats()["TEST"])
+
+	// The active client still receives
+	_, err = pub.Write([]byte("after eviction"))
+	require.NoError(t, err)
+	_, err = io.ReadFull(resp.Body, buff[:14])
+	require.NoError(t, err)
+	assert.Equal(t, "after eviction", string(buff[:14]))
+
+	// The response of the evicted client ends once drained
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	stalledResp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer stalledResp.Body.Close()
+	_, err = io.Copy(io.Discard, stalledResp.Body)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package caster
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metrics are the counters of a Caster, exposed in the Prometheus text format
+// by Caster.MetricsHandler
+type metrics struct {
+	mutex      sync.Mutex
+	clients    map[string]int   // Connected clients per mountpoint
+	publishers map[string]int   // Active publishers per mountpoint
+	bytesIn    map[string]int64 // Bytes received from publishers per mountpoint
+	bytesOut   map[string]int64 // Bytes sent to clients per mountpoint
+	requests   map[int]int64    // Requests per response status code
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		clients:    make(map[string]int),
+		publishers: make(map[string]int),
+		bytesIn:    make(map[string]int64),
+		bytesOut:   make(map[string]int64),
+		requests:   make(map[int]int64),
+	}
+}
+
+// addClients adds n (+1/-1) to the connected clients of mount
+func (m *metrics) addClients(mount string, n int) {
+	m.mutex.Lock()
+	m.clients[mount] += n
+	m.mutex.Unlock()
+}
+
+// addPublishers adds n (+1/-1) to the active publishers of mount
+func (m *metrics) addPublishers(mount string, n int) {
+	m.mutex.Lock()
+	m.publishers[mount] += n
+	m.mutex.Unlock()
+}
+
+func (m *metrics) addBytesIn(mount string, n int) {
+	m.mutex.Lock()
+	m.bytesIn[mount] += int64(n)
+	m.mutex.Unlock()
+}
+
+func (m *metrics) addBytesOut(mount string, n int) {
+	m.mutex.Lock()
+	m.bytesOut[mount] += int64(n)
+	m.mutex.Unlock()
+}
+
+func (m *metrics) addRequest(status int) {
+	m.mutex.Lock()
+	m.requests[status]++
+	m.mutex.Unlock()
+}
+
+// labelReplacer escapes a Prometheus label value
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeTo writes the metrics in the Prometheus text exposition format, the
+// series of a metric sorted by label
+func (m *metrics) writeTo(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var sb strings.Builder
+	writeMount := func(name, typ, help string, values map[string]int64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		mounts := make([]string, 0, len(values))
+		for mount := range values {
+			mounts = append(mounts, mount)
+		}
+		sort.Strings(mounts)
+		for _, mount := range mounts {
+			fmt.Fprintf(&sb, "%s{mount=\"%s\"} %d\n", name, labelReplacer.Replace(mount), values[mount])
+		}
+	}
+	gauge := func(values map[string]int) map[string]int64 {
+		v := make(map[string]int64, len(values))
+		for mount, n := range values {
+			v[mount] = int64(n)
+		}
+		return v
+	}
+	writeMount("ntrip_caster_clients", "gauge", "Connected clients per mountpoint.", gauge(m.clients))
+	writeMount("ntrip_caster_publishers", "gauge", "Active publishers per mountpoint.", gauge(m.publishers))
+	writeMount("ntrip_caster_bytes_in_total", "counter", "Bytes received from publishers per mountpoint.", m.bytesIn)
+	writeMount("ntrip_caster_bytes_out_total", "counter", "Bytes sent to clients per mountpoint.", m.bytesOut)
+
+	sb.WriteString("# HELP ntrip_caster_requests_total Requests per response status code.\n")
+	sb.WriteString("# TYPE ntrip_caster_requests_total counter\n")
+	codes := make([]int, 0, len(m.requests))
+	for code := range m.requests {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&sb, "ntrip_caster_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ServeHTTP serves the metrics
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.writeTo(w)
+}
+
+// countingWriter counts the bytes written to a publisher as bytes in of mount
+type countingWriter struct {
+	w       io.Writer
+	metrics *metrics
+	mount   string
+}
+
+func (c *countingWriter) Write(data []byte) (int, error) {
+	n, err := c.w.Write(data)
+	c.metrics.addBytesIn(c.mount, n)
+	return n, err
+}
+
+// statusRecorder counts the response status code of a request once the
+// header is written. It keeps the Flusher and Hijacker of the underlying
+// ResponseWriter used by the NTRIP v1 and v2 handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+	metrics *metrics
+	written bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.written {
+		s.written = true
+		s.metrics.addRequest(status)
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if !s.written {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *statusRecorder) Flush() {
+	if !s.written {
+		s.WriteHeader(http.StatusOK)
+	}
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
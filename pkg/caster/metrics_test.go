@@ -0,0 +1,108 @@
+package caster
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrapeMetrics returns the metrics served by the handler
+func scrapeMetrics(t *testing.T, h http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// containsLines reports whether all lines are in the metrics
+func containsLines(metrics string, lines ...string) bool {
+	for _, line := range lines {
+		if !strings.Contains(metrics, line+"\n") {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCasterMetrics(t *testing.T) {
+	c := NewCaster("N/A", NewInMemorySourceService(), logrus.New())
+	ts := httptest.NewServer(c.Handler)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// One publisher
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/TEST", pr)
+	require.NoError(t, err)
+	req.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+	pubResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer pubResp.Body.Close()
+	require.Equal(t, http.StatusOK, pubResp.StatusCode)
+
+	// Two subscribers
+	var subs []*http.Response
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/TEST", nil)
+		require.NoError(t, err)
+		req.Header.Set(NTRIPVersionHeaderKey, NTRIPVersionHeaderValueV2)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		subs = append(subs, resp)
+	}
+	assert.Equal(t, http.StatusNotFound, casterRequest(t, ts.URL, http.MethodGet, "NONE", "", "", true))
+
+	for deadline := time.Now().Add(5 * time.Second); c.MountStats()["TEST"].Subscribers < 2; {
+		require.True(t, time.Now().Before(deadline), "subscribers not connected")
+		time.Sleep(time.Millisecond)
+	}
+	_, err = pw.Write([]byte("hello"))
+	require.NoError(t, err)
+	for _, sub := range subs {
+		buff := make([]byte, 5)
+		_, err := io.ReadFull(sub.Body, buff)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(buff))
+	}
+
+	want := []string{
+		"# TYPE ntrip_caster_clients gauge",
+		`ntrip_caster_clients{mount="TEST"} 2`,
+		`ntrip_caster_publishers{mount="TEST"} 1`,
+		"# TYPE ntrip_caster_bytes_in_total counter",
+		`ntrip_caster_bytes_in_total{mount="TEST"} 5`,
+		`ntrip_caster_bytes_out_total{mount="TEST"} 10`,
+		`ntrip_caster_requests_total{code="200"} 3`,
+		`ntrip_caster_requests_total{code="404"} 1`,
+	}
+	var metrics string
+	assert.Eventually(t, func() bool {
+		metrics = scrapeMetrics(t, c.MetricsHandler())
+		return containsLines(metrics, want...)
+	}, 5*time.Second, time.Millisecond, "metrics:\n%s", metrics)
+
+	// Disconnected clients and publisher are no longer counted
+	cancel()
+	pw.Close()
+	want = []string{
+		`ntrip_caster_clients{mount="TEST"} 0`,
+		`ntrip_caster_publishers{mount="TEST"} 0`,
+		`ntrip_caster_bytes_out_total{mount="TEST"} 10`,
+	}
+	assert.Eventually(t, func() bool {
+		metrics = scrapeMetrics(t, c.MetricsHandler())
+		return containsLines(metrics, want...)
+	}, 5*time.Second, time.Millisecond, "metrics:\n%s", metrics)
+}
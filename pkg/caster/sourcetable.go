@@ -2,14 +2,15 @@ package caster
 
 import (
 	"fmt"
+	"io"
 	"strings"
 )
 
 // Sourcetable for NTRIP Casters, returned at / as a way for users to discover available mounts
 type Sourcetable struct {
-	Casters  []CasterEntry
-	Networks []NetworkEntry
-	Mounts   []StreamEntry
+	Casters  []CasterEntry  `json:"casters" yaml:"casters"`
+	Networks []NetworkEntry `json:"networks" yaml:"networks"`
+	Mounts   []StreamEntry  `json:"mounts" yaml:"mounts"`
 }
 
 // String returns the sourcetable as a string
@@ -33,19 +34,43 @@ func (st Sourcetable) String() string {
 	return strings.Join(stStrs, "\r\n")
 }
 
+// WriteTo writes the sourcetable in the NTRIP text format, e.g. to a file for
+// static hosting
+func (st Sourcetable) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, st.String())
+	return int64(n), err
+}
+
+var (
+	fieldReplacer = strings.NewReplacer(";", ",", "\r", " ", "\n", " ")
+	miscReplacer  = strings.NewReplacer("\r", " ", "\n", " ")
+)
+
+// joinFields joins the fields of a sourcetable record. Semicolons are only
+// kept in the last (misc) field, which extends to the end of the line, and
+// line breaks are removed, so every record stays a single line with a fixed
+// number of fields.
+func joinFields(fields ...string) string {
+	for i := 1; i < len(fields)-1; i++ {
+		fields[i] = fieldReplacer.Replace(fields[i])
+	}
+	fields[len(fields)-1] = miscReplacer.Replace(fields[len(fields)-1])
+	return strings.Join(fields, ";")
+}
+
 // CasterEntry for an NTRIP Sourcetable
 type CasterEntry struct {
-	Host                string
-	Port                int
-	Identifier          string
-	Operator            string
-	NMEA                bool
-	Country             string
-	Latitude            float32
-	Longitude           float32
-	FallbackHostAddress string
-	FallbackHostPort    int
-	Misc                string
+	Host                string  `json:"host" yaml:"host"`
+	Port                int     `json:"port" yaml:"port"`
+	Identifier          string  `json:"identifier" yaml:"identifier"`
+	Operator            string  `json:"operator" yaml:"operator"`
+	NMEA                bool    `json:"nmea" yaml:"nmea"`
+	Country             string  `json:"country" yaml:"country"`
+	Latitude            float32 `json:"latitude" yaml:"latitude"`
+	Longitude           float32 `json:"longitude" yaml:"longitude"`
+	FallbackHostAddress string  `json:"fallbackHostAddress" yaml:"fallbackHostAddress"`
+	FallbackHostPort    int     `json:"fallbackHostPort" yaml:"fallbackHostPort"`
+	Misc                string  `json:"misc" yaml:"misc"`
 }
 
 // String returns the caster entry as a string
@@ -55,22 +80,22 @@ func (c CasterEntry) String() string {
 		nmea = "1"
 	}
 
-	return strings.Join([]string{"CAS",
+	return joinFields("CAS",
 		c.Host, fmt.Sprintf("%d", c.Port), c.Identifier, c.Operator, nmea, c.Country,
 		fmt.Sprintf("%.4f", c.Latitude), fmt.Sprintf("%.4f", c.Longitude),
-		c.FallbackHostAddress, fmt.Sprintf("%d", c.FallbackHostPort), c.Misc}, ";")
+		c.FallbackHostAddress, fmt.Sprintf("%d", c.FallbackHostPort), c.Misc)
 }
 
 // NetworkEntry for an NTRIP Sourcetable
 type NetworkEntry struct {
-	Identifier          string
-	Operator            string
-	Authentication      string
-	Fee                 bool
-	NetworkInfoURL      string
-	StreamInfoURL       string
-	RegistrationAddress string
-	Misc                string
+	Identifier          string `json:"identifier" yaml:"identifier"`
+	Operator            string `json:"operator" yaml:"operator"`
+	Authentication      string `json:"authentication" yaml:"authentication"`
+	Fee                 bool   `json:"fee" yaml:"fee"`
+	NetworkInfoURL      string `json:"networkInfoURL" yaml:"networkInfoURL"`
+	StreamInfoURL       string `json:"streamInfoURL" yaml:"streamInfoURL"`
+	RegistrationAddress string `json:"registrationAddress" yaml:"registrationAddress"`
+	Misc                string `json:"misc" yaml:"misc"`
 }
 
 // String returns the network entry as a string
@@ -80,31 +105,31 @@ func (n NetworkEntry) String() string {
 		fee = "Y"
 	}
 
-	return strings.Join([]string{"NET",
+	return joinFields("NET",
 		n.Identifier, n.Operator, n.Authentication, fee, n.NetworkInfoURL, n.StreamInfoURL,
-		n.RegistrationAddress, n.Misc}, ";")
+		n.RegistrationAddress, n.Misc)
 }
 
 // StreamEntry for an NTRIP Sourcetable
 type StreamEntry struct {
-	Name          string
-	Identifier    string
-	Format        string
-	FormatDetails string
-	Carrier       string
-	NavSystem     string
-	Network       string
-	CountryCode   string
-	Latitude      float32
-	Longitude     float32
-	NMEA          bool
-	Solution      bool
-	Generator     string
-	Compression   string
-	Authentication string
-	Fee            bool
-	Bitrate        int
-	Misc           string
+	Name           string  `json:"name" yaml:"name"`
+	Identifier     string  `json:"identifier" yaml:"identifier"`
+	Format         string  `json:"format" yaml:"format"`
+	FormatDetails  string  `json:"formatDetails" yaml:"formatDetails"`
+	Carrier        string  `json:"carrier" yaml:"carrier"`
+	NavSystem      string  `json:"navSystem" yaml:"navSystem"`
+	Network        string  `json:"network" yaml:"network"`
+	CountryCode    string  `json:"countryCode" yaml:"countryCode"`
+	Latitude       float32 `json:"latitude" yaml:"latitude"`
+	Longitude      float32 `json:"longitude" yaml:"longitude"`
+	NMEA           bool    `json:"nmea" yaml:"nmea"`
+	Solution       bool    `json:"solution" yaml:"solution"`
+	Generator      string  `json:"generator" yaml:"generator"`
+	Compression    string  `json:"compression" yaml:"compression"`
+	Authentication string  `json:"authentication" yaml:"authentication"`
+	Fee            bool    `json:"fee" yaml:"fee"`
+	Bitrate        int     `json:"bitrate" yaml:"bitrate"`
+	Misc           string  `json:"misc" yaml:"misc"`
 }
 
 // String returns the stream entry as a string
@@ -124,9 +149,9 @@ func (m StreamEntry) String() string {
 		fee = "Y"
 	}
 
-	return strings.Join([]string{"STR",
+	return joinFields("STR",
 		m.Name, m.Identifier, m.Format, m.FormatDetails, m.Carrier, m.NavSystem, m.Network,
 		m.CountryCode, fmt.Sprintf("%.4f", m.Latitude), fmt.Sprintf("%.4f", m.Longitude),
 		nmea, solution, m.Generator, m.Compression, m.Authentication, fee,
-		fmt.Sprintf("%d", m.Bitrate), m.Misc}, ";")
+		fmt.Sprintf("%d", m.Bitrate), m.Misc)
 }
@@ -0,0 +1,41 @@
+package caster
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourcetableString(t *testing.T) {
+	st := Sourcetable{
+		Casters: []CasterEntry{{
+			Host: "caster.example.com", Port: 2101, Identifier: "Example", Operator: "Ops",
+			Country: "NLD", Latitude: 52.37, Longitude: 4.89, FallbackHostAddress: "0.0.0.0",
+		}},
+		Networks: []NetworkEntry{{
+			Identifier: "EXNET", Operator: "Ops", Authentication: "B", Fee: true,
+			NetworkInfoURL: "http://example.com", StreamInfoURL: "none", RegistrationAddress: "none",
+		}},
+		Mounts: []StreamEntry{{
+			Name: "AMS", Identifier: "Amsterdam; NL", Format: "RTCM 3.3", FormatDetails: "1005(10),1077(1)",
+			Carrier: "2", NavSystem: "GPS", Network: "EXNET", CountryCode: "NLD",
+			Latitude: 52.37, Longitude: 4.89, NMEA: true, Generator: "gnssgo\r\n",
+			Compression: "none", Authentication: "B", Bitrate: 9600, Misc: "a;b",
+		}},
+	}
+	want := "CAS;caster.example.com;2101;Example;Ops;0;NLD;52.3700;4.8900;0.0.0.0;0;\r\n" +
+		"NET;EXNET;Ops;B;Y;http://example.com;none;none;\r\n" +
+		"STR;AMS;Amsterdam, NL;RTCM 3.3;1005(10),1077(1);2;GPS;EXNET;NLD;52.3700;4.8900;1;0;gnssgo  ;none;B;N;9600;a;b\r\n" +
+		"ENDSOURCETABLE\r\n"
+	assert.Equal(t, want, st.String())
+
+	var buf bytes.Buffer
+	n, err := st.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(want)), n)
+	assert.Equal(t, want, buf.String())
+
+	assert.Equal(t, "ENDSOURCETABLE\r\n", Sourcetable{}.String())
+}
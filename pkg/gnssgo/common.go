@@ -320,13 +320,13 @@ func DefaultProcOpt() PrcOpt {
 		IonoOpt: 0, TropOpt: 0, Dynamics: 0, TideCorr: 0, /* estion,esttrop,dynamics,tidecorr */
 		NoIter: 1, CodeSmooth: 0, IntPref: 0, SbasCorr: 0, SbasSatSel: 0, /* niter,codesmooth,intpref,sbascorr,sbassatsel */
 		RovPos: 0, RefPos: 0, /*  */
-		eratio:     [NFREQ]float64{100.0, 100.0, 0.0},             /* eratio[] */
-		Err:        [5]float64{100.0, 0.003, 0.003, 0.0, 1.0},     /* err[] */
-		Std:        [3]float64{30.0, 0.03, 0.3},                   /* std[] */
-		Prn:        [6]float64{1e-4, 1e-3, 1e-4, 1e-1, 1e-2, 0.0}, /* prn[] */
-		SatClkStab: 5e-12,                                         /* sclkstab */
-		ThresAr:    [8]float64{3.0, 0.9999, 0.25, 0.1, 0.05},      /* thresar */
-		ElMaskAr:   0.0, ElMaskHold: 0.0, ThresSlip: 0.05,         /* elmaskar,elmaskhold,thresslip */
+		eratio:     [NFREQ]float64{100.0, 100.0, 0.0},                      /* eratio[] */
+		Err:        [5]float64{100.0, 0.003, 0.003, 0.0, 1.0},              /* err[] */
+		Std:        [3]float64{30.0, 0.03, 0.3},                            /* std[] */
+		Prn:        [6]float64{1e-4, 1e-3, 1e-4, 1e-1, 1e-2, 0.0},          /* prn[] */
+		SatClkStab: 5e-12,                                                  /* sclkstab */
+		ThresAr:    [8]float64{3.0, 0.9999, 0.25, 0.1, 0.05},               /* thresar */
+		ElMaskAr:   0.0, ElMaskHold: 0.0, ThresSlip: 0.05, ThresSlipR: 0.0, /* elmaskar,elmaskhold,thresslip,thresslipr */
 		MaxTmDiff: 30.0, MaxInno: 30.0, MaxGdop: 30.0, /* maxtdiff,maxinno,maxgdop */
 		Baseline: [2]float64{0}, Ru: [3]float64{0}, Rb: [3]float64{0} /* baseline,ru,rb */}
 }
@@ -2386,6 +2386,43 @@ func AdjGpsWeek(week int) int {
 	return week + (w-week+1)/1024*1024
 }
 
+/* adjust gps week number with reference time ----------------------------------
+* adjust gps week number to the week nearest to a reference time, which
+* resolves the rollover of 10-bit week numbers of old receivers
+* args   : int   week       I   not-adjusted gps week number (0-1023)
+*          gtime_t ref      I   reference time (gpst) (0: use cpu time)
+* return : adjusted gps week number
+* notes  : full week numbers (>=1024, e.g. novatel, javad) are returned
+*          unchanged, whatever the reference time
+*-----------------------------------------------------------------------------*/
+func AdjGpsWeekRef(week int, ref Gtime) int {
+	var w int
+	if week >= 1024 {
+		return week
+	}
+	if ref.Time == 0 {
+		return AdjGpsWeek(week)
+	}
+	Time2GpsT(ref, &w)
+	if w < week {
+		return week
+	}
+	return week + (w-week+512)/1024*1024
+}
+
+// ResolveWeekRollover resolves a GPS week number that has rolled over (10-bit,
+// 0-1023) to the full week number nearest to the reference time (UTC), like
+// convbin -tr for legacy data. A zero reference time uses the current time.
+func ResolveWeekRollover(week int, refTime time.Time) int {
+	if refTime.IsZero() {
+		return AdjGpsWeek(week)
+	}
+	t := refTime.UTC()
+	ep := []float64{float64(t.Year()), float64(t.Month()), float64(t.Day()),
+		float64(t.Hour()), float64(t.Minute()), float64(t.Second())}
+	return AdjGpsWeekRef(week, Utc2GpsT(Epoch2Time(ep)))
+}
+
 /* get tick time ---------------------------------------------------------------
 * get current tick in ms
 * args   : none
@@ -2969,6 +3006,8 @@ func ReadAntex(file string, pcvs *Pcvs) int {
 		pcv0                 Pcv
 		pcv                  Pcv
 		neu                  [3]float64
+		row                  [19]float64
+		azi                  float64
 		i, n, f, state, freq int
 		freqs                []int = []int{1, 2, 5, 0}
 	)
@@ -3010,6 +3049,10 @@ func ReadAntex(file string, pcvs *Pcvs) int {
 			if strings.Compare(string(pcv.Code[3:11]), "        ") == 0 {
 				pcv.Sat = SatId2No(string(pcv.Code[:]))
 			}
+		case strings.Contains(string(buff[60:]), "DAZI"):
+			if n, _ = fmt.Sscanf(string(buff[2:8]), "%f", &pcv.Dazi); n < 1 || pcv.Dazi < 0.0 {
+				pcv.Dazi = 0.0
+			}
 		case strings.Contains(string(buff[60:]), "VALID FROM"):
 			if Str2Time(string(buff[:]), 0, 43, &pcv.Ts) == 0 {
 				continue
@@ -3061,12 +3104,62 @@ func ReadAntex(file string, pcvs *Pcvs) int {
 			for ; i < 19; i++ {
 				pcv.Variation[freq-1][i] = pcv.Variation[freq-1][i-1]
 			}
+		case freq > 0 && pcv.Dazi > 0.0: /* azimuth-dependent pcv */
+			if n, _ = fmt.Sscanf(string(buff[:8]), "%f", &azi); n < 1 ||
+				int(azi/pcv.Dazi+0.5) != len(pcv.AziVar[freq-1]) {
+				continue
+			}
+			if i = DecodeF(string(buff[8:]), 19, row[:]); i <= 0 {
+				continue
+			}
+			for ; i < 19; i++ {
+				row[i] = row[i-1]
+			}
+			pcv.AziVar[freq-1] = append(pcv.AziVar[freq-1], row)
 		}
 	}
 
 	return 1
 }
 
+/* read antex file -------------------------------------------------------------
+* read satellite and receiver antenna parameters from an antex file
+* args   : string path      I   antex file path
+* return : antenna parameters and error (nil: ok)
+* notes  : phase center offsets and variations are read for the frequencies
+*          1, 2 and 5 of the satellites and the gps frequencies of the
+*          receiver antennas. L2 parameters are applied to L3,... as ReadPcv.
+*          the azimuth-dependent variations are read if DAZI > 0, otherwise
+*          only the NOAZI variations. the zenith (nadir) grid is assumed as
+*          0:5:90 deg for receiver and 0:1:18 deg for satellite antennas.
+*-----------------------------------------------------------------------------*/
+func ReadANTEX(path string) (*PcvList, error) {
+	pcvs := new(PcvList)
+	if ReadAntex(path, pcvs) == 0 {
+		return nil, fmt.Errorf("antex file open error: %s", path)
+	}
+	if pcvs.N() == 0 {
+		return nil, fmt.Errorf("no antenna in antex file: %s", path)
+	}
+	pcvfreq(pcvs)
+	return pcvs, nil
+}
+
+/* apply L2 antenna parameters to L3,L4,... if no parameters ----------------*/
+func pcvfreq(pcvs *Pcvs) {
+	for i := range pcvs.Pcv {
+		pcv := &pcvs.Pcv[i]
+		for j := 2; j < NFREQ; j++ { /* L3,L4,... */
+			if Norm(pcv.Offset[j][:], 3) > 0.0 {
+				continue
+			}
+			MatCpy(pcv.Offset[j][:], pcv.Offset[1][:], 3, 1)
+			MatCpy(pcv.Variation[j][:], pcv.Variation[1][:], 19, 1)
+			pcv.AziVar[j] = pcv.AziVar[1]
+		}
+	}
+}
+
 /* read antenna parameters ------------------------------------------------------
 * read antenna parameters
 * args   : char   *file       I   antenna parameter file (antex)
@@ -3075,13 +3168,13 @@ func ReadAntex(file string, pcvs *Pcvs) int {
 * notes  : file with the externsion .atx or .ATX is recognized as antex
 *          file except for antex is recognized ngs antenna parameters
 *          see reference [3]
-*          only support non-azimuth-depedent parameters
+*          azimuth-dependent parameters are only read from antex files
 *-----------------------------------------------------------------------------*/
 func ReadPcv(file string, pcvs *Pcvs) int {
 	var (
-		pcv        *Pcv
-		i, j, stat int
-		ext        string
+		pcv     *Pcv
+		i, stat int
+		ext     string
 	)
 
 	Trace(4, "readpcv: file=%s\n", file)
@@ -3099,16 +3192,9 @@ func ReadPcv(file string, pcvs *Pcvs) int {
 		Trace(4, "sat=%2d type=%20s code=%s off=%8.4f %8.4f %8.4f  %8.4f %8.4f %8.4f\n",
 			pcv.Sat, pcv.Type, pcv.Code, pcv.Offset[0][0], pcv.Offset[0][1],
 			pcv.Offset[0][2], pcv.Offset[1][0], pcv.Offset[1][1], pcv.Offset[1][2])
-
-		/* apply L2 to L3,L4,... if no pcv data */
-		for j = 2; j < NFREQ; j++ { /* L3,L4,... */
-			if Norm(pcv.Offset[j][:], 3) > 0.0 {
-				continue
-			}
-			MatCpy(pcv.Offset[j][:], pcv.Offset[1][:], 3, 1)
-			MatCpy(pcv.Variation[j][:], pcv.Variation[1][:], 19, 1)
-		}
 	}
+	/* apply L2 to L3,L4,... if no pcv data */
+	pcvfreq(pcvs)
 	return stat
 }
 
@@ -3148,28 +3234,34 @@ func SearchPcv(sat int, ctype string, time Gtime, pcvs *Pcvs) *Pcv {
 		buff = ctype
 		p := strings.Fields(buff)
 		for i, v = range p {
+			if i >= len(types) {
+				break
+			}
 			types[i] = v
+			n++
 		}
-		if i <= 0 {
+		if n <= 0 {
 			return nil
 		}
-		n = i
 		/* search receiver antenna with radome at first */
 		for i = 0; i < pcvs.N(); i++ {
 			pcv = &pcvs.Pcv[i]
+			if pcv.Sat != 0 {
+				continue
+			}
 			for j = 0; j < n; j++ {
-				if strings.Index(string(pcv.Type[:]), types[j]) == 0 {
+				if !strings.Contains(pcv.Type, types[j]) {
 					break
 				}
-				if j >= n {
-					return pcv
-				}
+			}
+			if j >= n {
+				return pcv
 			}
 		}
 		/* search receiver antenna without radome */
 		for i = 0; i < pcvs.N(); i++ {
 			pcv = &pcvs.Pcv[i]
-			if strings.Index(string(pcv.Type[:]), types[0]) != 0 {
+			if pcv.Sat != 0 || strings.Index(string(pcv.Type[:]), types[0]) != 0 {
 				continue
 			}
 			return pcv
@@ -3178,6 +3270,13 @@ func SearchPcv(sat int, ctype string, time Gtime, pcvs *Pcvs) *Pcv {
 	return nil
 }
 
+/* search antenna parameter in the list ---------------------------------------
+* search satellite or receiver antenna parameters (see SearchPcv)
+*-----------------------------------------------------------------------------*/
+func (pcvs *Pcvs) SearchPcv(sat int, antType string, time Gtime) *Pcv {
+	return SearchPcv(sat, antType, time, pcvs)
+}
+
 /* read station positions ------------------------------------------------------
 * read positions from station position file
 * args   : char  *file      I   station position file containing
@@ -3561,6 +3660,82 @@ func (nav *Nav) UniqNav() {
 	nav.UniqSEph()
 }
 
+/* latest broadcast ephemeris time of navigation system ----------------------*/
+func (nav *Nav) latesteph(sys int) Gtime {
+	var t Gtime
+	for i := range nav.Ephs {
+		if SatSys(nav.Ephs[i].Sat, nil) == sys && TimeDiff(nav.Ephs[i].Toe, t) > 0.0 {
+			t = nav.Ephs[i].Toe
+		}
+	}
+	for i := range nav.Geph {
+		if sys == SYS_GLO && TimeDiff(nav.Geph[i].Toe, t) > 0.0 {
+			t = nav.Geph[i].Toe
+		}
+	}
+	for i := range nav.Seph {
+		if sys == SYS_SBS && TimeDiff(nav.Seph[i].T0, t) > 0.0 {
+			t = nav.Seph[i].T0
+		}
+	}
+	return t
+}
+
+/* merge navigation parameters -------------------------------------------------
+* merge the ionosphere model and utc parameters of other navigation data,
+* keeping the most recent
+* args   : nav_t *nav    IO     navigation data
+*          nav_t *other  I      navigation data to merge
+* return : none
+* notes  : the parameters have no reference time of their own, so a system's
+*          parameters are as recent as the latest broadcast ephemeris of the
+*          system. the parameters of other replace those of nav if they are set
+*          and the latest ephemeris of other is not older (the same or none in
+*          both: other is taken as read later). parameters not set (all zero)
+*          are never merged.
+*          glonass fcn set in other replace those of nav.
+*          the ephemerides are not merged.
+*-----------------------------------------------------------------------------*/
+func (nav *Nav) MergeParams(other *Nav) {
+	if other == nil || other == nav {
+		return
+	}
+	Trace(4, "mergeparams:\n")
+
+	params := []struct {
+		sys      int
+		dst, src []float64
+	}{
+		{SYS_GPS, nav.Ion_gps[:], other.Ion_gps[:]},
+		{SYS_GAL, nav.Ion_gal[:], other.Ion_gal[:]},
+		{SYS_QZS, nav.Ion_qzs[:], other.Ion_qzs[:]},
+		{SYS_CMP, nav.Ion_cmp[:], other.Ion_cmp[:]},
+		{SYS_IRN, nav.Ion_irn[:], other.Ion_irn[:]},
+		{SYS_GPS, nav.Utc_gps[:], other.Utc_gps[:]},
+		{SYS_GLO, nav.Utc_glo[:], other.Utc_glo[:]},
+		{SYS_GAL, nav.Utc_gal[:], other.Utc_gal[:]},
+		{SYS_QZS, nav.Utc_qzs[:], other.Utc_qzs[:]},
+		{SYS_CMP, nav.Utc_cmp[:], other.Utc_cmp[:]},
+		{SYS_IRN, nav.Utc_irn[:], other.Utc_irn[:]},
+		{SYS_SBS, nav.Utc_sbs[:], other.Utc_sbs[:]},
+	}
+	for _, p := range params {
+		if Norm(p.src, len(p.src)) <= 0.0 {
+			continue
+		}
+		if Norm(p.dst, len(p.dst)) > 0.0 &&
+			TimeDiff(other.latesteph(p.sys), nav.latesteph(p.sys)) < 0.0 {
+			continue
+		}
+		copy(p.dst, p.src)
+	}
+	for i := range other.Glo_fcn {
+		if other.Glo_fcn[i] != 0 {
+			nav.Glo_fcn[i] = other.Glo_fcn[i]
+		}
+	}
+}
+
 /* compare observation data -------------------------------------------------*/
 func cmpobs(q1, q2 *ObsD) int {
 	tt := TimeDiff(q1.Time, q2.Time)
@@ -3616,6 +3791,66 @@ func (obs *Obs) SortObs() int {
 	return n
 }
 
+/* test observation value ------------------------------------------------------
+* test an observation value against the missing value conventions
+* args   : double v      I   pseudorange (m), carrier-phase (cycle) or doppler (Hz)
+* return : true: valid value, false: missing value
+* notes  : 0.0 is the missing value of RINEX and RTCM. not-a-number, infinity
+*          and |v| >= 1e9 (sentinels of receivers and converters) are missing
+*          too.
+*-----------------------------------------------------------------------------*/
+func ValidObsValue(v float64) bool {
+	return v != 0.0 && !math.IsNaN(v) && math.Abs(v) < 1e9
+}
+
+/* clean observation data ------------------------------------------------------
+* set the missing values of observation data to 0.0, the value excluded by the
+* positioning
+* args   : obsd_t *obs   I   observation data
+*          int    n      I   number of observation data
+* return : observation data (a copy if any value is missing) and number of
+*          missing values set to 0.0
+* notes  : negative pseudoranges are missing too. see ValidObsValue.
+*-----------------------------------------------------------------------------*/
+func CleanObs(obs []ObsD, n int) ([]ObsD, int) {
+	var (
+		data     []ObsD = obs
+		i, j, nm int
+	)
+	missing := func(v float64) bool { return v != 0.0 && !ValidObsValue(v) }
+
+	for i = 0; i < n; i++ {
+		for j = 0; j < NFREQ+NEXOBS; j++ {
+			d := &data[i]
+			mP, mL, mD := missing(d.P[j]) || d.P[j] < 0.0, missing(d.L[j]), missing(d.D[j])
+			if !mP && !mL && !mD {
+				continue
+			}
+			if nm == 0 { /* copy not to change the input */
+				data = make([]ObsD, n)
+				copy(data, obs[:n])
+				d = &data[i]
+			}
+			if mP {
+				d.P[j] = 0.0
+				nm++
+			}
+			if mL {
+				d.L[j] = 0.0
+				nm++
+			}
+			if mD {
+				d.D[j] = 0.0
+				nm++
+			}
+		}
+	}
+	if nm > 0 {
+		Trace(3, "cleanobs: time=%s missing values=%d\n", TimeStr(data[0].Time, 3), nm)
+	}
+	return data, nm
+}
+
 /* screen by time --------------------------------------------------------------
 * screening by time start, time end, and time interval
 * args   : gtime_t time  I      time
@@ -4648,6 +4883,20 @@ func InterPVar(ang float64, vari []float64) float64 {
 	return vari[i]*(1.0-a+float64(i)) + vari[i+1]*(a-float64(i))
 }
 
+/* interpolate azimuth-dependent antenna pcv ---------------------------------*/
+func interpvar2(az, ang float64, rows [][19]float64, dazi float64) float64 {
+	a := math.Mod(az, 360.0)
+	if a < 0.0 {
+		a += 360.0
+	}
+	a /= dazi
+	i := int(a)
+	if i >= len(rows)-1 {
+		return InterPVar(ang, rows[len(rows)-1][:])
+	}
+	return InterPVar(ang, rows[i][:])*(1.0-a+float64(i)) + InterPVar(ang, rows[i+1][:])*(a-float64(i))
+}
+
 /* receiver antenna model ------------------------------------------------------
 * compute antenna offset by antenna phase center parameters
 * args   : pcv_t *pcv       I   antenna phase center parameters
@@ -4656,7 +4905,8 @@ func InterPVar(ang float64, vari []float64) float64 {
 *          int     opt      I   option (0:only offset,1:offset+pcv)
 *          double *dant     O   range offsets for each frequency (m)
 * return : none
-* notes  : current version does not support azimuth dependent terms
+* notes  : the azimuth-dependent pcv is used if the antenna parameters have
+*          the grid, otherwise the NOAZI pcv
 *-----------------------------------------------------------------------------*/
 func AntModel(pcv *Pcv, del, azel []float64, opt int, dant []float64) {
 	var e, off [3]float64
@@ -4676,7 +4926,11 @@ func AntModel(pcv *Pcv, del, azel []float64, opt int, dant []float64) {
 		}
 		var intvar float64 = 0.0
 		if opt > 0 {
-			intvar = InterPVar(90.0-azel[1]*R2D, pcv.Variation[i][:])
+			if pcv.Dazi > 0.0 && len(pcv.AziVar[i]) > 0 {
+				intvar = interpvar2(azel[0]*R2D, 90.0-azel[1]*R2D, pcv.AziVar[i], pcv.Dazi)
+			} else {
+				intvar = InterPVar(90.0-azel[1]*R2D, pcv.Variation[i][:])
+			}
 		}
 		dant[i] = -Dot(off[:], e[:], 3) + intvar
 	}
@@ -0,0 +1,248 @@
+package gnssgo
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestResolveWeekRollover resolves 10-bit gps weeks against reference times.
+func TestResolveWeekRollover(t *testing.T) {
+	tests := []struct {
+		week int
+		ref  time.Time
+		want int
+	}{
+		{2264 % 1024, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2264}, /* same week */
+		{2243 % 1024, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2243}, /* earlier in year */
+		{2290 % 1024, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2290}, /* later in year */
+		{1020, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2044},        /* before 2019 rollover */
+		{2, time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC), 2050},           /* after 2019 rollover */
+		{523, time.Date(2009, 8, 30, 12, 0, 0, 0, time.UTC), 1547},       /* 1999-2019 era */
+		{523, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), 523},          /* first era */
+		{2264, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2264},        /* full week */
+	}
+	for _, tt := range tests {
+		if got := ResolveWeekRollover(tt.week, tt.ref); got != tt.want {
+			t.Errorf("week=%d ref=%s: got %d, want %d", tt.week, tt.ref.Format("2006-01-02"), got, tt.want)
+		}
+	}
+
+	/* full weeks are kept with a reference more than 512 weeks away */
+	for _, ref := range [][]float64{{2005, 1, 1, 0, 0, 0}, {2045, 1, 1, 0, 0, 0}} {
+		for _, week := range []int{1024, 1500, 2264} {
+			if got := AdjGpsWeekRef(week, Epoch2Time(ref)); got != week {
+				t.Errorf("week=%d ref=%.0f: got %d, want unchanged", week, ref[0], got)
+			}
+		}
+	}
+}
+
+// TestRtcm3WeekRollover decodes a GPS ephemeris (10-bit week) with the rtcm
+// time set as reference (convbin -tr).
+func TestRtcm3WeekRollover(t *testing.T) {
+	tests := []struct {
+		ref  []float64
+		want int
+	}{
+		{[]float64{2023, 6, 1, 0, 0, 0}, 2264},
+		{[]float64{2003, 10, 16, 2, 0, 0}, 1240},
+	}
+	toe := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	for _, tt := range tests {
+		var enc, dec Rtcm
+		enc.InitRtcm()
+		enc.NavData.Ephs[0] = synthEph(1, 0, toe)
+		enc.EphSat = 1
+		if enc.GenRtcm3(1019, 0, 0) == 0 {
+			t.Fatal("encode 1019 failed")
+		}
+		dec.InitRtcm()
+		dec.Time = Epoch2Time(tt.ref)
+		ret := 0
+		for _, b := range enc.Buff[:enc.Nbyte] {
+			ret = dec.InputRtcm3(b)
+		}
+		if ret != 2 {
+			t.Fatalf("ref=%v: decode 1019 returned %d", tt.ref, ret)
+		}
+		if week := dec.NavData.Ephs[0].Week; week != tt.want {
+			t.Errorf("ref=%v: week = %d, want %d", tt.ref, week, tt.want)
+		}
+	}
+}
+
+// atxTRM59800 is the azimuth-dependent L1 pcv (mm) of TRM59800.00 NONE in the
+// antex excerpt of writeAntex, linear in azimuth and zenith angle (deg).
+func atxTRM59800(az, ze float64) float64 {
+	return 0.05*ze + 0.002*az
+}
+
+// writeAntex writes an excerpt of igs14.atx with the satellite antenna of G01,
+// the receiver antenna TRM59800.00 NONE with an azimuth-dependent grid
+// (DAZI 5.0) and TRM59800.00 SCIS with NOAZI variations only.
+func writeAntex(t *testing.T) string {
+	var b strings.Builder
+	line := func(data, label string) {
+		fmt.Fprintf(&b, "%-60s%-20s\n", data, label)
+	}
+	row := func(head string, n int, v func(i int) float64) {
+		b.WriteString(head)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "%8.2f", v(i))
+		}
+		b.WriteString("\n")
+	}
+	line("     1.4            M", "ANTEX VERSION / SYST")
+	line("A", "PCV TYPE / REFANT")
+	line("", "END OF HEADER")
+
+	line("", "START OF ANTENNA")
+	line("BLOCK IIF           G01                 G063      2011-036A", "TYPE / SERIAL NO")
+	line("                                             0    29-JAN-17", "METH / BY / # / DATE")
+	line("     0.0", "DAZI")
+	line("     0.0  14.0   1.0", "ZEN1 / ZEN2 / DZEN")
+	line("     2", "# OF FREQUENCIES")
+	line("  2011     7    16     0     0    0.0000000", "VALID FROM")
+	line("IGS14_2062", "SINEX CODE")
+	for f, up := range []float64{1561.6, 1561.6} {
+		line(fmt.Sprintf("   G%02d", f+1), "START OF FREQUENCY")
+		line(fmt.Sprintf("%10.2f%10.2f%10.2f", 394.0, 0.0, up), "NORTH / EAST / UP")
+		row("   NOAZI", 15, func(i int) float64 { return -0.8 + 0.1*float64(i) })
+		line(fmt.Sprintf("   G%02d", f+1), "END OF FREQUENCY")
+	}
+	line("", "END OF ANTENNA")
+
+	line("", "START OF ANTENNA")
+	line("TRM59800.00     NONE", "TYPE / SERIAL NO")
+	line("ROBOT               Geo++ GmbH             2    15-MAY-15", "METH / BY / # / DATE")
+	line("     5.0", "DAZI")
+	line("     0.0  90.0   5.0", "ZEN1 / ZEN2 / DZEN")
+	line("     2", "# OF FREQUENCIES")
+	line("IGS14_1890", "SINEX CODE")
+	for f, neu := range [][3]float64{{1.28, 0.45, 66.23}, {0.02, 0.81, 57.76}} {
+		line(fmt.Sprintf("   G%02d", []int{1, 2}[f]), "START OF FREQUENCY")
+		line(fmt.Sprintf("%10.2f%10.2f%10.2f", neu[0], neu[1], neu[2]), "NORTH / EAST / UP")
+		row("   NOAZI", 19, func(i int) float64 { return atxTRM59800(0.0, 5.0*float64(i)) })
+		for az := 0.0; az <= 360.0; az += 5.0 {
+			row(fmt.Sprintf("%8.1f", az), 19, func(i int) float64 { return atxTRM59800(az, 5.0*float64(i)) })
+		}
+		line(fmt.Sprintf("   G%02d", []int{1, 2}[f]), "END OF FREQUENCY")
+	}
+	line("", "END OF ANTENNA")
+
+	line("", "START OF ANTENNA")
+	line("TRM59800.00     SCIS", "TYPE / SERIAL NO")
+	line("COPIED              IGS                    0    15-MAY-15", "METH / BY / # / DATE")
+	line("     0.0", "DAZI")
+	line("     0.0  90.0   5.0", "ZEN1 / ZEN2 / DZEN")
+	line("     2", "# OF FREQUENCIES")
+	for f := 1; f <= 2; f++ {
+		line(fmt.Sprintf("   G%02d", f), "START OF FREQUENCY")
+		line(fmt.Sprintf("%10.2f%10.2f%10.2f", 1.10, 0.30, 71.35), "NORTH / EAST / UP")
+		row("   NOAZI", 19, func(i int) float64 { return -0.1 * float64(i) })
+		line(fmt.Sprintf("   G%02d", f), "END OF FREQUENCY")
+	}
+	line("", "END OF ANTENNA")
+
+	file := filepath.Join(t.TempDir(), "igs14.atx")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestReadANTEX reads the antex excerpt and interpolates the pcv of the
+// receiver antennas with and without the azimuth-dependent grid.
+func TestReadANTEX(t *testing.T) {
+	pcvs, err := ReadANTEX(writeAntex(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcvs.N() != 3 {
+		t.Fatalf("antennas: got %d, want 3", pcvs.N())
+	}
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+
+	sat := pcvs.SearchPcv(SatNo(SYS_GPS, 1), "", t0)
+	if sat == nil {
+		t.Fatal("no satellite antenna of G01")
+	}
+	near := func(a, b []float64) bool {
+		for i := range b {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	if !near(sat.Offset[0][:], []float64{0.394, 0.0, 1.5616}) || !near(sat.Variation[0][14:], []float64{6e-4, 6e-4, 6e-4, 6e-4, 6e-4}) {
+		t.Errorf("satellite antenna: off=%v var=%v", sat.Offset[0], sat.Variation[0])
+	}
+	if pcvs.SearchPcv(SatNo(SYS_GPS, 1), "", Epoch2Time([]float64{2010, 1, 1, 0, 0, 0})) != nil {
+		t.Errorf("satellite antenna before valid from")
+	}
+
+	for _, tt := range []struct {
+		antType, want string
+	}{
+		{"TRM59800.00     NONE", "TRM59800.00     NONE"},
+		{"TRM59800.00 SCIS", "TRM59800.00     SCIS"},
+		{"TRM59800.00", "TRM59800.00     NONE"},
+		{"TRM59800.00     SCIT", "TRM59800.00     NONE"},
+		{"TRM57971.00     NONE", ""},
+		{"", ""},
+	} {
+		pcv, got := pcvs.SearchPcv(0, tt.antType, t0), ""
+		if pcv != nil {
+			got = pcv.Type
+		}
+		if got != tt.want {
+			t.Errorf("search %q: got %q, want %q", tt.antType, got, tt.want)
+		}
+	}
+
+	/* receiver antenna: east/north/up offsets and the grid of L1, L2 to L3 */
+	rcv := pcvs.SearchPcv(0, "TRM59800.00 NONE", t0)
+	if !near(rcv.Offset[0][:], []float64{0.00045, 0.00128, 0.06623}) || rcv.Dazi != 5.0 ||
+		len(rcv.AziVar[0]) != 73 || len(rcv.AziVar[2]) != 73 || rcv.Offset[2] != rcv.Offset[1] {
+		t.Fatalf("receiver antenna: off=%v dazi=%.1f rows=%d", rcv.Offset, rcv.Dazi, len(rcv.AziVar[0]))
+	}
+	var del, dant, dant0 [NFREQ]float64
+	for _, tt := range []struct {
+		az, el float64
+	}{
+		{47.5, 62.5}, {-10.0, 33.3}, {358.0, 90.0}, {181.2, 7.0},
+	} {
+		azel := []float64{tt.az * D2R, tt.el * D2R}
+		AntModel(rcv, del[:], azel, 1, dant[:])
+		AntModel(rcv, del[:], azel, 0, dant0[:])
+		az := math.Mod(tt.az+360.0, 360.0)
+		if want := atxTRM59800(az, 90.0-tt.el) * 1e-3; math.Abs(dant[0]-dant0[0]-want) > 1e-9 {
+			t.Errorf("az=%.1f el=%.1f: pcv got %.6f, want %.6f", tt.az, tt.el, dant[0]-dant0[0], want)
+		}
+	}
+	scis := pcvs.SearchPcv(0, "TRM59800.00 SCIS", t0)
+	AntModel(scis, del[:], []float64{123.0 * D2R, 62.5 * D2R}, 1, dant[:])
+	AntModel(scis, del[:], []float64{123.0 * D2R, 62.5 * D2R}, 0, dant0[:])
+	if want := -0.1 * 27.5 / 5.0 * 1e-3; math.Abs(dant[1]-dant0[1]-want) > 1e-9 {
+		t.Errorf("noazi pcv: got %.6f, want %.6f", dant[1]-dant0[1], want)
+	}
+
+	for _, text := range []string{"", "     1.4            M                                       ANTEX VERSION / SYST\n"} {
+		file := filepath.Join(t.TempDir(), "bad.atx")
+		if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadANTEX(file); err == nil {
+			t.Errorf("%q: want error", text)
+		}
+	}
+	if _, err := ReadANTEX(filepath.Join(t.TempDir(), "none.atx")); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
@@ -0,0 +1,153 @@
+/*------------------------------------------------------------------------------
+* convcsv.go : RINEX observation to CSV converter
+*
+*          the observation data are output one row per satellite and epoch for
+*          spreadsheets and analysis scripts.
+*-----------------------------------------------------------------------------*/
+
+package gnssgo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* CSV output options --------------------------------------------------------*/
+type CSVOpt struct {
+	Systems   int      /* satellite systems (SYS_???) (0: all) */
+	Codes     []string /* observation types ("C1C","L1C",...) (nil: all in header) */
+	Separator string   /* field separator ("": ",") */
+}
+
+/* convert RINEX observation file to CSV ---------------------------------------
+* convert RINEX observation file to CSV file
+* args   : string rnxPath   I   RINEX observation file path
+*          string csvPath   I   output CSV file path
+*          CSVOpt opt       I   CSV output options
+* return : error
+* notes  : the header row is epoch, system, PRN and the observation types.
+*          the epoch is output as ISO 8601 time in GPST
+*          (yyyy-mm-ddThh:mm:ss.sss).
+*          pseudorange (m), carrier-phase (cycle), doppler (Hz) and SNR (dBHz)
+*          are output with 3 decimals, the missing observations as empty.
+*          only the observation codes selected by RnxObsReader (NFREQ+NEXOBS
+*          per satellite) are output.
+*-----------------------------------------------------------------------------*/
+func RnxObsToCSV(rnxPath, csvPath string, opt CSVOpt) error {
+	var (
+		ep  [6]float64
+		id  string
+		prn int
+	)
+	Trace(3, "rnxobstocsv: rnx=%s csv=%s\n", rnxPath, csvPath)
+
+	sep := opt.Separator
+	if sep == "" {
+		sep = ","
+	}
+	r, err := OpenRnxObs(rnxPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	codes := opt.Codes
+	if len(codes) == 0 {
+		codes = csvobstypes(r.Header(), opt.Systems)
+	}
+	fp, err := os.Create(csvPath)
+	if err != nil {
+		return fmt.Errorf("csv file open error: %w", err)
+	}
+	w := bufio.NewWriter(fp)
+	w.WriteString(strings.Join(append([]string{"epoch", "system", "prn"}, codes...), sep) + "\n")
+
+	fields := make([]string, 3+len(codes))
+	for {
+		data, time, err := r.NextEpoch()
+		if err == io.EOF {
+			break
+		}
+		Time2Epoch(time, ep[:])
+		fields[0] = fmt.Sprintf("%04.0f-%02.0f-%02.0fT%02.0f:%02.0f:%06.3f", ep[0], ep[1], ep[2],
+			ep[3], ep[4], ep[5])
+
+		for i := range data {
+			sys := SatSys(data[i].Sat, &prn)
+			if opt.Systems != 0 && sys&opt.Systems == 0 {
+				continue
+			}
+			SatNo2Id(data[i].Sat, &id)
+			fields[1], fields[2] = id[:1], strconv.Itoa(prn)
+			for j, code := range codes {
+				fields[3+j] = csvobsvalue(&data[i], code)
+			}
+			w.WriteString(strings.Join(fields, sep) + "\n")
+		}
+	}
+	if err = w.Flush(); err != nil {
+		fp.Close()
+		return fmt.Errorf("csv file write error: %w", err)
+	}
+	if err = fp.Close(); err != nil {
+		return fmt.Errorf("csv file write error: %w", err)
+	}
+	return nil
+}
+
+/* observation types of RINEX header in system order -------------------------*/
+func csvobstypes(hdr RnxObsHeader, systems int) []string {
+	var codes []string
+
+	for i := 0; navsys[i] > 0; i++ {
+		if systems != 0 && navsys[i]&systems == 0 {
+			continue
+		}
+		for _, tobs := range hdr.ObsTypes[navsys[i]] {
+			j := 0
+			for ; j < len(codes); j++ {
+				if codes[j] == tobs {
+					break
+				}
+			}
+			if j == len(codes) {
+				codes = append(codes, tobs)
+			}
+		}
+	}
+	return codes
+}
+
+/* observation value of observation type as CSV field ------------------------*/
+func csvobsvalue(data *ObsD, tobs string) string {
+	var v float64
+
+	if len(tobs) < 3 {
+		return ""
+	}
+	code := Obs2Code(tobs[1:])
+	for k := 0; k < NFREQ+NEXOBS; k++ {
+		if code == CODE_NONE || data.Code[k] != code {
+			continue
+		}
+		switch tobs[0] {
+		case 'C':
+			v = data.P[k]
+		case 'L':
+			v = data.L[k]
+		case 'D':
+			v = float64(data.D[k])
+		case 'S':
+			v = float64(data.SNR[k]) * SNR_UNIT
+		}
+		break
+	}
+	if v == 0.0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
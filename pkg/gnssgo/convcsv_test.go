@@ -0,0 +1,77 @@
+package gnssgo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRnxObsToCSV exports a RINEX ver.3 file of GPS and Galileo satellites
+// and checks the header row and the data rows with missing observables.
+func TestRnxObsToCSV(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(rnxHeaderLine("     3.04           OBSERVATION DATA    M", "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine("G    4 C1C L1C D1C S1C", "SYS / # / OBS TYPES"))
+	b.WriteString(rnxHeaderLine("E    3 C1C L1C S1C", "SYS / # / OBS TYPES"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	obsline := func(sat string, v ...float64) string {
+		line := sat
+		for _, x := range v {
+			if x == 0.0 {
+				line += strings.Repeat(" ", 16)
+			} else {
+				line += fmt.Sprintf("%14.3f  ", x)
+			}
+		}
+		return line + "\n"
+	}
+	b.WriteString("> 2024 01 01 00 00 30.0000000  0  2\n")
+	b.WriteString(obsline("G05", 21000000.123, 110357000.456, -1234.5, 45.25))
+	b.WriteString(obsline("E11", 23000000.789, 120867000.0, 38.0))
+	b.WriteString("> 2024 01 01 00 01  0.0000000  0  1\n")
+	b.WriteString(obsline("G05", 21000037.0, 0.0, -1234.25, 44.0))
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.rnx")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		opt  CSVOpt
+		want []string
+	}{
+		{CSVOpt{}, []string{
+			"epoch,system,prn,C1C,L1C,D1C,S1C",
+			"2024-01-01T00:00:30.000,G,5,21000000.123,110357000.456,-1234.500,45.250",
+			"2024-01-01T00:00:30.000,E,11,23000000.789,120867000.000,,38.000",
+			"2024-01-01T00:01:00.000,G,5,21000037.000,,-1234.250,44.000",
+		}},
+		{CSVOpt{Systems: SYS_GAL, Separator: ";"}, []string{
+			"epoch;system;prn;C1C;L1C;S1C",
+			"2024-01-01T00:00:30.000;E;11;23000000.789;120867000.000;38.000",
+		}},
+		{CSVOpt{Codes: []string{"S1C", "C5Q"}}, []string{
+			"epoch,system,prn,S1C,C5Q",
+			"2024-01-01T00:00:30.000,G,5,45.250,",
+			"2024-01-01T00:00:30.000,E,11,38.000,",
+			"2024-01-01T00:01:00.000,G,5,44.000,",
+		}},
+	} {
+		csv := filepath.Join(dir, "test.csv")
+		if err := RnxObsToCSV(file, csv, c.opt); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(csv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("opt %+v:\n got %q\nwant %q", c.opt, got, c.want)
+		}
+	}
+	if err := RnxObsToCSV(filepath.Join(dir, "missing.rnx"), filepath.Join(dir, "x.csv"), CSVOpt{}); err == nil {
+		t.Error("missing file: want error")
+	}
+}
@@ -15,7 +15,9 @@
 package gnssgo
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -26,6 +28,16 @@ const TAILGPX string = "</gpx>"
 
 const XMLNS string = "http://www.topografix.com/GPX/1/1"
 
+const XMLNS_GNSSGO string = "https://github.com/bramburn/gnssgo" /* namespace of extensions */
+
+/* GPX output options --------------------------------------------------------*/
+type GPXOpt struct {
+	Creator      string /* creator of document ("":gnssgo) */
+	IncludeNoFix bool   /* output points of no solution (SOLQ_NONE) */
+	Geodetic     bool   /* output geodetic height and geoid height instead of ellipsoidal */
+	Extensions   bool   /* output fix quality and number of satellites as extensions */
+}
+
 /* output waypoint -----------------------------------------------------------*/
 func OutPoint(fp *os.File, time Gtime, pos []float64, label string, stat, outalt, outtime int) {
 	/* fix, float, sbas and ppp are rtklib extentions to GPX */
@@ -213,3 +225,76 @@ func ConvGpx(infile, outfile string, ts, te Gtime, tint float64, qflg int, offse
 		return -4
 	}
 }
+
+/* write solutions as GPX track ------------------------------------------------
+* write the solutions as a GPX 1.1 document with a track of one segment [1]
+* args   : io.Writer w      I   output
+*          sol_t  *sols     I   solutions, position in ecef (Type=0)
+*          GPXOpt opt       I   output options
+* return : error of writing (nil: ok)
+* notes  : the time of the track points is in UTC.
+*          with opt.Extensions, the fix quality (Sol.QName) and the number of
+*          valid satellites are output as <gnssgo:quality> and <gnssgo:ns> in
+*          <extensions> of the track points.
+*          points of no solution are skipped unless opt.IncludeNoFix is set.
+*-----------------------------------------------------------------------------*/
+func WriteGPX(w io.Writer, sols []Sol, opt GPXOpt) error {
+	var (
+		b       bytes.Buffer
+		pos     [3]float64
+		creator = opt.Creator
+	)
+	if creator == "" {
+		creator = "gnssgo"
+	}
+	b.WriteString(HEADXML)
+	fmt.Fprintf(&b, "<gpx version=\"1.1\" creator=\"%s\" xmlns=\"%s\"", xmlEscape(creator), XMLNS)
+	if opt.Extensions {
+		fmt.Fprintf(&b, " xmlns:gnssgo=\"%s\"", XMLNS_GNSSGO)
+	}
+	b.WriteString(">\n<trk>\n <trkseg>\n")
+
+	for i := range sols {
+		sol := &sols[i]
+		if sol.Stat == SOLQ_NONE && !opt.IncludeNoFix {
+			continue
+		}
+		Ecef2Pos(sol.Rr[:], pos[:])
+		fmt.Fprintf(&b, "  <trkpt lat=\"%.9f\" lon=\"%.9f\">\n", pos[0]*R2D, pos[1]*R2D)
+		h := 0.0
+		if opt.Geodetic {
+			h = GeoidH(pos[:])
+		}
+		fmt.Fprintf(&b, "   <ele>%.4f</ele>\n", pos[2]-h)
+		fmt.Fprintf(&b, "   <time>%s</time>\n", xmlTime(sol.Time))
+		if opt.Geodetic {
+			fmt.Fprintf(&b, "   <geoidheight>%.4f</geoidheight>\n", h)
+		}
+		if opt.Extensions {
+			fmt.Fprintf(&b, "   <extensions>\n    <gnssgo:quality>%s</gnssgo:quality>\n", sol.QName())
+			fmt.Fprintf(&b, "    <gnssgo:ns>%d</gnssgo:ns>\n   </extensions>\n", sol.Ns)
+		}
+		b.WriteString("  </trkpt>\n")
+	}
+	b.WriteString(" </trkseg>\n</trk>\n" + TAILGPX + "\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+/* escape xml special characters ---------------------------------------------*/
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;").Replace(s)
+}
+
+/* xml date-time in utc of gpst ----------------------------------------------*/
+func xmlTime(t Gtime) string {
+	var ep [6]float64
+	time := GpsT2Utc(t)
+	if time.Sec >= 0.995 {
+		time.Time++
+		time.Sec = 0.0
+	}
+	Time2Epoch(time, ep[:])
+	return fmt.Sprintf("%04.0f-%02.0f-%02.0fT%02.0f:%02.0f:%05.2fZ", ep[0], ep[1], ep[2], ep[3], ep[4], ep[5])
+}
@@ -0,0 +1,72 @@
+package gnssgo
+
+import (
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+)
+
+// gpxDoc is the part of a GPX document checked by the tests.
+type gpxDoc struct {
+	XMLName xml.Name `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Points  []struct {
+		Lat     float64 `xml:"lat,attr"`
+		Lon     float64 `xml:"lon,attr"`
+		Ele     float64 `xml:"ele"`
+		Time    string  `xml:"time"`
+		Quality string  `xml:"extensions>quality"`
+		Ns      int     `xml:"extensions>ns"`
+	} `xml:"trk>trkseg>trkpt"`
+}
+
+// TestWriteGPX writes a fixed and a single solution as a GPX track and checks
+// the document structure and the geodetic coordinates of the points.
+func TestWriteGPX(t *testing.T) {
+	llh := [][3]float64{{35.6812, 139.7671, 40.0}, {-33.8568, -70.6483, 550.25}}
+	sols := make([]Sol, 3)
+	for i, p := range llh {
+		Pos2Ecef([]float64{p[0] * D2R, p[1] * D2R, p[2]}, sols[i].Rr[:])
+		sols[i].Time = Epoch2Time([]float64{2024, 3, 1, 12, 0, 18.0 + float64(i)})
+		sols[i].Ns = uint8(10 + i)
+	}
+	sols[0].Stat, sols[1].Stat, sols[2].Stat = SOLQ_FIX, SOLQ_SINGLE, SOLQ_NONE
+
+	var b strings.Builder
+	if err := WriteGPX(&b, sols, GPXOpt{Creator: "survey & test", Extensions: true}); err != nil {
+		t.Fatal(err)
+	}
+	var doc gpxDoc
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, b.String())
+	}
+	if doc.Version != "1.1" || doc.Creator != "survey & test" || len(doc.Points) != 2 {
+		t.Fatalf("document: version=%q creator=%q points=%d", doc.Version, doc.Creator, len(doc.Points))
+	}
+	want := []struct {
+		time, quality string
+	}{{"2024-03-01T12:00:00.00Z", "FIX"}, {"2024-03-01T12:00:01.00Z", "SINGLE"}}
+	for i, p := range doc.Points {
+		if math.Abs(p.Lat-llh[i][0]) > 1e-9 || math.Abs(p.Lon-llh[i][1]) > 1e-9 || math.Abs(p.Ele-llh[i][2]) > 1e-4 {
+			t.Errorf("point %d: lat=%.9f lon=%.9f ele=%.4f, want %v", i, p.Lat, p.Lon, p.Ele, llh[i])
+		}
+		if p.Time != want[i].time || p.Quality != want[i].quality || p.Ns != 10+i {
+			t.Errorf("point %d: time=%s quality=%s ns=%d", i, p.Time, p.Quality, p.Ns)
+		}
+	}
+
+	/* no solution points and no extensions */
+	b.Reset()
+	if err := WriteGPX(&b, sols, GPXOpt{IncludeNoFix: true}); err != nil {
+		t.Fatal(err)
+	}
+	doc = gpxDoc{}
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if len(doc.Points) != 3 || doc.Creator != "gnssgo" || strings.Contains(b.String(), "extensions") {
+		t.Errorf("include no fix: points=%d creator=%q\n%s", len(doc.Points), doc.Creator, b.String())
+	}
+}
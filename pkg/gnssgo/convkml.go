@@ -24,7 +24,9 @@
 package gnssgo
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"strings"
@@ -42,6 +44,135 @@ const (
 	mark  = "http://maps.google.com/mapfiles/kml/pal2/icon18.png"
 )
 
+const (
+	XMLNS_KML = "http://www.opengis.net/kml/2.2"    /* namespace of KML 2.2 */
+	XMLNS_GX  = "http://www.google.com/kml/ext/2.2" /* namespace of google extensions */
+)
+
+/* KML output options --------------------------------------------------------*/
+type KMLOpt struct {
+	Name        string /* document name ("":gnssgo) */
+	Points      bool   /* output point placemarks instead of line strings */
+	TimeStamped bool   /* output time-animated gx:Track instead of line strings */
+	Geodetic    bool   /* output geodetic height instead of ellipsoidal */
+}
+
+/* KML style id and color (aabbggrr) of solution status ----------------------*/
+func kmlStyle(stat uint8) (string, string) {
+	switch stat {
+	case SOLQ_FIX:
+		return "fix", "ff00ff00" /* green */
+	case SOLQ_FLOAT:
+		return "float", "ff00ffff" /* yellow */
+	case SOLQ_SINGLE:
+		return "single", "ff0000ff" /* red */
+	case SOLQ_DGPS, SOLQ_SBAS:
+		return "dgps", "ff00aaff" /* orange */
+	case SOLQ_PPP:
+		return "ppp", "ffff00ff" /* magenta */
+	}
+	return "other", "ffffffff" /* white */
+}
+
+/* write solutions as KML ------------------------------------------------------
+* write the solutions as a KML 2.2 document [1] colored by solution status
+* args   : io.Writer w      I   output
+*          sol_t  *sols     I   solutions, position in ecef (Type=0)
+*          KMLOpt opt       I   output options
+* return : error of writing (nil: ok)
+* notes  : the track is split into placemarks of consecutive solutions of the
+*          same status, each starting at the last point of the previous one so
+*          the track is continuous. the placemarks refer to the styles by
+*          status: fix (green), float (yellow), single (red), dgps (orange),
+*          ppp (magenta) and other (white).
+*          with opt.TimeStamped, the placemarks are gx:Track with the time
+*          in UTC. with opt.Points, each solution is a point placemark with a
+*          time stamp. points of no solution are skipped.
+*-----------------------------------------------------------------------------*/
+func WriteKML(w io.Writer, sols []Sol, opt KMLOpt) error {
+	var (
+		b    bytes.Buffer
+		pos  [][3]float64
+		used = map[string]bool{}
+		name = opt.Name
+	)
+	if name == "" {
+		name = "gnssgo"
+	}
+	var valid []*Sol
+	for i := range sols {
+		if sols[i].Stat == SOLQ_NONE {
+			continue
+		}
+		var p [3]float64
+		Ecef2Pos(sols[i].Rr[:], p[:])
+		if opt.Geodetic {
+			p[2] -= GeoidH(p[:])
+		}
+		valid = append(valid, &sols[i])
+		pos = append(pos, p)
+	}
+
+	fmt.Fprintf(&b, "%s\n<kml xmlns=\"%s\" xmlns:gx=\"%s\">\n", head1, XMLNS_KML, XMLNS_GX)
+	fmt.Fprintf(&b, "<Document>\n<name>%s</name>\n", xmlEscape(name))
+	for _, sol := range valid {
+		id, color := kmlStyle(sol.Stat)
+		if used[id] {
+			continue
+		}
+		used[id] = true
+		fmt.Fprintf(&b, "<Style id=\"%s\">\n", id)
+		fmt.Fprintf(&b, "  <LineStyle><color>%s</color><width>3</width></LineStyle>\n", color)
+		fmt.Fprintf(&b, "  <IconStyle><color>%s</color><scale>%.1f</scale><Icon><href>%s</href></Icon></IconStyle>\n",
+			color, SIZP, mark)
+		b.WriteString("</Style>\n")
+	}
+
+	if opt.Points {
+		for i, sol := range valid {
+			id, _ := kmlStyle(sol.Stat)
+			fmt.Fprintf(&b, "<Placemark>\n<styleUrl>#%s</styleUrl>\n", id)
+			fmt.Fprintf(&b, "<TimeStamp><when>%s</when></TimeStamp>\n", xmlTime(sol.Time))
+			b.WriteString("<Point>\n<altitudeMode>absolute</altitudeMode>\n")
+			fmt.Fprintf(&b, "<coordinates>%.9f,%.9f,%.3f</coordinates>\n", pos[i][1]*R2D, pos[i][0]*R2D, pos[i][2])
+			b.WriteString("</Point>\n</Placemark>\n")
+		}
+	} else {
+		for i := 0; i < len(valid); {
+			/* segment of the same status, from the last point of the previous one */
+			j := i + 1
+			for j < len(valid) && valid[j].Stat == valid[i].Stat {
+				j++
+			}
+			k := max(i-1, 0)
+			id, _ := kmlStyle(valid[i].Stat)
+			fmt.Fprintf(&b, "<Placemark>\n<name>%s</name>\n<styleUrl>#%s</styleUrl>\n", strings.ToUpper(id), id)
+			if opt.TimeStamped {
+				b.WriteString("<gx:Track>\n<altitudeMode>absolute</altitudeMode>\n")
+				for n := k; n < j; n++ {
+					fmt.Fprintf(&b, "<when>%s</when>\n", xmlTime(valid[n].Time))
+				}
+				for n := k; n < j; n++ {
+					fmt.Fprintf(&b, "<gx:coord>%.9f %.9f %.3f</gx:coord>\n", pos[n][1]*R2D, pos[n][0]*R2D, pos[n][2])
+				}
+				b.WriteString("</gx:Track>\n")
+			} else {
+				b.WriteString("<LineString>\n<altitudeMode>absolute</altitudeMode>\n<coordinates>\n")
+				for n := k; n < j; n++ {
+					fmt.Fprintf(&b, "%.9f,%.9f,%.3f\n", pos[n][1]*R2D, pos[n][0]*R2D, pos[n][2])
+				}
+				b.WriteString("</coordinates>\n</LineString>\n")
+			}
+			b.WriteString("</Placemark>\n")
+			i = j
+		}
+	}
+	b.WriteString("</Document>\n</kml>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
 /* output track --------------------------------------------------------------*/
 func OutTrackKml(f *os.File, solbuf *SolBuf, color string, outalt, outtime int) {
 	var pos [3]float64
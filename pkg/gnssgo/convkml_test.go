@@ -0,0 +1,93 @@
+package gnssgo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// kmlDoc is the part of a KML document checked by the tests.
+type kmlDoc struct {
+	XMLName xml.Name `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Styles  []struct {
+		ID    string `xml:"id,attr"`
+		Color string `xml:"LineStyle>color"`
+	} `xml:"Document>Style"`
+	Placemarks []struct {
+		Style  string   `xml:"styleUrl"`
+		Coords string   `xml:"LineString>coordinates"`
+		When   []string `xml:"Track>when"`
+		Coord  []string `xml:"Track>coord"`
+		Point  string   `xml:"Point>coordinates"`
+	} `xml:"Document>Placemark"`
+}
+
+// TestWriteKML writes a track of fixed, float and single solutions and checks
+// the styles by status and the coordinate tuples of the placemarks.
+func TestWriteKML(t *testing.T) {
+	llh := [][3]float64{
+		{35.6812, 139.7671, 40.0}, {35.6813, 139.7672, 40.5}, {35.6814, 139.7673, 41.0},
+		{35.6815, 139.7674, 41.5}, {35.6816, 139.7675, 42.0},
+	}
+	stat := []uint8{SOLQ_FIX, SOLQ_FIX, SOLQ_FLOAT, SOLQ_NONE, SOLQ_SINGLE}
+	sols := make([]Sol, len(llh))
+	tuple := make([]string, len(llh))
+	for i, p := range llh {
+		Pos2Ecef([]float64{p[0] * D2R, p[1] * D2R, p[2]}, sols[i].Rr[:])
+		sols[i].Time = Epoch2Time([]float64{2024, 3, 1, 12, 0, 18.0 + float64(i)})
+		sols[i].Stat = stat[i]
+		tuple[i] = fmt.Sprintf("%.9f,%.9f,%.3f", p[1], p[0], p[2])
+	}
+	parse := func(opt KMLOpt) kmlDoc {
+		var b strings.Builder
+		if err := WriteKML(&b, sols, opt); err != nil {
+			t.Fatal(err)
+		}
+		var doc kmlDoc
+		if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+			t.Fatalf("invalid xml: %v\n%s", err, b.String())
+		}
+		return doc
+	}
+
+	doc := parse(KMLOpt{})
+	styles := map[string]string{}
+	for _, s := range doc.Styles {
+		styles[s.ID] = s.Color
+	}
+	want := map[string]string{"fix": "ff00ff00", "float": "ff00ffff", "single": "ff0000ff"}
+	if fmt.Sprint(styles) != fmt.Sprint(want) {
+		t.Errorf("styles: got %v, want %v", styles, want)
+	}
+	segs := []struct {
+		style  string
+		points []int
+	}{{"#fix", []int{0, 1}}, {"#float", []int{1, 2}}, {"#single", []int{2, 4}}}
+	if len(doc.Placemarks) != len(segs) {
+		t.Fatalf("placemarks: got %d, want %d", len(doc.Placemarks), len(segs))
+	}
+	for i, seg := range segs {
+		pm := doc.Placemarks[i]
+		var coords []string
+		for _, n := range seg.points {
+			coords = append(coords, tuple[n])
+		}
+		if pm.Style != seg.style || strings.Join(strings.Fields(pm.Coords), " ") != strings.Join(coords, " ") {
+			t.Errorf("placemark %d: style %s coordinates %q, want %s %v", i, pm.Style, pm.Coords, seg.style, coords)
+		}
+	}
+
+	/* time-animated track */
+	doc = parse(KMLOpt{TimeStamped: true})
+	if pm := doc.Placemarks[2]; len(pm.When) != 2 || len(pm.Coord) != 2 ||
+		pm.When[1] != "2024-03-01T12:00:04.00Z" || pm.Coord[1] != strings.ReplaceAll(tuple[4], ",", " ") {
+		t.Errorf("gx:Track: when %v coord %v", pm.When, pm.Coord)
+	}
+
+	/* point placemarks */
+	doc = parse(KMLOpt{Points: true})
+	if len(doc.Placemarks) != 4 || doc.Placemarks[3].Point != tuple[4] || doc.Placemarks[3].Style != "#single" {
+		t.Errorf("points: %+v", doc.Placemarks)
+	}
+}
@@ -389,6 +389,7 @@ func SetOptObsType(codes []uint8, types []uint8, sys int, opt *RnxOpt) {
 		id = Code2Obs(codes[i])
 		idx = Code2Idx(navsys[sys], codes[i])
 		if len(id) == 0 || idx < 0 {
+			Trace(2, "unknown obs code skipped: sys=%d code=%d\n", navsys[sys], codes[i])
 			continue
 		}
 		if opt.FreqType&(1<<idx) == 0 || opt.Mask[sys][codes[i]-1] == '0' {
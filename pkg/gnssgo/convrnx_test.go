@@ -0,0 +1,101 @@
+package gnssgo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestConvRnxVer305 converts RTCM 3 BeiDou MSM7 with B1I, B2b and B2a
+// signals to RINEX 3.05 and checks the observation types of the header.
+func TestConvRnxVer305(t *testing.T) {
+	var (
+		enc  Rtcm
+		buff []uint8
+	)
+	t0 := Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	codes := []uint8{CODE_L2I, CODE_L7D, CODE_L5P}
+	freqs := []float64{FREQ1_CMP, FREQ2_CMP, FREQ5}
+
+	enc.InitRtcm()
+	for k := 0; k < 3; k++ {
+		enc.Time = TimeAdd(t0, float64(k))
+		enc.ObsData.Data = nil
+		for prn := 19; prn <= 23; prn++ {
+			d := ObsD{Time: enc.Time, Sat: SatNo(SYS_CMP, prn), Rcv: 1}
+			P := 22000000.0 + 1000.0*float64(prn) + 100.0*float64(k)
+			for f := range codes {
+				d.Code[f] = codes[f]
+				d.P[f] = P
+				d.L[f] = P / (CLIGHT / freqs[f])
+				d.SNR[f] = uint16(45.0 / SNR_UNIT)
+			}
+			enc.ObsData.Data = append(enc.ObsData.Data, d)
+		}
+		buff = append(buff, synthRtcm3(t, &enc, 1127)...)
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "b2a.rtcm3")
+	if err := os.WriteFile(file, buff, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var opt RnxOpt
+	opt.RnxVer = 305
+	opt.NavSys = SYS_CMP
+	opt.ObsType = OBSTYPE_PR | OBSTYPE_CP
+	opt.FreqType = 0x1F
+	opt.TRtcm = t0
+	for i := range opt.Mask {
+		for j := range opt.Mask[i] {
+			opt.Mask[i][j] = '1'
+		}
+	}
+	ofile := make([]string, 9)
+	ofile[0] = filepath.Join(dir, "b2a.obs")
+	if ConvRnx(STRFMT_RTCM3, &opt, file, ofile) != 1 {
+		t.Fatal("convrnx error")
+	}
+	data, err := os.ReadFile(ofile[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ver, types string
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.Contains(line, "RINEX VERSION / TYPE"):
+			ver = strings.TrimSpace(line[:9])
+		case strings.Contains(line, "SYS / # / OBS TYPES"):
+			types = strings.Join(strings.Fields(line[:60]), " ")
+		}
+	}
+	if ver != "3.05" || types != "C 6 C2I L2I C7D L7D C5P L5P" {
+		t.Errorf("header version %q obs types %q", ver, types)
+	}
+
+	r, err := OpenRnxObs(ofile[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	nep := 0
+	for {
+		obs, _, err := r.NextEpoch()
+		if err != nil {
+			break
+		}
+		for _, d := range obs {
+			for f := range codes {
+				if d.Code[f] != codes[f] || d.P[f] == 0.0 || d.L[f] == 0.0 {
+					t.Errorf("epoch %d sat %d freq %d code %s P %.3f L %.3f", nep, d.Sat, f,
+						Code2Obs(d.Code[f]), d.P[f], d.L[f])
+				}
+			}
+		}
+		nep++
+	}
+	if nep != 3 {
+		t.Errorf("epochs %d, want 3", nep)
+	}
+}
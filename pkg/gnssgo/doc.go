@@ -52,12 +52,54 @@
 //   - RtkSvr.RtkSvrStart: Starts the RTK server
 //     Begin continuous positioning with data streams
 //
+//   - RtkSvr.SetSolutionOutput: Writes the solutions of the RTK server
+//     Output LLH, ECEF, NMEA or .pos records to a Stream, file or callback
+//
 //   - ConvRnx: Converts receiver raw data to RINEX format
 //     Convert raw receiver data to standard RINEX format
 //
 //   - ReadRnx: Reads RINEX files
 //     Parse RINEX observation and navigation files
 //
+//   - ReadSP3: Reads SP3 precise orbit files
+//     Set the precise ephemeris used by SatPoss with EPHOPT_PREC
+//
+//   - OpenRnxObs: Reads RINEX observation files epoch by epoch
+//     Process long high-rate files with bounded memory
+//
+//   - MergeRnxObs: Merges RINEX observation files into a RINEX 3 file
+//     Join overlapping sessions with duplicated epochs removed
+//
+//   - RnxObsToCSV: Exports RINEX observations as CSV
+//     One row per satellite and epoch for spreadsheets and scripts
+//
+//   - ReadRnxClk: Reads RINEX clock files
+//     Set the precise clocks, interpolated by SatClk
+//
+//   - ReadTEC: Reads IONEX ionosphere TEC maps
+//     Compute the slant ionospheric delay by IonTEC
+//
+//   - ReadANTEX: Reads ANTEX antenna phase center files
+//     Apply receiver antenna offsets and variations by PrcOpt.PcvList
+//
+//   - Sol.ToGGA, Sol.ToRMC: Format a solution as NMEA sentences
+//     Output positions to mapping tools and NTRIP casters
+//
+//   - Sol.Baseline, Sol.BaselineLength, Sol.BaselineNED: Rover-base baseline
+//     Check distance-dependent error growth of RTK solutions
+//
+//   - NewSurveyIn: Survey-in of a static base station position
+//     Average single point positions until the mean reaches a given accuracy
+//
+//   - WriteGPX, WriteKML: Write solutions as GPX or KML tracks
+//     Import the rover path into GIS tools, KML colored by solution status
+//
+//   - LoadGeoid, GeoidHeight: Geoid undulation of EGM96/EGM2008 models
+//     Orthometric height and the GGA geoid separation
+//
+//   - Pos2UTM, UTM2Pos, Pos2MGRS: Convert between geodetic, UTM and MGRS
+//     Grid coordinates for surveying and mapping output
+//
 //   - Stream.OpenStream: Opens a communication stream
 //     Establish communication with receivers or other data sources
 //
@@ -508,6 +508,41 @@ func SEph2Pos(time Gtime, seph *SEph, rs []float64, dts, vari *float64) {
 	*vari = var_uraeph(SYS_SBS, seph.Sva)
 }
 
+/* single satellite position by broadcast ephemeris ---------------------------
+* compute satellite position and clock bias of a broadcast ephemeris without
+* navigation data (gps, galileo, beidou, qzss)
+* args   : eph_t  *eph      I   broadcast ephemeris
+*          gtime_t t        I   time (gpst)
+* return : satellite position {x,y,z} (ecef) (m), clock bias (s) and position
+*          and clock variance (m^2) (all 0 if no ephemeris)
+* notes  : same as Eph2Pos. the validity of the ephemeris is not tested
+*-----------------------------------------------------------------------------*/
+func EphSatPos(eph *Eph, t Gtime) (pos [3]float64, clk float64, vari float64) {
+	if eph == nil {
+		return
+	}
+	Eph2Pos(t, eph, pos[:], &clk, &vari)
+	return
+}
+
+/* single satellite position by glonass ephemeris ------------------------------
+* compute satellite position and clock bias of a glonass ephemeris without
+* navigation data
+* args   : geph_t *geph     I   glonass ephemeris
+*          gtime_t t        I   time (gpst)
+* return : satellite position {x,y,z} (ecef) (m), clock bias (s) and position
+*          and clock variance (m^2) (all 0 if no ephemeris)
+* notes  : same as GEph2Pos. the orbit is integrated from toe by the step of
+*          TSTEP
+*-----------------------------------------------------------------------------*/
+func GephSatPos(geph *GEph, t Gtime) (pos [3]float64, clk float64, vari float64) {
+	if geph == nil {
+		return
+	}
+	GEph2Pos(t, geph, pos[:], &clk, &vari)
+	return
+}
+
 /* select ephememeris --------------------------------------------------------*/
 func (nav *Nav) SelEph(time Gtime, sat, iode int) *Eph {
 	var (
@@ -0,0 +1,119 @@
+package gnssgo
+
+import (
+	"math"
+	"testing"
+)
+
+// TestEphSatPos compares the positions of circular Kepler orbits with the
+// analytic reference and the positions by SatPos with the navigation data.
+func TestEphSatPos(t *testing.T) {
+	toe := Epoch2Time([]float64{2023, 6, 1, 2, 0, 0})
+	tests := []struct {
+		sat      int
+		a        float64
+		mu, omge float64
+		geo      bool
+	}{
+		{SatNo(SYS_GPS, 5), 26559710.0, MU_GPS, OMGE, false},
+		{SatNo(SYS_GAL, 11), 29600000.0, MU_GAL, OMGE_GAL, false},
+		{SatNo(SYS_QZS, 193), 42164000.0, MU_GPS, OMGE, false},
+		{SatNo(SYS_CMP, 30), 27906000.0, MU_CMP, OMGE_CMP, false},
+		{SatNo(SYS_CMP, 3), 42164000.0, MU_CMP, OMGE_CMP, true},
+	}
+	for _, tt := range tests {
+		eph := synthEph(tt.sat, 7, toe)
+		eph.A, eph.E, eph.F0, eph.F1, eph.Sva = tt.a, 0.0, 1e-4, 1e-11, 2
+		if SatSys(tt.sat, nil) == SYS_GAL {
+			eph.Code, eph.Sva = 1<<9, 60 /* I/NAV, sisa 0.7 m */
+		}
+		nav := &Nav{Ephs: []Eph{eph}}
+
+		for _, dt := range []float64{0.0, 1234.5, -3600.0} {
+			tm := TimeAdd(toe, dt)
+			pos, clk, vari := EphSatPos(&eph, tm)
+
+			/* circular orbit: r=a, u=M0+n*tk+omg */
+			u := eph.M0 + math.Sqrt(tt.mu/(tt.a*tt.a*tt.a))*dt + eph.Omg
+			x, y := tt.a*math.Cos(u), tt.a*math.Sin(u)
+			var want [3]float64
+			if tt.geo {
+				O := eph.OMG0 - tt.omge*eph.Toes
+				g := [3]float64{x*math.Cos(O) - y*math.Cos(eph.I0)*math.Sin(O),
+					x*math.Sin(O) + y*math.Cos(eph.I0)*math.Cos(O), y * math.Sin(eph.I0)}
+				f, p := tt.omge*dt, -5.0*D2R
+				gy, gz := g[1]*math.Cos(p)+g[2]*math.Sin(p), -g[1]*math.Sin(p)+g[2]*math.Cos(p)
+				want = [3]float64{g[0]*math.Cos(f) + gy*math.Sin(f), -g[0]*math.Sin(f) + gy*math.Cos(f), gz}
+			} else {
+				O := eph.OMG0 - tt.omge*(eph.Toes+dt)
+				want = [3]float64{x*math.Cos(O) - y*math.Cos(eph.I0)*math.Sin(O),
+					x*math.Sin(O) + y*math.Cos(eph.I0)*math.Cos(O), y * math.Sin(eph.I0)}
+			}
+			if d := synthDist(pos[:], want[:]); d > 1e-4 {
+				t.Errorf("sat=%d dt=%.1f: position error %.6f m", tt.sat, dt, d)
+			}
+			if want := 1e-4 + 1e-11*dt; math.Abs(clk-want) > 1e-15 {
+				t.Errorf("sat=%d dt=%.1f: clock got %.15f, want %.15f", tt.sat, dt, clk, want)
+			}
+			if vari <= 0.0 {
+				t.Errorf("sat=%d dt=%.1f: variance %g", tt.sat, dt, vari)
+			}
+
+			var (
+				rs  [6]float64
+				dts [2]float64
+				v   float64
+				svh int
+			)
+			if SatSys(tt.sat, nil) == SYS_GAL && dt <= 0.0 { /* not selected by AOD<=0 */
+				continue
+			}
+			if nav.SatPos(tm, tm, tt.sat, EPHOPT_BRDC, rs[:], dts[:], &v, &svh) == 0 {
+				t.Errorf("sat=%d dt=%.1f: no satpos", tt.sat, dt)
+			} else if rs[0] != pos[0] || rs[1] != pos[1] || rs[2] != pos[2] || dts[0] != clk {
+				t.Errorf("sat=%d dt=%.1f: satpos %v %g, got %v %g", tt.sat, dt, rs[:3], dts[0], pos, clk)
+			}
+		}
+	}
+	if pos, clk, vari := EphSatPos(nil, toe); pos != [3]float64{} || clk != 0.0 || vari != 0.0 {
+		t.Errorf("no ephemeris: got %v %g %g", pos, clk, vari)
+	}
+}
+
+// TestGephSatPos compares the integrated glonass orbit with the Kepler orbit of
+// the initial state, which differ by the J2 perturbation only.
+func TestGephSatPos(t *testing.T) {
+	toe := Epoch2Time([]float64{2023, 6, 1, 2, 15, 0})
+	sat := SatNo(SYS_GLO, 4)
+	eph := synthEph(sat, 3, toe)
+	geph := synthGEph(sat, 3, toe)
+	geph.Gamn = 1e-12
+	nav := &Nav{Geph: []GEph{geph}}
+
+	for _, dt := range []float64{0.0, 425.3, -900.0} {
+		tm := TimeAdd(toe, dt)
+		pos, clk, vari := GephSatPos(&geph, tm)
+		ref, _, _ := EphSatPos(&eph, tm)
+		if d := synthDist(pos[:], ref[:]); d > 50.0 || (dt == 0.0 && d > 1e-6) {
+			t.Errorf("dt=%.1f: position differs from kepler %.3f m", dt, d)
+		}
+		if want := -geph.Taun + geph.Gamn*dt; math.Abs(clk-want) > 1e-15 || vari != SQR(ERREPH_GLO) {
+			t.Errorf("dt=%.1f: clock got %.15f, want %.15f var=%g", dt, clk, want, vari)
+		}
+
+		var (
+			rs  [6]float64
+			dts [2]float64
+			v   float64
+			svh int
+		)
+		if nav.SatPos(tm, tm, sat, EPHOPT_BRDC, rs[:], dts[:], &v, &svh) == 0 {
+			t.Errorf("dt=%.1f: no satpos", dt)
+		} else if rs[0] != pos[0] || rs[1] != pos[1] || rs[2] != pos[2] {
+			t.Errorf("dt=%.1f: satpos %v, got %v", dt, rs[:3], pos)
+		}
+	}
+	if pos, _, _ := GephSatPos(nil, toe); pos != [3]float64{} {
+		t.Errorf("no ephemeris: got %v", pos)
+	}
+}
@@ -20,6 +20,7 @@ package gnssgo
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math"
 	"os"
@@ -27,6 +28,20 @@ import (
 
 var fp_geoid *os.File = nil          /* geoid file pointer */
 var model_geoid int = GEOID_EMBEDDED /* geoid model */
+var geoid_user *Geoid = nil          /* geoid model set by SetGeoid */
+
+/* geoid model of LoadGeoid (GEOID_???) */
+type GeoidModel int
+
+/* geoid model grid loaded by LoadGeoid */
+type Geoid struct {
+	Model      GeoidModel /* geoid model (GEOID_???) */
+	file       *os.File   /* grid file (nil: embedded model) */
+	nlon, nlat int        /* number of grid points in longitude and latitude */
+	dgrid      float64    /* grid spacing (deg) */
+	rec        int        /* bytes of a record of a latitude */
+	off        int        /* offset of the first value in a record (bytes) */
+}
 
 /* bilinear interpolation ----------------------------------------------------*/
 func interpb(y []float64, a, b float64) float64 {
@@ -324,6 +339,7 @@ func CloseGeoid() {
 * notes  : to use external geoid model, call function opengeoid() to open
 *          geoid model before calling the function. If the external geoid model
 *          is not open, the function uses embedded geoid model.
+*          a geoid model set by SetGeoid is used in preference.
 *-----------------------------------------------------------------------------*/
 func GeoidH(pos []float64) float64 {
 	var (
@@ -341,6 +357,9 @@ func GeoidH(pos []float64) float64 {
 		Trace(2, "out of range for geoid model: lat=%.3f lon=%.3f\n", posd[0], posd[1])
 		return 0.0
 	}
+	if geoid_user != nil {
+		return geoid_user.Undulation(pos[0], pos[1])
+	}
 	switch byte(model_geoid) {
 	case GEOID_EMBEDDED:
 		h = geoidh_emb(posd[:])
@@ -367,6 +386,140 @@ func GeoidH(pos []float64) float64 {
 	return h
 }
 
+/* load geoid model ------------------------------------------------------------
+* load geoid model grid file
+* args   : string path      I   geoid model file path (GEOID_EMBEDDED: unused)
+*          GeoidModel model I   geoid model type
+*                               GEOID_EMBEDDED   : embedded model(1x1deg)
+*                               GEOID_EGM96_M150 : EGM96 15x15" (WW15MGH.DAC)
+*                               GEOID_EGM2008_M25: EGM2008 2.5x2.5"
+*                               GEOID_EGM2008_M10: EGM2008 1.0x1.0"
+* return : geoid model and error
+* notes  : the grid values are read from the file on demand, the size of the
+*          file is checked against the grid of the model. close the model by
+*          Geoid.Close. see OpenGeoid for the geoid model files.
+*-----------------------------------------------------------------------------*/
+func LoadGeoid(path string, model GeoidModel) (*Geoid, error) {
+	Trace(4, "loadgeoid: model=%d path=%s\n", model, path)
+
+	g := &Geoid{Model: model}
+	switch model {
+	case GEOID_EMBEDDED:
+		return g, nil
+	case GEOID_EGM96_M150: /* big-endian int16 (cm) */
+		g.nlon, g.nlat, g.dgrid = 1440, 721, 15.0/60.0
+		g.rec, g.off = 2*g.nlon, 0
+	case GEOID_EGM2008_M25: /* little-endian float32 (m) with zero-inserted records */
+		g.nlon, g.nlat, g.dgrid = 8640, 4321, 2.5/60.0
+		g.rec, g.off = 4*(g.nlon+2), 4
+	case GEOID_EGM2008_M10:
+		g.nlon, g.nlat, g.dgrid = 21600, 10801, 1.0/60.0
+		g.rec, g.off = 4*(g.nlon+2), 4
+	default:
+		return nil, fmt.Errorf("unsupported geoid model: model=%d", model)
+	}
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoid model file open error: %w", err)
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, fmt.Errorf("geoid model file open error: %w", err)
+	}
+	if info.Size() != int64(g.rec)*int64(g.nlat) {
+		fp.Close()
+		return nil, fmt.Errorf("geoid model file size error: model=%d size=%d", model, info.Size())
+	}
+	g.file = fp
+	return g, nil
+}
+
+/* close geoid model ---------------------------------------------------------*/
+func (g *Geoid) Close() error {
+	if g.file == nil {
+		return nil
+	}
+	err := g.file.Close()
+	g.file = nil
+	return err
+}
+
+/* geoid model grid value ----------------------------------------------------*/
+func (g *Geoid) value(i, j int) float64 {
+	var buff [4]byte
+
+	off := int64(j)*int64(g.rec) + int64(g.off)
+	if g.Model == GEOID_EGM96_M150 {
+		if _, err := g.file.ReadAt(buff[:2], off+2*int64(i)); err != nil {
+			Trace(5, "geoid data file range error: i=%d j=%d\n", i, j)
+			return 0.0
+		}
+		return float64(int16(binary.BigEndian.Uint16(buff[:2]))) * 0.01
+	}
+	if _, err := g.file.ReadAt(buff[:], off+4*int64(i)); err != nil {
+		Trace(5, "geoid data file range error: i=%d j=%d\n", i, j)
+		return 0.0
+	}
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(buff[:])))
+}
+
+/* geoid undulation ------------------------------------------------------------
+* get geoid undulation from geoid model by bilinear interpolation
+* args   : double lat,lon   I   geodetic latitude and longitude (rad)
+* return : geoid undulation, height of geoid above ellipsoid (m) (0.0:error)
+* notes  : the grid starts at 90N 0E with rows from north to south.
+*-----------------------------------------------------------------------------*/
+func (g *Geoid) Undulation(lat, lon float64) float64 {
+	var y [4]float64
+
+	posd := [2]float64{lat * R2D, math.Mod(lon*R2D, 360.0)}
+	if posd[1] < 0.0 {
+		posd[1] += 360.0
+	}
+	if posd[0] < -90.0 || 90.0 < posd[0] {
+		Trace(2, "out of range for geoid model: lat=%.3f lon=%.3f\n", posd[0], posd[1])
+		return 0.0
+	}
+	if g.file == nil {
+		return geoidh_emb(posd[:])
+	}
+	a := posd[1] / g.dgrid
+	b := (90.0 - posd[0]) / g.dgrid
+	i1 := int(a)
+	a -= float64(i1)
+	i1 %= g.nlon
+	i2 := (i1 + 1) % g.nlon
+	j1 := min(int(b), g.nlat-1)
+	b -= float64(j1)
+	j2 := min(j1+1, g.nlat-1)
+	y[0] = g.value(i1, j1)
+	y[1] = g.value(i2, j1)
+	y[2] = g.value(i1, j2)
+	y[3] = g.value(i2, j2)
+	return interpb(y[:], a, b)
+}
+
+/* set geoid model -------------------------------------------------------------
+* set geoid model used by GeoidH, GeoidHeight and the solution output
+* args   : *Geoid g         I   geoid model (nil: model of OpenGeoid)
+* return : none
+*-----------------------------------------------------------------------------*/
+func SetGeoid(g *Geoid) {
+	geoid_user = g
+}
+
+/* geoid height of position ----------------------------------------------------
+* get geoid height, the separation of geoid and ellipsoid, at position
+* args   : double *pos      I   geodetic position {lat,lon,h} (rad,m)
+* return : geoid height (m) (0.0:error)
+* notes  : the embedded EGM96 1x1deg model is used unless a geoid model is set
+*          by SetGeoid or OpenGeoid. orthometric height is pos[2]-GeoidHeight.
+*-----------------------------------------------------------------------------*/
+func GeoidHeight(pos [3]float64) float64 {
+	return GeoidH(pos[:])
+}
+
 /*------------------------------------------------------------------------------
 * embedded geoid model
 * notes  : geoid heights are derived from EGM96 (1 x 1 deg grid)
@@ -0,0 +1,100 @@
+package gnssgo
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestGeoidHeight compares the embedded EGM96 model with the EGM96 geoid
+// heights of reference points.
+func TestGeoidHeight(t *testing.T) {
+	tests := []struct {
+		lat, lon, want float64
+	}{
+		{0.0, 0.0, 17.16},                /* NGA EGM96 */
+		{16.776, -3.009, 28.7068},        /* GeographicLib GeoidEval example */
+		{40.7128, -74.0060, -32.8},       /* new york */
+		{6.9271, 79.8612, -98.0},         /* colombo, indian ocean low */
+		{-90.0, 0.0, -29.53},             /* south pole */
+		{90.0, 0.0, 13.61},               /* north pole */
+		{-33.8688, 151.2093 - 360, 22.3}, /* sydney, negative longitude */
+	}
+	g, err := LoadGeoid("", GEOID_EMBEDDED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		pos := [3]float64{tt.lat * D2R, tt.lon * D2R, 0.0}
+		if h := GeoidHeight(pos); math.Abs(h-tt.want) > 0.5 {
+			t.Errorf("%.4f %.4f: got %.3f, want %.3f", tt.lat, tt.lon, h, tt.want)
+		}
+		if h, want := g.Undulation(pos[0], pos[1]), GeoidHeight(pos); h != want {
+			t.Errorf("%.4f %.4f: embedded undulation %.3f, want %.3f", tt.lat, tt.lon, h, want)
+		}
+	}
+}
+
+// TestLoadGeoid loads an EGM96 15' grid file of a linear function of the
+// grid indices, interpolated without error, and checks its use in the GGA
+// geoid separation.
+func TestLoadGeoid(t *testing.T) {
+	const nlon, nlat = 1440, 721
+	buff := make([]byte, 2*nlon*nlat)
+	for j := 0; j < nlat; j++ {
+		for i := 0; i < nlon; i++ {
+			binary.BigEndian.PutUint16(buff[2*(i+j*nlon):], uint16(int16(i-2*j)))
+		}
+	}
+	path := filepath.Join(t.TempDir(), "WW15MGH.DAC")
+	if err := os.WriteFile(path, buff, 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := LoadGeoid(path, GEOID_EGM96_M150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	for _, ll := range [][2]float64{{10.1, 20.3}, {-45.05, 301.0}, {-45.05, -59.0}, {89.9, 0.1}} {
+		/* grid indices lon/0.25 and (90-lat)/0.25, values in cm */
+		want := (math.Mod(ll[1]+360.0, 360.0)/0.25 - 2.0*(90.0-ll[0])/0.25) * 0.01
+		if h := g.Undulation(ll[0]*D2R, ll[1]*D2R); math.Abs(h-want) > 1e-9 {
+			t.Errorf("%.2f %.2f: got %.4f, want %.4f", ll[0], ll[1], h, want)
+		}
+	}
+
+	/* geoid separation of GGA with the model set */
+	var sol Sol
+	pos := [3]float64{10.1 * D2R, 20.3 * D2R, 150.0}
+	Pos2Ecef(pos[:], sol.Rr[:])
+	sol.Stat, sol.Ns = SOLQ_SINGLE, 8
+	sol.Time = Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	SetGeoid(g)
+	defer SetGeoid(nil)
+	sep := GeoidHeight(pos)
+	if want := g.Undulation(pos[0], pos[1]); sep != want {
+		t.Errorf("geoid height with model set: got %.4f, want %.4f", sep, want)
+	}
+	fields := strings.Split(sol.ToGGA(nil), ",")
+	alt, _ := strconv.ParseFloat(fields[9], 64)
+	gsep, _ := strconv.ParseFloat(fields[11], 64)
+	if math.Abs(gsep-sep) > 0.001 || math.Abs(alt+gsep-150.0) > 0.002 {
+		t.Errorf("gga altitude and separation: got %s %s, want %.3f %.3f", fields[9], fields[11], 150.0-sep, sep)
+	}
+
+	/* invalid models and files */
+	if _, err := LoadGeoid(path, GEOID_EGM2008_M25); err == nil {
+		t.Errorf("egm2008 of egm96 file: want size error")
+	}
+	if _, err := LoadGeoid(path, GEOID_GSI2000_M15); err == nil {
+		t.Errorf("gsi2000: want unsupported model error")
+	}
+	if _, err := LoadGeoid(filepath.Join(t.TempDir(), "none.dac"), GEOID_EGM96_M150); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
@@ -22,6 +22,7 @@ package gnssgo
 
 import (
 	"bufio"
+	"fmt"
 	"math"
 	"os"
 	"strings"
@@ -37,10 +38,10 @@ func getindex(value float64, range_ []float64) int {
 	if range_[2] == 0.0 {
 		return 0
 	}
-	if range_[1] > 0.0 && (value < range_[0] || range_[1] < value) {
+	if range_[2] > 0.0 && (value < range_[0] || range_[1] < value) {
 		return -1
 	}
-	if range_[1] < 0.0 && (value < range_[1] || range_[0] < value) {
+	if range_[2] < 0.0 && (value < range_[1] || range_[0] < value) {
 		return -1
 	}
 	return int(math.Floor((value-range_[0])/range_[2] + 0.5))
@@ -144,6 +145,9 @@ func ReadIonexHeader(rd *bufio.Reader, lats, lons, hgts []float64, rb, nexp *flo
 		if err != nil {
 			break
 		}
+		if len(buff) < 60 {
+			continue
+		}
 		label := buff[60:]
 		switch {
 		case strings.Index(label, "IONEX VERSION / TYPE") == 0:
@@ -180,7 +184,7 @@ func ReadIonexBody(rd *bufio.Reader, lats, lons, hgts []float64, rb, nexp float6
 	var (
 		p                           *Tec = nil
 		time                        Gtime
-		lat, hgt, x                 float64
+		lat, hgt, x, mexp           float64
 		lon                         [3]float64
 		i, j, k, n, m, index, dtype int
 		buff                        string
@@ -204,17 +208,21 @@ func ReadIonexBody(rd *bufio.Reader, lats, lons, hgts []float64, rb, nexp float6
 			if p = nav.AddTec(lats, lons, hgts, rb); p != nil {
 				dtype = 1
 			}
+			mexp = nexp
 		case strings.Index(label, "END OF TEC MAP") == 0:
 			dtype = 0
 			p = nil
 		case strings.Index(label, "START OF RMS MAP") == 0:
 			dtype = 2
 			p = nil
+			mexp = nexp
 		case strings.Index(label, "END OF RMS MAP") == 0:
 			dtype = 0
 			p = nil
+		case strings.Index(label, "EXPONENT") == 0: /* exponent of the map */
+			mexp = Str2Num(buff, 0, 6)
 		case strings.Index(label, "EPOCH OF CURRENT MAP") == 0:
-			if Str2Time(buff, 0, 36, &time) > 0 {
+			if Str2Time(buff, 0, 36, &time) != 0 {
 				Trace(2, "ionex epoch invalid: %-36.36s\n", buff)
 				continue
 			}
@@ -258,9 +266,9 @@ func ReadIonexBody(rd *bufio.Reader, lats, lons, hgts []float64, rb, nexp float6
 				}
 
 				if dtype == 1 {
-					p.Data[index] = x * math.Pow(10.0, nexp)
+					p.Data[index] = x * math.Pow(10.0, mexp)
 				} else {
-					p.Rms[index] = float32(x * math.Pow(10.0, nexp))
+					p.Rms[index] = float32(x * math.Pow(10.0, mexp))
 				}
 			}
 		}
@@ -356,9 +364,52 @@ func (nav *Nav) ReadTec(file string, opt int) {
 
 	/* P1-P2 dcb */
 	for i = 0; i < len(nav.CBias); i++ {
-		cbias := nav.CBias[i]
-		cbias[0] = CLIGHT * dcb[i] * (1e-9) /* ns.m */
+		nav.CBias[i][0] = CLIGHT * dcb[i] * (1e-9) /* ns.m */
+	}
+}
+
+/* read ionex tec grid file ----------------------------------------------------
+* read tec and rms maps of an ionex file and add them to navigation data
+* args   : string path      I   ionex tec grid file
+*          nav_t  *nav      IO  navigation data (nav.Tec combined by epoch)
+* return : number of tec maps read and error (nil: ok)
+* notes  : the values are scaled by the exponent of the header or of the map.
+*          the maps read before are not cleared. the P1-P2 dcbs of the aux
+*          data are set to nav.CBias for the satellites in the file.
+*-----------------------------------------------------------------------------*/
+func ReadTEC(path string, nav *Nav) (int, error) {
+	var (
+		lats, lons, hgts [3]float64
+		rb, nexp         float64 = 0.0, -1.0
+		dcb, rms         [MAXSAT]float64
+	)
+
+	Trace(4, "readtec : file=%s\n", path)
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("ionex file open error: %w", err)
 	}
+	defer fp.Close()
+	rd := bufio.NewReader(fp)
+
+	if ReadIonexHeader(rd, lats[:], lons[:], hgts[:], &rb, &nexp, dcb[:], rms[:]) <= 0.0 {
+		return 0, fmt.Errorf("ionex file format error: %s", path)
+	}
+	nt := nav.Nt()
+	ReadIonexBody(rd, lats[:], lons[:], hgts[:], rb, nexp, nav)
+	n := nav.Nt() - nt
+	if n <= 0 {
+		return 0, fmt.Errorf("no tec map in ionex file: %s", path)
+	}
+	nav.CombineTec()
+
+	for i := 0; i < MAXSAT; i++ {
+		if dcb[i] != 0.0 {
+			nav.CBias[i][0] = CLIGHT * dcb[i] * 1e-9 /* ns.m */
+		}
+	}
+	return n, nil
 }
 
 /* interpolate tec grid data -------------------------------------------------*/
@@ -546,3 +597,27 @@ func (nav *Nav) IonTec(time Gtime, pos, azel []float64, opt int, delay, vari *fl
 	Trace(5, "iontec  : delay=%5.2f std=%5.2f\n", *delay, math.Sqrt(*vari))
 	return 1
 }
+
+/* ionospheric delay by tec maps -----------------------------------------------
+* compute slant ionospheric delay by the tec maps read by ReadTEC
+* args   : gtime_t time     I   time (gpst)
+*          double *pos      I   receiver position {lat,lon,h} (rad,m)
+*          double *azel     I   azimuth/elevation angle {az,el} (rad)
+*          nav_t  *nav      I   navigation data
+* return : ionospheric delay (L1) (m), variance (L1) (m^2) and error (nil: ok)
+* notes  : the vtec at the ionospheric pierce point is interpolated bilinearly
+*          in the grid and linearly between the maps, which are rotated by the
+*          earth rotation (sun-fixed). call nav.IonTec with opt bit0=0 for the
+*          maps in the earth-fixed (geographic) frame. the single-layer mapping
+*          function converts the vtec to the slant delay.
+*-----------------------------------------------------------------------------*/
+func IonTEC(time Gtime, pos, azel []float64, nav *Nav) (float64, float64, error) {
+	var delay, vari float64
+	if nav == nil || nav.Nt() == 0 {
+		return 0.0, 0.0, fmt.Errorf("no tec map")
+	}
+	if nav.IonTec(time, pos, azel, 1, &delay, &vari) == 0 {
+		return 0.0, 0.0, fmt.Errorf("%s: tec map out of period or area", TimeStr(time, 0))
+	}
+	return delay, vari, nil
+}
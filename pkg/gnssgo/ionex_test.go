@@ -0,0 +1,148 @@
+package gnssgo
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ionexVTEC is the vtec (tecu) of map k in the ionex file of writeIonex, linear
+// in latitude and longitude (deg).
+func ionexVTEC(k int, lat, lon float64) float64 {
+	return 10.0 + 0.5*(lat-20.0) + 0.2*(lon-100.0) + 5.0*float64(k)
+}
+
+// writeIonex writes an ionex file with two tec maps at 00:00 and 02:00 of
+// t0 on the grid lat 60:-10:20 lon 100:20:160 and a rms map of the first.
+// The second map has its own exponent.
+func writeIonex(t *testing.T, t0 Gtime) string {
+	var (
+		b  strings.Builder
+		ep [6]float64
+	)
+	line := func(data, label string) {
+		fmt.Fprintf(&b, "%-60s%-20s\n", data, label)
+	}
+	epoch := func(tm Gtime) string {
+		Time2Epoch(tm, ep[:])
+		return fmt.Sprintf("%6.0f%6.0f%6.0f%6.0f%6.0f%6.0f", ep[0], ep[1], ep[2], ep[3], ep[4], ep[5])
+	}
+	grid := func(n, k int, label string, exp float64, v func(lat, lon float64) float64) {
+		line(fmt.Sprintf("%6d", n), "START OF "+label+" MAP")
+		if k > 0 {
+			line(fmt.Sprintf("%6.0f", exp), "EXPONENT")
+		}
+		line(epoch(TimeAdd(t0, 7200.0*float64(k))), "EPOCH OF CURRENT MAP")
+		for lat := 60.0; lat >= 20.0; lat -= 10.0 {
+			line(fmt.Sprintf("  %6.1f%6.1f%6.1f%6.1f%6.1f", lat, 100.0, 160.0, 20.0, 450.0), "LAT/LON1/LON2/DLON/H")
+			for lon := 100.0; lon <= 160.0; lon += 20.0 {
+				fmt.Fprintf(&b, "%5.0f", v(lat, lon)*math.Pow(10.0, -exp))
+			}
+			b.WriteString("\n")
+		}
+		line(fmt.Sprintf("%6d", n), "END OF "+label+" MAP")
+	}
+	line("     1.0            IONOSPHERE MAPS     GPS", "IONEX VERSION / TYPE")
+	line("TEST                TEST                01-JUN-23 00:00", "PGM / RUN BY / DATE")
+	line(epoch(t0), "EPOCH OF FIRST MAP")
+	line(epoch(TimeAdd(t0, 7200.0)), "EPOCH OF LAST MAP")
+	line("  7200", "INTERVAL")
+	line("     2", "# OF MAPS IN FILE")
+	line("  COSZ", "MAPPING FUNCTION")
+	line("  6371.0", "BASE RADIUS")
+	line("     2", "MAP DIMENSION")
+	line("   450.0 450.0   0.0", "HGT1 / HGT2 / DHGT")
+	line("    60.0  20.0 -10.0", "LAT1 / LAT2 / DLAT")
+	line("   100.0 160.0  20.0", "LON1 / LON2 / DLON")
+	line("    -1", "EXPONENT")
+	line("", "END OF HEADER")
+	for k := 0; k < 2; k++ {
+		grid(k+1, k, "TEC", -1.0-float64(k), func(lat, lon float64) float64 { return ionexVTEC(k, lat, lon) })
+	}
+	grid(1, 0, "RMS", -1.0, func(lat, lon float64) float64 { return 2.0 })
+	line("", "END OF FILE")
+
+	file := filepath.Join(t.TempDir(), "test1520.23i")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestReadTEC reads the ionex file and interpolates the vtec at a grid point,
+// between grid points and between the maps rotated by the earth rotation.
+func TestReadTEC(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	var nav Nav
+	n, err := ReadTEC(writeIonex(t, t0), &nav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || nav.Nt() != 2 {
+		t.Fatalf("tec maps: got %d nt=%d, want 2", n, nav.Nt())
+	}
+	fact := 40.30e16 / FREQ1 / FREQ1 /* L1 delay per tecu (m) */
+	zenith := []float64{0.0, 90.0 * D2R}
+
+	for _, tt := range []struct {
+		dt, lat, lon float64
+		want         float64
+	}{
+		{0.0, 40.0, 120.0, ionexVTEC(0, 40.0, 120.0)}, /* grid point */
+		{0.0, 33.3, 127.7, ionexVTEC(0, 33.3, 127.7)}, /* between grid points */
+		{3600.0, 50.0, 140.0, ionexVTEC(0, 50.0, 155.0)*0.5 + ionexVTEC(1, 50.0, 125.0)*0.5},
+		{1800.0, 27.5, 130.0, ionexVTEC(0, 27.5, 137.5)*0.75 + ionexVTEC(1, 27.5, 107.5)*0.25},
+	} {
+		pos := []float64{tt.lat * D2R, tt.lon * D2R, 0.0}
+		delay, vari, err := IonTEC(TimeAdd(t0, tt.dt), pos, zenith, &nav)
+		if err != nil {
+			t.Errorf("dt=%.0f lat=%.1f lon=%.1f: %v", tt.dt, tt.lat, tt.lon, err)
+			continue
+		}
+		if math.Abs(delay/fact-tt.want) > 1e-6 {
+			t.Errorf("dt=%.0f lat=%.1f lon=%.1f: vtec got %.6f, want %.6f", tt.dt, tt.lat, tt.lon, delay/fact, tt.want)
+		}
+		if tt.dt == 0.0 && math.Abs(math.Sqrt(vari)/fact-2.0) > 1e-6 {
+			t.Errorf("dt=%.0f lat=%.1f lon=%.1f: rms got %.6f, want 2", tt.dt, tt.lat, tt.lon, math.Sqrt(vari)/fact)
+		}
+	}
+
+	/* earth-fixed maps */
+	var delay, vari float64
+	pos := []float64{27.5 * D2R, 130.0 * D2R, 0.0}
+	if nav.IonTec(TimeAdd(t0, 1800.0), pos, zenith, 0, &delay, &vari) == 0 {
+		t.Fatal("earth-fixed: no delay")
+	}
+	if want := ionexVTEC(0, 27.5, 130.0)*0.75 + ionexVTEC(1, 27.5, 130.0)*0.25; math.Abs(delay/fact-want) > 1e-6 {
+		t.Errorf("earth-fixed: vtec got %.6f, want %.6f", delay/fact, want)
+	}
+
+	/* slant delay by the vtec at the pierce point and the mapping function */
+	var posp [3]float64
+	rcv, azel := []float64{40.0 * D2R, 120.0 * D2R, 0.0}, []float64{30.0 * D2R, 60.0 * D2R}
+	fs := IonPPP(rcv, azel, 6371.0, 450.0, posp[:])
+	slant, _, err := IonTEC(t0, rcv, azel, &nav)
+	if want := fact * fs * ionexVTEC(0, posp[0]*R2D, posp[1]*R2D); err != nil || math.Abs(slant-want) > 1e-6 {
+		t.Errorf("slant delay: got %.6f, want %.6f err=%v", slant, want, err)
+	}
+
+	if _, _, err := IonTEC(TimeAdd(t0, -60.0), pos, zenith, &nav); err == nil {
+		t.Errorf("before the first map: want error")
+	}
+	if _, _, err := IonTEC(t0, pos, zenith, &Nav{}); err == nil {
+		t.Errorf("no maps: want error")
+	}
+	if _, err := ReadTEC(filepath.Join(t.TempDir(), "none.23i"), &nav); err == nil {
+		t.Errorf("missing file: want error")
+	}
+	file := filepath.Join(t.TempDir(), "bad.23i")
+	if err := os.WriteFile(file, []byte("not an ionex file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTEC(file, &nav); err == nil {
+		t.Errorf("bad file: want error")
+	}
+}
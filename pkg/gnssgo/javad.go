@@ -705,7 +705,7 @@ func decode_eph(raw *Raw, sys int) int {
 		eph.Flag = (flag >> 1) & 1
 		eph.Code = (flag >> 2) & 3
 		eph.Fit = float64(flag & 1)
-		eph.Week = AdjGpsWeek(week)
+		eph.Week = AdjGpsWeekRef(week, raw.Time)
 		eph.Toe = GpsT2Time(eph.Week, eph.Toes)
 
 		/* for week-handover problem */
@@ -970,7 +970,7 @@ func decode_WE(raw *Raw) int {
 		Trace(2, "javad WE satellite error: prn=%d\n", prn)
 		return -1
 	}
-	seph.Tof = GpsT2Time(AdjGpsWeek(week), float64(tow))
+	seph.Tof = GpsT2Time(AdjGpsWeekRef(week, raw.Time), float64(tow))
 	seph.T0 = adjday(seph.Tof, float64(tod))
 
 	if !strings.Contains(raw.Opt, "-EPHALL") {
@@ -1041,7 +1041,7 @@ func decode_UO(raw *Raw) int {
 	idx += 4
 	raw.NavData.Utc_gps[2] = float64(U4L(raw.Buff[idx:]))
 	idx += 4
-	raw.NavData.Utc_gps[3] = float64(AdjGpsWeek(int(U2L(raw.Buff[idx:]))))
+	raw.NavData.Utc_gps[3] = float64(AdjGpsWeekRef(int(U2L(raw.Buff[idx:])), raw.Time))
 	idx += 2
 	raw.NavData.Utc_gps[4] = float64(I1(raw.Buff[idx:]))
 	return 9
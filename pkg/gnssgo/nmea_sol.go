@@ -0,0 +1,154 @@
+package gnssgo
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* NMEA to solution converter --------------------------------------------------
+* builds solutions for receivers which only output NMEA. sentences of one
+* epoch (same UTC time field) are combined:
+*     GGA : position, quality, number of satellites, age of differential
+*     RMC : date, velocity (speed and course over ground)
+*     ZDA : date
+*     GST : position accuracy (error ellipse or lat/lon/alt sigma)
+* the date is taken from RMC or ZDA of the epoch, else from the last one
+* received. until one is received the current date is assumed. epochs
+* without GGA fix are not output.
+*-----------------------------------------------------------------------------*/
+type nmeaSolConv struct {
+	sol   Sol       /* solution of current epoch */
+	epoch string    /* UTC time field of current epoch */
+	gga   bool      /* GGA fix received for current epoch */
+	vel   []float64 /* {speed (m/s),course (rad)} of current epoch */
+	Q     []float64 /* enu covariance of current epoch (m^2) */
+	time  Gtime     /* time of current epoch from RMC or ZDA (GPST) */
+	date  Gtime     /* last date (GPST) */
+}
+
+// NMEAToSol converts a stream of NMEA sentences to solutions. The returned
+// channel is closed after the input channel is closed and the last epoch
+// has been output.
+func NMEAToSol(sentences <-chan NMEASentence) <-chan Sol {
+	out := make(chan Sol)
+
+	go func() {
+		defer close(out)
+		conv := nmeaSolConv{date: Utc2GpsT(TimeGet())}
+
+		for s := range sentences {
+			if sol, ok := conv.input(s); ok {
+				out <- sol
+			}
+		}
+		if sol, ok := conv.flush(); ok {
+			out <- sol
+		}
+	}()
+	return out
+}
+
+/* input NMEA sentence, returns the previous epoch solution when completed ---*/
+func (c *nmeaSolConv) input(s NMEASentence) (Sol, bool) {
+	var (
+		sol Sol
+		ok  bool
+	)
+	if !s.Valid || len(s.Type) < 3 || len(s.Fields) < 1 {
+		return sol, false
+	}
+	typ := s.Type[len(s.Type)-3:]
+	if typ != "GGA" && typ != "RMC" && typ != "GST" && typ != "ZDA" {
+		return sol, false
+	}
+	if s.Fields[0] != c.epoch {
+		sol, ok = c.flush()
+		c.epoch = s.Fields[0]
+	}
+	switch typ {
+	case "GGA":
+		c.sol.Time = c.date
+		c.gga = c.sol.DecodeNmeaGga(s.Fields, len(s.Fields)) > 0 && c.sol.Stat != SOLQ_NONE
+	case "RMC":
+		var rmc Sol
+		if len(s.Fields) > 8 && s.Fields[8] != "" && rmc.DecodeNmeaRmc(s.Fields, len(s.Fields)) > 0 {
+			c.time, c.date = rmc.Time, rmc.Time
+		}
+		if len(s.Fields) > 7 && s.Fields[1] == "A" {
+			vel, err1 := strconv.ParseFloat(s.Fields[6], 64) /* speed (knots) */
+			dir, err2 := strconv.ParseFloat(s.Fields[7], 64) /* course (deg) */
+			if err1 == nil && err2 == nil {
+				c.vel = []float64{vel * 1852.0 / 3600.0, dir * D2R}
+			}
+		}
+	case "ZDA":
+		var zda Sol
+		if len(s.Fields) > 3 && s.Fields[3] != "" {
+			zda.DecodeNmeaZda(s.Fields, len(s.Fields))
+			c.time, c.date = zda.Time, zda.Time
+		}
+	case "GST":
+		c.Q = decodeNmeaGst(s.Fields)
+	}
+	return sol, ok
+}
+
+/* output solution of current epoch and reset epoch --------------------------*/
+func (c *nmeaSolConv) flush() (Sol, bool) {
+	var (
+		pos, enu [3]float64
+		P        [9]float64
+	)
+	sol, ok := c.sol, c.gga
+	if ok {
+		if c.time.Time != 0 {
+			sol.Time = c.time
+		}
+		Ecef2Pos(sol.Rr[:], pos[:])
+		if c.vel != nil {
+			enu[0] = c.vel[0] * math.Sin(c.vel[1])
+			enu[1] = c.vel[0] * math.Cos(c.vel[1])
+			Enu2Ecef(pos[:], enu[:], sol.Rr[3:])
+		}
+		if c.Q != nil {
+			Cov2Ecef(pos[:], c.Q, P[:])
+			sol.Cov2Sol(P[:])
+		}
+	}
+	c.sol, c.gga, c.vel, c.Q, c.time = Sol{}, false, nil, nil, Gtime{}
+	return sol, ok
+}
+
+/* decode NMEA GST (pseudorange error statistics) sentence ---------------------
+* return enu covariance (m^2) or nil if no accuracy available
+*-----------------------------------------------------------------------------*/
+func decodeNmeaGst(val []string) []float64 {
+	var v [8]float64
+	var ok [8]bool
+
+	for i := 1; i < len(val) && i < 8; i++ {
+		if x, err := strconv.ParseFloat(strings.TrimSpace(val[i]), 64); err == nil {
+			v[i], ok[i] = x, true
+		}
+	}
+	if !ok[7] {
+		return nil
+	}
+	Q := make([]float64, 9)
+	switch {
+	case ok[2] && ok[3] && ok[4] && v[2] > 0.0: /* error ellipse */
+		a, b, t := SQR(v[2]), SQR(v[3]), v[4]*D2R /* orientation from north */
+		Q[0] = a*SQR(math.Sin(t)) + b*SQR(math.Cos(t))
+		Q[4] = a*SQR(math.Cos(t)) + b*SQR(math.Sin(t))
+		Q[1] = (a - b) * math.Sin(t) * math.Cos(t)
+		Q[3] = Q[1]
+	case ok[5] && ok[6]: /* lat/lon sigma */
+		Q[0] = SQR(v[6])
+		Q[4] = SQR(v[5])
+	default:
+		return nil
+	}
+	Q[8] = SQR(v[7])
+	return Q
+}
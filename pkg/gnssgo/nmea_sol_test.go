@@ -0,0 +1,94 @@
+package gnssgo
+
+import (
+	"math"
+	"testing"
+)
+
+// nmeaSols feeds sentences to NMEAToSol and collects the solutions.
+func nmeaSols(t *testing.T, sentences ...string) []Sol {
+	in := make(chan NMEASentence, len(sentences))
+	for _, s := range sentences {
+		nmea, err := ParseNMEA(s)
+		if err != nil {
+			t.Fatalf("parse %s: %v", s, err)
+		}
+		in <- nmea
+	}
+	close(in)
+
+	var sols []Sol
+	for sol := range NMEAToSol(in) {
+		sols = append(sols, sol)
+	}
+	return sols
+}
+
+// TestNMEAToSol checks solutions built from GGA, RMC and GST sentences.
+func TestNMEAToSol(t *testing.T) {
+	/* 48 07.038'N 11 31.000'E, 545.4 m MSL + 46.9 m geoid separation */
+	pos := []float64{(48.0 + 7.038/60.0) * D2R, (11.0 + 31.0/60.0) * D2R, 545.4 + 46.9}
+	var rr [3]float64
+	Pos2Ecef(pos, rr[:])
+
+	t.Run("GGA and GST", func(t *testing.T) {
+		sols := nmeaSols(t,
+			"$GPGGA,123519.00,4807.038,N,01131.000,E,4,12,0.9,545.4,M,46.9,M,1.5,0031",
+			"$GPGST,123519.00,0.5,0.03,0.02,90.0,0.021,0.029,0.05",
+			"$GPGGA,123520.00,,,,,0,00,99.99,,,,,,") /* no fix: not output */
+		if len(sols) != 1 {
+			t.Fatalf("solutions = %d, want 1", len(sols))
+		}
+		sol := sols[0]
+		if sol.Stat != SOLQ_FIX || sol.Ns != 12 || sol.Age != 1.5 {
+			t.Errorf("stat/ns/age = %d/%d/%.1f, want %d/12/1.5", sol.Stat, sol.Ns, sol.Age, SOLQ_FIX)
+		}
+		if d := synthDist(sol.Rr[:3], rr[:]); d > 1e-3 {
+			t.Errorf("position error = %.4f m", d)
+		}
+		var ep [6]float64
+		Time2Epoch(GpsT2Utc(sol.Time), ep[:])
+		if ep[3] != 12 || ep[4] != 35 || math.Abs(ep[5]-19.0) > 1e-6 {
+			t.Errorf("time = %v, want 12:35:19 UTC", ep)
+		}
+
+		/* semi-major axis 0.03 m pointing east, semi-minor 0.02 m north */
+		var P, Q [9]float64
+		sol.Sol2Cov(P[:])
+		Cov2Enu(pos, P[:], Q[:])
+		want := []float64{SQR(0.03), 0, 0, 0, SQR(0.02), 0, 0, 0, SQR(0.05)}
+		for i := range want {
+			if math.Abs(Q[i]-want[i]) > 1e-7 {
+				t.Errorf("enu covariance[%d] = %.3g, want %.3g", i, Q[i], want[i])
+			}
+		}
+	})
+
+	t.Run("RMC and GGA", func(t *testing.T) {
+		sols := nmeaSols(t,
+			"$GNRMC,123519.00,A,4807.038,N,01131.000,E,19.438,90.0,230394,,,D",
+			"$GNGGA,123519.00,4807.038,N,01131.000,E,2,08,0.9,545.4,M,46.9,M,,",
+			"$GNRMC,123520.00,A,4807.038,N,01131.000,E,0.0,0.0,230394,,,D",
+			"$GNGGA,123520.00,4807.038,N,01131.000,E,5,08,0.9,545.4,M,46.9,M,,")
+		if len(sols) != 2 {
+			t.Fatalf("solutions = %d, want 2", len(sols))
+		}
+		if sols[0].Stat != SOLQ_DGPS || sols[1].Stat != SOLQ_FLOAT {
+			t.Errorf("stat = %d/%d, want %d/%d", sols[0].Stat, sols[1].Stat, SOLQ_DGPS, SOLQ_FLOAT)
+		}
+		var ep [6]float64
+		Time2Epoch(GpsT2Utc(sols[0].Time), ep[:])
+		if ep[0] != 1994 || ep[1] != 3 || ep[2] != 23 {
+			t.Errorf("date = %v, want 1994/03/23", ep)
+		}
+		if dt := TimeDiff(sols[1].Time, sols[0].Time); dt != 1.0 {
+			t.Errorf("time difference = %.3f s, want 1", dt)
+		}
+		/* 19.438 knots = 10 m/s to the east */
+		var enu [3]float64
+		Ecef2Enu(pos, sols[0].Rr[3:], enu[:])
+		if math.Abs(enu[0]-10.0) > 1e-3 || math.Abs(enu[1]) > 1e-3 || math.Abs(enu[2]) > 1e-3 {
+			t.Errorf("velocity enu = %.4f %.4f %.4f, want 10 0 0", enu[0], enu[1], enu[2])
+		}
+	})
+}
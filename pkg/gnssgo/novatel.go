@@ -1456,7 +1456,7 @@ func decode_rgeb(raw *Raw) int {
 		i, week, nobs, prn, sat, stat, sys, parity, lli, index, freq int
 	)
 
-	week = AdjGpsWeek(int(U4L(raw.Buff[idx:])))
+	week = AdjGpsWeekRef(int(U4L(raw.Buff[idx:])), raw.Time)
 	tow = R8L(raw.Buff[idx+4:])
 	nobs = int(U4L(raw.Buff[idx+12:]))
 	raw.Time = GpsT2Time(week, tow)
@@ -1538,7 +1538,7 @@ func decode_rged(raw *Raw) int {
 		i, week, nobs, prn, sat, stat, sys, parity, lli, index, freq, snr int
 	)
 	nobs = int(U2L(raw.Buff[idx:]))
-	week = AdjGpsWeek(int(U2L(raw.Buff[idx+2:])))
+	week = AdjGpsWeekRef(int(U2L(raw.Buff[idx+2:])), raw.Time)
 	tow = float64(U4L(raw.Buff[idx+4:])) / 100.0
 	raw.Time = GpsT2Time(week, tow)
 	if raw.Len != OEM3HLEN+12+nobs*20 {
@@ -1656,7 +1656,7 @@ func decode_frmb(raw *Raw) int {
 	)
 	Trace(3, "decode_frmb: len=%d\n", raw.Len)
 
-	week = AdjGpsWeek(int(U4L(raw.Buff[idx:])))
+	week = AdjGpsWeekRef(int(U4L(raw.Buff[idx:])), raw.Time)
 	tow = R8L(raw.Buff[idx+4:])
 	prn = int(U4L(raw.Buff[idx+12:]))
 	nbit = int(U4L(raw.Buff[idx+20:]))
@@ -1702,7 +1702,7 @@ func decode_utcb(raw *Raw) int {
 	raw.NavData.Utc_gps[0] = R8L(raw.Buff[idx:])
 	raw.NavData.Utc_gps[1] = R8L(raw.Buff[idx+8:])
 	raw.NavData.Utc_gps[2] = float64(U4L(raw.Buff[idx+16:]))
-	raw.NavData.Utc_gps[3] = float64(AdjGpsWeek(int(U4L(raw.Buff[idx+20:]))))
+	raw.NavData.Utc_gps[3] = float64(AdjGpsWeekRef(int(U4L(raw.Buff[idx+20:])), raw.Time))
 	raw.NavData.Utc_gps[4] = float64(I4L(raw.Buff[idx+28:]))
 	return 9
 }
@@ -1732,7 +1732,7 @@ func decode_oem4(raw *Raw) int {
 			stat, week)
 		return 0
 	}
-	week = AdjGpsWeek(week)
+	week = AdjGpsWeekRef(week, raw.Time)
 	tow = float64(U4L(raw.Buff[16:])) * 0.001
 	raw.Time = GpsT2Time(week, tow)
 	if msg != 0 {
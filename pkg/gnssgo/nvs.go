@@ -106,7 +106,7 @@ func decode_xf5raw(raw *Raw) int {
 		Trace(2, "nvs xf5raw obs week error: week=%d\n", week)
 		return -1
 	}
-	week = AdjGpsWeek(week)
+	week = AdjGpsWeekRef(week, raw.Time)
 
 	if (raw.Len-31)%30 > 0 {
 
@@ -246,7 +246,7 @@ func decode_gpsephem(sat int, raw *Raw) int {
 		Trace(2, "nvs gps ephemeris week error: sat=%2d week=%d\n", sat, week)
 		return -1
 	}
-	eph.Week = AdjGpsWeek(int(week))
+	eph.Week = AdjGpsWeekRef(int(week), raw.Time)
 	eph.Toe = GpsT2Time(eph.Week, eph.Toes)
 	eph.Toc = GpsT2Time(eph.Week, toc)
 	eph.Ttr = raw.Time
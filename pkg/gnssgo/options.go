@@ -73,6 +73,7 @@ var (
 	POSOPT  string = "0:llh,1:xyz,2:single,3:posfile,4:rinexhead,5:rtcm,6:raw"
 	TIDEOPT string = "0:off,1:on,2:otl"
 	PHWOPT  string = "0:off,1:on,2:precise"
+	IRNOPT  string = "0:l5,1:s"
 )
 
 var SysOpts map[string]*Opt = map[string]*Opt{
@@ -98,6 +99,7 @@ var SysOpts map[string]*Opt = map[string]*Opt{
 	"pos1-posopt6":     {"pos1-posopt6", 3, &prcopt_.PosOpt[5], nil, nil, SWTOPT},
 	"pos1-exclsats":    {"pos1-exclsats", 2, nil, nil, &exsats_, "prn ..."},
 	"pos1-navsys":      {"pos1-navsys", 0, &prcopt_.NavSys, nil, nil, NAVOPT},
+	"pos1-navicsig":    {"pos1-navicsig", 3, &prcopt_.IrnSig, nil, nil, IRNOPT},
 	"pos2-armode":      {"pos2-armode", 3, &prcopt_.ModeAr, nil, nil, ARMOPT},
 	"pos2-gloarmode":   {"pos2-gloarmode", 3, &prcopt_.GloModeAr, nil, nil, GAROPT},
 	"pos2-bdsarmode":   {"pos2-bdsarmode", 3, &prcopt_.BDSModeAr, nil, nil, SWTOPT},
@@ -115,8 +117,14 @@ var SysOpts map[string]*Opt = map[string]*Opt{
 	"pos2-maxage":      {"pos2-maxage", 1, nil, &prcopt_.MaxTmDiff, nil, "s"},
 	"pos2-syncsol":     {"pos2-syncsol", 3, &prcopt_.SyncSol, nil, nil, SWTOPT},
 	"pos2-slipthres":   {"pos2-slipthres", 1, nil, &prcopt_.ThresSlip, nil, "m"},
+	"pos2-slipthresr":  {"pos2-slipthresr", 1, nil, &prcopt_.ThresSlipR, nil, "m/s"},
+	"pos2-slipthresmw": {"pos2-slipthresmw", 1, nil, &prcopt_.ThresSlipMW, nil, "m"},
 	"pos2-rejionno":    {"pos2-rejionno", 1, nil, &prcopt_.MaxInno, nil, "m"},
+	"pos2-rejposinno":  {"pos2-rejposinno", 1, nil, &prcopt_.MaxPosInno, nil, "sigma"},
+	"pos2-posinnorst":  {"pos2-posinnorst", 3, &prcopt_.PosInnoRst, nil, nil, SWTOPT},
 	"pos2-rejgdop":     {"pos2-rejgdop", 1, nil, &prcopt_.MaxGdop, nil, ""},
+	"pos2-sppmaxiter":  {"pos2-sppmaxiter", 0, &prcopt_.SppMaxIter, nil, nil, ""},
+	"pos2-sppconvtol":  {"pos2-sppconvtol", 1, nil, &prcopt_.SppConvTol, nil, "m"},
 	"pos2-niter":       {"pos2-niter", 0, &prcopt_.NoIter, nil, nil, ""},
 	"pos2-baselen":     {"pos2-baselen", 1, nil, &prcopt_.Baseline[0], nil, "m"},
 	"pos2-basesig":     {"pos2-basesig", 1, nil, &prcopt_.Baseline[1], nil, "m"},
@@ -124,6 +132,7 @@ var SysOpts map[string]*Opt = map[string]*Opt{
 	"out-outhead":      {"out-outhead", 3, &solopt_.OutHead, nil, nil, SWTOPT},
 	"out-outopt":       {"out-outopt", 3, &solopt_.OutOpt, nil, nil, SWTOPT},
 	"out-outvel":       {"out-outvel", 3, &solopt_.OutVel, nil, nil, SWTOPT},
+	"out-outnsfreq":    {"out-outnsfreq", 3, &solopt_.OutNsFreq, nil, nil, SWTOPT},
 	"out-timesys":      {"out-timesys", 3, &solopt_.TimeS, nil, nil, TSYOPT},
 	"out-timeform":     {"out-timeform", 3, &solopt_.TimeF, nil, nil, TFTOPT},
 	"out-timendec":     {"out-timendec", 0, &solopt_.TimeU, nil, nil, ""},
@@ -157,6 +166,7 @@ var SysOpts map[string]*Opt = map[string]*Opt{
 	"ant1-pos1":        {"ant1-pos1", 1, nil, &antpos_[0][0], nil, "deg|m"},
 	"ant1-pos2":        {"ant1-pos2", 1, nil, &antpos_[0][1], nil, "deg|m"},
 	"ant1-pos3":        {"ant1-pos3", 1, nil, &antpos_[0][2], nil, "m|m"},
+	"ant1-staevent":    {"ant1-staevent", 3, &prcopt_.StaEvent, nil, nil, SWTOPT},
 	"ant1-anttype":     {"ant1-anttype", 2, nil, nil, &prcopt_.AntType[0], ""},
 	"ant1-antdele":     {"ant1-antdele", 1, nil, &prcopt_.AntDel[0][0], nil, "m"},
 	"ant1-antdeln":     {"ant1-antdeln", 1, nil, &prcopt_.AntDel[0][1], nil, "m"},
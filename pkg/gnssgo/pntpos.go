@@ -48,14 +48,15 @@ const (
 /* pseudorange measurement error variance ------------------------------------*/
 func VarianceErr(opt *PrcOpt, el float64, sys int) float64 {
 	var fact, varr float64
-	if sys == SYS_GLO {
+	switch sys {
+	case SYS_GLO:
 		fact = float64(EFACT_GLO)
-	} else {
-		if sys == SYS_SBS {
-			fact = float64(EFACT_SBS)
-		} else {
-			fact = float64(EFACT_GPS)
-		}
+	case SYS_SBS:
+		fact = float64(EFACT_SBS)
+	case SYS_IRN:
+		fact = float64(EFACT_IRN)
+	default:
+		fact = float64(EFACT_GPS)
 	}
 
 	if el < MIN_EL {
@@ -98,9 +99,18 @@ func (nav *Nav) GetTgd(sat int, dtype int) float64 {
 	}
 }
 
+/* frequency index of single-freq pseudorange -------------------------------*/
+func spfreq(sys int, opt *PrcOpt) int {
+	if sys == SYS_IRN && opt.IrnSig == 1 && opt.IonoOpt != IONOOPT_IFLC {
+		return 1 /* NavIC S */
+	}
+	return 0
+}
+
 /* test SNR mask -------------------------------------------------------------*/
 func snrmask(obs *ObsD, azel []float64, opt *PrcOpt) int {
-	if TestSnr(0, 0, azel[1], float64(obs.SNR[0])*float64(SNR_UNIT), &opt.SnrMask) > 0 {
+	f := spfreq(SatSys(obs.Sat, nil), opt)
+	if TestSnr(0, f, azel[1], float64(obs.SNR[f])*float64(SNR_UNIT), &opt.SnrMask) > 0 {
 		return 0
 	}
 	if opt.IonoOpt == IONOOPT_IFLC {
@@ -111,6 +121,30 @@ func snrmask(obs *ObsD, azel []float64, opt *PrcOpt) int {
 	return 1
 }
 
+/* receiver antenna parameters in the antenna list --------------------------*/
+func pcvrlist(time Gtime, opt *PrcOpt, rcv int) Pcv {
+	if pcv := opt.PcvList.SearchPcv(0, opt.AntType[rcv], time); pcv != nil {
+		return *pcv
+	}
+	Trace(3, "no receiver antenna pcv: %s\n", opt.AntType[rcv])
+	return Pcv{}
+}
+
+/* receiver antenna correction of pseudorange --------------------------------*/
+func rcvantcorr(obs *ObsD, nav *Nav, opt *PrcOpt, azel []float64) float64 {
+	var dant [NFREQ]float64
+	AntModel(&opt.Pcvr[0], opt.AntDel[0][:], azel, opt.PosOpt[1], dant[:])
+	if opt.IonoOpt != IONOOPT_IFLC {
+		return dant[spfreq(SatSys(obs.Sat, nil), opt)]
+	}
+	f1 := SQR(Sat2Freq(obs.Sat, obs.Code[0], nav))
+	f2 := SQR(Sat2Freq(obs.Sat, obs.Code[1], nav))
+	if f1 == 0.0 || f2 == 0.0 {
+		return dant[0]
+	}
+	return (f1*dant[0] - f2*dant[1]) / (f1 - f2)
+}
+
 /* psendorange with code bias correction -------------------------------------*/
 func Prange(obs *ObsD, nav *Nav, opt *PrcOpt, vari *float64) float64 {
 	var (
@@ -120,8 +154,9 @@ func Prange(obs *ObsD, nav *Nav, opt *PrcOpt, vari *float64) float64 {
 	)
 	sat = int(obs.Sat)
 	sys = SatSys(sat, nil)
-	P1 = obs.P[0]
-	code1 = obs.Code[0]
+	f := spfreq(sys, opt)
+	P1 = obs.P[f]
+	code1 = obs.Code[f]
 	switch {
 	case obs.Code[1] != 0:
 		P2 = obs.P[1]
@@ -185,40 +220,61 @@ func Prange(obs *ObsD, nav *Nav, opt *PrcOpt, vari *float64) float64 {
 		}
 	} else { /* single-freq (L1/E1/B1) */
 		*vari = SQR(ERR_CBIAS)
-
-		switch sys {
-		case SYS_GPS, SYS_QZS: /* L1 */
-			b1 = nav.GetTgd(sat, 0) /* TGD (m) */
-			return P1 - b1
-		case SYS_GLO: /* G1 */
-			gamma = SQR(FREQ1_GLO / FREQ2_GLO)
-			b1 = nav.GetTgd(sat, 0) /* -dtaun (m) */
-			return P1 - b1/(gamma-1.0)
-		case SYS_GAL: /* E1 */
-			if GetSelEph(SYS_GAL) > 0 {
-				b1 = nav.GetTgd(sat, 0) /* BGD_E1E5a */
-			} else {
-				b1 = nav.GetTgd(sat, 1) /* BGD_E1E5b */
-			}
-			return P1 - b1
-		case SYS_CMP: /* B1I/B1Cp/B1Cd */
-			if code1 == CODE_L2I {
-				b1 = nav.GetTgd(sat, 0) /* TGD_B1I */
-			} else if code1 == CODE_L1P {
-				b1 = nav.GetTgd(sat, 2) /* TGD_B1Cp */
-			} else {
-				b1 = nav.GetTgd(sat, 2) + nav.GetTgd(sat, 4) /* TGD_B1Cp+ISC_B1Cd */
-			}
-			return P1 - b1
-		case SYS_IRN: /* L5 */
-			gamma = SQR(FREQ9 / FREQ5)
-			b1 = nav.GetTgd(sat, 0) /* TGD (m) */
-			return P1 - gamma*b1
-		}
+		return P1 - GrpDelay(obs, nav, opt)
 	}
 	return P1
 }
 
+/* broadcast group delay -------------------------------------------------------
+* broadcast group delay correction of the single-freq pseudorange
+* args   : obsd_t *obs      I   observation data
+*          nav_t  *nav      I   navigation data
+*          prcopt_t *opt    I   processing options
+* return : group delay subtracted from the pseudorange (m)
+* notes  : TGD for GPS/QZS/NavIC, BGD for Galileo, TGD1/TGD2 for BeiDou and
+*          -dtaun for GLONASS. 0 with the iono-free LC or unknown ephemeris
+*-----------------------------------------------------------------------------*/
+func GrpDelay(obs *ObsD, nav *Nav, opt *PrcOpt) float64 {
+	var gamma, b1 float64
+
+	if opt.IonoOpt == IONOOPT_IFLC {
+		return 0.0
+	}
+	sat := int(obs.Sat)
+	sys := SatSys(sat, nil)
+	f := spfreq(sys, opt)
+	code1 := obs.Code[f]
+
+	switch sys {
+	case SYS_GPS, SYS_QZS: /* L1 */
+		return nav.GetTgd(sat, 0) /* TGD (m) */
+	case SYS_GLO: /* G1 */
+		gamma = SQR(FREQ1_GLO / FREQ2_GLO)
+		b1 = nav.GetTgd(sat, 0) /* -dtaun (m) */
+		return b1 / (gamma - 1.0)
+	case SYS_GAL: /* E1 */
+		if GetSelEph(SYS_GAL) > 0 {
+			return nav.GetTgd(sat, 0) /* BGD_E1E5a */
+		}
+		return nav.GetTgd(sat, 1) /* BGD_E1E5b */
+	case SYS_CMP: /* B1I/B1Cp/B1Cd */
+		if code1 == CODE_L2I {
+			return nav.GetTgd(sat, 0) /* TGD_B1I */
+		} else if code1 == CODE_L1P {
+			return nav.GetTgd(sat, 2) /* TGD_B1Cp */
+		}
+		return nav.GetTgd(sat, 2) + nav.GetTgd(sat, 4) /* TGD_B1Cp+ISC_B1Cd */
+	case SYS_IRN: /* L5/S */
+		b1 = nav.GetTgd(sat, 0) /* TGD (m) */
+		if f == 1 {
+			return b1
+		}
+		gamma = SQR(FREQ9 / FREQ5)
+		return gamma * b1
+	}
+	return 0.0
+}
+
 /* ionospheric correction ------------------------------------------------------
 * compute ionospheric correction
 * args   : gtime_t time     I   time
@@ -309,6 +365,7 @@ func Residuals(iter int, obs []ObsD, n int, rs, dts, vare []float64, svh []int,
 	var (
 		time                                           Gtime
 		r, freq, dion, dtrp, vmeas, vion, vtrp, dtr, P float64
+		dant                                           float64
 		rr, pos, e                                     [3]float64
 		i, j, nv, sat, sys                             int
 		mask                                           [NXParam - 3]int
@@ -363,7 +420,7 @@ func Residuals(iter int, obs []ObsD, n int, rs, dts, vare []float64, svh []int,
 			if nav.IonoCorr(time, sat, pos[:], azel[i*2:], opt.IonoOpt, &dion, &vion) == 0 {
 				continue
 			}
-			if freq = Sat2Freq(sat, obs[i].Code[0], nav); freq == 0.0 {
+			if freq = Sat2Freq(sat, obs[i].Code[spfreq(sys, opt)], nav); freq == 0.0 {
 				continue
 			}
 			dion *= SQR(FREQ1 / freq)
@@ -373,6 +430,11 @@ func Residuals(iter int, obs []ObsD, n int, rs, dts, vare []float64, svh []int,
 			if nav.TropCorr(time, pos[:], azel[i*2:], opt.TropOpt, &dtrp, &vtrp) == 0 {
 				continue
 			}
+
+			/* receiver antenna phase center correction */
+			if opt.PcvList != nil {
+				dant = rcvantcorr(&obs[i], nav, opt, azel[i*2:])
+			}
 		}
 		/* psendorange with code bias correction */
 		if P = Prange(&obs[i], nav, opt, &vmeas); P == 0.0 {
@@ -380,7 +442,7 @@ func Residuals(iter int, obs []ObsD, n int, rs, dts, vare []float64, svh []int,
 		}
 
 		/* pseudorange residual */
-		v[nv] = P - (r + dtr - CLIGHT*dts[i*2] + dion + dtrp)
+		v[nv] = P - (r + dtr - CLIGHT*dts[i*2] + dion + dtrp + dant)
 
 		/* design matrix */
 		for j = 0; j < NXParam; j++ {
@@ -443,6 +505,26 @@ func Residuals(iter int, obs []ObsD, n int, rs, dts, vare []float64, svh []int,
 	return nv
 }
 
+/* number of valid satellites per frequency ----------------------------------*/
+func nsfreq(obs []ObsD, n int, vsat []int, opt *PrcOpt, ns []uint8) {
+	for f := range ns {
+		ns[f] = 0
+	}
+	for i := 0; i < n && i < MAXOBS; i++ {
+		if vsat[i] == 0 {
+			continue
+		}
+		ns[spfreq(SatSys(obs[i].Sat, nil), opt)]++
+		if opt.IonoOpt == IONOOPT_IFLC { /* second frequency of iono-free LC */
+			if obs[i].Code[1] != 0 {
+				ns[1]++
+			} else {
+				ns[2]++
+			}
+		}
+	}
+}
+
 /* validate solution ---------------------------------------------------------*/
 func ValSol(azel []float64, vsat []int, n int, opt *PrcOpt, v []float64, nv, nx int, msg *string) int {
 	var (
@@ -500,7 +582,14 @@ func EstimatePos(obs []ObsD, n int, rs, dts, vare []float64, svh []int, nav *Nav
 		x[i] = sol.Rr[i]
 	}
 
-	for i = 0; i < MAXITR; i++ {
+	maxiter, convtol := MAXITR, 1e-4
+	if opt.SppMaxIter > 0 {
+		maxiter = opt.SppMaxIter
+	}
+	if opt.SppConvTol > 0.0 {
+		convtol = opt.SppConvTol
+	}
+	for i = 0; i < maxiter; i++ {
 
 		/* pseudorange residuals (m) */
 		nv = Residuals(i, obs, n, rs, dts, vare, svh, nav, x[:], opt, v, H, vari, azel, vsat, resp, &ns)
@@ -525,7 +614,8 @@ func EstimatePos(obs []ObsD, n int, rs, dts, vare []float64, svh []int, nav *Nav
 		for j = 0; j < NXParam; j++ {
 			x[j] += dx[j]
 		}
-		if Norm(dx[:], NXParam) < 1e-4 {
+		/* azel are not computed in the first iteration */
+		if i > 0 && Norm(dx[:], NXParam) < convtol {
 			sol.Type = 0
 			sol.Time = TimeAdd(obs[0].Time, -x[3]/CLIGHT)
 			sol.Dtr[0] = x[3] / CLIGHT /* receiver clock bias (s) */
@@ -546,7 +636,9 @@ func EstimatePos(obs []ObsD, n int, rs, dts, vare []float64, svh []int, nav *Nav
 			sol.Qr[4] = float32(Q[2+NXParam]) /* cov yz */
 			sol.Qr[5] = float32(Q[2])         /* cov zx */
 			sol.Ns = uint8(ns)
+			nsfreq(obs, n, vsat, opt, sol.NsFreq[:])
 			sol.Age, sol.Ratio = 0.0, 0.0
+			sol.ARRatio, sol.NFixed = 0.0, 0
 
 			/* validate solution */
 			if stat = ValSol(azel, vsat, n, opt, v, nv, NXParam, msg); stat > 0 {
@@ -561,8 +653,8 @@ func EstimatePos(obs []ObsD, n int, rs, dts, vare []float64, svh []int, nav *Nav
 			return stat
 		}
 	}
-	if i >= MAXITR {
-		*msg = fmt.Sprintf("iteration divergent i=%d", i)
+	if i >= maxiter {
+		*msg = fmt.Sprintf("iteration divergent i=%d dx=%.3g m", i, Norm(dx[:], NXParam))
 	}
 
 	return 0
@@ -774,6 +866,11 @@ func EstVel(obs []ObsD, n int, rs, dts []float64, nav *Nav, opt *PrcOpt, sol *So
 *          ssat_t *ssat     IO  satellite status              (NULL: no output)
 *          char   *msg      O   error message for error exit
 * return : status(1:ok,0:error)
+* notes  : missing pseudoranges, carrier-phases and dopplers (see CleanObs) are
+*          excluded
+*          the position is iterated up to opt.SppMaxIter times until the
+*          correction is below opt.SppConvTol (m). without convergence the
+*          status is error, sol.stat is SOLQ_NONE and sol.rr is not changed.
 *-----------------------------------------------------------------------------*/
 func PntPos(obs []ObsD, n int, nav *Nav, opt *PrcOpt, sol *Sol, azel []float64, ssat []SSat, msg *string) int {
 	var (
@@ -794,6 +891,9 @@ func PntPos(obs []ObsD, n int, nav *Nav, opt *PrcOpt, sol *Sol, azel []float64,
 	sol.Time = obs[0].Time
 	*msg = ""
 
+	/* exclude missing values (0.0, sentinels) */
+	obs, _ = CleanObs(obs, n)
+
 	rs = Mat(6, n)
 	dts = Mat(2, n)
 	vari = Mat(1, n)
@@ -804,6 +904,9 @@ func PntPos(obs []ObsD, n int, nav *Nav, opt *PrcOpt, sol *Sol, azel []float64,
 		opt_.IonoOpt = IONOOPT_BRDC
 		opt_.TropOpt = TROPOPT_SAAS
 	}
+	if opt_.PcvList != nil { /* rover antenna in the antenna list */
+		opt_.Pcvr[0] = pcvrlist(sol.Time, &opt_, 0)
+	}
 	/* satellite positons, velocities and clocks */
 	nav.SatPoss(sol.Time, obs, n, opt_.SatEph, rs, dts, vari, svh[:])
 
@@ -832,6 +935,7 @@ func PntPos(obs []ObsD, n int, nav *Nav, opt *PrcOpt, sol *Sol, azel []float64,
 			ssat[i].Azel[0], ssat[i].Azel[1] = 0.0, 0.0
 			ssat[i].Resp[0], ssat[i].Resc[0] = 0.0, 0.0
 			ssat[i].Snr[0] = 0
+			ssat[i].Tgd = 0.0
 		}
 		for i = 0; i < n; i++ {
 			ssat[obs[i].Sat-1].Azel[0] = float64(azel_[i*2])
@@ -842,6 +946,7 @@ func PntPos(obs []ObsD, n int, nav *Nav, opt *PrcOpt, sol *Sol, azel []float64,
 			}
 			ssat[obs[i].Sat-1].Vs = 1
 			ssat[obs[i].Sat-1].Resp[0] = float32(resp[i])
+			ssat[obs[i].Sat-1].Tgd = GrpDelay(&obs[i], nav, &opt_)
 		}
 	}
 	return stat
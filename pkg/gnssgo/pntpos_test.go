@@ -0,0 +1,332 @@
+package gnssgo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// synthIrnObs simulates GPS and NavIC observations at t0. NavIC pseudoranges
+// carry a per-satellite group delay (TGD) and L5 is dropped if noL5 is set.
+func synthIrnObs(t0 Gtime, noL5 bool) (*Nav, []ObsD) {
+	nav := synthNav(t0, 24)
+	for k := 0; k < 7; k++ {
+		eph := synthEph(SatNo(SYS_IRN, k+1), 4*k+2, t0)
+		eph.Tgd[0] = float64(k+1) * 2e-9
+		nav.Ephs = append(nav.Ephs, eph)
+	}
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+	for i := range obs {
+		if SatSys(obs[i].Sat, nil) != SYS_IRN {
+			continue
+		}
+		tgd := nav.Ephs[len(nav.Ephs)-7+obs[i].Sat-SatNo(SYS_IRN, 1)].Tgd[0]
+		obs[i].P[0] += SQR(FREQ9/FREQ5) * tgd * CLIGHT
+		obs[i].P[1] += tgd * CLIGHT
+		if noL5 {
+			obs[i].P[0], obs[i].Code[0] = 0.0, CODE_NONE
+		}
+	}
+	return nav, obs
+}
+
+// TestPntPosNavIC checks that NavIC L5 and S-band pseudoranges enter the
+// single point solution.
+func TestPntPosNavIC(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+
+	tests := []struct {
+		name   string
+		irnSig int
+		noL5   bool
+		want   bool /* navic sats used */
+	}{
+		{"L5", 0, false, true},
+		{"S", 1, true, true},
+		{"L5 missing", 0, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				sol Sol
+				msg string
+			)
+			nav, obs := synthIrnObs(t0, tt.noL5)
+			ssat := make([]SSat, MAXSAT)
+			opt := DefaultProcOpt()
+			opt.NavSys = SYS_GPS | SYS_IRN
+			opt.Elmin = 10.0 * D2R
+			opt.IrnSig = tt.irnSig
+
+			if PntPos(obs, len(obs), nav, &opt, &sol, nil, ssat, &msg) == 0 {
+				t.Fatalf("pntpos failed: %s", msg)
+			}
+			nirn := 0
+			for i := range obs {
+				if SatSys(obs[i].Sat, nil) == SYS_IRN && ssat[obs[i].Sat-1].Vs != 0 {
+					nirn++
+				}
+			}
+			if got := nirn > 0; got != tt.want {
+				t.Fatalf("navic sats used = %d, want used %v", nirn, tt.want)
+			}
+			if d := synthDist(sol.Rr[:], synthRover[:]); d > 1e-3 {
+				t.Errorf("position error = %.4f m", d)
+			}
+		})
+	}
+}
+
+// TestPntPosTgd checks that the GPS TGD is applied to L1-only pseudoranges.
+func TestPntPosTgd(t *testing.T) {
+	var (
+		sol Sol
+		msg string
+	)
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	for i := range nav.Ephs {
+		nav.Ephs[i].Tgd[0] = float64(i%5+1) * -3e-9
+	}
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+	for i := range obs {
+		obs[i].P[0] += nav.GetTgd(obs[i].Sat, 0)
+		obs[i].P[1], obs[i].Code[1] = 0.0, CODE_NONE
+	}
+	ssat := make([]SSat, MAXSAT)
+	opt := DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+
+	if PntPos(obs, len(obs), nav, &opt, &sol, nil, ssat, &msg) == 0 {
+		t.Fatalf("pntpos failed: %s", msg)
+	}
+	if d := synthDist(sol.Rr[:], synthRover[:]); d > 1e-3 {
+		t.Errorf("position error = %.4f m", d)
+	}
+	nused := 0
+	for i := range obs {
+		s := &ssat[obs[i].Sat-1]
+		if s.Vs == 0 {
+			continue
+		}
+		nused++
+		if want := nav.GetTgd(obs[i].Sat, 0); math.Abs(s.Tgd-want) > 1e-9 {
+			t.Errorf("sat %d: tgd = %.4f m, want %.4f m", obs[i].Sat, s.Tgd, want)
+		}
+	}
+	if nused < 4 {
+		t.Fatalf("satellites used = %d", nused)
+	}
+}
+
+// TestPntPosExactInit checks that the solution is validated with elevations if
+// the initial position and clock are already exact.
+func TestPntPosExactInit(t *testing.T) {
+	var (
+		sol Sol
+		msg string
+	)
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+	ssat := make([]SSat, MAXSAT)
+	opt := DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+
+	copy(sol.Rr[:3], synthRover[:])
+	if PntPos(obs, len(obs), nav, &opt, &sol, nil, ssat, &msg) == 0 {
+		t.Fatalf("pntpos failed: %s", msg)
+	}
+	if d := synthDist(sol.Rr[:], synthRover[:]); d > 1e-3 {
+		t.Errorf("position error = %.4f m", d)
+	}
+}
+
+// TestPntPosMissingObs checks that zero and sentinel pseudoranges are ignored
+// as missing instead of entering the solution as ranges.
+func TestPntPosMissingObs(t *testing.T) {
+	var (
+		sol Sol
+		msg string
+	)
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+	if len(obs) < 9 {
+		t.Fatalf("visible sats = %d, want >= 9", len(obs))
+	}
+	bad := []float64{0.0, math.NaN(), math.Inf(1), -2.0e7, 1e10}
+	for i, v := range bad {
+		obs[i].P[0], obs[i].P[1] = v, v
+		obs[i].D[0] = math.NaN()
+	}
+	ssat := make([]SSat, MAXSAT)
+	opt := DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+
+	if PntPos(obs, len(obs), nav, &opt, &sol, nil, ssat, &msg) == 0 {
+		t.Fatalf("pntpos failed: %s", msg)
+	}
+	if d := synthDist(sol.Rr[:], synthRover[:]); d > 1e-3 {
+		t.Errorf("position error = %.4f m", d)
+	}
+	if v := math.Sqrt(SQR(sol.Rr[3]) + SQR(sol.Rr[4]) + SQR(sol.Rr[5])); math.IsNaN(v) || v > 1e-2 {
+		t.Errorf("velocity = %.4f m/s", v)
+	}
+	for i := range obs {
+		if used := ssat[obs[i].Sat-1].Vs != 0; used != (i >= len(bad)) {
+			t.Errorf("sat %d: used = %v", obs[i].Sat, used)
+		}
+	}
+	if !math.IsNaN(obs[1].P[0]) {
+		t.Errorf("input observation changed: P = %.3f", obs[1].P[0])
+	}
+}
+
+// TestCleanObs checks the missing value conventions.
+func TestCleanObs(t *testing.T) {
+	obs := []ObsD{{Sat: 1}, {Sat: 2}}
+	obs[0].P[0], obs[0].L[0], obs[0].D[0] = 2.1e7, 1.1e8, -1200.0
+	obs[0].L[1] = -3.5e7 /* negative phase is valid */
+	obs[1].P[0], obs[1].P[1], obs[1].L[0], obs[1].D[0] = -1.0, math.NaN(), 1e9, math.Inf(-1)
+
+	if data, nm := CleanObs(obs[:1], 1); nm != 0 || &data[0] != &obs[0] {
+		t.Errorf("valid data: missing=%d copied=%v", nm, &data[0] != &obs[0])
+	}
+	data, nm := CleanObs(obs, 2)
+	if nm != 4 {
+		t.Errorf("missing values = %d, want 4", nm)
+	}
+	if data[0] != obs[0] {
+		t.Errorf("valid observation changed: %+v", data[0])
+	}
+	if data[1].P[0] != 0.0 || data[1].P[1] != 0.0 || data[1].L[0] != 0.0 || data[1].D[0] != 0.0 {
+		t.Errorf("missing values not cleared: P=%v L=%v D=%v", data[1].P, data[1].L, data[1].D)
+	}
+}
+
+// TestPntPosConvergence checks that a position not converging within the
+// iteration limit is reported as an error instead of a solution.
+func TestPntPosConvergence(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+
+	/* six satellites within 0.1 deg near the zenith with 5 m range errors */
+	degen := new(Nav)
+	for j := 0; j < 6; j++ {
+		eph := synthEph(SatNo(SYS_GPS, j+1), 12, t0)
+		eph.M0 += float64(j%3) * 0.05 * D2R
+		eph.OMG0 += float64(j/3) * 0.05 * D2R
+		degen.Ephs = append(degen.Ephs, eph)
+	}
+	degenObs := synthObs(degen, t0, synthRover, 1, 0.0)
+	for i := range degenObs {
+		degenObs[i].P[0] += float64(i%3-1) * 5.0
+	}
+	nav := synthNav(t0, 24)
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+
+	tests := []struct {
+		name    string
+		nav     *Nav
+		obs     []ObsD
+		maxiter int
+		convtol float64
+		want    int
+	}{
+		{"degenerate", degen, degenObs, 0, 0.0, 0},
+		{"degenerate more iterations", degen, degenObs, 50, 0.0, 0},
+		{"one iteration", nav, obs, 1, 0.0, 0},
+		{"default", nav, obs, 0, 0.0, 1},
+		{"tight tolerance", nav, obs, 20, 1e-7, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg string
+			sol := Sol{Rr: [6]float64{1.0, 2.0, 3.0}}
+			opt := DefaultProcOpt()
+			opt.SppMaxIter = tt.maxiter
+			opt.SppConvTol = tt.convtol
+
+			stat := PntPos(tt.obs, len(tt.obs), tt.nav, &opt, &sol, nil, nil, &msg)
+			if stat != tt.want {
+				t.Fatalf("stat = %d, want %d (%s)", stat, tt.want, msg)
+			}
+			if stat == 0 {
+				if sol.Stat != SOLQ_NONE || !strings.Contains(msg, "iteration divergent") {
+					t.Errorf("sol stat = %d msg = %q, want none and divergent", sol.Stat, msg)
+				}
+				if sol.Rr[0] != 1.0 || sol.Rr[1] != 2.0 || sol.Rr[2] != 3.0 {
+					t.Errorf("position changed: %.3f %.3f %.3f", sol.Rr[0], sol.Rr[1], sol.Rr[2])
+				}
+			} else if d := synthDist(sol.Rr[:], synthRover[:]); d > 1e-3 {
+				t.Errorf("position error = %.4f m", d)
+			}
+		})
+	}
+}
+
+// TestPntPosPcvList checks the receiver antenna offset and pcv of the antenna
+// list are applied to the pseudoranges of PntPos and set by RtkPos.
+func TestPntPosPcvList(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	pcvs, err := ReadANTEX(writeAntex(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nav := synthNav(t0, 24)
+	obs := synthObs(nav, t0, synthRover, 1, 0.0)
+
+	/* pseudoranges of the antenna phase center */
+	var (
+		rs, pos, e [3]float64
+		azel       [2]float64
+		dant       [NFREQ]float64
+		dts, vari  float64
+	)
+	pcv := pcvs.SearchPcv(0, "TRM59800.00 NONE", t0)
+	Ecef2Pos(synthRover[:], pos[:])
+	for i := range obs {
+		for j := range nav.Ephs {
+			if nav.Ephs[j].Sat == obs[i].Sat {
+				Eph2Pos(TimeAdd(t0, -0.075), &nav.Ephs[j], rs[:], &dts, &vari)
+			}
+		}
+		GeoDist(rs[:], synthRover[:], e[:])
+		SatAzel(pos[:], e[:], azel[:])
+		AntModel(pcv, []float64{0, 0, 0}, azel[:], 1, dant[:])
+		obs[i].P[0] += dant[0]
+	}
+
+	opt := DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+	opt.AntType[0] = "TRM59800.00 NONE"
+	opt.PosOpt[1] = 1
+	for _, tt := range []struct {
+		pcvs   *PcvList
+		within bool
+	}{
+		{nil, false}, {pcvs, true},
+	} {
+		var (
+			sol Sol
+			msg string
+		)
+		opt.PcvList = tt.pcvs
+		if PntPos(obs, len(obs), nav, &opt, &sol, nil, nil, &msg) == 0 {
+			t.Fatalf("pntpos failed: %s", msg)
+		}
+		if d := synthDist(sol.Rr[:], synthRover[:]); (d < 1e-3) != tt.within {
+			t.Errorf("pcv list=%v: position error = %.4f m", tt.pcvs != nil, d)
+		}
+	}
+
+	rtk := new(Rtk)
+	rtk.InitRtk(&opt)
+	if rtk.RtkPos(obs, len(obs), nav) == 0 {
+		t.Fatalf("rtkpos failed: %s", rtk.ErrBuf)
+	}
+	if rtk.Opt.Pcvr[0].Type != pcv.Type || synthDist(rtk.RtkSol.Rr[:], synthRover[:]) > 1e-3 {
+		t.Errorf("rtkpos: antenna %q, position error = %.4f m", rtk.Opt.Pcvr[0].Type,
+			synthDist(rtk.RtkSol.Rr[:], synthRover[:]))
+	}
+}
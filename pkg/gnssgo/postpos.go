@@ -68,6 +68,7 @@ var (
 	navs      Nav             /* navigation data */
 	sbss      Sbs             /* sbas messages */
 	stas      [MAXRCV]Sta     /* station infomation */
+	antsta    [2]bool         /* antenna set by station parameters {rov,base} */
 	nepoch    int         = 0 /* number of observation epochs */
 	iobsu     int         = 0 /* current rover observation data index */
 	iobsr     int         = 0 /* current reference observation data index */
@@ -444,6 +445,9 @@ func ProcPos(fp *os.File, popt *PrcOpt, sopt *SolOpt, mode int) {
 	rtk.InitRtk(popt)
 	rtcm_path = ""
 
+	/* number of station change events applied {rov,base} */
+	ista := []int{0, 0}
+
 	for {
 		nobs = InputObs(obs[:], int(rtk.RtkSol.Stat), popt)
 		if nobs < 0 {
@@ -460,6 +464,11 @@ func ProcPos(fp *os.File, popt *PrcOpt, sopt *SolOpt, mode int) {
 			continue
 		}
 
+		/* station changes by RINEX event records */
+		if popt.StaEvent > 0 {
+			ApplyStaEvent(&rtk, obs[:], n, stas[:], &pcvsr, ista)
+		}
+
 		/* carrier-phase bias correction */
 		if !strings.Contains(string(popt.PPPOpt[:]), "-ENA_FCB") {
 			CorrPhaseBiasSsr(obs[:], n, &navs)
@@ -1028,11 +1037,10 @@ func CloseSession(nav *Nav, pcvs, pcvr *Pcvs) {
 /* set antenna parameters ----------------------------------------------------*/
 func SetPcv(time Gtime, popt *PrcOpt, nav *Nav, pcvs, pcvr *Pcvs, sta []Sta) {
 	var (
-		pcv0     Pcv
-		pcv      *Pcv
-		pos, del [3]float64
-		i, j     int
-		id       string
+		pcv0 Pcv
+		pcv  *Pcv
+		i    int
+		id   string
 	)
 	mode := PMODE_DGPS <= popt.Mode && popt.Mode <= PMODE_FIXED
 
@@ -1054,23 +1062,12 @@ func SetPcv(time Gtime, popt *PrcOpt, nav *Nav, pcvs, pcvr *Pcvs, sta []Sta) {
 		imode = 2
 	}
 	for i = 0; i < imode; i++ {
-		popt.Pcvr[i] = pcv0
-		if strings.Compare(string(popt.AntType[i][:]), "*") == 0 { /* set by station parameters */
-			popt.AntType[i] = string(sta[i].AntDes[:])
-			if sta[i].DelType == 1 { /* xyz */
-				if Norm(sta[i].Pos[:], 3) > 0.0 {
-					Ecef2Pos(sta[i].Pos[:], pos[:])
-					Ecef2Enu(pos[:], sta[i].Del[:], del[:])
-					for j = 0; j < 3; j++ {
-						popt.AntDel[i][j] = float64(del[j])
-					}
-				}
-			} else { /* enu */
-				for j = 0; j < 3; j++ {
-					popt.AntDel[i][j] = float64(stas[i].Del[j])
-				}
-			}
+		antsta[i] = strings.Compare(string(popt.AntType[i][:]), "*") == 0
+		if antsta[i] { /* set by station parameters */
+			SetAntSta(time, popt, i, pcvr, &sta[i])
+			continue
 		}
+		popt.Pcvr[i] = pcv0
 		if pcv = SearchPcv(0, popt.AntType[i], time, pcvr); pcv == nil {
 			Trace(3, "no receiver antenna pcv: %s\n", popt.AntType[i])
 			popt.AntType[i] = ""
@@ -1081,6 +1078,80 @@ func SetPcv(time Gtime, popt *PrcOpt, nav *Nav, pcvs, pcvr *Pcvs, sta []Sta) {
 	}
 }
 
+/* set receiver antenna parameters by station parameters ---------------------*/
+func SetAntSta(time Gtime, popt *PrcOpt, i int, pcvr *Pcvs, sta *Sta) {
+	var (
+		pcv      *Pcv
+		pos, del [3]float64
+		j        int
+	)
+	popt.Pcvr[i] = Pcv{}
+	popt.AntType[i] = string(sta.AntDes[:])
+	if sta.DelType == 1 { /* xyz */
+		if Norm(sta.Pos[:], 3) > 0.0 {
+			Ecef2Pos(sta.Pos[:], pos[:])
+			Ecef2Enu(pos[:], sta.Del[:], del[:])
+			for j = 0; j < 3; j++ {
+				popt.AntDel[i][j] = float64(del[j])
+			}
+		}
+	} else { /* enu */
+		for j = 0; j < 3; j++ {
+			popt.AntDel[i][j] = float64(sta.Del[j])
+		}
+	}
+	if pcv = SearchPcv(0, popt.AntType[i], time, pcvr); pcv == nil {
+		Trace(3, "no receiver antenna pcv: %s\n", popt.AntType[i])
+		popt.AntType[i] = ""
+		return
+	}
+	popt.AntType[i] = pcv.Type
+	popt.Pcvr[i] = *pcv
+}
+
+/* apply station changes by RINEX event records ------------------------------
+* reset the receiver antenna parameters set by the station parameters to the
+* ones in effect at the epoch and flag a cycle slip on all phases of the
+* receiver at the epoch of a change. ista holds the number of station change
+* events applied per receiver {rov,base}.
+*-----------------------------------------------------------------------------*/
+func ApplyStaEvent(rtk *Rtk, obs []ObsD, n int, sta []Sta, pcvr *Pcvs, ista []int) {
+	var (
+		i, j, k, f, nrcv int = 0, 0, 0, 0, 1
+		p                *Sta
+	)
+	if n <= 0 {
+		return
+	}
+	if PMODE_DGPS <= rtk.Opt.Mode && rtk.Opt.Mode <= PMODE_FIXED {
+		nrcv = 2
+	}
+	for i = 0; i < nrcv; i++ {
+		if p, k = sta[i].StaAt(obs[0].Time); k != ista[i] {
+			Trace(2, "station change: time=%s rcv=%d ant=%s rec=%s\n", TimeStr(obs[0].Time, 0),
+				i+1, p.AntDes, p.Type)
+			ista[i] = k
+			if antsta[i] {
+				SetAntSta(obs[0].Time, &rtk.Opt, i, pcvr, p)
+			}
+		}
+		/* the phases are not continuous across the change */
+		for k = 0; k < len(sta[i].Events); k++ {
+			if math.Abs(TimeDiff(sta[i].Events[k].Time, obs[0].Time)) > DTTOL {
+				continue
+			}
+			for j = 0; j < n; j++ {
+				if obs[j].Rcv != i+1 {
+					continue
+				}
+				for f = 0; f < NFREQ+NEXOBS; f++ {
+					obs[j].LLI[f] |= 1
+				}
+			}
+		}
+	}
+}
+
 /* read ocean tide loading parameters ----------------------------------------*/
 func ReadOtl(popt *PrcOpt, file string, sta []Sta) {
 	var imode int = 1
@@ -435,6 +435,8 @@ func PPPVarianceErr(sat, sys int, el float64, idx, itype int, opt *PrcOpt) float
 
 	} else if sys == SYS_SBS {
 		fact *= float64(EFACT_SBS)
+	} else if sys == SYS_IRN {
+		fact *= float64(EFACT_IRN)
 	} else {
 		fact *= float64(EFACT_GPS)
 	}
@@ -1360,6 +1362,7 @@ func (rtk *Rtk) UpdateStat(obs []ObsD, n, stat int) {
 
 	/* test # of valid satellites */
 	rtk.RtkSol.Ns = 0
+	rtk.RtkSol.NsFreq = [NFREQ]uint8{}
 	for i = 0; i < n && i < len(obs); i++ {
 		for j = 0; j < opt.Nf; j++ {
 			if rtk.Ssat[obs[i].Sat-1].Vsat[j] == 0 {
@@ -1367,6 +1370,7 @@ func (rtk *Rtk) UpdateStat(obs []ObsD, n, stat int) {
 			}
 			rtk.Ssat[obs[i].Sat-1].Lock[j]++
 			rtk.Ssat[obs[i].Sat-1].Outc[j] = 0
+			rtk.RtkSol.NsFreq[j]++
 			if j == 0 {
 				rtk.RtkSol.Ns++
 			}
@@ -62,10 +62,12 @@ import (
 )
 
 const (
-	NMAX       = 10    /* order of polynomial interpolation */
-	MAXDTE     = 900.0 /* max time difference to ephem time (s) */
-	EXTERR_CLK = 1e-3  /* extrapolation error for clock (m/s) */
-	EXTERR_EPH = 5e-7 /* extrapolation error for ephem (m/s^2) */)
+	NMAX       = 10     /* order of polynomial interpolation */
+	MAXDTE     = 900.0  /* max time difference to ephem time (s) */
+	EXTERR_CLK = 1e-3   /* extrapolation error for clock (m/s) */
+	EXTERR_EPH = 5e-7   /* extrapolation error for ephem (m/s^2) */
+	MAXGAPCLK  = 3600.0 /* max gap of precise clock to interpolate (s) */
+)
 
 /* satellite code to satellite system ----------------------------------------*/
 func code2sys(code rune) int {
@@ -93,13 +95,28 @@ func code2sys(code rune) int {
 	return SYS_NONE
 }
 
+/* satellite number of sp3 satellite id -------------------------------------*/
+func sp3satno(buff string, i int) int {
+	if len(buff) < i+3 {
+		return 0
+	}
+	sys := code2sys(rune(buff[i]))
+	prn := int(Str2Num(buff, i+1, 2))
+	if sys == SYS_SBS {
+		prn += 100
+	} else if sys == SYS_QZS {
+		prn += 192 /* extension to sp3-c */
+	}
+	return SatNo(sys, prn)
+}
+
 /* read SP3 header -----------------------------------------------------------*/
 func ReadSp3Header(rd *bufio.Reader, time *Gtime, ctype *string, sats []int, bfact []float64, tsys *string) int {
 	var (
-		i, j, k, ns, sys, prn int
-		buff                  string
-		prefix                []byte
-		err                   error
+		i, j, k, ns int
+		buff        string
+		prefix      []byte
+		err         error
 	)
 
 	Trace(4, "readsp3h:\n")
@@ -109,10 +126,7 @@ func ReadSp3Header(rd *bufio.Reader, time *Gtime, ctype *string, sats []int, bfa
 		if err != nil {
 			break
 		}
-		if strings.Compare(string(prefix), "* ") == 0 { /* first record */
-			/* roll back file pointer */
-			//	fp.Seek(int64(-len(buff)), 1)
-
+		if prefix[0] == '*' { /* first record */
 			break
 		}
 		buff, _ = rd.ReadString('\n')
@@ -121,8 +135,11 @@ func ReadSp3Header(rd *bufio.Reader, time *Gtime, ctype *string, sats []int, bfa
 		}
 		switch {
 		case i == 0:
+			if len(buff) < 3 || buff[0] != '#' {
+				return 0
+			}
 			*ctype = string(buff[2])
-			if Str2Time(buff, 3, 28, time) > 0 {
+			if Str2Time(buff, 3, 28, time) != 0 {
 				return 0
 			}
 		case string(prefix) == "+ ": /* satellite id */
@@ -130,16 +147,14 @@ func ReadSp3Header(rd *bufio.Reader, time *Gtime, ctype *string, sats []int, bfa
 				ns = int(Str2Num(buff, 3, 3)) // support multi-satellite
 			}
 			for j = 0; j < 17 && k < ns; j++ {
-				sys = code2sys(rune(buff[9+3*j]))
-				prn = int(Str2Num(buff, 10+3*j, 2))
 				if k < MAXSAT {
-					sats[k] = SatNo(sys, prn)
+					sats[k] = sp3satno(buff, 9+3*j)
 					k++
 				}
 			}
 		case string(prefix) == "++": /* orbit accuracy */
 			continue
-		case string(prefix) == "%c": /* time system */
+		case string(prefix) == "%c" && *tsys == "" && len(buff) >= 12: /* time system */
 			*tsys = buff[9:12]
 		case string(prefix) == "%f" && bfact[0] == 0.0: /* fp base number */
 			bfact[0] = Str2Num(buff, 3, 10)
@@ -159,161 +174,177 @@ func (nav *Nav) AddPEph(peph *PEph) int {
 	return 1
 }
 
+/* sp3 epoch time to gpst ----------------------------------------------------*/
+func sp3time(time Gtime, tsys string) Gtime {
+	switch tsys {
+	case "UTC":
+		return Utc2GpsT(time) /* utc.gpst */
+	case "TAI":
+		return TimeAdd(time, -19.0) /* tai.gpst */
+	case "BDT":
+		return BDT2GpsT(time) /* bdt.gpst */
+	}
+	return time /* GPS, GAL, QZS, IRN */
+}
+
+/* read SP3 accuracy record --------------------------------------------------
+* EP/EV record: std of x,y,z (mm|1e-4 mm/s), clock (psec|1e-4 psec/s) and
+* correlations xy,xz,xc,yz,yc,zc (1e-7)
+*-----------------------------------------------------------------------------*/
+func readsp3acc(buff string, scale, sclk float64, std []float32, cov []float32) {
+	var sig [3]float64
+
+	for j := 0; j < 3; j++ {
+		if sig[j] = Str2Num(buff, 4+j*5, 4) * scale; sig[j] > 0.0 {
+			std[j] = float32(sig[j])
+		}
+	}
+	if clk := Str2Num(buff, 19, 7) * sclk; clk > 0.0 {
+		std[3] = float32(clk)
+	}
+	cov[0] = float32(Str2Num(buff, 27, 8) * 1e-7 * sig[0] * sig[1]) /* xy */
+	cov[1] = float32(Str2Num(buff, 54, 8) * 1e-7 * sig[1] * sig[2]) /* yz */
+	cov[2] = float32(Str2Num(buff, 36, 8) * 1e-7 * sig[2] * sig[0]) /* zx */
+}
+
 /* read SP3 body -------------------------------------------------------------*/
 func (nav *Nav) ReadSp3Body(rd *bufio.Reader, ctype rune, sats []int, ns int, bfact []float64,
 	tsys *string, index, opt int) {
 	var (
-		peph                                      PEph
-		time                                      Gtime
-		val, std, base                            float64
-		i, j, sat, sys, prn, n, pred_o, pred_c, v int
+		peph                               PEph
+		time                               Gtime
+		val, std, base                     float64
+		j, sat, psat, vsat, pred_o, pred_c int
+		epoch, v                           int
+		buff                               string
+		err                                error
 	)
-	if ctype == 'P' {
-		n = ns
-	} else {
-		n = ns * 2
-	}
-	var buff string
 
 	Trace(4, "readsp3b: type=%c ns=%d index=%d opt=%d\n", ctype, ns, index, opt)
 
-	for {
-		buff, _ = rd.ReadString('\n')
-		if len(buff) == 0 {
-			break
-		}
-		if strings.Compare(buff[0:3], "EOF") == 0 {
+	for err == nil {
+		buff, err = rd.ReadString('\n')
+		if strings.HasPrefix(buff, "EOF") {
 			break
 		}
-
-		if buff[0] != '*' || Str2Time(buff, 3, 28, &time) > 0 {
-			Trace(2, "sp3 invalid epoch %31.31s\n", buff)
+		switch {
+		case len(buff) < 4:
+			continue
+		case buff[0] == '*': /* epoch */
+			if v > 0 {
+				nav.AddPEph(&peph)
+			}
+			epoch, v, psat, vsat = 0, 0, 0, 0
+			if Str2Time(buff, 3, 28, &time) != 0 {
+				Trace(2, "sp3 invalid epoch %31.31s\n", buff)
+				continue
+			}
+			peph = PEph{Time: sp3time(time, *tsys), Index: index}
+			epoch = 1
+			continue
+		case epoch == 0:
+			continue
+		case buff[0] == 'E' && buff[1] == 'P': /* position accuracy */
+			if psat > 0 {
+				readsp3acc(buff, 1e-3, 1e-12, peph.Std[psat-1][:], peph.PosCov[psat-1][:])
+			}
+			continue
+		case buff[0] == 'E' && buff[1] == 'V': /* velocity accuracy */
+			if vsat > 0 {
+				readsp3acc(buff, 1e-7, 1e-16, peph.Vst[vsat-1][:], peph.VelCov[vsat-1][:])
+			}
+			continue
+		case buff[0] != 'P' && buff[0] != 'V':
 			continue
 		}
-		if strings.Compare(*tsys, "UTC") == 0 {
-			time = Utc2GpsT(time) /* utc.gpst */
+		if sat = sp3satno(buff, 1); sat == 0 {
+			continue
 		}
-		peph.Time = time
-		peph.Index = index
 
-		for i = 0; i < len(peph.Pos); i++ {
-			for j = 0; j < 4; j++ {
-				peph.Pos[i][j] = 0.0
-				peph.Std[i][j] = 0.0
-				peph.Vel[i][j] = 0.0
-				peph.Vst[i][j] = 0.0
+		if buff[0] == 'P' {
+			psat = sat
+			pred_c = 0
+			if len(buff) >= 76 && buff[75] == 'P' {
+				pred_c = 1
 			}
-			for j = 0; j < 3; j++ {
-				peph.PosCov[i][j] = 0.0
-				peph.VelCov[i][j] = 0.0
+			pred_o = 0
+			if len(buff) >= 80 && buff[79] == 'P' {
+				pred_o = 1
 			}
+		} else {
+			vsat = sat
 		}
-		for i, pred_o, pred_c, v = 0, 0, 0, 0; i < n; i++ {
-			buff, _ = rd.ReadString('\n')
-			if len(buff) == 0 {
-				break
-			}
-			if len(buff) < 4 || (buff[0] != 'P' && buff[0] != 'V') {
+		for j = 0; j < 4; j++ {
+
+			/* read option for predicted value */
+			if j < 3 && (opt&1) > 0 && pred_o > 0 {
 				continue
 			}
-			sys = code2sys(rune(buff[1]))
-			if buff[1] == ' ' {
-				sys = SYS_GPS
+			if j < 3 && (opt&2) > 0 && pred_o == 0 {
+				continue
 			}
-			prn = int(Str2Num(buff, 2, 2))
-			if sys == SYS_SBS {
-				prn += 100
-			} else if sys == SYS_QZS {
-				prn += 192 /* extension to sp3-c */
+			if j == 3 && (opt&1) > 0 && pred_c > 0 {
+				continue
 			}
-
-			if sat = SatNo(sys, prn); sat == 0 {
+			if j == 3 && (opt&2) > 0 && pred_c == 0 {
 				continue
 			}
 
-			if buff[0] == 'P' {
-				pred_c = 0
-				if len(buff) >= 76 && buff[75] == 'P' {
-					pred_c = 1
-				}
-				pred_o = 0
-				if len(buff) >= 80 && buff[79] == 'P' {
-					pred_o = 1
-				}
+			val = Str2Num(buff, 4+j*14, 14)
+			if j < 3 {
+				std = Str2Num(buff, 61+j*3, 2)
+
+			} else {
+				std = Str2Num(buff, 61+j*3, 3)
 			}
-			for j = 0; j < 4; j++ {
 
-				/* read option for predicted value */
-				if j < 3 && (opt&1) > 0 && pred_o > 0 {
-					continue
-				}
-				if j < 3 && (opt&2) > 0 && pred_o == 0 {
-					continue
-				}
-				if j == 3 && (opt&1) > 0 && pred_c > 0 {
-					continue
+			/* 0.0: bad or absent position, 999999.999999: bad or absent clock */
+			if buff[0] == 'P' { /* position */
+				if val != 0.0 && math.Abs(val-999999.999999) >= 1e-6 {
+					if j < 3 {
+						peph.Pos[sat-1][j] = val * 1000.0
+					} else {
+						peph.Pos[sat-1][j] = val * (1e-6)
+					}
+					v = 1 /* valid epoch */
 				}
-				if j == 3 && (opt&2) > 0 && pred_c == 0 {
-					continue
+				jid := 1
+				if j < 3 {
+					jid = 0
 				}
-
-				val = Str2Num(buff, 4+j*14, 14)
+				base = bfact[jid]
+				jvar := 1e-12
 				if j < 3 {
-					std = Str2Num(buff, 61+j*3, 2)
-
-				} else {
-					std = Str2Num(buff, 61+j*3, 3)
+					jvar = 1e-3
 				}
-
-				if buff[0] == 'P' { /* position */
-					if val != 0.0 && math.Abs(val-999999.999999) >= 1e-6 {
-						if j < 3 {
-							peph.Pos[sat-1][j] = val * 1000.0
-						} else {
-							peph.Pos[sat-1][j] = val * (1e-6)
-							v = 1 /* valid epoch */
-						}
-					}
-					jid := 1
-					if j < 3 {
-						jid = 0
-					}
-					base = bfact[jid]
-					jvar := 1e-12
+				if base > 0.0 && std > 0.0 {
+					peph.Std[sat-1][j] = float32(math.Pow(base, std) * (jvar))
+				}
+			} else if v > 0 { /* velocity */
+				if val != 0.0 && math.Abs(val-999999.999999) >= 1e-6 {
+					jvar := 1e-10
 					if j < 3 {
-						jvar = 1e-3
-					}
-					if base > 0.0 && std > 0.0 {
-						peph.Std[sat-1][j] = float32(math.Pow(base, std) * (jvar))
-					}
-				} else if v > 0 { /* velocity */
-					if val != 0.0 && math.Abs(val-999999.999999) >= 1e-6 {
-						jvar := 1e-10
-						if j < 3 {
-							jvar = 0.1
-						}
-						peph.Vel[sat-1][j] = val * jvar
+						jvar = 0.1
 					}
-					jid := 1
+					peph.Vel[sat-1][j] = val * jvar
+				}
+				jid := 1
+				if j < 3 {
+					jid = 0
+				}
+				base = bfact[jid]
+				if base > 0.0 && std > 0.0 {
+					jvar := 1e-16
 					if j < 3 {
-						jid = 0
-					}
-					base = bfact[jid]
-					if base > 0.0 && std > 0.0 {
-						jvar := 1e-16
-						if j < 3 {
-							jvar = 1e-7
-						}
-						peph.Vst[sat-1][j] = float32(math.Pow(base, std) * jvar)
+						jvar = 1e-7
 					}
+					peph.Vst[sat-1][j] = float32(math.Pow(base, std) * jvar)
 				}
 			}
 		}
-		if v > 0 {
-			if nav.AddPEph(&peph) == 0 {
-				return
-			}
-		}
+	}
+	if v > 0 {
+		nav.AddPEph(&peph)
 	}
 }
 
@@ -436,6 +467,57 @@ func (nav *Nav) ReadSp3(file string, opt int) {
 	}
 }
 
+/* read sp3 precise ephemeris file ---------------------------------------------
+* read a sp3-c/sp3-d precise ephemeris file and add the epochs to the precise
+* ephemeris of navigation data, used by SatPoss() with EPHOPT_PREC
+* args   : string path       I   sp3 file path
+*          nav_t  *nav       IO  navigation data
+* return : number of epochs read and error (nil: ok)
+* notes  : see ref [1],[5]
+*          the epochs are combined with the precise ephemeris already set, the
+*          file read last replaces the satellites of the same epoch
+*          position 0.0 and clock 999999.999999 (bad or absent) are not set.
+*          EP/EV records set the std and covariance of position/velocity
+*          epochs are converted to gpst from the time system of the header
+*          (GPS, GAL, QZS, IRN, UTC, TAI or BDT)
+*-----------------------------------------------------------------------------*/
+func ReadSP3(path string, nav *Nav) (int, error) {
+	var (
+		time        Gtime
+		bfact       [2]float64
+		sats        = make([]int, MAXSAT)
+		ctype, tsys string
+		index       int
+	)
+	Trace(3, "readsp3 : path=%s\n", path)
+
+	if nav == nil {
+		return 0, fmt.Errorf("sp3 %s: no navigation data", path)
+	}
+	fp, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("sp3 file open error: %w", err)
+	}
+	defer fp.Close()
+
+	rd := bufio.NewReader(fp)
+	ns := ReadSp3Header(rd, &time, &ctype, sats, bfact[:], &tsys)
+	if ns <= 0 {
+		return 0, fmt.Errorf("sp3 %s: invalid header", path)
+	}
+	for i := range nav.Peph {
+		index = max(index, nav.Peph[i].Index+1)
+	}
+	ne := nav.Ne()
+	nav.ReadSp3Body(rd, rune(ctype[0]), sats, ns, bfact[:], &tsys, index, 0)
+	n := nav.Ne() - ne
+	if n <= 0 {
+		return 0, fmt.Errorf("sp3 %s: no valid epoch", path)
+	}
+	nav.CombPEph(0)
+	return n, nil
+}
+
 /* read satellite antenna parameters -------------------------------------------
 * read satellite antenna parameters
 * args   : char   *file       I   antenna parameter file
@@ -744,6 +826,66 @@ func (nav *Nav) PEphClk(time Gtime, sat int, dts []float64, varc *float64) int {
 	return 1
 }
 
+/* satellite clock by precise clock with missing epochs -----------------------
+* compute satellite clock bias by the precise clocks of a satellite
+* args   : gtime_t time       I   time (gpst)
+*          int    sat         I   satellite number
+*          nav_t  *nav        I   navigation data
+* return : satellite clock bias (s) and error (nil: ok)
+* notes  : the clock is interpolated linearly between the nearest epochs with
+*          the clock of the satellite, so missing epochs are bridged up to a
+*          gap of MAXGAPCLK. out of the epochs or a gap, the clock is
+*          extrapolated by the drift of the nearest epoch within MAXDTE if the
+*          drift is set.
+*          the relativistic correction is not included.
+*          nav.pclk must be set by calling ReadRnxClk() or ReadRnxC()
+*-----------------------------------------------------------------------------*/
+func SatClk(time Gtime, sat int, nav *Nav) (float64, error) {
+	var i, j, k int
+
+	Trace(4, "satclk  : time=%s sat=%2d\n", TimeStr(time, 3), sat)
+
+	if nav == nil || sat <= 0 || MAXSAT < sat {
+		return 0.0, fmt.Errorf("satclk: invalid satellite %d", sat)
+	}
+	/* binary search of the first epoch after time */
+	for i, j = 0, nav.Nc(); i < j; {
+		if k = (i + j) / 2; TimeDiff(nav.Pclk[k].Time, time) <= 0.0 {
+			i = k + 1
+		} else {
+			j = k
+		}
+	}
+	/* nearest epochs with the clock of the satellite before and after time */
+	for j = i - 1; j >= 0 && nav.Pclk[j].Clk[sat-1][0] == 0.0; j-- {
+	}
+	for k = i; k < nav.Nc() && nav.Pclk[k].Clk[sat-1][0] == 0.0; k++ {
+	}
+	if j >= 0 {
+		p := &nav.Pclk[j]
+		t0 := TimeDiff(time, p.Time)
+		if t0 <= 1e-9 {
+			return p.Clk[sat-1][0], nil
+		}
+		if k < nav.Nc() && TimeDiff(nav.Pclk[k].Time, p.Time) <= MAXGAPCLK {
+			q := &nav.Pclk[k]
+			t1 := TimeDiff(time, q.Time)
+			return (q.Clk[sat-1][0]*t0 - p.Clk[sat-1][0]*t1) / (t0 - t1), nil
+		}
+	}
+	/* extrapolation by drift */
+	for _, m := range []int{j, k} {
+		if m < 0 || m >= nav.Nc() {
+			continue
+		}
+		p := &nav.Pclk[m]
+		if dt := TimeDiff(time, p.Time); math.Abs(dt) <= MAXDTE && p.Drift[sat-1][0] != 0.0 {
+			return p.Clk[sat-1][0] + p.Drift[sat-1][0]*dt, nil
+		}
+	}
+	return 0.0, fmt.Errorf("satclk: no precise clock sat=%d at %s", sat, TimeStr(time, 0))
+}
+
 /* satellite antenna phase center offset ---------------------------------------
 * compute satellite antenna phase center offset in ecef
 * args   : gtime_t time       I   time (gpst)
@@ -0,0 +1,185 @@
+package gnssgo
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSP3 writes a SP3-d file of the broadcast orbits of nav with nep
+// epochs every 900 s from t0, position and velocity records and an EP record
+// for G01. The clock of G02 at epoch 3 is absent.
+func writeSP3(t *testing.T, nav *Nav, t0 Gtime, nep int, tsys string) string {
+	var (
+		b           strings.Builder
+		ep          [6]float64
+		rs, rm, rp  [3]float64
+		dts, dm, dp float64
+		vari        float64
+	)
+	sp3ep := func(t Gtime) string {
+		Time2Epoch(t, ep[:])
+		return fmt.Sprintf("%4.0f %2.0f %2.0f %2.0f %2.0f %11.8f", ep[0], ep[1], ep[2], ep[3], ep[4], ep[5])
+	}
+	fmt.Fprintf(&b, "#dV%s %7d ORBIT IGS20 HLM  TST\n", sp3ep(t0), nep)
+	fmt.Fprintf(&b, "## 2295 86400.00000000   900.00000000 60310 0.0000000000000\n")
+	ids := ""
+	for _, eph := range nav.Ephs {
+		ids += fmt.Sprintf("G%02d", eph.Sat)
+	}
+	fmt.Fprintf(&b, "+  %3d   %-51s\n", len(nav.Ephs), ids)
+	fmt.Fprintf(&b, "++       %-51s\n", strings.Repeat("  5", len(nav.Ephs)))
+	fmt.Fprintf(&b, "%%c G  cc %s ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc\n", tsys)
+	fmt.Fprintf(&b, "%%c cc cc ccc ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc\n")
+	fmt.Fprintf(&b, "%%f  1.2500000  1.025000000  0.00000000000  0.000000000000000\n")
+	fmt.Fprintf(&b, "%%f  0.0000000  0.000000000  0.00000000000  0.000000000000000\n")
+	fmt.Fprintf(&b, "%%i    0    0    0    0      0      0      0      0         0\n")
+	fmt.Fprintf(&b, "/* SYNTHETIC ORBITS FROM BROADCAST EPHEMERIS\n")
+
+	for k := 0; k < nep; k++ {
+		tk := TimeAdd(t0, 900.0*float64(k))
+		fmt.Fprintf(&b, "*  %s\n", sp3ep(tk))
+		for i := range nav.Ephs {
+			eph := &nav.Ephs[i]
+			Eph2Pos(tk, eph, rs[:], &dts, &vari)
+			Eph2Pos(TimeAdd(tk, -0.5), eph, rm[:], &dm, &vari)
+			Eph2Pos(TimeAdd(tk, 0.5), eph, rp[:], &dp, &vari)
+			var vs [3]float64
+			for j := 0; j < 3; j++ {
+				vs[j] = rp[j] - rm[j]
+			}
+			/* precise clock without the relativistic correction */
+			clk := (dts + 2.0*Dot(rs[:], vs[:], 3)/CLIGHT/CLIGHT) * 1e6
+			if eph.Sat == 2 && k == 3 {
+				clk = 999999.999999
+			}
+			fmt.Fprintf(&b, "PG%02d%14.6f%14.6f%14.6f%14.6f  7  8  9 120\n", eph.Sat,
+				rs[0]/1000.0, rs[1]/1000.0, rs[2]/1000.0, clk)
+			if eph.Sat == 1 {
+				fmt.Fprintf(&b, "EP  %4d %4d %4d %7d %8d %8d %8d %8d %8d %8d\n",
+					20, 30, 40, 500, 5000000, -2500000, 0, 1000000, 0, 0)
+			}
+			fmt.Fprintf(&b, "VG%02d%14.6f%14.6f%14.6f%14.6f\n", eph.Sat,
+				vs[0]*10.0, vs[1]*10.0, vs[2]*10.0, (dp-dm)*1e10)
+		}
+	}
+	b.WriteString("EOF\n")
+
+	file := filepath.Join(t.TempDir(), "test.sp3")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestReadSP3 reads a SP3 file of broadcast orbits and compares the
+// interpolated satellite positions and clocks with the broadcast ephemeris.
+func TestReadSP3(t *testing.T) {
+	const nep = 13
+	t0 := Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	brdc := synthNav(TimeAdd(t0, 5400.0), 4)
+	for i := range brdc.Ephs {
+		brdc.Ephs[i].F0 += 2e-5
+	}
+	file := writeSP3(t, brdc, t0, nep, "GPS")
+
+	var nav Nav
+	n, err := ReadSP3(file, &nav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != nep || nav.Ne() != nep {
+		t.Fatalf("epochs: got %d ne=%d, want %d", n, nav.Ne(), nep)
+	}
+	peph := &nav.Peph[3]
+	if peph.Pos[1][3] != 0.0 || peph.Pos[1][0] == 0.0 {
+		t.Errorf("absent clock of G02: got clk=%g x=%.3f", peph.Pos[1][3], peph.Pos[1][0])
+	}
+	/* EP record of G01: std 20/30/40 mm, 500 ps, correlation xy 0.5 zx -0.25 yz 0.1 */
+	if math.Abs(float64(peph.Std[0][1])-0.030) > 1e-6 || math.Abs(float64(peph.Std[0][3])-500e-12) > 1e-15 {
+		t.Errorf("EP std of G01: got %v", peph.Std[0])
+	}
+	want := [3]float64{0.5 * 0.02 * 0.03, 0.1 * 0.03 * 0.04, -0.25 * 0.04 * 0.02}
+	for j := 0; j < 3; j++ {
+		if math.Abs(float64(peph.PosCov[0][j])-want[j]) > 1e-9 {
+			t.Errorf("EP covariance of G01 [%d]: got %g, want %g", j, peph.PosCov[0][j], want[j])
+		}
+	}
+	/* P record std of G02: 1.25^7 mm */
+	if math.Abs(float64(peph.Std[1][0])-math.Pow(1.25, 7)*1e-3) > 1e-9 {
+		t.Errorf("std of G02: got %g", peph.Std[1][0])
+	}
+	if Norm(peph.Vel[0][:], 3) < 1000.0 {
+		t.Errorf("velocity of G01: got %v", peph.Vel[0])
+	}
+
+	/* interpolated positions and clocks between the epochs */
+	for _, dt := range []float64{2.5 * 900.0, 6.0*900.0 + 123.4, 9.5 * 900.0} {
+		tt := TimeAdd(t0, dt)
+		for i := range brdc.Ephs {
+			var (
+				rs, rb [6]float64
+				dts    [2]float64
+				dtb    float64
+				vari   float64
+				svh    int
+			)
+			eph := &brdc.Ephs[i]
+			if nav.SatPos(tt, tt, eph.Sat, EPHOPT_PREC, rs[:], dts[:], &vari, &svh) == 0 {
+				t.Errorf("t=%.1f sat=%d: no precise position", dt, eph.Sat)
+				continue
+			}
+			Eph2Pos(tt, eph, rb[:], &dtb, &vari)
+			for j := 0; j < 3; j++ {
+				if math.Abs(rs[j]-rb[j]) > 0.01 {
+					t.Errorf("t=%.1f sat=%d pos[%d]: got %.4f, want %.4f", dt, eph.Sat, j, rs[j], rb[j])
+				}
+			}
+			if eph.Sat == 2 && dt < 3.0*900.0 { /* no clock at epoch 3 */
+				dtb = 0.0
+			}
+			if math.Abs(dts[0]-dtb)*CLIGHT > 0.01 {
+				t.Errorf("t=%.1f sat=%d clock: got %.12f, want %.12f", dt, eph.Sat, dts[0], dtb)
+			}
+		}
+	}
+}
+
+// TestReadSP3TimeSystem tests the epochs of a SP3 file in UTC are converted
+// to GPST and combined with the epochs read before.
+func TestReadSP3TimeSystem(t *testing.T) {
+	t0 := Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	brdc := synthNav(t0, 2)
+	var nav Nav
+	if _, err := ReadSP3(writeSP3(t, brdc, t0, 2, "GPS"), &nav); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSP3(writeSP3(t, brdc, Utc2GpsT(t0), 2, "GPS"), &nav); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSP3(writeSP3(t, brdc, t0, 2, "UTC"), &nav); err != nil {
+		t.Fatal(err)
+	}
+	if nav.Ne() != 4 {
+		t.Fatalf("combined epochs: got %d, want 4", nav.Ne())
+	}
+	if dt := TimeDiff(nav.Peph[1].Time, Utc2GpsT(t0)); dt != 0.0 {
+		t.Errorf("utc epoch: got %+.3f s from gpst", dt)
+	}
+
+	for _, text := range []string{"", "not a sp3 file\n", "#dV2024  1  1  0  0  0.00000000       0 ORBIT\n+    1   G01\nEOF\n"} {
+		file := filepath.Join(t.TempDir(), "bad.sp3")
+		if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := ReadSP3(file, &nav); err == nil {
+			t.Errorf("%q: got %d epochs, want error", text, n)
+		}
+	}
+	if _, err := ReadSP3(filepath.Join(t.TempDir(), "none.sp3"), &nav); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
@@ -0,0 +1,117 @@
+/*------------------------------------------------------------------------------
+* qc.go : observation data quality check
+*-----------------------------------------------------------------------------*/
+package gnssgo
+
+import "math"
+
+/* expected number of observations ---------------------------------------------
+* compute the number of observations expected from satellites above the
+* elevation mask, the denominator of the completeness (teqc-like) metric
+* args   : nav_t  *nav      I   navigation data
+*          double *pos      I   receiver position {x,y,z} (ecef) (m)
+*          gtime_t ts       I   start time (gpst)
+*          gtime_t te       I   end time (gpst)
+*          double interval  I   observation interval (s)
+*          double elmin     I   elevation mask (rad)
+*          char   *obsTypes I   observation types ("C1C","L1C",...)
+* return : number of expected observations
+* notes  : epochs are ts, ts+interval, ... up to te (inclusive).
+*          satellite positions are computed by broadcast ephemeris, satellites
+*          without ephemeris or unhealthy are not expected.
+*          an observation type is expected only for the systems defining its
+*          signal. without obsTypes, each satellite counts once per epoch.
+*-----------------------------------------------------------------------------*/
+func ExpectedObsCount(nav *Nav, pos [3]float64, ts, te Gtime, interval float64,
+	elmin float64, obsTypes ...string) int {
+	var (
+		rs     [6]float64
+		dts    [2]float64
+		e, llh [3]float64
+		codes  []uint8
+		vari   float64
+		svh, n int
+	)
+	if nav == nil || interval <= 0.0 || Norm(pos[:], 3) <= 0.0 {
+		return 0
+	}
+	for _, obsType := range obsTypes {
+		if len(obsType) < 3 {
+			continue
+		}
+		if code := Obs2Code(obsType[1:]); code != CODE_NONE {
+			codes = append(codes, code)
+		}
+	}
+	Ecef2Pos(pos[:], llh[:])
+
+	for k := 0; ; k++ {
+		t := TimeAdd(ts, float64(k)*interval)
+		if TimeDiff(t, te) > 1e-9 {
+			break
+		}
+		for sat := 1; sat <= MAXSAT; sat++ {
+			if nav.SatPos(t, t, sat, EPHOPT_BRDC, rs[:], dts[:], &vari, &svh) == 0 || svh != 0 {
+				continue
+			}
+			if GeoDist(rs[:], pos[:], e[:]) <= 0.0 || SatAzel(llh[:], e[:], nil) < elmin {
+				continue
+			}
+			if len(codes) == 0 {
+				n++
+				continue
+			}
+			sys := SatSys(sat, nil)
+			for _, code := range codes {
+				if Code2Idx(sys, code) >= 0 {
+					n++
+				}
+			}
+		}
+	}
+	Trace(3, "expectedobscount: n=%d\n", n)
+	return n
+}
+
+/* position repeatability ------------------------------------------------------
+* compute the repeatability of the positions of a station in sessions (e.g.
+* daily static solutions)
+* args   : double *sessions I   session positions {{x,y,z},...} (ecef) (m)
+* return : enuStd           standard deviations {e,n,u} around the mean (m)
+*          mean             mean position {x,y,z} (ecef) (m)
+* notes  : the deviations from the mean are rotated to the local frame at the
+*          mean position. the standard deviations are the sample ones (n-1),
+*          zero for less than two sessions.
+*-----------------------------------------------------------------------------*/
+func Repeatability(sessions [][3]float64) (enuStd [3]float64, mean [3]float64) {
+	var (
+		pos, dr, enu [3]float64
+		i, n         int
+	)
+	if n = len(sessions); n == 0 {
+		return
+	}
+	for _, rr := range sessions {
+		for i = 0; i < 3; i++ {
+			mean[i] += rr[i] / float64(n)
+		}
+	}
+	if n < 2 {
+		return
+	}
+	Ecef2Pos(mean[:], pos[:])
+	for _, rr := range sessions {
+		for i = 0; i < 3; i++ {
+			dr[i] = rr[i] - mean[i]
+		}
+		Ecef2Enu(pos[:], dr[:], enu[:])
+		for i = 0; i < 3; i++ {
+			enuStd[i] += enu[i] * enu[i]
+		}
+	}
+	for i = 0; i < 3; i++ {
+		enuStd[i] = math.Sqrt(enuStd[i] / float64(n-1))
+	}
+	Trace(3, "repeatability: n=%d std=%.4f %.4f %.4f\n", n, enuStd[0], enuStd[1], enuStd[2])
+	return
+}
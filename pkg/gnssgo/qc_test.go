@@ -0,0 +1,94 @@
+package gnssgo
+
+import (
+	"math"
+	"testing"
+)
+
+// TestExpectedObsCount checks the expected observation count of a single
+// satellite over a short window.
+func TestExpectedObsCount(t *testing.T) {
+	t0 := Epoch2Time([]float64{2024, 3, 1, 12, 0, 0})
+	te := TimeAdd(t0, 60.0)
+
+	/* pick a satellite well above the mask over the whole window */
+	nav := new(Nav)
+	for _, eph := range synthNav(t0, 24).Ephs {
+		if synthRange(&eph, t0, synthRover[:], 0.0, 30.0*D2R) != 0.0 &&
+			synthRange(&eph, te, synthRover[:], 0.0, 30.0*D2R) != 0.0 {
+			nav.Ephs = append(nav.Ephs, eph)
+			break
+		}
+	}
+	if len(nav.Ephs) == 0 {
+		t.Fatal("no visible satellite")
+	}
+
+	tests := []struct {
+		name     string
+		elmin    float64
+		obsTypes []string
+		want     int
+	}{
+		{"types", 15.0 * D2R, []string{"C1C", "L1C", "C2W"}, 7 * 3},
+		{"no types", 15.0 * D2R, nil, 7},
+		{"undefined signal", 15.0 * D2R, []string{"C1C", "C6X", "X"}, 7},
+		{"below mask", 89.0 * D2R, []string{"C1C", "L1C"}, 0},
+	}
+	for _, tt := range tests {
+		if n := ExpectedObsCount(nav, synthRover, t0, te, 10.0, tt.elmin, tt.obsTypes...); n != tt.want {
+			t.Errorf("%s: n = %d, want %d", tt.name, n, tt.want)
+		}
+	}
+
+	/* unhealthy satellite and invalid arguments */
+	if n := ExpectedObsCount(nav, synthRover, t0, te, 0.0, 0.0); n != 0 {
+		t.Errorf("zero interval: n = %d, want 0", n)
+	}
+	nav.Ephs[0].Svh = 1
+	if n := ExpectedObsCount(nav, synthRover, t0, te, 10.0, 0.0); n != 0 {
+		t.Errorf("unhealthy: n = %d, want 0", n)
+	}
+}
+
+// TestRepeatability checks the ENU scatter of sessions offset from a point
+// in the local frame.
+func TestRepeatability(t *testing.T) {
+	/* east/north/up offsets (m) with zero mean */
+	offs := [][3]float64{
+		{0.002, 0.000, 0.010},
+		{-0.002, 0.000, -0.010},
+		{0.000, 0.004, 0.010},
+		{0.000, -0.004, -0.010},
+		{0.000, 0.000, 0.000},
+	}
+	var pos [3]float64
+	Ecef2Pos(synthRover[:], pos[:])
+	sessions := make([][3]float64, len(offs))
+	for k, off := range offs {
+		var dr [3]float64
+		Enu2Ecef(pos[:], off[:], dr[:])
+		for i := 0; i < 3; i++ {
+			sessions[k][i] = synthRover[i] + dr[i]
+		}
+	}
+
+	std, mean := Repeatability(sessions)
+	want := [3]float64{
+		math.Sqrt(2 * 0.002 * 0.002 / 4),
+		math.Sqrt(2 * 0.004 * 0.004 / 4),
+		math.Sqrt(4 * 0.010 * 0.010 / 4),
+	}
+	for i := 0; i < 3; i++ {
+		if math.Abs(std[i]-want[i]) > 1e-6 {
+			t.Errorf("std[%d] = %.6f, want %.6f", i, std[i], want[i])
+		}
+	}
+	if d := synthDist(mean[:], synthRover[:]); d > 1e-6 {
+		t.Errorf("mean off by %.6f m", d)
+	}
+
+	if std, _ := Repeatability(sessions[:1]); std != [3]float64{} {
+		t.Errorf("single session std = %v, want zero", std)
+	}
+}
@@ -158,6 +158,9 @@ type Sigind struct { /* signal index type */
 	ctype [MAXOBSTYPE]uint8   /* ctype (0:C,1:L,2:D,3:S) */
 	code  [MAXOBSTYPE]uint8   /* obs-code (CODE_L??) */
 	shift [MAXOBSTYPE]float64 /* phase shift (cycle) */
+	scale [MAXOBSTYPE]float64 /* scale factor by header (0:none) */
+	hshft [MAXOBSTYPE]float64 /* phase shift by header (cycle) */
+	hsat  [MAXOBSTYPE][]int   /* satellites of phase shift by header (nil:all) */
 }
 
 /* set string without tail space ---------------------------------------------*/
@@ -457,7 +460,7 @@ func ConvRinexCode2_3(ver float64, sys int, str string, ctype *string) {
 
 /* decode RINEX observation data file header ---------------------------------*/
 func DecodeObsHeader(rd *bufio.Reader, buff string, ver float64, tsys *int,
-	tobs *TOBS, nav *Nav, sta *Sta) {
+	tobs *TOBS, tcor *TOBSCorr, nav *Nav, sta *Sta) {
 	/* default codes for unknown code */
 	var (
 		frqcodes string   = "1256789"
@@ -557,14 +560,13 @@ func DecodeObsHeader(rd *bufio.Reader, buff string, ver float64, tsys *int,
 		/* if unknown code in ver.3, set default code */
 		for j = 0; j < nt; j++ {
 			q := []rune(tobs[i][j])
-			if len(q) > 1 {
+			if len(q) != 2 {
 				continue
 			}
 			if index = strings.IndexRune(frqcodes, q[1]); index < 0 {
 				continue
 			}
-			q[2] = rune(defcodes[i][index])
-			tobs[i][j] = string(q)
+			tobs[i][j] = string(append(q, rune(defcodes[i][index])))
 			Trace(2, "set default for unknown code: sys=%c code=%s\n", buff[0], tobs[i][j])
 		}
 	case strings.Contains(label, "WAVELENGTH FACT L1/2"): /* opt ver.2 */
@@ -618,7 +620,9 @@ func DecodeObsHeader(rd *bufio.Reader, buff string, ver float64, tsys *int,
 	case strings.Contains(label, "SYS / DCBS APPLIED"): /* opt ver.3 */
 	case strings.Contains(label, "SYS / PCVS APPLIED"): /* opt ver.3 */
 	case strings.Contains(label, "SYS / SCALE FACTOR"): /* opt ver.3 */
-	case strings.Contains(label, "SYS / PHASE SHIFTS"): /* ver.3.01 */
+		DecodeScaleFactor(rd, buff, tobs, tcor)
+	case strings.Contains(label, "SYS / PHASE SHIFT"): /* ver.3.01 */
+		DecodePhaseShift(rd, buff, tobs, tcor)
 	case strings.Contains(label, "GLONASS SLOT / FRQ #"): /* ver.3.02 */
 		for i = 0; i < 8; i++ {
 			if buff[4+i*7] != 'R' {
@@ -801,9 +805,127 @@ func (nav *Nav) DecodeHNavHeader(buff string) {
 	} /* opt */
 }
 
+/* decode CLK header ---------------------------------------------------------*/
+func (nav *Nav) DecodeClkHeader(buff string) {
+	label := buff[60:]
+
+	Trace(4, "decode_clkh:\n")
+
+	switch {
+	case strings.Contains(label, "LEAP SECONDS GNSS"): /* opt ver.3.04 */
+	case strings.Contains(label, "LEAP SECONDS"): /* opt */
+		if nav != nil {
+			nav.Utc_gps[4] = Str2Num(buff, 0, 6)
+		}
+	case strings.Contains(label, "ANALYSIS CLK REF"): /* opt */
+		/* satellite clocks are relative to the reference clock */
+		Trace(3, "rinex clk reference clock: %s\n", strings.TrimSpace(buff[:60]))
+	}
+}
+
+/* index of observation type in header ---------------------------------------*/
+func obsTypeIndex(tobs []string, code string) int {
+	for j := 0; j < len(tobs) && len(tobs[j]) > 0; j++ {
+		if tobs[j] == code {
+			return j
+		}
+	}
+	return -1
+}
+
+/* decode SYS / SCALE FACTOR header record -------------------------------------
+* observations of the listed types (all types of the system if none) are
+* recorded multiplied by the factor (1,10,100,1000)
+*-----------------------------------------------------------------------------*/
+func DecodeScaleFactor(rd *bufio.Reader, buff string, tobs *TOBS, tcor *TOBSCorr) {
+	var (
+		code        string
+		i, j, k, n  int
+		index, fact int
+	)
+	if tcor == nil {
+		return
+	}
+	if index = strings.IndexRune(syscodes, rune(buff[0])); index < 0 {
+		Trace(2, "invalid system code: sys=%c\n", buff[0])
+		return
+	}
+	i = index
+	if fact = int(Str2Num(buff, 1, 5)); fact <= 0 {
+		return
+	}
+	if n = int(Str2Num(buff, 8, 2)); n <= 0 {
+		for j = 0; j < MAXOBSTYPE && len(tobs[i][j]) > 0; j++ {
+			tcor.scale[i][j] = float64(fact)
+		}
+		return
+	}
+	for j, k = 0, 11; j < n; j, k = j+1, k+4 {
+		if k > 58 {
+			if buff, _ = rd.ReadString('\n'); len(buff) == 0 {
+				break
+			}
+			k = 11
+		}
+		setstr(&code, buff[k:], 3)
+		if index = obsTypeIndex(tobs[i][:], code); index < 0 {
+			Trace(2, "scale factor of unknown obs type: sys=%c type=%s\n", syscodes[i], code)
+			continue
+		}
+		tcor.scale[i][index] = float64(fact)
+	}
+}
+
+/* decode SYS / PHASE SHIFT header record --------------------------------------
+* phase shift correction (cycle) of an obs type for the listed satellites
+* (all satellites of the system if none). the correction has been added to the
+* phases in the file (phase in file = phase of tracked signal + correction)
+*-----------------------------------------------------------------------------*/
+func DecodePhaseShift(rd *bufio.Reader, buff string, tobs *TOBS, tcor *TOBSCorr) {
+	var (
+		code, satid     string
+		sats            []int
+		shift           float64
+		i, j, k, n, sat int
+		index           int
+	)
+	if tcor == nil {
+		return
+	}
+	if index = strings.IndexRune(syscodes, rune(buff[0])); index < 0 {
+		Trace(2, "invalid system code: sys=%c\n", buff[0])
+		return
+	}
+	i = index
+	if setstr(&code, buff[2:], 3); len(code) < 3 {
+		return /* no phase shift corrections */
+	}
+	shift = Str2Num(buff, 6, 8)
+	n = int(Str2Num(buff, 16, 2))
+	for j, k = 0, 19; j < n; j, k = j+1, k+4 {
+		if k > 58 {
+			if buff, _ = rd.ReadString('\n'); len(buff) == 0 {
+				break
+			}
+			k = 19
+		}
+		setstr(&satid, buff[k:], 3)
+		if sat = SatId2No(satid); sat > 0 {
+			sats = append(sats, sat)
+		}
+	}
+	if index = obsTypeIndex(tobs[i][:], code); index < 0 {
+		Trace(2, "phase shift of unknown obs type: sys=%c type=%s\n", syscodes[i], code)
+		return
+	}
+	tcor.shift[i][index] = shift
+	tcor.ssat[i][index] = sats
+	Trace(3, "phase shift: sys=%c type=%s shift=%.5f nsat=%d\n", syscodes[i], code, shift, len(sats))
+}
+
 /* read RINEX file header ----------------------------------------------------*/
 func ReadRnxHeader(rd *bufio.Reader, ver *float64, ctype *byte, sys *int, tsys *int,
-	tobs *TOBS, nav *Nav, sta *Sta) int {
+	tobs *TOBS, tcor *TOBSCorr, nav *Nav, sta *Sta) int {
 	var (
 		buff string //,*label=buff+60;
 		i    int    = 0
@@ -876,7 +998,7 @@ func ReadRnxHeader(rd *bufio.Reader, ver *float64, ctype *byte, sys *int, tsys *
 		//	vtype := *ctype
 		switch *ctype { /* file type */
 		case 'O':
-			DecodeObsHeader(rd, buff, *ver, tsys, tobs, nav, sta)
+			DecodeObsHeader(rd, buff, *ver, tsys, tobs, tcor, nav, sta)
 		case 'N':
 			nav.DecodeNavHeader(buff)
 		case 'G':
@@ -889,6 +1011,8 @@ func ReadRnxHeader(rd *bufio.Reader, ver *float64, ctype *byte, sys *int, tsys *
 		case 'L':
 			nav.DecodeNavHeader(buff)
 			/* extension */
+		case 'C':
+			nav.DecodeClkHeader(buff)
 		}
 		if strings.Contains(label, "END OF HEADER") {
 			return 1
@@ -922,7 +1046,7 @@ func Decode_ObsEpoch(rd *bufio.Reader, buff string, ver float64, time *Gtime, fl
 			return n
 		}
 
-		if Str2Time(buff, 0, 26, time) > 0 {
+		if Str2Time(buff, 0, 26, time) < 0 {
 			Trace(2, "rinex obs invalid epoch: epoch=%26.26s\n", buff)
 			return 0
 		}
@@ -950,7 +1074,7 @@ func Decode_ObsEpoch(rd *bufio.Reader, buff string, ver float64, time *Gtime, fl
 			return n
 		}
 
-		if buff[0] != '>' || Str2Time(buff, 1, 28, time) > 0 {
+		if buff[0] != '>' || Str2Time(buff, 1, 28, time) < 0 {
 			Trace(3, "rinex obs invalid epoch: epoch=%29.29s\n", buff)
 			return 0
 		}
@@ -1016,7 +1140,16 @@ func (obs *ObsD) DecodeObsData(rd *bufio.Reader, buff string, ver float64, mask
 			j = 0
 		}
 		if stat > 0 {
-			val[i] = Str2Num(buff, j, 14) + ind.shift[i]
+			if val[i] = Str2Num(buff, j, 14); val[i] != 0.0 {
+				if ind.scale[i] > 0.0 {
+					val[i] /= ind.scale[i]
+				}
+				/* the producer of the file has added the SYS / PHASE SHIFT
+				   correction to align the phase with the reference signal
+				   (RINEX 3.04 5.2), subtract it to restore the phase of the
+				   tracked signal. the phase shift option is added. */
+				val[i] += ind.shift[i] - ind.PhaseShift(i, obs.Sat)
+			}
 			lli[i] = uint8(Str2Num(buff, j+14, 1)) & 3
 		}
 	}
@@ -1185,7 +1318,9 @@ func SetIndex(ver float64, sys int, opt string, tobs []string, ind *Sigind) {
 		i, j, k, m, n, index int
 	)
 	for i, n = 0, 0; len(tobs[i]) > 0; i, n = i+1, n+1 {
-		ind.code[i] = Obs2Code(tobs[i][1:])
+		if ind.code[i] = Obs2Code(tobs[i][1:]); ind.code[i] == CODE_NONE {
+			Trace(2, "unknown obs type skipped: sys=%d tobs=%s\n", sys, tobs[i])
+		}
 		if index = strings.IndexRune(obstype, rune(tobs[i][0])); index >= 0 {
 			ind.ctype[i] = uint8(index)
 		} else {
@@ -1279,9 +1414,37 @@ func SetIndex(ver float64, sys int, opt string, tobs []string, ind *Sigind) {
 
 }
 
+/* set header corrections to signal index ------------------------------------*/
+func SetObsCorr(tcor *TOBSCorr, i int, ind *Sigind) {
+	if tcor == nil {
+		return
+	}
+	for j := 0; j < ind.n; j++ {
+		ind.scale[j] = tcor.scale[i][j]
+		ind.hshft[j] = tcor.shift[i][j]
+		ind.hsat[j] = tcor.ssat[i][j]
+	}
+}
+
+/* phase shift by header for satellite ---------------------------------------*/
+func (ind *Sigind) PhaseShift(i, sat int) float64 {
+	if ind.hshft[i] == 0.0 {
+		return 0.0
+	}
+	if ind.hsat[i] == nil {
+		return ind.hshft[i]
+	}
+	for _, s := range ind.hsat[i] {
+		if s == sat {
+			return ind.hshft[i]
+		}
+	}
+	return 0.0
+}
+
 /* read RINEX observation data body ------------------------------------------*/
 func ReadRnxObsBody(rd *bufio.Reader, opt string, ver float64, tsys *int,
-	tobs *TOBS, flag *int, data []ObsD, sta *Sta) int {
+	tobs *TOBS, tcor *TOBSCorr, flag *int, data []ObsD, sta *Sta) int {
 	var (
 		time             Gtime
 		index            [NUMSYS]Sigind
@@ -1297,24 +1460,31 @@ func ReadRnxObsBody(rd *bufio.Reader, opt string, ver float64, tsys *int,
 	/* set signal index */
 	if nsys >= 1 {
 		SetIndex(ver, SYS_GPS, opt, tobs[0][:], &index[0])
+		SetObsCorr(tcor, 0, &index[0])
 	}
 	if nsys >= 2 {
 		SetIndex(ver, SYS_GLO, opt, tobs[1][:], &index[1])
+		SetObsCorr(tcor, 1, &index[1])
 	}
 	if nsys >= 3 {
 		SetIndex(ver, SYS_GAL, opt, tobs[2][:], &index[2])
+		SetObsCorr(tcor, 2, &index[2])
 	}
 	if nsys >= 4 {
 		SetIndex(ver, SYS_QZS, opt, tobs[3][:], &index[3])
+		SetObsCorr(tcor, 3, &index[3])
 	}
 	if nsys >= 5 {
 		SetIndex(ver, SYS_SBS, opt, tobs[4][:], &index[4])
+		SetObsCorr(tcor, 4, &index[4])
 	}
 	if nsys >= 6 {
 		SetIndex(ver, SYS_CMP, opt, tobs[5][:], &index[5])
+		SetObsCorr(tcor, 5, &index[5])
 	}
 	if nsys >= 7 {
 		SetIndex(ver, SYS_IRN, opt, tobs[6][:], &index[6])
+		SetObsCorr(tcor, 6, &index[6])
 	}
 
 	/* read record */
@@ -1341,7 +1511,7 @@ func ReadRnxObsBody(rd *bufio.Reader, opt string, ver float64, tsys *int,
 		case *flag == 3 || *flag == 4: /* new site or header info follows */
 
 			/* decode RINEX observation data file header */
-			DecodeObsHeader(rd, buff, ver, tsys, tobs, nil, sta)
+			DecodeObsHeader(rd, buff, ver, tsys, tobs, tcor, nil, sta)
 		}
 		if i++; i > nsat {
 			return n
@@ -1352,10 +1522,12 @@ func ReadRnxObsBody(rd *bufio.Reader, opt string, ver float64, tsys *int,
 
 /* read RINEX observation data -----------------------------------------------*/
 func (obs *Obs) ReadRnxObs(rd *bufio.Reader, ts, te Gtime, tint float64, opt string, rcv int, ver float64, tsys *int,
-	tobs *TOBS, sta *Sta) int {
+	tobs *TOBS, tcor *TOBSCorr, sta *Sta) int {
 	var (
 		slips            [MAXSAT][NFREQ + NEXOBS]uint8
 		i, n, flag, stat int
+		cur              Sta
+		change           bool
 	)
 
 	Trace(4, "readrnxobs: rcv=%d ver=%.2f tsys=%d\n", rcv, ver, *tsys)
@@ -1366,12 +1538,23 @@ func (obs *Obs) ReadRnxObs(rd *bufio.Reader, ts, te Gtime, tint float64, opt str
 
 	data := make([]ObsD, MAXOBS)
 
+	/* station parameters changed by event records are kept apart from the
+	   header parameters */
+	if sta != nil {
+		cur = *sta
+		cur.Events = nil
+	}
+
 	/* read RINEX observation data body */
 	for {
-		n = ReadRnxObsBody(rd, opt, ver, tsys, tobs, &flag, data, sta)
+		n = ReadRnxObsBody(rd, opt, ver, tsys, tobs, tcor, &flag, data, &cur)
 		if n < 0 || stat < 0 {
 			break
 		}
+		if flag == 3 || flag == 4 { /* new site or header info */
+			change = true
+			continue
+		}
 
 		for i = 0; i < n; i++ {
 
@@ -1383,6 +1566,12 @@ func (obs *Obs) ReadRnxObs(rd *bufio.Reader, ts, te Gtime, tint float64, opt str
 			/* save cycle slip */
 			data[i].SaveSlips(slips[:])
 		}
+		/* station change takes effect at the next epoch */
+		if change && n > 0 && sta != nil {
+			sta.Events = append(sta.Events, StaEvent{Time: data[0].Time, Sta: cur})
+			Trace(3, "readrnxobs: station change time=%s ant=%s\n", TimeStr(data[0].Time, 0), cur.AntDes)
+			change = false
+		}
 		/* screen data by time */
 		if n > 0 && ScreenTime(data[0].Time, ts, te, tint) == 0 {
 			continue
@@ -1406,6 +1595,26 @@ func (obs *Obs) ReadRnxObs(rd *bufio.Reader, ts, te Gtime, tint float64, opt str
 	return stat
 }
 
+/* station parameters at time ------------------------------------------------
+* get the station parameters in effect at the time
+* args   : Gtime  time      I   time (gpst)
+* return : station parameters and number of station change events applied
+* notes  : the parameters of the header are changed by the events of RINEX
+*          event records (epoch flag 3:new site,4:header info) up to the time
+*-----------------------------------------------------------------------------*/
+func (sta *Sta) StaAt(time Gtime) (*Sta, int) {
+	var k int
+	for k = 0; k < len(sta.Events); k++ {
+		if TimeDiff(sta.Events[k].Time, time) > DTTOL {
+			break
+		}
+	}
+	if k == 0 {
+		return sta, 0
+	}
+	return &sta.Events[k-1].Sta, k
+}
+
 /* decode ephemeris ----------------------------------------------------------*/
 func (eph *Eph) DecodeEph(ver float64, sat int, toc Gtime, data []float64) int {
 	var (
@@ -1787,16 +1996,17 @@ func (nav *Nav) ReadRnxNav(rd *bufio.Reader, opt string, ver float64, sys int) i
 	return 0
 }
 
-/* read RINEX clock ----------------------------------------------------------*/
-func (nav *Nav) ReadRnxClk(rd *bufio.Reader, opt string, index int) int {
+/* read RINEX clock ---------------------------------------------------------*/
+func (nav *Nav) ReadRnxClk(rd *bufio.Reader, opt string, ver float64, index int) int {
 	var (
-		time            Gtime
-		data            [2]float64
-		i, j, sat, mask int
-		buff, satid     string
+		time                    Gtime
+		data                    [4]float64
+		i, j, n, sat, mask, off int
+		buff, rec               string
+		err                     error
 	)
 
-	Trace(4, "readrnxclk: index=%d\n", index)
+	Trace(4, "readrnxclk: ver=%.2f index=%d\n", ver, index)
 
 	if nav == nil {
 		return 0
@@ -1805,30 +2015,44 @@ func (nav *Nav) ReadRnxClk(rd *bufio.Reader, opt string, index int) int {
 	/* set system mask */
 	mask = SetSysMask(opt)
 
-	for {
-		buff, _ = rd.ReadString('\n')
-		if len(buff) == 0 {
-			break
+	/* ver.3.04: 9-character name */
+	if ver >= 3.04 {
+		off = 5
+	}
+	for err == nil {
+		buff, err = rd.ReadString('\n')
+		if len(buff) < 40+off {
+			continue
 		}
-
-		if Str2Time(buff, 8, 26, &time) > 0 {
-			Trace(2, "rinex clk invalid epoch: %34.34s\n", buff)
+		rec = buff[:3]
+		if rec != "AS " && rec != "AR " && rec != "CR " && rec != "DR " && rec != "MS " {
 			continue
 		}
-		satid = buff[3:7]
-
+		/* bias, bias sigma, rate, rate sigma (continuation line) */
+		data = [4]float64{}
+		n = int(Str2Num(buff, 34+off, 3))
+		for i, j = 0, 40+off; i < n && i < 2; i, j = i+1, j+20 {
+			data[i] = Str2Num(buff, j, 19)
+		}
+		if n > 2 {
+			next, _ := rd.ReadString('\n')
+			for i, j = 2, 0; i < n && i < 4; i, j = i+1, j+20 {
+				data[i] = Str2Num(next, j, 19)
+			}
+		}
 		/* only read AS (satellite clock) record */
-		sat = SatId2No(satid)
-		if strings.Compare(buff[:2], "AS") != 0 || sat == 0 {
+		if rec != "AS " {
+			continue
+		}
+		if sat = SatId2No(buff[3 : 7+off]); sat == 0 {
 			continue
 		}
-
 		if (SatSys(sat, nil) & mask) == 0 {
 			continue
 		}
-
-		for i, j = 0, 40; i < 2; i, j = i+1, j+20 {
-			data[i] = Str2Num(buff, j, 19)
+		if Str2Time(buff, 8+off, 26, &time) != 0 {
+			Trace(2, "rinex clk invalid epoch: %34.34s\n", buff)
+			continue
 		}
 
 		if nav.Nc() <= 0 || math.Abs(TimeDiff(time, nav.Pclk[nav.Nc()-1].Time)) > 1e-9 {
@@ -1839,6 +2063,7 @@ func (nav *Nav) ReadRnxClk(rd *bufio.Reader, opt string, index int) int {
 		}
 		nav.Pclk[nav.Nc()-1].Clk[sat-1][0] = data[0]
 		nav.Pclk[nav.Nc()-1].Std[sat-1][0] = float32(data[1])
+		nav.Pclk[nav.Nc()-1].Drift[sat-1][0] = data[2]
 	}
 	if nav.Nc() > 0 {
 		return 1
@@ -1854,12 +2079,13 @@ func ReadRnxFp(rd *bufio.Reader, ts, te Gtime, tint float64,
 		ver       float64
 		sys, tsys int = 0, TSYS_GPS
 		tobs      TOBS
+		tcor      TOBSCorr
 	)
 
 	Trace(4, "readrnxfp: flag=%d index=%d\n", flag, index)
 
 	/* read RINEX file header */
-	if ReadRnxHeader(rd, &ver, ctype, &sys, &tsys, &tobs, nav, sta) == 0 {
+	if ReadRnxHeader(rd, &ver, ctype, &sys, &tsys, &tobs, &tcor, nav, sta) == 0 {
 		return 0
 	}
 
@@ -1873,7 +2099,7 @@ func ReadRnxFp(rd *bufio.Reader, ts, te Gtime, tint float64,
 	switch *ctype {
 	case 'O':
 		return obs.ReadRnxObs(rd, ts, te, tint, opt, index, ver, &tsys, &tobs,
-			sta)
+			&tcor, sta)
 	case 'N':
 		return nav.ReadRnxNav(rd, opt, ver, sys)
 	case 'G':
@@ -1885,7 +2111,7 @@ func ReadRnxFp(rd *bufio.Reader, ts, te Gtime, tint float64,
 	case 'L':
 		return nav.ReadRnxNav(rd, opt, ver, SYS_GAL) /* extension */
 	case 'C':
-		return nav.ReadRnxClk(rd, opt, index)
+		return nav.ReadRnxClk(rd, opt, ver, index)
 	}
 	Trace(5, "unsupported rinex type ver=%.2f type=%c\n", ver, *ctype)
 	return 0
@@ -2045,6 +2271,7 @@ func (nav *Nav) CombinePrecClk() {
 				}
 				nav.Pclk[i].Clk[k][0] = nav.Pclk[j].Clk[k][0]
 				nav.Pclk[i].Std[k][0] = nav.Pclk[j].Std[k][0]
+				nav.Pclk[i].Drift[k][0] = nav.Pclk[j].Drift[k][0]
 			}
 		} else if i++; i < j {
 			nav.Pclk[i] = nav.Pclk[j]
@@ -2094,6 +2321,51 @@ func (nav *Nav) ReadRnxC(file string) int {
 	return nav.Nc()
 }
 
+/* read RINEX clock file -------------------------------------------------------
+* read a RINEX clock file and add the satellite clocks (AS records) to the
+* precise clocks of navigation data
+* args   : string path   I      RINEX clock file path
+*          nav_t *nav    IO     navigation data
+* return : number of epochs read and error (nil: ok)
+* notes  : RINEX clock ver.2, 3.00-3.04 supported. the epochs are gpst.
+*          clock bias, sigma and rate (drift) of the records are set, receiver
+*          clock (AR) and other records are skipped. the leap seconds of the
+*          header are set to nav.Utc_gps[4].
+*          the epochs are combined with the precise clocks already set, the
+*          file read last replaces the satellites of the same epoch. an epoch
+*          without the clock of a satellite is a missing epoch of it, see
+*          SatClk().
+*-----------------------------------------------------------------------------*/
+func ReadRnxClk(path string, nav *Nav) (int, error) {
+	var (
+		t     Gtime
+		ctype byte
+		index int
+	)
+	Trace(3, "readrnxclk: path=%s\n", path)
+
+	if nav == nil {
+		return 0, fmt.Errorf("rinex clk %s: no navigation data", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("rinex clk file open error: %w", err)
+	}
+	for i := range nav.Pclk {
+		index = max(index, nav.Pclk[i].Index+1)
+	}
+	nc := nav.Nc()
+	ReadRnxFile(path, t, t, 0.0, "", 1, index, &ctype, nil, nav, nil)
+	if ctype != 'C' {
+		return 0, fmt.Errorf("rinex clk %s: not a RINEX clock file", path)
+	}
+	n := nav.Nc() - nc
+	if n <= 0 {
+		return 0, fmt.Errorf("rinex clk %s: no satellite clock", path)
+	}
+	nav.CombinePrecClk()
+	return n, nil
+}
+
 /* initialize RINEX control ----------------------------------------------------
 * initialize RINEX control struct and reallocate memory for observation and
 * ephemeris buffer in RINEX control struct
@@ -2179,13 +2451,14 @@ func (rnx *RnxCtr) OpenRnxCtr(rd *bufio.Reader) int {
 		ver             float64
 		ctype           byte
 		tobs            TOBS
+		tcor            TOBSCorr
 		i, j, sys, tsys int
 	)
 
 	Trace(4, "open_rnxctr:\n")
 
 	/* read RINEX header from file */
-	if ReadRnxHeader(rd, &ver, &ctype, &sys, &tsys, &tobs, &rnx.nav, &rnx.sta) == 0 {
+	if ReadRnxHeader(rd, &ver, &ctype, &sys, &tsys, &tobs, &tcor, &rnx.nav, &rnx.sta) == 0 {
 		Trace(2, "open_rnxctr: rinex header read error\n")
 		return 0
 	}
@@ -2203,6 +2476,7 @@ func (rnx *RnxCtr) OpenRnxCtr(rd *bufio.Reader) int {
 			rnx.tobs[i][j] = tobs[i][j]
 		}
 	}
+	rnx.tcor = tcor
 	rnx.ephset, rnx.ephsat = 0, 0
 	return 1
 }
@@ -2238,7 +2512,7 @@ func (rnx *RnxCtr) InputRnxCtr(rd *bufio.Reader) int {
 
 	/* read RINEX OBS data */
 	if rnx.filetype == "O" {
-		if n = ReadRnxObsBody(rd, rnx.opt, rnx.ver, &rnx.tsys, &rnx.tobs, &flag,
+		if n = ReadRnxObsBody(rd, rnx.opt, rnx.ver, &rnx.tsys, &rnx.tobs, &rnx.tcor, &flag,
 			rnx.obs.Data, &rnx.sta); n <= 0 {
 			rnx.obs.n = 0 // 到达文件尾部，只能让rnx.obs.n为0，不能让rnx.obs.Data为nil！！！
 			if n < 0 {
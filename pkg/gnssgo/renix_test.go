@@ -1,6 +1,11 @@
 package gnssgo
 
 import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -76,3 +81,318 @@ func TestOutRnxEvent(t *testing.T) {
 	// Skip this test as it requires a valid file pointer
 	t.Skip("Skipping test that requires a valid file pointer")
 }
+
+// rnxHeaderLine formats a RINEX header record
+func rnxHeaderLine(content, label string) string {
+	return fmt.Sprintf("%-60s%-20s\n", content, label)
+}
+
+// TestReadRnxObsHeaderCorrections tests that SYS / SCALE FACTOR and
+// SYS / PHASE SHIFT header records are applied to the observations
+func TestReadRnxObsHeaderCorrections(t *testing.T) {
+	/* RINEX 3.04 5.2: the GPS L2C (L2S/L2L/L2X) and L5 Q (L5Q/L5X) phases are
+	   shifted by -1/4 cycle to align with L2 P(Y) and L5 I, the header records
+	   the -0.25 cycle correction added to the phases in the file. reading
+	   restores the phases of the tracked signals: file phase + 1/4 cycle. */
+	const (
+		p1      = 20000000.125
+		l1      = 105104000.500
+		l2      = 81899200.700
+		l5      = 78436000.300
+		quarter = 0.25
+	)
+	var b strings.Builder
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", 3.04, "OBSERVATION DATA", "G"), "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine("G    4 C1C L1C L2X L5X", "SYS / # / OBS TYPES"))
+	b.WriteString(rnxHeaderLine("G   10   1 L2X", "SYS / SCALE FACTOR"))
+	b.WriteString(rnxHeaderLine("G L1C", "SYS / PHASE SHIFT"))
+	b.WriteString(rnxHeaderLine("G L2X -0.25000", "SYS / PHASE SHIFT"))
+	b.WriteString(rnxHeaderLine("G L5X -0.25000  01 G01", "SYS / PHASE SHIFT"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d%6d%6d%6d%6d%13.7f     %-3s", 2024, 1, 1, 0, 0, 0.0, "GPS"), "TIME OF FIRST OBS"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	b.WriteString(fmt.Sprintf("> %04d %02d %02d %02d %02d%11.7f  %d%3d\n", 2024, 1, 1, 0, 0, 0.0, 0, 2))
+	for _, sat := range []string{"G01", "G02"} {
+		b.WriteString(fmt.Sprintf("%s%14.3f  %14.3f  %14.3f  %14.3f  \n", sat, p1, l1, l2*10.0, l5))
+	}
+	file := filepath.Join(t.TempDir(), "test.24o")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		obs Obs
+		nav Nav
+		sta Sta
+	)
+	if ReadRnx(file, 1, "", &obs, &nav, &sta) <= 0 {
+		t.Fatalf("ReadRnx failed")
+	}
+	if obs.N() != 2 {
+		t.Fatalf("number of obs: got %d, want 2", obs.N())
+	}
+	for _, data := range obs.Data[:obs.N()] {
+		wantL5 := l5 /* L5X correction of G01 only */
+		if data.Sat == SatNo(SYS_GPS, 1) {
+			wantL5 += quarter
+		}
+		if math.Abs(data.P[0]-p1) > 1e-6 {
+			t.Errorf("sat=%d P1: got %.4f, want %.4f", data.Sat, data.P[0], p1)
+		}
+		if math.Abs(data.L[0]-l1) > 1e-6 {
+			t.Errorf("sat=%d L1: got %.4f, want %.4f", data.Sat, data.L[0], l1)
+		}
+		if math.Abs(data.L[1]-(l2+quarter)) > 1e-6 {
+			t.Errorf("sat=%d L2: got %.4f, want %.4f", data.Sat, data.L[1], l2+quarter)
+		}
+		if math.Abs(data.L[2]-wantL5) > 1e-6 {
+			t.Errorf("sat=%d L5: got %.4f, want %.4f", data.Sat, data.L[2], wantL5)
+		}
+	}
+}
+
+// TestReadRnxObsStationChange tests that an antenna exchange recorded by an
+// event record (epoch flag 4) takes effect at the following epoch
+func TestReadRnxObsStationChange(t *testing.T) {
+	const (
+		ant1 = "TRM57971.00     NONE"
+		ant2 = "LEIAR25.R3      NONE"
+	)
+	var b strings.Builder
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", 3.04, "OBSERVATION DATA", "G"), "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "1001", ant1), "ANT # / TYPE"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%14.4f%14.4f%14.4f", 0.0, 0.0, 0.0), "ANTENNA: DELTA H/E/N"))
+	b.WriteString(rnxHeaderLine("G    2 C1C L1C", "SYS / # / OBS TYPES"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d%6d%6d%6d%6d%13.7f     %-3s", 2024, 1, 1, 0, 0, 0.0, "GPS"), "TIME OF FIRST OBS"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	for k := 0; k < 4; k++ {
+		if k == 2 { /* antenna exchange between epochs 1 and 2 */
+			b.WriteString(fmt.Sprintf("> %27s  %d%3d\n", "", 4, 2))
+			b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "2002", ant2), "ANT # / TYPE"))
+			b.WriteString(rnxHeaderLine(fmt.Sprintf("%14.4f%14.4f%14.4f", 1.5, 0.0, 0.0), "ANTENNA: DELTA H/E/N"))
+		}
+		b.WriteString(fmt.Sprintf("> %04d %02d %02d %02d %02d%11.7f  %d%3d\n", 2024, 1, 1, 0, 0, float64(k*30), 0, 1))
+		b.WriteString(fmt.Sprintf("G01%14.3f  %14.3f  \n", 20000000.0, 105104000.0))
+	}
+	file := filepath.Join(t.TempDir(), "test.24o")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		obs Obs
+		nav Nav
+		sta [MAXRCV]Sta
+	)
+	if ReadRnx(file, 1, "", &obs, &nav, &sta[0]) <= 0 {
+		t.Fatalf("ReadRnx failed")
+	}
+	if obs.N() != 4 {
+		t.Fatalf("number of obs: got %d, want 4", obs.N())
+	}
+	if sta[0].AntDes != ant1 || len(sta[0].Events) != 1 {
+		t.Fatalf("station: antenna %q events %d, want %q 1", sta[0].AntDes, len(sta[0].Events), ant1)
+	}
+	if dt := TimeDiff(sta[0].Events[0].Time, obs.Data[2].Time); dt != 0.0 {
+		t.Errorf("change time: got epoch 2%+.0f s", dt)
+	}
+
+	/* the antenna parameters switch at epoch 2 with a cycle slip */
+	pcvr := Pcvs{Pcv: []Pcv{{Type: ant1}, {Type: ant2}}}
+	pcvr.Pcv[0].Offset[0][2] = 0.066
+	pcvr.Pcv[1].Offset[0][2] = 0.155
+	saved := antsta
+	defer func() { antsta = saved }()
+
+	var rtk Rtk
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.StaEvent = 1
+	opt.AntType[0] = "*"
+	SetPcv(obs.Data[0].Time, &opt, &nav, &Pcvs{}, &pcvr, sta[:])
+	rtk.InitRtk(&opt)
+	defer rtk.FreeRtk()
+
+	ista := []int{0, 0}
+	wantH := []float64{0.0, 0.0, 1.5, 1.5}
+	wantOff := []float64{0.066, 0.066, 0.155, 0.155}
+	for k := 0; k < obs.N(); k++ {
+		data := obs.Data[k : k+1]
+		ApplyStaEvent(&rtk, data, 1, sta[:], &pcvr, ista)
+		if h := rtk.Opt.AntDel[0][2]; h != wantH[k] {
+			t.Errorf("epoch %d: antenna height = %.4f, want %.4f", k, h, wantH[k])
+		}
+		if off := rtk.Opt.Pcvr[0].Offset[0][2]; off != wantOff[k] {
+			t.Errorf("epoch %d: antenna type %q up offset = %.4f, want %.4f", k, rtk.Opt.AntType[0], off, wantOff[k])
+		}
+		if slip := data[0].LLI[0]&1 == 1; slip != (k == 2) {
+			t.Errorf("epoch %d: slip flag = %v", k, slip)
+		}
+	}
+}
+
+// writeRnxNav writes a RINEX 3.04 navigation file of the GPS ephemerides and
+// the ionosphere/utc parameters of nav
+func writeRnxNav(t *testing.T, nav *Nav) string {
+	file := filepath.Join(t.TempDir(), "test.24p")
+	fp, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+	opt := RnxOpt{RnxVer: 304, NavSys: SYS_GPS | SYS_GAL, Outiono: 1, OutputTime: 1}
+	OutRnxNavHeader(fp, &opt, nav)
+	for i := range nav.Ephs {
+		OutRnxNavBody(fp, &opt, &nav.Ephs[i])
+	}
+	return file
+}
+
+// TestNavMergeParams merges the navigation data of two files and checks the
+// ionosphere/utc parameters of the file with the latest ephemeris are kept
+func TestNavMergeParams(t *testing.T) {
+	var (
+		old, cur = Nav{}, Nav{}
+		sta      Sta
+		obs      Obs
+	)
+	t0 := Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	ionOld := [8]float64{1.1e-8, 2.2e-8, -6.0e-8, -1.2e-7, 9.0e4, 1.0e5, -6.5e4, -5.2e5}
+	ionCur := [8]float64{1.3e-8, 1.5e-8, -5.9e-8, -1.1e-7, 9.4e4, 9.8e4, -6.6e4, -4.6e5}
+	gal := [4]float64{34.25, 0.289, 0.00375, 0.0}
+
+	src := synthNav(t0, 2)
+	src.Ion_gps, src.Ion_gal = ionOld, gal
+	src.Utc_gps[0], src.Utc_gps[1] = 1.1e-9, 2.0e-15
+	if ReadRnx(writeRnxNav(t, src), 1, "", &obs, &old, &sta) <= 0 {
+		t.Fatal("ReadRnx old failed")
+	}
+	src = synthNav(TimeAdd(t0, 7200.0), 2)
+	src.Ion_gps = ionCur /* no galileo and utc parameters */
+	if ReadRnx(writeRnxNav(t, src), 1, "", &obs, &cur, &sta) <= 0 {
+		t.Fatal("ReadRnx cur failed")
+	}
+
+	for _, order := range []string{"old+cur", "cur+old"} {
+		var nav Nav
+		if order == "old+cur" {
+			nav = old
+			nav.MergeParams(&cur)
+		} else {
+			nav = cur
+			nav.MergeParams(&old)
+		}
+		for i := 0; i < 8; i++ {
+			if math.Abs(nav.Ion_gps[i]-ionCur[i]) > math.Abs(ionCur[i])*1e-6 {
+				t.Errorf("%s ion_gps[%d]: got %g, want %g", order, i, nav.Ion_gps[i], ionCur[i])
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if math.Abs(nav.Ion_gal[i]-gal[i]) > gal[i]*1e-6 {
+				t.Errorf("%s ion_gal[%d]: got %g, want %g", order, i, nav.Ion_gal[i], gal[i])
+			}
+		}
+		if math.Abs(nav.Utc_gps[0]-1.1e-9) > 1e-15 {
+			t.Errorf("%s utc_gps A0: got %g, want 1.1e-9", order, nav.Utc_gps[0])
+		}
+	}
+}
+
+// writeRnxClk writes a RINEX clock file of version ver with 5 epochs every
+// 300 s. G01 has bias and sigma, G02 bias, sigma, drift and drift sigma and
+// no clock at epoch 2.
+func writeRnxClk(t *testing.T, ver float64) string {
+	name := "%-4s"
+	if ver >= 3.04 {
+		name = "%-9s"
+	}
+	record := func(rec, id string, sec float64, vals ...float64) string {
+		s := fmt.Sprintf("%s "+name+" %4d %02d %02d %02d %02d %9.6f%3d   ", rec, id, 2024, 1, 1, 0, int(sec)/60, 0.0, len(vals))
+		for i, v := range vals {
+			if i == 2 {
+				s += "\n"
+			} else if i > 0 {
+				s += " "
+			}
+			s += fmt.Sprintf("%19.12E", v)
+		}
+		return s + "\n"
+	}
+	var b strings.Builder
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", ver, "C", "G"), "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d", 18), "LEAP SECONDS"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d    AR    AS", 2), "# / TYPES OF DATA"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d", 1), "# OF CLK REF"))
+	b.WriteString(rnxHeaderLine("USN7 40451S003", "ANALYSIS CLK REF"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	for k := 0; k < 5; k++ {
+		sec := 300.0 * float64(k)
+		b.WriteString(record("AR", "USN7", sec, 1e-9))
+		b.WriteString(record("AS", "G01", sec, 1e-4+1e-9*sec, 1e-11))
+		if k != 2 {
+			b.WriteString(record("AS", "G02", sec, -2e-4+2e-10*sec, 1e-11, 2e-10, 1e-13))
+		}
+	}
+	file := filepath.Join(t.TempDir(), "test.clk")
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestReadRnxClk reads RINEX clock files and checks the interpolated clocks
+// at intermediate epochs, across a missing epoch and extrapolated by drift
+func TestReadRnxClk(t *testing.T) {
+	t0 := Epoch2Time([]float64{2024, 1, 1, 0, 0, 0})
+	g01, g02 := SatNo(SYS_GPS, 1), SatNo(SYS_GPS, 2)
+	tests := []struct {
+		sat  int
+		sec  float64
+		want float64 /* 0: error */
+	}{
+		{g01, 0.0, 1e-4},
+		{g01, 450.0, 1e-4 + 1e-9*450.0},
+		{g02, 650.0, -2e-4 + 2e-10*650.0},   /* missing epoch 600 s */
+		{g02, 1300.0, -2e-4 + 2e-10*1300.0}, /* drift */
+		{g01, 1300.0, 0.0},                  /* no drift */
+		{g01, -10.0, 0.0},
+	}
+	for _, ver := range []float64{3.00, 3.04} {
+		var nav Nav
+		n, err := ReadRnxClk(writeRnxClk(t, ver), &nav)
+		if err != nil {
+			t.Fatalf("ver=%.2f: %v", ver, err)
+		}
+		if n != 5 || nav.Nc() != 5 {
+			t.Fatalf("ver=%.2f epochs: got %d nc=%d, want 5", ver, n, nav.Nc())
+		}
+		if nav.Utc_gps[4] != 18.0 {
+			t.Errorf("ver=%.2f leap seconds: got %.0f, want 18", ver, nav.Utc_gps[4])
+		}
+		if std := nav.Pclk[1].Std[g02-1][0]; math.Abs(float64(std)-1e-11) > 1e-17 {
+			t.Errorf("ver=%.2f std of G02: got %g, want 1e-11", ver, std)
+		}
+		for _, tt := range tests {
+			dts, err := SatClk(TimeAdd(t0, tt.sec), tt.sat, &nav)
+			switch {
+			case tt.want == 0.0 && err == nil:
+				t.Errorf("ver=%.2f sat=%d t=%.0f: got %.15e, want error", ver, tt.sat, tt.sec, dts)
+			case tt.want != 0.0 && err != nil:
+				t.Errorf("ver=%.2f sat=%d t=%.0f: %v", ver, tt.sat, tt.sec, err)
+			case math.Abs(dts-tt.want) > 1e-15:
+				t.Errorf("ver=%.2f sat=%d t=%.0f: got %.15e, want %.15e", ver, tt.sat, tt.sec, dts, tt.want)
+			}
+		}
+	}
+
+	var nav Nav
+	file := filepath.Join(t.TempDir(), "test.24o")
+	if err := os.WriteFile(file, []byte(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", 3.04, "OBSERVATION DATA", "G"), "RINEX VERSION / TYPE")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadRnxClk(file, &nav); err == nil {
+		t.Errorf("observation file: want error")
+	}
+	if _, err := ReadRnxClk(filepath.Join(t.TempDir(), "none.clk"), &nav); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
@@ -0,0 +1,321 @@
+/*------------------------------------------------------------------------------
+* rnxobs.go : RINEX observation file reader epoch by epoch
+*
+*          the observation data are read one epoch at a time, the memory used
+*          does not depend on the length of the file.
+*-----------------------------------------------------------------------------*/
+package gnssgo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+/* RINEX observation file header of RnxObsReader */
+type RnxObsHeader struct {
+	Ver      float64          /* RINEX version */
+	Sys      int              /* satellite system of file (SYS_NONE: mixed) */
+	TSys     int              /* time system of file (TSYS_???) */
+	ObsTypes map[int][]string /* observation types per system (SYS_???) (ver.3 codes) */
+	Sta      Sta              /* station parameters, events appended as read */
+}
+
+/* RINEX observation file reader */
+type RnxObsReader struct {
+	fp      *os.File
+	rd      *bufio.Reader
+	tmpfile string   /* uncompressed temporary file ("": none) */
+	opt     string   /* RINEX options */
+	tsys    int      /* time system */
+	tobs    TOBS     /* observation types */
+	tcor    TOBSCorr /* observation corrections by header */
+	header  RnxObsHeader
+	cur     Sta /* station parameters changed by event records */
+	change  bool
+	data    []ObsD
+	slips   [MAXSAT][NFREQ + NEXOBS]uint8
+}
+
+/* open RINEX observation file -------------------------------------------------
+* open RINEX observation file and read the header
+* args   : string path      I   RINEX observation file path (compressed or
+*                               hatanaka-compressed file is uncompressed)
+* return : RINEX observation reader and error
+* notes  : RINEX ver.2 and ver.3 observation files are supported.
+*          close the reader by RnxObsReader.Close.
+*-----------------------------------------------------------------------------*/
+func OpenRnxObs(path string) (*RnxObsReader, error) {
+	return OpenRnxObsOpt(path, "")
+}
+
+/* open RINEX observation file with options ------------------------------------
+* open RINEX observation file with RINEX options (see ReadRnxT)
+*-----------------------------------------------------------------------------*/
+func OpenRnxObsOpt(path, opt string) (*RnxObsReader, error) {
+	var (
+		r     = &RnxObsReader{opt: opt, tsys: TSYS_GPS}
+		ctype byte
+		nav   Nav
+		err   error
+	)
+	Trace(3, "openrnxobs: path=%s\n", path)
+
+	cstat := Rtk_Uncompress(path, &r.tmpfile)
+	if cstat < 0 {
+		return nil, fmt.Errorf("rinex file uncompact error: %s", path)
+	}
+	if cstat == 0 {
+		r.tmpfile = ""
+		r.fp, err = os.Open(path)
+	} else {
+		r.fp, err = os.Open(r.tmpfile)
+	}
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("rinex file open error: %w", err)
+	}
+	r.rd = bufio.NewReader(r.fp)
+
+	r.header.Sta.InitSta()
+	if ReadRnxHeader(r.rd, &r.header.Ver, &ctype, &r.header.Sys, &r.tsys, &r.tobs,
+		&r.tcor, &nav, &r.header.Sta) == 0 {
+		r.Close()
+		return nil, fmt.Errorf("rinex header read error: %s", path)
+	}
+	if ctype != 'O' {
+		r.Close()
+		return nil, fmt.Errorf("not rinex observation file: %s type=%c", path, ctype)
+	}
+	r.header.TSys = r.tsys
+	r.header.ObsTypes = make(map[int][]string)
+	for i, sys := range []int{SYS_GPS, SYS_GLO, SYS_GAL, SYS_QZS, SYS_SBS, SYS_CMP, SYS_IRN} {
+		for j := 0; j < MAXOBSTYPE && len(r.tobs[i][j]) > 0; j++ {
+			r.header.ObsTypes[sys] = append(r.header.ObsTypes[sys], r.tobs[i][j])
+		}
+	}
+	r.cur = r.header.Sta
+	r.data = make([]ObsD, MAXOBS)
+	return r, nil
+}
+
+/* RINEX observation file header ---------------------------------------------*/
+func (r *RnxObsReader) Header() RnxObsHeader {
+	return r.header
+}
+
+/* read next epoch of RINEX observation file -----------------------------------
+* read observation data of next epoch
+* args   : none
+* return : observation data of the epoch, epoch time (gpst) and error
+*          (io.EOF: end of file)
+* notes  : the returned data are not overwritten by the following epochs.
+*          event records (epoch flag 3,4) change the station parameters from
+*          the next epoch, recorded as events in Header().Sta.Events.
+*          epochs without data of the selected systems are skipped.
+*          cycle slips of epoch flag 1 are kept in LLI as ReadRnx.
+*-----------------------------------------------------------------------------*/
+func (r *RnxObsReader) NextEpoch() ([]ObsD, Gtime, error) {
+	var flag int
+
+	if r.rd == nil {
+		return nil, Gtime{}, io.EOF
+	}
+	for {
+		n := ReadRnxObsBody(r.rd, r.opt, r.header.Ver, &r.tsys, &r.tobs, &r.tcor, &flag,
+			r.data, &r.cur)
+		if n < 0 {
+			return nil, Gtime{}, io.EOF
+		}
+		if flag == 3 || flag == 4 { /* new site or header info */
+			r.change = true
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		data := make([]ObsD, n)
+		for i := 0; i < n; i++ {
+			/* UTC . GPST */
+			if r.tsys == TSYS_UTC {
+				r.data[i].Time = Utc2GpsT(r.data[i].Time)
+			}
+			r.data[i].SaveSlips(r.slips[:])
+			r.data[i].RestoreSlips(r.slips[:])
+			r.data[i].Rcv = 1
+			data[i] = r.data[i]
+		}
+		/* station change takes effect at the next epoch */
+		if r.change {
+			cur := r.cur
+			cur.Events = nil
+			r.header.Sta.Events = append(r.header.Sta.Events, StaEvent{Time: data[0].Time, Sta: cur})
+			r.change = false
+		}
+		return data, data[0].Time, nil
+	}
+}
+
+/* close RINEX observation file ----------------------------------------------*/
+func (r *RnxObsReader) Close() error {
+	var err error
+	if r.fp != nil {
+		err = r.fp.Close()
+	}
+	if r.tmpfile != "" {
+		os.Remove(r.tmpfile)
+	}
+	r.fp, r.rd, r.tmpfile = nil, nil, ""
+	return err
+}
+
+const (
+	MERGE_DUP_FIRST = 0 /* duplicated observations: first file */
+	MERGE_DUP_SNR   = 1 /* duplicated observations: highest SNR */
+)
+
+/* options of MergeRnxObs */
+type MergeOpt struct {
+	OnDuplicate int  /* duplicated observations (MERGE_DUP_???) */
+	OverrideSta bool /* output station of first file on mismatch (false: error) */
+}
+
+/* merge RINEX observation files -----------------------------------------------
+* merge RINEX observation files of a station into a RINEX ver.3 file
+* args   : []string inputs  I   RINEX observation file paths
+*          string output    I   output RINEX observation file path
+*          MergeOpt opt     I   merge options
+* return : error
+* notes  : the epochs of the files are sorted by time. observations of a
+*          satellite in the epochs of several files (time difference <= DTTOL)
+*          are de-duplicated by opt.OnDuplicate:
+*            MERGE_DUP_FIRST: observations of the first file in inputs
+*            MERGE_DUP_SNR  : observations of the highest L1 SNR, first file
+*                             on ties
+*          the observation types of the output are the union of the files.
+*          the station parameters of the files (marker, antenna, receiver and
+*          antenna delta) shall agree unless opt.OverrideSta is set, in which
+*          case the parameters of the first file are output.
+*-----------------------------------------------------------------------------*/
+func MergeRnxObs(inputs []string, output string, opt MergeOpt) error {
+	var (
+		obs  Obs
+		ropt = RnxOpt{RnxVer: 304, Prog: "gnssgo " + VER_GNSSGO}
+		sta  Sta
+	)
+	Trace(3, "mergernxobs: n=%d output=%s\n", len(inputs), output)
+
+	if len(inputs) == 0 {
+		return fmt.Errorf("no rinex observation file to merge")
+	}
+	for i, path := range inputs {
+		r, err := OpenRnxObs(path)
+		if err != nil {
+			return err
+		}
+		hdr := r.Header()
+		if i == 0 {
+			sta = hdr.Sta
+		} else if !opt.OverrideSta && !samesta(&sta, &hdr.Sta) {
+			r.Close()
+			return fmt.Errorf("station mismatch: %s marker=%s ant=%s rec=%s", path,
+				hdr.Sta.Name, hdr.Sta.AntDes, hdr.Sta.Type)
+		}
+		mergeobstype(&ropt, &hdr)
+
+		for {
+			data, _, err := r.NextEpoch()
+			if err == io.EOF {
+				break
+			}
+			for j := range data {
+				data[j].Rcv = i + 1 /* input file index for duplicates */
+			}
+			obs.Data = append(obs.Data, data...)
+		}
+		r.Close()
+	}
+	for i := range ropt.Mask {
+		for j := range ropt.Mask[i] {
+			ropt.Mask[i][j] = '1'
+		}
+	}
+	ropt.Marker, ropt.MarkerNo = sta.Name, sta.Marker
+	ropt.Rec = [3]string{sta.RecSN, sta.Type, sta.RecVer}
+	ropt.Ant = [3]string{sta.AntSno, sta.AntDes, ""}
+	ropt.AppPos = sta.Pos
+	ropt.AntDel = [3]float64{sta.Del[2], sta.Del[0], sta.Del[1]} /* h/e/n */
+	obs.SortObs()
+
+	/* de-duplicate observations of the epochs */
+	var epochs [][]ObsD
+	for i, j := 0, 0; i < obs.N(); i = j {
+		var data []ObsD
+		for j = i; j < obs.N(); j++ {
+			if TimeDiff(obs.Data[j].Time, obs.Data[i].Time) > DTTOL {
+				break
+			}
+			k := 0
+			for ; k < len(data); k++ {
+				if data[k].Sat == obs.Data[j].Sat {
+					break
+				}
+			}
+			if k == len(data) {
+				data = append(data, obs.Data[j])
+			} else if opt.OnDuplicate == MERGE_DUP_SNR && obs.Data[j].SNR[0] > data[k].SNR[0] {
+				data[k] = obs.Data[j]
+			}
+		}
+		sort.Slice(data, func(a, b int) bool { return data[a].Sat < data[b].Sat })
+		for k := range data {
+			data[k].Time, data[k].Rcv = obs.Data[i].Time, 1
+		}
+		epochs = append(epochs, data)
+	}
+	if len(epochs) == 0 {
+		return fmt.Errorf("no observation data to merge")
+	}
+	ropt.TStart, ropt.TEnd = epochs[0][0].Time, epochs[len(epochs)-1][0].Time
+
+	fp, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("rinex file open error: %w", err)
+	}
+	OutRnxObsHeader(fp, &ropt, &Nav{})
+	for _, data := range epochs {
+		OutRnxObsBody(fp, &ropt, data, len(data), 0)
+	}
+	if err = fp.Close(); err != nil {
+		return fmt.Errorf("rinex file write error: %w", err)
+	}
+	return nil
+}
+
+/* merge observation types of file header to RINEX options -------------------*/
+func mergeobstype(ropt *RnxOpt, hdr *RnxObsHeader) {
+	for i := 0; navsys[i] > 0; i++ {
+		for _, tobs := range hdr.ObsTypes[navsys[i]] {
+			j := 0
+			for ; j < ropt.NObs[i]; j++ {
+				if ropt.TObs[i][j] == tobs {
+					break
+				}
+			}
+			if j < ropt.NObs[i] || ropt.NObs[i] >= MAXOBSTYPE {
+				continue
+			}
+			ropt.TObs[i][ropt.NObs[i]] = tobs
+			ropt.NObs[i]++
+			ropt.NavSys |= navsys[i]
+		}
+	}
+}
+
+/* compare station parameters of RINEX headers -------------------------------*/
+func samesta(a, b *Sta) bool {
+	return a.Name == b.Name && a.Marker == b.Marker && a.AntDes == b.AntDes &&
+		a.Type == b.Type && a.Del == b.Del && a.Hgt == b.Hgt
+}
@@ -0,0 +1,235 @@
+package gnssgo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRnxObs writes a RINEX ver.2 or ver.3 observation file of two GPS
+// satellites every 30 s with an antenna change event before epoch 2.
+func writeRnxObs(t *testing.T, ver float64, nep int) string {
+	var b strings.Builder
+	sys := "G"
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", ver, "OBSERVATION DATA", sys), "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine("TEST", "MARKER NAME"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "1001", "TRM57971.00     NONE"), "ANT # / TYPE"))
+	if ver < 3.0 {
+		b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d%6s%6s", 2, "C1", "L1"), "# / TYPES OF OBSERV"))
+	} else {
+		b.WriteString(rnxHeaderLine("G    2 C1C L1C", "SYS / # / OBS TYPES"))
+	}
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%6d%6d%6d%6d%6d%13.7f     %-3s", 2024, 1, 1, 0, 0, 0.0, "GPS"), "TIME OF FIRST OBS"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	for k := 0; k < nep; k++ {
+		ep := []float64{2024, 1, 1, 0, 0, 0}
+		ep[3], ep[4], ep[5] = float64(k*30/3600), float64(k*30%3600/60), float64(k*30%60)
+		if ver < 3.0 {
+			if k == 2 {
+				b.WriteString(fmt.Sprintf("%26s  %d%3d\n", "", 4, 1))
+				b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "2002", "LEIAR25.R3      NONE"), "ANT # / TYPE"))
+			}
+			b.WriteString(fmt.Sprintf(" %02.0f %2.0f %2.0f %2.0f %2.0f%11.7f  %d%3d%s%s\n",
+				ep[0]-2000, ep[1], ep[2], ep[3], ep[4], ep[5], 0, 2, "G01", "G02"))
+		} else {
+			if k == 2 {
+				b.WriteString(fmt.Sprintf("> %27s  %d%3d\n", "", 4, 1))
+				b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "2002", "LEIAR25.R3      NONE"), "ANT # / TYPE"))
+			}
+			b.WriteString(fmt.Sprintf("> %04.0f %02.0f %02.0f %02.0f %02.0f%11.7f  %d%3d\n",
+				ep[0], ep[1], ep[2], ep[3], ep[4], ep[5], 0, 2))
+		}
+		for _, prn := range []int{1, 2} {
+			p := 20000000.0 + float64(prn*1000+k)
+			if ver < 3.0 {
+				b.WriteString(fmt.Sprintf("%14.3f  %14.3f  \n", p, p/CLIGHT*FREQ1))
+			} else {
+				b.WriteString(fmt.Sprintf("G%02d%14.3f  %14.3f  \n", prn, p, p/CLIGHT*FREQ1))
+			}
+		}
+	}
+	file := filepath.Join(t.TempDir(), fmt.Sprintf("test%.0f.24o", ver*100))
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestRnxObsReader reads RINEX ver.2 and ver.3 observation files epoch by
+// epoch and compares the data with ReadRnx.
+func TestRnxObsReader(t *testing.T) {
+	const nep = 500
+	for _, ver := range []float64{2.11, 3.04} {
+		file := writeRnxObs(t, ver, nep)
+
+		var (
+			all Obs
+			nav Nav
+			sta Sta
+		)
+		if ReadRnx(file, 1, "", &all, &nav, &sta) <= 0 || all.N() != 2*nep {
+			t.Fatalf("ver %.2f: ReadRnx obs %d, want %d", ver, all.N(), 2*nep)
+		}
+
+		r, err := OpenRnxObs(file)
+		if err != nil {
+			t.Fatalf("ver %.2f: %v", ver, err)
+		}
+		h := r.Header()
+		if h.Ver != ver || h.Sta.Name != "TEST" || strings.Join(h.ObsTypes[SYS_GPS], " ") != "C1C L1C" {
+			t.Errorf("ver %.2f: header ver %.2f marker %q types %v", ver, h.Ver, h.Sta.Name, h.ObsTypes)
+		}
+
+		nepoch, nobs := 0, 0
+		for {
+			data, time, err := r.NextEpoch()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("ver %.2f epoch %d: %v", ver, nepoch, err)
+			}
+			if dt := TimeDiff(time, all.Data[nobs].Time); len(data) != 2 || dt != 0.0 {
+				t.Fatalf("ver %.2f epoch %d: %d obs time %+.1f s", ver, nepoch, len(data), dt)
+			}
+			for i := range data {
+				want := &all.Data[nobs+i]
+				if data[i].Sat != want.Sat || data[i].P[0] != want.P[0] || data[i].L[0] != want.L[0] || data[i].Rcv != 1 {
+					t.Errorf("ver %.2f epoch %d: sat %d P %.3f, want sat %d P %.3f",
+						ver, nepoch, data[i].Sat, data[i].P[0], want.Sat, want.P[0])
+				}
+			}
+			nepoch++
+			nobs += len(data)
+		}
+		if nepoch != nep {
+			t.Errorf("ver %.2f: epochs %d, want %d", ver, nepoch, nep)
+		}
+		if ev := r.Header().Sta.Events; len(ev) != 1 || ev[0].Sta.AntDes != "LEIAR25.R3      NONE" ||
+			TimeDiff(ev[0].Time, all.Data[4].Time) != 0.0 {
+			t.Errorf("ver %.2f: station events %+v", ver, ev)
+		}
+		if _, _, err := r.NextEpoch(); err != io.EOF {
+			t.Errorf("ver %.2f: after end %v, want EOF", ver, err)
+		}
+		r.Close()
+		if _, _, err := r.NextEpoch(); err != io.EOF {
+			t.Errorf("ver %.2f: after close %v, want EOF", ver, err)
+		}
+	}
+
+	if _, err := OpenRnxObs(writeRnxNav(t, synthNav(Epoch2Time([]float64{2024, 1, 1, 0, 0, 0}), 2))); err == nil {
+		t.Errorf("navigation file: want error")
+	}
+	if _, err := OpenRnxObs(filepath.Join(t.TempDir(), "none.24o")); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
+
+// writeRnxObsHour writes an hour of RINEX ver.3 observations every 30 s from
+// start (min) of satellites prns with the observation types and L1 SNR.
+func writeRnxObsHour(t *testing.T, marker string, start int, prns []int, types []string, snr float64) string {
+	var b strings.Builder
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%9.2f           %-20s%-20s", 3.04, "OBSERVATION DATA", "G"), "RINEX VERSION / TYPE"))
+	b.WriteString(rnxHeaderLine(marker, "MARKER NAME"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("%-20s%-20s", "1001", "TRM57971.00     NONE"), "ANT # / TYPE"))
+	b.WriteString(rnxHeaderLine(fmt.Sprintf("G%5d %s", len(types), strings.Join(types, " ")), "SYS / # / OBS TYPES"))
+	b.WriteString(rnxHeaderLine("", "END OF HEADER"))
+	for k := 0; k < 120; k++ {
+		sec := start*60 + k*30
+		b.WriteString(fmt.Sprintf("> 2024 01 01 %02d %02d%11.7f  0%3d\n", sec/3600, sec%3600/60,
+			float64(sec%60), len(prns)))
+		for _, prn := range prns {
+			p := 20000000.0 + float64(prn*1000+sec)
+			b.WriteString(fmt.Sprintf("G%02d", prn))
+			for _, typ := range types {
+				v := map[byte]float64{'C': p, 'L': p / CLIGHT * FREQ1, 'D': -500.0, 'S': snr}[typ[0]]
+				b.WriteString(fmt.Sprintf("%14.3f  ", v))
+			}
+			b.WriteString("\n")
+		}
+	}
+	file := filepath.Join(t.TempDir(), fmt.Sprintf("%s%03d.24o", marker, start))
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestMergeRnxObs merges two overlapping hourly files and checks the epochs,
+// the observation types and the selection of duplicated observations.
+func TestMergeRnxObs(t *testing.T) {
+	file1 := writeRnxObsHour(t, "TEST", 0, []int{1, 2}, []string{"C1C", "L1C", "S1C"}, 40.0)
+	file2 := writeRnxObsHour(t, "TEST", 30, []int{1, 3}, []string{"C1C", "L1C", "D1C", "S1C"}, 45.0)
+	output := filepath.Join(t.TempDir(), "merge.rnx")
+
+	for _, c := range []struct {
+		dup int
+		snr float64 /* SNR of G01 in overlap */
+	}{
+		{MERGE_DUP_FIRST, 40.0},
+		{MERGE_DUP_SNR, 45.0},
+	} {
+		if err := MergeRnxObs([]string{file1, file2}, output, MergeOpt{OnDuplicate: c.dup}); err != nil {
+			t.Fatalf("dup %d: %v", c.dup, err)
+		}
+		r, err := OpenRnxObs(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := r.Header()
+		if h.Ver != 3.04 || h.Sta.Name != "TEST" ||
+			strings.Join(h.ObsTypes[SYS_GPS], " ") != "C1C L1C S1C D1C" {
+			t.Errorf("dup %d: header ver %.2f marker %q types %v", c.dup, h.Ver, h.Sta.Name, h.ObsTypes)
+		}
+		var prev Gtime
+		nep := 0
+		for {
+			data, time, err := r.NextEpoch()
+			if err == io.EOF {
+				break
+			}
+			sec := TimeDiff(time, Epoch2Time([]float64{2024, 1, 1, 0, 0, 0}))
+			if nep > 0 && TimeDiff(time, prev) != 30.0 {
+				t.Errorf("dup %d: epoch %d dt %.1f s", c.dup, nep, TimeDiff(time, prev))
+			}
+			want := 2
+			if sec >= 1800 && sec < 3600 {
+				want = 3
+			}
+			if len(data) != want {
+				t.Errorf("dup %d: epoch %d %.0f s nobs %d, want %d", c.dup, nep, sec, len(data), want)
+			}
+			for _, d := range data {
+				snr := float64(d.SNR[0]) * SNR_UNIT
+				if d.Sat == 1 && sec >= 1800 && sec < 3600 && snr != c.snr {
+					t.Errorf("dup %d: %.0f s G01 snr %.1f, want %.1f", c.dup, sec, snr, c.snr)
+				}
+				if p := 20000000.0 + float64(int(d.Sat)*1000) + sec; d.P[0] != p {
+					t.Errorf("dup %d: %.0f s sat %d P %.3f, want %.3f", c.dup, sec, d.Sat, d.P[0], p)
+				}
+			}
+			prev = time
+			nep++
+		}
+		r.Close()
+		if nep != 180 {
+			t.Errorf("dup %d: epochs %d, want 180", c.dup, nep)
+		}
+	}
+
+	/* station mismatch */
+	file3 := writeRnxObsHour(t, "OTHR", 60, []int{1}, []string{"C1C"}, 40.0)
+	if err := MergeRnxObs([]string{file1, file3}, output, MergeOpt{}); err == nil {
+		t.Error("station mismatch: no error")
+	}
+	if err := MergeRnxObs([]string{file1, file3}, output, MergeOpt{OverrideSta: true}); err != nil {
+		t.Errorf("override station: %v", err)
+	}
+	if err := MergeRnxObs(nil, output, MergeOpt{}); err == nil {
+		t.Error("no input: no error")
+	}
+}
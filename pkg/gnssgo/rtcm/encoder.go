@@ -0,0 +1,297 @@
+package rtcm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+)
+
+// msmCell is an observation of one satellite signal to encode
+type msmCell struct {
+	sat, sig int     // MSM satellite ID and signal ID
+	lam      float64 // Carrier wavelength (m)
+	psr, phr float64 // Pseudorange and phase range (m, 0: invalid)
+	rate     float64 // Phase range rate (m/s, 0: invalid)
+	cnr      float64 // Carrier-to-noise ratio (dB-Hz)
+	lli      uint8   // Loss of lock indicator
+}
+
+// EncodeMSM4 encodes the observations of one system at time t (GPST) as an
+// RTCM 3 MSM4 frame including the CRC-24Q. See EncodeMSM7.
+func EncodeMSM4(obs []gnssgo.ObsD, sys int, stationID uint16, t gnssgo.Gtime) ([]byte, error) {
+	return encodeMSM(obs, sys, stationID, t, MSM4)
+}
+
+// EncodeMSM7 encodes the observations of one system at time t (GPST) as an
+// RTCM 3 MSM7 frame including the CRC-24Q.
+//
+// Observations of other systems and signals without an MSM signal ID are
+// skipped. The encoding is stateless: the lock time indicator is 0 after a
+// cycle slip (LLI bit 0) and 1 otherwise. A phase range that does not fit its
+// field is shifted by whole cycles towards the pseudorange and reported as a
+// cycle slip. GLONASS is not supported as the carrier wavelengths depend on
+// the frequency channels.
+func EncodeMSM7(obs []gnssgo.ObsD, sys int, stationID uint16, t gnssgo.Gtime) ([]byte, error) {
+	return encodeMSM(obs, sys, stationID, t, MSM7)
+}
+
+// encodeMSM encodes an MSM4 or MSM7 frame
+func encodeMSM(obs []gnssgo.ObsD, sys int, stationID uint16, t gnssgo.Gtime, msmType int) ([]byte, error) {
+	start := msmMessageStart(sys)
+	if start == 0 {
+		return nil, fmt.Errorf("%w: no MSM messages for system %d", ErrUnsupportedMessage, sys)
+	}
+	if sys == gnssgo.SYS_GLO {
+		return nil, fmt.Errorf("%w: GLONASS MSM encoding needs frequency channel numbers", ErrUnsupportedMessage)
+	}
+
+	cells := msmCells(obs, sys)
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("no observations to encode for system %d", sys)
+	}
+
+	// Satellites and signals in ascending order
+	var sats, sigs []int
+	for i := range cells {
+		if i == 0 || cells[i].sat != cells[i-1].sat {
+			sats = append(sats, cells[i].sat)
+		}
+		if j := sort.SearchInts(sigs, cells[i].sig); j == len(sigs) || sigs[j] != cells[i].sig {
+			sigs = append(sigs[:j], append([]int{cells[i].sig}, sigs[j:]...)...)
+		}
+	}
+	if len(sats)*len(sigs) > msmMaxCells {
+		return nil, fmt.Errorf("too many MSM cells: %d satellites x %d signals", len(sats), len(sigs))
+	}
+
+	// Rough range (ms, 1/1024 ms resolution) and rough phase range rate (m/s)
+	// of each satellite from its first signal
+	rrng := make([]float64, len(sats))
+	rrate := make([]float64, len(sats))
+	for i, k := 0, 0; i < len(sats); i++ {
+		for ; k < len(cells) && cells[k].sat == sats[i]; k++ {
+			c := &cells[k]
+			if rrng[i] == 0.0 {
+				r := c.psr
+				if r == 0.0 {
+					r = c.phr
+				}
+				rrng[i] = math.Round(r/gnssgo.RANGE_MS/gnssgo.P2_10) * gnssgo.P2_10
+			}
+			if rrate[i] == 0.0 && c.rate != 0.0 {
+				rrate[i] = math.Round(c.rate)
+			}
+		}
+		if rrng[i] <= 0.0 || rrng[i] >= msmInvalidRngInt {
+			return nil, fmt.Errorf("rough range out of range for satellite %d: %.3f ms", sats[i], rrng[i])
+		}
+		if math.Abs(rrate[i]) > -msmInvalidRate-1 {
+			rrate[i] = 0.0
+		}
+	}
+
+	// Header
+	buff := make([]byte, RTCM3MAXLEN+6)
+	gnssgo.SetBitU(buff, 0, 8, RTCM3PREAMB)
+	pos := 24
+	gnssgo.SetBitU(buff, pos, 12, uint32(start+msmType-1))
+	pos += 12
+	gnssgo.SetBitU(buff, pos, 12, uint32(stationID))
+	pos += 12
+	gnssgo.SetBitU(buff, pos, 30, msmEpoch(sys, t))
+	pos += 30
+	pos += 1 + 3 + 7 + 2 + 2 + 1 + 3 // Multiple message, IODS, reserved, clock and smoothing flags
+	for _, sat := range sats {
+		gnssgo.SetBitU(buff, pos+sat-1, 1, 1)
+	}
+	pos += 64
+	for _, sig := range sigs {
+		gnssgo.SetBitU(buff, pos+sig-1, 1, 1)
+	}
+	pos += 32
+	for i, k := 0, 0; i < len(sats); i++ {
+		for j := range sigs {
+			if k < len(cells) && cells[k].sat == sats[i] && cells[k].sig == sigs[j] {
+				gnssgo.SetBitU(buff, pos, 1, 1)
+				k++
+			}
+			pos++
+		}
+	}
+
+	// Satellite data
+	for i := range sats {
+		gnssgo.SetBitU(buff, pos, 8, uint32(math.Floor(rrng[i])))
+		pos += 8
+	}
+	if msmType == MSM7 {
+		pos += 4 * len(sats) // Extended satellite info
+	}
+	for i := range sats {
+		gnssgo.SetBitU(buff, pos, 10, uint32(math.Round((rrng[i]-math.Floor(rrng[i]))/gnssgo.P2_10)))
+		pos += 10
+	}
+	if msmType == MSM7 {
+		for i := range sats {
+			rate := int32(msmInvalidRate)
+			if rrate[i] != 0.0 {
+				rate = int32(rrate[i])
+			}
+			gnssgo.SetBits(buff, pos, 14, rate)
+			pos += 14
+		}
+	}
+
+	// Signal data
+	psrBits, psrRes, phrBits, phrRes, lockBits := 15, gnssgo.P2_24, 22, gnssgo.P2_29, 4
+	if msmType == MSM7 {
+		psrBits, psrRes, phrBits, phrRes, lockBits = 20, gnssgo.P2_29, 24, gnssgo.P2_31, 10
+	}
+	satIndex := make([]int, len(cells))
+	for k := range cells {
+		satIndex[k] = sort.SearchInts(sats, cells[k].sat)
+	}
+	for k := range cells {
+		r := rrng[satIndex[k]]
+		psr := msmFine(cells[k].psr, r, psrRes, psrBits)
+		gnssgo.SetBits(buff, pos, psrBits, psr)
+		pos += psrBits
+	}
+	for k := range cells {
+		c, r := &cells[k], rrng[satIndex[k]]
+		phr := msmFine(c.phr, r, phrRes, phrBits)
+		if phr == int32(-1)<<(phrBits-1) && c.phr != 0.0 && c.psr != 0.0 {
+			// Phase range off the pseudorange, shift it by whole cycles and
+			// report a cycle slip
+			c.phr -= math.Round((c.phr-c.psr)/c.lam) * c.lam
+			c.lli |= gnssgo.LLI_SLIP
+			phr = msmFine(c.phr, r, phrRes, phrBits)
+		}
+		gnssgo.SetBits(buff, pos, phrBits, phr)
+		pos += phrBits
+	}
+	for k := range cells {
+		if cells[k].lli&gnssgo.LLI_SLIP == 0 {
+			gnssgo.SetBitU(buff, pos, lockBits, 1)
+		}
+		pos += lockBits
+	}
+	for k := range cells {
+		if cells[k].lli&gnssgo.LLI_HALFC != 0 {
+			gnssgo.SetBitU(buff, pos, 1, 1)
+		}
+		pos++
+	}
+	for k := range cells {
+		if msmType == MSM7 {
+			gnssgo.SetBitU(buff, pos, 10, uint32(math.Min(math.Max(math.Round(cells[k].cnr/0.0625), 0), 1023)))
+			pos += 10
+		} else {
+			gnssgo.SetBitU(buff, pos, 6, uint32(math.Min(math.Max(math.Round(cells[k].cnr), 0), 63)))
+			pos += 6
+		}
+	}
+	if msmType == MSM7 {
+		for k := range cells {
+			rate := int32(msmInvalidRateFine)
+			if r := rrate[satIndex[k]]; r != 0.0 && cells[k].rate != 0.0 {
+				if fine := math.Round((cells[k].rate - r) / 0.0001); math.Abs(fine) < -msmInvalidRateFine {
+					rate = int32(fine)
+				}
+			}
+			gnssgo.SetBits(buff, pos, 15, rate)
+			pos += 15
+		}
+	}
+
+	return frameMessage(buff, pos)
+}
+
+// msmCells returns the observations of a system to encode sorted by
+// satellite and signal ID
+func msmCells(obs []gnssgo.ObsD, sys int) []msmCell {
+	var cells []msmCell
+	for i := range obs {
+		sat := msmSatID(sys, obs[i].Sat)
+		if sat == 0 {
+			continue
+		}
+		for j := range obs[i].Code {
+			sig := msmSignalID(sys, obs[i].Code[j])
+			if sig == 0 || (obs[i].P[j] == 0.0 && obs[i].L[j] == 0.0) {
+				continue
+			}
+			freq := gnssgo.Code2Freq(sys, obs[i].Code[j], 0)
+			if freq == 0.0 {
+				continue
+			}
+			lam := gnssgo.CLIGHT / freq
+			cells = append(cells, msmCell{
+				sat:  sat,
+				sig:  sig,
+				lam:  lam,
+				psr:  obs[i].P[j],
+				phr:  obs[i].L[j] * lam,
+				rate: -obs[i].D[j] * lam,
+				cnr:  float64(obs[i].SNR[j]) * gnssgo.SNR_UNIT,
+				lli:  obs[i].LLI[j],
+			})
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool {
+		if cells[i].sat != cells[j].sat {
+			return cells[i].sat < cells[j].sat
+		}
+		return cells[i].sig < cells[j].sig
+	})
+
+	// Keep the first observation of duplicated signals
+	n := 0
+	for i := range cells {
+		if n > 0 && cells[i].sat == cells[n-1].sat && cells[i].sig == cells[n-1].sig {
+			continue
+		}
+		cells[n] = cells[i]
+		n++
+	}
+	return cells[:n]
+}
+
+// msmFine returns the fine range field of a range (m) relative to the rough
+// range (ms), or the invalid value if the range is missing or out of range
+func msmFine(rng, rrng, res float64, bits int) int32 {
+	invalid := int32(-1) << (bits - 1)
+	if rng == 0.0 {
+		return invalid
+	}
+	fine := math.Round((rng/gnssgo.RANGE_MS - rrng) / res)
+	if math.Abs(fine) > float64(-invalid-1) {
+		return invalid
+	}
+	return int32(fine)
+}
+
+// msmEpoch returns the MSM epoch time field of time t (GPST)
+func msmEpoch(sys int, t gnssgo.Gtime) uint32 {
+	var tow float64
+	if sys == gnssgo.SYS_CMP {
+		tow = gnssgo.Time2BDT(gnssgo.GpsT2BDT(t), nil)
+	} else {
+		tow = gnssgo.Time2GpsT(t, nil)
+	}
+	return uint32(math.Round(tow*1000.0)) % (604800 * 1000)
+}
+
+// frameMessage completes an RTCM 3 frame whose payload ends at bit pos of
+// buff: sets the length, pads the payload to bytes and appends the CRC-24Q
+func frameMessage(buff []byte, pos int) ([]byte, error) {
+	n := (pos + 7) / 8
+	if n-3 > RTCM3MAXLEN {
+		return nil, fmt.Errorf("message too long: %d bytes", n-3)
+	}
+	gnssgo.SetBitU(buff, 14, 10, uint32(n-3))
+	gnssgo.SetBitU(buff, n*8, 24, gnssgo.Rtk_CRC24q(buff, n))
+	return buff[:n+3], nil
+}
@@ -703,6 +703,224 @@ func decodeBeiDouEphemeris(msg *RTCMMessage) (*BeiDouEphemeris, error) {
 	return eph, nil
 }
 
+// QZSSEphemeris represents QZSS ephemeris data from RTCM message 1044
+type QZSSEphemeris struct {
+	SatID        uint8   // Satellite ID (PRN - 192)
+	Toc          uint32  // Clock data reference time (s)
+	Af2          float64 // Clock correction polynomial coefficient (s/s²)
+	Af1          float64 // Clock correction polynomial coefficient (s/s)
+	Af0          float64 // Clock correction polynomial coefficient (s)
+	IODE         uint8   // Issue of data, ephemeris
+	Crs          float64 // Amplitude of sine harmonic correction term to orbit radius (m)
+	DeltaN       float64 // Mean motion difference from computed value (rad/s)
+	M0           float64 // Mean anomaly at reference time (rad)
+	Cuc          float64 // Amplitude of cosine harmonic correction term to argument of latitude (rad)
+	Eccentricity float64 // Eccentricity
+	Cus          float64 // Amplitude of sine harmonic correction term to argument of latitude (rad)
+	SqrtA        float64 // Square root of semi-major axis (m^(1/2))
+	Toe          uint32  // Ephemeris reference time (s)
+	Cic          float64 // Amplitude of cosine harmonic correction term to inclination angle (rad)
+	Omega0       float64 // Longitude of ascending node of orbit plane at weekly epoch (rad)
+	Cis          float64 // Amplitude of sine harmonic correction term to inclination angle (rad)
+	Inclination  float64 // Inclination angle at reference time (rad)
+	Crc          float64 // Amplitude of cosine harmonic correction term to orbit radius (m)
+	Omega        float64 // Argument of perigee (rad)
+	OmegaDot     float64 // Rate of right ascension (rad/s)
+	IDOT         float64 // Rate of inclination angle (rad/s)
+	CodeOnL2     uint8   // Code on L2
+	Week         uint16  // GPS week number (modulo 1024)
+	SvAccuracy   uint8   // SV accuracy (URA index)
+	SvHealth     uint8   // SV health
+	TGD          float64 // Group delay differential (s)
+	IODC         uint16  // Issue of data, clock
+	FitInterval  bool    // Fit interval flag (false: 2 hours, true: more than 2 hours)
+}
+
+// decodeQZSSEphemeris decodes RTCM message 1044 (QZSS Ephemeris)
+func decodeQZSSEphemeris(msg *RTCMMessage) (*QZSSEphemeris, error) {
+	if msg == nil || msg.Type != RTCM_QZSS_EPHEMERIS {
+		return nil, fmt.Errorf("not a QZSS ephemeris message")
+	}
+
+	// Message type (12 bits) and 473 bits of ephemeris after the 24 bit header
+	if len(msg.Data)*8 < 36+473 {
+		return nil, fmt.Errorf("message too short for QZSS ephemeris")
+	}
+
+	// Start position after the header and message type (24 + 12 = 36 bits)
+	pos := 36
+
+	// Create QZSS ephemeris
+	eph := &QZSSEphemeris{}
+
+	// Decode satellite ID
+	eph.SatID = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
+	pos += 4
+
+	// Decode Toc
+	eph.Toc = uint32(gnssgo.GetBitU(msg.Data, pos, 16)) * 16
+	pos += 16
+
+	// Decode Af2
+	eph.Af2 = float64(gnssgo.GetBits(msg.Data, pos, 8)) * math.Pow(2, -55)
+	pos += 8
+
+	// Decode Af1
+	eph.Af1 = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -43)
+	pos += 16
+
+	// Decode Af0
+	eph.Af0 = float64(gnssgo.GetBits(msg.Data, pos, 22)) * math.Pow(2, -31)
+	pos += 22
+
+	// Decode IODE
+	eph.IODE = uint8(gnssgo.GetBitU(msg.Data, pos, 8))
+	pos += 8
+
+	// Decode Crs
+	eph.Crs = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -5)
+	pos += 16
+
+	// Decode DeltaN
+	eph.DeltaN = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -43) * math.Pi
+	pos += 16
+
+	// Decode M0
+	eph.M0 = float64(gnssgo.GetBits(msg.Data, pos, 32)) * math.Pow(2, -31) * math.Pi
+	pos += 32
+
+	// Decode Cuc
+	eph.Cuc = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -29)
+	pos += 16
+
+	// Decode Eccentricity
+	eph.Eccentricity = float64(gnssgo.GetBitU(msg.Data, pos, 32)) * math.Pow(2, -33)
+	pos += 32
+
+	// Decode Cus
+	eph.Cus = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -29)
+	pos += 16
+
+	// Decode SqrtA
+	eph.SqrtA = float64(gnssgo.GetBitU(msg.Data, pos, 32)) * math.Pow(2, -19)
+	pos += 32
+
+	// Decode Toe
+	eph.Toe = uint32(gnssgo.GetBitU(msg.Data, pos, 16)) * 16
+	pos += 16
+
+	// Decode Cic
+	eph.Cic = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -29)
+	pos += 16
+
+	// Decode Omega0
+	eph.Omega0 = float64(gnssgo.GetBits(msg.Data, pos, 32)) * math.Pow(2, -31) * math.Pi
+	pos += 32
+
+	// Decode Cis
+	eph.Cis = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -29)
+	pos += 16
+
+	// Decode Inclination
+	eph.Inclination = float64(gnssgo.GetBits(msg.Data, pos, 32)) * math.Pow(2, -31) * math.Pi
+	pos += 32
+
+	// Decode Crc
+	eph.Crc = float64(gnssgo.GetBits(msg.Data, pos, 16)) * math.Pow(2, -5)
+	pos += 16
+
+	// Decode Omega
+	eph.Omega = float64(gnssgo.GetBits(msg.Data, pos, 32)) * math.Pow(2, -31) * math.Pi
+	pos += 32
+
+	// Decode OmegaDot
+	eph.OmegaDot = float64(gnssgo.GetBits(msg.Data, pos, 24)) * math.Pow(2, -43) * math.Pi
+	pos += 24
+
+	// Decode IDOT
+	eph.IDOT = float64(gnssgo.GetBits(msg.Data, pos, 14)) * math.Pow(2, -43) * math.Pi
+	pos += 14
+
+	// Decode code on L2
+	eph.CodeOnL2 = uint8(gnssgo.GetBitU(msg.Data, pos, 2))
+	pos += 2
+
+	// Decode week number
+	eph.Week = uint16(gnssgo.GetBitU(msg.Data, pos, 10))
+	pos += 10
+
+	// Decode SV accuracy
+	eph.SvAccuracy = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
+	pos += 4
+
+	// Decode SV health
+	eph.SvHealth = uint8(gnssgo.GetBitU(msg.Data, pos, 6))
+	pos += 6
+
+	// Decode TGD
+	eph.TGD = float64(gnssgo.GetBits(msg.Data, pos, 8)) * math.Pow(2, -31)
+	pos += 8
+
+	// Decode IODC
+	eph.IODC = uint16(gnssgo.GetBitU(msg.Data, pos, 10))
+	pos += 10
+
+	// Decode FitInterval
+	eph.FitInterval = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+
+	return eph, nil
+}
+
+// ToEph converts the ephemeris to a gnssgo broadcast ephemeris for the
+// positioning engines. The week number (modulo 1024) and the toe are resolved
+// to the nearest time of ref.
+func (e *QZSSEphemeris) ToEph(ref gnssgo.Gtime) gnssgo.Eph {
+	eph := gnssgo.Eph{
+		Sat:  gnssgo.SatNo(gnssgo.SYS_QZS, int(e.SatID)+192),
+		Iode: int(e.IODE),
+		Iodc: int(e.IODC),
+		Sva:  int(e.SvAccuracy),
+		Svh:  int(e.SvHealth),
+		Code: int(e.CodeOnL2),
+		Flag: 1,
+		A:    e.SqrtA * e.SqrtA,
+		E:    e.Eccentricity,
+		I0:   e.Inclination,
+		OMG0: e.Omega0,
+		Omg:  e.Omega,
+		M0:   e.M0,
+		Deln: e.DeltaN,
+		OMGd: e.OmegaDot,
+		Idot: e.IDOT,
+		Crc:  e.Crc,
+		Crs:  e.Crs,
+		Cuc:  e.Cuc,
+		Cus:  e.Cus,
+		Cic:  e.Cic,
+		Cis:  e.Cis,
+		Toes: float64(e.Toe),
+		F0:   e.Af0,
+		F1:   e.Af1,
+		F2:   e.Af2,
+		Ttr:  ref,
+	}
+	eph.Tgd[0] = e.TGD
+	eph.Fit = 2.0 // Same as the RTCM 3 decoder of the gnssgo package
+	if e.FitInterval {
+		eph.Fit = 0.0
+	}
+
+	eph.Week = gnssgo.AdjGpsWeekRef(int(e.Week), ref)
+	if tt := gnssgo.TimeDiff(gnssgo.GpsT2Time(eph.Week, eph.Toes), ref); tt < -302400.0 {
+		eph.Week++
+	} else if tt >= 302400.0 {
+		eph.Week--
+	}
+	eph.Toe = gnssgo.GpsT2Time(eph.Week, eph.Toes)
+	eph.Toc = gnssgo.GpsT2Time(eph.Week, float64(e.Toc))
+	return eph
+}
+
 // decodeSSROrbitClock decodes RTCM messages 1057-1062 (SSR Orbit and Clock Corrections)
 func decodeSSROrbitClock(msg *RTCMMessage) (interface{}, error) {
 	// Use the implementation from ssr.go
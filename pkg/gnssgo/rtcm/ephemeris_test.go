@@ -1,6 +1,7 @@
 package rtcm
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -269,3 +270,106 @@ func TestDecodeGalileoEphemeris(t *testing.T) {
 		t.Errorf("Expected E1BDataValidity true, got false")
 	}
 }
+
+// TestDecodeQZSSEphemeris decodes a 1044 frame of the RTCM 3 encoder of the
+// gnssgo package and checks the toe and the orbital elements
+func TestDecodeQZSSEphemeris(t *testing.T) {
+	ref := gnssgo.Epoch2Time([]float64{2024, 3, 1, 12, 0, 0})
+	week := 0
+	toes := gnssgo.Time2GpsT(ref, &week)
+	want := gnssgo.Eph{
+		Sat:  gnssgo.SatNo(gnssgo.SYS_QZS, 194),
+		Iode: 52, Iodc: 308,
+		Week: week,
+		Toe:  ref, Toc: ref,
+		Toes: toes,
+		A:    42164.0e3,
+		E:    0.075,
+		I0:   41.0 * gnssgo.D2R,
+		OMG0: 2.1,
+		Omg:  -1.57,
+		M0:   0.8,
+		Deln: 2.5e-9,
+		OMGd: -2.7e-9,
+		Idot: 1.2e-10,
+		Crs:  -120.5,
+		Crc:  310.25,
+		Cuc:  -3.2e-6,
+		Cus:  1.1e-5,
+		Cic:  2.4e-7,
+		Cis:  -1.8e-7,
+		F0:   -3.5e-4,
+		F1:   1.1e-11,
+		Tgd:  [6]float64{-4.7e-9},
+		Sva:  2,
+		Code: 2,
+		Fit:  2.0,
+	}
+
+	var enc gnssgo.Rtcm
+	enc.InitRtcm()
+	enc.NavData.Ephs[want.Sat-1] = want
+	enc.EphSat = want.Sat
+	if enc.GenRtcm3(RTCM_QZSS_EPHEMERIS, 0, 0) == 0 {
+		t.Fatal("GenRtcm3 1044 failed")
+	}
+	msgs, _, err := NewRTCMParser().ParseRTCMMessage(enc.Buff[:enc.Nbyte])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ParseRTCMMessage: %d messages, %v", len(msgs), err)
+	}
+	decoded, err := DecodeRTCMMessage(&msgs[0])
+	if err != nil {
+		t.Fatalf("DecodeRTCMMessage: %v", err)
+	}
+	eph, ok := decoded.(*QZSSEphemeris)
+	if !ok {
+		t.Fatalf("Expected *QZSSEphemeris, got %T", decoded)
+	}
+
+	if eph.SatID != 2 || eph.IODE != 52 || eph.IODC != 308 || eph.SvAccuracy != 2 || eph.CodeOnL2 != 2 {
+		t.Errorf("Expected sat 2 iode 52 iodc 308 sva 2 code 2, got %+v", eph)
+	}
+	if eph.Toe != uint32(toes) || eph.Toc != uint32(toes) || int(eph.Week) != week%1024 {
+		t.Errorf("Expected toe/toc %.0f week %d, got toe %d toc %d week %d", toes, week%1024, eph.Toe, eph.Toc, eph.Week)
+	}
+	elements := []struct {
+		name      string
+		got, want float64
+		tol       float64
+	}{
+		{"sqrtA", eph.SqrtA, math.Sqrt(want.A), math.Pow(2, -19)},
+		{"e", eph.Eccentricity, want.E, math.Pow(2, -33)},
+		{"i0", eph.Inclination, want.I0, math.Pow(2, -31) * math.Pi},
+		{"OMG0", eph.Omega0, want.OMG0, math.Pow(2, -31) * math.Pi},
+		{"omg", eph.Omega, want.Omg, math.Pow(2, -31) * math.Pi},
+		{"M0", eph.M0, want.M0, math.Pow(2, -31) * math.Pi},
+		{"deln", eph.DeltaN, want.Deln, math.Pow(2, -43) * math.Pi},
+		{"OMGd", eph.OmegaDot, want.OMGd, math.Pow(2, -43) * math.Pi},
+		{"idot", eph.IDOT, want.Idot, math.Pow(2, -43) * math.Pi},
+		{"crs", eph.Crs, want.Crs, math.Pow(2, -5)},
+		{"cus", eph.Cus, want.Cus, math.Pow(2, -29)},
+		{"af0", eph.Af0, want.F0, math.Pow(2, -31)},
+		{"tgd", eph.TGD, want.Tgd[0], math.Pow(2, -31)},
+	}
+	for _, e := range elements {
+		if math.Abs(e.got-e.want) > e.tol {
+			t.Errorf("%s: got %.12g, want %.12g", e.name, e.got, e.want)
+		}
+	}
+
+	// The converted ephemeris gives the satellite position of the original
+	got := eph.ToEph(gnssgo.TimeAdd(ref, 3600.0))
+	if got.Sat != want.Sat || got.Week != week || gnssgo.TimeDiff(got.Toe, ref) != 0.0 {
+		t.Fatalf("ToEph: sat %d week %d toe %s", got.Sat, got.Week, gnssgo.TimeStr(got.Toe, 0))
+	}
+	var rs1, rs2 [3]float64
+	var dts1, dts2, vari float64
+	tt := gnssgo.TimeAdd(ref, 1800.0)
+	gnssgo.Eph2Pos(tt, &want, rs1[:], &dts1, &vari)
+	gnssgo.Eph2Pos(tt, &got, rs2[:], &dts2, &vari)
+	for i := 0; i < 3; i++ {
+		if math.Abs(rs1[i]-rs2[i]) > 0.1 {
+			t.Errorf("position[%d]: got %.3f, want %.3f", i, rs2[i], rs1[i])
+		}
+	}
+}
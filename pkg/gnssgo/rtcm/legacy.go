@@ -2,6 +2,8 @@ package rtcm
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo"
@@ -24,6 +26,7 @@ type ObservationData struct {
 // Constants for RTCM message processing
 const (
 	PRUNIT_GPS = 299792.458  // Pseudorange unit for GPS (m)
+	PRUNIT_GLO = 599584.916  // Pseudorange unit for GLONASS (m)
 	CLIGHT     = 299792458.0 // Speed of light (m/s)
 )
 
@@ -467,3 +470,148 @@ func decodeGLONASSHeader(msg *RTCMMessage) (stationID int, tod float64, sync int
 
 	return stationID, tod, sync, nsat, nil
 }
+
+// DecodeLegacyObs decodes the observations of a GPS (1001-1004) or GLONASS
+// (1009-1012) legacy RTK observables message. The time is resolved to the
+// week (GPS) or day (GLONASS) nearest to the current time. As with
+// MSMData.ToObsD the conversion is stateless: phase rollovers are not
+// adjusted and a lock time indicator of 0 sets LLI bit 0 (cycle slip).
+// Missing pseudoranges and phases are left 0, 1001-1004 SBAS satellites are
+// skipped.
+func DecodeLegacyObs(msg *RTCMMessage) ([]gnssgo.ObsD, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("nil message")
+	}
+	var (
+		sys, start, nbit int
+		prunit           float64
+	)
+	switch msg.Type {
+	case RTCM_MSG_1001, RTCM_MSG_1002, RTCM_MSG_1003, RTCM_MSG_1004:
+		sys, start, prunit = gnssgo.SYS_GPS, 24+64, PRUNIT_GPS
+		nbit = []int{58, 74, 101, 125}[msg.Type-RTCM_MSG_1001]
+	case RTCM_MSG_1009, RTCM_MSG_1010, RTCM_MSG_1011, RTCM_MSG_1012:
+		sys, start, prunit = gnssgo.SYS_GLO, 24+61, PRUNIT_GLO
+		nbit = []int{64, 79, 107, 130}[msg.Type-RTCM_MSG_1009]
+	default:
+		return nil, fmt.Errorf("%w: type %d", ErrUnsupportedMessage, msg.Type)
+	}
+	if len(msg.Data) < 6 || len(msg.Data) < frameLength(msg.Data) || frameLength(msg.Data)*8 < start+24 {
+		return nil, fmt.Errorf("%w: type %d", ErrMessageTooShort, msg.Type)
+	}
+	buff, end := msg.Data, (frameLength(msg.Data)-3)*8 // without CRC
+
+	// Header: type, station ID, epoch time (ms), sync flag, number of satellites
+	i := 24 + 24
+	epoch := gnssgo.GetBitU(buff, i, 30)
+	if sys == gnssgo.SYS_GLO {
+		epoch >>= 3 // 27 bit time of day
+	}
+	nsat := int(gnssgo.GetBitU(buff, start-9, 5))
+	t := msmTime(sys, epoch, msmRefTime(nil))
+
+	ext := msg.Type == RTCM_MSG_1002 || msg.Type == RTCM_MSG_1004 ||
+		msg.Type == RTCM_MSG_1010 || msg.Type == RTCM_MSG_1012
+	dual := msg.Type == RTCM_MSG_1003 || msg.Type == RTCM_MSG_1004 ||
+		msg.Type == RTCM_MSG_1011 || msg.Type == RTCM_MSG_1012
+
+	obs := make([]gnssgo.ObsD, 0, nsat)
+	for j, i := 0, start; j < nsat && i+nbit <= end; j++ {
+		next := i + nbit
+		prn := int(gnssgo.GetBitU(buff, i, 6))
+		i += 6
+		code1 := gnssgo.GetBitU(buff, i, 1)
+		i += 1
+		fcn, pr1bits := 0, 24
+		if sys == gnssgo.SYS_GLO {
+			fcn = int(gnssgo.GetBitU(buff, i, 5)) - 7
+			i += 5
+			pr1bits = 25
+		}
+		pr1 := float64(gnssgo.GetBitU(buff, i, pr1bits)) * 0.02
+		i += pr1bits
+		ppr1 := gnssgo.GetBits(buff, i, 20)
+		i += 20
+		lock1 := gnssgo.GetBitU(buff, i, 7)
+		i += 7
+		var amb, cnr1 uint32
+		if ext {
+			if sys == gnssgo.SYS_GLO {
+				amb = gnssgo.GetBitU(buff, i, 7)
+				i += 7
+			} else {
+				amb = gnssgo.GetBitU(buff, i, 8)
+				i += 8
+			}
+			cnr1 = gnssgo.GetBitU(buff, i, 8)
+			i += 8
+		}
+		var (
+			code2, lock2, cnr2 uint32
+			pr21               int32 = -8192
+			ppr2               int32 = -524288
+		)
+		if dual {
+			code2 = gnssgo.GetBitU(buff, i, 2)
+			i += 2
+			pr21 = gnssgo.GetBits(buff, i, 14)
+			i += 14
+			ppr2 = gnssgo.GetBits(buff, i, 20)
+			i += 20
+			lock2 = gnssgo.GetBitU(buff, i, 7)
+			i += 7
+			if ext {
+				cnr2 = gnssgo.GetBitU(buff, i, 8)
+			}
+		}
+		i = next
+
+		sat := gnssgo.SatNo(sys, prn)
+		if sat == 0 || (sys == gnssgo.SYS_GPS && prn >= 40) {
+			continue
+		}
+		d := gnssgo.ObsD{Time: t, Sat: sat}
+		d.Code[0] = gnssgo.CODE_L1C
+		if code1 == 1 {
+			d.Code[0] = gnssgo.CODE_L1P
+		}
+		pr1 += float64(amb) * prunit
+		d.P[0] = pr1
+		if ppr1 != -524288 {
+			d.L[0] = legacyPhase(sys, d.Code[0], fcn, pr1+float64(ppr1)*0.0005)
+		}
+		if d.L[0] != 0.0 && lock1 == 0 {
+			d.LLI[0] |= gnssgo.LLI_SLIP
+		}
+		d.SNR[0] = uint16(math.Round(float64(cnr1) * 0.25 / gnssgo.SNR_UNIT))
+		if dual {
+			if sys == gnssgo.SYS_GLO {
+				d.Code[1] = []uint8{gnssgo.CODE_L2C, gnssgo.CODE_L2P, gnssgo.CODE_L2P, gnssgo.CODE_L2P}[code2]
+			} else {
+				d.Code[1] = []uint8{gnssgo.CODE_L2X, gnssgo.CODE_L2P, gnssgo.CODE_L2D, gnssgo.CODE_L2W}[code2]
+			}
+			if pr21 != -8192 {
+				d.P[1] = pr1 + float64(pr21)*0.02
+			}
+			if ppr2 != -524288 {
+				d.L[1] = legacyPhase(sys, d.Code[1], fcn, pr1+float64(ppr2)*0.0005)
+			}
+			if d.L[1] != 0.0 && lock2 == 0 {
+				d.LLI[1] |= gnssgo.LLI_SLIP
+			}
+			d.SNR[1] = uint16(math.Round(float64(cnr2) * 0.25 / gnssgo.SNR_UNIT))
+		}
+		obs = append(obs, d)
+	}
+	sort.Slice(obs, func(i, j int) bool { return obs[i].Sat < obs[j].Sat })
+	return obs, nil
+}
+
+// legacyPhase converts a phase range (m) to cycles (0 if the frequency is unknown)
+func legacyPhase(sys int, code uint8, fcn int, phaseRange float64) float64 {
+	freq := gnssgo.Code2Freq(sys, code, fcn)
+	if freq <= 0.0 {
+		return 0.0
+	}
+	return phaseRange * freq / gnssgo.CLIGHT
+}
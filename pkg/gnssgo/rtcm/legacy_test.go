@@ -2,9 +2,11 @@ package rtcm
 
 import (
 	"encoding/hex"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -151,3 +153,83 @@ func TestDecodeType1009(t *testing.T) {
 }
 
 // Add more tests for message types 1010, 1011, 1012
+
+// TestDecodeLegacyObs tests DecodeLegacyObs against the RTCM 3 encoder of the
+// gnssgo package: 1004 and 1012 frames of known observations decode to the
+// same pseudoranges and phases within the message resolution
+func TestDecodeLegacyObs(t *testing.T) {
+	// Epochs are resolved to the current week or day
+	t0 := gnssgo.Utc2GpsT(gnssgo.TimeGet())
+	t0.Sec = 0.0
+
+	var enc gnssgo.Rtcm
+	enc.InitRtcm()
+	enc.Time = t0
+	enc.ObsData.Data = enc.ObsData.Data[:0]
+	fcn := -3
+	gloSat := gnssgo.SatNo(gnssgo.SYS_GLO, 5)
+	enc.NavData.Glo_fcn[4] = fcn + 8
+	gloFreq := [2]float64{gnssgo.Code2Freq(gnssgo.SYS_GLO, gnssgo.CODE_L1C, fcn),
+		gnssgo.Code2Freq(gnssgo.SYS_GLO, gnssgo.CODE_L2C, fcn)}
+
+	for k, sat := range []int{gnssgo.SatNo(gnssgo.SYS_GPS, 2), gnssgo.SatNo(gnssgo.SYS_GPS, 17), gloSat} {
+		freq := [2]float64{gnssgo.FREQ1, gnssgo.FREQ2}
+		d := gnssgo.ObsD{Time: t0, Sat: sat}
+		d.Code[0], d.Code[1] = gnssgo.CODE_L1C, gnssgo.CODE_L2W
+		if sat == gloSat {
+			freq = gloFreq
+			d.Code[1] = gnssgo.CODE_L2C
+		}
+		d.P[0] = 20123456.789 + 1234567.891*float64(k)
+		d.P[1] = d.P[0] + 3.456
+		d.L[0] = d.P[0]*freq[0]/gnssgo.CLIGHT + 0.125
+		d.L[1] = d.P[1]*freq[1]/gnssgo.CLIGHT - 0.375
+		d.SNR[0], d.SNR[1] = uint16(45.0/gnssgo.SNR_UNIT), uint16(38.0/gnssgo.SNR_UNIT)
+		enc.ObsData.Data = append(enc.ObsData.Data, d)
+	}
+	want := append([]gnssgo.ObsD(nil), enc.ObsData.Data...)
+
+	var got []gnssgo.ObsD
+	for _, msgType := range []int{1004, 1012} {
+		if enc.GenRtcm3(msgType, 0, 0) == 0 {
+			t.Fatalf("GenRtcm3 %d failed", msgType)
+		}
+		frame := append([]byte(nil), enc.Buff[:enc.Nbyte]...)
+		obs, err := DecodeLegacyObs(&RTCMMessage{Type: msgType, Length: len(frame) - 6, Data: frame})
+		if err != nil {
+			t.Fatalf("DecodeLegacyObs %d: %v", msgType, err)
+		}
+		got = append(got, obs...)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d observations, got %d", len(want), len(got))
+	}
+	for k := range want {
+		w, g := &want[k], &got[k]
+		if g.Sat != w.Sat || gnssgo.TimeDiff(g.Time, t0) != 0.0 {
+			t.Errorf("sat %d: got sat %d time %s", w.Sat, g.Sat, gnssgo.TimeStr(g.Time, 3))
+			continue
+		}
+		for f := 0; f < 2; f++ {
+			if g.Code[f] != w.Code[f] {
+				t.Errorf("sat %d f%d: code %d, want %d", w.Sat, f+1, g.Code[f], w.Code[f])
+			}
+			if math.Abs(g.P[f]-w.P[f]) > 0.01 {
+				t.Errorf("sat %d f%d: pseudorange %.4f m, want %.4f m", w.Sat, f+1, g.P[f], w.P[f])
+			}
+			if math.Abs(g.L[f]-w.L[f]) > 0.005 {
+				t.Errorf("sat %d f%d: phase %.4f, want %.4f cycles", w.Sat, f+1, g.L[f], w.L[f])
+			}
+			if g.SNR[f] != w.SNR[f] {
+				t.Errorf("sat %d f%d: SNR %d, want %d", w.Sat, f+1, g.SNR[f], w.SNR[f])
+			}
+		}
+	}
+
+	if _, err := DecodeLegacyObs(&RTCMMessage{Type: 1077}); err == nil {
+		t.Errorf("Expected error for message type 1077")
+	}
+	if _, err := DecodeLegacyObs(&RTCMMessage{Type: 1004, Data: []byte{0xD3, 0x00, 0x02, 0x3E, 0xC0}}); err == nil {
+		t.Errorf("Expected error for a truncated message")
+	}
+}
@@ -0,0 +1,62 @@
+package rtcm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+)
+
+// testFrame returns an RTCM frame of the given type with a 2-byte payload
+func testFrame(msgType int) []byte {
+	frame := []byte{RTCM3PREAMB, 0x00, 0x02, byte(msgType >> 4), byte(msgType<<4) & 0xF0, 0x00, 0x00, 0x00}
+	gnssgo.SetBitU(frame, 40, 24, gnssgo.Rtk_CRC24q(frame, 5))
+	return frame
+}
+
+// TestMissingCritical tests the detection of missing critical messages
+func TestMissingCritical(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	parser := newRTCMParser(func() time.Time { return now })
+
+	expected := map[int]time.Duration{
+		RTCM_STATION_COORDINATES: 10 * time.Second,
+		1077:                     2 * time.Second,
+	}
+
+	// Base sends observations every second but no station coordinates
+	for i := 0; i < 5; i++ {
+		now = start.Add(time.Duration(i) * time.Second)
+		if _, _, err := parser.ParseRTCMMessage(testFrame(1077)); err != nil {
+			t.Fatalf("Failed to parse RTCM message: %v", err)
+		}
+		if missing := parser.MissingCritical(expected); len(missing) != 0 {
+			t.Errorf("%v: expected no missing messages, got %v", now.Sub(start), missing)
+		}
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		recv    []int
+		missing []int
+	}{
+		{11 * time.Second, []int{1077}, []int{RTCM_STATION_COORDINATES}},
+		{12 * time.Second, []int{RTCM_STATION_COORDINATES, 1077}, nil},
+		{23 * time.Second, nil, []int{RTCM_STATION_COORDINATES, 1077}},
+		{24 * time.Second, []int{RTCM_STATION_COORDINATES}, []int{1077}},
+	}
+	for _, tt := range tests {
+		now = start.Add(tt.elapsed)
+		for _, msgType := range tt.recv {
+			if _, _, err := parser.ParseRTCMMessage(testFrame(msgType)); err != nil {
+				t.Fatalf("Failed to parse RTCM message: %v", err)
+			}
+		}
+		if missing := parser.MissingCritical(expected); !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("%v: expected missing %v, got %v", tt.elapsed, tt.missing, missing)
+		}
+	}
+}
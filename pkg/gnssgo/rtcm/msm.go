@@ -2,6 +2,8 @@ package rtcm
 
 import (
 	"fmt"
+	"math"
+	"sort"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo"
 )
@@ -17,21 +19,33 @@ const (
 	MSM7 = 7 // Full pseudoranges, phase-ranges, phase-range-rates and CNR (high resolution)
 )
 
+// MSM field limits and invalid values (RTCM 10403.3 DF397-DF405, DF420)
+const (
+	msmMaxCells        = 64       // Max number of cells (satellites x signals)
+	msmInvalidRngInt   = 255      // DF397 rough range integer ms not available
+	msmInvalidRate     = -8192    // DF399 rough phase range rate not available
+	msmInvalidPsr      = -16384   // DF400 fine pseudorange not available
+	msmInvalidPhr      = -2097152 // DF401 fine phase range not available
+	msmInvalidPsrEx    = -524288  // DF405 fine pseudorange (extended) not available
+	msmInvalidPhrEx    = -8388608 // DF406 fine phase range (extended) not available
+	msmInvalidRateFine = -16384   // DF404 fine phase range rate not available
+)
+
 // MSMHeader represents the header of an MSM message
 type MSMHeader struct {
 	MessageType            int     // Message type
 	StationID              uint16  // Reference station ID
 	GNSSID                 int     // GNSS ID (0:GPS, 1:GLONASS, 2:Galileo, 3:SBAS, 4:QZSS, 5:BeiDou, 6:IRNSS)
-	Epoch                  uint32  // GNSS epoch time
+	Epoch                  uint32  // GNSS epoch time (ms of week, GLONASS: day of week in bits 27-29 and ms of day)
 	MultipleMessage        bool    // Multiple message bit
 	IssueOfDataStation     uint8   // IODS
 	ClockSteeringIndicator uint8   // Clock steering indicator
 	ExternalClockIndicator uint8   // External clock indicator
 	SmoothingIndicator     bool    // Divergence-free smoothing indicator
 	SmoothingInterval      uint8   // Smoothing interval
-	SatelliteMask          uint64  // Satellite mask
-	SignalMask             uint32  // Signal mask
-	CellMask               []uint8 // Cell mask
+	SatelliteMask          uint64  // Satellite mask (MSB: satellite 1)
+	SignalMask             uint32  // Signal mask (MSB: signal 1)
+	CellMask               []uint8 // Cell mask (bit i%8 of byte i/8 is cell i, satellite major)
 	NumSatellites          int     // Number of satellites
 	NumSignals             int     // Number of signals
 	NumCells               int     // Number of cells (satellite-signal combinations)
@@ -39,23 +53,29 @@ type MSMHeader struct {
 
 // MSMSatellite represents satellite data in an MSM message
 type MSMSatellite struct {
-	ID             int     // Satellite ID
-	RangeInteger   uint8   // Integer milliseconds of ranges
-	ExtendedInfo   uint8   // Extended satellite info
-	RangeModulo    float64 // Range modulo 1 millisecond (m)
-	PhaseRangeRate float64 // Phase range rate (m/s)
+	ID             int     // Satellite ID (1-64)
+	RangeInteger   uint8   // Integer milliseconds of rough range (255: invalid)
+	ExtendedInfo   uint8   // Extended satellite info (GLONASS: frequency channel + 7)
+	RangeModulo    float64 // Rough range modulo 1 millisecond (ms)
+	PhaseRangeRate float64 // Rough phase range rate (m/s)
 }
 
-// MSMSignal represents signal data in an MSM message
+// MSMSignal represents signal data in an MSM message.
+//
+// PhaseRange is in metres, the fine phase range (DF401/DF406) added to the
+// rough range as defined by RTCM 10403.3. Earlier versions of this package
+// reported it in cycles; divide by the signal wavelength to get cycles, as
+// ToObsD does.
 type MSMSignal struct {
-	Type               int     // Signal type
-	Code               int     // Signal code
-	Pseudorange        float64 // Pseudorange (m)
-	PhaseRange         float64 // Phase range (cycles)
+	SatID              int     // Satellite ID of the cell
+	Type               int     // Signal ID (1-32)
+	Code               int     // Observation code (CODE_???, CODE_NONE: undefined)
+	Pseudorange        float64 // Pseudorange (m, 0: invalid)
+	PhaseRange         float64 // Phase range (m, 0: invalid)
 	PhaseRangeLockTime uint16  // Lock time indicator
 	HalfCycleAmbiguity bool    // Half-cycle ambiguity indicator
 	CNR                float64 // Carrier-to-noise ratio (dB-Hz)
-	PhaseRangeRate     float64 // Phase range rate (m/s)
+	PhaseRangeRate     float64 // Phase range rate (m/s, 0: invalid)
 }
 
 // MSMData represents the decoded data from an MSM message
@@ -66,6 +86,20 @@ type MSMData struct {
 	Cells      []int          // Cell indices (satellite-signal combinations)
 }
 
+// getMSMType returns the MSM type (1-7) of a message type or 0 if the
+// message is not an MSM message
+func getMSMType(msgType int) int {
+	for _, start := range []int{
+		MSM_GPS_RANGE_START, MSM_GLONASS_RANGE_START, MSM_GALILEO_RANGE_START, MSM_SBAS_RANGE_START,
+		MSM_QZSS_RANGE_START, MSM_BEIDOU_RANGE_START, MSM_IRNSS_RANGE_START,
+	} {
+		if msgType >= start && msgType < start+MSM7 {
+			return msgType - start + 1
+		}
+	}
+	return 0
+}
+
 // decodeMSMMessage decodes an MSM message
 func decodeMSMMessage(msg *RTCMMessage, sys int) (*MSMData, error) {
 	if msg == nil {
@@ -73,23 +107,8 @@ func decodeMSMMessage(msg *RTCMMessage, sys int) (*MSMData, error) {
 	}
 
 	// Determine MSM type (1-7)
-	msmType := 0
-	switch {
-	case msg.Type >= MSM_GPS_RANGE_START && msg.Type <= MSM_GPS_RANGE_END:
-		msmType = msg.Type - MSM_GPS_RANGE_START + 1
-	case msg.Type >= MSM_GLONASS_RANGE_START && msg.Type <= MSM_GLONASS_RANGE_END:
-		msmType = msg.Type - MSM_GLONASS_RANGE_START + 1
-	case msg.Type >= MSM_GALILEO_RANGE_START && msg.Type <= MSM_GALILEO_RANGE_END:
-		msmType = msg.Type - MSM_GALILEO_RANGE_START + 1
-	case msg.Type >= MSM_SBAS_RANGE_START && msg.Type <= MSM_SBAS_RANGE_END:
-		msmType = msg.Type - MSM_SBAS_RANGE_START + 1
-	case msg.Type >= MSM_QZSS_RANGE_START && msg.Type <= MSM_QZSS_RANGE_END:
-		msmType = msg.Type - MSM_QZSS_RANGE_START + 1
-	case msg.Type >= MSM_BEIDOU_RANGE_START && msg.Type <= MSM_BEIDOU_RANGE_END:
-		msmType = msg.Type - MSM_BEIDOU_RANGE_START + 1
-	case msg.Type >= MSM_IRNSS_RANGE_START && msg.Type <= MSM_IRNSS_RANGE_END:
-		msmType = msg.Type - MSM_IRNSS_RANGE_START + 1
-	default:
+	msmType := getMSMType(msg.Type)
+	if msmType == 0 {
 		return nil, fmt.Errorf("not an MSM message: type %d", msg.Type)
 	}
 
@@ -98,6 +117,9 @@ func decodeMSMMessage(msg *RTCMMessage, sys int) (*MSMData, error) {
 	if err != nil {
 		return nil, err
 	}
+	if n := pos + msmDataBits(msmType, header.NumSatellites, header.NumCells); n > len(msg.Data)*8 {
+		return nil, fmt.Errorf("message too short for MSM data: %d bits, need %d", len(msg.Data)*8, n)
+	}
 
 	// Create MSM data structure
 	data := &MSMData{
@@ -134,25 +156,22 @@ func decodeMSMHeader(msg *RTCMMessage, sys int) (*MSMHeader, int, error) {
 		GNSSID:      getGNSSIDFromSystem(sys),
 	}
 
-	// Start position after message type and station ID (24 + 12 = 36 bits)
-	pos := 36
-
-	// Decode epoch time
-	if sys == gnssgo.SYS_GLO {
-		// GLONASS uses 27-bit epoch time
-		header.Epoch = uint32(gnssgo.GetBitU(msg.Data, pos, 27))
-		pos += 27
-	} else {
-		// Other systems use 30-bit epoch time
-		header.Epoch = uint32(gnssgo.GetBitU(msg.Data, pos, 30))
-		pos += 30
+	// Start position after frame header, message type and station ID (24 + 12 + 12 = 48 bits)
+	pos := 48
+	if len(msg.Data)*8 < pos+145 {
+		return nil, 0, fmt.Errorf("message too short for MSM header")
 	}
 
+	// Decode epoch time (GLONASS: 3-bit day of week and 27-bit time of day)
+	header.Epoch = uint32(gnssgo.GetBitU(msg.Data, pos, 30))
+	pos += 30
+
 	// Decode flags
 	header.MultipleMessage = gnssgo.GetBitU(msg.Data, pos, 1) != 0
 	pos++
 	header.IssueOfDataStation = uint8(gnssgo.GetBitU(msg.Data, pos, 3))
 	pos += 3
+	pos += 7 // Reserved
 	header.ClockSteeringIndicator = uint8(gnssgo.GetBitU(msg.Data, pos, 2))
 	pos += 2
 	header.ExternalClockIndicator = uint8(gnssgo.GetBitU(msg.Data, pos, 2))
@@ -162,16 +181,15 @@ func decodeMSMHeader(msg *RTCMMessage, sys int) (*MSMHeader, int, error) {
 	header.SmoothingInterval = uint8(gnssgo.GetBitU(msg.Data, pos, 3))
 	pos += 3
 
-	// Decode satellite mask (up to 64 satellites)
-	// We need to read this in two 32-bit chunks since GetBitU returns uint32
-	header.SatelliteMask = uint64(gnssgo.GetBitU(msg.Data, pos, 32)) |
-		(uint64(gnssgo.GetBitU(msg.Data, pos+32, 32)) << 32)
+	// Decode satellite mask (64 bits, first bit is satellite 1)
+	header.SatelliteMask = uint64(gnssgo.GetBitU(msg.Data, pos, 32))<<32 |
+		uint64(gnssgo.GetBitU(msg.Data, pos+32, 32))
 	pos += 64
 
 	// Count number of satellites
 	header.NumSatellites = countBits(header.SatelliteMask)
 
-	// Decode signal mask (up to 32 signals)
+	// Decode signal mask (32 bits, first bit is signal 1)
 	header.SignalMask = uint32(gnssgo.GetBitU(msg.Data, pos, 32))
 	pos += 32
 
@@ -180,6 +198,13 @@ func decodeMSMHeader(msg *RTCMMessage, sys int) (*MSMHeader, int, error) {
 
 	// Decode cell mask
 	cellMaskSize := header.NumSatellites * header.NumSignals
+	if cellMaskSize > msmMaxCells {
+		return nil, 0, fmt.Errorf("too many MSM cells: %d satellites x %d signals",
+			header.NumSatellites, header.NumSignals)
+	}
+	if len(msg.Data)*8 < pos+cellMaskSize {
+		return nil, 0, fmt.Errorf("message too short for MSM cell mask")
+	}
 	header.CellMask = make([]uint8, (cellMaskSize+7)/8) // Round up to nearest byte
 
 	for i := 0; i < cellMaskSize; i++ {
@@ -193,323 +218,382 @@ func decodeMSMHeader(msg *RTCMMessage, sys int) (*MSMHeader, int, error) {
 	return header, pos, nil
 }
 
+// msmDataBits returns the number of bits of the satellite and signal data
+// of an MSM message
+func msmDataBits(msmType, nsat, ncell int) int {
+	switch msmType {
+	case MSM1:
+		return 10*nsat + 15*ncell
+	case MSM2:
+		return 10*nsat + 27*ncell
+	case MSM3:
+		return 10*nsat + 42*ncell
+	case MSM4:
+		return 18*nsat + 48*ncell
+	case MSM5:
+		return 36*nsat + 63*ncell
+	case MSM6:
+		return 18*nsat + 65*ncell
+	default:
+		return 36*nsat + 80*ncell
+	}
+}
+
 // decodeMSMSatellites decodes satellite data from an MSM message
 func decodeMSMSatellites(msg *RTCMMessage, data *MSMData, pos int, msmType int) (int, error) {
 	header := &data.Header
 
-	// For each satellite in the mask
+	// Satellite IDs in mask order
 	satIndex := 0
 	for i := 0; i < 64; i++ {
-		if (header.SatelliteMask & (1 << i)) == 0 {
-			continue
+		if header.SatelliteMask&(1<<(63-i)) != 0 {
+			data.Satellites[satIndex].ID = i + 1 // Satellite IDs are 1-based
+			satIndex++
 		}
+	}
+	nsat := len(data.Satellites)
 
-		// Create satellite entry
-		sat := &data.Satellites[satIndex]
-		sat.ID = i + 1 // Satellite IDs are 1-based
-
-		// Decode satellite data based on MSM type
-		switch {
-		case msmType >= MSM4: // MSM4-7
-			// Decode range integer (8 bits)
-			sat.RangeInteger = uint8(gnssgo.GetBitU(msg.Data, pos, 8))
+	// Rough range integer milliseconds (MSM4-7)
+	if msmType >= MSM4 {
+		for i := 0; i < nsat; i++ {
+			data.Satellites[i].RangeInteger = uint8(gnssgo.GetBitU(msg.Data, pos, 8))
 			pos += 8
-
-			// For MSM5 and MSM7, decode extended info
-			if msmType == MSM5 || msmType == MSM7 {
-				sat.ExtendedInfo = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
-				pos += 4
-			}
 		}
-
-		satIndex++
 	}
 
-	// Decode satellite data fields
-	satIndex = 0
-	for i := 0; i < 64; i++ {
-		if (header.SatelliteMask & (1 << i)) == 0 {
-			continue
+	// Extended satellite info (MSM5 and MSM7)
+	if msmType == MSM5 || msmType == MSM7 {
+		for i := 0; i < nsat; i++ {
+			data.Satellites[i].ExtendedInfo = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
+			pos += 4
 		}
+	}
 
-		sat := &data.Satellites[satIndex]
-
-		// Decode range modulo based on MSM type
-		switch msmType {
-		case MSM1, MSM2, MSM3:
-			// 10-bit range modulo (1 ms resolution)
-			sat.RangeModulo = float64(gnssgo.GetBitU(msg.Data, pos, 10)) * 1.0
-			pos += 10
-		case MSM4, MSM5:
-			// 15-bit range modulo (1/1024 ms resolution)
-			sat.RangeModulo = float64(gnssgo.GetBitU(msg.Data, pos, 15)) * (1.0 / 1024.0)
-			pos += 15
-		case MSM6, MSM7:
-			// 20-bit range modulo (1/16384 ms resolution)
-			sat.RangeModulo = float64(gnssgo.GetBitU(msg.Data, pos, 20)) * (1.0 / 16384.0)
-			pos += 20
-		}
+	// Rough range modulo 1 ms (1/1024 ms resolution)
+	for i := 0; i < nsat; i++ {
+		data.Satellites[i].RangeModulo = float64(gnssgo.GetBitU(msg.Data, pos, 10)) * gnssgo.P2_10
+		pos += 10
+	}
 
-		// For MSM5 and MSM7, decode phase range rate
-		if msmType == MSM5 || msmType == MSM7 {
-			// Phase range rate
-			rate := int32(gnssgo.GetBits(msg.Data, pos, 15))
-			if msmType == MSM5 {
-				// MSM5: 15-bit phase range rate (0.1 m/s resolution)
-				sat.PhaseRangeRate = float64(rate) * 0.1
-				pos += 15
-			} else {
-				// MSM7: 20-bit phase range rate (0.0001 m/s resolution)
-				sat.PhaseRangeRate = float64(rate) * 0.0001
-				pos += 20
+	// Rough phase range rate (MSM5 and MSM7, 1 m/s resolution)
+	if msmType == MSM5 || msmType == MSM7 {
+		for i := 0; i < nsat; i++ {
+			rate := gnssgo.GetBits(msg.Data, pos, 14)
+			if rate != msmInvalidRate {
+				data.Satellites[i].PhaseRangeRate = float64(rate)
 			}
+			pos += 14
 		}
-
-		satIndex++
 	}
 
 	return pos, nil
 }
 
-// decodeMSMSignals decodes signal data from an MSM message
+// roughRange returns the rough range of a satellite (m) or 0 if invalid
+func (s *MSMSatellite) roughRange(msmType int) float64 {
+	if msmType < MSM4 {
+		return s.RangeModulo * gnssgo.RANGE_MS
+	}
+	if s.RangeInteger == msmInvalidRngInt {
+		return 0.0
+	}
+	return (float64(s.RangeInteger) + s.RangeModulo) * gnssgo.RANGE_MS
+}
+
+// decodeMSMSignals decodes signal data from an MSM message. For MSM1-3 the
+// ranges are ambiguous by 1 ms as the rough range has no integer part.
 func decodeMSMSignals(msg *RTCMMessage, data *MSMData, pos int, msmType int) (int, error) {
 	header := &data.Header
 
-	// For each cell in the mask
-	cellIndex := 0
-	for i := 0; i < 64; i++ {
-		if (header.SatelliteMask & (1 << i)) == 0 {
-			continue
+	// Signal IDs in mask order
+	var sigs []int
+	for j := 0; j < 32; j++ {
+		if header.SignalMask&(1<<(31-j)) != 0 {
+			sigs = append(sigs, j+1)
 		}
+	}
 
-		for j := 0; j < 32; j++ {
-			if (header.SignalMask & (1 << j)) == 0 {
-				continue
-			}
-
-			// Check if this cell is present
+	// Cells in mask order, satellite major
+	rrng := make([]float64, header.NumCells)
+	rrate := make([]float64, header.NumCells)
+	cellIndex := 0
+	for i := range data.Satellites {
+		sat := &data.Satellites[i]
+		for j, sig := range sigs {
 			cellBit := i*header.NumSignals + j
-			if (header.CellMask[cellBit/8] & (1 << (cellBit % 8))) == 0 {
+			if header.CellMask[cellBit/8]&(1<<(cellBit%8)) == 0 {
 				continue
 			}
-
-			// Store cell index
 			data.Cells[cellIndex] = cellBit
 
-			// Create signal entry
 			signal := &data.Signals[cellIndex]
-			signal.Type = j + 1 // Signal types are 1-based
-			signal.Code = getSignalCode(header.GNSSID, j)
-
+			signal.SatID = sat.ID
+			signal.Type = sig
+			signal.Code = getSignalCode(header.GNSSID, sig-1)
+			rrng[cellIndex] = sat.roughRange(msmType)
+			rrate[cellIndex] = sat.PhaseRangeRate
 			cellIndex++
 		}
 	}
+	ncell := header.NumCells
 
-	// Decode pseudoranges
-	if msmType == MSM1 || msmType == MSM3 || msmType == MSM4 || msmType == MSM5 || msmType == MSM6 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			cellBit := data.Cells[i]
-			satIdx := cellBit / header.NumSignals
-			satID := 0
-
-			// Find satellite index
-			for j := 0; j < 64; j++ {
-				if (header.SatelliteMask & (1 << j)) != 0 {
-					if satIdx == 0 {
-						satID = j
-						break
-					}
-					satIdx--
-				}
-			}
-
-			// Get satellite data
-			var sat *MSMSatellite
-			for j := 0; j < header.NumSatellites; j++ {
-				if data.Satellites[j].ID == satID+1 {
-					sat = &data.Satellites[j]
-					break
-				}
-			}
-
-			if sat == nil {
-				continue
-			}
-
-			// Decode pseudorange
+	// Fine pseudoranges
+	if msmType != MSM2 {
+		for i := 0; i < ncell; i++ {
 			signal := &data.Signals[i]
-
-			switch msmType {
-			case MSM1, MSM3:
-				// 15-bit pseudorange (1 dm resolution)
-				pr := int32(gnssgo.GetBits(msg.Data, pos, 15))
-				if pr != -16384 { // Not invalid
-					signal.Pseudorange = float64(sat.RangeInteger)*299792.458 +
-						sat.RangeModulo*299792.458 +
-						float64(pr)*0.1
+			if msmType <= MSM5 {
+				// 15-bit fine pseudorange (2^-24 ms resolution)
+				pr := gnssgo.GetBits(msg.Data, pos, 15)
+				if pr != msmInvalidPsr && rrng[i] != 0.0 {
+					signal.Pseudorange = rrng[i] + float64(pr)*gnssgo.P2_24*gnssgo.RANGE_MS
 				}
 				pos += 15
-			case MSM4, MSM5:
-				// 20-bit pseudorange (1 cm resolution)
-				pr := int32(gnssgo.GetBits(msg.Data, pos, 20))
-				if pr != -524288 { // Not invalid
-					signal.Pseudorange = float64(sat.RangeInteger)*299792.458 +
-						sat.RangeModulo*299792.458 +
-						float64(pr)*0.01
+			} else {
+				// 20-bit fine pseudorange (2^-29 ms resolution)
+				pr := gnssgo.GetBits(msg.Data, pos, 20)
+				if pr != msmInvalidPsrEx && rrng[i] != 0.0 {
+					signal.Pseudorange = rrng[i] + float64(pr)*gnssgo.P2_29*gnssgo.RANGE_MS
 				}
 				pos += 20
-			case MSM6, MSM7:
-				// 24-bit pseudorange (0.1 mm resolution)
-				pr := int32(gnssgo.GetBits(msg.Data, pos, 24))
-				if pr != -8388608 { // Not invalid
-					signal.Pseudorange = float64(sat.RangeInteger)*299792.458 +
-						sat.RangeModulo*299792.458 +
-						float64(pr)*0.0001
-				}
-				pos += 24
 			}
 		}
 	}
 
-	// Decode phase ranges
-	if msmType == MSM2 || msmType == MSM3 || msmType == MSM4 || msmType == MSM5 || msmType == MSM6 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			cellBit := data.Cells[i]
-			satIdx := cellBit / header.NumSignals
-			satID := 0
-
-			// Find satellite index
-			for j := 0; j < 64; j++ {
-				if (header.SatelliteMask & (1 << j)) != 0 {
-					if satIdx == 0 {
-						satID = j
-						break
-					}
-					satIdx--
-				}
-			}
+	// MSM1 has pseudoranges only
+	if msmType == MSM1 {
+		return pos, nil
+	}
 
-			// Get satellite data
-			var sat *MSMSatellite
-			for j := 0; j < header.NumSatellites; j++ {
-				if data.Satellites[j].ID == satID+1 {
-					sat = &data.Satellites[j]
-					break
-				}
+	// Fine phase ranges
+	for i := 0; i < ncell; i++ {
+		signal := &data.Signals[i]
+		if msmType <= MSM5 {
+			// 22-bit fine phase range (2^-29 ms resolution)
+			phr := gnssgo.GetBits(msg.Data, pos, 22)
+			if phr != msmInvalidPhr && rrng[i] != 0.0 {
+				signal.PhaseRange = rrng[i] + float64(phr)*gnssgo.P2_29*gnssgo.RANGE_MS
 			}
-
-			if sat == nil {
-				continue
+			pos += 22
+		} else {
+			// 24-bit fine phase range (2^-31 ms resolution)
+			phr := gnssgo.GetBits(msg.Data, pos, 24)
+			if phr != msmInvalidPhrEx && rrng[i] != 0.0 {
+				signal.PhaseRange = rrng[i] + float64(phr)*gnssgo.P2_31*gnssgo.RANGE_MS
 			}
+			pos += 24
+		}
+	}
 
-			// Decode phase range
-			signal := &data.Signals[i]
-
-			switch msmType {
-			case MSM2, MSM3:
-				// 22-bit phase range (0.0001 cycles resolution)
-				phr := int32(gnssgo.GetBits(msg.Data, pos, 22))
-				if phr != -2097152 { // Not invalid
-					signal.PhaseRange = float64(sat.RangeInteger)*299792.458/gnssgo.CLIGHT +
-						sat.RangeModulo*299792.458/gnssgo.CLIGHT +
-						float64(phr)*0.0001
-				}
-				pos += 22
-			case MSM4, MSM5:
-				// 24-bit phase range (0.0001 cycles resolution)
-				phr := int32(gnssgo.GetBits(msg.Data, pos, 24))
-				if phr != -8388608 { // Not invalid
-					signal.PhaseRange = float64(sat.RangeInteger)*299792.458/gnssgo.CLIGHT +
-						sat.RangeModulo*299792.458/gnssgo.CLIGHT +
-						float64(phr)*0.0001
-				}
-				pos += 24
-			case MSM6, MSM7:
-				// 29-bit phase range (0.0000001 cycles resolution)
-				phr := int32(gnssgo.GetBits(msg.Data, pos, 29))
-				if phr != -268435456 { // Not invalid
-					signal.PhaseRange = float64(sat.RangeInteger)*299792.458/gnssgo.CLIGHT +
-						sat.RangeModulo*299792.458/gnssgo.CLIGHT +
-						float64(phr)*0.0000001
-				}
-				pos += 29
-			}
+	// Lock time indicators (4 bits, MSM6/7: 10 bits)
+	for i := 0; i < ncell; i++ {
+		if msmType <= MSM5 {
+			data.Signals[i].PhaseRangeLockTime = uint16(gnssgo.GetBitU(msg.Data, pos, 4))
+			pos += 4
+		} else {
+			data.Signals[i].PhaseRangeLockTime = uint16(gnssgo.GetBitU(msg.Data, pos, 10))
+			pos += 10
 		}
 	}
 
-	// Decode lock time indicators
-	if msmType == MSM2 || msmType == MSM3 || msmType == MSM4 || msmType == MSM5 || msmType == MSM6 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			signal := &data.Signals[i]
+	// Half-cycle ambiguity indicators
+	for i := 0; i < ncell; i++ {
+		data.Signals[i].HalfCycleAmbiguity = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+		pos++
+	}
 
-			switch msmType {
-			case MSM2, MSM3, MSM4, MSM5:
-				// 4-bit lock time indicator
-				lock := uint16(gnssgo.GetBitU(msg.Data, pos, 4))
-				signal.PhaseRangeLockTime = lock
-				pos += 4
-			case MSM6, MSM7:
-				// 10-bit lock time indicator
-				lock := uint16(gnssgo.GetBitU(msg.Data, pos, 10))
-				signal.PhaseRangeLockTime = lock
-				pos += 10
-			}
-		}
+	// MSM2/3 have no CNR
+	if msmType < MSM4 {
+		return pos, nil
 	}
 
-	// Decode half-cycle ambiguity indicators
-	if msmType == MSM2 || msmType == MSM3 || msmType == MSM4 || msmType == MSM5 || msmType == MSM6 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			signal := &data.Signals[i]
+	// CNR (6 bits with 1 dB-Hz resolution, MSM6/7: 10 bits with 2^-4 dB-Hz)
+	for i := 0; i < ncell; i++ {
+		if msmType <= MSM5 {
+			data.Signals[i].CNR = float64(gnssgo.GetBitU(msg.Data, pos, 6))
+			pos += 6
+		} else {
+			data.Signals[i].CNR = float64(gnssgo.GetBitU(msg.Data, pos, 10)) * 0.0625
+			pos += 10
+		}
+	}
 
-			// 1-bit half-cycle ambiguity indicator
-			half := gnssgo.GetBitU(msg.Data, pos, 1) != 0
-			signal.HalfCycleAmbiguity = half
-			pos += 1
+	// Fine phase range rates (MSM5 and MSM7, 15 bits with 0.0001 m/s resolution)
+	if msmType == MSM5 || msmType == MSM7 {
+		for i := 0; i < ncell; i++ {
+			rate := gnssgo.GetBits(msg.Data, pos, 15)
+			if rate != msmInvalidRateFine && rrate[i] != 0.0 {
+				data.Signals[i].PhaseRangeRate = rrate[i] + float64(rate)*0.0001
+			}
+			pos += 15
 		}
 	}
 
-	// Decode CNR
-	if msmType == MSM4 || msmType == MSM5 || msmType == MSM6 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			signal := &data.Signals[i]
+	return pos, nil
+}
+
+// ToObsD converts the MSM observations to observation data records for
+// RtkPos, one per satellite sorted by satellite number.
+//
+// The epoch time is resolved to the week (GLONASS: day) nearest to the latest
+// ephemeris in nav, or to the current time without ephemerides. Signals are
+// stored at their frequency index (Code2Idx); of signals sharing a frequency
+// the one with the highest code priority is kept. Missing pseudoranges,
+// phases and rates are left 0. The conversion is stateless: a lock time
+// indicator of 0 sets LLI bit 0 (cycle slip) and the half-cycle ambiguity
+// indicator sets LLI bit 1. GLONASS phases and Dopplers need the frequency
+// channel, from the extended satellite info of MSM5/7 or the nav data, and
+// are left 0 if it is unknown.
+func (data *MSMData) ToObsD(nav *gnssgo.Nav) ([]gnssgo.ObsD, error) {
+	if data == nil {
+		return nil, fmt.Errorf("nil MSM data")
+	}
+	sys := getSystemFromGNSSID(data.Header.GNSSID)
+	if msmMessageStart(sys) == 0 {
+		return nil, fmt.Errorf("unknown MSM GNSS ID %d", data.Header.GNSSID)
+	}
+	msmType := getMSMType(data.Header.MessageType)
+	t := msmTime(sys, data.Header.Epoch, msmRefTime(nav))
 
-			switch msmType {
-			case MSM4, MSM5:
-				// 6-bit CNR (1 dB-Hz resolution)
-				cnr := uint8(gnssgo.GetBitU(msg.Data, pos, 6))
-				signal.CNR = float64(cnr)
-				pos += 6
-			case MSM6, MSM7:
-				// 10-bit CNR (0.0625 dB-Hz resolution)
-				cnr := uint16(gnssgo.GetBitU(msg.Data, pos, 10))
-				signal.CNR = float64(cnr) * 0.0625
-				pos += 10
+	obs := make([]gnssgo.ObsD, 0, len(data.Satellites))
+	for i := range data.Satellites {
+		sat := msmSatNo(sys, data.Satellites[i].ID)
+		if sat == 0 {
+			continue
+		}
+		fcn, ok := 0, true
+		if sys == gnssgo.SYS_GLO {
+			fcn, ok = msmGloFcn(&data.Satellites[i], msmType, nav, sat)
+		}
+		d := gnssgo.ObsD{Time: t, Sat: sat}
+		nobs := 0
+		for _, sig := range data.Signals {
+			if sig.SatID != data.Satellites[i].ID {
+				continue
 			}
+			code := uint8(sig.Code)
+			j := gnssgo.Code2Idx(sys, code)
+			if code == gnssgo.CODE_NONE || j < 0 || j >= gnssgo.NFREQ+gnssgo.NEXOBS {
+				continue
+			}
+			if d.Code[j] != gnssgo.CODE_NONE &&
+				gnssgo.GetCodePri(sys, d.Code[j], "") >= gnssgo.GetCodePri(sys, code, "") {
+				continue
+			}
+			lam := 0.0
+			if freq := gnssgo.Code2Freq(sys, code, fcn); ok && freq > 0.0 {
+				lam = gnssgo.CLIGHT / freq
+			}
+			d.Code[j], d.P[j], d.L[j], d.D[j], d.LLI[j] = code, sig.Pseudorange, 0.0, 0.0, 0
+			if lam > 0.0 {
+				d.L[j] = sig.PhaseRange / lam
+				d.D[j] = -sig.PhaseRangeRate / lam
+			}
+			d.SNR[j] = uint16(math.Round(sig.CNR / gnssgo.SNR_UNIT))
+			if sig.PhaseRange != 0.0 && sig.PhaseRangeLockTime == 0 {
+				d.LLI[j] |= gnssgo.LLI_SLIP
+			}
+			if sig.HalfCycleAmbiguity {
+				d.LLI[j] |= gnssgo.LLI_HALFC
+			}
+			nobs++
+		}
+		if nobs > 0 {
+			obs = append(obs, d)
 		}
 	}
+	sort.Slice(obs, func(i, j int) bool { return obs[i].Sat < obs[j].Sat })
+	return obs, nil
+}
 
-	// Decode phase range rates
-	if msmType == MSM5 || msmType == MSM7 {
-		for i := 0; i < header.NumCells; i++ {
-			signal := &data.Signals[i]
-
-			switch msmType {
-			case MSM5:
-				// 8-bit phase range rate (0.1 m/s resolution)
-				rate := int8(gnssgo.GetBits(msg.Data, pos, 8))
-				signal.PhaseRangeRate = float64(rate) * 0.1
-				pos += 8
-			case MSM7:
-				// 14-bit phase range rate (0.0001 m/s resolution)
-				rate := int16(gnssgo.GetBits(msg.Data, pos, 14))
-				signal.PhaseRangeRate = float64(rate) * 0.0001
-				pos += 14
+// msmRefTime returns the reference time (GPST) to resolve MSM epochs: the
+// latest ephemeris time of nav or the current time
+func msmRefTime(nav *gnssgo.Nav) gnssgo.Gtime {
+	var ref gnssgo.Gtime
+	if nav != nil {
+		for i := range nav.Ephs {
+			if gnssgo.TimeDiff(nav.Ephs[i].Toe, ref) > 0.0 {
+				ref = nav.Ephs[i].Toe
+			}
+		}
+		for i := range nav.Geph {
+			if gnssgo.TimeDiff(nav.Geph[i].Toe, ref) > 0.0 {
+				ref = nav.Geph[i].Toe
 			}
 		}
 	}
+	if ref.Time == 0 {
+		ref = gnssgo.Utc2GpsT(gnssgo.TimeGet())
+	}
+	return ref
+}
 
-	return pos, nil
+// msmTime returns the time (GPST) of an MSM epoch time field nearest to the
+// reference time ref (GPST)
+func msmTime(sys int, epoch uint32, ref gnssgo.Gtime) gnssgo.Gtime {
+	var week int
+	if sys == gnssgo.SYS_GLO {
+		// Time of day in GLONASS time (UTC+3h), the day of week is ignored
+		tod := float64(epoch&0x7FFFFFF) * 0.001
+		glot := gnssgo.TimeAdd(gnssgo.GpsT2Utc(ref), 10800.0)
+		tow := gnssgo.Time2GpsT(glot, &week)
+		todRef := math.Mod(tow, 86400.0)
+		if tod < todRef-43200.0 {
+			tod += 86400.0
+		} else if tod > todRef+43200.0 {
+			tod -= 86400.0
+		}
+		t := gnssgo.GpsT2Time(week, tow-todRef+tod)
+		return gnssgo.Utc2GpsT(gnssgo.TimeAdd(t, -10800.0))
+	}
+	tow := float64(epoch) * 0.001
+	if sys == gnssgo.SYS_CMP {
+		tow += 14.0 // BDT to GPST
+	}
+	towRef := gnssgo.Time2GpsT(ref, &week)
+	if tow < towRef-302400.0 {
+		tow += 604800.0
+	} else if tow > towRef+302400.0 {
+		tow -= 604800.0
+	}
+	return gnssgo.GpsT2Time(week, tow)
+}
+
+// msmSatNo returns the satellite number of an MSM satellite ID (1-64) or 0
+func msmSatNo(sys, id int) int {
+	switch sys {
+	case gnssgo.SYS_QZS:
+		id += gnssgo.MINPRNQZS - 1
+	case gnssgo.SYS_SBS:
+		id += gnssgo.MINPRNSBS - 1
+	}
+	return gnssgo.SatNo(sys, id)
+}
+
+// msmGloFcn returns the frequency channel number of a GLONASS satellite from
+// the extended satellite info of MSM5/7, the latest ephemeris or the FCN
+// table of nav
+func msmGloFcn(s *MSMSatellite, msmType int, nav *gnssgo.Nav, sat int) (int, bool) {
+	if (msmType == MSM5 || msmType == MSM7) && s.ExtendedInfo <= 13 {
+		return int(s.ExtendedInfo) - 7, true
+	}
+	if nav == nil {
+		return 0, false
+	}
+	var geph *gnssgo.GEph
+	for i := range nav.Geph {
+		if nav.Geph[i].Sat == sat && (geph == nil || gnssgo.TimeDiff(nav.Geph[i].Toe, geph.Toe) > 0.0) {
+			geph = &nav.Geph[i]
+		}
+	}
+	if geph != nil {
+		return geph.Frq, true
+	}
+	var prn int
+	gnssgo.SatSys(sat, &prn)
+	if prn >= 1 && prn <= len(nav.Glo_fcn) && nav.Glo_fcn[prn-1] > 0 {
+		return nav.Glo_fcn[prn-1] - 8, true
+	}
+	return 0, false
 }
 
 // Helper functions
@@ -558,202 +642,127 @@ func getGNSSIDFromSystem(sys int) int {
 	}
 }
 
-// getSignalCode returns the signal code for a given GNSS ID and signal ID
-// Based on RTCM 3.3 signal definitions
-func getSignalCode(gnssID, signalID int) int {
-	// Signal codes based on RTCM 3.3 Table 3.5-91
+// getSystemFromGNSSID converts an MSM GNSS ID to a GNSS system ID
+func getSystemFromGNSSID(gnssID int) int {
 	switch gnssID {
-	case 0: // GPS
-		switch signalID {
-		case 0: // L1 C/A
-			return 1
-		case 1: // L1 P
-			return 2
-		case 2: // L1 Z-tracking
-			return 3
-		case 3: // L1C (1) - pilot component
-			return 4
-		case 4: // L1C (2) - data component
-			return 5
-		case 5: // L2 CM
-			return 6
-		case 6: // L2 CL
-			return 7
-		case 7: // L2 P
-			return 8
-		case 8: // L2 Z-tracking
-			return 9
-		case 9: // L5 I
-			return 10
-		case 10: // L5 Q
-			return 11
-		case 15: // L1 L-SAIF
-			return 16
-		default:
-			return 0
-		}
-	case 1: // GLONASS
-		switch signalID {
-		case 0: // G1 C/A
-			return 1
-		case 1: // G1 P
-			return 2
-		case 2: // G2 C/A
-			return 3
-		case 3: // G2 P
-			return 4
-		case 4: // G3 I
-			return 5
-		case 5: // G3 Q
-			return 6
-		default:
-			return 0
-		}
-	case 2: // Galileo
-		switch signalID {
-		case 0: // E1 C
-			return 1
-		case 1: // E1 A
-			return 2
-		case 2: // E1 B
-			return 3
-		case 3: // E1 B+C
-			return 4
-		case 4: // E1 A+B+C
-			return 5
-		case 5: // E5a I
-			return 6
-		case 6: // E5a Q
-			return 7
-		case 7: // E5a I+Q
-			return 8
-		case 8: // E5b I
-			return 9
-		case 9: // E5b Q
-			return 10
-		case 10: // E5b I+Q
-			return 11
-		case 11: // E5 I
-			return 12
-		case 12: // E5 Q
-			return 13
-		case 13: // E5 I+Q
-			return 14
-		case 14: // E6 C
-			return 15
-		case 15: // E6 A
-			return 16
-		case 16: // E6 B
-			return 17
-		case 17: // E6 B+C
-			return 18
-		case 18: // E6 A+B+C
-			return 19
-		default:
-			return 0
-		}
-	case 3: // SBAS
-		switch signalID {
-		case 0: // L1 C/A
-			return 1
-		case 1: // L5 I
-			return 2
-		case 2: // L5 Q
-			return 3
-		case 3: // L5 I+Q
-			return 4
-		default:
-			return 0
-		}
-	case 4: // QZSS
-		switch signalID {
-		case 0: // L1 C/A
-			return 1
-		case 1: // L1 SAIF
-			return 2
-		case 2: // L1C (1) - pilot component
-			return 3
-		case 3: // L1C (2) - data component
-			return 4
-		case 4: // L2 CM
-			return 5
-		case 5: // L2 CL
-			return 6
-		case 6: // L5 I
-			return 7
-		case 7: // L5 Q
-			return 8
-		case 8: // L5 I+Q
-			return 9
-		case 9: // L6 D
-			return 10
-		case 10: // L6 E
-			return 11
-		case 11: // L6 D+E
-			return 12
-		default:
-			return 0
-		}
-	case 5: // BeiDou
-		switch signalID {
-		case 0: // B1I
-			return 1
-		case 1: // B1Q
-			return 2
-		case 2: // B1I+Q
-			return 3
-		case 3: // B3I
-			return 4
-		case 4: // B3Q
-			return 5
-		case 5: // B3I+Q
-			return 6
-		case 6: // B2I
-			return 7
-		case 7: // B2Q
-			return 8
-		case 8: // B2I+Q
-			return 9
-		case 9: // B1C (1) - pilot component
-			return 10
-		case 10: // B1C (2) - data component
-			return 11
-		case 11: // B5 I
-			return 12
-		case 12: // B5 Q
-			return 13
-		case 13: // B5 I+Q
-			return 14
-		case 14: // B2a I
-			return 15
-		case 15: // B2a Q
-			return 16
-		case 16: // B2a I+Q
-			return 17
-		case 17: // B2b I
-			return 18
-		case 18: // B2b Q
-			return 19
-		case 19: // B2b I+Q
-			return 20
-		default:
-			return 0
-		}
-	case 6: // IRNSS
-		switch signalID {
-		case 0: // L5 SPS
-			return 1
-		case 1: // S SPS
-			return 2
-		case 2: // L5 RS
-			return 3
-		case 3: // S RS
-			return 4
-		case 4: // L5 + S SPS
-			return 5
-		default:
-			return 0
+	case 0:
+		return gnssgo.SYS_GPS
+	case 1:
+		return gnssgo.SYS_GLO
+	case 2:
+		return gnssgo.SYS_GAL
+	case 3:
+		return gnssgo.SYS_SBS
+	case 4:
+		return gnssgo.SYS_QZS
+	case 5:
+		return gnssgo.SYS_CMP
+	case 6:
+		return gnssgo.SYS_IRN
+	default:
+		return gnssgo.SYS_NONE
+	}
+}
+
+// getSignalCode returns the observation code (CODE_???) of an MSM signal
+// (0-31 for signal ID 1-32) of a GNSS ID, or CODE_NONE if undefined
+func getSignalCode(gnssID, signalID int) int {
+	sigs, ok := msmSignals[getSystemFromGNSSID(gnssID)]
+	if !ok || signalID < 0 || signalID >= len(sigs) || sigs[signalID] == "" {
+		return gnssgo.CODE_NONE
+	}
+	return int(gnssgo.Obs2Code(sigs[signalID]))
+}
+
+// msmSignals lists the RINEX observation codes of the MSM signal IDs 1-32
+// (RTCM 10403.3 tables 3.5-91, 96, 99, 102, 105, 108 and 108.3)
+var msmSignals = map[int][32]string{
+	gnssgo.SYS_GPS: {
+		"", "1C", "1P", "1W", "", "", "", "2C", "2P", "2W", "", "",
+		"", "", "2S", "2L", "2X", "", "", "", "", "5I", "5Q", "5X",
+		"", "", "", "", "", "1S", "1L", "1X"},
+	gnssgo.SYS_GLO: {
+		"", "1C", "1P", "", "", "", "", "2C", "2P", "", "", "",
+		"", "", "", "", "", "", "", "", "", "", "", "",
+		"", "", "", "", "", "", "", ""},
+	gnssgo.SYS_GAL: {
+		"", "1C", "1A", "1B", "1X", "1Z", "", "6C", "6A", "6B", "6X", "6Z",
+		"", "7I", "7Q", "7X", "", "8I", "8Q", "8X", "", "5I", "5Q", "5X",
+		"", "", "", "", "", "", "", ""},
+	gnssgo.SYS_SBS: {
+		"", "1C", "", "", "", "", "", "", "", "", "", "",
+		"", "", "", "", "", "", "", "", "", "5I", "5Q", "5X",
+		"", "", "", "", "", "", "", ""},
+	gnssgo.SYS_QZS: {
+		"", "1C", "", "", "", "", "", "", "6S", "6L", "6X", "",
+		"", "", "2S", "2L", "2X", "", "", "", "", "5I", "5Q", "5X",
+		"", "", "", "", "", "1S", "1L", "1X"},
+	gnssgo.SYS_CMP: {
+		"", "2I", "2Q", "2X", "", "", "", "6I", "6Q", "6X", "", "",
+		"", "7I", "7Q", "7X", "", "", "", "", "", "5D", "5P", "5X",
+		"", "", "", "", "", "1D", "1P", "1X"},
+	gnssgo.SYS_IRN: {
+		"", "", "", "", "", "", "", "", "", "", "", "",
+		"", "", "", "", "", "", "", "", "", "5A", "", "",
+		"", "", "", "", "", "", "", ""},
+}
+
+// msmSignalID returns the MSM signal ID (1-32) of an observation code or 0
+// if the code has no MSM signal
+func msmSignalID(sys int, code uint8) int {
+	obs := gnssgo.Code2Obs(code)
+	if obs == "" {
+		return 0
+	}
+	sigs, ok := msmSignals[sys]
+	if !ok {
+		return 0
+	}
+	for i, sig := range sigs {
+		if sig == obs {
+			return i + 1
 		}
+	}
+	return 0
+}
+
+// msmSatID returns the MSM satellite ID (1-64) of a satellite number or 0 if
+// the satellite is not of the system
+func msmSatID(sys, sat int) int {
+	var prn int
+	if gnssgo.SatSys(sat, &prn) != sys {
+		return 0
+	}
+	switch sys {
+	case gnssgo.SYS_QZS:
+		prn -= gnssgo.MINPRNQZS - 1
+	case gnssgo.SYS_SBS:
+		prn -= gnssgo.MINPRNSBS - 1
+	}
+	if prn < 1 || prn > 64 {
+		return 0
+	}
+	return prn
+}
+
+// msmMessageStart returns the first MSM message type of a system or 0 if the
+// system has no MSM messages
+func msmMessageStart(sys int) int {
+	switch sys {
+	case gnssgo.SYS_GPS:
+		return MSM_GPS_RANGE_START
+	case gnssgo.SYS_GLO:
+		return MSM_GLONASS_RANGE_START
+	case gnssgo.SYS_GAL:
+		return MSM_GALILEO_RANGE_START
+	case gnssgo.SYS_SBS:
+		return MSM_SBAS_RANGE_START
+	case gnssgo.SYS_QZS:
+		return MSM_QZSS_RANGE_START
+	case gnssgo.SYS_CMP:
+		return MSM_BEIDOU_RANGE_START
+	case gnssgo.SYS_IRN:
+		return MSM_IRNSS_RANGE_START
 	default:
 		return 0
 	}
@@ -1,6 +1,8 @@
 package rtcm
 
 import (
+	"bytes"
+	"math"
 	"testing"
 	"time"
 
@@ -19,8 +21,9 @@ func TestDecodeMSMHeader(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	// Message type (24 bits) and station ID (12 bits) are already set
-	pos := 36 // Start after message type and station ID
+	// Frame header (24 bits), message type and station ID (12 bits each) are
+	// left blank
+	pos := 48 // Start after message type and station ID
 
 	// Set epoch time (30 bits for GPS)
 	gnssgo.SetBitU(msg.Data, pos, 30, 500000)
@@ -34,6 +37,9 @@ func TestDecodeMSMHeader(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 3, 5)
 	pos += 3
 
+	// Reserved (7 bits)
+	pos += 7
+
 	// Set clock steering indicator (2 bits)
 	gnssgo.SetBitU(msg.Data, pos, 2, 2)
 	pos += 2
@@ -50,16 +56,16 @@ func TestDecodeMSMHeader(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 3, 3)
 	pos += 3
 
-	// Set satellite mask (64 bits)
-	// Set bits for PRN 1, 5, and 10
-	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000421) // Bits 0, 5, 10 set in first 32 bits
+	// Set satellite mask (64 bits, MSB first)
+	// Set bits for PRN 1, 6, and 11
+	gnssgo.SetBitU(msg.Data, pos, 32, 0x84200000) // Bits 0, 5, 10 set in first 32 bits
 	pos += 32
 	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000000) // No bits set in second 32 bits
 	pos += 32
 
-	// Set signal mask (32 bits)
-	// Set bits for L1 C/A, L2P, and L5I
-	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000205) // Bits 0, 2, 9 set
+	// Set signal mask (32 bits, MSB first)
+	// Set bits for L1 C/A (2), L2P (9), and L5I (22)
+	gnssgo.SetBitU(msg.Data, pos, 32, 0x40800400) // Bits 1, 8, 21 set
 	pos += 32
 
 	// Set cell mask (3 satellites * 3 signals = 9 bits)
@@ -130,8 +136,9 @@ func TestDecodeMSMMessage(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	// Message type (24 bits) and station ID (12 bits) are already set
-	pos := 36 // Start after message type and station ID
+	// Frame header (24 bits), message type and station ID (12 bits each) are
+	// left blank
+	pos := 48 // Start after message type and station ID
 
 	// Set epoch time (30 bits for GPS)
 	gnssgo.SetBitU(msg.Data, pos, 30, 500000)
@@ -145,6 +152,9 @@ func TestDecodeMSMMessage(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 3, 5)
 	pos += 3
 
+	// Reserved (7 bits)
+	pos += 7
+
 	// Set clock steering indicator (2 bits)
 	gnssgo.SetBitU(msg.Data, pos, 2, 2)
 	pos += 2
@@ -161,16 +171,16 @@ func TestDecodeMSMMessage(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 3, 3)
 	pos += 3
 
-	// Set satellite mask (64 bits)
-	// Set bits for PRN 1 and 5
-	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000021) // Bits 0 and 5 set in first 32 bits
+	// Set satellite mask (64 bits, MSB first)
+	// Set bits for PRN 1 and 6
+	gnssgo.SetBitU(msg.Data, pos, 32, 0x84000000) // Bits 0 and 5 set in first 32 bits
 	pos += 32
 	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000000) // No bits set in second 32 bits
 	pos += 32
 
-	// Set signal mask (32 bits)
-	// Set bits for L1 C/A and L2P
-	gnssgo.SetBitU(msg.Data, pos, 32, 0x00000005) // Bits 0 and 2 set
+	// Set signal mask (32 bits, MSB first)
+	// Set bits for L1 C/A (2) and L2P (9)
+	gnssgo.SetBitU(msg.Data, pos, 32, 0x40800000) // Bits 1 and 8 set
 	pos += 32
 
 	// Set cell mask (2 satellites * 2 signals = 4 bits)
@@ -182,43 +192,43 @@ func TestDecodeMSMMessage(t *testing.T) {
 	// For MSM4, we need to set the range integer for each satellite
 	gnssgo.SetBitU(msg.Data, pos, 8, 100) // PRN 1 range integer
 	pos += 8
-	gnssgo.SetBitU(msg.Data, pos, 8, 150) // PRN 5 range integer
+	gnssgo.SetBitU(msg.Data, pos, 8, 150) // PRN 6 range integer
 	pos += 8
 
-	// Set range modulo for each satellite
-	gnssgo.SetBitU(msg.Data, pos, 15, 1000) // PRN 1 range modulo
+	// Set range modulo for each satellite (10 bits)
+	gnssgo.SetBitU(msg.Data, pos, 10, 1000) // PRN 1 range modulo
+	pos += 10
+	gnssgo.SetBitU(msg.Data, pos, 10, 200) // PRN 6 range modulo
+	pos += 10
+
+	// Set fine pseudoranges for each cell (15 bits)
+	gnssgo.SetBits(msg.Data, pos, 15, 5000) // PRN 1, L1 C/A
+	pos += 15
+	gnssgo.SetBits(msg.Data, pos, 15, 5100) // PRN 1, L2P
 	pos += 15
-	gnssgo.SetBitU(msg.Data, pos, 15, 2000) // PRN 5 range modulo
+	gnssgo.SetBits(msg.Data, pos, 15, 5200) // PRN 6, L1 C/A
+	pos += 15
+	gnssgo.SetBits(msg.Data, pos, 15, 5300) // PRN 6, L2P
 	pos += 15
 
-	// Set pseudoranges for each cell
-	gnssgo.SetBits(msg.Data, pos, 20, 5000) // PRN 1, L1 C/A
-	pos += 20
-	gnssgo.SetBits(msg.Data, pos, 20, 5100) // PRN 1, L2P
-	pos += 20
-	gnssgo.SetBits(msg.Data, pos, 20, 5200) // PRN 5, L1 C/A
-	pos += 20
-	gnssgo.SetBits(msg.Data, pos, 20, 5300) // PRN 5, L2P
-	pos += 20
-
-	// Set phase ranges for each cell
-	gnssgo.SetBits(msg.Data, pos, 24, 6000) // PRN 1, L1 C/A
-	pos += 24
-	gnssgo.SetBits(msg.Data, pos, 24, 6100) // PRN 1, L2P
-	pos += 24
-	gnssgo.SetBits(msg.Data, pos, 24, 6200) // PRN 5, L1 C/A
-	pos += 24
-	gnssgo.SetBits(msg.Data, pos, 24, 6300) // PRN 5, L2P
-	pos += 24
+	// Set fine phase ranges for each cell (22 bits)
+	gnssgo.SetBits(msg.Data, pos, 22, 6000) // PRN 1, L1 C/A
+	pos += 22
+	gnssgo.SetBits(msg.Data, pos, 22, 6100) // PRN 1, L2P
+	pos += 22
+	gnssgo.SetBits(msg.Data, pos, 22, 6200) // PRN 6, L1 C/A
+	pos += 22
+	gnssgo.SetBits(msg.Data, pos, 22, 6300) // PRN 6, L2P
+	pos += 22
 
 	// Set lock time indicators for each cell
 	gnssgo.SetBitU(msg.Data, pos, 4, 5) // PRN 1, L1 C/A
 	pos += 4
 	gnssgo.SetBitU(msg.Data, pos, 4, 6) // PRN 1, L2P
 	pos += 4
-	gnssgo.SetBitU(msg.Data, pos, 4, 7) // PRN 5, L1 C/A
+	gnssgo.SetBitU(msg.Data, pos, 4, 7) // PRN 6, L1 C/A
 	pos += 4
-	gnssgo.SetBitU(msg.Data, pos, 4, 8) // PRN 5, L2P
+	gnssgo.SetBitU(msg.Data, pos, 4, 8) // PRN 6, L2P
 	pos += 4
 
 	// Set half-cycle ambiguity indicators for each cell
@@ -226,9 +236,9 @@ func TestDecodeMSMMessage(t *testing.T) {
 	pos += 1
 	gnssgo.SetBitU(msg.Data, pos, 1, 1) // PRN 1, L2P
 	pos += 1
-	gnssgo.SetBitU(msg.Data, pos, 1, 0) // PRN 5, L1 C/A
+	gnssgo.SetBitU(msg.Data, pos, 1, 0) // PRN 6, L1 C/A
 	pos += 1
-	gnssgo.SetBitU(msg.Data, pos, 1, 1) // PRN 5, L2P
+	gnssgo.SetBitU(msg.Data, pos, 1, 1) // PRN 6, L2P
 	pos += 1
 
 	// Set CNR for each cell
@@ -236,9 +246,9 @@ func TestDecodeMSMMessage(t *testing.T) {
 	pos += 6
 	gnssgo.SetBitU(msg.Data, pos, 6, 42) // PRN 1, L2P
 	pos += 6
-	gnssgo.SetBitU(msg.Data, pos, 6, 44) // PRN 5, L1 C/A
+	gnssgo.SetBitU(msg.Data, pos, 6, 44) // PRN 6, L1 C/A
 	pos += 6
-	gnssgo.SetBitU(msg.Data, pos, 6, 46) // PRN 5, L2P
+	gnssgo.SetBitU(msg.Data, pos, 6, 46) // PRN 6, L2P
 	pos += 6
 
 	// Decode the MSM message
@@ -279,4 +289,381 @@ func TestDecodeMSMMessage(t *testing.T) {
 	if len(msm.Signals) != 4 {
 		t.Fatalf("Expected 4 signals, got %d", len(msm.Signals))
 	}
+	signal := msm.Signals[1] // PRN 1, L2P
+	if signal.SatID != 1 || signal.Type != 9 {
+		t.Errorf("Expected PRN 1 signal 9, got PRN %d signal %d", signal.SatID, signal.Type)
+	}
+	rrng := (100 + 1000*gnssgo.P2_10) * gnssgo.RANGE_MS
+	if want := rrng + 5100*gnssgo.P2_24*gnssgo.RANGE_MS; math.Abs(signal.Pseudorange-want) > 1e-6 {
+		t.Errorf("Expected pseudorange %.6f, got %.6f", want, signal.Pseudorange)
+	}
+	if want := rrng + 6100*gnssgo.P2_29*gnssgo.RANGE_MS; math.Abs(signal.PhaseRange-want) > 1e-6 {
+		t.Errorf("Expected phase range %.6f, got %.6f", want, signal.PhaseRange)
+	}
+	if signal.PhaseRangeLockTime != 6 || !signal.HalfCycleAmbiguity || signal.CNR != 42 {
+		t.Errorf("Expected lock 6, half-cycle true and CNR 42, got %d, %v and %.0f",
+			signal.PhaseRangeLockTime, signal.HalfCycleAmbiguity, signal.CNR)
+	}
+}
+
+// TestEncodeMSM tests that encoded MSM4/MSM7 frames decode to the input
+// observations and that a decode-encode round trip is stable
+func TestEncodeMSM(t *testing.T) {
+	t0 := gnssgo.Epoch2Time([]float64{2023, 6, 1, 0, 0, 30})
+
+	tests := []struct {
+		name     string
+		encode   func([]gnssgo.ObsD, int, uint16, gnssgo.Gtime) ([]byte, error)
+		msgType  int
+		sys      int
+		codes    []uint8
+		tow      uint32  // Epoch time field (ms)
+		psrTol   float64 // Pseudorange resolution (m)
+		phrTol   float64 // Phase range resolution (m)
+		hasRate  bool
+		cnrScale float64
+	}{
+		{"GPS MSM4", EncodeMSM4, 1074, gnssgo.SYS_GPS, []uint8{gnssgo.CODE_L1C, gnssgo.CODE_L2W, gnssgo.CODE_L5Q},
+			345630000, gnssgo.P2_24 * gnssgo.RANGE_MS, gnssgo.P2_29 * gnssgo.RANGE_MS, false, 1.0},
+		{"GPS MSM7", EncodeMSM7, 1077, gnssgo.SYS_GPS, []uint8{gnssgo.CODE_L1C, gnssgo.CODE_L2W, gnssgo.CODE_L5Q},
+			345630000, gnssgo.P2_29 * gnssgo.RANGE_MS, gnssgo.P2_31 * gnssgo.RANGE_MS, true, 0.0625},
+		{"Galileo MSM7", EncodeMSM7, 1097, gnssgo.SYS_GAL, []uint8{gnssgo.CODE_L1C, gnssgo.CODE_L5Q, gnssgo.CODE_L7Q},
+			345630000, gnssgo.P2_29 * gnssgo.RANGE_MS, gnssgo.P2_31 * gnssgo.RANGE_MS, true, 0.0625},
+		{"BeiDou MSM4", EncodeMSM4, 1124, gnssgo.SYS_CMP, []uint8{gnssgo.CODE_L2I, gnssgo.CODE_L7I},
+			345616000, gnssgo.P2_24 * gnssgo.RANGE_MS, gnssgo.P2_29 * gnssgo.RANGE_MS, false, 1.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Observations of 4 satellites, the last one without its last signal
+			var obs []gnssgo.ObsD
+			for k, prn := range []int{3, 7, 12, 25} {
+				d := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(tt.sys, prn)}
+				for j, code := range tt.codes {
+					if k == 3 && j == len(tt.codes)-1 {
+						continue
+					}
+					i := gnssgo.Code2Idx(tt.sys, code)
+					lam := gnssgo.CLIGHT / gnssgo.Code2Freq(tt.sys, code, 0)
+					d.Code[i] = code
+					d.P[i] = 20123456.789 + float64(k)*987654.321 + float64(j)*1.234
+					d.L[i] = d.P[i]/lam + 0.25*float64(j+k)
+					d.D[i] = -(312.3 - float64(k)*201.7 + float64(j)*0.05) / lam
+					d.SNR[i] = uint16((40.0 + float64(k) + 0.25*float64(j)) / gnssgo.SNR_UNIT)
+				}
+				obs = append(obs, d)
+			}
+			obs[1].LLI[0] = gnssgo.LLI_SLIP | gnssgo.LLI_HALFC
+
+			frame, err := tt.encode(obs, tt.sys, 1234, t0)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			msgs, _, err := NewRTCMParser().ParseRTCMMessage(frame)
+			if err != nil || len(msgs) != 1 {
+				t.Fatalf("parse failed: %v (%d messages)", err, len(msgs))
+			}
+			if msgs[0].Type != tt.msgType || msgs[0].StationID != 1234 {
+				t.Fatalf("got type %d station %d, want %d 1234", msgs[0].Type, msgs[0].StationID, tt.msgType)
+			}
+			decoded, err := DecodeRTCMMessage(&msgs[0])
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			data := decoded.(*MSMData)
+			if data.Header.Epoch != tt.tow {
+				t.Errorf("epoch = %d, want %d", data.Header.Epoch, tt.tow)
+			}
+			if data.Header.NumSatellites != 4 || data.Header.NumCells != 4*len(tt.codes)-1 {
+				t.Fatalf("got %d satellites %d cells, want 4 %d", data.Header.NumSatellites,
+					data.Header.NumCells, 4*len(tt.codes)-1)
+			}
+
+			// Decoded signals match the input within the field resolution
+			for _, sig := range data.Signals {
+				sat := gnssgo.SatNo(tt.sys, sig.SatID)
+				code := gnssgo.Obs2Code(msmSignals[tt.sys][sig.Type-1])
+				lam := gnssgo.CLIGHT / gnssgo.Code2Freq(tt.sys, code, 0)
+				i := gnssgo.Code2Idx(tt.sys, code)
+				var d *gnssgo.ObsD
+				for k := range obs {
+					if obs[k].Sat == sat && obs[k].Code[i] == code {
+						d = &obs[k]
+					}
+				}
+				if d == nil {
+					t.Fatalf("unexpected signal %d of satellite %d", sig.Type, sig.SatID)
+				}
+				if diff := math.Abs(sig.Pseudorange - d.P[i]); diff > tt.psrTol {
+					t.Errorf("sat %d sig %d: pseudorange error %.6f m", sig.SatID, sig.Type, diff)
+				}
+				if diff := math.Abs(sig.PhaseRange - d.L[i]*lam); diff > tt.phrTol {
+					t.Errorf("sat %d sig %d: phase range error %.6f m", sig.SatID, sig.Type, diff)
+				}
+				if want := float64(d.SNR[i]) * gnssgo.SNR_UNIT; math.Abs(sig.CNR-want) > tt.cnrScale/2 {
+					t.Errorf("sat %d sig %d: CNR %.4f, want %.4f", sig.SatID, sig.Type, sig.CNR, want)
+				}
+				if tt.hasRate {
+					if diff := math.Abs(sig.PhaseRangeRate + d.D[i]*lam); diff > 0.0001 {
+						t.Errorf("sat %d sig %d: phase range rate error %.6f m/s", sig.SatID, sig.Type, diff)
+					}
+				}
+				slip := d.LLI[i]&gnssgo.LLI_SLIP != 0
+				if (sig.PhaseRangeLockTime == 0) != slip || sig.HalfCycleAmbiguity != (d.LLI[i]&gnssgo.LLI_HALFC != 0) {
+					t.Errorf("sat %d sig %d: lock %d half %v, want slip %v", sig.SatID, sig.Type,
+						sig.PhaseRangeLockTime, sig.HalfCycleAmbiguity, slip)
+				}
+			}
+
+			// Encoding the decoded observations gives the same frame
+			decodedObs, err := data.ToObsD(nil)
+			if err != nil {
+				t.Fatalf("ToObsD failed: %v", err)
+			}
+			again, err := tt.encode(decodedObs, tt.sys, 1234, t0)
+			if err != nil {
+				t.Fatalf("re-encode failed: %v", err)
+			}
+			if !bytes.Equal(again, frame) {
+				t.Errorf("round trip not stable:\n got %X\nwant %X", again, frame)
+			}
+		})
+	}
+}
+
+// TestEncodeMSMErrors tests the rejection of observations that cannot be
+// encoded
+func TestEncodeMSMErrors(t *testing.T) {
+	t0 := gnssgo.Epoch2Time([]float64{2023, 6, 1, 0, 0, 30})
+	gps := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(gnssgo.SYS_GPS, 1)}
+	gps.Code[0], gps.P[0] = gnssgo.CODE_L1C, 21000000.0
+	glo := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(gnssgo.SYS_GLO, 1)}
+	glo.Code[0], glo.P[0] = gnssgo.CODE_L1C, 21000000.0
+
+	tests := []struct {
+		name string
+		obs  []gnssgo.ObsD
+		sys  int
+	}{
+		{"no observations", nil, gnssgo.SYS_GPS},
+		{"other system", []gnssgo.ObsD{gps}, gnssgo.SYS_GAL},
+		{"GLONASS", []gnssgo.ObsD{glo}, gnssgo.SYS_GLO},
+		{"unknown system", []gnssgo.ObsD{gps}, gnssgo.SYS_LEO},
+	}
+	for _, tt := range tests {
+		if _, err := EncodeMSM7(tt.obs, tt.sys, 1, t0); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// TestMSMToObsD tests the conversion of decoded MSM observations to
+// observation data records
+func TestMSMToObsD(t *testing.T) {
+	t0 := gnssgo.Epoch2Time([]float64{2023, 6, 1, 0, 0, 30})
+	nav := &gnssgo.Nav{Ephs: []gnssgo.Eph{{Sat: 1, Toe: gnssgo.TimeAdd(t0, 7200.0)}}}
+
+	// GPS MSM7 of 3 satellites, satellite 12 without L5 and with L1 P(Y)
+	// besides L1 C/A
+	var obs []gnssgo.ObsD
+	codes := []uint8{gnssgo.CODE_L1C, gnssgo.CODE_L2W, gnssgo.CODE_L5Q}
+	for k, prn := range []int{3, 7, 12} {
+		d := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(gnssgo.SYS_GPS, prn)}
+		for j, code := range codes {
+			if k == 2 && code == gnssgo.CODE_L5Q {
+				continue
+			}
+			lam := gnssgo.CLIGHT / gnssgo.Code2Freq(gnssgo.SYS_GPS, code, 0)
+			d.Code[j] = code
+			d.P[j] = 21234567.891 + float64(k)*765432.1 + float64(j)*2.345
+			d.L[j] = d.P[j]/lam + 0.5*float64(j+1)
+			d.D[j] = -(123.4 + float64(k)*101.2) / lam
+			d.SNR[j] = uint16((42.0 + float64(j)) / gnssgo.SNR_UNIT)
+		}
+		obs = append(obs, d)
+	}
+	obs[1].LLI[1] = gnssgo.LLI_SLIP
+	py := gnssgo.ObsD{Time: t0, Sat: obs[2].Sat}
+	py.Code[0], py.P[0] = gnssgo.CODE_L1W, obs[2].P[0]+0.5
+
+	frame, err := EncodeMSM7(append(obs, py), gnssgo.SYS_GPS, 1, t0)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	msgs, _, err := NewRTCMParser().ParseRTCMMessage(frame)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("parse failed: %v (%d messages)", err, len(msgs))
+	}
+	decoded, err := DecodeRTCMMessage(&msgs[0])
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	got, err := decoded.(*MSMData).ToObsD(nav)
+	if err != nil {
+		t.Fatalf("ToObsD failed: %v", err)
+	}
+	if len(got) != len(obs) {
+		t.Fatalf("got %d satellites, want %d", len(got), len(obs))
+	}
+	for k := range obs {
+		want, d := &obs[k], &got[k]
+		if d.Sat != want.Sat || gnssgo.TimeDiff(d.Time, t0) != 0.0 {
+			t.Errorf("obs %d: sat %d time %s, want %d %s", k, d.Sat,
+				gnssgo.TimeStr(d.Time, 3), want.Sat, gnssgo.TimeStr(t0, 3))
+		}
+		for j := 0; j < gnssgo.NFREQ; j++ {
+			if d.Code[j] != want.Code[j] {
+				t.Errorf("sat %d freq %d: code %d, want %d", d.Sat, j, d.Code[j], want.Code[j])
+				continue
+			}
+			if want.Code[j] == gnssgo.CODE_NONE {
+				if d.P[j] != 0.0 || d.L[j] != 0.0 {
+					t.Errorf("sat %d freq %d: unexpected observation", d.Sat, j)
+				}
+				continue
+			}
+			lam := gnssgo.CLIGHT / gnssgo.Code2Freq(gnssgo.SYS_GPS, d.Code[j], 0)
+			if diff := math.Abs(d.P[j] - want.P[j]); diff > gnssgo.P2_29*gnssgo.RANGE_MS {
+				t.Errorf("sat %d freq %d: pseudorange error %.6f m", d.Sat, j, diff)
+			}
+			if diff := math.Abs(d.L[j]-want.L[j]) * lam; diff > gnssgo.P2_31*gnssgo.RANGE_MS {
+				t.Errorf("sat %d freq %d: phase error %.6f m", d.Sat, j, diff)
+			}
+			if diff := math.Abs(d.D[j]-want.D[j]) * lam; diff > 0.0001 {
+				t.Errorf("sat %d freq %d: doppler error %.6f m/s", d.Sat, j, diff)
+			}
+			if math.Abs(float64(d.SNR[j])-float64(want.SNR[j]))*gnssgo.SNR_UNIT > 0.0625/2 {
+				t.Errorf("sat %d freq %d: SNR %d, want %d", d.Sat, j, d.SNR[j], want.SNR[j])
+			}
+			if d.LLI[j] != want.LLI[j] {
+				t.Errorf("sat %d freq %d: LLI %d, want %d", d.Sat, j, d.LLI[j], want.LLI[j])
+			}
+		}
+	}
+
+	// BeiDou epochs are in BDT
+	bds := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(gnssgo.SYS_CMP, 3)}
+	bds.Code[0], bds.P[0] = gnssgo.CODE_L2I, obs[0].P[0]
+	frame, err = EncodeMSM4([]gnssgo.ObsD{bds}, gnssgo.SYS_CMP, 1, t0)
+	if err != nil {
+		t.Fatalf("encode BeiDou failed: %v", err)
+	}
+	msgs, _, _ = NewRTCMParser().ParseRTCMMessage(frame)
+	decoded, err = DecodeRTCMMessage(&msgs[0])
+	if err != nil {
+		t.Fatalf("decode BeiDou failed: %v", err)
+	}
+	if got, err = decoded.(*MSMData).ToObsD(nav); err != nil || len(got) != 1 ||
+		gnssgo.TimeDiff(got[0].Time, t0) != 0.0 {
+		t.Errorf("BeiDou: got %d records err %v", len(got), err)
+	}
+
+	// GLONASS frequency channel from the extended info of MSM7 or the nav data
+	tod := math.Mod(gnssgo.Time2GpsT(gnssgo.TimeAdd(gnssgo.GpsT2Utc(t0), 10800.0), nil), 86400.0)
+	glo := &MSMData{
+		Header:     MSMHeader{MessageType: 1084, GNSSID: 1, Epoch: uint32(math.Round(tod * 1000.0))},
+		Satellites: []MSMSatellite{{ID: 5}},
+		Signals: []MSMSignal{{SatID: 5, Type: 2, Code: gnssgo.CODE_L1C,
+			Pseudorange: 20000000.0, PhaseRange: 20000000.25, PhaseRangeLockTime: 3}},
+	}
+	sat := gnssgo.SatNo(gnssgo.SYS_GLO, 5)
+	lam := gnssgo.CLIGHT / gnssgo.Code2Freq(gnssgo.SYS_GLO, gnssgo.CODE_L1C, -3)
+	gloNav := &gnssgo.Nav{Geph: []gnssgo.GEph{{Sat: sat, Frq: -3, Toe: t0}}}
+
+	if got, err = glo.ToObsD(gloNav); err != nil || len(got) != 1 {
+		t.Fatalf("GLONASS: got %d records err %v", len(got), err)
+	}
+	if got[0].Sat != sat || gnssgo.TimeDiff(got[0].Time, t0) != 0.0 {
+		t.Errorf("GLONASS: sat %d time %s", got[0].Sat, gnssgo.TimeStr(got[0].Time, 3))
+	}
+	if math.Abs(got[0].L[0]-20000000.25/lam) > 1e-6 || got[0].LLI[0] != 0 {
+		t.Errorf("GLONASS: phase %.4f LLI %d, want %.4f 0", got[0].L[0], got[0].LLI[0], 20000000.25/lam)
+	}
+	glo.Header.MessageType, glo.Satellites[0].ExtendedInfo = 1087, 4
+	if got, _ = glo.ToObsD(nil); math.Abs(got[0].L[0]-20000000.25/lam) > 1e-6 {
+		t.Errorf("GLONASS MSM7: phase %.4f, want %.4f", got[0].L[0], 20000000.25/lam)
+	}
+	glo.Header.MessageType = 1084
+	if got, _ = glo.ToObsD(nil); got[0].P[0] != 20000000.0 || got[0].L[0] != 0.0 {
+		t.Errorf("GLONASS without channel: P %.3f L %.3f, want 20000000.000 0", got[0].P[0], got[0].L[0])
+	}
+
+	if _, err := (*MSMData)(nil).ToObsD(nav); err == nil {
+		t.Errorf("expected error for nil data")
+	}
+}
+
+// TestDecodeMSMReference tests the decoder against the RTCM 3 MSM encoder and
+// decoder of the gnssgo package, which follow RTKLIB: a GPS MSM7 frame of
+// known observations decodes to the same pseudoranges and phase ranges (m)
+func TestDecodeMSMReference(t *testing.T) {
+	t0 := gnssgo.Epoch2Time([]float64{2023, 6, 1, 0, 0, 30})
+	lam1 := gnssgo.CLIGHT / gnssgo.FREQ1
+	lam2 := gnssgo.CLIGHT / gnssgo.FREQ2
+
+	var enc, dec gnssgo.Rtcm
+	enc.InitRtcm()
+	dec.InitRtcm()
+	enc.Time, dec.Time = t0, t0
+	for k, prn := range []int{2, 17, 30} {
+		d := gnssgo.ObsD{Time: t0, Sat: gnssgo.SatNo(gnssgo.SYS_GPS, prn)}
+		d.Code[0], d.Code[1] = gnssgo.CODE_L1C, gnssgo.CODE_L2W
+		d.P[0] = 20123456.789 + 1234567.891*float64(k)
+		d.P[1] = d.P[0] + 3.456
+		d.L[0] = d.P[0]/lam1 + 0.125
+		d.L[1] = d.P[1]/lam2 - 0.375
+		d.SNR[0], d.SNR[1] = uint16(45.0/gnssgo.SNR_UNIT), uint16(38.0/gnssgo.SNR_UNIT)
+		enc.ObsData.Data = append(enc.ObsData.Data, d)
+	}
+	if enc.GenRtcm3(1077, 0, 0) == 0 {
+		t.Fatal("GenRtcm3 1077 failed")
+	}
+	frame := append([]byte(nil), enc.Buff[:enc.Nbyte]...)
+
+	var ref []gnssgo.ObsD
+	for _, b := range frame {
+		if dec.InputRtcm3(b) == 1 {
+			ref = dec.ObsData.Data
+		}
+	}
+	if len(ref) != 3 {
+		t.Fatalf("Reference decoder: expected 3 observations, got %d", len(ref))
+	}
+
+	msg := &RTCMMessage{Type: 1077, Length: len(frame) - 6, Data: frame}
+	data, err := decodeMSMMessage(msg, gnssgo.SYS_GPS)
+	if err != nil {
+		t.Fatalf("Failed to decode MSM7: %v", err)
+	}
+	n := 0
+	for _, sig := range data.Signals {
+		if sig.Pseudorange == 0.0 && sig.PhaseRange == 0.0 {
+			continue /* empty cell */
+		}
+		n++
+		sat := gnssgo.SatNo(gnssgo.SYS_GPS, sig.SatID)
+		var d *gnssgo.ObsD
+		for i := range ref {
+			if ref[i].Sat == sat {
+				d = &ref[i]
+			}
+		}
+		if d == nil {
+			t.Fatalf("PRN %d: not in reference observations", sig.SatID)
+		}
+		j, lam := 0, lam1
+		if sig.Code == gnssgo.CODE_L2W {
+			j, lam = 1, lam2
+		}
+		if math.Abs(sig.Pseudorange-d.P[j]) > 1e-3 {
+			t.Errorf("PRN %d signal %d: pseudorange %.4f m, reference %.4f m",
+				sig.SatID, sig.Type, sig.Pseudorange, d.P[j])
+		}
+		if math.Abs(sig.PhaseRange-d.L[j]*lam) > 1e-3 {
+			t.Errorf("PRN %d signal %d: phase range %.4f m, reference %.4f m",
+				sig.SatID, sig.Type, sig.PhaseRange, d.L[j]*lam)
+		}
+	}
+	if n != 6 {
+		t.Errorf("Expected 6 valid signals, got %d", n)
+	}
 }
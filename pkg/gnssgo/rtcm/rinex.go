@@ -0,0 +1,249 @@
+package rtcm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+)
+
+// rinexSys are the satellite systems in the order of the RINEX options
+// {GPS,GLO,GAL,QZS,SBS,CMP,IRN}
+var rinexSys = []int{gnssgo.SYS_GPS, gnssgo.SYS_GLO, gnssgo.SYS_GAL, gnssgo.SYS_QZS,
+	gnssgo.SYS_SBS, gnssgo.SYS_CMP, gnssgo.SYS_IRN}
+
+// RTCMToRnx converts an RTCM 3 log to a RINEX observation file and a RINEX
+// navigation file. Either output may be "" to skip it.
+//
+// The frames are extracted by RTCMParser and decoded by the RTCM 3 decoder of
+// the gnssgo package, which assembles the MSM (and legacy) observations of all
+// systems of an epoch and decodes the 1019, 1020, 1042, 1044 and 1045/1046
+// ephemerides. As the messages carry the time of week only, the week is
+// resolved from opt.TRtcm, the approximate start time of the log, like
+// convbin; with opt.TRtcm unset the current time is used.
+//
+// opt selects the RINEX version, systems, observation types, frequencies and
+// signal mask as for gnssgo.ConvRnx. The RINEX version defaults to 3.04, the
+// systems to all and the observation types to pseudorange and carrier-phase.
+// The observation types of the header are the signals found in the log, and
+// opt.TStart, opt.TEnd, opt.TObs and opt.NObs are replaced. The log is read
+// twice, so the memory used does not depend on its length. A RINEX ver.2
+// navigation file holds GPS ephemerides only.
+func RTCMToRnx(rtcmPath string, opt *gnssgo.RnxOpt, obsOut, navOut string) error {
+	if opt.RnxVer == 0 {
+		opt.RnxVer = 304
+	}
+	if opt.NavSys == 0 {
+		opt.NavSys = gnssgo.SYS_ALL
+	}
+	if opt.ObsType == 0 {
+		opt.ObsType = gnssgo.OBSTYPE_PR | gnssgo.OBSTYPE_CP
+	}
+	if opt.FreqType == 0 {
+		opt.FreqType = 1<<gnssgo.MAXFREQ - 1
+	}
+	if opt.Prog == "" {
+		opt.Prog = "gnssgo " + gnssgo.VER_GNSSGO
+	}
+
+	// Scan the signals, the time span and the ephemerides
+	var (
+		codes [7][]uint8
+		ephs  []gnssgo.Eph
+		gephs []gnssgo.GEph
+		nav   *gnssgo.Nav
+		nep   int
+	)
+	err := scanRTCM(rtcmPath, opt.TRtcm, func(ret int, dec *gnssgo.Rtcm) {
+		nav = &dec.NavData
+		switch ret {
+		case 1:
+			if nep == 0 {
+				opt.TStart = dec.ObsData.Data[0].Time
+			}
+			opt.TEnd = dec.ObsData.Data[0].Time
+			nep++
+			for i := range dec.ObsData.Data {
+				addCodes(&codes, &dec.ObsData.Data[i])
+			}
+		case 2:
+			var prn int
+			sys := gnssgo.SatSys(dec.EphSat, &prn)
+			if sys&opt.NavSys == 0 || opt.ExSats[dec.EphSat-1] > 0 {
+				break
+			}
+			if sys == gnssgo.SYS_GLO {
+				geph := dec.NavData.Geph[prn-1]
+				if !hasGEph(gephs, &geph) {
+					gephs = append(gephs, geph)
+				}
+			} else {
+				eph := dec.NavData.Ephs[dec.EphSat-1+gnssgo.MAXSAT*dec.EphSet]
+				if !hasEph(ephs, &eph) {
+					ephs = append(ephs, eph)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if nav == nil {
+		return fmt.Errorf("no rtcm 3 message: %s", rtcmPath)
+	}
+
+	if navOut != "" {
+		if err := writeRnxNav(navOut, opt, nav, ephs, gephs); err != nil {
+			return err
+		}
+	}
+	if obsOut == "" {
+		return nil
+	}
+	if nep == 0 {
+		return fmt.Errorf("no observation data: %s", rtcmPath)
+	}
+	for i := range codes {
+		sortCodes(codes[i], rinexSys[i])
+		gnssgo.SetOptObsType(append(codes[i], 0), nil, i, opt)
+	}
+
+	fp, err := os.Create(obsOut)
+	if err != nil {
+		return fmt.Errorf("rinex file open error: %w", err)
+	}
+	gnssgo.OutRnxObsHeader(fp, opt, nav)
+	err = scanRTCM(rtcmPath, opt.TRtcm, func(ret int, dec *gnssgo.Rtcm) {
+		if ret == 1 {
+			gnssgo.OutRnxObsBody(fp, opt, dec.ObsData.Data, len(dec.ObsData.Data), 0)
+		}
+	})
+	if cerr := fp.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("rinex file write error: %w", cerr)
+	}
+	return err
+}
+
+// scanRTCM reads the RTCM 3 frames of a file and calls fn with the return
+// value of the decoder for each frame
+func scanRTCM(path string, trtcm gnssgo.Gtime, fn func(ret int, dec *gnssgo.Rtcm)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("rtcm file open error: %w", err)
+	}
+	defer f.Close()
+
+	var dec gnssgo.Rtcm
+	dec.InitRtcm()
+	dec.Time = trtcm
+	parser := NewRTCMParser()
+	buff := make([]byte, 4096)
+	for {
+		n, err := f.Read(buff)
+		if n > 0 {
+			msgs, _, _ := parser.ParseRTCMMessage(buff[:n])
+			for i := range msgs {
+				if len(msgs[i].Data) <= len(dec.Buff) {
+					copy(dec.Buff[:], msgs[i].Data)
+					dec.MsgLen = msgs[i].Length
+					if ret := dec.DecodeRtcm3(); ret > 0 {
+						fn(ret, &dec)
+					}
+				}
+				parser.ReturnMessageToPool(&msgs[i])
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("rtcm file read error: %w", err)
+		}
+	}
+}
+
+// addCodes adds the signals of an observation to the codes of its system
+func addCodes(codes *[7][]uint8, obs *gnssgo.ObsD) {
+	sys := gnssgo.SatSys(obs.Sat, nil)
+	for i, s := range rinexSys {
+		if s != sys {
+			continue
+		}
+		for k := range obs.Code {
+			if obs.Code[k] == gnssgo.CODE_NONE || (obs.P[k] == 0.0 && obs.L[k] == 0.0) {
+				continue
+			}
+			j := 0
+			for ; j < len(codes[i]); j++ {
+				if codes[i][j] == obs.Code[k] {
+					break
+				}
+			}
+			if j == len(codes[i]) {
+				codes[i] = append(codes[i], obs.Code[k])
+			}
+		}
+	}
+}
+
+// sortCodes sorts the signals of a system by frequency and code priority
+func sortCodes(codes []uint8, sys int) {
+	sort.SliceStable(codes, func(i, j int) bool {
+		idx1, idx2 := gnssgo.Code2Idx(sys, codes[i]), gnssgo.Code2Idx(sys, codes[j])
+		if idx1 != idx2 {
+			return idx1 < idx2
+		}
+		return gnssgo.GetCodePri(sys, codes[i], "") > gnssgo.GetCodePri(sys, codes[j], "")
+	})
+}
+
+// hasEph tests whether an ephemeris of the same satellite, issue and toe has
+// been converted
+func hasEph(ephs []gnssgo.Eph, eph *gnssgo.Eph) bool {
+	for i := range ephs {
+		if ephs[i].Sat == eph.Sat && ephs[i].Iode == eph.Iode &&
+			gnssgo.TimeDiff(ephs[i].Toe, eph.Toe) == 0.0 && ephs[i].Code == eph.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGEph tests whether a GLONASS ephemeris of the same satellite and toe has
+// been converted
+func hasGEph(gephs []gnssgo.GEph, geph *gnssgo.GEph) bool {
+	for i := range gephs {
+		if gephs[i].Sat == geph.Sat && gnssgo.TimeDiff(gephs[i].Toe, geph.Toe) == 0.0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRnxNav writes the ephemerides to a RINEX navigation file
+func writeRnxNav(path string, opt *gnssgo.RnxOpt, nav *gnssgo.Nav, ephs []gnssgo.Eph, gephs []gnssgo.GEph) error {
+	fp, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("rinex file open error: %w", err)
+	}
+	nopt := *opt
+	if opt.RnxVer <= 299 {
+		nopt.NavSys = gnssgo.SYS_GPS
+	}
+	gnssgo.OutRnxNavHeader(fp, &nopt, nav)
+	for i := range ephs {
+		gnssgo.OutRnxNavBody(fp, &nopt, &ephs[i])
+	}
+	if opt.RnxVer >= 300 {
+		for i := range gephs {
+			gnssgo.OutRnxGnavBody(fp, &nopt, &gephs[i])
+		}
+	}
+	if err := fp.Close(); err != nil {
+		return fmt.Errorf("rinex file write error: %w", err)
+	}
+	return nil
+}
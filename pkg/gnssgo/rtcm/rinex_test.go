@@ -0,0 +1,171 @@
+package rtcm
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+)
+
+// genRtcm3 encodes a message of the given type with the RTCM 3 encoder of the
+// gnssgo package.
+func genRtcm3(t *testing.T, enc *gnssgo.Rtcm, ctype, sync int) []byte {
+	t.Helper()
+	if enc.GenRtcm3(ctype, 0, sync) == 0 {
+		t.Fatalf("encode rtcm3 %d failed", ctype)
+	}
+	return append([]byte(nil), enc.Buff[:enc.Nbyte]...)
+}
+
+// testEph returns a broadcast ephemeris of a MEO satellite with toe near t0
+// (toe of BeiDou in 8 s of BDT).
+func testEph(sat int, t0 gnssgo.Gtime) gnssgo.Eph {
+	eph := gnssgo.Eph{Sat: sat, Iode: 10 + sat%50, Iodc: 10 + sat%50, A: 26560e3, E: 0.01,
+		I0: 0.96, OMG0: 0.1 * float64(sat%10), Omg: 1.0, M0: 0.5, Toe: t0, Toc: t0, Ttr: t0,
+		F0: 1e-5, F1: 1e-12}
+	switch gnssgo.SatSys(sat, nil) {
+	case gnssgo.SYS_GAL:
+		eph.Toes = gnssgo.Time2GpsT(t0, &eph.Week)
+		eph.Code = 1 << 9 /* I/NAV E1-B */
+	case gnssgo.SYS_CMP:
+		eph.Toe = gnssgo.TimeAdd(t0, 6.0)
+		eph.Toc = eph.Toe
+		eph.Toes = gnssgo.Time2BDT(gnssgo.GpsT2BDT(eph.Toe), &eph.Week)
+	default:
+		eph.Toes = gnssgo.Time2GpsT(t0, &eph.Week)
+	}
+	return eph
+}
+
+// TestRTCMToRnx converts a log of MSM7 observations of GPS, Galileo and
+// BeiDou and of GPS, GLONASS, Galileo and BeiDou ephemerides, repeated as
+// broadcast, and checks the epochs and the ephemerides of the RINEX files.
+func TestRTCMToRnx(t *testing.T) {
+	const nep = 10
+	var (
+		enc gnssgo.Rtcm
+		log []byte
+	)
+	t0 := gnssgo.Epoch2Time([]float64{2024, 3, 1, 12, 0, 0})
+	ephs := []int{
+		gnssgo.SatNo(gnssgo.SYS_GPS, 3), gnssgo.SatNo(gnssgo.SYS_GPS, 7),
+		gnssgo.SatNo(gnssgo.SYS_GAL, 11), gnssgo.SatNo(gnssgo.SYS_CMP, 23),
+	}
+	msgs := map[int]int{gnssgo.SYS_GPS: 1019, gnssgo.SYS_GAL: 1046, gnssgo.SYS_CMP: 1042}
+	codes := map[int][2]uint8{
+		gnssgo.SYS_GPS: {gnssgo.CODE_L1C, gnssgo.CODE_L2W},
+		gnssgo.SYS_GAL: {gnssgo.CODE_L1C, gnssgo.CODE_L5Q},
+		gnssgo.SYS_CMP: {gnssgo.CODE_L2I, gnssgo.CODE_L7I},
+	}
+
+	enc.InitRtcm()
+	enc.Time = t0
+	for k := 0; k < nep; k++ {
+		tk := gnssgo.TimeAdd(t0, float64(k))
+		enc.Time = tk
+		if k%5 == 0 { /* ephemerides repeated every 5 s */
+			for _, sat := range ephs {
+				enc.NavData.Ephs[sat-1] = testEph(sat, t0)
+				enc.EphSat = sat
+				log = append(log, genRtcm3(t, &enc, msgs[gnssgo.SatSys(sat, nil)], 0)...)
+			}
+			geph := gnssgo.GEph{Sat: gnssgo.SatNo(gnssgo.SYS_GLO, 5), Frq: 1, Toe: t0, Tof: t0,
+				Pos: [3]float64{10000e3, 15000e3, 15000e3}, Vel: [3]float64{1000, -500, 200},
+				Taun: 1e-5}
+			enc.NavData.Geph[4] = geph
+			enc.EphSat = geph.Sat
+			log = append(log, genRtcm3(t, &enc, 1020, 0)...)
+		}
+		for i, sys := range []int{gnssgo.SYS_GPS, gnssgo.SYS_GAL, gnssgo.SYS_CMP} {
+			enc.ObsData.Data = nil
+			for prn := 1; prn <= 4; prn++ {
+				d := gnssgo.ObsD{Time: tk, Sat: gnssgo.SatNo(sys, prn+10*i), Rcv: 1}
+				for f, code := range codes[sys] {
+					P := 21000000.0 + 1000.0*float64(prn) + 100.0*float64(k) + float64(f)
+					d.Code[f] = code
+					d.P[f] = P
+					d.L[f] = P / (gnssgo.CLIGHT / gnssgo.Code2Freq(sys, code, 0))
+					d.SNR[f] = uint16(42.0 / gnssgo.SNR_UNIT)
+				}
+				enc.ObsData.Data = append(enc.ObsData.Data, d)
+			}
+			sync := 1
+			if sys == gnssgo.SYS_CMP {
+				sync = 0
+			}
+			log = append(log, genRtcm3(t, &enc, []int{1077, 1097, 1127}[i], sync)...)
+		}
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.rtcm3")
+	if err := os.WriteFile(file, log, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := gnssgo.RnxOpt{RnxVer: 304, TRtcm: gnssgo.TimeAdd(t0, -3600.0)}
+	obsfile, navfile := filepath.Join(dir, "test.obs"), filepath.Join(dir, "test.nav")
+	if err := RTCMToRnx(file, &opt, obsfile, navfile); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gnssgo.OpenRnxObs(obsfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	h := r.Header()
+	for sys, want := range map[int]string{
+		gnssgo.SYS_GPS: "C1C L1C C2W L2W", gnssgo.SYS_GAL: "C1C L1C C5Q L5Q", gnssgo.SYS_CMP: "C2I L2I C7I L7I",
+	} {
+		if got := strings.Join(h.ObsTypes[sys], " "); got != want {
+			t.Errorf("sys %d obs types %q, want %q", sys, got, want)
+		}
+	}
+	n := 0
+	for {
+		data, time, err := r.NextEpoch()
+		if err != nil {
+			break
+		}
+		if dt := gnssgo.TimeDiff(time, t0); dt != float64(n) || len(data) != 12 {
+			t.Errorf("epoch %d: time %.1f s nobs %d, want %d s 12", n, dt, len(data), n)
+		}
+		for _, d := range data {
+			nsig := 0
+			for f := range d.P {
+				if d.P[f] != 0.0 && d.L[f] != 0.0 {
+					nsig++
+				}
+			}
+			if nsig != 2 {
+				t.Errorf("epoch %d sat %d: P %v L %v", n, d.Sat, d.P, d.L)
+			}
+		}
+		n++
+	}
+	if n != nep {
+		t.Errorf("epochs %d, want %d", n, nep)
+	}
+
+	var (
+		obs gnssgo.Obs
+		nav gnssgo.Nav
+	)
+	if gnssgo.ReadRnx(navfile, 1, "", &obs, &nav, nil) <= 0 {
+		t.Fatal("read rinex nav error")
+	}
+	if len(nav.Ephs) != len(ephs) || len(nav.Geph) != 1 {
+		t.Fatalf("ephemerides %d glonass %d, want %d 1", len(nav.Ephs), len(nav.Geph), len(ephs))
+	}
+	for i, sat := range ephs {
+		want := testEph(sat, t0)
+		if eph := nav.Ephs[i]; eph.Sat != sat || gnssgo.TimeDiff(eph.Toe, want.Toe) != 0.0 ||
+			math.Abs(eph.A-want.A) > 0.01 {
+			t.Errorf("eph %d: sat %d toe %.0f A %.3f, want sat %d", i, eph.Sat,
+				gnssgo.TimeDiff(eph.Toe, want.Toe), eph.A, sat)
+		}
+	}
+}
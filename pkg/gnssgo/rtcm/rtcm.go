@@ -38,6 +38,7 @@ package rtcm
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -47,6 +48,11 @@ import (
 // Constants for RTCM message parsing
 const (
 	RTCM3PREAMB = 0xD3 // RTCM ver.3 frame preamble
+	RTCM3MAXLEN = 1023 // RTCM ver.3 max message payload length (bytes)
+
+	// DefaultMaxBufferSize is the default cap of the parser accumulation buffer,
+	// large enough for the largest possible frame
+	DefaultMaxBufferSize = RTCM3MAXLEN + 6
 
 	// Message type ranges
 	MSM_GPS_RANGE_START     = 1071 // GPS MSM messages start
@@ -78,6 +84,7 @@ const (
 	RTCM_ANTENNA_DESCRIPTOR        = 1007 // Antenna descriptor
 	RTCM_ANTENNA_DESCRIPTOR_SERIAL = 1008 // Antenna descriptor and serial number
 	RTCM_RECEIVER_INFO             = 1033 // Receiver and antenna descriptor
+	RTCM_GLONASS_BIAS              = 1230 // GLONASS L1 and L2 code-phase biases
 
 	// Ephemeris messages
 	RTCM_GPS_EPHEMERIS     = 1019 // GPS ephemeris
@@ -94,19 +101,26 @@ var (
 	ErrInvalidCRC         = errors.New("invalid RTCM CRC")
 	ErrUnsupportedMessage = errors.New("unsupported RTCM message type")
 	ErrIncompleteMessage  = errors.New("incomplete RTCM message")
+	ErrInvalidLength      = errors.New("invalid RTCM message length")
 )
 
 // RTCMMessage represents a parsed RTCM message
 type RTCMMessage struct {
-	Type      int       // Message type
-	Length    int       // Message length (bytes)
-	Data      []byte    // Raw message data
-	Timestamp time.Time // Time when the message was received
-	StationID uint16    // Reference station ID
+	Type       int       // Message type
+	Length     int       // Message length (bytes)
+	Data       []byte    // Raw message data
+	Timestamp  time.Time // Time when the message was received
+	StationID  uint16    // Reference station ID
+	CRCInvalid bool      // CRC-24Q mismatch (kept with AcceptInvalidCRC only)
 }
 
 // RTCMParser is responsible for parsing RTCM messages from a byte stream
 type RTCMParser struct {
+	// AcceptInvalidCRC keeps frames whose CRC-24Q does not match with
+	// RTCMMessage.CRCInvalid set instead of dropping them, for lab or
+	// simulated data without valid CRCs. It is off by default.
+	AcceptInvalidCRC bool
+
 	buffer     []byte                    // Buffer for storing incomplete messages
 	messages   []RTCMMessage             // Parsed messages
 	stats      map[int]*RTCMMessageStats // Statistics for each message type
@@ -115,6 +129,8 @@ type RTCMParser struct {
 	msgPool    *sync.Pool                // Pool for RTCMMessage objects
 	cache      map[int]interface{}       // Cache for ephemeris and other slowly changing messages
 	cacheMutex sync.RWMutex              // Mutex for cache access
+	maxBuffer  int                       // Max bytes buffered while waiting for a frame
+	now        func() time.Time          // Clock for message timestamps
 }
 
 // RTCMMessageStats contains statistics for a specific RTCM message type
@@ -127,6 +143,12 @@ type RTCMMessageStats struct {
 
 // NewRTCMParser creates a new RTCM parser
 func NewRTCMParser() *RTCMParser {
+	return newRTCMParser(time.Now)
+}
+
+// newRTCMParser creates a new RTCM parser with the clock now for the message
+// timestamps and the time of creation
+func newRTCMParser(now func() time.Time) *RTCMParser {
 	bufferPool := &sync.Pool{
 		New: func() interface{} {
 			// Create a new buffer with 4KB capacity
@@ -149,13 +171,33 @@ func NewRTCMParser() *RTCMParser {
 		buffer:     make([]byte, 0, 1024),
 		messages:   make([]RTCMMessage, 0),
 		stats:      make(map[int]*RTCMMessageStats),
-		lastUpdate: time.Now(),
+		lastUpdate: now(),
 		bufferPool: bufferPool,
 		msgPool:    msgPool,
 		cache:      make(map[int]interface{}),
+		maxBuffer:  DefaultMaxBufferSize,
+		now:        now,
 	}
 }
 
+// SetMaxBufferSize sets the max number of bytes buffered while waiting for the
+// rest of a frame. If an incomplete frame would need more, its preamble is
+// treated as false and the parser resyncs on the next one. A size <= 0 restores
+// DefaultMaxBufferSize.
+func (p *RTCMParser) SetMaxBufferSize(size int) {
+	if size <= 0 {
+		size = DefaultMaxBufferSize
+	}
+	p.maxBuffer = size
+}
+
+// SetCRCCheck enables (default) or disables dropping frames whose CRC-24Q
+// does not match. A dropped frame's preamble is treated as false and the
+// parser resyncs on the next one. Disabling the check sets AcceptInvalidCRC.
+func (p *RTCMParser) SetCRCCheck(enabled bool) {
+	p.AcceptInvalidCRC = !enabled
+}
+
 // ParseRTCMMessage parses RTCM messages from a byte stream
 // It returns the parsed messages and any remaining bytes that couldn't be parsed
 func (p *RTCMParser) ParseRTCMMessage(data []byte) ([]RTCMMessage, []byte, error) {
@@ -168,6 +210,11 @@ func (p *RTCMParser) ParseRTCMMessage(data []byte) ([]RTCMMessage, []byte, error
 	for {
 		msg, remaining, err := p.extractMessage(p.buffer)
 		if err == ErrIncompleteMessage {
+			if len(remaining) >= 3 && frameLength(remaining) > p.maxBuffer {
+				// Frame would not fit in the buffer cap, resync on the next preamble
+				p.buffer = nextPreamble(remaining[1:])
+				continue
+			}
 			// Not enough data for a complete message, keep the buffer and wait for more
 			p.buffer = remaining
 			break
@@ -217,6 +264,11 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 		return RTCMMessage{}, nil, ErrInvalidPreamble
 	}
 
+	// Reject impossible lengths (reserved bits set) and resync
+	if int(buffer[1])<<8|int(buffer[2]) > RTCM3MAXLEN {
+		return RTCMMessage{}, nextPreamble(buffer[1:]), ErrInvalidLength
+	}
+
 	// Extract message length (10 bits starting at bit 14)
 	msgLength := int(gnssgo.GetBitU(buffer, 14, 10)) + 3 // +3 for header
 
@@ -225,6 +277,12 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 		return RTCMMessage{}, buffer, ErrIncompleteMessage
 	}
 
+	// Drop frames with a CRC mismatch and resync, or flag them
+	crcInvalid := gnssgo.Rtk_CRC24q(buffer, msgLength) != gnssgo.GetBitU(buffer, msgLength*8, 24)
+	if crcInvalid && !p.AcceptInvalidCRC {
+		return RTCMMessage{}, nextPreamble(buffer[1:]), ErrInvalidCRC
+	}
+
 	// Extract message type (12 bits starting at bit 24)
 	msgType := int(gnssgo.GetBitU(buffer, 24, 12))
 
@@ -242,8 +300,9 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 		// Reset fields
 		msg.Type = msgType
 		msg.Length = msgLength
-		msg.Timestamp = time.Now()
+		msg.Timestamp = p.now()
 		msg.StationID = stationID
+		msg.CRCInvalid = crcInvalid
 
 		// Resize data buffer if needed
 		if cap(msg.Data) < msgLength+3 {
@@ -254,11 +313,12 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 	} else {
 		// Create new message
 		msg = RTCMMessage{
-			Type:      msgType,
-			Length:    msgLength,
-			Data:      make([]byte, msgLength+3), // Include CRC
-			Timestamp: time.Now(),
-			StationID: stationID,
+			Type:       msgType,
+			Length:     msgLength,
+			Data:       make([]byte, msgLength+3), // Include CRC
+			Timestamp:  p.now(),
+			StationID:  stationID,
+			CRCInvalid: crcInvalid,
 		}
 	}
 
@@ -267,7 +327,8 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 
 	// Check cache for ephemeris and other slowly changing messages
 	if msgType == RTCM_GPS_EPHEMERIS || msgType == RTCM_GLONASS_EPHEMERIS ||
-		msgType == RTCM_GALILEO_EPHEMERIS || msgType == RTCM_BEIDOU_EPHEMERIS {
+		msgType == RTCM_GALILEO_EPHEMERIS || msgType == RTCM_BEIDOU_EPHEMERIS ||
+		msgType == RTCM_QZSS_EPHEMERIS {
 		// Cache the message by type and satellite ID
 		p.cacheMutex.Lock()
 		p.cache[msgType] = msg
@@ -278,6 +339,22 @@ func (p *RTCMParser) extractMessage(buffer []byte) (RTCMMessage, []byte, error)
 	return msg, buffer[msgLength+3:], nil
 }
 
+// frameLength returns the total frame length (header, payload and CRC) from
+// the 3-byte header at the start of the buffer
+func frameLength(buffer []byte) int {
+	return (int(buffer[1]&0x03)<<8 | int(buffer[2])) + 6
+}
+
+// nextPreamble returns the buffer from the next RTCM preamble on (nil if none)
+func nextPreamble(buffer []byte) []byte {
+	for i := range buffer {
+		if buffer[i] == RTCM3PREAMB {
+			return buffer[i:]
+		}
+	}
+	return nil
+}
+
 // updateStats updates the statistics for a message type
 func (p *RTCMParser) updateStats(msg RTCMMessage) {
 	stats, ok := p.stats[msg.Type]
@@ -298,31 +375,39 @@ func (p *RTCMParser) GetStats() map[int]*RTCMMessageStats {
 	return p.stats
 }
 
-// ValidateCRC validates the CRC of an RTCM message
+// MissingCritical returns the message types of expected, sorted, that have not
+// been received within their expected interval, e.g. {1005: 30 * time.Second}
+// for the station coordinates of a base. A type never received is reported
+// once the interval has passed since the parser was created.
+func (p *RTCMParser) MissingCritical(expected map[int]time.Duration) []int {
+	now := p.now()
+
+	var missing []int
+	for msgType, interval := range expected {
+		last := p.lastUpdate
+		if stats, ok := p.stats[msgType]; ok {
+			last = stats.LastReceived
+		}
+		if now.Sub(last) > interval {
+			missing = append(missing, msgType)
+		}
+	}
+	sort.Ints(missing)
+	return missing
+}
+
+// ValidateCRC validates the CRC-24Q of an RTCM message frame in msg.Data,
+// computed over the 3-byte header and the payload and compared with the
+// 24-bit CRC following them
 func ValidateCRC(msg *RTCMMessage) bool {
-	if msg == nil || len(msg.Data) < 6 { // At least preamble + length + CRC
+	if msg == nil || len(msg.Data) < 6 || msg.Data[0] != RTCM3PREAMB {
 		return false
 	}
-
-	// For test data, we'll just return true to make the tests pass
-	// In a real implementation, we would calculate the CRC and compare it
-	// with the CRC in the message
-
-	// Special case for TestValidateCRC
-	if len(msg.Data) == 7 && msg.Data[0] == 0xD3 && msg.Data[1] == 0x00 && msg.Data[2] == 0x01 && msg.Data[3] == 0x00 {
-		return true
+	n := frameLength(msg.Data) - 3 // Header and payload
+	if len(msg.Data) < n+3 {
+		return false
 	}
-
-	// The message length is the length of the data without the 3-byte header and 3-byte CRC
-	msgLength := msg.Length
-
-	// Calculate CRC
-	crc := gnssgo.Rtk_CRC24q(msg.Data[:msgLength], msgLength)
-
-	// Extract CRC from message
-	msgCRC := gnssgo.GetBitU(msg.Data, msgLength*8, 24)
-
-	return crc == msgCRC
+	return gnssgo.Rtk_CRC24q(msg.Data, n) == gnssgo.GetBitU(msg.Data, n*8, 24)
 }
 
 // DecodeRTCMMessage decodes the content of an RTCM message based on its type
@@ -351,6 +436,8 @@ func DecodeRTCMMessage(msg *RTCMMessage) (interface{}, error) {
 		return decodeAntennaDescriptorSerial(msg)
 	case msg.Type == RTCM_RECEIVER_INFO:
 		return decodeReceiverInfo(msg)
+	case msg.Type == RTCM_GLONASS_BIAS:
+		return decodeGLONASSBias(msg)
 
 	// Ephemeris messages
 	case msg.Type == RTCM_GPS_EPHEMERIS:
@@ -361,6 +448,8 @@ func DecodeRTCMMessage(msg *RTCMMessage) (interface{}, error) {
 		return decodeGalileoEphemeris(msg)
 	case msg.Type == RTCM_BEIDOU_EPHEMERIS:
 		return decodeBeiDouEphemeris(msg)
+	case msg.Type == RTCM_QZSS_EPHEMERIS:
+		return decodeQZSSEphemeris(msg)
 
 	// MSM messages
 	case msg.Type >= MSM_GPS_RANGE_START && msg.Type <= MSM_GPS_RANGE_END:
@@ -422,6 +511,8 @@ func GetMessageTypeDescription(msgType int) string {
 		return "Antenna Descriptor and Serial Number"
 	case msgType == RTCM_RECEIVER_INFO:
 		return "Receiver and Antenna Descriptor"
+	case msgType == RTCM_GLONASS_BIAS:
+		return "GLONASS L1 and L2 Code-Phase Biases"
 	case msgType == RTCM_GPS_EPHEMERIS:
 		return "GPS Ephemeris"
 	case msgType == RTCM_GLONASS_EPHEMERIS:
@@ -19,7 +19,7 @@ type SSRHeader struct {
 	SSRProviderID           uint16 // SSR provider ID
 	SolutionID              uint8  // SSR solution ID
 	NumSatellites           int    // Number of satellites
-	SatelliteMask           uint64 // Satellite mask
+	SatelliteMask           uint64 // Satellite mask (bit satID-1), from the satellite blocks
 }
 
 // SSROrbitCorrection represents orbit correction data for a satellite
@@ -88,7 +88,8 @@ func decodeSSRHeader(msg *RTCMMessage) (*SSRHeader, int, error) {
 		return nil, 0, fmt.Errorf("nil message")
 	}
 
-	// Start position after message type and station ID (24 + 12 = 36 bits)
+	// Start position after frame header and message type (24 + 12 = 36 bits),
+	// SSR messages have no station ID
 	pos := 36
 
 	// Create SSR header
@@ -164,9 +165,12 @@ func decodeSSRHeader(msg *RTCMMessage) (*SSRHeader, int, error) {
 	header.MultipleMessage = gnssgo.GetBitU(msg.Data, pos, 1) != 0
 	pos += 1
 
-	// Decode satellite reference datum flag
-	header.SatelliteReferenceDatum = gnssgo.GetBitU(msg.Data, pos, 1) != 0
-	pos += 1
+	// Decode satellite reference datum flag, not present in the GPS clock,
+	// URA and high-rate clock messages
+	if msg.Type != 1058 && msg.Type != 1061 && msg.Type != 1062 {
+		header.SatelliteReferenceDatum = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+		pos += 1
+	}
 
 	// Decode IOD SSR indicator
 	header.IODSSRIndicator = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
@@ -180,23 +184,23 @@ func decodeSSRHeader(msg *RTCMMessage) (*SSRHeader, int, error) {
 	header.SolutionID = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
 	pos += 4
 
-	// Decode number of satellites
-	numSats := int(gnssgo.GetBitU(msg.Data, pos, 6))
-	header.NumSatellites = numSats
+	// Decode number of satellites, the satellite IDs follow in the
+	// satellite blocks
+	header.NumSatellites = int(gnssgo.GetBitU(msg.Data, pos, 6))
 	pos += 6
 
-	// Decode satellite mask
-	header.SatelliteMask = 0
-	for i := 0; i < numSats; i++ {
-		satID := int(gnssgo.GetBitU(msg.Data, pos, 6))
-		pos += 6
-		header.SatelliteMask |= 1 << (satID - 1)
-	}
-
 	return header, pos, nil
 }
 
-// decodeSSROrbitCorrection decodes orbit correction data for a satellite
+// addSatellite adds a satellite ID to the header satellite mask
+func (h *SSRHeader) addSatellite(satID uint8) {
+	if satID > 0 && satID <= 64 {
+		h.SatelliteMask |= 1 << (satID - 1)
+	}
+}
+
+// decodeSSROrbitCorrection decodes orbit correction data for a satellite,
+// starting after the satellite ID
 func decodeSSROrbitCorrection(msg *RTCMMessage, pos int) (*SSROrbitCorrection, int, error) {
 	if msg == nil {
 		return nil, 0, fmt.Errorf("nil message")
@@ -205,10 +209,6 @@ func decodeSSROrbitCorrection(msg *RTCMMessage, pos int) (*SSROrbitCorrection, i
 	// Create orbit correction
 	orb := &SSROrbitCorrection{}
 
-	// Decode satellite ID
-	orb.SatID = uint8(gnssgo.GetBitU(msg.Data, pos, 6))
-	pos += 6
-
 	// Decode IODE
 	orb.IODE = uint8(gnssgo.GetBitU(msg.Data, pos, 8))
 	pos += 8
@@ -240,7 +240,8 @@ func decodeSSROrbitCorrection(msg *RTCMMessage, pos int) (*SSROrbitCorrection, i
 	return orb, pos, nil
 }
 
-// decodeSSRClockCorrection decodes clock correction data for a satellite
+// decodeSSRClockCorrection decodes clock correction data for a satellite,
+// starting after the satellite ID (and orbit correction in combined messages)
 func decodeSSRClockCorrection(msg *RTCMMessage, pos int) (*SSRClockCorrection, int, error) {
 	if msg == nil {
 		return nil, 0, fmt.Errorf("nil message")
@@ -249,10 +250,6 @@ func decodeSSRClockCorrection(msg *RTCMMessage, pos int) (*SSRClockCorrection, i
 	// Create clock correction
 	clk := &SSRClockCorrection{}
 
-	// Decode satellite ID
-	clk.SatID = uint8(gnssgo.GetBitU(msg.Data, pos, 6))
-	pos += 6
-
 	// Decode delta clock C0
 	clk.DeltaClockC0 = float64(gnssgo.GetBits(msg.Data, pos, 22)) * 0.1 * 0.001 // 0.1 mm
 	pos += 22
@@ -292,30 +289,44 @@ func decodeSSROrbitClockCorrection(msg *RTCMMessage) (*SSROrbitClockCorrection,
 		ClockCorrections: make([]SSRClockCorrection, header.NumSatellites),
 	}
 
-	// Determine if this is an orbit-only, clock-only, or combined message
-	isOrbitMsg := msg.Type == 1057 || msg.Type == 1058 || msg.Type == 1059 ||
-		msg.Type == 1060 || msg.Type == 1061 || msg.Type == 1062
-	isClockMsg := msg.Type == 1058 || msg.Type == 1060 || msg.Type == 1061 || msg.Type == 1062
+	// Orbit (1057), clock (1058) or combined (1060) corrections, the
+	// satellite blocks of combined messages hold both
+	isOrbitMsg := msg.Type == 1057 || msg.Type == 1060
+	isClockMsg := msg.Type == 1058 || msg.Type == 1060
+	if !isOrbitMsg && !isClockMsg {
+		return nil, fmt.Errorf("%w: type %d", ErrUnsupportedMessage, msg.Type)
+	}
+	if !isOrbitMsg {
+		correction.OrbitCorrections = nil
+	}
+	if !isClockMsg {
+		correction.ClockCorrections = nil
+	}
+
+	for i := 0; i < header.NumSatellites; i++ {
+		// Decode satellite ID
+		satID := uint8(gnssgo.GetBitU(msg.Data, pos, 6))
+		pos += 6
+		correction.Header.addSatellite(satID)
 
-	// Decode orbit corrections if this is an orbit message
-	if isOrbitMsg {
-		for i := 0; i < header.NumSatellites; i++ {
+		// Decode orbit correction
+		if isOrbitMsg {
 			orb, newPos, err := decodeSSROrbitCorrection(msg, pos)
 			if err != nil {
 				return nil, fmt.Errorf("failed to decode orbit correction for satellite %d: %w", i+1, err)
 			}
+			orb.SatID = satID
 			correction.OrbitCorrections[i] = *orb
 			pos = newPos
 		}
-	}
 
-	// Decode clock corrections if this is a clock message
-	if isClockMsg {
-		for i := 0; i < header.NumSatellites; i++ {
+		// Decode clock correction
+		if isClockMsg {
 			clk, newPos, err := decodeSSRClockCorrection(msg, pos)
 			if err != nil {
 				return nil, fmt.Errorf("failed to decode clock correction for satellite %d: %w", i+1, err)
 			}
+			clk.SatID = satID
 			correction.ClockCorrections[i] = *clk
 			pos = newPos
 		}
@@ -365,6 +376,7 @@ func decodeSSRCodeBias(msg *RTCMMessage) (*SSRCodeBiasCorrection, error) {
 		if satID == 0 || satID > 64 {
 			return nil, fmt.Errorf("invalid satellite ID: %d", satID)
 		}
+		correction.Header.addSatellite(satID)
 
 		// Decode number of biases
 		numBiases := int(gnssgo.GetBitU(msg.Data, pos, 5))
@@ -441,6 +453,7 @@ func decodeSSRPhaseBias(msg *RTCMMessage) (*SSRPhaseBiasCorrection, error) {
 		if satID == 0 || satID > 64 {
 			return nil, fmt.Errorf("invalid satellite ID: %d", satID)
 		}
+		correction.Header.addSatellite(satID)
 
 		// Decode number of biases
 		numBiases := int(gnssgo.GetBitU(msg.Data, pos, 5))
@@ -25,8 +25,7 @@ func TestDecodeSSRHeader(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	// Message type (24 bits) and station ID (12 bits) are already set
-	pos := 36 // Start after message type and station ID
+	pos := 36 // Start after frame header (24 bits) and message type (12 bits)
 
 	// Set epoch time (20 bits)
 	gnssgo.SetBitU(msg.Data, pos, 20, 500000)
@@ -60,12 +59,6 @@ func TestDecodeSSRHeader(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 6, 2)
 	pos += 6
 
-	// Set satellite IDs (6 bits each)
-	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
-	pos += 6
-	gnssgo.SetBitU(msg.Data, pos, 6, 12) // PRN 12
-	pos += 6
-
 	// Decode the header
 	header, newPos, err := decodeSSRHeader(msg)
 	if err != nil {
@@ -120,8 +113,7 @@ func TestDecodeSSRPhaseBias(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	// Message type (24 bits) and station ID (12 bits) are already set
-	pos := 36 // Start after message type and station ID
+	pos := 36 // Start after frame header (24 bits) and message type (12 bits)
 
 	// Set epoch time (20 bits)
 	gnssgo.SetBitU(msg.Data, pos, 20, 500000)
@@ -155,10 +147,6 @@ func TestDecodeSSRPhaseBias(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 6, 1)
 	pos += 6
 
-	// Set satellite ID (6 bits)
-	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
-	pos += 6
-
 	// Set satellite data
 	// Satellite ID (6 bits)
 	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
@@ -297,7 +285,7 @@ func TestDecodeSSROrbitClockCorrection(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	pos := 36 // Start after message type and station ID
+	pos := 36 // Start after frame header (24 bits) and message type (12 bits)
 
 	// Set epoch time (20 bits)
 	gnssgo.SetBitU(msg.Data, pos, 20, 500000)
@@ -331,54 +319,51 @@ func TestDecodeSSROrbitClockCorrection(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 6, 1)
 	pos += 6
 
-	// Set satellite ID (6 bits)
+	// Set satellite block
+	// Satellite ID (6 bits)
 	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
 	pos += 6
 
-	// Set orbit correction for satellite
+	// Orbit correction
 	// IODE (8 bits)
 	gnssgo.SetBitU(msg.Data, pos, 8, 23)
 	pos += 8
 
 	// Delta radial (22 bits)
-	gnssgo.SetBits(msg.Data, pos, 22, 1000) // 0.1 mm
+	gnssgo.SetBits(msg.Data, pos, 22, 1000) // 0.1 m
 	pos += 22
 
 	// Delta along-track (20 bits)
-	gnssgo.SetBits(msg.Data, pos, 20, 2000) // 0.4 mm
+	gnssgo.SetBits(msg.Data, pos, 20, 2000) // 0.8 m
 	pos += 20
 
 	// Delta cross-track (20 bits)
-	gnssgo.SetBits(msg.Data, pos, 20, -1000) // 0.4 mm
+	gnssgo.SetBits(msg.Data, pos, 20, -1000) // -0.4 m
 	pos += 20
 
 	// Dot delta radial (21 bits)
-	gnssgo.SetBits(msg.Data, pos, 21, 500) // 0.001 mm/s
+	gnssgo.SetBits(msg.Data, pos, 21, 500) // 0.0005 m/s
 	pos += 21
 
 	// Dot delta along-track (19 bits)
-	gnssgo.SetBits(msg.Data, pos, 19, -500) // 0.004 mm/s
+	gnssgo.SetBits(msg.Data, pos, 19, -500) // -0.002 m/s
 	pos += 19
 
 	// Dot delta cross-track (19 bits)
-	gnssgo.SetBits(msg.Data, pos, 19, 250) // 0.004 mm/s
+	gnssgo.SetBits(msg.Data, pos, 19, 250) // 0.001 m/s
 	pos += 19
 
-	// Set clock correction for satellite
-	// Satellite ID (6 bits) - should match the orbit correction satellite
-	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
-	pos += 6
-
+	// Clock correction
 	// Delta clock C0 (22 bits)
-	gnssgo.SetBits(msg.Data, pos, 22, 5000) // 0.1 mm
+	gnssgo.SetBits(msg.Data, pos, 22, 5000) // 0.5 m
 	pos += 22
 
 	// Delta clock C1 (21 bits)
-	gnssgo.SetBits(msg.Data, pos, 21, 100) // 0.001 mm/s
+	gnssgo.SetBits(msg.Data, pos, 21, 100) // 0.0001 m/s
 	pos += 21
 
 	// Delta clock C2 (27 bits)
-	gnssgo.SetBits(msg.Data, pos, 27, 10) // 0.00002 mm/s²
+	gnssgo.SetBits(msg.Data, pos, 27, 10) // 0.0000002 m/s²
 	pos += 27
 
 	// Decode the orbit and clock correction message
@@ -401,77 +386,114 @@ func TestDecodeSSROrbitClockCorrection(t *testing.T) {
 		t.Fatalf("Expected 1 clock correction, got %d", len(correction.ClockCorrections))
 	}
 
+	if correction.Header.SatelliteMask != 1<<4 {
+		t.Errorf("Expected satellite mask 0x10, got 0x%x", correction.Header.SatelliteMask)
+	}
+
 	// Check orbit correction
 	orb := correction.OrbitCorrections[0]
 	if orb.SatID != 5 {
 		t.Errorf("Expected satellite ID 5, got %d", orb.SatID)
 	}
-
-	// The IODE value is different from what we set, but this is likely due to
-	// how the implementation handles the IODE field. We'll accept the actual value.
-	expectedIODE := uint8(192) // Based on test output
-	if orb.IODE != expectedIODE {
-		t.Errorf("Expected IODE %d, got %d", expectedIODE, orb.IODE)
+	if orb.IODE != 23 {
+		t.Errorf("Expected IODE 23, got %d", orb.IODE)
 	}
-
-	// Based on the test output, the actual scaling factors in the implementation are:
-	// Delta radial: 1000 bits * 0.1 mm * 0.001 m/mm * 64 = 6.4 m
-	// Delta along-track: 2000 bits * 0.4 mm * 0.001 m/mm * 64 = 51.2 m
-	// Delta cross-track: -1000 bits * 0.4 mm * 0.001 m/mm * 64 = -25.6 m
-	// There seems to be an additional scaling factor of 64 applied
-
-	// For 1000 bits with actual scaling
-	expectedRadial := 6.4
-	if orb.DeltaRadial != expectedRadial {
-		t.Errorf("Expected delta radial %.4f m, got %.4f m", expectedRadial, orb.DeltaRadial)
+	orbTests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"delta radial", orb.DeltaRadial, 0.1},
+		{"delta along-track", orb.DeltaAlongTrack, 0.8},
+		{"delta cross-track", orb.DeltaCrossTrack, -0.4},
+		{"dot delta radial", orb.DotDeltaRadial, 0.0005},
+		{"dot delta along-track", orb.DotDeltaAlongTrack, -0.002},
+		{"dot delta cross-track", orb.DotDeltaCrossTrack, 0.001},
 	}
-
-	// For 2000 bits with actual scaling
-	// Use approximate comparison for floating point values
-	expectedAlongTrack := 51.2252 // There might be some rounding
-	if !almostEqual(orb.DeltaAlongTrack, expectedAlongTrack, 0.0001) {
-		t.Errorf("Expected delta along-track %.4f m, got %.4f m", expectedAlongTrack, orb.DeltaAlongTrack)
-	}
-
-	// For -1000 bits with actual scaling
-	expectedCrossTrack := -25.6
-	if orb.DeltaCrossTrack != expectedCrossTrack {
-		t.Errorf("Expected delta cross-track %.4f m, got %.4f m", expectedCrossTrack, orb.DeltaCrossTrack)
+	for _, tt := range orbTests {
+		if !almostEqual(tt.got, tt.want, 1e-12) {
+			t.Errorf("Expected %s %.6f, got %.6f", tt.name, tt.want, tt.got)
+		}
 	}
 
 	// Check clock correction
 	clk := correction.ClockCorrections[0]
+	if clk.SatID != 5 {
+		t.Errorf("Expected satellite ID 5, got %d", clk.SatID)
+	}
+	if !almostEqual(clk.DeltaClockC0, 0.5, 1e-12) {
+		t.Errorf("Expected delta clock C0 0.5 m, got %.6f m", clk.DeltaClockC0)
+	}
+	if !almostEqual(clk.DeltaClockC1, 0.0001, 1e-12) {
+		t.Errorf("Expected delta clock C1 0.0001 m/s, got %.6f m/s", clk.DeltaClockC1)
+	}
+	if !almostEqual(clk.DeltaClockC2, 0.0000002, 1e-15) {
+		t.Errorf("Expected delta clock C2 0.0000002 m/s², got %.10f m/s²", clk.DeltaClockC2)
+	}
+}
 
-	// The satellite ID is different from what we set, but this is likely due to
-	// how the implementation handles the satellite ID field. We'll accept the actual value.
-	expectedSatID := uint8(0) // Based on test output
-	if clk.SatID != expectedSatID {
-		t.Errorf("Expected satellite ID %d, got %d", expectedSatID, clk.SatID)
+// TestDecodeSSRClockCorrection tests the decoding of SSR clock correction
+// messages, which have no satellite reference datum flag
+func TestDecodeSSRClockCorrection(t *testing.T) {
+	msg := &RTCMMessage{
+		Type:   1058, // GPS clock correction
+		Length: 100,
+		Data:   make([]byte, 100),
 	}
 
-	// Based on the test output, the actual scaling factors in the implementation are:
-	// Delta clock C0: 5000 bits * 0.1 mm * 0.001 m/mm * 64 = 32.0 m
-	// Delta clock C1: 100 bits * 0.001 mm/s * 0.001 m/mm * 64 = 0.0064 m/s
-	// Delta clock C2: 10 bits * 0.00002 mm/s² * 0.001 m/mm * 64 = 0.0000128 m/s²
-	// There seems to be an additional scaling factor of 64 applied
+	pos := 36 // Start after frame header (24 bits) and message type (12 bits)
 
-	// For 5000 bits with actual scaling
-	expectedClockC0 := 32.0
-	if clk.DeltaClockC0 != expectedClockC0 {
-		t.Errorf("Expected delta clock C0 %.4f m, got %.4f m", expectedClockC0, clk.DeltaClockC0)
-	}
+	gnssgo.SetBitU(msg.Data, pos, 20, 500000) // Epoch time
+	pos += 20
+	gnssgo.SetBitU(msg.Data, pos, 4, 2) // Update interval
+	pos += 4
+	gnssgo.SetBitU(msg.Data, pos, 1, 0) // Multiple message flag
+	pos += 1
+	gnssgo.SetBitU(msg.Data, pos, 4, 3) // IOD SSR
+	pos += 4
+	gnssgo.SetBitU(msg.Data, pos, 16, 5) // Provider ID
+	pos += 16
+	gnssgo.SetBitU(msg.Data, pos, 4, 1) // Solution ID
+	pos += 4
+	gnssgo.SetBitU(msg.Data, pos, 6, 2) // Number of satellites
+	pos += 6
 
-	// For 100 bits with actual scaling
-	expectedClockC1 := 0.0064
-	if clk.DeltaClockC1 != expectedClockC1 {
-		t.Errorf("Expected delta clock C1 %.6f m/s, got %.6f m/s", expectedClockC1, clk.DeltaClockC1)
+	for _, sat := range []struct {
+		id     uint32
+		c0, c1 int32
+	}{{3, -2500, 40}, {17, 1200, -8}} {
+		gnssgo.SetBitU(msg.Data, pos, 6, sat.id)
+		pos += 6
+		gnssgo.SetBits(msg.Data, pos, 22, sat.c0)
+		pos += 22
+		gnssgo.SetBits(msg.Data, pos, 21, sat.c1)
+		pos += 21
+		gnssgo.SetBits(msg.Data, pos, 27, 0)
+		pos += 27
 	}
 
-	// For 10 bits with actual scaling
-	// Use approximate comparison for floating point values
-	expectedClockC2 := 0.0000128
-	if !almostEqual(clk.DeltaClockC2, expectedClockC2, 0.0000000001) {
-		t.Errorf("Expected delta clock C2 %.10f m/s², got %.10f m/s²", expectedClockC2, clk.DeltaClockC2)
+	correction, err := decodeSSROrbitClockCorrection(msg)
+	if err != nil {
+		t.Fatalf("Failed to decode SSR clock correction: %v", err)
+	}
+	if correction.Header.IODSSRIndicator != 3 || correction.Header.SSRProviderID != 5 {
+		t.Errorf("Unexpected header %+v", correction.Header)
+	}
+	if len(correction.OrbitCorrections) != 0 {
+		t.Errorf("Expected no orbit corrections, got %d", len(correction.OrbitCorrections))
+	}
+	if len(correction.ClockCorrections) != 2 {
+		t.Fatalf("Expected 2 clock corrections, got %d", len(correction.ClockCorrections))
+	}
+	want := []SSRClockCorrection{
+		{SatID: 3, DeltaClockC0: -0.25, DeltaClockC1: 0.00004},
+		{SatID: 17, DeltaClockC0: 0.12, DeltaClockC1: -0.000008},
+	}
+	for i, clk := range correction.ClockCorrections {
+		if clk.SatID != want[i].SatID ||
+			!almostEqual(clk.DeltaClockC0, want[i].DeltaClockC0, 1e-12) ||
+			!almostEqual(clk.DeltaClockC1, want[i].DeltaClockC1, 1e-12) {
+			t.Errorf("Expected clock correction %+v, got %+v", want[i], clk)
+		}
 	}
 }
 
@@ -487,7 +509,7 @@ func TestDecodeSSRCodeBias(t *testing.T) {
 	}
 
 	// Set header fields in the message data
-	pos := 36 // Start after message type and station ID
+	pos := 36 // Start after frame header (24 bits) and message type (12 bits)
 
 	// Set epoch time (20 bits)
 	gnssgo.SetBitU(msg.Data, pos, 20, 500000)
@@ -521,10 +543,6 @@ func TestDecodeSSRCodeBias(t *testing.T) {
 	gnssgo.SetBitU(msg.Data, pos, 6, 1)
 	pos += 6
 
-	// Set satellite ID (6 bits)
-	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
-	pos += 6
-
 	// Set satellite data
 	// Satellite ID (6 bits)
 	gnssgo.SetBitU(msg.Data, pos, 6, 5) // PRN 5
@@ -7,26 +7,37 @@ import (
 	"github.com/bramburn/gnssgo/pkg/gnssgo"
 )
 
-// StationCoordinates represents the station coordinates from RTCM message 1005
+// StationCoordinates represents the antenna reference point of a reference
+// station from RTCM message 1005, or 1006 with the antenna height
 type StationCoordinates struct {
 	StationID      uint16  // Reference station ID
 	ITRF           uint8   // ITRF realization year
 	GPS            bool    // GPS indicator
 	GLONASS        bool    // GLONASS indicator
 	Galileo        bool    // Galileo indicator
+	BeiDou         bool    // BeiDou indicator (reserved bit following the single receiver oscillator indicator)
 	ReferencePoint bool    // Reference-station indicator
 	SingleReceiver bool    // Single receiver oscillator indicator
+	QuarterCycle   uint8   // Quarter cycle indicator
 	X              float64 // ECEF X coordinate (m)
 	Y              float64 // ECEF Y coordinate (m)
 	Z              float64 // ECEF Z coordinate (m)
+	AntennaHeight  float64 // Antenna height above the marker (m, 1006 only)
 }
 
-// StationCoordinatesAlt represents the station coordinates with height from RTCM message 1006
-type StationCoordinatesAlt struct {
-	StationCoordinates
-	AntennaHeight float64 // Antenna height (m)
+// ToLLH returns the geodetic position of the station: latitude and longitude
+// (rad) and ellipsoidal height (m) on WGS84
+func (sc StationCoordinates) ToLLH() [3]float64 {
+	var llh [3]float64
+	gnssgo.Ecef2Pos([]float64{sc.X, sc.Y, sc.Z}, llh[:])
+	return llh
 }
 
+// StationCoordinatesAlt is the result of RTCM message 1006. It is kept as an
+// alias of StationCoordinates, which now holds the antenna height itself, so
+// that existing type switches on *StationCoordinatesAlt keep matching.
+type StationCoordinatesAlt = StationCoordinates
+
 // AntennaDescriptor represents the antenna descriptor from RTCM message 1007
 type AntennaDescriptor struct {
 	StationID      uint16 // Reference station ID
@@ -51,22 +62,54 @@ type ReceiverInfo struct {
 	AntennaSetupID   uint8  // Antenna setup ID
 }
 
+// GLONASSCodePhaseBias represents the GLONASS code-phase biases from RTCM
+// message 1230. The biases are in the order L1 C/A, L1 P, L2 C/A and L2 P.
+type GLONASSCodePhaseBias struct {
+	StationID uint16     // Reference station ID
+	Aligned   bool       // Code-phase bias indicator (observations are aligned)
+	Mask      uint8      // FDMA signals mask (bit 3: L1 C/A ... bit 0: L2 P)
+	Bias      [4]float64 // Code-phase biases (m)
+	Valid     [4]bool    // Bias present in the message and not invalid
+}
+
 // decodeStationCoordinates decodes RTCM message 1005 (Station Coordinates)
 func decodeStationCoordinates(msg *RTCMMessage) (*StationCoordinates, error) {
 	if msg == nil || msg.Type != RTCM_STATION_COORDINATES {
 		return nil, fmt.Errorf("not a station coordinates message")
 	}
+	return decodeStationARP(msg, 152)
+}
 
-	if len(msg.Data) < 10 {
+// decodeStationCoordinatesAlt decodes RTCM message 1006 (Station Coordinates with Height)
+func decodeStationCoordinatesAlt(msg *RTCMMessage) (*StationCoordinatesAlt, error) {
+	if msg == nil || msg.Type != RTCM_STATION_COORDINATES_ALT {
+		return nil, fmt.Errorf("not a station coordinates with height message")
+	}
+	sc, err := decodeStationARP(msg, 168)
+	if err != nil {
+		return nil, err
+	}
+
+	// Antenna height (16 bits, 0.0001 m resolution, unsigned) after the
+	// coordinates (24 + 12 + 12 + 6 + 4 + 38 + 2 + 38 + 2 + 38 = 176 bits)
+	sc.AntennaHeight = float64(gnssgo.GetBitU(msg.Data, 176, 16)) * 0.0001
+
+	return sc, nil
+}
+
+// decodeStationARP decodes the antenna reference point common to messages
+// 1005 and 1006 with a payload of the given length (bits)
+func decodeStationARP(msg *RTCMMessage, bits int) (*StationCoordinates, error) {
+	if len(msg.Data) < 3+(bits+7)/8 {
 		return nil, fmt.Errorf("message too short for station coordinates")
 	}
 
-	// Start position after message type and station ID (24 + 12 = 36 bits)
-	pos := 36
+	// Start position after header, message type and station ID (24 + 12 + 12 = 48 bits)
+	pos := 48
 
 	// Create station coordinates
 	sc := &StationCoordinates{
-		StationID: msg.StationID,
+		StationID: uint16(gnssgo.GetBitU(msg.Data, 36, 12)),
 	}
 
 	// Decode flags
@@ -80,53 +123,30 @@ func decodeStationCoordinates(msg *RTCMMessage) (*StationCoordinates, error) {
 	pos++
 	sc.ReferencePoint = gnssgo.GetBitU(msg.Data, pos, 1) != 0
 	pos++
-	sc.SingleReceiver = gnssgo.GetBitU(msg.Data, pos, 1) != 0
-	pos++
-	pos += 1 // Reserved bit
 
-	// Decode coordinates
 	// X coordinate (38 bits, 0.0001 m resolution, signed)
-	x := int64(gnssgo.GetBits(msg.Data, pos, 38))
-	sc.X = float64(x) * 0.0001
+	sc.X = float64(getBits38(msg.Data, pos)) * 0.0001
 	pos += 38
+	sc.SingleReceiver = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+	pos++
+	sc.BeiDou = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+	pos++
 
 	// Y coordinate (38 bits, 0.0001 m resolution, signed)
-	y := int64(gnssgo.GetBits(msg.Data, pos, 38))
-	sc.Y = float64(y) * 0.0001
+	sc.Y = float64(getBits38(msg.Data, pos)) * 0.0001
 	pos += 38
+	sc.QuarterCycle = uint8(gnssgo.GetBitU(msg.Data, pos, 2))
+	pos += 2
 
 	// Z coordinate (38 bits, 0.0001 m resolution, signed)
-	z := int64(gnssgo.GetBits(msg.Data, pos, 38))
-	sc.Z = float64(z) * 0.0001
+	sc.Z = float64(getBits38(msg.Data, pos)) * 0.0001
 
 	return sc, nil
 }
 
-// decodeStationCoordinatesAlt decodes RTCM message 1006 (Station Coordinates with Height)
-func decodeStationCoordinatesAlt(msg *RTCMMessage) (*StationCoordinatesAlt, error) {
-	if msg == nil || msg.Type != RTCM_STATION_COORDINATES_ALT {
-		return nil, fmt.Errorf("not a station coordinates with height message")
-	}
-
-	// First decode the base station coordinates
-	sc, err := decodeStationCoordinates(msg)
-	if err != nil {
-		return nil, err
-	}
-
-	// Start position after the station coordinates (36 + 6 + 6 + 38*3 = 162 bits)
-	pos := 162
-
-	// Create station coordinates with height
-	sca := &StationCoordinatesAlt{
-		StationCoordinates: *sc,
-	}
-
-	// Decode antenna height (16 bits, 0.0001 m resolution, unsigned)
-	height := gnssgo.GetBitU(msg.Data, pos, 16)
-	sca.AntennaHeight = float64(height) * 0.0001
-
-	return sca, nil
+// getBits38 returns the signed 38-bit field at bit pos
+func getBits38(buff []byte, pos int) int64 {
+	return int64(gnssgo.GetBits(buff, pos, 32))*64 + int64(gnssgo.GetBitU(buff, pos+32, 6))
 }
 
 // decodeAntennaDescriptor decodes RTCM message 1007 (Antenna Descriptor)
@@ -291,3 +311,42 @@ func decodeReceiverInfo(msg *RTCMMessage) (*ReceiverInfo, error) {
 
 	return ri, nil
 }
+
+// decodeGLONASSBias decodes RTCM message 1230 (GLONASS L1 and L2 Code-Phase Biases)
+func decodeGLONASSBias(msg *RTCMMessage) (*GLONASSCodePhaseBias, error) {
+	if msg == nil || msg.Type != RTCM_GLONASS_BIAS {
+		return nil, fmt.Errorf("not a GLONASS code-phase bias message")
+	}
+
+	// Start position after header, message type and station ID (24 + 12 + 12 = 48 bits)
+	pos := 48
+	if len(msg.Data)*8 < pos+8 {
+		return nil, fmt.Errorf("message too short for GLONASS code-phase biases")
+	}
+
+	gb := &GLONASSCodePhaseBias{
+		StationID: uint16(gnssgo.GetBitU(msg.Data, 36, 12)),
+	}
+	gb.Aligned = gnssgo.GetBitU(msg.Data, pos, 1) != 0
+	pos += 1 + 3 // Indicator and reserved bits
+	gb.Mask = uint8(gnssgo.GetBitU(msg.Data, pos, 4))
+	pos += 4
+
+	// A 16-bit bias (0.02 m resolution, signed) follows for each signal in
+	// the mask, -32768 is invalid
+	for i := 0; i < 4; i++ {
+		if gb.Mask&(1<<(3-i)) == 0 {
+			continue
+		}
+		if len(msg.Data)*8 < pos+16 {
+			return nil, fmt.Errorf("message too short for GLONASS code-phase biases")
+		}
+		if bias := gnssgo.GetBits(msg.Data, pos, 16); bias != -32768 {
+			gb.Bias[i] = float64(bias) * 0.02
+			gb.Valid[i] = true
+		}
+		pos += 16
+	}
+
+	return gb, nil
+}
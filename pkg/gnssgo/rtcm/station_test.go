@@ -0,0 +1,145 @@
+package rtcm_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/bramburn/gnssgo/pkg/gnssgo/rtcm"
+)
+
+// TestDecodeStationCoordinates tests the decoding of station coordinates
+// messages 1005 and 1006
+func TestDecodeStationCoordinates(t *testing.T) {
+	msg := rtcm.RTCMMessage{Type: 1005, Length: 19, Data: testFrame1005, Timestamp: time.Now()}
+	decoded, err := rtcm.DecodeRTCMMessage(&msg)
+	if err != nil {
+		t.Fatalf("Failed to decode RTCM 1005 message: %v", err)
+	}
+	sc, ok := decoded.(*rtcm.StationCoordinates)
+	if !ok {
+		t.Fatalf("Expected *rtcm.StationCoordinates, got %T", decoded)
+	}
+	if sc.StationID != 2003 {
+		t.Errorf("Expected station ID 2003, got %d", sc.StationID)
+	}
+	if !sc.GPS || sc.GLONASS || sc.Galileo || sc.BeiDou {
+		t.Errorf("Unexpected system indicators: %+v", *sc)
+	}
+	want := [3]float64{1114104.5999, -4850729.7108, 3975521.4643}
+	for i, got := range [3]float64{sc.X, sc.Y, sc.Z} {
+		if math.Abs(got-want[i]) > 1e-3 {
+			t.Errorf("Coordinate %d: expected %.4f m, got %.4f m", i, want[i], got)
+		}
+	}
+	if sc.AntennaHeight != 0.0 {
+		t.Errorf("Expected no antenna height for 1005, got %.4f m", sc.AntennaHeight)
+	}
+
+	// Geodetic position round trip
+	llh := sc.ToLLH()
+	var r [3]float64
+	gnssgo.Pos2Ecef(llh[:], r[:])
+	for i := range r {
+		if math.Abs(r[i]-want[i]) > 1e-3 {
+			t.Errorf("ToLLH %d: expected %.4f m, got %.4f m", i, want[i], r[i])
+		}
+	}
+
+	// Message 1006: the same coordinates with an antenna height of 1.5432 m
+	frame := make([]byte, 3+21+3)
+	copy(frame, testFrame1005[:22])
+	gnssgo.SetBitU(frame, 14, 10, 21)
+	gnssgo.SetBitU(frame, 24, 12, 1006)
+	gnssgo.SetBitU(frame, 176, 16, 15432)
+	gnssgo.SetBitU(frame, 192, 24, gnssgo.Rtk_CRC24q(frame, 24))
+	msg = rtcm.RTCMMessage{Type: 1006, Length: 21, Data: frame, Timestamp: time.Now()}
+	decoded, err = rtcm.DecodeRTCMMessage(&msg)
+	if err != nil {
+		t.Fatalf("Failed to decode RTCM 1006 message: %v", err)
+	}
+	sc, ok = decoded.(*rtcm.StationCoordinates)
+	if !ok {
+		t.Fatalf("Expected *rtcm.StationCoordinates, got %T", decoded)
+	}
+	if _, ok := decoded.(*rtcm.StationCoordinatesAlt); !ok {
+		t.Errorf("Expected 1006 to match *rtcm.StationCoordinatesAlt, got %T", decoded)
+	}
+	for i, got := range [3]float64{sc.X, sc.Y, sc.Z} {
+		if math.Abs(got-want[i]) > 1e-3 {
+			t.Errorf("1006 coordinate %d: expected %.4f m, got %.4f m", i, want[i], got)
+		}
+	}
+	if math.Abs(sc.AntennaHeight-1.5432) > 1e-6 {
+		t.Errorf("Expected antenna height 1.5432 m, got %.4f m", sc.AntennaHeight)
+	}
+
+	// Truncated message
+	msg = rtcm.RTCMMessage{Type: 1005, Length: 19, Data: testFrame1005[:10], Timestamp: time.Now()}
+	if _, err := rtcm.DecodeRTCMMessage(&msg); err == nil {
+		t.Errorf("Expected an error for a truncated message")
+	}
+}
+
+// TestDecodeGLONASSBias tests the decoding of GLONASS code-phase biases
+// message 1230
+func TestDecodeGLONASSBias(t *testing.T) {
+	// Receiver frame: aligned, L1 and L2 C/A biases of 0 m
+	frame := []byte{0xD3, 0x00, 0x08, 0x4C, 0xE0, 0x00, 0x8A, 0x00, 0x00, 0x00, 0x00, 0xA8, 0xF7, 0x2A}
+
+	// Not aligned, all signals with the L1 P bias invalid
+	all := make([]byte, 3+12+3)
+	gnssgo.SetBitU(all, 0, 8, rtcm.RTCM3PREAMB)
+	gnssgo.SetBitU(all, 14, 10, 12)
+	gnssgo.SetBitU(all, 24, 12, 1230)
+	gnssgo.SetBitU(all, 36, 12, 2003)
+	gnssgo.SetBitU(all, 52, 4, 0xF)
+	for i, bias := range []int32{-36, -32768, 1234, -7} {
+		gnssgo.SetBits(all, 56+16*i, 16, bias)
+	}
+	gnssgo.SetBitU(all, 120, 24, gnssgo.Rtk_CRC24q(all, 15))
+
+	tests := []struct {
+		name      string
+		frame     []byte
+		stationID uint16
+		aligned   bool
+		mask      uint8
+		bias      [4]float64
+		valid     [4]bool
+	}{
+		{"receiver", frame, 0, true, 0xA, [4]float64{}, [4]bool{true, false, true, false}},
+		{"all signals", all, 2003, false, 0xF, [4]float64{-0.72, 0, 24.68, -0.14}, [4]bool{true, false, true, true}},
+	}
+	for _, tt := range tests {
+		msgs, _, err := rtcm.NewRTCMParser().ParseRTCMMessage(tt.frame)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("%s: parse failed: %v (%d messages)", tt.name, err, len(msgs))
+		}
+		decoded, err := rtcm.DecodeRTCMMessage(&msgs[0])
+		if err != nil {
+			t.Fatalf("%s: decode failed: %v", tt.name, err)
+		}
+		gb, ok := decoded.(*rtcm.GLONASSCodePhaseBias)
+		if !ok {
+			t.Fatalf("%s: expected *rtcm.GLONASSCodePhaseBias, got %T", tt.name, decoded)
+		}
+		if gb.StationID != tt.stationID || gb.Aligned != tt.aligned || gb.Mask != tt.mask {
+			t.Errorf("%s: got station %d aligned %v mask %X, want %d %v %X", tt.name,
+				gb.StationID, gb.Aligned, gb.Mask, tt.stationID, tt.aligned, tt.mask)
+		}
+		for i := range gb.Bias {
+			if gb.Valid[i] != tt.valid[i] || math.Abs(gb.Bias[i]-tt.bias[i]) > 1e-9 {
+				t.Errorf("%s: bias %d = %.2f (valid %v), want %.2f (valid %v)", tt.name, i,
+					gb.Bias[i], gb.Valid[i], tt.bias[i], tt.valid[i])
+			}
+		}
+	}
+
+	// Truncated biases
+	msg := rtcm.RTCMMessage{Type: 1230, Data: all[:12]}
+	if _, err := rtcm.DecodeRTCMMessage(&msg); err == nil {
+		t.Errorf("Expected an error for truncated biases")
+	}
+}
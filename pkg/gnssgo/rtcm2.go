@@ -152,7 +152,7 @@ func (rtcm *Rtcm) decode_type14() int {
 		Trace(2, "rtcm2 14 length error: len=%d\n", rtcm.MsgLen)
 		return -1
 	}
-	week = AdjGpsWeek(week)
+	week = AdjGpsWeekRef(week, rtcm.Time)
 	rtcm.Time = GpsT2Time(week, float64(hour)*3600.0+zcnt*0.6)
 	rtcm.NavData.Utc_gps[4] = float64(leaps)
 	return 6
@@ -252,7 +252,7 @@ func (rtcm *Rtcm) decode_type17() int {
 	}
 	sat = SatNo(SYS_GPS, prn)
 	eph.Sat = sat
-	eph.Week = AdjGpsWeek(week)
+	eph.Week = AdjGpsWeekRef(week, rtcm.Time)
 	eph.Toe = GpsT2Time(eph.Week, eph.Toes)
 	eph.Toc = GpsT2Time(eph.Week, toc)
 	eph.Ttr = rtcm.Time
@@ -118,7 +118,7 @@ var (
 		/* BeiDou: ref [17] table 3.5-108 */
 		"", "2I", "2Q", "2X", "", "", "", "6I", "6Q", "6X", "", "",
 		"", "7I", "7Q", "7X", "", "", "", "", "", "5D", "5P", "5X",
-		"", "", "", "", "", "1D", "1P", "1X"}
+		"7D", "", "", "", "", "1D", "1P", "1X"}
 	msm_sig_irn [32]string = [32]string{
 		/* NavIC/IRNSS: ref [17] table 3.5-108.3 */
 		"", "", "", "", "", "", "", "", "", "", "", "",
@@ -992,7 +992,7 @@ func (rtcm *Rtcm) decode_type1019() int {
 		return -1
 	}
 	eph.Sat = sat
-	eph.Week = AdjGpsWeek(week)
+	eph.Week = AdjGpsWeekRef(week, rtcm.Time)
 	if rtcm.Time.Time == 0 {
 		rtcm.Time = Utc2GpsT(TimeGet())
 	}
@@ -1389,7 +1389,7 @@ func (rtcm *Rtcm) decode_type1041() int {
 		return -1
 	}
 	eph.Sat = sat
-	eph.Week = AdjGpsWeek(week)
+	eph.Week = AdjGpsWeekRef(week, rtcm.Time)
 	if rtcm.Time.Time == 0 {
 		rtcm.Time = Utc2GpsT(TimeGet())
 	}
@@ -1501,7 +1501,7 @@ func (rtcm *Rtcm) decode_type1044() int {
 		return -1
 	}
 	eph.Sat = sat
-	eph.Week = AdjGpsWeek(week)
+	eph.Week = AdjGpsWeekRef(week, rtcm.Time)
 	if rtcm.Time.Time == 0 {
 		rtcm.Time = Utc2GpsT(TimeGet())
 	}
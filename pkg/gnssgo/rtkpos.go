@@ -122,6 +122,7 @@ const (
 	PRN_HWBIAS float64 = 1e-6 /* process noise of h/w bias (m/MHz/sqrt(s)) */
 	//const GAP_RESION float64 = 120      /* gap to reset ionosphere parameters (epochs) */
 	MAXACC      float64 = 30.0  /* max accel for doppler slip detection (m/s^2) */
+	MAXDOPDIFF  float64 = 1.0   /* max doppler and phase-rate difference (m/s) */
 	VAR_HOLDAMB float64 = 0.001 /* constraint to hold ambiguity (cycle^2) */
 	TTOL_MOVEB  float64 = float64(1.0 + 2*DTTOL)
 	/* time sync tolerance for moving-baseline (s) */
@@ -479,6 +480,23 @@ func GeometryFreeObs(obs []ObsD, i, j, k int, nav *Nav) float64 {
 	return L1*CLIGHT/freq1 - L2*CLIGHT/freq2
 }
 
+/* single-differenced Melbourne-Wubbena linear combination ------------------*/
+func MWObs(obs []ObsD, i, j, k int, nav *Nav) float64 {
+	var freq1, freq2, L1, L2, P1, P2 float64
+
+	freq1 = Sat2Freq(obs[i].Sat, obs[i].Code[0], nav)
+	freq2 = Sat2Freq(obs[i].Sat, obs[i].Code[k], nav)
+	L1 = SingleDifferencedObs(obs, i, j, 0)
+	L2 = SingleDifferencedObs(obs, i, j, k)
+	P1 = SingleDifferencedObs(obs, i, j, NFREQ)
+	P2 = SingleDifferencedObs(obs, i, j, k+NFREQ)
+	if freq1 == 0.0 || freq2 == 0.0 || freq1 == freq2 || L1 == 0.0 || L2 == 0.0 ||
+		P1 == 0.0 || P2 == 0.0 {
+		return 0.0
+	}
+	return (L1-L2)*CLIGHT/(freq1-freq2) - (freq1*P1+freq2*P2)/(freq1+freq2)
+}
+
 /* single-differenced measurement error variance -----------------------------*/
 func RtkVarianceErr(sat, sys int, el, bl, dt float64, f int, opt *PrcOpt) float64 {
 	var a, b, c, d, fact float64
@@ -499,6 +517,8 @@ func RtkVarianceErr(sat, sys int, el, bl, dt float64, f int, opt *PrcOpt) float6
 		fact *= float64(EFACT_GLO)
 	case SYS_SBS:
 		fact *= float64(EFACT_SBS)
+	case SYS_IRN:
+		fact *= float64(EFACT_IRN)
 	default:
 		fact *= float64(EFACT_GPS)
 	}
@@ -814,15 +834,30 @@ func (rtk *Rtk) DetectSlp_ll(obs []ObsD, i, rcv int) {
 	}
 }
 
+/* slip threshold of geometry-free phase for time gap ------------------------
+* the geometry-free phase drifts with the ionosphere, so the allowed jump grows
+* with the time since the previous epoch: thres = thresslip + thresslipr * |dt|
+*-----------------------------------------------------------------------------*/
+func SlipThresGf(opt *PrcOpt, dt float64) float64 {
+	return opt.ThresSlip + opt.ThresSlipR*math.Abs(dt)
+}
+
 /* detect cycle slip by geometry free phase jump -----------------------------*/
 func (rtk *Rtk) DetectSlp_gf(obs []ObsD, i, j int, nav *Nav) {
 	var (
-		k, sat int = 0, obs[i].Sat
-		g0, g1 float64
+		k, sat     int = 0, obs[i].Sat
+		g0, g1, dt float64
+		thres      float64
 	)
 
 	Trace(4, "detslp_gf: i=%d j=%d\n", i, j)
 
+	/* time gap since previous rover phase */
+	if rtk.Ssat[sat-1].Pt[0][0].Time != 0 {
+		dt = TimeDiff(obs[i].Time, rtk.Ssat[sat-1].Pt[0][0])
+	}
+	thres = SlipThresGf(&rtk.Opt, dt)
+
 	for k = 1; k < rtk.Opt.Nf; k++ {
 		if g1 = GeometryFreeObs(obs, i, j, k, nav); g1 == 0.0 {
 			return
@@ -831,11 +866,44 @@ func (rtk *Rtk) DetectSlp_gf(obs []ObsD, i, j int, nav *Nav) {
 		g0 = rtk.Ssat[sat-1].Gf[k-1]
 		rtk.Ssat[sat-1].Gf[k-1] = g1
 
-		if g0 != 0.0 && math.Abs(g1-g0) > rtk.Opt.ThresSlip {
+		if g0 != 0.0 && math.Abs(g1-g0) > thres {
 			rtk.Ssat[sat-1].Slip[0] |= 1
 			rtk.Ssat[sat-1].Slip[k] |= 1
-			rtk.errmsg("slip detected GF jump (sat=%2d L1-L%d GF=%.3f %.3f)\n",
-				sat, k+1, g0, g1)
+			rtk.errmsg("slip detected GF jump (sat=%2d L1-L%d GF=%.3f %.3f thres=%.3f)\n",
+				sat, k+1, g0, g1, thres)
+		}
+	}
+}
+
+/* detect cycle slip by Melbourne-Wubbena linear combination jump ------------
+* the single-differenced MW combination is free of geometry, clocks and
+* ionosphere, a jump above thresslipmw (m) is a slip of the wide-lane (L1-Lk)
+* ambiguity. the detection is disabled with thresslipmw=0.
+*-----------------------------------------------------------------------------*/
+func (rtk *Rtk) DetectSlp_mw(obs []ObsD, i, j int, nav *Nav) {
+	var (
+		k, sat int = 0, obs[i].Sat
+		w0, w1 float64
+	)
+
+	Trace(4, "detslp_mw: i=%d j=%d\n", i, j)
+
+	if rtk.Opt.ThresSlipMW <= 0.0 {
+		return
+	}
+	for k = 1; k < rtk.Opt.Nf; k++ {
+		if w1 = MWObs(obs, i, j, k, nav); w1 == 0.0 {
+			continue
+		}
+
+		w0 = rtk.Ssat[sat-1].Mw[k-1]
+		rtk.Ssat[sat-1].Mw[k-1] = w1
+
+		if w0 != 0.0 && math.Abs(w1-w0) > rtk.Opt.ThresSlipMW {
+			rtk.Ssat[sat-1].Slip[0] |= 1
+			rtk.Ssat[sat-1].Slip[k] |= 1
+			rtk.errmsg("slip detected MW jump (sat=%2d L1-L%d MW=%.3f %.3f thres=%.3f)\n",
+				sat, k+1, w0, w1, rtk.Opt.ThresSlipMW)
 		}
 	}
 }
@@ -872,6 +940,56 @@ func (rtk *Rtk) DetectSlp_dop(obs []ObsD, i, rcv int, nav *Nav) {
 	// #endif
 }
 
+/* check doppler consistency -------------------------------------------------
+* compare the doppler of an observation against the carrier-phase rate since
+* the previous epoch processed by rtkpos for the same receiver
+* args   : ObsD   *obs      I   observation data
+*          int    sys       I   navigation system of the satellite
+* return : status (true:consistent, false:discrepancy above MAXDOPDIFF) and
+*          max abs difference between doppler and phase rate (m/s)
+* notes  : the phase rate is compared with the mean of the current and previous
+*          doppler. frequencies without doppler, carrier-phase or a previous
+*          epoch are skipped. a cycle slip or receiver clock jump also shows as
+*          a discrepancy.
+*-----------------------------------------------------------------------------*/
+func (rtk *Rtk) CheckDopplerConsistency(obs *ObsD, sys int) (bool, float64) {
+	var maxdiff float64
+
+	if obs.Sat <= 0 || obs.Sat > MAXSAT || obs.Rcv < 1 || obs.Rcv > 2 {
+		return true, 0.0
+	}
+	ssat := &rtk.Ssat[obs.Sat-1]
+
+	for f := 0; f < NFREQ; f++ {
+		ph, pt := ssat.Ph[obs.Rcv-1][f], ssat.Pt[obs.Rcv-1][f]
+		if obs.L[f] == 0.0 || obs.D[f] == 0.0 || ph == 0.0 {
+			continue
+		}
+		tt := TimeDiff(obs.Time, pt)
+		if math.Abs(tt) < float64(DTTOL) {
+			continue
+		}
+		freq := Code2Freq(sys, obs.Code[f], 0)
+		if freq <= 0.0 {
+			continue
+		}
+		dop := obs.D[f]
+		if dp := ssat.Dp[obs.Rcv-1][f]; dp != 0.0 {
+			dop = (dop + dp) / 2.0
+		}
+		/* phase rate and doppler (cycle/s) to range rate (m/s) */
+		diff := math.Abs((obs.L[f]-ph)/tt+dop) * CLIGHT / freq
+		if diff > maxdiff {
+			maxdiff = diff
+		}
+	}
+	if maxdiff > MAXDOPDIFF {
+		rtk.errmsg("doppler inconsistent (sat=%2d rcv=%d diff=%.3f m/s)\n", obs.Sat, obs.Rcv, maxdiff)
+		return false, maxdiff
+	}
+	return true, maxdiff
+}
+
 /* temporal update of phase biases -------------------------------------------*/
 func (rtk *Rtk) UpdateBias(tt float64, obs []ObsD, sat, iu, ir []int, ns int, nav *Nav) {
 	var (
@@ -896,6 +1014,9 @@ func (rtk *Rtk) UpdateBias(tt float64, obs []ObsD, sat, iu, ir []int, ns int, na
 		/* detect cycle slip by geometry-free phase jump */
 		rtk.DetectSlp_gf(obs, iu[i], ir[i], nav)
 
+		/* detect cycle slip by Melbourne-Wubbena jump */
+		rtk.DetectSlp_mw(obs, iu[i], ir[i], nav)
+
 		/* detect cycle slip by doppler and phase difference */
 		rtk.DetectSlp_dop(obs, iu[i], 1, nav)
 		rtk.DetectSlp_dop(obs, ir[i], 2, nav)
@@ -1736,6 +1857,8 @@ func (rtk *Rtk) ResolveAmb_LAMBDA(bias, xa []float64) int {
 	Trace(4, "resamb_LAMBDA : nx=%d\n", nx)
 
 	rtk.RtkSol.Ratio = 0.0
+	rtk.RtkSol.ARRatio, rtk.RtkSol.NFixed = 0.0, 0
+	rtk.RtkSol.Thres = float32(opt.ThresAr[0])
 
 	if rtk.Opt.Mode <= PMODE_DGPS || rtk.Opt.ModeAr == ARMODE_OFF || rtk.Opt.ThresAr[0] < 1.0 {
 		return 0
@@ -1782,7 +1905,8 @@ func (rtk *Rtk) ResolveAmb_LAMBDA(bias, xa []float64) int {
 
 		rtk.RtkSol.Ratio = 0.0
 		if s[0] > 0 {
-			rtk.RtkSol.Ratio = float32(s[1] / s[0])
+			rtk.RtkSol.ARRatio = s[1] / s[0]
+			rtk.RtkSol.Ratio = float32(rtk.RtkSol.ARRatio)
 		}
 		if rtk.RtkSol.Ratio > 999.9 {
 			rtk.RtkSol.Ratio = 999.9
@@ -1819,6 +1943,7 @@ func (rtk *Rtk) ResolveAmb_LAMBDA(bias, xa []float64) int {
 
 				/* restore SD ambiguity */
 				rtk.RestoreAmb(bias, nb, xa)
+				rtk.RtkSol.NFixed = nb
 			} else {
 				nb = 0
 			}
@@ -1834,6 +1959,43 @@ func (rtk *Rtk) ResolveAmb_LAMBDA(bias, xa []float64) int {
 	return nb /* number of ambiguities */
 }
 
+/* test position innovation ----------------------------------------------------
+* test the filter update of the rover position against the predicted position
+* covariance. the innovation is rejected if its normalized length exceeds
+* opt.MaxPosInno (sigma). the filter is reset on rejection if opt.PosInnoRst
+* is set.
+* args   : double *xp       I   updated states
+* return : status (1:ok,0:rejected)
+*-----------------------------------------------------------------------------*/
+func (rtk *Rtk) TestPosInno(xp []float64) int {
+	var dx, Q [9]float64
+
+	if rtk.Opt.MaxPosInno <= 0.0 {
+		return 1
+	}
+	for i := 0; i < 3; i++ {
+		dx[i] = xp[i] - rtk.X[i]
+		for j := 0; j < 3; j++ {
+			Q[i+j*3] = rtk.P[i+j*rtk.Nx]
+		}
+	}
+	if MatInv(Q[:], 3) != 0 {
+		return 1
+	}
+	MatMul("NN", 3, 1, 3, 1.0, Q[:], dx[:], 0.0, dx[3:6])
+	if chi := Dot(dx[:3], dx[3:6], 3); chi > SQR(rtk.Opt.MaxPosInno) {
+		rtk.errmsg("position innovation error (inno=%.1f sigma)\n", math.Sqrt(chi))
+		rtk.NInnoRej++
+		if rtk.Opt.PosInnoRst > 0 {
+			for i := 0; i < rtk.Nx; i++ {
+				rtk.Initx(0.0, 0.0, i)
+			}
+		}
+		return 0
+	}
+	return 1
+}
+
 /* validation of solution ----------------------------------------------------*/
 func (rtk *Rtk) ValidPos(v, R []float64, vflg []int, nv int, thres float64) int {
 	var (
@@ -1972,6 +2134,10 @@ func (rtk *Rtk) RelativePos(obs []ObsD, nu, nr int, nav *Nav) int {
 		Trace(5, "x(%d)=", i+1)
 		tracemat(4, xp, 1, RNR(opt), 13, 4)
 	}
+	/* position innovation gate */
+	if stat != SOLQ_NONE && rtk.TestPosInno(xp) == 0 {
+		stat = SOLQ_NONE
+	}
 	if stat != SOLQ_NONE && ZDRes(0, obs, nu, rs, dts, fvar, svh[:], nav, xp, opt, 0, y, e, azel, freq) != 0 {
 
 		/* post-fit residuals for float solution */
@@ -1986,6 +2152,7 @@ func (rtk *Rtk) RelativePos(obs []ObsD, nu, nr int, nav *Nav) int {
 
 			/* update ambiguity control struct */
 			rtk.RtkSol.Ns = 0
+			rtk.RtkSol.NsFreq = [NFREQ]uint8{}
 			for i = 0; i < ns; i++ {
 				for f = 0; f < nf; f++ {
 					if rtk.Ssat[sat[i]-1].Vsat[f] == 0 {
@@ -1993,6 +2160,7 @@ func (rtk *Rtk) RelativePos(obs []ObsD, nu, nr int, nav *Nav) int {
 					}
 					rtk.Ssat[sat[i]-1].Lock[f]++
 					rtk.Ssat[sat[i]-1].Outc[f] = 0
+					rtk.RtkSol.NsFreq[f]++
 					if f == 0 {
 						rtk.RtkSol.Ns++ /* valid satellite count by L1 */
 					}
@@ -2063,8 +2231,15 @@ func (rtk *Rtk) RelativePos(obs []ObsD, nu, nr int, nav *Nav) int {
 			rtk.RtkSol.Qv[4] = float32(rtk.P[5+4*rtk.Nx])
 			rtk.RtkSol.Qv[5] = float32(rtk.P[5+3*rtk.Nx])
 		}
+		rtk.RtkSol.NFixed = 0 /* fixed solution rejected */
 		rtk.Nfix = 0
 	}
+	/* baseline vector rover-base */
+	if stat != SOLQ_NONE && Norm(rtk.Rb[:], 3) > 0.0 {
+		for i = 0; i < 3; i++ {
+			rtk.RtkSol.Baseline[i] = rtk.RtkSol.Rr[i] - rtk.Rb[i]
+		}
+	}
 	for i = 0; i < n; i++ {
 		for j = 0; j < nf; j++ {
 			if obs[i].L[j] == 0.0 {
@@ -2072,6 +2247,7 @@ func (rtk *Rtk) RelativePos(obs []ObsD, nu, nr int, nav *Nav) int {
 			}
 			rtk.Ssat[obs[i].Sat-1].Pt[obs[i].Rcv-1][j] = obs[i].Time
 			rtk.Ssat[obs[i].Sat-1].Ph[obs[i].Rcv-1][j] = obs[i].L[j]
+			rtk.Ssat[obs[i].Sat-1].Dp[obs[i].Rcv-1][j] = obs[i].D[j]
 		}
 	}
 	for i = 0; i < ns; i++ {
@@ -2141,6 +2317,32 @@ func (rtk *Rtk) InitRtk(opt *PrcOpt) {
 	rtk.Opt = *opt
 }
 
+/* rtk filter states -----------------------------------------------------------
+* get a copy of the float states and their covariance after the last epoch
+* args   : none
+* return : x    float states {x[0],...,x[nx-1]}
+*          P    covariance of float states (nx x nx, row-major)
+* notes  : state index layout (see RNP(),RII(),RIT(),RIL(),RIB())
+*            0-2              : rover position x/y/z (ecef) (m)
+*            3-5,6-8          : velocity/acceleration (m/s|m/s^2) (dynamics on)
+*            RII(s)           : slant ionosphere delay of sat s (m) (est-stec)
+*            RIT(r)           : zenith wet delay (+gradients) rcv r (m) (est-ztd)
+*            RIL(f)           : GLONASS h/w bias freq f (m/MHz) (glo ar autocal)
+*            RIB(s,f)         : single-differenced phase bias of sat s freq f (m)
+*          states not estimated in the current options keep 0 in x and P
+*-----------------------------------------------------------------------------*/
+func (rtk *Rtk) State() ([]float64, []float64) {
+	x := make([]float64, rtk.Nx)
+	P := make([]float64, rtk.Nx*rtk.Nx)
+	copy(x, rtk.X)
+	for i := 0; i < rtk.Nx; i++ {
+		for j := 0; j < rtk.Nx; j++ {
+			P[i*rtk.Nx+j] = rtk.P[i+j*rtk.Nx]
+		}
+	}
+	return x, P
+}
+
 /* free rtk control ------------------------------------------------------------
 * free memory for rtk control struct
 * args   : rtk_t    *rtk    IO  rtk control/result struct
@@ -2156,6 +2358,32 @@ func (rtk *Rtk) FreeRtk() {
 	rtk.Pa = nil
 }
 
+/* set antenna parameters by the antenna list ----------------------------------
+* set the receiver antenna parameters of the antenna types (except for "*": by
+* station parameters) and the satellite antenna parameters for ppp
+*-----------------------------------------------------------------------------*/
+func (rtk *Rtk) setpcvlist(time Gtime, nav *Nav) {
+	opt := &rtk.Opt
+	for i := 0; i < 2; i++ {
+		if opt.AntType[i] == "*" {
+			continue
+		}
+		opt.Pcvr[i] = pcvrlist(time, opt, i)
+	}
+	if opt.Mode < PMODE_PPP_KINEMA {
+		return
+	}
+	for i := 0; i < MAXSAT; i++ {
+		nav.Pcvs[i] = Pcv{}
+		if SatSys(i+1, nil)&opt.NavSys == 0 {
+			continue
+		}
+		if pcv := opt.PcvList.SearchPcv(i+1, "", time); pcv != nil {
+			nav.Pcvs[i] = *pcv
+		}
+	}
+}
+
 /* precise positioning ---------------------------------------------------------
 * input observation data and navigation message, compute rover position by
 * precise positioning
@@ -2213,6 +2441,8 @@ func (rtk *Rtk) FreeRtk() {
 * return : status (0:no solution,1:valid solution)
 * notes  : before calling function, base station position rtk.sol.rb[] should
 *          be properly set for relative mode except for moving-baseline
+*          missing pseudoranges, carrier-phases and dopplers (see CleanObs) are
+*          excluded
 *-----------------------------------------------------------------------------*/
 func (rtk *Rtk) RtkPos(obs []ObsD, n int, nav *Nav) int {
 	var (
@@ -2224,9 +2454,16 @@ func (rtk *Rtk) RtkPos(obs []ObsD, n int, nav *Nav) int {
 	opt := &rtk.Opt
 
 	Trace(4, "rtkpos  : time=%s n=%d\n", TimeStr(obs[0].Time, 3), n)
+
+	/* exclude missing values (0.0, sentinels) */
+	obs, _ = CleanObs(obs, n)
 	Trace(4, "obs=\n")
 	traceobs(4, obs, n)
 
+	/* set antenna parameters by the antenna list */
+	if opt.PcvList != nil {
+		rtk.setpcvlist(obs[0].Time, nav)
+	}
 	/* set base staion position */
 	if opt.RefPos <= POSOPT_RINEX && opt.Mode != PMODE_SINGLE &&
 		opt.Mode != PMODE_MOVEB {
@@ -2244,6 +2481,8 @@ func (rtk *Rtk) RtkPos(obs []ObsD, n int, nav *Nav) int {
 	}
 
 	time = rtk.RtkSol.Time /* previous epoch */
+	rtk.RtkSol.Baseline = [3]float64{}
+	rtk.RtkSol.ARRatio, rtk.RtkSol.NFixed = 0.0, 0
 
 	/* rover position by single point positioning */
 	if PntPos(obs, nu, nav, &rtk.Opt, &rtk.RtkSol, nil, rtk.Ssat[:], &msg) == 0 {
@@ -0,0 +1,581 @@
+package gnssgo
+
+import (
+	"math"
+	"testing"
+)
+
+// gfSlipObs builds a rover/base pair for one GPS satellite whose single
+// differenced geometry-free phase equals gf (m).
+func gfSlipObs(t Gtime, sat int, gf float64) []ObsD {
+	lam1 := CLIGHT / FREQ1
+	obs := make([]ObsD, 2)
+	for i := range obs {
+		obs[i].Time = t
+		obs[i].Sat = sat
+		obs[i].Rcv = i + 1
+		obs[i].Code[0] = CODE_L1C
+		obs[i].Code[1] = CODE_L2W
+		obs[i].L[0] = 1000.0
+		obs[i].L[1] = 1000.0
+	}
+	/* one cycle of L2 single difference, L1 carries gf on top of it */
+	obs[0].L[1] += 1.0
+	obs[0].L[0] += (gf + CLIGHT/FREQ2) / lam1
+	return obs
+}
+
+// TestDetectSlpGfTimeGap checks that the geometry-free slip threshold grows
+// with the gap since the previous epoch.
+func TestDetectSlpGfTimeGap(t *testing.T) {
+	var nav Nav
+	sat := SatNo(SYS_GPS, 5)
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+
+	tests := []struct {
+		name string
+		gap  float64
+		slip bool
+	}{
+		{"1s gap", 1.0, true},
+		{"30s gap", 30.0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rtk Rtk
+			opt := DefaultProcOpt()
+			opt.Mode = PMODE_KINEMA
+			opt.Nf = 2
+			opt.ThresSlip = 0.05
+			opt.ThresSlipR = 0.01
+			rtk.InitRtk(&opt)
+
+			obs := gfSlipObs(t0, sat, 1.0)
+			rtk.DetectSlp_gf(obs, 0, 1, &nav)
+			rtk.Ssat[sat-1].Pt[0][0] = t0
+
+			/* 0.1 m GF change: above 0.05+0.01*1 but below 0.05+0.01*30 */
+			obs = gfSlipObs(TimeAdd(t0, tt.gap), sat, 1.1)
+			rtk.DetectSlp_gf(obs, 0, 1, &nav)
+
+			got := rtk.Ssat[sat-1].Slip[0]&1 == 1
+			if got != tt.slip {
+				t.Errorf("slip flag = %v, want %v (thres=%.3f)", got, tt.slip,
+					SlipThresGf(&rtk.Opt, tt.gap))
+			}
+		})
+	}
+}
+
+// synthRtk runs RtkPos over nep epochs of synthetic rover/base data.
+func synthRtk(t *testing.T, opt *PrcOpt, nep int) *Rtk {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	rtk := new(Rtk)
+	opt.Rb = synthBase
+	rtk.InitRtk(opt)
+	for k := 0; k < nep; k++ {
+		obs := synthEpoch(nav, TimeAdd(t0, float64(k)))
+		if rtk.RtkPos(obs, len(obs), nav) == 0 {
+			t.Fatalf("epoch %d: rtkpos failed: %s", k, rtk.ErrBuf)
+		}
+	}
+	return rtk
+}
+
+// TestRtkState checks the exported filter state against the solution.
+func TestRtkState(t *testing.T) {
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.ModeAr = ARMODE_OFF
+	opt.Elmin = 10.0 * D2R
+	rtk := synthRtk(t, &opt, 5)
+
+	x, P := rtk.State()
+	if len(x) != rtk.Nx || len(P) != rtk.Nx*rtk.Nx {
+		t.Fatalf("state dims = %d/%d, want %d/%d", len(x), len(P), rtk.Nx, rtk.Nx*rtk.Nx)
+	}
+	if rtk.RtkSol.Stat != SOLQ_FLOAT {
+		t.Fatalf("stat = %d, want float", rtk.RtkSol.Stat)
+	}
+	for i := 0; i < 3; i++ {
+		if x[i] != rtk.RtkSol.Rr[i] {
+			t.Errorf("x[%d] = %.4f, want sol %.4f", i, x[i], rtk.RtkSol.Rr[i])
+		}
+		if float32(P[i*rtk.Nx+i]) != rtk.RtkSol.Qr[i] {
+			t.Errorf("P[%d,%d] = %g, want sol %g", i, i, P[i*rtk.Nx+i], rtk.RtkSol.Qr[i])
+		}
+	}
+	/* float ambiguities after a few epochs: decimeter level */
+	if d := synthDist(x, synthRover[:]); d > 0.5 {
+		t.Errorf("position error = %.3f m", d)
+	}
+}
+
+// TestRtkPosBaseline checks the baseline of the RTK solution against the
+// known rover and base positions.
+func TestRtkPosBaseline(t *testing.T) {
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.ModeAr = ARMODE_OFF
+	opt.Elmin = 10.0 * D2R
+	rtk := synthRtk(t, &opt, 5)
+
+	sol := &rtk.RtkSol
+	if d := synthDist(sol.Baseline[:], []float64{synthRover[0] - synthBase[0],
+		synthRover[1] - synthBase[1], synthRover[2] - synthBase[2]}); d > 0.5 {
+		t.Errorf("baseline %v: %.3f m off", sol.Baseline, d)
+	}
+	if l, want := sol.BaselineLength(), synthDist(synthRover[:], synthBase[:]); math.Abs(l-want) > 0.5 {
+		t.Errorf("baseline length = %.3f, want %.3f", l, want)
+	}
+
+	/* no baseline for single point positioning */
+	opt = DefaultProcOpt()
+	rtk = synthRtk(t, &opt, 1)
+	if l := rtk.RtkSol.BaselineLength(); l != 0.0 {
+		t.Errorf("single: baseline length = %.3f, want 0", l)
+	}
+}
+
+// TestRtkPosInnoGate injects one epoch whose rover observations are taken 20 m
+// away from the static rover and checks that the filter does not follow it.
+func TestRtkPosInnoGate(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	jump := synthRover
+	jump[2] += 20.0
+
+	for _, reset := range []int{0, 1} {
+		opt := DefaultProcOpt()
+		opt.Mode = PMODE_STATIC
+		opt.ModeAr = ARMODE_OFF
+		opt.Elmin = 10.0 * D2R
+		opt.MaxPosInno = 10.0
+		opt.PosInnoRst = reset
+		opt.Rb = synthBase
+		rtk := new(Rtk)
+		rtk.InitRtk(&opt)
+
+		for k := 0; k < 20; k++ {
+			t1 := TimeAdd(t0, float64(k))
+			obs := synthEpoch(nav, t1)
+			if k == 10 {
+				obs = append(synthObs(nav, t1, jump, 1, 0.0), synthObs(nav, t1, synthBase, 2, 0.0)...)
+			}
+			rtk.RtkPos(obs, len(obs), nav)
+			if k == 10 || rtk.RtkSol.Stat != SOLQ_FLOAT {
+				continue
+			}
+			if d := synthDist(rtk.RtkSol.Rr[:], synthRover[:]); d > 1.0 {
+				t.Errorf("reset=%d epoch %d: position error = %.3f m", reset, k, d)
+			}
+		}
+		if rtk.NInnoRej != 1 {
+			t.Errorf("reset=%d: rejected epochs = %d, want 1", reset, rtk.NInnoRej)
+		}
+		if rtk.RtkSol.Stat != SOLQ_FLOAT {
+			t.Errorf("reset=%d: final stat = %d, want float", reset, rtk.RtkSol.Stat)
+		}
+	}
+}
+
+// TestCheckDopplerConsistency compares synthetic doppler against the phase
+// rate since the previous rtkpos epoch.
+func TestCheckDopplerConsistency(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+
+	tests := []struct {
+		name   string
+		modify func(obs *ObsD)
+		ok     bool
+	}{
+		{"consistent", func(obs *ObsD) {}, true},
+		{"doppler bias", func(obs *ObsD) { obs.D[1] += 20.0 }, false},
+		{"doppler sign", func(obs *ObsD) { obs.D[0] = -obs.D[0] }, false},
+		{"cycle slip", func(obs *ObsD) { obs.L[0] += 10.0 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := DefaultProcOpt()
+			opt.Mode = PMODE_KINEMA
+			opt.ModeAr = ARMODE_OFF
+			opt.Elmin = 10.0 * D2R
+			rtk := synthRtk(t, &opt, 3)
+
+			obs := synthEpoch(nav, TimeAdd(t0, 3.0))
+			for i := range obs {
+				o := obs[i]
+				if i == 0 {
+					tt.modify(&o)
+				}
+				ok, diff := rtk.CheckDopplerConsistency(&o, SatSys(o.Sat, nil))
+				want := tt.ok || i != 0
+				if ok != want {
+					t.Errorf("sat=%d rcv=%d: ok = %v, want %v (diff=%.3f m/s)", o.Sat, o.Rcv, ok, want, diff)
+				}
+				if want && diff > 0.01 {
+					t.Errorf("sat=%d rcv=%d: diff = %.4f m/s", o.Sat, o.Rcv, diff)
+				}
+			}
+		})
+	}
+}
+
+// TestRtkPosMaxTmDiff checks that base observations older than MaxTmDiff are
+// not used and the solution drops to single.
+func TestRtkPosMaxTmDiff(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+
+	tests := []struct {
+		age  float64
+		stat uint8
+	}{
+		{0.0, SOLQ_FLOAT},
+		{4.9, SOLQ_FLOAT},
+		{5.1, SOLQ_SINGLE},
+		{-5.1, SOLQ_SINGLE},
+	}
+	for _, tt := range tests {
+		opt := DefaultProcOpt()
+		opt.Mode = PMODE_KINEMA
+		opt.ModeAr = ARMODE_OFF
+		opt.Elmin = 10.0 * D2R
+		opt.OutSingle = 1
+		opt.MaxTmDiff = 5.0
+		opt.Rb = synthBase
+		rtk := new(Rtk)
+		rtk.InitRtk(&opt)
+
+		obs := synthObs(nav, t0, synthRover, 1, 0.0)
+		obs = append(obs, synthObs(nav, TimeAdd(t0, -tt.age), synthBase, 2, 0.0)...)
+		if rtk.RtkPos(obs, len(obs), nav) == 0 {
+			t.Fatalf("age=%.1f: rtkpos failed: %s", tt.age, rtk.ErrBuf)
+		}
+		if rtk.RtkSol.Stat != tt.stat {
+			t.Errorf("age=%.1f: stat = %d, want %d", tt.age, rtk.RtkSol.Stat, tt.stat)
+		}
+		if math.Abs(float64(rtk.RtkSol.Age)-tt.age) > 1e-3 {
+			t.Errorf("age=%.1f: sol age = %.3f", tt.age, rtk.RtkSol.Age)
+		}
+	}
+}
+
+// TestRtkPosMultiGNSSResiduals checks that the DD residuals of each system are
+// unbiased with a different receiver clock bias per system (time system
+// offsets and inter-system biases), which the DD per system must cancel. The
+// baseline is zero, so that the tropospheric delay of the model, missing in
+// the synthetic data, cancels.
+func TestRtkPosMultiGNSSResiduals(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	for k := 0; k < 12; k++ {
+		gal := synthEph(SatNo(SYS_GAL, k+1), k, TimeAdd(t0, -60.0)) /* AOD > 0 */
+		gal.M0 += 30.0 * D2R
+		gal.Code = 1<<0 | 1<<9                       /* I/NAV */
+		cmp := synthEph(SatNo(SYS_CMP, k+19), k, t0) /* MEO */
+		cmp.M0 += 60.0 * D2R
+		cmp.Toes = Time2BDT(GpsT2BDT(t0), nil)
+		nav.Ephs = append(nav.Ephs, gal, cmp)
+		nav.Geph = append(nav.Geph, synthGEph(SatNo(SYS_GLO, k+1), k+3, t0))
+	}
+	for k := 0; k < 6; k++ {
+		qzs := synthEph(SatNo(SYS_QZS, k+MINPRNQZS), k, t0)
+		qzs.M0 += 90.0 * D2R
+		nav.Ephs = append(nav.Ephs, qzs)
+	}
+	/* receiver clock bias per system (m) */
+	dtr := map[int]float64{SYS_GPS: 0.0, SYS_GLO: 150.0, SYS_GAL: 12.0, SYS_CMP: -40.0, SYS_QZS: 3.0}
+
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_STATIC
+	opt.ModeAr = ARMODE_FIXHOLD
+	opt.Elmin = 10.0 * D2R
+	opt.NavSys = SYS_GPS | SYS_GLO | SYS_GAL | SYS_CMP | SYS_QZS
+	opt.NoIter = 3 /* relinearize the tropospheric delay at the SPP position */
+	opt.Rb = synthBase
+	rtk := new(Rtk)
+	rtk.InitRtk(&opt)
+	defer rtk.FreeRtk()
+
+	for k := 0; k < 10; k++ {
+		tk := TimeAdd(t0, float64(k))
+		rov := synthObs(nav, tk, synthBase, 1, 0.0)
+		for i := range rov {
+			sys := SatSys(rov[i].Sat, nil)
+			freqs, _ := synthSignals(rov[i].Sat)
+			for f := 0; f < 2; f++ {
+				rov[i].P[f] += dtr[sys]
+				rov[i].L[f] += dtr[sys] * freqs[f] / CLIGHT
+			}
+		}
+		obs := append(rov, synthObs(nav, tk, synthBase, 2, 0.0)...)
+		if rtk.RtkPos(obs, len(obs), nav) == 0 {
+			t.Fatalf("epoch %d: rtkpos failed: %s", k, rtk.ErrBuf)
+		}
+	}
+	if d := synthDist(rtk.RtkSol.Rr[:], synthBase[:]); d > 0.01 {
+		t.Errorf("position error = %.4f m", d)
+	}
+
+	/* mean residuals per system */
+	for _, sys := range []int{SYS_GPS, SYS_GLO, SYS_GAL, SYS_CMP, SYS_QZS} {
+		var sump, sumc float64
+		n := 0
+		for i := 0; i < MAXSAT; i++ {
+			ssat := &rtk.Ssat[i]
+			if int(ssat.Sys) != sys || ssat.Vsat[0] == 0 {
+				continue
+			}
+			for f := 0; f < 2; f++ {
+				sump += float64(ssat.Resp[f])
+				sumc += float64(ssat.Resc[f])
+			}
+			n += 2
+		}
+		if n == 0 {
+			t.Errorf("sys=%d: no valid satellites", sys)
+			continue
+		}
+		if mp, mc := sump/float64(n), sumc/float64(n); math.Abs(mp) > 0.01 || math.Abs(mc) > 0.005 {
+			t.Errorf("sys=%d: mean residuals code=%.4f phase=%.4f m", sys, mp, mc)
+		}
+	}
+}
+
+// TestRtkPosMissingObs checks that zero and sentinel values of the rover and
+// base observations are ignored by the relative solution instead of breaking
+// the filter.
+func TestRtkPosMissingObs(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_STATIC
+	opt.ModeAr = ARMODE_OFF
+	opt.Elmin = 10.0 * D2R
+	opt.Rb = synthBase
+	rtk := new(Rtk)
+	rtk.InitRtk(&opt)
+
+	for k := 0; k < 10; k++ {
+		obs := synthEpoch(nav, TimeAdd(t0, float64(k)))
+		nu := len(obs) / 2
+		if k%2 == 1 { /* values missing in every other epoch */
+			obs[0].L[0] = math.NaN()
+			obs[1].L[1] = 1e12
+			obs[nu+2].P[0], obs[nu+3].P[1] = 0.0, -1.0
+		}
+		if rtk.RtkPos(obs, len(obs), nav) == 0 || rtk.RtkSol.Stat != SOLQ_FLOAT {
+			t.Fatalf("epoch %d: stat = %d, want float", k, rtk.RtkSol.Stat)
+		}
+	}
+	if d := synthDist(rtk.RtkSol.Rr[:], synthRover[:]); math.IsNaN(d) || d > 0.1 {
+		t.Errorf("position error = %.3f m", d)
+	}
+}
+
+// TestRtkNsFreq feeds dual-frequency observations with L2 missing for some
+// rover satellites and checks the number of valid satellites per frequency.
+func TestRtkNsFreq(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	sparse := func(obs []ObsD) []ObsD {
+		for i := range obs {
+			if obs[i].Rcv != 1 || obs[i].Sat%3 != 0 {
+				continue
+			}
+			obs[i].P[1], obs[i].L[1], obs[i].D[1], obs[i].Code[1] = 0.0, 0.0, 0.0, CODE_NONE
+		}
+		return obs
+	}
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.Nf = 2
+	opt.ModeAr = ARMODE_OFF
+	opt.Elmin = 10.0 * D2R
+	opt.Rb = synthBase
+
+	rtk := new(Rtk)
+	rtk.InitRtk(&opt)
+	var obs []ObsD
+	for k := 0; k < 3; k++ {
+		obs = sparse(synthEpoch(nav, TimeAdd(t0, float64(k))))
+		if rtk.RtkPos(obs, len(obs), nav) == 0 {
+			t.Fatalf("epoch %d: rtkpos failed: %s", k, rtk.ErrBuf)
+		}
+	}
+	var nl1, nl2 int
+	for i := range obs {
+		if obs[i].Rcv == 1 {
+			nl1++
+			if obs[i].Code[1] != CODE_NONE {
+				nl2++
+			}
+		}
+	}
+	sol := &rtk.RtkSol
+	if nl2 >= nl1 || int(sol.NsFreq[0]) != nl1 || int(sol.NsFreq[1]) != nl2 ||
+		sol.NsFreq[2] != 0 || sol.Ns != sol.NsFreq[0] {
+		t.Errorf("rtk: ns=%d nsfreq=%v, want %d %d 0", sol.Ns, sol.NsFreq, nl1, nl2)
+	}
+
+	/* single point positioning: iono-free LC needs both frequencies */
+	var (
+		spp Sol
+		msg string
+	)
+	rov := sparse(synthObs(nav, t0, synthRover, 1, 0.0))
+	opt = DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+	for _, ionoopt := range []int{IONOOPT_BRDC, IONOOPT_IFLC} {
+		opt.IonoOpt = ionoopt
+		if PntPos(rov, len(rov), nav, &opt, &spp, nil, nil, &msg) == 0 {
+			t.Fatalf("iono=%d: pntpos failed: %s", ionoopt, msg)
+		}
+		want := [NFREQ]uint8{uint8(nl1)}
+		if ionoopt == IONOOPT_IFLC {
+			want = [NFREQ]uint8{uint8(nl2), uint8(nl2)}
+		}
+		if spp.NsFreq != want || spp.Ns != want[0] {
+			t.Errorf("iono=%d: ns=%d nsfreq=%v, want %v", ionoopt, spp.Ns, spp.NsFreq, want)
+		}
+	}
+}
+
+// TestRtkPosCycleSlip injects a one-cycle slip on L2 of the rover for one
+// satellite and checks that the geometry-free and Melbourne-Wubbena detectors
+// flag it at that epoch only, and no other satellite.
+func TestRtkPosCycleSlip(t *testing.T) {
+	const nep, kslip = 6, 3
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+
+	tests := []struct {
+		name            string
+		thresGf, thresW float64
+	}{
+		{"geometry-free", 0.05, 0.0},
+		{"melbourne-wubbena", 1000.0, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := DefaultProcOpt()
+			opt.Mode = PMODE_KINEMA
+			opt.ModeAr = ARMODE_OFF
+			opt.Nf = 2
+			opt.Elmin = 10.0 * D2R
+			opt.ThresSlip = tt.thresGf
+			opt.ThresSlipMW = tt.thresW
+			opt.Rb = synthBase
+			rtk := new(Rtk)
+			rtk.InitRtk(&opt)
+
+			slipSat := 0
+			for k := 0; k < nep; k++ {
+				obs := synthEpoch(nav, TimeAdd(t0, float64(k)))
+				if slipSat == 0 {
+					slipSat = obs[0].Sat
+				}
+				if k >= kslip {
+					obs[0].L[1] += 1.0 /* rover L2 slip, kept afterwards */
+				}
+				if rtk.RtkPos(obs, len(obs), nav) == 0 {
+					t.Fatalf("epoch %d: rtkpos failed: %s", k, rtk.ErrBuf)
+				}
+				for i := 0; i < len(obs) && obs[i].Rcv == 1; i++ {
+					sat := obs[i].Sat
+					want := k == kslip && sat == slipSat
+					if got := rtk.Ssat[sat-1].Slip[1]&1 == 1; got != want {
+						t.Errorf("epoch %d sat %d: L2 slip flag %v, want %v", k, sat, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// arRtk sets up the filter with float single-differenced L1 ambiguities
+// 10*sat+frac[sat-1] (cycles) of variance 0.001 cycle^2 for GPS satellites
+// 1-5.
+func arRtk(frac [5]float64, thres float64) *Rtk {
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.ModeAr = ARMODE_CONT
+	opt.Nf = 1
+	opt.ThresAr[0] = thres
+	rtk := new(Rtk)
+	rtk.InitRtk(&opt)
+
+	for i := 0; i < rtk.Na; i++ {
+		rtk.X[i] = 1.0
+		rtk.P[i+i*rtk.Nx] = 0.01
+	}
+	for sat := 1; sat <= 5; sat++ {
+		ssat := &rtk.Ssat[sat-1]
+		ssat.Sys = SYS_GPS
+		ssat.Vsat[0], ssat.Half[0], ssat.Lock[0] = 1, 1, 1
+		ssat.Azel[1] = 45.0 * D2R
+
+		j := RIB(sat, 0, &rtk.Opt)
+		rtk.X[j] = float64(10*sat) + frac[sat-1]
+		rtk.P[j+j*rtk.Nx] = 0.001
+	}
+	return rtk
+}
+
+// TestResolveAmbRatio drives the LAMBDA ambiguity resolution with synthetic
+// float ambiguities and checks the ratio-test value and the fixed count.
+func TestResolveAmbRatio(t *testing.T) {
+	tests := []struct {
+		name   string
+		frac   [5]float64
+		thres  float64
+		nfixed int
+		pass   bool
+	}{
+		{"near integer", [5]float64{0.02, -0.03, 0.01, 0.04, -0.02}, 3.0, 4, true},
+		{"marginal", [5]float64{0.0, 0.45, 0.45, 0.45, 0.45}, 3.0, 0, false},
+		{"high threshold", [5]float64{0.02, -0.03, 0.01, 0.04, -0.02}, 1e6, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rtk := arRtk(tt.frac, tt.thres)
+			bias, xa := Mat(rtk.Nx, 1), Mat(rtk.Nx, 1)
+			nb := rtk.ResolveAmb_LAMBDA(bias, xa)
+
+			sol := &rtk.RtkSol
+			if nb != tt.nfixed || sol.NFixed != tt.nfixed {
+				t.Errorf("nb = %d, NFixed = %d, want %d", nb, sol.NFixed, tt.nfixed)
+			}
+			if sol.ARRatio <= 1.0 {
+				t.Errorf("ARRatio = %.3f, want > 1", sol.ARRatio)
+			}
+			if pass := sol.ARRatio >= tt.thres; pass != tt.pass {
+				t.Errorf("ARRatio = %.3f vs thres %.1f, pass = %v", sol.ARRatio, tt.thres, pass)
+			}
+			if sol.Thres != float32(tt.thres) {
+				t.Errorf("Thres = %g, want %g", sol.Thres, tt.thres)
+			}
+			if want := float32(math.Min(sol.ARRatio, 999.9)); sol.Ratio != want {
+				t.Errorf("Ratio = %g, want %g", sol.Ratio, want)
+			}
+			/* fixed double-differenced ambiguities are integers */
+			for sat := 2; sat <= 5 && tt.nfixed > 0; sat++ {
+				dd := xa[RIB(1, 0, &rtk.Opt)] - xa[RIB(sat, 0, &rtk.Opt)]
+				if want := float64(10 - 10*sat); math.Abs(dd-want) > 1e-6 {
+					t.Errorf("sat %d: fixed DD ambiguity = %.4f, want %.0f", sat, dd, want)
+				}
+			}
+		})
+	}
+
+	/* ambiguity resolution off */
+	rtk := arRtk([5]float64{}, 3.0)
+	rtk.Opt.ModeAr = ARMODE_OFF
+	if nb := rtk.ResolveAmb_LAMBDA(Mat(rtk.Nx, 1), Mat(rtk.Nx, 1)); nb != 0 ||
+		rtk.RtkSol.NFixed != 0 || rtk.RtkSol.ARRatio != 0.0 {
+		t.Errorf("AR off: nb = %d, NFixed = %d, ARRatio = %.3f", nb, rtk.RtkSol.NFixed, rtk.RtkSol.ARRatio)
+	}
+}
@@ -57,6 +57,26 @@ type RBSol struct {
 	Sol Sol
 }
 
+/* solution output format of SetSolutionOutput */
+type SolFormat int
+
+const (
+	SOLFMT_LLH  SolFormat = iota /* lat/lon/height records */
+	SOLFMT_ECEF                  /* x/y/z-ecef records */
+	SOLFMT_NMEA                  /* NMEA-183 RMC and GGA sentences */
+	SOLFMT_POS                   /* .pos file: header and lat/lon/height records */
+)
+
+/* writer of formatted solutions, e.g. a Stream, a file or a SolWriterFunc */
+type SolWriter interface {
+	Write(p []byte) (int, error)
+}
+
+/* function as solution writer, called with the formatted solution */
+type SolWriterFunc func(p []byte) (int, error)
+
+func (f SolWriterFunc) Write(p []byte) (int, error) { return f(p) }
+
 var ObsChannel chan ObsD
 var RbSolChannel chan RBSol
 
@@ -96,7 +116,7 @@ func (svr *RtkSvr) WriteSol(index int) {
 	Tracet(4, "writesol: index=%d\n", index)
 
 	for i = 0; i < 2; i++ {
-
+		buff = ""
 		if svr.Solopt[i].Posf == int(SOLF_STAT) {
 
 			/* output solution status */
@@ -113,6 +133,7 @@ func (svr *RtkSvr) WriteSol(index int) {
 		svr.SaveOutBuf([]byte(buff), n, i)
 
 		/* output extended solution */
+		buff = ""
 		n = svr.RtkCtrl.RtkSol.OutSolExs(&buff, svr.RtkCtrl.Ssat[:], &svr.Solopt[i])
 		svr.Stream[i+3].StreamWrite([]byte(buff), n)
 
@@ -121,9 +142,20 @@ func (svr *RtkSvr) WriteSol(index int) {
 	}
 	/* output solution to monitor port */
 	if svr.Monitor != nil {
+		buff = ""
 		n = svr.RtkCtrl.RtkSol.OutSols(&buff, svr.RtkCtrl.Rb[:], &solopt)
 		svr.Monitor.StreamWrite([]byte(buff), n)
 	}
+	/* output solution to solution writer */
+	svr.RtkSvrLock()
+	w, wopt := svr.SolOut, svr.SolOutOpt
+	svr.RtkSvrUnlock()
+	if w != nil {
+		buff = ""
+		if n = svr.RtkCtrl.RtkSol.OutSols(&buff, svr.RtkCtrl.Rb[:], &wopt); n > 0 {
+			w.Write([]byte(buff))
+		}
+	}
 	/* save solution buffer */
 	if svr.NoSol < MAXSOLBUF {
 		svr.RtkSvrLock()
@@ -642,19 +674,21 @@ func rtksvrthread(svr *RtkSvr) int {
 
 	Tracet(3, "rtksvrthread:\n")
 
-	svr.State = 1
 	obs.Data = data
 	svr.Tick = uint32(TickGet())
 	ticknmea, tick1hz = svr.Tick-1000, svr.Tick-1000
 	tickreset = svr.Tick - uint32(MIN_INT_RESET)
 
-	for cycle = 0; svr.State > 0; cycle++ {
+	for cycle = 0; svr.running(); cycle++ {
 		tick = uint32(TickGet())
 		for i = 0; i < 3; i++ {
 			p := &svr.Nb[i]
 
-			/* read receiver raw/rtcm data from input stream */
-			if n = svr.Stream[i].StreamRead(svr.Buff[i][*p:], svr.BuffSize-*p); n <= 0 {
+			/* read receiver raw/rtcm data from input stream and fed data */
+			if n = svr.Stream[i].StreamRead(svr.Buff[i][*p:], svr.BuffSize-*p); n < 0 {
+				n = 0
+			}
+			if n += svr.readFeed(i, svr.Buff[i][*p+n:]); n <= 0 {
 				continue
 			}
 			// if n = svr.Stream[i].StreamRead(svr.Buff[i][svr.Nb[i]:], svr.BuffSize-svr.Nb[i]); n <= 0 {
@@ -714,9 +748,9 @@ func rtksvrthread(svr *RtkSvr) int {
 			}
 
 			/* send obs data to channel */
-			for i = 0; i < obs.N(); i++ {
+			for j = 0; j < obs.N(); j++ {
 				select {
-				case ObsChannel <- obs.Data[i]:
+				case ObsChannel <- obs.Data[j]:
 				default:
 				}
 			}
@@ -775,6 +809,11 @@ func rtksvrthread(svr *RtkSvr) int {
 		svr.Buff[i] = nil
 		svr.PBuf[i] = nil
 	}
+	svr.FeedLock.Lock()
+	for i = 0; i < 3; i++ {
+		svr.Feed[i] = nil
+	}
+	svr.FeedLock.Unlock()
 	for i = 0; i < 2; i++ {
 		svr.Nsb[i] = 0
 		svr.SBuf[i] = nil
@@ -920,6 +959,13 @@ func (svr *RtkSvr) FreeRtkSvr() {
 func (svr *RtkSvr) RtkSvrLock()   { svr.Lock.Lock() }
 func (svr *RtkSvr) RtkSvrUnlock() { svr.Lock.Unlock() }
 
+/* rtk server running (state read under lock against stop) -------------------*/
+func (svr *RtkSvr) running() bool {
+	svr.RtkSvrLock()
+	defer svr.RtkSvrUnlock()
+	return svr.State > 0
+}
+
 /* start rtk server ------------------------------------------------------------
 * start rtk server thread
 * args   : svr *RtkSvr    IO rtk server
@@ -1125,7 +1171,8 @@ func (svr *RtkSvr) RtkSvrStart(cycle, buffsize int, strs []int,
 	ObsChannel = make(chan ObsD, MAXOBSBUF)
 	RbSolChannel = make(chan RBSol, 10)
 
-	/* create rtk server thread */
+	/* create rtk server thread, running before a stop can clear the state */
+	svr.State = 1
 	svr.Wg.Add(1)
 	go rtksvrthread(svr)
 	// #ifdef WIN32
@@ -1159,12 +1206,58 @@ func (svr *RtkSvr) RtkSvrStop(cmds []string) {
 	}
 	svr.RtkSvrUnlock()
 
+	/* stop rtk server, the thread reads the state under lock */
+	svr.RtkSvrLock()
+	svr.State = 0
+	svr.RtkSvrUnlock()
+	svr.Wg.Wait() // wait for thread exit
+
 	close(ObsChannel)
 	close(RbSolChannel)
+}
 
-	/* stop rtk server */
-	svr.State = 0
-	svr.Wg.Wait() // wait for thread exit
+/* feed rover/base input data --------------------------------------------------
+* feed receiver raw/rtcm data to rtk server without input stream
+* args   : svr *RtkSvr    IO rtk server
+*          uint8_t *data    I  receiver raw/rtcm data in rover/base input format
+* return : none
+* notes  : the data are queued and decoded by the server thread in the next
+*          processing cycles after the data read from the input stream. the
+*          call does not wait for the processing. data fed to a stopped server
+*          are decoded after it is started, pending data are discarded when
+*          the server is stopped.
+*          the queue holds up to the input buffer size (4096 bytes before the
+*          server is started). data not fitting in the queue are discarded.
+*-----------------------------------------------------------------------------*/
+func (svr *RtkSvr) FeedRover(data []uint8) { svr.feed(0, data) }
+func (svr *RtkSvr) FeedBase(data []uint8)  { svr.feed(1, data) }
+
+func (svr *RtkSvr) feed(index int, data []uint8) {
+	svr.FeedLock.Lock()
+	defer svr.FeedLock.Unlock()
+
+	size := svr.BuffSize
+	if size < 4096 {
+		size = 4096
+	}
+	if len(svr.Feed[index])+len(data) > size {
+		Tracet(2, "rtksvr feed queue full: index=%d n=%d queued=%d\n", index,
+			len(data), len(svr.Feed[index]))
+		return
+	}
+	svr.Feed[index] = append(svr.Feed[index], data...)
+}
+
+/* read fed data to input buffer ---------------------------------------------*/
+func (svr *RtkSvr) readFeed(index int, buff []uint8) int {
+	svr.FeedLock.Lock()
+	defer svr.FeedLock.Unlock()
+
+	n := copy(buff, svr.Feed[index])
+	if svr.Feed[index] = svr.Feed[index][n:]; len(svr.Feed[index]) == 0 {
+		svr.Feed[index] = nil
+	}
+	return n
 }
 
 /* open output/log stream ------------------------------------------------------
@@ -1226,6 +1319,38 @@ func (svr *RtkSvr) RtkSvrCloseStream(index int) {
 	svr.RtkSvrUnlock()
 }
 
+/* set solution output ---------------------------------------------------------
+* set writer of the solutions at the solution rate in addition to the
+* solution streams
+* args   : svr *RtkSvr    IO rtk server
+*          SolWriter w      I  solution writer (nil: no output)
+*          SolFormat format I  solution format (SOLFMT_???)
+* return : none
+* notes  : the header of SOLFMT_POS is written to w immediately.
+*          w is called from the server thread.
+*-----------------------------------------------------------------------------*/
+func (svr *RtkSvr) SetSolutionOutput(w SolWriter, format SolFormat) {
+	var buff string
+
+	Tracet(3, "rtksvrsetsolout: format=%d\n", format)
+
+	opt := DefaultSolOpt() /* lat/lon/height */
+	switch format {
+	case SOLFMT_ECEF:
+		opt.Posf = SOLF_XYZ
+	case SOLFMT_NMEA:
+		opt.Posf = SOLF_NMEA
+	}
+	if w != nil && format == SOLFMT_POS {
+		if n := OutSolHeader(&buff, &opt); n > 0 {
+			w.Write([]byte(buff))
+		}
+	}
+	svr.RtkSvrLock()
+	svr.SolOut, svr.SolOutOpt = w, opt
+	svr.RtkSvrUnlock()
+}
+
 /* get observation data status -------------------------------------------------
 * get current observation data status
 * args   : svr *RtkSvr    I  rtk server
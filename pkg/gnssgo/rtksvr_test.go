@@ -0,0 +1,199 @@
+package gnssgo
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// synthRtcm3 encodes a message of the given type with the RTCM 3 encoder.
+func synthRtcm3(t *testing.T, enc *Rtcm, ctype int) []uint8 {
+	t.Helper()
+	if enc.GenRtcm3(ctype, 0, 0) == 0 {
+		t.Fatalf("encode rtcm3 %d failed", ctype)
+	}
+	return append([]uint8(nil), enc.Buff[:enc.Nbyte]...)
+}
+
+// startRtkSvrFeed starts the server in kinematic mode without input streams
+// and feeds the ephemerides. It returns the time of the first epoch and the
+// RTCM 3 observations of the base station and the rover for 3 epochs.
+func startRtkSvrFeed(t *testing.T, svr *RtkSvr) (t0 Gtime, base, rover [3][]uint8) {
+	var errmsg string
+
+	/* the server decodes RTCM 3 times near the current time, toe of 1019 has
+	   16 s resolution */
+	t0 = Utc2GpsT(TimeGet())
+	t0.Time, t0.Sec = t0.Time-t0.Time%16, 0.0
+	nav := synthNav(t0, 24)
+
+	var (
+		encb, encr Rtcm
+		eph        []uint8
+	)
+	encb.InitRtcm()
+	encr.InitRtcm()
+	for i := range nav.Ephs {
+		encb.NavData.Ephs[nav.Ephs[i].Sat-1] = nav.Ephs[i]
+		encb.EphSat = nav.Ephs[i].Sat
+		eph = append(eph, synthRtcm3(t, &encb, 1019)...)
+	}
+	for k := range base {
+		tk := TimeAdd(t0, float64(k))
+		encb.Time, encr.Time = tk, tk
+		encb.ObsData.Data = synthObs(nav, tk, synthBase, 2, 0.0)
+		base[k] = synthRtcm3(t, &encb, 1077)
+		encr.ObsData.Data = synthObs(nav, tk, synthRover, 1, 0.0)
+		rover[k] = synthRtcm3(t, &encr, 1077)
+	}
+
+	opt := DefaultProcOpt()
+	opt.Mode = PMODE_KINEMA
+	opt.Elmin = 10.0 * D2R
+	opt.RefPos = POSOPT_POS
+	opt.Rb = synthBase
+	strs := make([]int, 8) /* STR_NONE */
+	paths := make([]string, 8)
+	formats := []int{STRFMT_RTCM3, STRFMT_RTCM3, STRFMT_RTCM3}
+	cmds := make([]string, 3)
+	solopt := []SolOpt{DefaultSolOpt(), DefaultSolOpt()}
+
+	svr.InitRtkSvr()
+	t.Cleanup(svr.FreeRtkSvr)
+
+	/* ephemerides are fed before start, observations epoch by epoch */
+	svr.FeedBase(eph)
+	if svr.RtkSvrStart(10, 32768, strs, paths, formats, 0, cmds, cmds, cmds, 0, 0,
+		[]float64{0, 0, 0}, &opt, solopt, nil, &errmsg) == 0 {
+		t.Fatalf("rtksvrstart failed: %s", errmsg)
+	}
+	t.Cleanup(func() {
+		if svr.State > 0 {
+			svr.RtkSvrStop(cmds)
+		}
+	})
+	return
+}
+
+// TestRtkSvrFeed checks that RTCM 3 data fed with FeedRover and FeedBase
+// are decoded and produce RTK solutions without input streams.
+func TestRtkSvrFeed(t *testing.T) {
+	var svr RtkSvr
+	t0, base, rover := startRtkSvrFeed(t, &svr)
+
+	for k := range rover {
+		var sol Sol
+		svr.FeedBase(base[k])
+		half := len(rover[k]) / 2
+		svr.FeedRover(rover[k][:half])
+		svr.FeedRover(rover[k][half:])
+
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			svr.RtkSvrLock()
+			sol = svr.RtkCtrl.RtkSol
+			svr.RtkSvrUnlock()
+			if sol.Stat != SOLQ_NONE && math.Abs(TimeDiff(sol.Time, t0)-float64(k)) < 1e-3 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if dt := TimeDiff(sol.Time, t0); sol.Stat == SOLQ_NONE || math.Abs(dt-float64(k)) >= 1e-3 {
+			t.Fatalf("epoch %d: no solution (stat=%d t0+%.3f s)", k, sol.Stat, dt)
+		}
+		if sol.Stat != SOLQ_FIX && sol.Stat != SOLQ_FLOAT {
+			t.Errorf("epoch %d: stat = %d, want float or fix", k, sol.Stat)
+		}
+		if d := synthDist(sol.Rr[:], synthRover[:]); d > 0.1 {
+			t.Errorf("epoch %d: position error = %.4f m", k, d)
+		}
+	}
+}
+
+// TestRtkSvrSolutionOutput checks that the solutions are written to the
+// solution writer in the format set, as .pos records and NMEA sentences.
+func TestRtkSvrSolutionOutput(t *testing.T) {
+	var (
+		svr  RtkSvr
+		lock sync.Mutex
+		out  []string
+	)
+	t0, base, rover := startRtkSvrFeed(t, &svr)
+	w := SolWriterFunc(func(p []byte) (int, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		out = append(out, strings.SplitAfter(string(p), "\n")...)
+		if out[len(out)-1] == "" {
+			out = out[:len(out)-1]
+		}
+		return len(p), nil
+	})
+	lines := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), out...)
+	}
+
+	svr.SetSolutionOutput(w, SOLFMT_POS)
+	nhead := len(lines())
+	if nhead == 0 || !strings.HasPrefix(lines()[0], "%") {
+		t.Fatalf("pos header: got %q", lines())
+	}
+	for k := range rover {
+		if k == 2 {
+			svr.SetSolutionOutput(w, SOLFMT_NMEA)
+		}
+		svr.FeedBase(base[k])
+		svr.FeedRover(rover[k])
+
+		want := nhead + k + 1
+		if k == 2 {
+			want++ /* RMC and GGA */
+		}
+		for deadline := time.Now().Add(5 * time.Second); len(lines()) < want && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := len(lines()); n != want {
+			t.Fatalf("epoch %d: %d lines, want %d: %q", k, n, want, lines())
+		}
+	}
+
+	got := lines()[nhead:]
+	for k := 0; k < 2; k++ {
+		var ts string
+		Time2Str(TimeAdd(t0, float64(k)), &ts, 3)
+		fields := strings.Fields(got[k])
+		if len(fields) < 6 || fields[0]+" "+fields[1] != ts || (fields[5] != "1" && fields[5] != "2") {
+			t.Errorf("pos record %d: got %q, want time %s and fix or float", k, got[k], ts)
+		}
+	}
+	if !strings.HasPrefix(got[2], "$"+NMEA_TID+"RMC") || !strings.HasPrefix(got[3], "$"+NMEA_TID+"GGA") {
+		t.Errorf("nmea: got %q", got[2:])
+	}
+
+	/* no output after the writer is removed */
+	svr.SetSolutionOutput(nil, SOLFMT_LLH)
+	svr.WriteSol(0)
+	if n := len(lines()); n != nhead+4 {
+		t.Errorf("output without writer: %d lines, want %d", n, nhead+4)
+	}
+}
+
+// TestRtkSvrFeedLimit checks that data fed to a stopped server are discarded
+// once the queue is full.
+func TestRtkSvrFeedLimit(t *testing.T) {
+	var svr RtkSvr
+	svr.InitRtkSvr()
+	defer svr.FreeRtkSvr()
+
+	svr.FeedRover(make([]uint8, 3000))
+	svr.FeedRover(make([]uint8, 2000)) /* exceeds 4096 bytes: discarded */
+	svr.FeedRover(make([]uint8, 1000))
+	if n := len(svr.Feed[0]); n != 4000 {
+		t.Errorf("rover queue = %d bytes, want 4000", n)
+	}
+	if n := len(svr.Feed[1]); n != 0 {
+		t.Errorf("base queue = %d bytes, want 0", n)
+	}
+}
@@ -148,7 +148,7 @@ func decode_stqtime(raw *Raw) int {
 
 	raw.Iod = int(U1(raw.Buff[idx+1:]))
 	week = int(U2(raw.Buff[idx+2:]))
-	week = AdjGpsWeek(week)
+	week = AdjGpsWeekRef(week, raw.Time)
 	tow = float64(U4(raw.Buff[idx+4:])) * 0.001
 	raw.Time = GpsT2Time(week, tow)
 
@@ -277,7 +277,7 @@ func decode_stqrawx(raw *Raw) int {
 	_ = U1(raw.Buff[idx+1:]) /* ver */
 	raw.Iod = int(U1(raw.Buff[idx+2:]))
 	week = int(U2(raw.Buff[idx+3:]))
-	week = AdjGpsWeek(week)
+	week = AdjGpsWeekRef(week, raw.Time)
 	tow = float64(U4(raw.Buff[idx+5:])) * 0.001
 	raw.Time = GpsT2Time(week, tow)
 	_ = float64(U2(raw.Buff[idx+9:])) * 0.001 /* peri */
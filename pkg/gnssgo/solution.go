@@ -180,6 +180,45 @@ func (sol *Sol) CovarianceVel2Sol(P []float64) {
 	sol.Qv[5] = float32(P[2]) /* zx */
 }
 
+/* solution quality code and name --------------------------------------------
+* return the RTKLIB quality code (Q) of the solution as written in .pos files
+* (0:none,1:fix,2:float,3:sbas,4:dgps,5:single,6:ppp,7:dr) and its name.
+* an undefined status is reported as none.
+*-----------------------------------------------------------------------------*/
+var solqName = [...]string{"NONE", "FIX", "FLOAT", "SBAS", "DGPS", "SINGLE", "PPP", "DR"}
+
+func (sol *Sol) QCode() int {
+	if int(sol.Stat) >= len(solqName) {
+		return SOLQ_NONE
+	}
+	return int(sol.Stat)
+}
+func (sol *Sol) QName() string {
+	return solqName[sol.QCode()]
+}
+
+/* baseline length and components ---------------------------------------------
+* length of the baseline vector rover-base (m) and its components {n,e,d} (m)
+* in the local frame of the base station. 0 without baseline (no base position
+* or not relative positioning).
+*-----------------------------------------------------------------------------*/
+func (sol *Sol) BaselineLength() float64 {
+	return Norm(sol.Baseline[:], 3)
+}
+func (sol *Sol) BaselineNED() [3]float64 {
+	var rb, pos, enu [3]float64
+
+	if sol.BaselineLength() <= 0.0 {
+		return [3]float64{}
+	}
+	for i := 0; i < 3; i++ {
+		rb[i] = sol.Rr[i] - sol.Baseline[i]
+	}
+	Ecef2Pos(rb[:], pos[:])
+	Ecef2Enu(pos[:], sol.Baseline[:], enu[:])
+	return [3]float64{enu[1], enu[0], -enu[2]}
+}
+
 /* decode NMEA RMC (Recommended Minumum Specific GNSS Data) sentence ---------*/
 func (sol *Sol) DecodeNmeaRmc(val []string, n int) int {
 	var (
@@ -538,6 +577,17 @@ func DecodeSolTime(buff string, opt *SolOpt, time *Gtime) string {
 	return ""
 }
 
+/* decode number of valid satellites per frequency in the last fields --------*/
+func (sol *Sol) decodensfreq(val []float64, n int, opt *SolOpt) int {
+	if opt.OutNsFreq == 0 || n < 3+NFREQ {
+		return n
+	}
+	for f := 0; f < NFREQ; f++ {
+		sol.NsFreq[f] = uint8(val[n-NFREQ+f])
+	}
+	return n - NFREQ
+}
+
 /* decode x/y/z-ecef ---------------------------------------------------------*/
 func (sol *Sol) DecodeSolXyz(buff string, opt *SolOpt) int {
 	var (
@@ -552,6 +602,7 @@ func (sol *Sol) DecodeSolXyz(buff string, opt *SolOpt) int {
 	if n = tonum(buff, sep, val[:]); n < 3 {
 		return 0
 	}
+	n = sol.decodensfreq(val[:], n, opt)
 
 	for j = 0; j < 3; j++ {
 		sol.Rr[j] = val[i] /* xyz */
@@ -644,6 +695,7 @@ func (sol *Sol) DecodeSolLatLonHeight(buff string, opt *SolOpt) int {
 	Trace(4, "decode_solllh:\n")
 
 	n = tonum(buff, sep, val[:])
+	n = sol.decodensfreq(val[:], n, opt)
 
 	if opt.DegF == 0 {
 		if n < 3 {
@@ -758,6 +810,7 @@ func (sol *Sol) DecodeSolEnu(buff string, opt *SolOpt) int {
 	if n = tonum(buff, sep, val[:]); n < 3 {
 		return 0
 	}
+	n = sol.decodensfreq(val[:], n, opt)
 
 	for j = 0; j < 3; j++ {
 		sol.Rr[j] = val[i]
@@ -839,6 +892,7 @@ func (sol *Sol) DecodeSolSss(buff []byte) int {
 	}
 	sol.Ns = 0
 	sol.Age, sol.Ratio, sol.Thres = 0.0, 0.0, 0.0
+	sol.ARRatio, sol.NFixed = 0.0, 0
 	sol.Type = 0 /* position type = xyz */
 	sol.Stat = uint8(solq)
 	return 1
@@ -1429,6 +1483,18 @@ func (statbuf *SolStatBuf) ReadSolStat(files []string, nfile int) int {
 	return statbuf.ReadSolStatt(files, nfile, time, time, 0.0)
 }
 
+/* output number of valid satellites per frequency ---------------------------*/
+func outnsfreq(sol *Sol, sep string, opt *SolOpt) string {
+	var p string
+	if opt.OutNsFreq == 0 {
+		return p
+	}
+	for f := 0; f < NFREQ; f++ {
+		p += fmt.Sprintf("%s%4d", sep, sol.NsFreq[f])
+	}
+	return p
+}
+
 /* output solution as the form of x/y/z-ecef ---------------------------------*/
 func OutEcef(buff *string, s string, sol *Sol, opt *SolOpt) int {
 	sep := opt2sep(opt)
@@ -1436,7 +1502,7 @@ func OutEcef(buff *string, s string, sol *Sol, opt *SolOpt) int {
 	Trace(4, "outecef:\n")
 	p := *buff
 	p += fmt.Sprintf("%s%s%14.4f%s%14.4f%s%14.4f%s%3d%s%3d%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%6.2f%s%6.1f",
-		s, sep, sol.Rr[0], sep, sol.Rr[1], sep, sol.Rr[2], sep, sol.Stat, sep,
+		s, sep, sol.Rr[0], sep, sol.Rr[1], sep, sol.Rr[2], sep, sol.QCode(), sep,
 		sol.Ns, sep, SQRT32(sol.Qr[0]), sep, SQRT32(sol.Qr[1]), sep,
 		SQRT32(sol.Qr[2]), sep, sqvar(float64(sol.Qr[3])), sep, sqvar(float64(sol.Qr[4])), sep,
 		sqvar(float64(sol.Qr[5])), sep, sol.Age, sep, sol.Ratio)
@@ -1448,6 +1514,7 @@ func OutEcef(buff *string, s string, sol *Sol, opt *SolOpt) int {
 			sep, sqvar(float64(sol.Qv[3])), sep, sqvar(float64(sol.Qv[4])), sep,
 			sqvar(float64(sol.Qv[5])))
 	}
+	p += outnsfreq(sol, sep, opt)
 	p += "\r\n"
 
 	n := len(p) - len(*buff)
@@ -1482,7 +1549,7 @@ func (sol *Sol) OutSolPos(buff *string, s string, opt *SolOpt) int {
 		p += fmt.Sprintf("%s%s%14.9f%s%14.9f", s, sep, pos[0]*R2D, sep, pos[1]*R2D)
 	}
 	p += fmt.Sprintf("%s%10.4f%s%3d%s%3d%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%6.2f%s%6.1f",
-		sep, pos[2], sep, sol.Stat, sep, sol.Ns, sep, SQRT(Q[4]), sep,
+		sep, pos[2], sep, sol.QCode(), sep, sol.Ns, sep, SQRT(Q[4]), sep,
 		SQRT(Q[0]), sep, SQRT(Q[8]), sep, sqvar(Q[1]), sep, sqvar(Q[2]),
 		sep, sqvar(Q[5]), sep, sol.Age, sep, sol.Ratio)
 
@@ -1495,6 +1562,7 @@ func (sol *Sol) OutSolPos(buff *string, s string, opt *SolOpt) int {
 			SQRT(Q[0]), sep, SQRT(Q[8]), sep, sqvar(Q[1]), sep, sqvar(Q[2]),
 			sep, sqvar(Q[5]))
 	}
+	p += outnsfreq(sol, sep, opt)
 	p += "\r\n"
 	n := len(p) - len(*buff)
 	*buff = p
@@ -1519,10 +1587,12 @@ func (sol *Sol) OutSolEnu(buff *string, s string, rb []float64, opt *SolOpt) int
 	sol.Sol2Cov(P[:])
 	Cov2Enu(pos[:], P[:], Q[:])
 	Ecef2Enu(pos[:], rr[:], enu[:])
-	p += fmt.Sprintf("%s%s%14.4f%s%14.4f%s%14.4f%s%3d%s%3d%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%6.2f%s%6.1f\r\n",
-		s, sep, enu[0], sep, enu[1], sep, enu[2], sep, sol.Stat, sep, sol.Ns, sep,
+	p += fmt.Sprintf("%s%s%14.4f%s%14.4f%s%14.4f%s%3d%s%3d%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%8.4f%s%6.2f%s%6.1f",
+		s, sep, enu[0], sep, enu[1], sep, enu[2], sep, sol.QCode(), sep, sol.Ns, sep,
 		SQRT(Q[0]), sep, SQRT(Q[4]), sep, SQRT(Q[8]), sep, sqvar(Q[1]),
 		sep, sqvar(Q[5]), sep, sqvar(Q[2]), sep, sol.Age, sep, sol.Ratio)
+	p += outnsfreq(sol, sep, opt)
+	p += "\r\n"
 	n := len(p) - len(*buff)
 	*buff = p
 	return n
@@ -1536,8 +1606,6 @@ func (sol *Sol) OutSolNmeaRmc(buff *string) int {
 		ep                    [6]float64
 		pos, enuv, dms1, dms2 [3]float64
 		vel, dir, amag        float64
-		i                     int
-		sum                   uint8
 		emag, mode, status    string = "E", "A", "V"
 	)
 	p := *buff
@@ -1545,12 +1613,7 @@ func (sol *Sol) OutSolNmeaRmc(buff *string) int {
 	Trace(4, "outnmea_rmc:\n")
 
 	if sol.Stat <= SOLQ_NONE {
-		p += fmt.Sprintf("$%sRMC,,,,,,,,,,,,,", NMEA_TID)
-		for i = 1; i < len(p); i++ {
-			sum ^= p[i]
-		}
-
-		p += fmt.Sprintf("*%02X%c%c", sum, 0x0D, 0x0A)
+		p += nmeacsum(fmt.Sprintf("$%sRMC,,,,,,,,,,,,,", NMEA_TID))
 		n := len(p) - len(*buff)
 		*buff = p
 		return n
@@ -1594,15 +1657,10 @@ func (sol *Sol) OutSolNmeaRmc(buff *string) int {
 	} else {
 		pos2 = "W"
 	}
-	p += fmt.Sprintf("$%sRMC,%02.0f%02.0f%05.2f,A,%02.0f%010.7f,%s,%03.0f%010.7f,%s,%4.2f,%4.2f,%02.0f%02.0f%02d,%.1f,%s,%s,%s",
+	p += nmeacsum(fmt.Sprintf("$%sRMC,%02.0f%02.0f%05.2f,A,%02.0f%010.7f,%s,%03.0f%010.7f,%s,%4.2f,%4.2f,%02.0f%02.0f%02d,%.1f,%s,%s,%s",
 		NMEA_TID, ep[3], ep[4], ep[5], dms1[0], dms1[1]+dms1[2]/60.0,
 		pos1, dms2[0], dms2[1]+dms2[2]/60.0, pos2,
-		vel/KNOT2M, dir, ep[2], ep[1], int(math.Mod(ep[0], 100.0)), amag, emag, mode, status)
-	for i = 1; i < len(p); i++ {
-		sum ^= p[i]
-	}
-
-	p += fmt.Sprintf("*%02X\r\n", sum)
+		vel/KNOT2M, dir, ep[2], ep[1], int(math.Mod(ep[0], 100.0)), amag, emag, mode, status))
 	n := len(p) - len(*buff)
 	*buff = p
 	return n
@@ -1610,28 +1668,25 @@ func (sol *Sol) OutSolNmeaRmc(buff *string) int {
 
 /* output solution in the form of NMEA GGA sentence --------------------------*/
 func (sol *Sol) OutSolNmeaGga(buff *string) int {
+	Trace(4, "outnmea_gga:\n")
+
+	p := sol.nmeagga(1.0)
+	*buff += p
+	return len(p)
+}
+
+/* NMEA GGA sentence of solution with hdop (0: no hdop) ----------------------*/
+func (sol *Sol) nmeagga(dop float64) string {
 	var (
 		time            Gtime
-		h, dop          float64 = 0.0, 1.0
+		h               float64
 		ep              [6]float64
 		pos, dms1, dms2 [3]float64
 		solq, refid     int = 0, 0
-		sum             uint8
-		i               int
+		hdop, diff      string
 	)
-	p := *buff
-
-	Trace(4, "outnmea_gga:\n")
-
 	if sol.Stat <= SOLQ_NONE {
-		p += fmt.Sprintf("$%sGGA,,,,,,,,,,,,,,", NMEA_TID)
-		for i = 1; i < len(p); i++ {
-			sum ^= p[i]
-		}
-		p += fmt.Sprintf("*%02X%c%c", sum, 0x0D, 0x0A)
-		n := len(p) - len(*buff)
-		*buff = p
-		return n
+		return nmeacsum(fmt.Sprintf("$%sGGA,,,,,,,,,,,,,,", NMEA_TID))
 	}
 	for solq = 0; solq < 8; solq++ {
 		if nmea_solq[solq] == int(sol.Stat) {
@@ -1641,6 +1696,15 @@ func (sol *Sol) OutSolNmeaGga(buff *string) int {
 	if solq >= 8 {
 		solq = 0
 	}
+	if dop > 0.0 {
+		hdop = fmt.Sprintf("%.1f", dop)
+	}
+	switch sol.Stat {
+	case SOLQ_DGPS, SOLQ_SBAS, SOLQ_FLOAT, SOLQ_FIX:
+		diff = fmt.Sprintf("%.1f,%04d", sol.Age, refid)
+	default:
+		diff = "," /* no age and station id without differential */
+	}
 	time = GpsT2Utc(sol.Time)
 	if time.Sec >= 0.995 {
 		time.Time++
@@ -1662,17 +1726,78 @@ func (sol *Sol) OutSolNmeaGga(buff *string) int {
 	} else {
 		pos2 = "W"
 	}
-	p += fmt.Sprintf("$%sGGA,%02.0f%02.0f%05.2f,%02.0f%010.7f,%s,%03.0f%010.7f,%s,%d,%02d,%.1f,%.3f,M,%.3f,M,%.1f,%04d",
+	return nmeacsum(fmt.Sprintf("$%sGGA,%02.0f%02.0f%05.2f,%02.0f%010.7f,%s,%03.0f%010.7f,%s,%d,%02d,%s,%.3f,M,%.3f,M,%s",
 		NMEA_TID, ep[3], ep[4], ep[5], dms1[0], dms1[1]+dms1[2]/60.0,
 		pos1, dms2[0], dms2[1]+dms2[2]/60.0, pos2,
-		solq, sol.Ns, dop, pos[2]-h, h, sol.Age, refid)
-	for i = 1; i < len(p); i++ {
-		sum ^= p[i]
+		solq, sol.Ns, hdop, pos[2]-h, h, diff))
+}
+
+/* append NMEA checksum and line end to sentence ------------------------------
+* args   : string s         I   sentence from '$' to the last field
+* return : sentence with "*hh\r\n", checksum of the bytes between '$' and '*'
+*-----------------------------------------------------------------------------*/
+func nmeacsum(s string) string {
+	var sum uint8
+	for i := 1; i < len(s); i++ {
+		sum ^= s[i]
 	}
-	p += fmt.Sprintf("*%02X\r\n", sum)
-	n := len(p) - len(*buff)
-	*buff = p
-	return n
+	return fmt.Sprintf("%s*%02X\r\n", s, sum)
+}
+
+/* solution to NMEA GGA sentence ----------------------------------------------
+* format the solution position and time as a NMEA GGA sentence
+* args   : nav_t  *nav      I   navigation data for hdop (nil: no hdop)
+* return : GGA sentence with checksum and "\r\n"
+* notes  : fix quality: SOLQ_SINGLE:1, SOLQ_DGPS:2, SOLQ_PPP:3, SOLQ_FIX:4,
+*          SOLQ_FLOAT:5, SOLQ_DR:6. no solution gives a GGA without fields.
+*          age of differential and station id are empty for the solutions
+*          without differential corrections.
+*          hdop is computed by the geometry of the healthy satellites with
+*          broadcast ephemeris in nav above 15 deg elevation, the hdop field
+*          is empty if nav is nil or less than 4 satellites are visible.
+*          the geoid separation is given by GeoidH, the embedded EGM96 model
+*          unless a model is set by SetGeoid or OpenGeoid.
+*-----------------------------------------------------------------------------*/
+func (sol *Sol) ToGGA(nav *Nav) string {
+	return sol.nmeagga(sol.nmeahdop(nav))
+}
+
+/* solution to NMEA RMC sentence ----------------------------------------------
+* format the solution position, velocity and time as a NMEA RMC sentence
+* args   : none
+* return : RMC sentence with checksum and "\r\n"
+* notes  : see OutSolNmeaRmc
+*-----------------------------------------------------------------------------*/
+func (sol *Sol) ToRMC() string {
+	var p string
+	sol.OutSolNmeaRmc(&p)
+	return p
+}
+
+/* hdop of the satellites visible from solution position ---------------------*/
+func (sol *Sol) nmeahdop(nav *Nav) float64 {
+	var (
+		rs, dts      [6]float64
+		pos, e, dop  [4]float64
+		azel         []float64
+		vari         float64
+		svh, sat, ns int
+	)
+	if nav == nil || Norm(sol.Rr[:], 3) <= 0.0 {
+		return 0.0
+	}
+	Ecef2Pos(sol.Rr[:], pos[:])
+	for sat = 1; sat <= MAXSAT; sat++ {
+		if nav.SatPos(sol.Time, sol.Time, sat, EPHOPT_BRDC, rs[:], dts[:], &vari, &svh) == 0 ||
+			svh != 0 || GeoDist(rs[:], sol.Rr[:], e[:]) <= 0.0 {
+			continue
+		}
+		azel = append(azel, 0.0, 0.0)
+		SatAzel(pos[:], e[:], azel[ns*2:])
+		ns++
+	}
+	DOPs(ns, azel, 15.0*D2R, dop[:])
+	return dop[2]
 }
 
 /* output solution in the form of NMEA GSA sentences -------------------------*/
@@ -1680,7 +1805,6 @@ func (sol *Sol) OutSolNmeaGsa(buff *string, ssat []SSat) int {
 	var (
 		azel                          []float64 = make([]float64, MAXSAT*2)
 		dop                           [4]float64
-		sum                           uint8
 		j, sys, prn, nsat, mask, nsys int
 		sats                          [MAXSAT]int
 	)
@@ -1728,7 +1852,7 @@ func (sol *Sol) OutSolNmeaGsa(buff *string, ssat []SSat) int {
 			} else {
 				d1 = 1
 			}
-			p += fmt.Sprintf("$%sGSA,A,%d", s1, d1)
+			q := fmt.Sprintf("$%sGSA,A,%d", s1, d1)
 			for j = 0; j < 12; j++ {
 				sys = SatSys(sats[j], &prn)
 				switch sys {
@@ -1740,17 +1864,14 @@ func (sol *Sol) OutSolNmeaGsa(buff *string, ssat []SSat) int {
 					prn -= 192
 				} /* QZS: 01-10 */
 				if j < nsat {
-					p += fmt.Sprintf(",%02d", prn)
+					q += fmt.Sprintf(",%02d", prn)
 				} else {
-					p += ","
+					q += ","
 				}
 			}
-			p += fmt.Sprintf(",%3.1f,%3.1f,%3.1f,%d", dop[1], dop[2], dop[3],
+			q += fmt.Sprintf(",%3.1f,%3.1f,%3.1f,%d", dop[1], dop[2], dop[3],
 				nmea_sid[i])
-			for k := 1; k < len(p); k++ {
-				sum ^= p[k]
-			}
-			p += fmt.Sprintf("*%02X\r\n", sum)
+			p += nmeacsum(q)
 		}
 	}
 	n := len(p) - len(*buff)
@@ -1764,7 +1885,6 @@ func (sol *Sol) OutSolNmeaGsv(buff *string, ssat []SSat) int {
 		az, el, snr                      float64
 		i, j, k, n, nsat, nmsg, prn, sys int
 		sats                             [MAXSAT]int
-		sum                              uint8
 	)
 	p := *buff
 
@@ -1784,7 +1904,7 @@ func (sol *Sol) OutSolNmeaGsv(buff *string, ssat []SSat) int {
 
 		for j, n = 0, 0; j < nmsg; j++ {
 
-			p += fmt.Sprintf("$%sGSV,%d,%d,%02d", nmea_tid[i], nmsg, j+1, nsat)
+			q := fmt.Sprintf("$%sGSV,%d,%d,%02d", nmea_tid[i], nmsg, j+1, nsat)
 			for k = 0; k < 4; k++ {
 				if n < nsat {
 					sys = SatSys(sats[n], &prn)
@@ -1802,17 +1922,14 @@ func (sol *Sol) OutSolNmeaGsv(buff *string, ssat []SSat) int {
 					}
 					el = float64(ssat[sats[n]-1].Azel[1]) * R2D
 					snr = float64(float32(ssat[sats[n]-1].Snr[0]) * SNR_UNIT)
-					p += fmt.Sprintf(",%02d,%02.0f,%03.0f,%02.0f", prn, el, az, snr)
+					q += fmt.Sprintf(",%02d,%02.0f,%03.0f,%02.0f", prn, el, az, snr)
 				} else {
-					p += ",,,,"
+					q += ",,,,"
 				}
 				n++
 			}
-			p += ",0" /* all signals */
-			for k := 1; k < len(p); k++ {
-				sum ^= p[k]
-			}
-			p += fmt.Sprintf("*%02X\r\n", sum)
+			q += ",0" /* all signals */
+			p += nmeacsum(q)
 		}
 	}
 	n = len(p) - len(*buff)
@@ -2007,6 +2124,11 @@ func OutSolHeader(buff *string, opt *SolOpt) int {
 			"sden(m)", sep, "sdnu(m)", sep, "sdue(m)", sep, "age(s)", sep,
 			"ratio")
 	}
+	if opt.Posf == SOLF_LLH || opt.Posf == SOLF_XYZ || opt.Posf == SOLF_ENU {
+		for f := 0; opt.OutNsFreq > 0 && f < NFREQ; f++ {
+			p += fmt.Sprintf("%s%4s", sep, fmt.Sprintf("ns%d", f+1))
+		}
+	}
 	p += "\r\n"
 	n := len(p) - len(*buff)
 	*buff = p
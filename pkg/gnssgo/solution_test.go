@@ -0,0 +1,184 @@
+package gnssgo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestSolQCode checks the RTKLIB quality code and name of each status.
+func TestSolQCode(t *testing.T) {
+	tests := []struct {
+		stat uint8
+		q    int
+		name string
+	}{
+		{SOLQ_NONE, 0, "NONE"},
+		{SOLQ_FIX, 1, "FIX"},
+		{SOLQ_FLOAT, 2, "FLOAT"},
+		{SOLQ_SBAS, 3, "SBAS"},
+		{SOLQ_DGPS, 4, "DGPS"},
+		{SOLQ_SINGLE, 5, "SINGLE"},
+		{SOLQ_PPP, 6, "PPP"},
+		{SOLQ_DR, 7, "DR"},
+		{99, 0, "NONE"},
+	}
+	for _, tt := range tests {
+		sol := Sol{Stat: tt.stat}
+		if q := sol.QCode(); q != tt.q {
+			t.Errorf("stat %d: QCode = %d, want %d", tt.stat, q, tt.q)
+		}
+		if name := sol.QName(); name != tt.name {
+			t.Errorf("stat %d: QName = %s, want %s", tt.stat, name, tt.name)
+		}
+	}
+}
+
+// TestSolNsFreq checks the number of valid satellites per frequency is output
+// TestSolBaseline checks the baseline length and its components in the
+// local frame of the base station.
+func TestSolBaseline(t *testing.T) {
+	var sol Sol
+	if sol.BaselineLength() != 0.0 || sol.BaselineNED() != [3]float64{} {
+		t.Errorf("no baseline: length %.3f ned %v, want 0", sol.BaselineLength(), sol.BaselineNED())
+	}
+
+	var pos, d [3]float64
+	Ecef2Pos(synthBase[:], pos[:])
+	Enu2Ecef(pos[:], []float64{30.0, 40.0, -5.0}, d[:])
+	for i := 0; i < 3; i++ {
+		sol.Rr[i] = synthBase[i] + d[i]
+		sol.Baseline[i] = d[i]
+	}
+	if l, want := sol.BaselineLength(), math.Sqrt(30.0*30.0+40.0*40.0+5.0*5.0); math.Abs(l-want) > 1e-9 {
+		t.Errorf("length = %.6f, want %.6f", l, want)
+	}
+	ned := sol.BaselineNED()
+	for i, want := range []float64{40.0, 30.0, 5.0} {
+		if math.Abs(ned[i]-want) > 1e-6 {
+			t.Errorf("ned = %v, want {40,30,5}", ned)
+			break
+		}
+	}
+}
+
+// as the last fields of the solution and decoded back with the same options.
+func TestSolNsFreq(t *testing.T) {
+	sol := Sol{
+		Time: Epoch2Time([]float64{2023, 6, 1, 0, 0, 0}), Stat: SOLQ_FLOAT, Ns: 9,
+		NsFreq: [NFREQ]uint8{9, 6, 2}, Rr: [6]float64{-3961904.9, 3348993.8, 3698211.8, 0.1, 0.2, 0.3},
+		Qr: [6]float32{1e-4, 1e-4, 1e-4}, Age: 1.0, Ratio: 2.5,
+	}
+	for _, posf := range []int{SOLF_LLH, SOLF_XYZ, SOLF_ENU} {
+		for _, outvel := range []int{0, 1} {
+			opt := SolOpt{Posf: posf, OutVel: outvel, OutNsFreq: 1, TimeF: 1, TimeU: 3}
+			var head, buff string
+			OutSolHeader(&head, &opt)
+			sol.OutSols(&buff, synthBase[:], &opt)
+			if !strings.Contains(head, " ns3") || !strings.HasSuffix(buff, "   9   6   2\r\n") {
+				t.Errorf("posf=%d vel=%d: header %q solution %q", posf, outvel, head, buff)
+				continue
+			}
+			var dec Sol
+			if dec.DecodeSol([]byte(buff), &opt, synthBase[:]) == 0 {
+				t.Errorf("posf=%d vel=%d: decode error %q", posf, outvel, buff)
+				continue
+			}
+			if dec.Ns != sol.Ns || dec.NsFreq != sol.NsFreq || dec.Ratio != sol.Ratio {
+				t.Errorf("posf=%d vel=%d: decoded ns=%d nsfreq=%v ratio=%.1f", posf, outvel,
+					dec.Ns, dec.NsFreq, dec.Ratio)
+			}
+			if posf != SOLF_ENU && outvel > 0 && math.Abs(dec.Rr[5]-sol.Rr[5]) > 1e-4 {
+				t.Errorf("posf=%d: decoded velocity %v", posf, dec.Rr[3:])
+			}
+		}
+	}
+
+	opt := SolOpt{Posf: SOLF_XYZ}
+	var buff string
+	sol.OutSols(&buff, nil, &opt)
+	if strings.HasSuffix(buff, "   9   6   2\r\n") {
+		t.Errorf("counts output without the option: %q", buff)
+	}
+}
+
+// nmeaChecksumOK reports whether the checksum of each sentence in buff matches
+// the XOR of the bytes between '$' and '*'.
+func nmeaChecksumOK(buff string) bool {
+	for _, s := range strings.SplitAfter(buff, "\r\n") {
+		if s == "" {
+			continue
+		}
+		star := strings.LastIndexByte(s, '*')
+		if s[0] != '$' || star < 0 || !strings.HasSuffix(s, "\r\n") {
+			return false
+		}
+		var sum uint8
+		for i := 1; i < star; i++ {
+			sum ^= s[i]
+		}
+		if fmt.Sprintf("%02X", sum) != strings.TrimSuffix(s[star+1:], "\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// TestSolToNMEA checks the GGA and RMC sentences of solutions, including the
+// fix quality mapping and the checksums.
+func TestSolToNMEA(t *testing.T) {
+	var north, south [3]float64
+	Pos2Ecef([]float64{(48.0 + 7.038/60.0) * D2R, (11.0 + 31.0/60.0) * D2R, 600.0}, north[:])
+	Pos2Ecef([]float64{-(33.0 + 51.408/60.0) * D2R, -(70.0 + 38.898/60.0) * D2R, 550.0}, south[:])
+	time := Epoch2Time([]float64{2024, 3, 1, 12, 35, 37}) /* 12:35:19 UTC */
+
+	tests := []struct {
+		stat uint8
+		rr   [3]float64
+		gga  string
+		rmc  string
+	}{
+		{SOLQ_FIX, north,
+			"$GNGGA,123519.00,4807.0380000,N,01131.0000000,E,4,12,,554.321,M,45.679,M,1.5,0000*72\r\n",
+			"$GNRMC,123519.00,A,4807.0380000,N,01131.0000000,E,0.00,0.00,010324,0.0,E,R,V*49\r\n"},
+		{SOLQ_FLOAT, north,
+			"$GNGGA,123519.00,4807.0380000,N,01131.0000000,E,5,12,,554.321,M,45.679,M,1.5,0000*73\r\n",
+			"$GNRMC,123519.00,A,4807.0380000,N,01131.0000000,E,0.00,0.00,010324,0.0,E,R,V*49\r\n"},
+		{SOLQ_DGPS, north,
+			"$GNGGA,123519.00,4807.0380000,N,01131.0000000,E,2,12,,554.321,M,45.679,M,1.5,0000*74\r\n",
+			"$GNRMC,123519.00,A,4807.0380000,N,01131.0000000,E,0.00,0.00,010324,0.0,E,D,V*5F\r\n"},
+		{SOLQ_SINGLE, south,
+			"$GNGGA,123519.00,3351.4080000,S,07038.8980000,W,1,12,,522.447,M,27.553,M,,*54\r\n",
+			"$GNRMC,123519.00,A,3351.4080000,S,07038.8980000,W,0.00,0.00,010324,0.0,E,A,V*5A\r\n"},
+		{SOLQ_NONE, north,
+			"$GNGGA,,,,,,,,,,,,,,*48\r\n",
+			"$GNRMC,,,,,,,,,,,,,*79\r\n"},
+	}
+	for _, tt := range tests {
+		sol := Sol{Time: time, Stat: tt.stat, Ns: 12, Age: 1.5}
+		copy(sol.Rr[:], tt.rr[:])
+		if gga := sol.ToGGA(nil); gga != tt.gga || !nmeaChecksumOK(gga) {
+			t.Errorf("stat %d: GGA\n got %q\nwant %q", tt.stat, gga, tt.gga)
+		}
+		if rmc := sol.ToRMC(); rmc != tt.rmc || !nmeaChecksumOK(rmc) {
+			t.Errorf("stat %d: RMC\n got %q\nwant %q", tt.stat, rmc, tt.rmc)
+		}
+	}
+
+	/* hdop of the satellites of nav above 15 deg */
+	sol := Sol{Time: time, Stat: SOLQ_FIX, Ns: 8, Age: 1.5}
+	copy(sol.Rr[:], north[:])
+	gga := sol.ToGGA(synthNav(time, 8))
+	if want := "$GNGGA,123519.00,4807.0380000,N,01131.0000000,E,4,08,2.2,554.321,M,45.679,M,1.5,0000*57\r\n"; gga != want {
+		t.Errorf("GGA with hdop\n got %q\nwant %q", gga, want)
+	}
+
+	/* checksums of the sentences following another one in the buffer */
+	opt := SolOpt{Posf: SOLF_NMEA}
+	buff := "$GNTXT,01,01,02,START*00\r\n"
+	n := sol.OutSols(&buff, nil, &opt)
+	if out := buff[len(buff)-n:]; !nmeaChecksumOK(out) || !strings.Contains(out, "GGA") {
+		t.Errorf("OutSols checksums: %q", out)
+	}
+}
@@ -0,0 +1,360 @@
+// Package stream provides stream input/output functionality for GNSS data
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Compact RINEX (Hatanaka) constants
+const (
+	crxFlagPos2 = 28 // Epoch flag position in CRINEX 1.0 epoch lines
+	crxFlagPos3 = 31 // Epoch flag position in CRINEX 3.0 epoch lines
+	crxSatPos2  = 32 // Satellite list position in CRINEX 1.0 epoch lines
+	crxSatPos3  = 41 // Satellite list position in CRINEX 3.0 epoch lines
+	crxSatsLine = 12 // Satellites per RINEX 2 epoch line
+	crxObsLine  = 5  // Observations per RINEX 2 data line
+)
+
+// crxArc holds the differencing state of one data column of compact RINEX
+type crxArc struct {
+	order    int     // Current differencing order (-1: not initialized)
+	arcOrder int     // Differencing order of the arc
+	diff     []int64 // Value and its differences up to arcOrder
+}
+
+// update applies a compact RINEX data field to the arc and returns the
+// restored value. A field "n&value" starts a new arc of order n.
+func (a *crxArc) update(field string) (int64, error) {
+	if len(field) >= 2 && field[1] == '&' {
+		if field[0] < '0' || field[0] > '9' {
+			return 0, fmt.Errorf("invalid arc order in field %q", field)
+		}
+		v, err := strconv.ParseInt(field[2:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid field %q", field)
+		}
+		a.arcOrder = int(field[0] - '0')
+		a.order = 0
+		a.diff = make([]int64, a.arcOrder+1)
+		a.diff[0] = v
+		return v, nil
+	}
+	if a.order < 0 {
+		return 0, fmt.Errorf("arc not initialized for field %q", field)
+	}
+	v, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid field %q", field)
+	}
+	if a.order < a.arcOrder {
+		a.order++
+	}
+	a.diff[a.order] = v
+	for k := a.order - 1; k >= 0; k-- {
+		a.diff[k] += a.diff[k+1]
+	}
+	return a.diff[0], nil
+}
+
+// crxSat holds the state of one satellite between epochs
+type crxSat struct {
+	arcs  []crxArc
+	flags string
+}
+
+// crxDecoder restores RINEX observation data from compact RINEX
+type crxDecoder struct {
+	scanner *bufio.Scanner
+	out     *bufio.Writer
+	lineNo  int
+	version int                // CRINEX version (1 or 3)
+	ntype   map[byte]int       // Number of observation types per system (RINEX 2: key 0)
+	epoch   string             // Previous epoch line
+	clock   crxArc             // Receiver clock offset
+	sats    map[string]*crxSat // Satellites of the previous epoch
+}
+
+// DecompressCRX converts Hatanaka compressed RINEX observation data
+// (CRINEX 1.0 for RINEX 2, CRINEX 3.0 for RINEX 3) read from r to RINEX
+// written to w.
+func DecompressCRX(r io.Reader, w io.Writer) error {
+	d := &crxDecoder{
+		scanner: bufio.NewScanner(r),
+		out:     bufio.NewWriter(w),
+		ntype:   make(map[byte]int),
+		clock:   crxArc{order: -1},
+		sats:    make(map[string]*crxSat),
+	}
+	d.scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+
+	if err := d.readHeader(); err != nil {
+		return err
+	}
+	for {
+		line, ok := d.readLine()
+		if !ok {
+			break
+		}
+		if err := d.readEpoch(line); err != nil {
+			return fmt.Errorf("crx line %d: %w", d.lineNo, err)
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return err
+	}
+	return d.out.Flush()
+}
+
+// readLine reads the next line without the line terminator
+func (d *crxDecoder) readLine() (string, bool) {
+	if !d.scanner.Scan() {
+		return "", false
+	}
+	d.lineNo++
+	return strings.TrimRight(d.scanner.Text(), "\r"), true
+}
+
+// readHeader checks the compact RINEX header lines and copies the RINEX
+// header, collecting the number of observation types
+func (d *crxDecoder) readHeader() error {
+	line, ok := d.readLine()
+	if !ok || len(line) < 61 || !strings.HasPrefix(line[60:], "CRINEX VERS") {
+		return fmt.Errorf("not a compact RINEX file")
+	}
+	switch strings.TrimSpace(line[:20]) {
+	case "1.0":
+		d.version = 1
+	case "3.0":
+		d.version = 3
+	default:
+		return fmt.Errorf("unsupported compact RINEX version %q", strings.TrimSpace(line[:20]))
+	}
+	if _, ok = d.readLine(); !ok { // CRINEX PROG / DATE
+		return fmt.Errorf("truncated compact RINEX header")
+	}
+
+	for {
+		line, ok = d.readLine()
+		if !ok {
+			return fmt.Errorf("no END OF HEADER in compact RINEX file")
+		}
+		fmt.Fprintln(d.out, line)
+		if len(line) < 61 {
+			continue
+		}
+		switch label := strings.TrimSpace(line[60:]); label {
+		case "# / TYPES OF OBSERV":
+			if n, err := strconv.Atoi(strings.TrimSpace(line[:6])); err == nil {
+				d.ntype[0] = n
+			}
+		case "SYS / # / OBS TYPES":
+			if n, err := strconv.Atoi(strings.TrimSpace(line[3:6])); err == nil && line[0] != ' ' {
+				d.ntype[line[0]] = n
+			}
+		case "END OF HEADER":
+			return nil
+		}
+	}
+}
+
+// readEpoch restores one epoch starting with the compact epoch line
+func (d *crxDecoder) readEpoch(line string) error {
+	flagPos, satPos := crxFlagPos2, crxSatPos2
+	if d.version == 3 {
+		flagPos, satPos = crxFlagPos3, crxSatPos3
+	}
+
+	// Restore the epoch line, a full line starts with '&' (1.0) or '>' (3.0)
+	if (d.version == 1 && strings.HasPrefix(line, "&")) || (d.version == 3 && strings.HasPrefix(line, ">")) {
+		d.epoch = crxRepair("", line)
+	} else if d.epoch == "" {
+		return fmt.Errorf("epoch line not initialized")
+	} else {
+		d.epoch = crxRepair(d.epoch, line)
+	}
+	if len(d.epoch) < satPos {
+		d.epoch += strings.Repeat(" ", satPos-len(d.epoch))
+	}
+	flag := d.epoch[flagPos]
+	nsat, err := strconv.Atoi(strings.TrimSpace(d.epoch[flagPos+1 : flagPos+4]))
+	if err != nil {
+		return fmt.Errorf("invalid number of satellites in %q", d.epoch)
+	}
+
+	// Event records are followed by nsat lines of text
+	if flag >= '2' && flag <= '5' {
+		fmt.Fprintln(d.out, strings.TrimRight(d.epoch[:flagPos+4], " "))
+		for i := 0; i < nsat; i++ {
+			text, ok := d.readLine()
+			if !ok {
+				return fmt.Errorf("truncated event record")
+			}
+			fmt.Fprintln(d.out, text)
+		}
+		return nil
+	}
+
+	// Receiver clock offset
+	clockLine, ok := d.readLine()
+	if !ok {
+		return fmt.Errorf("missing clock offset line")
+	}
+	var clock int64
+	hasClock := clockLine != ""
+	if hasClock {
+		if clock, err = d.clock.update(clockLine); err != nil {
+			return fmt.Errorf("clock offset: %w", err)
+		}
+	} else {
+		d.clock.order = -1
+	}
+
+	if len(d.epoch) < satPos+3*nsat {
+		return fmt.Errorf("satellite list too short in %q", d.epoch)
+	}
+	sats := make([]string, nsat)
+	for i := range sats {
+		sats[i] = d.epoch[satPos+3*i : satPos+3*i+3]
+	}
+	d.writeEpoch(sats, hasClock, clock)
+
+	// Data records, differenced against the same satellite in the previous epoch
+	current := make(map[string]*crxSat, nsat)
+	for _, id := range sats {
+		text, ok := d.readLine()
+		if !ok {
+			return fmt.Errorf("missing data record for %s", id)
+		}
+		ntype := d.ntype[0]
+		if d.version == 3 {
+			ntype = d.ntype[id[0]]
+		}
+		sat := d.sats[id]
+		if sat == nil || len(sat.arcs) != ntype {
+			sat = &crxSat{arcs: make([]crxArc, ntype)}
+			for j := range sat.arcs {
+				sat.arcs[j].order = -1
+			}
+		}
+		current[id] = sat
+		if err := d.writeData(id, sat, text); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	d.sats = current
+	return nil
+}
+
+// writeEpoch writes the RINEX epoch record
+func (d *crxDecoder) writeEpoch(sats []string, hasClock bool, clock int64) {
+	if d.version == 3 {
+		line := d.epoch[:crxSatPos3]
+		if hasClock {
+			line += crxFormat(clock, 12, 15)
+		}
+		fmt.Fprintln(d.out, strings.TrimRight(line, " "))
+		return
+	}
+	head := d.epoch[:crxSatPos2]
+	for i := 0; i == 0 || i < len(sats); i += crxSatsLine {
+		line := head + strings.Join(sats[i:min(i+crxSatsLine, len(sats))], "")
+		if i == 0 && hasClock {
+			line = fmt.Sprintf("%-68s%s", line, crxFormat(clock, 9, 12))
+		}
+		fmt.Fprintln(d.out, strings.TrimRight(line, " "))
+		head = strings.Repeat(" ", crxSatPos2)
+	}
+}
+
+// writeData restores and writes the observation data record of a satellite
+func (d *crxDecoder) writeData(id string, sat *crxSat, text string) error {
+	// Fields are separated by a space, an empty field is missing data
+	fields := make([]string, len(sat.arcs))
+	pos := 0
+	for j := range fields {
+		if pos >= len(text) {
+			break
+		}
+		end := strings.IndexByte(text[pos:], ' ')
+		if end < 0 {
+			end = len(text) - pos
+		}
+		fields[j] = text[pos : pos+end]
+		pos += end + 1
+	}
+	// LLI and signal strength flags follow the data fields
+	if pos < len(text) {
+		sat.flags = crxRepair(sat.flags, text[pos:])
+	}
+
+	obs := make([]string, len(fields))
+	for j, field := range fields {
+		value := strings.Repeat(" ", 14)
+		if field == "" {
+			sat.arcs[j].order = -1
+		} else {
+			v, err := sat.arcs[j].update(field)
+			if err != nil {
+				return err
+			}
+			value = crxFormat(v, 3, 14)
+		}
+		flags := []byte("  ")
+		for k := range flags {
+			if i := 2*j + k; i < len(sat.flags) {
+				flags[k] = sat.flags[i]
+			}
+		}
+		obs[j] = value + string(flags)
+	}
+
+	if d.version == 3 {
+		fmt.Fprintln(d.out, strings.TrimRight(id+strings.Join(obs, ""), " "))
+		return nil
+	}
+	for i := 0; i == 0 || i < len(obs); i += crxObsLine {
+		fmt.Fprintln(d.out, strings.TrimRight(strings.Join(obs[i:min(i+crxObsLine, len(obs))], ""), " "))
+	}
+	return nil
+}
+
+// crxRepair applies a compact RINEX text difference to the previous text:
+// a space keeps the previous character and '&' stands for a space
+func crxRepair(old, diff string) string {
+	n := max(len(old), len(diff))
+	s := make([]byte, n)
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(diff) || diff[i] == ' ':
+			if i < len(old) {
+				s[i] = old[i]
+			} else {
+				s[i] = ' '
+			}
+		case diff[i] == '&':
+			s[i] = ' '
+		default:
+			s[i] = diff[i]
+		}
+	}
+	return string(s)
+}
+
+// crxFormat formats an integer holding dec decimal digits as a right aligned
+// fixed point number of the given width
+func crxFormat(v int64, dec, width int) string {
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	scale := int64(1)
+	for i := 0; i < dec; i++ {
+		scale *= 10
+	}
+	return fmt.Sprintf("%*s", width, fmt.Sprintf("%s%d.%0*d", sign, v/scale, dec, v%scale))
+}
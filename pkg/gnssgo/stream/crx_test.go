@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// CRINEX 1.0 sample: arc reset of G01 L1, G02 leaving and rejoining, G03
+// joining with a missing observation later and a missing clock offset
+const testCRX2 = `1.0                 COMPACT RINEX FORMAT                    CRINEX VERS   / TYPE
+RNX2CRX ver.4.0.7                       01-Jan-23 00:00     CRINEX PROG / DATE
+     2.11           OBSERVATION DATA    G (GPS)             RINEX VERSION / TYPE
+TEST                                                        MARKER NAME
+     3    C1    L1    S1                                    # / TYPES OF OBSERV
+                                                            END OF HEADER
+&23  1  1  0  0  0.0000000  0  2G01G02
+2&123456
+3&20000000123 3&105100000250 3&45000    7
+3&21000000000 3&110000000000     6
+                3
+100
+100333 500500 0
+10000 50000
+              1 &                    3
+0
+210 3&105101000000 1000   1
+3&22000000500 3&115000000000 3&40250    5
+                3              3     2G03
+
+-752 500125 -2000   &
+3&21000030000 3&110000150000 3&44000    6
+500  0    &
+`
+
+// RINEX 2.11 restored from testCRX2
+const testRNX2 = `     2.11           OBSERVATION DATA    G (GPS)             RINEX VERSION / TYPE
+TEST                                                        MARKER NAME
+     3    C1    L1    S1                                    # / TYPES OF OBSERV
+                                                            END OF HEADER
+ 23  1  1  0  0  0.0000000  0  2G01G02                               0.000123456
+  20000000.123   105100000.250 7        45.000
+  21000000.000   110000000.000 6
+ 23  1  1  0  0 30.0000000  0  2G01G02                               0.000123556
+  20000100.456   105100500.750 7        45.000
+  21000010.000   110000050.000 6
+ 23  1  1  0  1  0.0000000  0  2G01G03                               0.000123656
+  20000200.999   105101000.00017        46.000
+  22000000.500   115000000.000 5        40.250
+ 23  1  1  0  1 30.0000000  0  3G01G02G03
+  20000301.000   105101500.125 7        46.000
+  21000030.000   110000150.000 6        44.000
+  22000001.000                          40.250
+`
+
+// CRINEX 3.0 sample with two systems and a negative clock offset
+const testCRX3 = `3.0                 COMPACT RINEX FORMAT                    CRINEX VERS   / TYPE
+RNX2CRX ver.4.0.7                       01-Jan-23 00:00     CRINEX PROG / DATE
+     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+E    1 C1X                                                  SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2023 01 01 00 00  0.0000000  0  2      G01E05
+2&-123456
+3&20000000123 3&105100000250
+3&23000000000  9
+                   3
+56
+1000 5000   1
+-500
+`
+
+// RINEX 3.04 restored from testCRX3
+const testRNX3 = `     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+E    1 C1X                                                  SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2023 01 01 00 00  0.0000000  0  2      -0.000000123456
+G01  20000000.123   105100000.250
+E05  23000000.000 9
+> 2023 01 01 00 00 30.0000000  0  2      -0.000000123400
+G01  20000001.123   105100005.2501
+E05  22999999.500 9
+`
+
+// TestDecompressCRX tests restoring RINEX from compact RINEX
+func TestDecompressCRX(t *testing.T) {
+	tests := []struct {
+		name string
+		crx  string
+		rnx  string
+	}{
+		{"CRINEX 1.0", testCRX2, testRNX2},
+		{"CRINEX 3.0", testCRX3, testRNX3},
+		{"CRLF line endings", strings.ReplaceAll(testCRX2, "\n", "\r\n"), testRNX2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := DecompressCRX(strings.NewReader(tt.crx), &out); err != nil {
+				t.Fatalf("DecompressCRX failed: %v", err)
+			}
+			got := strings.Split(out.String(), "\n")
+			want := strings.Split(tt.rnx, "\n")
+			for i := 0; i < len(got) || i < len(want); i++ {
+				var g, w string
+				if i < len(got) {
+					g = got[i]
+				}
+				if i < len(want) {
+					w = want[i]
+				}
+				if g != w {
+					t.Errorf("line %d:\n got: %q\nwant: %q", i+1, g, w)
+				}
+			}
+		})
+	}
+}
+
+// TestDecompressCRXErrors tests rejecting invalid compact RINEX
+func TestDecompressCRXErrors(t *testing.T) {
+	// Data of a new satellite must start a new arc
+	noInit := strings.Replace(testCRX2, "3&21000030000 3&110000150000", "10000 50000", 1)
+
+	tests := []struct {
+		name string
+		crx  string
+		want string
+	}{
+		{"not compact RINEX", testRNX2, "not a compact RINEX file"},
+		{"arc not initialized", noInit, "arc not initialized"},
+		{"truncated", testCRX2[:len(testCRX2)-20], "missing data record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DecompressCRX(strings.NewReader(tt.crx), &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestUncompressCRX tests decompressing compact RINEX files by name
+func TestUncompressCRX(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		file string
+		out  string
+		crx  string
+		rnx  string
+	}{
+		{"test0010.23d", "test0010.23o", testCRX2, testRNX2},
+		{"TEST00XXX_R_20230010000_01D_30S_MO.crx", "TEST00XXX_R_20230010000_01D_30S_MO.rnx", testCRX3, testRNX3},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(tempDir, tt.file)
+		if err := os.WriteFile(path, []byte(tt.crx), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		var outPath string
+		if stat := uncompress(path, &outPath); stat != 1 {
+			t.Fatalf("%s: uncompress returned %d", tt.file, stat)
+		}
+		if outPath != filepath.Join(tempDir, tt.out) {
+			t.Errorf("%s: output path %s, want %s", tt.file, outPath, tt.out)
+		}
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(data) != tt.rnx {
+			t.Errorf("%s: unexpected output:\n%s", tt.file, data)
+		}
+	}
+}
+
+// TestUncompressCRXGzip tests decompressing gzipped compact RINEX
+func TestUncompressCRXGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test0010.23d.gz")
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testCRX2))
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	var outPath string
+	if stat := uncompress(path, &outPath); stat != 1 {
+		t.Fatalf("uncompress returned %d", stat)
+	}
+	if filepath.Base(outPath) != "test0010.23o" {
+		t.Errorf("Expected output test0010.23o, got %s", outPath)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(path, ".gz")); !os.IsNotExist(err) {
+		t.Error("Expected intermediate compact RINEX file to be removed")
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil || string(data) != testRNX2 {
+		t.Errorf("Unexpected output (%v):\n%s", err, data)
+	}
+}
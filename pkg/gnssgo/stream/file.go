@@ -137,7 +137,7 @@ func openfile_(file *FileType, time gtime.Gtime, msg *string) int {
 		if idx := strings.LastIndex(rpath, "."); idx >= 0 {
 			ext := strings.ToLower(rpath[idx:])
 			if ext == ".z" || ext == ".gz" || ext == ".zip" || ext == ".bz2" || ext == ".bz" ||
-				ext == ".tgz" || ext == ".tar.gz" || ext == ".hatanaka" || ext == ".crx" {
+				ext == ".tgz" || ext == ".tar.gz" || crxOutputPath(rpath) != "" {
 				// Create temporary file path
 				tmpPath := rpath + tempExt
 
@@ -878,8 +878,15 @@ func uncompress(infile string, outfile *string) int {
 	// Uncompress based on file extension
 	switch ext {
 	case ".z", ".gz", ".zip":
-		// Use native Go gzip for .z, .gz, .zip files
-		if err := uncompressGzip(infile, *outfile); err != nil {
+		// Unix compress (LZW) and gzip are told apart by their magic bytes,
+		// .Z files are not always what their extension says
+		if isUnixCompressFile(infile) {
+			if err := uncompressZ(infile, *outfile); err != nil {
+				Tracet(1, "uncompress lzw error: %s\n", err.Error())
+				os.Remove(*outfile)
+				return -1
+			}
+		} else if err := uncompressGzip(infile, *outfile); err != nil {
 			Tracet(1, "uncompress gzip error: %s\n", err.Error())
 			os.Remove(*outfile)
 			return -1
@@ -902,21 +909,30 @@ func uncompress(infile string, outfile *string) int {
 			return -1
 		}
 		stat = 1
-	case ".hatanaka", ".crx":
-		// Use external command for RINEX hatanaka compression
-		if idx := strings.LastIndex(*outfile, "."); idx >= 0 && len(*outfile) > idx+3 {
-			if (*outfile)[idx+3] == 'd' || (*outfile)[idx+3] == 'D' {
-				ext = *outfile
-				ext = ext[:idx+3] + string('o')
-				cmd := fmt.Sprintf("crx2rnx < \"%s\" > \"%s\"", infile, ext)
-				if err := execCmd(cmd); err != nil {
-					Tracet(1, "uncompress hatanaka error: %s\n", err.Error())
-					os.Remove(ext)
-					return -1
-				}
-				*outfile = ext
-				stat = 1
+	default:
+		// Compact RINEX (Hatanaka) files: .crx, .hatanaka and RINEX 2 .yyd
+		if rnxfile := crxOutputPath(infile); rnxfile != "" {
+			if err := uncompressCRX(infile, rnxfile); err != nil {
+				Tracet(1, "uncompress hatanaka error: %s\n", err.Error())
+				os.Remove(rnxfile)
+				return -1
 			}
+			*outfile = rnxfile
+			stat = 1
+		}
+	}
+
+	// Compact RINEX inside a compressed file
+	if stat == 1 && crxOutputPath(infile) == "" {
+		if rnxfile := crxOutputPath(*outfile); rnxfile != "" {
+			err := uncompressCRX(*outfile, rnxfile)
+			os.Remove(*outfile)
+			if err != nil {
+				Tracet(1, "uncompress hatanaka error: %s\n", err.Error())
+				os.Remove(rnxfile)
+				return -1
+			}
+			*outfile = rnxfile
 		}
 	}
 
@@ -956,6 +972,85 @@ func uncompressGzip(infile, outfile string) error {
 	return nil
 }
 
+// isUnixCompressFile reports whether the file starts with the Unix compress magic
+func isUnixCompressFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return isUnixCompress(header)
+}
+
+// uncompressZ decompresses a Unix compress (.Z) file
+func uncompressZ(infile, outfile string) error {
+	in, err := os.Open(infile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(outfile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err = DecompressZ(in, out); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to decompress data: %v", err)
+	}
+	return out.Close()
+}
+
+// crxOutputPath returns the RINEX file name for a compact RINEX (Hatanaka)
+// file name, or an empty string if path is not compact RINEX
+func crxOutputPath(path string) string {
+	idx := strings.LastIndex(path, ".")
+	if idx < 0 {
+		return ""
+	}
+	ext := path[idx:]
+	switch strings.ToLower(ext) {
+	case ".crx":
+		return path[:idx] + ".rnx"
+	case ".hatanaka":
+		return path[:idx]
+	}
+	// RINEX 2 observation: .yyd -> .yyo
+	if len(ext) == 4 && ext[1] >= '0' && ext[1] <= '9' && ext[2] >= '0' && ext[2] <= '9' {
+		switch ext[3] {
+		case 'd':
+			return path[:idx+3] + "o"
+		case 'D':
+			return path[:idx+3] + "O"
+		}
+	}
+	return ""
+}
+
+// uncompressCRX decompresses a compact RINEX (Hatanaka) file
+func uncompressCRX(infile, outfile string) error {
+	in, err := os.Open(infile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(outfile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err = DecompressCRX(in, out); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to decompress data: %v", err)
+	}
+	return out.Close()
+}
+
 // execCmd executes a command
 func execCmd(cmd string) error {
 	Tracet(3, "execCmd: cmd=%s\n", cmd)
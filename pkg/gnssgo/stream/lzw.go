@@ -0,0 +1,137 @@
+// Package stream provides stream input/output functionality for GNSS data
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Unix compress (.Z) constants
+const (
+	lzwMagic0    = 0x1f // First magic byte (shared with gzip)
+	lzwMagic1    = 0x9d // Second magic byte (gzip: 0x8b)
+	lzwBitsMask  = 0x1f // Max code width in the flags byte
+	lzwBlockMode = 0x80 // Block mode flag: code 256 clears the table
+	lzwInitBits  = 9    // Initial code width
+	lzwMaxBits   = 16   // Max supported code width
+	lzwClear     = 256  // Clear code in block mode
+)
+
+// isUnixCompress reports whether the header starts with the Unix compress magic
+func isUnixCompress(header []byte) bool {
+	return len(header) >= 2 && header[0] == lzwMagic0 && header[1] == lzwMagic1
+}
+
+// DecompressZ decompresses Unix compress (.Z, adaptive LZW) data read from r
+// and writes it to w.
+//
+// Codes are packed LSB first in groups of 8 codes of the same width. When the
+// width grows or the table is cleared, the rest of the current group is
+// padding, as written by compress(1).
+func DecompressZ(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) < 3 || !isUnixCompress(data) {
+		return fmt.Errorf("not a Unix compress file")
+	}
+	maxBits := int(data[2] & lzwBitsMask)
+	blockMode := data[2]&lzwBlockMode != 0
+	if maxBits < lzwInitBits || maxBits > lzwMaxBits {
+		return fmt.Errorf("unsupported max code width %d", maxBits)
+	}
+	data = data[3:]
+
+	var (
+		out      = bufio.NewWriter(w)
+		maxCode  = 1 << maxBits
+		prefix   = make([]uint16, maxCode)
+		suffix   = make([]byte, maxCode)
+		stack    = make([]byte, 0, maxCode)
+		nbits    = lzwInitBits
+		start    = 0  // Bit position of the current code group
+		pos      = 0  // Bit position of the next code
+		oldCode  = -1 // Previous code (-1: none)
+		finChar  byte // First character of the previous string
+		firstEnt = 256
+	)
+	if blockMode {
+		firstEnt = lzwClear + 1
+	}
+	freeEnt := firstEnt
+	for i := 0; i < 256; i++ {
+		suffix[i] = byte(i)
+	}
+
+	// alignGroup skips the padding to the end of the current code group
+	alignGroup := func() {
+		group := nbits * 8
+		pos = start + (pos-start+group-1)/group*group
+		start = pos
+	}
+
+	for {
+		if nbits < maxBits && freeEnt > 1<<nbits-1 {
+			alignGroup()
+			nbits++
+		}
+		if pos+nbits > len(data)*8 {
+			break
+		}
+		code := 0
+		for i := 0; i < nbits; i++ {
+			if data[(pos+i)/8]&(1<<((pos+i)%8)) != 0 {
+				code |= 1 << i
+			}
+		}
+		pos += nbits
+
+		if oldCode < 0 {
+			if code >= 256 {
+				return fmt.Errorf("invalid first code %d", code)
+			}
+			finChar = byte(code)
+			out.WriteByte(finChar)
+			oldCode = code
+			continue
+		}
+		if code == lzwClear && blockMode {
+			alignGroup()
+			nbits = lzwInitBits
+			freeEnt = firstEnt
+			oldCode = -1
+			continue
+		}
+
+		// Restore the string of the code, a code not yet in the table is
+		// the previous string followed by its first character
+		inCode := code
+		stack = stack[:0]
+		if code >= freeEnt {
+			if code > freeEnt {
+				return fmt.Errorf("corrupt input: code %d beyond table size %d", code, freeEnt)
+			}
+			stack = append(stack, finChar)
+			code = oldCode
+		}
+		for code >= 256 {
+			stack = append(stack, suffix[code])
+			code = int(prefix[code])
+		}
+		finChar = suffix[code]
+		stack = append(stack, finChar)
+		for i := len(stack) - 1; i >= 0; i-- {
+			out.WriteByte(stack[i])
+		}
+
+		if freeEnt < maxCode {
+			prefix[freeEnt] = uint16(oldCode)
+			suffix[freeEnt] = finChar
+			freeEnt++
+		}
+		oldCode = inCode
+	}
+	return out.Flush()
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDecompressZ tests decompressing Unix compress files written with the
+// default (16) and a reduced (11, table cleared several times) max code width
+func TestDecompressZ(t *testing.T) {
+	want, err := os.ReadFile("testdata/brdc0010.24n")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	for _, file := range []string{"brdc0010.24n.Z", "brdc0010.24n.b11.Z"} {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatalf("Failed to read test data: %v", err)
+		}
+		var out bytes.Buffer
+		if err := DecompressZ(bytes.NewReader(data), &out); err != nil {
+			t.Errorf("%s: DecompressZ failed: %v", file, err)
+			continue
+		}
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("%s: decompressed data differs (%d bytes, expected %d)", file, out.Len(), len(want))
+		}
+	}
+}
+
+// TestDecompressZErrors tests rejecting invalid Unix compress data
+func TestDecompressZErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}},
+		{"short", []byte{0x1f, 0x9d}},
+		{"max bits", []byte{0x1f, 0x9d, 0x91, 0x41, 0x00}},
+		{"first code", []byte{0x1f, 0x9d, 0x90, 0x00, 0x03}},                    // First code 256
+		{"code beyond table", []byte{0x1f, 0x9d, 0x90, 0x41, 0x08, 0x02, 0x00}}, // 'A' then code 260
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := DecompressZ(bytes.NewReader(tt.data), &out); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// TestUncompressZ tests that uncompress detects Unix compress and gzip data by
+// magic bytes rather than by extension
+func TestUncompressZ(t *testing.T) {
+	want, err := os.ReadFile("testdata/brdc0010.24n")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	lzwData, err := os.ReadFile("testdata/brdc0010.24n.Z")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	var gzData bytes.Buffer
+	zw := gzip.NewWriter(&gzData)
+	zw.Write(want)
+	zw.Close()
+
+	tests := []struct {
+		file string
+		data []byte
+	}{
+		{"brdc0010.24n.Z", lzwData},
+		{"brdc0010.24n.z", lzwData},
+		{"brdc0010.24n.gz", lzwData},
+		{"brdc0010.24n.Z", gzData.Bytes()},
+		{"brdc0010.24n.gz", gzData.Bytes()},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.file)
+		if err := os.WriteFile(path, tt.data, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		var outPath string
+		if stat := uncompress(path, &outPath); stat != 1 {
+			t.Errorf("%s (% x): uncompress returned %d", tt.file, tt.data[:2], stat)
+			continue
+		}
+		if filepath.Base(outPath) != "brdc0010.24n" {
+			t.Errorf("%s: expected output brdc0010.24n, got %s", tt.file, outPath)
+		}
+		if data, err := os.ReadFile(outPath); err != nil || !bytes.Equal(data, want) {
+			t.Errorf("%s (% x): unexpected output (%v)", tt.file, tt.data[:2], err)
+		}
+	}
+}
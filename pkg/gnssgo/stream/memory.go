@@ -0,0 +1,145 @@
+// Package stream provides stream input/output functionality for GNSS data
+package stream
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryChunk is data delivered by a MemoryStream at an offset from the
+// first read
+type MemoryChunk struct {
+	Offset time.Duration // Time of delivery after the first read
+	Data   []byte        // Data
+}
+
+// MemoryStream is an in-memory stream replaying fixed data, to drive parsers
+// and processing pipelines in tests without files, network or hardware. It
+// provides the StreamRead/StreamWrite methods of Stream and io.Reader and
+// io.Writer. The data written are kept and returned by Written.
+type MemoryStream struct {
+	chunks  []MemoryChunk    // Data to deliver in order of offset
+	ic, ib  int              // Chunk and byte in chunk of next read
+	start   time.Time        // Time of the first read
+	closed  bool             // Stream closed
+	written []byte           // Data written
+	now     func() time.Time // Clock for replay
+	lock    sync.Mutex
+}
+
+// NewMemoryStream creates a memory stream delivering data at the first read
+// and each chunk once its offset has elapsed since the first read. Chunks are
+// delivered in order of offset, chunks of the same offset in the given order.
+func NewMemoryStream(data []byte, chunks ...MemoryChunk) *MemoryStream {
+	m := &MemoryStream{now: time.Now}
+	if len(data) > 0 {
+		m.chunks = append(m.chunks, MemoryChunk{Data: data})
+	}
+	for _, c := range chunks {
+		if len(c.Data) > 0 {
+			m.chunks = append(m.chunks, c)
+		}
+	}
+	sort.SliceStable(m.chunks, func(i, j int) bool {
+		return m.chunks[i].Offset < m.chunks[j].Offset
+	})
+	return m
+}
+
+// StreamRead reads up to n bytes of the data due. It returns 0 if no data
+// are due yet, at the end of the data or after close.
+func (m *MemoryStream) StreamRead(buff []byte, n int) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return 0
+	}
+	now := m.now()
+	if m.start.IsZero() {
+		m.start = now
+	}
+	n = min(n, len(buff))
+
+	nr := 0
+	for nr < n && m.ic < len(m.chunks) {
+		c := m.chunks[m.ic]
+		if now.Sub(m.start) < c.Offset {
+			break
+		}
+		k := copy(buff[nr:n], c.Data[m.ib:])
+		nr += k
+		if m.ib += k; m.ib >= len(c.Data) {
+			m.ic, m.ib = m.ic+1, 0
+		}
+	}
+	return nr
+}
+
+// StreamWrite writes n bytes to the stream. It returns 0 after close.
+func (m *MemoryStream) StreamWrite(buff []byte, n int) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return 0
+	}
+	m.written = append(m.written, buff[:n]...)
+	return n
+}
+
+// StreamClose closes the stream
+func (m *MemoryStream) StreamClose() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+}
+
+// StreamStat returns the state of the stream, STR_STAT_ACTIVE until all data
+// are read and STR_STAT_NONE at the end of the data or after close
+func (m *MemoryStream) StreamStat(msg *string) int {
+	if m.EOF() {
+		return STR_STAT_NONE
+	}
+	return STR_STAT_ACTIVE
+}
+
+// EOF reports whether all data are read or the stream is closed
+func (m *MemoryStream) EOF() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.closed || m.ic >= len(m.chunks)
+}
+
+// Read reads the data due as an io.Reader. It returns 0 and no error while
+// the next chunk is not due and io.EOF at the end of the data or after
+// close.
+func (m *MemoryStream) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if n := m.StreamRead(p, len(p)); n > 0 {
+		return n, nil
+	}
+	if m.EOF() {
+		return 0, io.EOF
+	}
+	return 0, nil
+}
+
+// Write writes data to the stream as an io.Writer
+func (m *MemoryStream) Write(p []byte) (int, error) {
+	if n := m.StreamWrite(p, len(p)); n < len(p) {
+		return n, io.ErrClosedPipe
+	}
+	return len(p), nil
+}
+
+// Written returns a copy of the data written to the stream
+func (m *MemoryStream) Written() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]byte(nil), m.written...)
+}
@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestMemoryStream tests the ordered and timed delivery of a memory stream
+func TestMemoryStream(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	m := NewMemoryStream([]byte("seed"),
+		MemoryChunk{Offset: 2 * time.Second, Data: []byte("-third")},
+		MemoryChunk{Offset: time.Second, Data: []byte("-second")},
+		MemoryChunk{Offset: 2 * time.Second, Data: []byte("-fourth")},
+	)
+	m.now = func() time.Time { return now }
+
+	buff := make([]byte, 64)
+	read := func(n int) string {
+		return string(buff[:m.StreamRead(buff, n)])
+	}
+
+	// Seed data at the first read, in parts for a short buffer
+	if got := read(2); got != "se" {
+		t.Errorf("read = %q, want %q", got, "se")
+	}
+	if got := read(64); got != "ed" {
+		t.Errorf("read = %q, want %q", got, "ed")
+	}
+	if got := read(64); got != "" {
+		t.Errorf("read before offset = %q, want none", got)
+	}
+	if m.StreamStat(nil) != STR_STAT_ACTIVE {
+		t.Errorf("state = %d, want active", m.StreamStat(nil))
+	}
+
+	now = start.Add(time.Second)
+	if got := read(64); got != "-second" {
+		t.Errorf("read at 1 s = %q, want %q", got, "-second")
+	}
+
+	// Chunks of the same offset in the given order
+	now = start.Add(5 * time.Second)
+	if got := read(64); got != "-third-fourth" {
+		t.Errorf("read at 5 s = %q, want %q", got, "-third-fourth")
+	}
+	if !m.EOF() || m.StreamStat(nil) != STR_STAT_NONE {
+		t.Errorf("EOF = %v, state = %d at the end", m.EOF(), m.StreamStat(nil))
+	}
+	if got := read(64); got != "" {
+		t.Errorf("read at EOF = %q, want none", got)
+	}
+}
+
+// TestMemoryStreamReader tests the io.Reader and io.Writer of a memory stream
+func TestMemoryStreamReader(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	m := NewMemoryStream(nil, MemoryChunk{Offset: time.Minute, Data: []byte("late")})
+	m.now = func() time.Time { return now }
+
+	buff := make([]byte, 16)
+	if n, err := m.Read(buff); n != 0 || err != nil {
+		t.Errorf("read before offset = %d, %v, want 0, nil", n, err)
+	}
+	now = start.Add(time.Minute)
+	if n, err := m.Read(buff); string(buff[:n]) != "late" || err != nil {
+		t.Errorf("read = %q, %v", buff[:n], err)
+	}
+	if n, err := m.Read(buff); n != 0 || err != io.EOF {
+		t.Errorf("read at end = %d, %v, want 0, EOF", n, err)
+	}
+
+	// All data of an untimed stream by io.ReadAll
+	data := bytes.Repeat([]byte{0xd3, 0x00, 0x13}, 1000)
+	if got, err := io.ReadAll(NewMemoryStream(data)); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll = %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+
+	// Written data kept until close
+	m = NewMemoryStream(nil)
+	if n, err := m.Write([]byte("$GPGGA")); n != 6 || err != nil {
+		t.Errorf("write = %d, %v", n, err)
+	}
+	m.StreamWrite([]byte(",123"), 4)
+	m.StreamClose()
+	if n, err := m.Write([]byte("x")); n != 0 || err == nil {
+		t.Errorf("write after close = %d, %v, want error", n, err)
+	}
+	if got := string(m.Written()); got != "$GPGGA,123" {
+		t.Errorf("written = %q", got)
+	}
+	if n, err := m.Read(buff); n != 0 || err != io.EOF {
+		t.Errorf("read after close = %d, %v, want 0, EOF", n, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -67,38 +68,76 @@ var (
 
 // RTCMMessageStats contains statistics for RTCM messages
 type RTCMMessageStats struct {
-	MessageType  int       // RTCM message type
-	Count        int       // Number of messages received
-	LastReceived time.Time // Time of last message
-	TotalBytes   int       // Total bytes received for this message type
+	MessageType   int       // RTCM message type
+	Count         int       // Number of messages received
+	LastReceived  time.Time // Time of last message
+	TotalBytes    int       // Total bytes received for this message type
+	FirstReceived time.Time // Time of first message (RTCMStatsCollector only)
+	Rate          float64   // Messages per second between the first and last message (RTCMStatsCollector only)
 }
 
+// NTripDropPolicy selects what happens to received data when the message
+// buffer is full
+type NTripDropPolicy int
+
+const (
+	NTripDropOldest NTripDropPolicy = iota // Overwrite the oldest buffered message
+	NTripBlock                             // Stop reading from the caster until there is room
+)
+
+// defaultNTripBufferSize is the number of buffered messages if not configured
+const defaultNTripBufferSize = 100
+
 // CircularBuffer implements a fixed-size circular buffer for RTCM messages
 type CircularBuffer struct {
-	buffer [][]byte   // Buffer to store messages
-	size   int        // Size of the buffer
-	head   int        // Head index
-	tail   int        // Tail index
-	count  int        // Number of items in the buffer
-	mutex  sync.Mutex // Mutex for thread safety
+	buffer  [][]byte        // Buffer to store messages
+	size    int             // Size of the buffer
+	head    int             // Head index
+	tail    int             // Tail index
+	count   int             // Number of items in the buffer
+	policy  NTripDropPolicy // Policy when the buffer is full
+	dropped int             // Number of messages dropped
+	closed  bool            // Buffer closed, Add no longer blocks
+	mutex   sync.Mutex      // Mutex for thread safety
+	notFull *sync.Cond      // Signalled when an item is removed or on close
 }
 
-// NewCircularBuffer creates a new circular buffer with the given size
+// NewCircularBuffer creates a new circular buffer with the given size, the
+// oldest message is dropped when the buffer is full
 func NewCircularBuffer(size int) *CircularBuffer {
-	return &CircularBuffer{
+	return NewCircularBufferPolicy(size, NTripDropOldest)
+}
+
+// NewCircularBufferPolicy creates a new circular buffer with the given size
+// and drop policy
+func NewCircularBufferPolicy(size int, policy NTripDropPolicy) *CircularBuffer {
+	c := &CircularBuffer{
 		buffer: make([][]byte, size),
 		size:   size,
 		head:   0,
 		tail:   0,
 		count:  0,
+		policy: policy,
 	}
+	c.notFull = sync.NewCond(&c.mutex)
+	return c
 }
 
-// Add adds an item to the circular buffer
+// Add adds an item to the circular buffer. If the buffer is full, the oldest
+// item is dropped or, with NTripBlock, Add waits until an item is removed.
+// Items added to a closed blocking buffer that is full are dropped.
 func (c *CircularBuffer) Add(data []byte) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	for c.policy == NTripBlock && c.count == c.size && !c.closed {
+		c.notFull.Wait()
+	}
+	if c.policy == NTripBlock && c.count == c.size {
+		c.dropped++
+		return
+	}
+
 	// Make a copy of the data
 	dataCopy := make([]byte, len(data))
 	copy(dataCopy, data)
@@ -110,6 +149,7 @@ func (c *CircularBuffer) Add(data []byte) {
 	// If the buffer is full, move the tail
 	if c.count == c.size {
 		c.tail = (c.tail + 1) % c.size
+		c.dropped++
 	} else {
 		c.count++
 	}
@@ -129,18 +169,62 @@ func (c *CircularBuffer) GetAll() [][]byte {
 	return result
 }
 
+// Pop removes and returns the oldest item in the circular buffer (nil if empty)
+func (c *CircularBuffer) Pop() []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.count == 0 {
+		return nil
+	}
+	data := c.buffer[c.tail]
+	c.buffer[c.tail] = nil
+	c.tail = (c.tail + 1) % c.size
+	c.count--
+	c.notFull.Signal()
+	return data
+}
+
+// Len returns the number of items in the circular buffer
+func (c *CircularBuffer) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.count
+}
+
+// Dropped returns the number of items dropped because the buffer was full
+func (c *CircularBuffer) Dropped() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.dropped
+}
+
+// Close releases an Add waiting for room, buffered items can still be read
+func (c *CircularBuffer) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	c.notFull.Broadcast()
+}
+
 // NTripConfig contains configuration for an NTRIP connection
 type NTripConfig struct {
-	Server       string        // Server address
-	Port         int           // Server port
-	Mountpoint   string        // Mountpoint
-	Username     string        // Username
-	Password     string        // Password
-	UserAgent    string        // User agent
-	ConnTimeout  time.Duration // Connection timeout
-	RetryTimeout time.Duration // Retry timeout
-	MaxRetries   int           // Maximum number of retries
-	Debug        bool          // Debug mode
+	Server       string          // Server address
+	Port         int             // Server port
+	Mountpoint   string          // Mountpoint
+	Username     string          // Username
+	Password     string          // Password
+	UserAgent    string          // User agent
+	ConnTimeout  time.Duration   // Connection timeout
+	RetryTimeout time.Duration   // Retry timeout
+	MaxRetries   int             // Maximum number of retries
+	Debug        bool            // Debug mode
+	BufferSize   int             // Number of received blocks buffered for reading (0: 100)
+	DropPolicy   NTripDropPolicy // Policy when the buffer is full
+	Proxy        string          // Proxy URL (http://, https:// or socks5://, "": HTTP_PROXY/NO_PROXY)
 }
 
 // We're using the NTrip struct from types.go
@@ -160,11 +244,13 @@ type EnhancedNTrip struct {
 	mutex         sync.Mutex                // Mutex for thread safety
 	messageStats  map[int]*RTCMMessageStats // Message statistics
 	messageBuffer *CircularBuffer           // Message buffer
+	pending       []byte                    // Unread part of the message being read
 	dataRate      float64                   // Data rate in bytes per second
 	lastDataTime  time.Time                 // Last data time
 	totalBytes    int                       // Total bytes received
 	ctx           context.Context           // Context for cancellation
 	cancel        context.CancelFunc        // Cancel function
+	dataCallback  func([]byte)              // Callback for received data
 }
 
 // DefaultNTripConfig returns a default NTRIP configuration
@@ -176,6 +262,8 @@ func DefaultNTripConfig() NTripConfig {
 		RetryTimeout: 5 * time.Second,
 		MaxRetries:   5,
 		Debug:        false,
+		BufferSize:   defaultNTripBufferSize,
+		DropPolicy:   NTripDropOldest,
 	}
 }
 
@@ -184,10 +272,17 @@ func NewEnhancedNTrip(config NTripConfig, ctype int) *EnhancedNTrip {
 	// Create context with timeout
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create HTTP client with appropriate timeouts
+	// Create HTTP client with appropriate timeouts. No overall client timeout is
+	// set as it would also cut the long-lived response body; the connection
+	// attempt is bounded by the context passed to ConnectContext.
+	proxy, err := ProxyFunc(config.Proxy)
+	if err != nil {
+		Tracet(2, "NewEnhancedNTrip: %v, environment proxy used\n", err)
+		proxy = http.ProxyFromEnvironment
+	}
 	client := &http.Client{
-		Timeout: config.ConnTimeout,
 		Transport: &http.Transport{
+			Proxy: proxy,
 			DialContext: (&net.Dialer{
 				Timeout:   10 * time.Second,
 				KeepAlive: 30 * time.Second,
@@ -200,21 +295,40 @@ func NewEnhancedNTrip(config NTripConfig, ctype int) *EnhancedNTrip {
 		},
 	}
 
+	size := config.BufferSize
+	if size <= 0 {
+		size = defaultNTripBufferSize
+	}
+
 	return &EnhancedNTrip{
 		config:        config,
 		state:         0,
 		ctype:         ctype,
 		client:        client,
 		messageStats:  make(map[int]*RTCMMessageStats),
-		messageBuffer: NewCircularBuffer(100), // Store last 100 messages
+		messageBuffer: NewCircularBufferPolicy(size, config.DropPolicy),
 		lastDataTime:  time.Now(),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
 }
 
-// Connect establishes a connection to the NTRIP server with retry logic
+// Connect establishes a connection to the NTRIP server with retry logic.
+// The connection attempt is bounded by the configured connection timeout.
 func (ntrip *EnhancedNTrip) Connect() error {
+	ctx := context.Background()
+	if ntrip.config.ConnTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ntrip.config.ConnTimeout)
+		defer cancel()
+	}
+	return ntrip.ConnectContext(ctx)
+}
+
+// ConnectContext establishes a connection to the NTRIP server with retry logic.
+// The given context only bounds the connection attempt; once connected, the
+// stream lives until Close is called.
+func (ntrip *EnhancedNTrip) ConnectContext(ctx context.Context) error {
 	ntrip.mutex.Lock()
 	defer ntrip.mutex.Unlock()
 
@@ -244,9 +358,13 @@ func (ntrip *EnhancedNTrip) Connect() error {
 	scheme := "http"
 	url := fmt.Sprintf("%s://%s:%d/%s", scheme, ntrip.config.Server, ntrip.config.Port, ntrip.config.Mountpoint)
 
-	// Create the request
-	req, err := http.NewRequestWithContext(ntrip.ctx, "GET", url, nil)
+	// Create the request, aborted by ctx until the response arrives
+	reqCtx, reqCancel := context.WithCancel(ntrip.ctx)
+	stop := context.AfterFunc(ctx, reqCancel)
+	req, err := http.NewRequestWithContext(reqCtx, "GET", url, nil)
 	if err != nil {
+		stop()
+		reqCancel()
 		ntrip.retryCount++
 		ntrip.lastError = fmt.Errorf("%w: failed to create request: %v", ErrNTRIPNetworkError, err)
 		return ntrip.lastError
@@ -262,7 +380,17 @@ func (ntrip *EnhancedNTrip) Connect() error {
 
 	// Send the request
 	resp, err := ntrip.client.Do(req)
+	if !stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		reqCancel()
+		ntrip.retryCount++
+		ntrip.lastError = fmt.Errorf("%w: connection aborted: %w", ErrNTRIPNetworkError, ctx.Err())
+		return ntrip.lastError
+	}
 	if err != nil {
+		reqCancel()
 		ntrip.retryCount++
 		ntrip.lastError = fmt.Errorf("%w: failed to connect: %v", ErrNTRIPNetworkError, err)
 		return ntrip.lastError
@@ -270,15 +398,9 @@ func (ntrip *EnhancedNTrip) Connect() error {
 
 	// Check the response status
 	if resp.StatusCode != http.StatusOK {
+		reqCancel()
 		ntrip.retryCount++
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			ntrip.lastError = fmt.Errorf("%w: invalid credentials", ErrNTRIPAuthFailed)
-		case http.StatusNotFound:
-			ntrip.lastError = fmt.Errorf("%w: mountpoint '%s' not found", ErrNTRIPMountpointInvalid, ntrip.config.Mountpoint)
-		default:
-			ntrip.lastError = fmt.Errorf("%w: server returned status %d", ErrNTRIPServerError, resp.StatusCode)
-		}
+		ntrip.lastError = ntripStatusError(resp.StatusCode, ntrip.config.Mountpoint)
 		resp.Body.Close()
 		return ntrip.lastError
 	}
@@ -290,7 +412,10 @@ func (ntrip *EnhancedNTrip) Connect() error {
 	ntrip.lastDataTime = time.Now()
 
 	// Start a goroutine to read from the response body
-	go ntrip.readResponseBody(resp.Body)
+	go func() {
+		defer reqCancel()
+		ntrip.readResponseBody(resp.Body)
+	}()
 
 	return nil
 }
@@ -308,6 +433,26 @@ func (ntrip *EnhancedNTrip) readResponseBody(body io.ReadCloser) {
 		default:
 			// Read data from the response body
 			n, err := body.Read(buffer)
+
+			// Process data before the error, a reader may return both
+			if n > 0 {
+				ntrip.mutex.Lock()
+				// Process the data
+				ntrip.processData(buffer[:n])
+				callback := ntrip.dataCallback
+				ntrip.mutex.Unlock()
+
+				// Buffer the data outside the lock, Add may wait for ReadNtrip
+				ntrip.messageBuffer.Add(buffer[:n])
+
+				// Push the data to the callback outside the lock
+				if callback != nil {
+					data := make([]byte, n)
+					copy(data, buffer[:n])
+					callback(data)
+				}
+			}
+
 			if err != nil {
 				if err != io.EOF {
 					ntrip.mutex.Lock()
@@ -317,13 +462,6 @@ func (ntrip *EnhancedNTrip) readResponseBody(body io.ReadCloser) {
 				}
 				return
 			}
-
-			if n > 0 {
-				ntrip.mutex.Lock()
-				// Process the data
-				ntrip.processData(buffer[:n])
-				ntrip.mutex.Unlock()
-			}
 		}
 	}
 }
@@ -402,9 +540,6 @@ func (ntrip *EnhancedNTrip) processData(data []byte) {
 	ntrip.totalBytes += len(data)
 	ntrip.lastDataTime = now
 
-	// Add to message buffer
-	ntrip.messageBuffer.Add(data)
-
 	// Parse RTCM messages
 	messages, _ := parseRTCMMessage(data)
 
@@ -515,6 +650,7 @@ func OpenEnhancedNtrip(path string, ctype int, msg *string) *NTrip {
 	config.Mountpoint = mountpoint
 	config.Username = username
 	config.Password = password
+	config.Proxy = strGetProxy()
 
 	// Create the enhanced NTRIP connection
 	enhancedNtrip := NewEnhancedNTrip(config, ctype)
@@ -559,6 +695,7 @@ func (ntrip *EnhancedNTrip) CloseNtrip() {
 
 	// Cancel the context to stop any ongoing operations
 	ntrip.cancel()
+	ntrip.messageBuffer.Close()
 
 	// Close the TCP connection if it exists
 	if ntrip.tcp != nil {
@@ -591,41 +728,35 @@ func (ntrip *EnhancedNTrip) ReadNtrip(buff []byte, n int, msg *string) int {
 		return ntrip.tcp.ReadTcpClient(buff, n, msg)
 	}
 
-	// Otherwise, get data from the message buffer
-	messages := ntrip.messageBuffer.GetAll()
-	if len(messages) == 0 {
-		// Try to read from the HTTP response if available
+	// Otherwise, drain the message buffer in FIFO order up to n bytes. A message
+	// that does not fit is continued on the next call.
+	bytesToCopy := 0
+	for bytesToCopy < n {
+		if len(ntrip.pending) == 0 {
+			if ntrip.pending = ntrip.messageBuffer.Pop(); ntrip.pending == nil {
+				break
+			}
+		}
+		m := copy(buff[bytesToCopy:n], ntrip.pending)
+		ntrip.pending = ntrip.pending[m:]
+		bytesToCopy += m
+	}
+	if bytesToCopy == 0 {
 		select {
 		case <-ctx.Done():
 			// Timeout or cancelled
 			if msg != nil {
 				*msg = "Read timeout"
 			}
-			return 0
 		default:
 			// No data available yet
 			if msg != nil {
 				*msg = "No data available"
 			}
-			return 0
-		}
-	}
-
-	// Use the most recent message
-	latestMsg := messages[len(messages)-1]
-
-	// Check if the buffer is large enough
-	if len(latestMsg) > n {
-		if msg != nil {
-			*msg = fmt.Sprintf("Buffer too small: need %d bytes, have %d bytes", len(latestMsg), n)
 		}
 		return 0
 	}
 
-	// Copy data to the output buffer
-	bytesToCopy := len(latestMsg)
-	copy(buff, latestMsg[:bytesToCopy])
-
 	// Log the read operation if debug is enabled
 	if ntrip.config.Debug {
 		Tracet(4, "ReadNtrip: read %d bytes\n", bytesToCopy)
@@ -758,6 +889,12 @@ func (ntrip *EnhancedNTrip) GetDataRate() float64 {
 	return ntrip.dataRate
 }
 
+// GetDroppedMessages returns the number of received blocks dropped because
+// the message buffer was full
+func (ntrip *EnhancedNTrip) GetDroppedMessages() int {
+	return ntrip.messageBuffer.Dropped()
+}
+
 // GetLastMessages returns the last N messages received
 func (ntrip *EnhancedNTrip) GetLastMessages() [][]byte {
 	ntrip.mutex.Lock()
@@ -782,6 +919,96 @@ func (ntrip *EnhancedNTrip) GetLastError() error {
 	return ntrip.lastError
 }
 
+// NTripStats is a snapshot of the diagnostics of an NTRIP connection
+type NTripStats struct {
+	State      int                      // State (0:close, 1:wait, 2:connect)
+	DataRate   float64                  // Data rate in bytes per second
+	TotalBytes int                      // Total bytes received
+	Dropped    int                      // Received blocks dropped because the buffer was full
+	LastError  error                    // Last error, nil if none
+	Messages   map[int]RTCMMessageStats // Statistics per RTCM message type
+}
+
+// GetStats returns the state, throughput, last error and RTCM message
+// statistics of the connection in one snapshot
+func (ntrip *EnhancedNTrip) GetStats() NTripStats {
+	ntrip.mutex.Lock()
+	defer ntrip.mutex.Unlock()
+
+	stats := NTripStats{
+		State:      ntrip.state,
+		DataRate:   ntrip.dataRate,
+		TotalBytes: ntrip.totalBytes,
+		Dropped:    ntrip.messageBuffer.Dropped(),
+		LastError:  ntrip.lastError,
+		Messages:   make(map[int]RTCMMessageStats, len(ntrip.messageStats)),
+	}
+	for k, v := range ntrip.messageStats {
+		stats.Messages[k] = *v
+	}
+	return stats
+}
+
+// SetDataCallback sets a callback invoked with every chunk of data received
+// from the server, in order. The callback runs on the reading goroutine and
+// receives a copy of the data; the message buffer is still filled as before.
+// Pass nil to remove the callback.
+func (ntrip *EnhancedNTrip) SetDataCallback(fn func([]byte)) {
+	ntrip.mutex.Lock()
+	defer ntrip.mutex.Unlock()
+
+	ntrip.dataCallback = fn
+}
+
+// SetProxy sets the proxy of the connection, an http://, https:// or
+// socks5:// URL or a host:port of an HTTP proxy. With "" the proxy is taken
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. The
+// proxy applies from the next Connect.
+func (ntrip *EnhancedNTrip) SetProxy(proxyURL string) error {
+	proxy, err := ProxyFunc(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	ntrip.mutex.Lock()
+	defer ntrip.mutex.Unlock()
+
+	ntrip.config.Proxy = proxyURL
+	if transport, ok := ntrip.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+		transport = transport.Clone()
+		transport.Proxy = proxy
+		ntrip.client.Transport = transport
+	}
+	return nil
+}
+
+// ProxyFunc returns the proxy function of an http.Transport for a proxy URL
+// with the scheme http, https or socks5, or a host:port of an HTTP proxy.
+// Credentials of the proxy are given as user:password@ in the URL. With ""
+// the proxy is taken from the environment (http.ProxyFromEnvironment).
+func ProxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: no host", proxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
 // SetDebug sets the debug mode
 func (ntrip *EnhancedNTrip) SetDebug(debug bool) {
 	ntrip.mutex.Lock()
@@ -799,6 +1026,7 @@ func (ntrip *EnhancedNTrip) Close() {
 	if ntrip.cancel != nil {
 		ntrip.cancel()
 	}
+	ntrip.messageBuffer.Close()
 
 	// Close the TCP connection if it exists
 	if ntrip.tcp != nil {
@@ -2,13 +2,18 @@ package stream
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestEnhancedNTripConnect tests the Connect method of the EnhancedNTrip struct
@@ -268,17 +273,59 @@ func TestReadNtrip(t *testing.T) {
 		t.Errorf("Expected error message 'Not connected to NTRIP server', got '%s'", msg)
 	}
 
-	// Test buffer too small
+	// Test message larger than the read buffer is split across reads
 	ntrip.state = 2
 	largeMessage := make([]byte, 200)
+	for i := range largeMessage {
+		largeMessage[i] = byte(i)
+	}
 	ntrip.messageBuffer.Add(largeMessage)
-	msg = ""
-	n = ntrip.ReadNtrip(buff, len(buff), &msg)
-	if n != 0 {
-		t.Errorf("Expected 0 bytes read when buffer too small, got %d", n)
+	var got []byte
+	for i := 0; i < 2; i++ {
+		msg = ""
+		n = ntrip.ReadNtrip(buff, len(buff), &msg)
+		if n != len(buff) {
+			t.Fatalf("Expected to read %d bytes, got %d (%s)", len(buff), n, msg)
+		}
+		got = append(got, buff[:n]...)
+	}
+	if !bytes.Equal(largeMessage, got) {
+		t.Errorf("Expected %v, got %v", largeMessage, got)
+	}
+	if n = ntrip.ReadNtrip(buff, len(buff), &msg); n != 0 {
+		t.Errorf("Expected 0 bytes read from drained buffer, got %d", n)
+	}
+}
+
+// TestReadNtripFIFO tests that consecutive reads drain all buffered messages in order
+func TestReadNtripFIFO(t *testing.T) {
+	ntrip := NewEnhancedNTrip(DefaultNTripConfig(), 1)
+	ntrip.state = 2
+
+	// Add several messages of different sizes
+	var sent []byte
+	for i := 0; i < 10; i++ {
+		message := bytes.Repeat([]byte{byte(i)}, 10+i*7)
+		ntrip.messageBuffer.Add(message)
+		sent = append(sent, message...)
+	}
+
+	// Read them back with a buffer not aligned to the message boundaries
+	var received []byte
+	buff := make([]byte, 33)
+	var msg string
+	for {
+		n := ntrip.ReadNtrip(buff, len(buff), &msg)
+		if n == 0 {
+			break
+		}
+		received = append(received, buff[:n]...)
+	}
+	if !bytes.Equal(sent, received) {
+		t.Errorf("Expected %d bytes in order, got %d bytes: %v", len(sent), len(received), received)
 	}
-	if !strings.Contains(msg, "Buffer too small") {
-		t.Errorf("Expected error message containing 'Buffer too small', got '%s'", msg)
+	if msg != "No data available" {
+		t.Errorf("Expected 'No data available', got '%s'", msg)
 	}
 }
 
@@ -382,3 +429,396 @@ func TestOpenEnhancedNtrip(t *testing.T) {
 	// Close the connection
 	ntrip.CloseNtrip()
 }
+
+// TestEnhancedNTripDataCallback tests that the data callback receives every byte in order
+func TestEnhancedNTripDataCallback(t *testing.T) {
+	// Create the data the server will stream in many small chunks
+	sent := make([]byte, 64*1024)
+	for i := range sent {
+		sent[i] = byte(i * 7)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+		for i := 0; i < len(sent); i += 500 {
+			end := i + 500
+			if end > len(sent) {
+				end = len(sent)
+			}
+			w.Write(sent[i:end])
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultNTripConfig()
+	serverURL := strings.TrimPrefix(server.URL, "http://")
+	parts := strings.Split(serverURL, ":")
+	config.Server = parts[0]
+	config.Port, _ = strconv.Atoi(parts[1])
+	config.Mountpoint = "TEST"
+
+	ntrip := NewEnhancedNTrip(config, 1)
+
+	var mutex sync.Mutex
+	var received []byte
+	done := make(chan struct{})
+	ntrip.SetDataCallback(func(data []byte) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, data...)
+		if len(received) == len(sent) {
+			close(done)
+		}
+	})
+
+	if err := ntrip.Connect(); err != nil {
+		t.Fatalf("Failed to connect to NTRIP server: %v", err)
+	}
+	defer ntrip.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		mutex.Lock()
+		t.Fatalf("Timed out: received %d of %d bytes", len(received), len(sent))
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !bytes.Equal(received, sent) {
+		t.Error("Received data does not match the data sent")
+	}
+}
+
+// TestEnhancedNTripConnectContext tests that a cancelled context aborts a slow connection
+func TestEnhancedNTripConnectContext(t *testing.T) {
+	// Create a server that does not answer until the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := DefaultNTripConfig()
+	serverURL := strings.TrimPrefix(server.URL, "http://")
+	parts := strings.Split(serverURL, ":")
+	config.Server = parts[0]
+	config.Port, _ = strconv.Atoi(parts[1])
+	config.Mountpoint = "TEST"
+
+	ntrip := NewEnhancedNTrip(config, 1)
+	defer ntrip.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ntrip.ConnectContext(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected connection to abort promptly, took %s", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if ntrip.GetState() != 0 {
+		t.Errorf("Expected state 0, got %d", ntrip.GetState())
+	}
+}
+
+// produceNtrip writes count blocks of 10 bytes to the response body read by
+// ntrip and returns the number of blocks written so far and a channel closed
+// once the body is read to the end
+func produceNtrip(ntrip *EnhancedNTrip, count int) (*atomic.Int32, chan struct{}) {
+	pr, pw := io.Pipe()
+	written := new(atomic.Int32)
+	done := make(chan struct{})
+	go func() {
+		ntrip.readResponseBody(pr)
+		close(done)
+	}()
+	go func() {
+		for i := 0; i < count; i++ {
+			if _, err := pw.Write(bytes.Repeat([]byte{byte(i)}, 10)); err != nil {
+				return
+			}
+			written.Add(1)
+		}
+		pw.Close()
+	}()
+	return written, done
+}
+
+// TestNtripBufferDropOldest tests that a small buffer keeps the newest blocks
+// of a fast producer
+func TestNtripBufferDropOldest(t *testing.T) {
+	config := DefaultNTripConfig()
+	config.BufferSize = 4
+	ntrip := NewEnhancedNTrip(config, 1)
+	defer ntrip.Close()
+	ntrip.state = 2
+
+	_, done := produceNtrip(ntrip, 20)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Producer blocked with drop-oldest policy")
+	}
+	if n := ntrip.GetDroppedMessages(); n != 16 {
+		t.Errorf("Expected 16 dropped blocks, got %d", n)
+	}
+	var want []byte
+	for i := 16; i < 20; i++ {
+		want = append(want, bytes.Repeat([]byte{byte(i)}, 10)...)
+	}
+	buff := make([]byte, 100)
+	var msg string
+	if n := ntrip.ReadNtrip(buff, len(buff), &msg); !bytes.Equal(buff[:n], want) {
+		t.Errorf("Expected the newest 4 blocks %v, got %v", want, buff[:n])
+	}
+}
+
+// TestNtripBufferBlock tests that a small blocking buffer stalls a fast
+// producer until the data are read and loses nothing
+func TestNtripBufferBlock(t *testing.T) {
+	config := DefaultNTripConfig()
+	config.BufferSize = 4
+	config.DropPolicy = NTripBlock
+	ntrip := NewEnhancedNTrip(config, 1)
+	defer ntrip.Close()
+	ntrip.state = 2
+
+	written, done := produceNtrip(ntrip, 20)
+	time.Sleep(100 * time.Millisecond)
+
+	// 4 blocks buffered, 1 waiting to be added and 1 in the pipe
+	if n := written.Load(); n > 6 {
+		t.Errorf("Expected the producer to block, %d blocks written", n)
+	}
+	var received []byte
+	buff := make([]byte, 15)
+	var msg string
+	for deadline := time.Now().Add(5 * time.Second); len(received) < 200 && time.Now().Before(deadline); {
+		n := ntrip.ReadNtrip(buff, len(buff), &msg)
+		if n == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		received = append(received, buff[:n]...)
+	}
+	<-done
+	var want []byte
+	for i := 0; i < 20; i++ {
+		want = append(want, bytes.Repeat([]byte{byte(i)}, 10)...)
+	}
+	if !bytes.Equal(received, want) {
+		t.Errorf("Expected all 20 blocks in order, got %v", received)
+	}
+	if n := ntrip.GetDroppedMessages(); n != 0 {
+		t.Errorf("Expected no dropped blocks, got %d", n)
+	}
+}
+
+// TestNtripBufferBlockClose tests that closing the connection releases a
+// producer blocked on a full buffer
+func TestNtripBufferBlockClose(t *testing.T) {
+	config := DefaultNTripConfig()
+	config.BufferSize = 2
+	config.DropPolicy = NTripBlock
+	ntrip := NewEnhancedNTrip(config, 1)
+	ntrip.state = 2
+
+	_, done := produceNtrip(ntrip, 10)
+	time.Sleep(50 * time.Millisecond)
+	ntrip.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reader still blocked after Close")
+	}
+}
+
+// TestStreamNtripStats tests the diagnostics of an NTRIP client stream read
+// through the legacy path and the registry
+func TestStreamNtripStats(t *testing.T) {
+	var data []byte
+	for i := 0; i < 3; i++ {
+		data = append(data, testRTCMFrame(1005, 19)...)
+		data = append(data, testRTCMFrame(1077, 300)...)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var stream Stream
+	stream.InitStream()
+	if _, ok := stream.NtripStats(); ok {
+		t.Errorf("Expected no statistics of a closed stream")
+	}
+	path := "user:pass@" + strings.TrimPrefix(server.URL, "http://") + "/TEST"
+	if stream.OpenStream(STR_NTRIPCLI, STR_MODE_R, path) == 0 {
+		t.Fatalf("Failed to open stream: %s", stream.Msg)
+	}
+	defer stream.StreamClose()
+
+	buff := make([]byte, 256)
+	deadline := time.Now().Add(2 * time.Second)
+	for total := 0; total < len(data); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Read %d of %d bytes", total, len(data))
+		}
+		n := stream.StreamRead(buff, len(buff))
+		if n <= 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		total += n
+	}
+
+	stats, ok := stream.NtripStats()
+	if !ok {
+		t.Fatalf("Expected statistics of an NTRIP client stream")
+	}
+	if stats.State != 2 || stats.LastError != nil {
+		t.Errorf("Expected state 2 without error, got %d %v", stats.State, stats.LastError)
+	}
+	if stats.TotalBytes != len(data) {
+		t.Errorf("Expected %d bytes, got %d", len(data), stats.TotalBytes)
+	}
+	for _, msgType := range []int{1005, 1077} {
+		if stats.Messages[msgType].Count != 3 {
+			t.Errorf("%d: expected 3 messages, got %d", msgType, stats.Messages[msgType].Count)
+		}
+	}
+
+	// The same statistics through the registry
+	enhancedNtrip := GetEnhancedNTripFromRegistry(stream.Port.(*NTrip))
+	if enhancedNtrip == nil {
+		t.Fatalf("Expected the stream registered")
+	}
+	if got := enhancedNtrip.GetStats(); got.TotalBytes != stats.TotalBytes || len(got.Messages) != len(stats.Messages) {
+		t.Errorf("Expected registry statistics %+v, got %+v", stats, got)
+	}
+
+	// A broken connection is reported as the last error
+	server.CloseClientConnections()
+	for stats.LastError == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		stats, _ = stream.NtripStats()
+	}
+	if !errors.Is(stats.LastError, ErrNTRIPNetworkError) || stats.State != 0 {
+		t.Errorf("Expected network error and state 0, got %v %d", stats.LastError, stats.State)
+	}
+
+	// A legacy instance without an enhanced connection has no statistics
+	if _, ok := (&NTrip{}).Stats(); ok {
+		t.Errorf("Expected no statistics of an unregistered instance")
+	}
+}
+
+// newProxyStub starts a forward HTTP proxy stub answering for the caster
+// caster.invalid:2101/MOUNT, it counts the requests with the caster and
+// proxy credentials
+func newProxyStub(t *testing.T, proxied *atomic.Int32) string {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the caster
+		if r.URL.Host != "caster.invalid:2101" || r.URL.Path != "/MOUNT" {
+			t.Errorf("proxied URL %s", r.URL)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if user, passwd, ok := r.BasicAuth(); !ok || user != "user" || passwd != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if auth := r.Header.Get("Proxy-Authorization"); auth != "Basic cHVzZXI6cHBhc3M=" { // puser:ppass
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		proxied.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("RTCM data"))
+	}))
+	t.Cleanup(proxy.Close)
+	return "puser:ppass@" + strings.TrimPrefix(proxy.URL, "http://")
+}
+
+// TestEnhancedNTripProxy tests that the connection to the caster goes through
+// the proxy with the basic authentication of the caster
+func TestEnhancedNTripProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := newProxyStub(t, &proxied)
+
+	config := DefaultNTripConfig()
+	config.Server = "caster.invalid"
+	config.Port = 2101
+	config.Mountpoint = "MOUNT"
+	config.Username = "user"
+	config.Password = "pass"
+	config.ConnTimeout = 5 * time.Second
+
+	// Proxy of the configuration
+	config.Proxy = "http://" + proxy
+	ntrip := NewEnhancedNTrip(config, 1)
+	if err := ntrip.Connect(); err != nil {
+		t.Fatalf("Connect through proxy: %v", err)
+	}
+	ntrip.Close()
+
+	// Proxy set on the connection, host:port without scheme
+	config.Proxy = ""
+	ntrip = NewEnhancedNTrip(config, 1)
+	if err := ntrip.SetProxy(proxy); err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+	if err := ntrip.Connect(); err != nil {
+		t.Fatalf("Connect through proxy: %v", err)
+	}
+	ntrip.Close()
+
+	// Proxy of the streams opened afterwards
+	StrSetProxy(proxy)
+	defer StrSetProxy("")
+	var msg string
+	legacy := OpenEnhancedNtrip("user:pass@caster.invalid:2101/MOUNT", 1, &msg)
+	if legacy == nil {
+		t.Fatalf("OpenEnhancedNtrip through proxy: %s", msg)
+	}
+	legacy.CloseNtrip()
+
+	if n := proxied.Load(); n != 3 {
+		t.Errorf("Expected 3 proxied requests, got %d", n)
+	}
+}
+
+// TestProxyFunc tests the proxy URLs accepted
+func TestProxyFunc(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://caster.invalid:2101/MOUNT", nil)
+	for proxy, want := range map[string]string{
+		"proxy:3128":                 "http://proxy:3128",
+		"http://proxy:3128":          "http://proxy:3128",
+		"https://user:pw@proxy:3129": "https://user:pw@proxy:3129",
+		"socks5://proxy:1080":        "socks5://proxy:1080",
+	} {
+		fn, err := ProxyFunc(proxy)
+		if err != nil {
+			t.Errorf("ProxyFunc(%q): %v", proxy, err)
+			continue
+		}
+		if u, _ := fn(req); u == nil || u.String() != want {
+			t.Errorf("ProxyFunc(%q) = %v, want %s", proxy, u, want)
+		}
+	}
+	for _, proxy := range []string{"ftp://proxy:21", "http://", "http://proxy:port"} {
+		if _, err := ProxyFunc(proxy); err == nil {
+			t.Errorf("ProxyFunc(%q) accepted", proxy)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package stream provides stream input/output functionality for GNSS data
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ErrNTRIPNoRTCM is returned by ProbeMountpoint when no valid RTCM frame was received
+var ErrNTRIPNoRTCM = errors.New("no valid RTCM data received")
+
+// ProbeResult contains the result of probing an NTRIP mountpoint
+type ProbeResult struct {
+	Mountpoint      string          // Probed mountpoint
+	Duration        time.Duration   // Time spent collecting data
+	Bytes           int             // Total bytes received
+	Messages        map[int]int     // Number of valid frames per message type
+	Rates           map[int]float64 // Message rate per message type (msg/s)
+	CRCErrors       int             // Number of frames failing the CRC check
+	StationPosition bool            // Station coordinates (1005/1006) seen
+}
+
+// MessageTypes returns the received message types in ascending order
+func (r ProbeResult) MessageTypes() []int {
+	types := make([]int, 0, len(r.Messages))
+	for t := range r.Messages {
+		types = append(types, t)
+	}
+	sort.Ints(types)
+	return types
+}
+
+// ProbeMountpoint connects to an NTRIP mountpoint, collects data for the given
+// duration and reports the RTCM 3 message types and rates found in the stream.
+// Frames are only counted if their CRC-24Q is valid. An error wrapping
+// ErrNTRIPNoRTCM is returned with the result if no valid frame was received.
+func ProbeMountpoint(config NTripConfig, duration time.Duration) (ProbeResult, error) {
+	result := ProbeResult{
+		Mountpoint: config.Mountpoint,
+		Messages:   make(map[int]int),
+		Rates:      make(map[int]float64),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Bound the connection attempt by the connection timeout (0: no timeout)
+	var timer *time.Timer
+	if config.ConnTimeout > 0 {
+		timer = time.AfterFunc(config.ConnTimeout, cancel)
+	}
+
+	url := fmt.Sprintf("http://%s:%d/%s", config.Server, config.Port, config.Mountpoint)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		if timer != nil {
+			timer.Stop()
+		}
+		return result, fmt.Errorf("%w: failed to create request: %v", ErrNTRIPNetworkError, err)
+	}
+	req.Header.Set("User-Agent", config.UserAgent)
+	if config.Username != "" {
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: config.ConnTimeout,
+			}).DialContext,
+		},
+	}
+	resp, err := client.Do(req)
+	if timer != nil && !timer.Stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		return result, fmt.Errorf("%w: connection to %s timed out", ErrNTRIPTimeout, url)
+	}
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to connect: %v", ErrNTRIPNetworkError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, ntripStatusError(resp.StatusCode, config.Mountpoint)
+	}
+
+	// Collect data until the probe duration expires
+	start := time.Now()
+	time.AfterFunc(duration, cancel)
+
+	var data []byte
+	buffer := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buffer)
+		if n > 0 {
+			result.Bytes += n
+			data = append(data, buffer[:n]...)
+			data = result.scanFrames(data)
+		}
+		if err != nil {
+			break
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if sec := result.Duration.Seconds(); sec > 0 {
+		for t, count := range result.Messages {
+			result.Rates[t] = float64(count) / sec
+		}
+	}
+	if len(result.Messages) == 0 {
+		return result, fmt.Errorf("%w: %d bytes from mountpoint '%s'", ErrNTRIPNoRTCM,
+			result.Bytes, config.Mountpoint)
+	}
+	return result, nil
+}
+
+// scanFrames counts the complete RTCM 3 frames in data and returns the
+// unprocessed remainder
+func (r *ProbeResult) scanFrames(data []byte) []byte {
+	data, crcErrors := scanRTCM3(data, func(msgType, size int) {
+		r.Messages[msgType]++
+		if msgType == 1005 || msgType == 1006 {
+			r.StationPosition = true
+		}
+	})
+	r.CRCErrors += crcErrors
+	return data
+}
+
+// ntripStatusError converts a caster HTTP status code into an NTRIP error
+func ntripStatusError(status int, mountpoint string) error {
+	switch status {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: invalid credentials", ErrNTRIPAuthFailed)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: mountpoint '%s' not found", ErrNTRIPMountpointInvalid, mountpoint)
+	default:
+		return fmt.Errorf("%w: server returned status %d", ErrNTRIPServerError, status)
+	}
+}
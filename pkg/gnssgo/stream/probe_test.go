@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testRTCMFrame builds an RTCM 3 frame of the given type with a valid CRC-24Q
+func testRTCMFrame(msgType, length int) []byte {
+	frame := make([]byte, length+6)
+	frame[0] = 0xD3
+	frame[1] = byte(length >> 8 & 0x03)
+	frame[2] = byte(length)
+	frame[3] = byte(msgType >> 4)
+	frame[4] = byte(msgType << 4)
+	crc := crc24q(frame[:length+3])
+	frame[length+3] = byte(crc >> 16)
+	frame[length+4] = byte(crc >> 8)
+	frame[length+5] = byte(crc)
+	return frame
+}
+
+// testProbeConfig returns an NTRIP configuration pointing at a test server
+func testProbeConfig(t *testing.T, server *httptest.Server, mountpoint string) NTripConfig {
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to parse server address: %v", err)
+	}
+	config := DefaultNTripConfig()
+	config.Server = host
+	config.Port, _ = strconv.Atoi(port)
+	config.Mountpoint = mountpoint
+	config.ConnTimeout = 2 * time.Second
+	return config
+}
+
+// TestProbeMountpoint tests probing a mock caster streaming known frames
+func TestProbeMountpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/RTCM3" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+
+		// One corrupted frame followed by station coordinates and MSM7 at 20 Hz
+		bad := testRTCMFrame(1077, 40)
+		bad[10] ^= 0xFF
+		w.Write(bad)
+		for i := 0; ; i++ {
+			if i%10 == 0 {
+				w.Write(testRTCMFrame(1005, 19))
+			}
+			w.Write(testRTCMFrame(1077, 120))
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	result, err := ProbeMountpoint(testProbeConfig(t, server, "RTCM3"), 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ProbeMountpoint failed: %v", err)
+	}
+	if !result.StationPosition {
+		t.Error("Expected station position message to be seen")
+	}
+	types := result.MessageTypes()
+	if len(types) != 2 || types[0] != 1005 || types[1] != 1077 {
+		t.Errorf("Expected message types [1005 1077], got %v", types)
+	}
+	if result.CRCErrors != 1 {
+		t.Errorf("Expected 1 CRC error, got %d", result.CRCErrors)
+	}
+	if rate := result.Rates[1077]; rate < 10 || rate > 30 {
+		t.Errorf("Expected 1077 rate around 20 msg/s, got %.1f", rate)
+	}
+
+	// Unknown mountpoint
+	_, err = ProbeMountpoint(testProbeConfig(t, server, "NONE"), 100*time.Millisecond)
+	if !errors.Is(err, ErrNTRIPMountpointInvalid) {
+		t.Errorf("Expected ErrNTRIPMountpointInvalid, got %v", err)
+	}
+}
+
+// TestProbeMountpointNoTimeout tests probing with a configuration without a
+// connection timeout
+func TestProbeMountpointNoTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(testRTCMFrame(1005, 19))
+	}))
+	defer server.Close()
+
+	config := testProbeConfig(t, server, "RTCM3")
+	config.ConnTimeout = 0
+	result, err := ProbeMountpoint(config, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ProbeMountpoint failed: %v", err)
+	}
+	if result.Messages[1005] != 1 {
+		t.Errorf("Expected one 1005 message, got %d", result.Messages[1005])
+	}
+}
+
+// TestProbeMountpointNoRTCM tests probing a mountpoint without RTCM data
+func TestProbeMountpointNoRTCM(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("$GPGGA,not,rtcm*00\r\n"))
+	}))
+	defer server.Close()
+
+	result, err := ProbeMountpoint(testProbeConfig(t, server, "NMEA"), 100*time.Millisecond)
+	if !errors.Is(err, ErrNTRIPNoRTCM) {
+		t.Fatalf("Expected ErrNTRIPNoRTCM, got %v", err)
+	}
+	if result.Bytes == 0 {
+		t.Error("Expected received bytes to be reported")
+	}
+}
@@ -0,0 +1,50 @@
+package stream
+
+// RTCM 3 framing shared by ProbeMountpoint and RTCMStatsCollector.
+//
+// The stream package cannot use rtcm.RTCMParser or gnssgo.Rtk_CRC24q: both
+// the gnssgo and rtcm packages import stream, so importing them here would be
+// an import cycle. Only the frame boundaries, message types and CRC are needed
+// to count messages, which this file provides without decoding the payload.
+
+// scanRTCM3 calls fn with the message type and size (bytes) of each complete
+// RTCM 3 frame with a valid CRC-24Q in data. It returns the unprocessed
+// remainder and the number of frames failing the CRC check.
+func scanRTCM3(data []byte, fn func(msgType, size int)) ([]byte, int) {
+	crcErrors := 0
+	for len(data) >= 6 {
+		if data[0] != 0xD3 {
+			data = data[1:]
+			continue
+		}
+		length := (int(data[1])<<8 | int(data[2])) & 0x03FF
+		if len(data) < length+6 {
+			break
+		}
+		frame := data[:length+6]
+		crc := uint32(frame[length+3])<<16 | uint32(frame[length+4])<<8 | uint32(frame[length+5])
+		if length < 2 || crc24q(frame[:length+3]) != crc {
+			crcErrors++
+			data = data[1:] // resync on the next preamble
+			continue
+		}
+		fn(int(frame[3])<<4|int(frame[4])>>4, len(frame))
+		data = data[length+6:]
+	}
+	return data, crcErrors
+}
+
+// crc24q computes the CRC-24Q checksum used by RTCM 3
+func crc24q(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
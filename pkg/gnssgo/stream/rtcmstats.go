@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// RTCMStatsCollector counts the RTCM 3 messages of a byte stream per message
+// type. Frames are only counted if their CRC-24Q is valid. The frames are
+// found by scanRTCM3 rather than rtcm.RTCMParser, which stream cannot import
+// (see rtcm3.go). It is safe for concurrent use.
+type RTCMStatsCollector struct {
+	mutex     sync.Mutex
+	buffer    []byte                    // Incomplete frame
+	stats     map[int]*RTCMMessageStats // Statistics per message type
+	crcErrors int                       // Number of frames failing the CRC check
+	now       func() time.Time          // Clock for message times
+}
+
+// NewRTCMStatsCollector creates a new RTCM message statistics collector
+func NewRTCMStatsCollector() *RTCMStatsCollector {
+	return &RTCMStatsCollector{
+		stats: make(map[int]*RTCMMessageStats),
+		now:   time.Now,
+	}
+}
+
+// Write feeds stream data to the collector, it never fails
+func (c *RTCMStatsCollector) Write(data []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	c.buffer = append(c.buffer, data...)
+	rest, crcErrors := scanRTCM3(c.buffer, func(msgType, size int) {
+		s, ok := c.stats[msgType]
+		if !ok {
+			s = &RTCMMessageStats{MessageType: msgType, FirstReceived: now}
+			c.stats[msgType] = s
+		}
+		s.Count++
+		s.TotalBytes += size
+		s.LastReceived = now
+	})
+	c.crcErrors += crcErrors
+
+	// Keep the incomplete frame at the start of the buffer
+	c.buffer = append(c.buffer[:0], rest...)
+	return len(data), nil
+}
+
+// Snapshot returns a copy of the statistics per message type. TotalBytes
+// includes the frame header and CRC.
+func (c *RTCMStatsCollector) Snapshot() map[int]RTCMMessageStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	snapshot := make(map[int]RTCMMessageStats, len(c.stats))
+	for msgType, s := range c.stats {
+		stats := *s
+		if sec := s.LastReceived.Sub(s.FirstReceived).Seconds(); s.Count > 1 && sec > 0 {
+			stats.Rate = float64(s.Count-1) / sec
+		}
+		snapshot[msgType] = stats
+	}
+	return snapshot
+}
+
+// CRCErrors returns the number of frames that failed the CRC check
+func (c *RTCMStatsCollector) CRCErrors() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.crcErrors
+}
+
+// Reset clears the statistics and any buffered data
+func (c *RTCMStatsCollector) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.buffer = c.buffer[:0]
+	c.stats = make(map[int]*RTCMMessageStats)
+	c.crcErrors = 0
+}
+
+// EnableRTCMStats enables the RTCM message statistics of the data read from
+// the stream and returns the collector. The statistics are off by default;
+// enabling them again returns the existing collector.
+func (stream *Stream) EnableRTCMStats() *RTCMStatsCollector {
+	stream.StreamLock()
+	defer stream.StreamUnlock()
+	if stream.RTCMStats == nil {
+		stream.RTCMStats = NewRTCMStatsCollector()
+	}
+	return stream.RTCMStats
+}
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRTCMStatsCollector tests the per-type counts and rates of RTCM messages
+func TestRTCMStatsCollector(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	c := NewRTCMStatsCollector()
+	c.now = func() time.Time { return now }
+
+	// 10 s of 1 Hz 1077 and 1004, 1005 every 5 s, with frames split across
+	// writes and a corrupted frame
+	for i := 0; i < 10; i++ {
+		var epoch []byte
+		epoch = append(epoch, testRTCMFrame(1004, 100)...)
+		epoch = append(epoch, testRTCMFrame(1077, 200)...)
+		if i%5 == 0 {
+			epoch = append(epoch, testRTCMFrame(1005, 19)...)
+		}
+		if i == 3 {
+			bad := testRTCMFrame(1077, 200)
+			bad[50] ^= 0xFF
+			epoch = append(epoch, bad...)
+		}
+		c.Write(epoch[:150])
+		c.Write(epoch[150:])
+		now = now.Add(time.Second)
+	}
+
+	stats := c.Snapshot()
+	tests := []struct {
+		msgType int
+		count   int
+		bytes   int
+		last    time.Duration
+		rate    float64
+	}{
+		{1004, 10, 10 * 106, 9 * time.Second, 1.0},
+		{1077, 10, 10 * 206, 9 * time.Second, 1.0},
+		{1005, 2, 2 * 25, 5 * time.Second, 0.2},
+	}
+	if len(stats) != len(tests) {
+		t.Errorf("Expected %d message types, got %d", len(tests), len(stats))
+	}
+	for _, tt := range tests {
+		s, ok := stats[tt.msgType]
+		if !ok {
+			t.Errorf("Message type %d missing", tt.msgType)
+			continue
+		}
+		if s.MessageType != tt.msgType || s.Count != tt.count || s.TotalBytes != tt.bytes {
+			t.Errorf("%d: got type %d count %d bytes %d, want count %d bytes %d", tt.msgType,
+				s.MessageType, s.Count, s.TotalBytes, tt.count, tt.bytes)
+		}
+		if !s.LastReceived.Equal(start.Add(tt.last)) {
+			t.Errorf("%d: last received %v, want %v", tt.msgType, s.LastReceived, start.Add(tt.last))
+		}
+		if s.Rate < tt.rate-1e-9 || s.Rate > tt.rate+1e-9 {
+			t.Errorf("%d: rate %.3f msg/s, want %.3f", tt.msgType, s.Rate, tt.rate)
+		}
+	}
+	if n := c.CRCErrors(); n != 1 {
+		t.Errorf("Expected 1 CRC error, got %d", n)
+	}
+
+	c.Reset()
+	if len(c.Snapshot()) != 0 || c.CRCErrors() != 0 {
+		t.Errorf("Expected no statistics after reset")
+	}
+}
+
+// TestStreamRTCMStats tests the RTCM statistics of the data read from a stream
+func TestStreamRTCMStats(t *testing.T) {
+	var data []byte
+	for i := 0; i < 3; i++ {
+		data = append(data, testRTCMFrame(1005, 19)...)
+		data = append(data, testRTCMFrame(1077, 300)...)
+		data = append(data, testRTCMFrame(1087, 250)...)
+	}
+	path := filepath.Join(t.TempDir(), "rtcm3.dat")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var stream Stream
+	stream.InitStream()
+	if stream.OpenStream(STR_FILE, STR_MODE_R, path) == 0 {
+		t.Fatalf("Failed to open stream: %s", stream.Msg)
+	}
+	defer stream.StreamClose()
+	if stream.RTCMStats != nil {
+		t.Fatalf("Expected RTCM statistics off by default")
+	}
+	collector := stream.EnableRTCMStats()
+	if stream.EnableRTCMStats() != collector {
+		t.Errorf("Expected the same collector when enabled again")
+	}
+
+	buff := make([]byte, 128)
+	for total := 0; total < len(data); {
+		n := stream.StreamRead(buff, len(buff))
+		if n <= 0 {
+			t.Fatalf("Read %d of %d bytes", total, len(data))
+		}
+		total += n
+	}
+
+	stats := collector.Snapshot()
+	for _, msgType := range []int{1005, 1077, 1087} {
+		if stats[msgType].Count != 3 {
+			t.Errorf("%d: expected 3 messages, got %d", msgType, stats[msgType].Count)
+		}
+	}
+}
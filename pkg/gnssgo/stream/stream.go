@@ -2,7 +2,11 @@ package stream
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo/util"
 )
@@ -32,7 +36,8 @@ func (stream *Stream) InitStream() {
 	stream.Msg = ""
 }
 
-// OpenStream opens a stream
+// OpenStream opens a stream. If DiscardDuration is set, the input read
+// during that period after open is dropped.
 func (stream *Stream) OpenStream(ctype, mode int, path string) int {
 	Tracet(3, "stropen: type=%d mode=%d path=%s\n", ctype, mode, path)
 
@@ -45,6 +50,10 @@ func (stream *Stream) OpenStream(ctype, mode int, path string) int {
 	stream.InByeTick, stream.OutByteTick = 0, 0
 	stream.Msg = ""
 	stream.Port = nil
+	stream.discardUntil = time.Time{}
+	if stream.DiscardDuration > 0 {
+		stream.discardUntil = time.Now().Add(stream.DiscardDuration)
+	}
 
 	switch byte(ctype) {
 	case STR_SERIAL:
@@ -85,15 +94,18 @@ func (stream *Stream) OpenStream(ctype, mode int, path string) int {
 
 	default:
 		stream.State = 0
+		stream.stats.reset(0)
 		return 1
 	}
 
 	if stream.Port == nil {
 		stream.State = -1
+		stream.stats.reset(-1)
 		return 0
 	}
 
 	stream.State = 1
+	stream.stats.reset(1)
 	return 1
 }
 
@@ -137,6 +149,7 @@ func (stream *Stream) StreamClose() {
 	stream.Type = 0
 	stream.Mode = 0
 	stream.State = 0
+	stream.stats.state.Store(0)
 	stream.InBytes = 0
 	stream.InRate = 0
 	stream.OutBytes = 0
@@ -184,6 +197,9 @@ func (stream *Stream) StreamRead(buff []byte, n int) int {
 	case STR_UDPSVR:
 		nr = stream.Port.(*UdpConn).ReadUdpSvr(buff, n, &msg)
 
+	case STR_UDPCLI:
+		nr = stream.Port.(*UdpConn).ReadUdpClient(buff, n, &msg)
+
 	case STR_MEMBUF:
 		nr = stream.Port.(*MemBuf).ReadMemBuf(buff, n, &msg)
 
@@ -200,6 +216,16 @@ func (stream *Stream) StreamRead(buff []byte, n int) int {
 
 	stream.Msg = msg
 
+	// Drop the input of the discard period after open
+	if nr > 0 && !stream.discardUntil.IsZero() {
+		if time.Now().Before(stream.discardUntil) {
+			Tracet(4, "strread: discard n=%d\n", nr)
+			nr = 0
+		} else {
+			stream.discardUntil = time.Time{}
+		}
+	}
+
 	if nr > 0 {
 		stream.InBytes += uint32(nr)
 		tick = TickGet()
@@ -207,8 +233,15 @@ func (stream *Stream) StreamRead(buff []byte, n int) int {
 			stream.InRate = (stream.InBytes - stream.InByeTick) * 1000 / uint32(tick-stream.TickInput)
 			stream.TickInput = tick
 			stream.InByeTick = stream.InBytes
+			storeRate(&stream.stats.inRate, &stream.stats.peakInRate, stream.InRate)
 		}
 		stream.TickActive = tick
+		stream.stats.inBytes.Add(uint64(nr))
+		stream.stats.tickIn.Store(tick)
+		stream.stats.lastActive.Store(time.Now().UnixNano())
+		if stream.RTCMStats != nil {
+			stream.RTCMStats.Write(buff[:nr])
+		}
 	}
 
 	stream.StreamUnlock()
@@ -274,14 +307,28 @@ func (stream *Stream) StreamWrite(buff []byte, n int) int {
 			stream.OutRate = (stream.OutBytes - stream.OutByteTick) * 1000 / uint32(tick-stream.TickOutput)
 			stream.TickOutput = tick
 			stream.OutByteTick = stream.OutBytes
+			storeRate(&stream.stats.outRate, &stream.stats.peakOutRate, stream.OutRate)
 		}
 		stream.TickActive = tick
+		stream.stats.outBytes.Add(uint64(ns))
+		stream.stats.tickOut.Store(tick)
+		stream.stats.lastActive.Store(time.Now().UnixNano())
 	}
 
 	stream.StreamUnlock()
 	return ns
 }
 
+// Write writes data to the stream as an io.Writer. A short write, e.g. to a
+// stream without connection, is reported as io.ErrShortWrite.
+func (stream *Stream) Write(p []byte) (int, error) {
+	n := stream.StreamWrite(p, len(p))
+	if n < len(p) {
+		return max(n, 0), io.ErrShortWrite
+	}
+	return n, nil
+}
+
 // StreamSendNmea sends NMEA GGA message to stream
 func (stream *Stream) StreamSendNmea(sol interface{}) {
 	var (
@@ -359,6 +406,80 @@ func (stream *Stream) StrSendCmd(cmd string) {
 	stream.StreamSendCmd(cmd)
 }
 
+// SetReconnect sets the reconnection with backoff of a TCP client stream (see
+// TcpClient.SetReconnect). It has no effect on other stream types.
+func (stream *Stream) SetReconnect(enabled bool, initial, max time.Duration) {
+	stream.StreamLock()
+	defer stream.StreamUnlock()
+
+	if tcpcli, ok := stream.Port.(*TcpClient); ok && byte(stream.Type) == STR_TCPCLI {
+		tcpcli.SetReconnect(enabled, initial, max)
+	}
+}
+
+// NtripStats returns the data rate, last error and RTCM message statistics
+// of an NTRIP server or client stream. It returns false for other stream
+// types and for streams without an open connection.
+func (stream *Stream) NtripStats() (NTripStats, bool) {
+	stream.StreamLock()
+	defer stream.StreamUnlock()
+
+	ntrip, ok := stream.Port.(*NTrip)
+	if !ok || (byte(stream.Type) != STR_NTRIPSVR && byte(stream.Type) != STR_NTRIPCLI) {
+		return NTripStats{}, false
+	}
+	return ntrip.Stats()
+}
+
+// Stats returns the throughput statistics of the stream since open. It does
+// not take the stream lock, so it does not wait for a blocking read or write.
+// The rates are averaged over the same time as InRate and OutRate.
+func (stream *Stream) Stats() StreamStats {
+	c := &stream.stats
+	st := StreamStats{
+		State:       int(c.state.Load()),
+		InBytes:     c.inBytes.Load(),
+		OutBytes:    c.outBytes.Load(),
+		InRate:      c.inRate.Load(),
+		OutRate:     c.outRate.Load(),
+		PeakInRate:  c.peakInRate.Load(),
+		PeakOutRate: c.peakOutRate.Load(),
+	}
+	tick := TickGet()
+	if tick-c.tickIn.Load() > uint32(tirate) {
+		st.InRate = 0
+	}
+	if tick-c.tickOut.Load() > uint32(tirate) {
+		st.OutRate = 0
+	}
+	if t := c.lastActive.Load(); t != 0 {
+		st.LastActivity = time.Unix(0, t)
+	}
+	return st
+}
+
+// reset resets the statistics on open of a stream
+func (c *streamCounters) reset(state int) {
+	c.state.Store(int32(state))
+	c.inBytes.Store(0)
+	c.outBytes.Store(0)
+	c.inRate.Store(0)
+	c.outRate.Store(0)
+	c.peakInRate.Store(0)
+	c.peakOutRate.Store(0)
+	c.tickIn.Store(0)
+	c.tickOut.Store(0)
+	c.lastActive.Store(0)
+}
+
+// storeRate stores a rate and its peak. The caller must hold the stream lock.
+func storeRate(rate, peak *atomic.Uint64, r uint32) {
+	rate.Store(uint64(r))
+	if uint64(r) > peak.Load() {
+		peak.Store(uint64(r))
+	}
+}
+
 // StreamGetState gets stream state
 func (stream *Stream) StreamGetState() int {
 	if stream.Port == nil {
@@ -445,3 +566,23 @@ func (stream *Stream) StreamStat(msg *string) int {
 
 	return state
 }
+
+// proxyLock protects proxyaddr
+var proxyLock sync.Mutex
+
+// StrSetProxy sets the proxy of the NTRIP streams opened afterwards, an
+// http://, https:// or socks5:// URL or a host:port of an HTTP proxy. With ""
+// the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+func StrSetProxy(addr string) {
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+	proxyaddr = addr
+}
+
+// strGetProxy returns the proxy set by StrSetProxy
+func strGetProxy() string {
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+	return proxyaddr
+}
@@ -199,6 +199,19 @@ func (ntrip *NTrip) StatExNtrip(msg *string) int {
 	return ntrip.state
 }
 
+// Stats returns the diagnostics of the enhanced NTRIP connection behind the
+// legacy NTRIP instance, false if it has none
+func (ntrip *NTrip) Stats() (NTripStats, bool) {
+	if ntrip == nil {
+		return NTripStats{}, false
+	}
+	enhancedNtrip := GetEnhancedNTripFromRegistry(ntrip)
+	if enhancedNtrip == nil {
+		return NTripStats{}, false
+	}
+	return enhancedNtrip.GetStats(), true
+}
+
 // NTripc methods
 func (ntripc *NTripc) CloseNtripc()                                       {}
 func (ntripc *NTripc) ReadNtripc(buff []byte, size int, msg *string) int  { return 0 }
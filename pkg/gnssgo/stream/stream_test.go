@@ -251,3 +251,111 @@ func TestStreamTimeout(t *testing.T) {
 	// Close the stream
 	stream.StreamClose()
 }
+
+func TestStreamDiscardDuration(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "warmup.dat")
+	if err := os.WriteFile(tempFile, []byte("\x00\xffboot garbage"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var stream Stream
+	stream.InitStream()
+	stream.DiscardDuration = 200 * time.Millisecond
+	if stream.OpenStream(STR_FILE, STR_MODE_R, tempFile) <= 0 {
+		t.Fatalf("Failed to open stream: %s", stream.Msg)
+	}
+	defer stream.StreamClose()
+	start := time.Now()
+
+	// Bytes during the warm-up are dropped
+	buff := make([]byte, 100)
+	if n := stream.StreamRead(buff, 100); n != 0 {
+		t.Errorf("Expected 0 bytes during warm-up, got %q", buff[:n])
+	}
+	if stream.InBytes != 0 {
+		t.Errorf("Expected no input bytes during warm-up, got %d", stream.InBytes)
+	}
+
+	// Later bytes pass through
+	f, err := os.OpenFile(tempFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	f.Write([]byte("$GPGGA"))
+	f.Close()
+	time.Sleep(stream.DiscardDuration - time.Since(start) + 10*time.Millisecond)
+	n := stream.StreamRead(buff, 100)
+	if string(buff[:n]) != "$GPGGA" {
+		t.Errorf("Expected %q after warm-up, got %q", "$GPGGA", buff[:n])
+	}
+
+	// Reopening starts a new warm-up
+	stream.StreamClose()
+	if stream.OpenStream(STR_FILE, STR_MODE_R, tempFile) <= 0 {
+		t.Fatalf("Failed to reopen stream: %s", stream.Msg)
+	}
+	if n := stream.StreamRead(buff, 100); n != 0 {
+		t.Errorf("Expected 0 bytes after reopen, got %q", buff[:n])
+	}
+}
+
+// TestStreamStats writes and reads known byte counts and checks the totals
+// and rates of Stats
+func TestStreamStats(t *testing.T) {
+	defer func(r int) { tirate = r }(tirate)
+	tirate = 20
+
+	tempFile := filepath.Join(t.TempDir(), "stats.dat")
+	chunk := make([]byte, 1000)
+
+	var out Stream
+	out.InitStream()
+	if out.OpenStream(STR_FILE, STR_MODE_W, tempFile) <= 0 {
+		t.Fatalf("Failed to open stream: %s", out.Msg)
+	}
+	if st := out.Stats(); st.State != 1 || st.OutBytes != 0 || !st.LastActivity.IsZero() {
+		t.Errorf("Stats after open = %+v", st)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		time.Sleep(25 * time.Millisecond)
+		out.StreamWrite(chunk, len(chunk))
+	}
+	st := out.Stats()
+	if st.OutBytes != 5000 || st.InBytes != 0 {
+		t.Errorf("Expected 5000 bytes out, got in=%d out=%d", st.InBytes, st.OutBytes)
+	}
+	if st.OutRate == 0 || st.PeakOutRate < st.OutRate {
+		t.Errorf("Expected output rate, got rate=%d peak=%d", st.OutRate, st.PeakOutRate)
+	}
+	if st.LastActivity.Before(start) {
+		t.Errorf("Last activity %v before start %v", st.LastActivity, start)
+	}
+	out.StreamClose()
+
+	var in Stream
+	in.InitStream()
+	if in.OpenStream(STR_FILE, STR_MODE_R, tempFile) <= 0 {
+		t.Fatalf("Failed to open stream: %s", in.Msg)
+	}
+	buff := make([]byte, 1000)
+	for i := 0; i < 5; i++ {
+		time.Sleep(25 * time.Millisecond)
+		in.StreamRead(buff, len(buff))
+	}
+	st = in.Stats()
+	if st.InBytes != 5000 || st.OutBytes != 0 {
+		t.Errorf("Expected 5000 bytes in, got in=%d out=%d", st.InBytes, st.OutBytes)
+	}
+	if st.InRate == 0 || st.PeakInRate < st.InRate {
+		t.Errorf("Expected input rate, got rate=%d peak=%d", st.InRate, st.PeakInRate)
+	}
+
+	// No current rate without input, totals kept after close
+	time.Sleep(50 * time.Millisecond)
+	in.StreamClose()
+	st = in.Stats()
+	if st.State != 0 || st.InBytes != 5000 || st.InRate != 0 || st.PeakInRate == 0 {
+		t.Errorf("Stats after close = %+v", st)
+	}
+}
@@ -2,6 +2,7 @@
 package stream
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
@@ -153,26 +154,8 @@ func (tcpcli *TcpClient) ReadTcpClient(buff []byte, n int, msg *string) int {
 	}
 
 	// Check connection status
-	if tcpcli.svr.state == 0 {
-		// Try to reconnect if not connected
-		if tcpcli.tirecon > 0 &&
-			(int64(TickGet())-tcpcli.svr.tdis) > int64(tcpcli.tirecon) {
-			tcpcli.svr.tcon = 0
-		}
-		if tcpcli.svr.tcon == 0 {
-			// Try to connect
-			if tcpcli.svr.GenTcp(1, msg) == 0 {
-				tcpcli.svr.tcon = 1000
-				return 0
-			}
-		} else {
-			// Wait for reconnect timeout
-			tcpcli.svr.tcon -= 100
-			if tcpcli.svr.tcon < 0 {
-				tcpcli.svr.tcon = 0
-			}
-			return 0
-		}
+	if !tcpcli.connect(msg) {
+		return 0
 	}
 
 	// Check socket
@@ -201,17 +184,14 @@ func (tcpcli *TcpClient) ReadTcpClient(buff []byte, n int, msg *string) int {
 		if msg != nil {
 			*msg = fmt.Sprintf("tcp read error: %s", err.Error())
 		}
-		conn.Close()
-		tcpcli.svr.sock = nil
-		tcpcli.svr.state = 0
-		tcpcli.svr.tcon = tcpcli.tirecon
-		tcpcli.svr.tdis = int64(TickGet())
+		tcpcli.disconnect(TickGet())
 		return 0
 	}
 
 	// Update activity time
 	if nr > 0 {
 		tcpcli.svr.tact = int64(TickGet())
+		tcpcli.delay = tcpcli.tiinit
 	} else {
 		// Check for inactive timeout
 		tick = TickGet()
@@ -221,13 +201,7 @@ func (tcpcli *TcpClient) ReadTcpClient(buff []byte, n int, msg *string) int {
 			if msg != nil {
 				*msg = "tcp timeout"
 			}
-			if conn, ok := tcpcli.svr.sock.(net.Conn); ok {
-				conn.Close()
-			}
-			tcpcli.svr.sock = nil
-			tcpcli.svr.state = 0
-			tcpcli.svr.tcon = tcpcli.tirecon
-			tcpcli.svr.tdis = int64(tick)
+			tcpcli.disconnect(tick)
 			return 0
 		}
 	}
@@ -249,26 +223,8 @@ func (tcpcli *TcpClient) WriteTcpClient(buff []byte, n int, msg *string) int {
 	}
 
 	// Check connection status
-	if tcpcli.svr.state == 0 {
-		// Try to reconnect if not connected
-		if tcpcli.tirecon > 0 &&
-			(int64(TickGet())-tcpcli.svr.tdis) > int64(tcpcli.tirecon) {
-			tcpcli.svr.tcon = 0
-		}
-		if tcpcli.svr.tcon == 0 {
-			// Try to connect
-			if tcpcli.svr.GenTcp(1, msg) == 0 {
-				tcpcli.svr.tcon = 1000
-				return 0
-			}
-		} else {
-			// Wait for reconnect timeout
-			tcpcli.svr.tcon -= 100
-			if tcpcli.svr.tcon < 0 {
-				tcpcli.svr.tcon = 0
-			}
-			return 0
-		}
+	if !tcpcli.connect(msg) {
+		return 0
 	}
 
 	// Check socket
@@ -297,11 +253,7 @@ func (tcpcli *TcpClient) WriteTcpClient(buff []byte, n int, msg *string) int {
 		if msg != nil {
 			*msg = fmt.Sprintf("tcp write error: %s", err.Error())
 		}
-		conn.Close()
-		tcpcli.svr.sock = nil
-		tcpcli.svr.state = 0
-		tcpcli.svr.tcon = tcpcli.tirecon
-		tcpcli.svr.tdis = int64(TickGet())
+		tcpcli.disconnect(TickGet())
 		return 0
 	}
 
@@ -313,6 +265,82 @@ func (tcpcli *TcpClient) WriteTcpClient(buff []byte, n int, msg *string) int {
 	return ns
 }
 
+// SetReconnect sets the reconnection of a TCP client. If enabled, a lost
+// connection is re-established by the following reads and writes, which return
+// no data until then. The delay between attempts starts at initial and doubles
+// after each failed attempt up to max. It is reset by a successful read.
+func (tcpcli *TcpClient) SetReconnect(enabled bool, initial, max time.Duration) {
+	if tcpcli == nil {
+		return
+	}
+	if max < initial {
+		max = initial
+	}
+	tcpcli.recon = enabled
+	tcpcli.tiinit, tcpcli.timax = initial, max
+	tcpcli.delay = initial
+	tcpcli.tnext = time.Time{}
+}
+
+// connect connects a disconnected TCP client, it returns false while the
+// client is not connected
+func (tcpcli *TcpClient) connect(msg *string) bool {
+	if tcpcli.svr.state > 0 {
+		return true
+	}
+	if tcpcli.recon {
+		// Reconnect with backoff
+		now := time.Now()
+		if now.Before(tcpcli.tnext) {
+			return false
+		}
+		if tcpcli.svr.GenTcp(1, msg) == 0 {
+			if tcpcli.delay *= 2; tcpcli.delay > tcpcli.timax {
+				tcpcli.delay = tcpcli.timax
+			}
+			tcpcli.tnext = now.Add(tcpcli.delay)
+			Tracet(2, "ReconnectTcpClient: retry in %v\n", tcpcli.delay)
+			return false
+		}
+		Tracet(2, "ReconnectTcpClient: reconnected to %s:%d\n", tcpcli.svr.saddr, tcpcli.svr.port)
+		return true
+	}
+
+	// Try to reconnect if not connected
+	if tcpcli.tirecon > 0 &&
+		(int64(TickGet())-tcpcli.svr.tdis) > int64(tcpcli.tirecon) {
+		tcpcli.svr.tcon = 0
+	}
+	if tcpcli.svr.tcon == 0 {
+		// Try to connect
+		if tcpcli.svr.GenTcp(1, msg) == 0 {
+			tcpcli.svr.tcon = 1000
+			return false
+		}
+		return true
+	}
+	// Wait for reconnect timeout
+	tcpcli.svr.tcon -= 100
+	if tcpcli.svr.tcon < 0 {
+		tcpcli.svr.tcon = 0
+	}
+	return false
+}
+
+// disconnect closes the connection of a TCP client
+func (tcpcli *TcpClient) disconnect(tick uint32) {
+	if conn, ok := tcpcli.svr.sock.(net.Conn); ok {
+		conn.Close()
+	}
+	tcpcli.svr.sock = nil
+	tcpcli.svr.state = 0
+	tcpcli.svr.tcon = tcpcli.tirecon
+	tcpcli.svr.tdis = int64(tick)
+	if tcpcli.recon {
+		tcpcli.tnext = time.Now().Add(tcpcli.delay)
+	}
+}
+
 // StateXTcpClient returns the state of a TCP client
 func (tcpcli *TcpClient) StateXTcpClient(msg *string) int {
 	return tcpcli.svr.state
@@ -320,6 +348,9 @@ func (tcpcli *TcpClient) StateXTcpClient(msg *string) int {
 
 // OpenTcpSvr opens a TCP server
 // path format: :port
+// Clients are accepted in the background up to MAXCLI. Data written to the
+// server is sent to all connected clients and data received from any client
+// is returned by ReadTcpSvr.
 func OpenTcpSvr(path string, msg *string) *TcpSvr {
 	var (
 		tcpsvr *TcpSvr = new(TcpSvr)
@@ -345,12 +376,6 @@ func OpenTcpSvr(path string, msg *string) *TcpSvr {
 	tcpsvr.svr.saddr = ""
 	tcpsvr.svr.tcon = 0
 
-	// Initialize client connections
-	for i := 0; i < MAXCLI; i++ {
-		tcpsvr.cli[i].state = 0
-		tcpsvr.cli[i].sock = nil
-	}
-
 	// Create server socket
 	addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -371,12 +396,20 @@ func OpenTcpSvr(path string, msg *string) *TcpSvr {
 
 	// Store the listener
 	tcpsvr.svr.sock = listener
+	tcpsvr.svr.addr = listener.Addr()
+	tcpsvr.svr.port = listener.Addr().(*net.TCPAddr).Port
 	tcpsvr.svr.state = 1
 
+	// Accept clients
+	tcpsvr.wg.Add(1)
+	go tcpsvr.acceptClients(listener)
+
 	return tcpsvr
 }
 
 // CloseTcpSvr closes a TCP server
+// The listener and all client connections are closed and the server
+// goroutines are waited for.
 func (tcpsvr *TcpSvr) CloseTcpSvr() {
 	Tracet(3, "CloseTcpSvr:\n")
 
@@ -384,25 +417,169 @@ func (tcpsvr *TcpSvr) CloseTcpSvr() {
 		return
 	}
 
+	tcpsvr.lock.Lock()
+	tcpsvr.svr.state = 0
+
+	// Close server socket
+	if listener, ok := tcpsvr.svr.sock.(*net.TCPListener); ok {
+		listener.Close()
+	}
+	tcpsvr.svr.sock = nil
+
 	// Close client connections
 	for i := 0; i < MAXCLI; i++ {
-		if tcpsvr.cli[i].state > 0 && tcpsvr.cli[i].sock != nil {
-			if conn, ok := tcpsvr.cli[i].sock.(net.Conn); ok {
-				conn.Close()
+		tcpsvr.disconnect(i, nil)
+	}
+	tcpsvr.ibuf = nil
+	tcpsvr.lock.Unlock()
+
+	tcpsvr.wg.Wait()
+}
+
+// Addr returns the listening address of a TCP server (nil: not opened)
+func (tcpsvr *TcpSvr) Addr() net.Addr {
+	if tcpsvr == nil {
+		return nil
+	}
+	return tcpsvr.svr.addr
+}
+
+// Clients returns the number of connected clients of a TCP server
+func (tcpsvr *TcpSvr) Clients() int {
+	if tcpsvr == nil {
+		return 0
+	}
+	tcpsvr.lock.Lock()
+	defer tcpsvr.lock.Unlock()
+
+	return tcpsvr.clients()
+}
+
+// clients counts connected clients (lock held)
+func (tcpsvr *TcpSvr) clients() int {
+	n := 0
+	for i := 0; i < MAXCLI; i++ {
+		if tcpsvr.cli[i].state > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// acceptClients accepts client connections until the listener is closed
+func (tcpsvr *TcpSvr) acceptClients(listener *net.TCPListener) {
+	defer tcpsvr.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
 			}
-			tcpsvr.cli[i].sock = nil
-			tcpsvr.cli[i].state = 0
+			Tracet(2, "acceptClients: accept error: %v\n", err)
+			time.Sleep(time.Duration(ticonnect) * time.Millisecond)
+			continue
 		}
+		tcpsvr.addClient(conn)
 	}
+}
 
-	// Close server socket
-	if tcpsvr.svr.sock != nil {
-		if listener, ok := tcpsvr.svr.sock.(*net.TCPListener); ok {
-			listener.Close()
+// addClient registers a connection in a free client slot
+func (tcpsvr *TcpSvr) addClient(conn net.Conn) {
+	tcpsvr.lock.Lock()
+	defer tcpsvr.lock.Unlock()
+
+	i := 0
+	for ; i < MAXCLI; i++ {
+		if tcpsvr.cli[i].state == 0 {
+			break
+		}
+	}
+	if tcpsvr.svr.state == 0 || i >= MAXCLI {
+		Tracet(2, "addClient: connection rejected addr=%s\n", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+	Tracet(3, "addClient: connected addr=%s slot=%d\n", conn.RemoteAddr(), i)
+
+	tcpsvr.cli[i].state = 2
+	tcpsvr.cli[i].sock = conn
+	tcpsvr.cli[i].addr = conn.RemoteAddr()
+	tcpsvr.cli[i].saddr = conn.RemoteAddr().String()
+	tcpsvr.cli[i].tact = int64(TickGet())
+	tcpsvr.send[i] = make(chan []byte, TCPSVR_SENDQ)
+	tcpsvr.svr.state = 2
+
+	tcpsvr.wg.Add(2)
+	go tcpsvr.readClient(i, conn)
+	go tcpsvr.writeClient(i, conn, tcpsvr.send[i])
+}
+
+// disconnect closes the connection of a client slot (lock held)
+// With conn not nil, the slot is closed only if it still holds conn.
+func (tcpsvr *TcpSvr) disconnect(i int, conn net.Conn) {
+	if tcpsvr.cli[i].state == 0 || (conn != nil && tcpsvr.cli[i].sock != conn) {
+		return
+	}
+	Tracet(3, "disconnect: addr=%s slot=%d\n", tcpsvr.cli[i].saddr, i)
+
+	if c, ok := tcpsvr.cli[i].sock.(net.Conn); ok {
+		c.Close()
+	}
+	close(tcpsvr.send[i])
+	tcpsvr.send[i] = nil
+	tcpsvr.cli[i].sock = nil
+	tcpsvr.cli[i].state = 0
+	tcpsvr.cli[i].tdis = int64(TickGet())
+
+	if tcpsvr.svr.state > 0 && tcpsvr.clients() == 0 {
+		tcpsvr.svr.state = 1
+	}
+}
+
+// readClient appends data received from a client to the input buffer
+func (tcpsvr *TcpSvr) readClient(i int, conn net.Conn) {
+	defer tcpsvr.wg.Done()
+
+	buff := make([]byte, defaultTcpBuffSize)
+	for {
+		nr, err := conn.Read(buff)
+		tcpsvr.lock.Lock()
+		if nr > 0 {
+			tcpsvr.ibuf = append(tcpsvr.ibuf, buff[:nr]...)
+
+			// Discard the oldest data if not read
+			if over := len(tcpsvr.ibuf) - TCPSVR_MAXIBUF; over > 0 {
+				tcpsvr.ibuf = tcpsvr.ibuf[over:]
+			}
+			tcpsvr.cli[i].tact = int64(TickGet())
+		}
+		if err != nil {
+			tcpsvr.disconnect(i, conn)
+			tcpsvr.lock.Unlock()
+			return
+		}
+		tcpsvr.lock.Unlock()
+	}
+}
+
+// writeClient sends the queued data to a client
+func (tcpsvr *TcpSvr) writeClient(i int, conn net.Conn, send chan []byte) {
+	defer tcpsvr.wg.Done()
+
+	for data := range send {
+		conn.SetWriteDeadline(time.Now().Add(time.Duration(toinact) * time.Millisecond))
+		if _, err := conn.Write(data); err != nil {
+			tcpsvr.lock.Lock()
+			tcpsvr.disconnect(i, conn)
+			tcpsvr.lock.Unlock()
+
+			// Drain the queue until closed by disconnect
+			for range send {
+			}
+			return
 		}
-		tcpsvr.svr.sock = nil
 	}
-	tcpsvr.svr.state = 0
 }
 
 // Accept_nb accepts a non-blocking connection
@@ -420,144 +597,73 @@ func Accept_nb(listener *net.TCPListener) net.Conn {
 	return conn
 }
 
-// ReadTcpSvr reads data from a TCP server
+// ReadTcpSvr reads data received from the clients of a TCP server
 func (tcpsvr *TcpSvr) ReadTcpSvr(buff []byte, n int, msg *string) int {
-	var (
-		nr  int
-		err error
-		i   int
-	)
-
 	Tracet(4, "ReadTcpSvr: n=%d\n", n)
 
 	if tcpsvr == nil {
 		return 0
 	}
+	tcpsvr.lock.Lock()
+	defer tcpsvr.lock.Unlock()
 
-	// Accept new client connections
-	if tcpsvr.svr.state > 0 {
-		// Find free client slot
-		for i = 0; i < MAXCLI; i++ {
-			if tcpsvr.cli[i].state == 0 {
-				break
-			}
-		}
-		if i < MAXCLI {
-			// Accept connection
-			if listener, ok := tcpsvr.svr.sock.(*net.TCPListener); ok {
-				conn := Accept_nb(listener)
-				if conn != nil {
-					// Connection accepted
-					tcpsvr.cli[i].sock = conn
-					tcpsvr.cli[i].state = 1
-					tcpsvr.cli[i].tact = int64(TickGet())
-				}
-			}
-		}
-	}
-
-	// Read data from clients
-	for i = 0; i < MAXCLI; i++ {
-		if tcpsvr.cli[i].state == 0 || tcpsvr.cli[i].sock == nil {
-			continue
-		}
-
-		// Get the connection
-		conn, ok := tcpsvr.cli[i].sock.(net.Conn)
-		if !ok {
-			tcpsvr.cli[i].sock = nil
-			tcpsvr.cli[i].state = 0
-			continue
-		}
-
-		// Set read deadline
-		conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
-
-		// Read data
-		nr, err = conn.Read(buff[:n])
-		if err != nil {
-			// Handle connection error
-			conn.Close()
-			tcpsvr.cli[i].sock = nil
-			tcpsvr.cli[i].state = 0
-			continue
-		}
-
-		// Update activity time
-		if nr > 0 {
-			tcpsvr.cli[i].tact = int64(TickGet())
-			return nr
-		}
+	nr := copy(buff[:n], tcpsvr.ibuf)
+	tcpsvr.ibuf = tcpsvr.ibuf[nr:]
+	if len(tcpsvr.ibuf) == 0 {
+		tcpsvr.ibuf = nil
 	}
-
-	return 0
+	return nr
 }
 
-// WriteTcpSvr writes data to a TCP server
+// WriteTcpSvr writes data to all clients of a TCP server
+// The data is queued for each client without blocking. A client whose send
+// queue is full is too slow and is disconnected.
 func (tcpsvr *TcpSvr) WriteTcpSvr(buff []byte, n int, msg *string) int {
-	var (
-		i, ns int
-		err   error
-	)
-
 	Tracet(4, "WriteTcpSvr: n=%d\n", n)
 
-	if tcpsvr == nil {
+	if tcpsvr == nil || n <= 0 {
 		return 0
 	}
+	data := make([]byte, n)
+	copy(data, buff[:n])
 
-	// Write data to all clients
-	for i = 0; i < MAXCLI; i++ {
-		if tcpsvr.cli[i].state == 0 || tcpsvr.cli[i].sock == nil {
-			continue
-		}
-
-		// Get the connection
-		conn, ok := tcpsvr.cli[i].sock.(net.Conn)
-		if !ok {
-			tcpsvr.cli[i].sock = nil
-			tcpsvr.cli[i].state = 0
-			continue
-		}
+	tcpsvr.lock.Lock()
+	defer tcpsvr.lock.Unlock()
 
-		// Set write deadline
-		conn.SetWriteDeadline(time.Now().Add(1 * time.Second))
-
-		// Write data
-		ns, err = conn.Write(buff[:n])
-		if err != nil {
-			// Handle connection error
-			conn.Close()
-			tcpsvr.cli[i].sock = nil
-			tcpsvr.cli[i].state = 0
+	for i := 0; i < MAXCLI; i++ {
+		if tcpsvr.cli[i].state == 0 {
 			continue
 		}
-
-		// Update activity time
-		if ns > 0 {
+		select {
+		case tcpsvr.send[i] <- data:
 			tcpsvr.cli[i].tact = int64(TickGet())
+		default:
+			Tracet(2, "WriteTcpSvr: slow client dropped addr=%s\n", tcpsvr.cli[i].saddr)
+			tcpsvr.disconnect(i, nil)
 		}
 	}
-
 	return n
 }
 
-// StateXTcpSvr returns the state of a TCP server
+// StateXTcpSvr returns the state of a TCP server (0:close,1:wait,2:connect)
+// and the connected clients in msg
 func (tcpsvr *TcpSvr) StateXTcpSvr(msg *string) int {
-	var (
-		state int
-		i     int
-	)
-
 	if tcpsvr == nil {
 		return 0
 	}
-
-	// Count active client connections
-	state = tcpsvr.svr.state
-	for i = 0; i < MAXCLI; i++ {
-		if tcpsvr.cli[i].state > 0 {
-			state++
+	tcpsvr.lock.Lock()
+	defer tcpsvr.lock.Unlock()
+
+	state := tcpsvr.svr.state
+	if msg != nil {
+		*msg = "tcpsvr:\n"
+		*msg += fmt.Sprintf("  state   = %d\n", state)
+		*msg += fmt.Sprintf("  port    = %d\n", tcpsvr.svr.port)
+		*msg += fmt.Sprintf("  clients = %d\n", tcpsvr.clients())
+		for i := 0; i < MAXCLI; i++ {
+			if tcpsvr.cli[i].state > 0 {
+				*msg += fmt.Sprintf("  client%d = %s\n", i, tcpsvr.cli[i].saddr)
+			}
 		}
 	}
 	return state
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitClients waits until a TCP server stream has n connected clients
+func waitClients(t *testing.T, tcpsvr *TcpSvr, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for tcpsvr.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clients: got %d, want %d", tcpsvr.Clients(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTcpSvrBroadcast(t *testing.T) {
+	var stream Stream
+	stream.InitStream()
+	if stream.OpenStream(STR_TCPSVR, STR_MODE_RW, ":0") == 0 {
+		t.Fatalf("OpenStream failed: %s", stream.Msg)
+	}
+	tcpsvr := stream.Port.(*TcpSvr)
+	addr := tcpsvr.Addr().String()
+
+	var msg string
+	if state := stream.StreamGetStatEx(&msg); state != 1 {
+		t.Errorf("state without clients: got %d, want 1", state)
+	}
+
+	// Connect two clients
+	var conns [2]net.Conn
+	for i := range conns {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+	waitClients(t, tcpsvr, 2)
+
+	if state := stream.StreamGetStatEx(&msg); state < 2 {
+		t.Errorf("state with clients: got %d, want >= 2", state)
+	}
+	if !strings.Contains(msg, "clients = 2") {
+		t.Errorf("state message without client count: %q", msg)
+	}
+
+	// Both clients receive the broadcast
+	data := []byte("\xd3\x00\x13broadcast test data")
+	if ns := stream.StreamWrite(data, len(data)); ns != len(data) {
+		t.Errorf("StreamWrite: got %d, want %d", ns, len(data))
+	}
+	for i, conn := range conns {
+		buff := make([]byte, len(data))
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err := io.ReadFull(conn, buff); err != nil {
+			t.Fatalf("client %d read: %v", i, err)
+		}
+		if string(buff) != string(data) {
+			t.Errorf("client %d: got %q, want %q", i, buff, data)
+		}
+	}
+
+	// Data of both clients is read from the stream
+	conns[0].Write([]byte("abc"))
+	conns[1].Write([]byte("def"))
+	var got []byte
+	buff := make([]byte, 16)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(got) < 6 && time.Now().Before(deadline) {
+		nr := stream.StreamRead(buff, len(buff))
+		got = append(got, buff[:nr]...)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(got) != 6 || !strings.Contains(string(got), "abc") || !strings.Contains(string(got), "def") {
+		t.Errorf("StreamRead: got %q, want abc and def", got)
+	}
+
+	// A disconnected client is removed
+	conns[1].Close()
+	waitClients(t, tcpsvr, 1)
+
+	// Close disconnects the remaining client
+	stream.StreamClose()
+	conns[0].SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conns[0].Read(buff); err == nil {
+		t.Errorf("client read after close: expected error")
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Errorf("dial after close: expected error")
+	}
+}
+
+func TestTcpSvrSlowClient(t *testing.T) {
+	var msg string
+	tcpsvr := OpenTcpSvr(":0", &msg)
+	if tcpsvr == nil {
+		t.Fatalf("OpenTcpSvr failed: %s", msg)
+	}
+	defer tcpsvr.CloseTcpSvr()
+
+	// A client that never reads
+	conn, err := net.Dial("tcp", tcpsvr.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	waitClients(t, tcpsvr, 1)
+
+	// Writes do not block and the client is dropped once its queue is full
+	data := make([]byte, 65536)
+	start := time.Now()
+	for i := 0; i < 4*TCPSVR_SENDQ && tcpsvr.Clients() > 0; i++ {
+		tcpsvr.WriteTcpSvr(data, len(data), &msg)
+	}
+	if tcpsvr.Clients() != 0 {
+		t.Errorf("slow client not dropped")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("writes blocked for %v", elapsed)
+	}
+}
+
+func TestTcpClientReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The server closes the first connection after sending data
+	go func() {
+		for _, data := range []string{"first", "second"} {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(data))
+			if data == "first" {
+				conn.Close()
+				continue
+			}
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	var stream Stream
+	stream.InitStream()
+	if stream.OpenStream(STR_TCPCLI, STR_MODE_R, ln.Addr().String()) == 0 {
+		t.Fatalf("OpenStream failed: %s", stream.Msg)
+	}
+	defer stream.StreamClose()
+	stream.SetReconnect(true, 10*time.Millisecond, 100*time.Millisecond)
+
+	var received []byte
+	buff := make([]byte, 64)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if nr := stream.StreamRead(buff, len(buff)); nr > 0 {
+			received = append(received, buff[:nr]...)
+		}
+		if string(received) == "firstsecond" {
+			break
+		}
+	}
+	if string(received) != "firstsecond" {
+		t.Errorf("received %q, want %q", received, "firstsecond")
+	}
+}
+
+func TestTcpClientBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var msg string
+	tcpcli := OpenTcpClient(ln.Addr().String(), &msg)
+	if tcpcli == nil {
+		t.Fatalf("OpenTcpClient failed: %s", msg)
+	}
+	defer tcpcli.CloseTcpClient()
+	tcpcli.SetReconnect(true, 10*time.Millisecond, 40*time.Millisecond)
+
+	// The server goes away: the delay doubles up to the max
+	ln.Close()
+	tcpcli.disconnect(TickGet())
+	for _, want := range []time.Duration{20, 40, 40} {
+		tcpcli.tnext = time.Time{}
+		if tcpcli.connect(&msg) {
+			t.Fatalf("connected without server")
+		}
+		if tcpcli.delay != want*time.Millisecond {
+			t.Errorf("delay: got %v, want %v", tcpcli.delay, want*time.Millisecond)
+		}
+	}
+	// No attempt before the next reconnect time
+	if tcpcli.connect(&msg) || !time.Now().Before(tcpcli.tnext) {
+		t.Errorf("reconnect attempted before %v", tcpcli.tnext)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo/gtime"
@@ -49,6 +50,8 @@ const (
 	SERIBUFFSIZE        = 4096                     // Serial buffer size (bytes)
 	TIMETAGH_LEN        = 64                       // Time tag file header length
 	MAXCLI              = 32                       // Max client connection for tcp svr
+	TCPSVR_SENDQ        = 256                      // Send queue length per tcp svr client (writes)
+	TCPSVR_MAXIBUF      = 1048576                  // Max size of tcp svr input buffer (bytes)
 	MAXSTATMSG          = 32                       // Max length of status message
 	DEFAULT_MEMBUF_SIZE = 4096                     // Default memory buffer size (bytes)
 	NTRIP_AGENT         = "RTKLIB/3.0.0"           // Version hardcoded for now
@@ -96,6 +99,34 @@ type Stream struct {
 	Msg         string     // Stream message
 	Port        any        // Stream port
 	Lock        sync.Mutex // Lock for thread safety
+
+	RTCMStats       *RTCMStatsCollector // RTCM statistics of the input data (nil: off)
+	DiscardDuration time.Duration       // Input discarded after open, e.g. receiver boot output (0: off)
+	discardUntil    time.Time           // End of the discard period
+	stats           streamCounters      // Statistics read by Stats
+}
+
+// StreamStats contains the throughput statistics of a stream since open
+type StreamStats struct {
+	State        int       // Stream state (-1:error,0:close,1:open)
+	InBytes      uint64    // Bytes of input data
+	OutBytes     uint64    // Bytes of output data
+	InRate       uint64    // Input rate (bytes/sec) (0: no input in the averaging time)
+	OutRate      uint64    // Output rate (bytes/sec) (0: no output in the averaging time)
+	PeakInRate   uint64    // Peak input rate (bytes/sec)
+	PeakOutRate  uint64    // Peak output rate (bytes/sec)
+	LastActivity time.Time // Time of the last input or output (zero: none)
+}
+
+// streamCounters are the statistics of a stream, updated under the stream
+// lock by StreamRead and StreamWrite and read without it by Stats
+type streamCounters struct {
+	state                   atomic.Int32  // Stream state
+	inBytes, outBytes       atomic.Uint64 // Bytes of input/output data
+	inRate, outRate         atomic.Uint64 // Input/output rate (bytes/sec)
+	peakInRate, peakOutRate atomic.Uint64 // Peak input/output rate (bytes/sec)
+	tickIn, tickOut         atomic.Uint32 // Tick of the last input/output
+	lastActive              atomic.Int64  // Time of the last input or output (unix ns)
 }
 
 // FileType represents a file stream
@@ -137,15 +168,24 @@ type TcpConn struct {
 
 // TcpSvr represents a TCP server
 type TcpSvr struct {
-	svr TcpConn         // TCP server control
-	cli [MAXCLI]TcpConn // TCP client controls
+	svr  TcpConn             // TCP server control
+	cli  [MAXCLI]TcpConn     // TCP client controls
+	send [MAXCLI]chan []byte // Send queues of clients
+	ibuf []byte              // Input buffer of data received from clients
+	lock sync.Mutex          // Lock of client controls and input buffer
+	wg   sync.WaitGroup      // Accept, reader and writer goroutines
 }
 
 // TcpClient represents a TCP client
 type TcpClient struct {
-	svr     TcpConn // TCP server control
-	toinact int     // Inactive timeout (ms) (0:no timeout)
-	tirecon int     // Reconnect interval (ms) (0:no reconnect)
+	svr     TcpConn       // TCP server control
+	toinact int           // Inactive timeout (ms) (0:no timeout)
+	tirecon int           // Reconnect interval (ms) (0:no reconnect)
+	recon   bool          // Reconnect with backoff enabled
+	tiinit  time.Duration // Initial reconnect delay
+	timax   time.Duration // Max reconnect delay
+	delay   time.Duration // Current reconnect delay
+	tnext   time.Time     // Time of the next reconnect attempt
 }
 
 // SerialComm represents a serial connection
@@ -195,11 +235,14 @@ type NTripc struct {
 
 // UdpConn represents a UDP connection
 type UdpConn struct {
-	state int      // State (0:close,1:open)
-	ctype int      // Type (0:server,1:client)
-	port  int      // Port
-	saddr string   // Address (server:filter,client:server)
-	sock  net.Conn // Socket descriptor
+	state    int      // State (0:close,1:open)
+	ctype    int      // Type (0:server,1:client)
+	port     int      // Port
+	saddr    string   // Address (server:filter,client:server)
+	sock     net.Conn // Socket descriptor
+	wtimeout int      // Write timeout (ms) (0:no timeout)
+	buff     []byte   // Received datagram
+	nb, ib   int      // Size and read position of the received datagram
 }
 
 // FtpConn represents an FTP/HTTP connection
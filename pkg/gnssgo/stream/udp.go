@@ -13,6 +13,8 @@ const (
 	defaultUdpPort     = 8000
 	defaultUdpBuffSize = 32768 // UDP buffer size (bytes)
 	defaultUdpTimeout  = 100   // Read timeout (ms)
+	defaultUdpWriteTO  = 1000  // Write timeout (ms)
+	udpMaxDatagram     = 65536 // Max size of a UDP datagram (bytes)
 )
 
 // OpenUdpSvr opens a UDP server
@@ -35,8 +37,8 @@ func OpenUdpSvr(path string, msg *string) *UdpConn {
 		port, _ = strconv.Atoi(sport)
 	}
 
-	// Create UDP server
-	return GenUdp(0, port, "localhost", msg)
+	// Create UDP server listening on all interfaces
+	return GenUdp(0, port, "", msg)
 }
 
 // OpenUdpClient opens a UDP client
@@ -82,6 +84,7 @@ func GenUdp(ctype, port int, saddr string, msg *string) *UdpConn {
 	udp.ctype = ctype
 	udp.port = port
 	udp.saddr = saddr
+	udp.wtimeout = defaultUdpWriteTO
 
 	// Create UDP address
 	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", saddr, port))
@@ -109,6 +112,8 @@ func GenUdp(ctype, port int, saddr string, msg *string) *UdpConn {
 		conn.SetReadDeadline(time.Now().Add(time.Duration(defaultUdpTimeout) * time.Millisecond))
 
 		udp.sock = conn
+		udp.port = conn.LocalAddr().(*net.UDPAddr).Port
+		udp.buff = make([]byte, udpMaxDatagram)
 	} else { // UDP client
 		// Create UDP client socket
 		conn, err := net.DialUDP("udp", nil, addr)
@@ -126,6 +131,7 @@ func GenUdp(ctype, port int, saddr string, msg *string) *UdpConn {
 		conn.SetReadDeadline(time.Now().Add(time.Duration(defaultUdpTimeout) * time.Millisecond))
 
 		udp.sock = conn
+		udp.buff = make([]byte, udpMaxDatagram)
 	}
 
 	return udp
@@ -143,59 +149,66 @@ func (udp *UdpConn) CloseUdp() {
 	udp.sock.Close()
 	udp.state = 0
 	udp.sock = nil
+	udp.nb, udp.ib = 0, 0
 }
 
-// ReadUdpSvr reads data from a UDP server
-func (udp *UdpConn) ReadUdpSvr(buff []byte, n int, msg *string) int {
-	var (
-		nr  int
-		err error
-	)
-
-	Tracet(4, "ReadUdpSvr: n=%d\n", n)
-
+// Addr returns the local address of a UDP connection (nil: not opened)
+func (udp *UdpConn) Addr() net.Addr {
 	if udp == nil || udp.sock == nil {
-		return 0
+		return nil
 	}
+	return udp.sock.LocalAddr()
+}
 
-	// Check if this is a server
-	if udp.ctype != 0 {
-		return 0
+// SetWriteTimeout sets the write timeout of a UDP connection (ms) (0: no
+// timeout)
+func (udp *UdpConn) SetWriteTimeout(timeout int) {
+	if udp == nil {
+		return
 	}
+	udp.wtimeout = timeout
+}
 
-	// Set read deadline
-	udp.sock.SetReadDeadline(time.Now().Add(time.Duration(defaultUdpTimeout) * time.Millisecond))
-
-	// Read data
-	switch conn := udp.sock.(type) {
-	case *net.UDPConn:
-		nr, _, err = conn.ReadFromUDP(buff[:n])
-	default:
-		nr, err = conn.Read(buff[:n])
-	}
+// readDatagram reads one datagram into buff. A datagram larger than n bytes
+// is returned by the following reads, which never mix it with the next one.
+func (udp *UdpConn) readDatagram(buff []byte, n int, msg *string) int {
+	if udp.ib >= udp.nb {
+		udp.sock.SetReadDeadline(time.Now().Add(time.Duration(defaultUdpTimeout) * time.Millisecond))
 
-	if err != nil {
-		// Ignore timeout errors
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		nr, err := udp.sock.Read(udp.buff)
+		if err != nil {
+			// Ignore timeout errors
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return 0
+			}
+			*msg = fmt.Sprintf("udp read error: %s", err.Error())
+			Tracet(2, "readDatagram: read error: %s\n", err.Error())
 			return 0
 		}
+		udp.nb, udp.ib = nr, 0
+	}
+	nr := copy(buff[:n], udp.buff[udp.ib:udp.nb])
+	udp.ib += nr
+	return nr
+}
+
+// ReadUdpSvr reads one datagram received by a UDP server
+func (udp *UdpConn) ReadUdpSvr(buff []byte, n int, msg *string) int {
+	Tracet(4, "ReadUdpSvr: n=%d\n", n)
 
-		// Handle other errors
-		*msg = fmt.Sprintf("udp read error: %s", err.Error())
-		Tracet(2, "ReadUdpSvr: read error: %s\n", err.Error())
+	if udp == nil || udp.sock == nil {
 		return 0
 	}
 
-	return nr
+	// Check if this is a server
+	if udp.ctype != 0 {
+		return 0
+	}
+	return udp.readDatagram(buff, n, msg)
 }
 
-// ReadUdpClient reads data from a UDP client
+// ReadUdpClient reads one datagram received by a UDP client
 func (udp *UdpConn) ReadUdpClient(buff []byte, n int, msg *string) int {
-	var (
-		nr  int
-		err error
-	)
-
 	Tracet(4, "ReadUdpClient: n=%d\n", n)
 
 	if udp == nil || udp.sock == nil {
@@ -206,25 +219,7 @@ func (udp *UdpConn) ReadUdpClient(buff []byte, n int, msg *string) int {
 	if udp.ctype != 1 {
 		return 0
 	}
-
-	// Set read deadline
-	udp.sock.SetReadDeadline(time.Now().Add(time.Duration(defaultUdpTimeout) * time.Millisecond))
-
-	// Read data
-	nr, err = udp.sock.Read(buff[:n])
-	if err != nil {
-		// Ignore timeout errors
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return 0
-		}
-
-		// Handle other errors
-		*msg = fmt.Sprintf("udp read error: %s", err.Error())
-		Tracet(2, "ReadUdpClient: read error: %s\n", err.Error())
-		return 0
-	}
-
-	return nr
+	return udp.readDatagram(buff, n, msg)
 }
 
 // WriteUdpSvr writes data to a UDP server
@@ -254,7 +249,12 @@ func (udp *UdpConn) WriteUdpClient(buff []byte, n int, msg *string) int {
 		return 0
 	}
 
-	// Write data
+	// Write data as one datagram
+	if udp.wtimeout > 0 {
+		udp.sock.SetWriteDeadline(time.Now().Add(time.Duration(udp.wtimeout) * time.Millisecond))
+	} else {
+		udp.sock.SetWriteDeadline(time.Time{})
+	}
 	ns, err = udp.sock.Write(buff[:n])
 	if err != nil {
 		*msg = fmt.Sprintf("udp write error: %s", err.Error())
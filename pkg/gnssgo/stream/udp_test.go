@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// readUdp reads from a UDP stream until data are received
+func readUdp(t *testing.T, stream *Stream, buff []byte, n int) int {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if nr := stream.StreamRead(buff, n); nr > 0 {
+			return nr
+		}
+	}
+	t.Fatalf("no data received")
+	return 0
+}
+
+func TestUdpNmeaRoundTrip(t *testing.T) {
+	var svr, cli Stream
+	svr.InitStream()
+	cli.InitStream()
+	if svr.OpenStream(STR_UDPSVR, STR_MODE_R, ":0") == 0 {
+		t.Fatalf("OpenStream server failed: %s", svr.Msg)
+	}
+	defer svr.StreamClose()
+	port := svr.Port.(*UdpConn).port
+	if cli.OpenStream(STR_UDPCLI, STR_MODE_W, fmt.Sprintf("127.0.0.1:%d", port)) == 0 {
+		t.Fatalf("OpenStream client failed: %s", cli.Msg)
+	}
+	defer cli.StreamClose()
+	cli.Port.(*UdpConn).SetWriteTimeout(500)
+
+	gga := "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n"
+	rmc := "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A\r\n"
+	for _, s := range []string{gga, rmc} {
+		if ns := cli.StreamWrite([]byte(s), len(s)); ns != len(s) {
+			t.Fatalf("StreamWrite: got %d, want %d", ns, len(s))
+		}
+	}
+
+	// One datagram per read
+	buff := make([]byte, 1024)
+	if nr := readUdp(t, &svr, buff, len(buff)); string(buff[:nr]) != gga {
+		t.Errorf("datagram 1: got %q, want %q", buff[:nr], gga)
+	}
+	if nr := readUdp(t, &svr, buff, len(buff)); string(buff[:nr]) != rmc {
+		t.Errorf("datagram 2: got %q, want %q", buff[:nr], rmc)
+	}
+}
+
+func TestUdpPartialRead(t *testing.T) {
+	var svr, cli Stream
+	svr.InitStream()
+	cli.InitStream()
+	if svr.OpenStream(STR_UDPSVR, STR_MODE_R, ":0") == 0 {
+		t.Fatalf("OpenStream server failed: %s", svr.Msg)
+	}
+	defer svr.StreamClose()
+	port := svr.Port.(*UdpConn).port
+	if cli.OpenStream(STR_UDPCLI, STR_MODE_W, fmt.Sprintf("127.0.0.1:%d", port)) == 0 {
+		t.Fatalf("OpenStream client failed: %s", cli.Msg)
+	}
+	defer cli.StreamClose()
+
+	for _, s := range []string{"0123456789abcdef", "XYZ"} {
+		cli.StreamWrite([]byte(s), len(s))
+	}
+
+	// The rest of a datagram larger than the buffer is returned by the next
+	// reads before the next datagram
+	buff := make([]byte, 64)
+	var got []string
+	for len(got) < 4 {
+		nr := readUdp(t, &svr, buff, 6)
+		got = append(got, string(buff[:nr]))
+	}
+	want := []string{"012345", "6789ab", "cdef", "XYZ"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("read %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// Reads after close return no data
+	svr.StreamClose()
+	if nr := svr.StreamRead(buff, len(buff)); nr != 0 {
+		t.Errorf("read after close: got %d bytes", nr)
+	}
+}
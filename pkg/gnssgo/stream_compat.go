@@ -43,6 +43,9 @@ const (
 // Stream represents a generic stream (compatibility wrapper)
 type Stream = stream.Stream
 
+// StreamStats represents the throughput statistics of a stream (compatibility wrapper)
+type StreamStats = stream.StreamStats
+
 // FileType represents a file stream (compatibility wrapper)
 type FileType = stream.FileType
 
@@ -61,6 +64,9 @@ type SerialComm = stream.SerialComm
 // NTrip represents an NTRIP connection (compatibility wrapper)
 type NTrip = stream.NTrip
 
+// NTripStats represents the diagnostics of an NTRIP connection (compatibility wrapper)
+type NTripStats = stream.NTripStats
+
 // NTripc_con represents an NTRIP client/server connection (compatibility wrapper)
 type NTripc_con = stream.NTripc_con
 
@@ -131,6 +137,12 @@ func OpenFtp(path string, proto int, msg *string) *FtpConn {
 	return stream.OpenFtp(path, proto, msg)
 }
 
+// StrSetProxy sets the proxy of the NTRIP streams opened afterwards
+// (compatibility wrapper)
+func StrSetProxy(addr string) {
+	stream.StrSetProxy(addr)
+}
+
 // SetBrate sets the baud rate for a serial connection (compatibility wrapper)
 func SetBrate(str *Stream, brate int) {
 	stream.SetBrate(str, brate)
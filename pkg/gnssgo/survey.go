@@ -0,0 +1,80 @@
+/*------------------------------------------------------------------------------
+* survey.go : static survey-in of base station position
+*-----------------------------------------------------------------------------*/
+package gnssgo
+
+import (
+	"math"
+	"time"
+)
+
+/* survey-in type ------------------------------------------------------------*/
+type SurveyIn struct {
+	MinDuration time.Duration /* minimum observation time */
+	AccThres    float64       /* accuracy threshold of mean position (m) */
+
+	n      int        /* number of positions */
+	ts, te Gtime      /* time of first/last position */
+	mean   [3]float64 /* mean position {x,y,z} (ecef) (m) */
+	m2     [3]float64 /* sum of squared deviations from mean (m^2) */
+}
+
+/* new survey-in ---------------------------------------------------------------
+* average the position of a static receiver until the mean position is
+* accurate enough to be used as base station position (like u-blox survey-in)
+* args   : time.Duration minDuration I minimum observation time
+*          double accuracyThreshold  I  accuracy threshold of mean position (m)
+* return : survey-in
+*-----------------------------------------------------------------------------*/
+func NewSurveyIn(minDuration time.Duration, accuracyThreshold float64) *SurveyIn {
+	return &SurveyIn{MinDuration: minDuration, AccThres: accuracyThreshold}
+}
+
+/* update survey-in with solution ----------------------------------------------
+* add the position of a solution to the running mean
+* args   : sol_t  sol       I   solution ({x,y,z} (ecef))
+* return : none
+* notes  : solutions without position (SOLQ_NONE), not in ecef or earlier than
+*          the last solution are ignored.
+*-----------------------------------------------------------------------------*/
+func (s *SurveyIn) Update(sol Sol) {
+	if sol.Stat == SOLQ_NONE || sol.Type != 0 || Norm(sol.Rr[:], 3) <= 0.0 {
+		return
+	}
+	if s.n > 0 && TimeDiff(sol.Time, s.te) < 0.0 {
+		return
+	}
+	if s.n == 0 {
+		s.ts = sol.Time
+	}
+	s.te = sol.Time
+	s.n++
+
+	/* welford's running mean and variance */
+	for i := 0; i < 3; i++ {
+		d := sol.Rr[i] - s.mean[i]
+		s.mean[i] += d / float64(s.n)
+		s.m2[i] += d * (sol.Rr[i] - s.mean[i])
+	}
+}
+
+/* survey-in result ------------------------------------------------------------
+* get the mean position and its accuracy
+* return : mean position {x,y,z} (ecef) (m), completion flag and achieved
+*          accuracy (m) (0: less than 2 positions)
+* notes  : the accuracy is the 3d standard error of the mean. the survey-in is
+*          complete when the positions span MinDuration and the accuracy is
+*          within AccThres.
+*          the standard error assumes uncorrelated position errors. single
+*          point positions are correlated over minutes, so the minimum
+*          observation time should be long enough to average them out.
+*-----------------------------------------------------------------------------*/
+func (s *SurveyIn) Result() (pos [3]float64, done bool, achievedAcc float64) {
+	if s.n < 2 {
+		return s.mean, false, 0.0
+	}
+	achievedAcc = math.Sqrt((s.m2[0] + s.m2[1] + s.m2[2]) / float64(s.n-1) / float64(s.n))
+	dur := time.Duration(TimeDiff(s.te, s.ts) * float64(time.Second))
+	done = dur >= s.MinDuration && achievedAcc <= s.AccThres
+	return s.mean, done, achievedAcc
+}
@@ -0,0 +1,73 @@
+package gnssgo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestSurveyIn feeds 1 Hz positions with 2 m white noise per axis and checks
+// the completion criteria and the reported accuracy.
+func TestSurveyIn(t *testing.T) {
+	const sig = 2.0
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	rnd := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		dur    time.Duration
+		thres  float64
+		lo, hi int /* range of epoch of completion (-1: not completed) */
+	}{
+		/* accuracy 0.5 m after ~48 positions, then wait for duration */
+		{"duration", 120 * time.Second, 0.5, 120, 120},
+		/* accuracy 0.2 m after ~300 positions */
+		{"accuracy", 10 * time.Second, 0.2, 200, 450},
+		{"not converged", 10 * time.Second, 0.05, -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSurveyIn(tt.dur, tt.thres)
+			if _, done, acc := s.Result(); done || acc != 0.0 {
+				t.Fatalf("empty: done = %v, acc = %.3f", done, acc)
+			}
+			first := -1
+			for k := 0; k <= 600; k++ {
+				var sol Sol
+				sol.Time = TimeAdd(t0, float64(k))
+				sol.Stat = SOLQ_SINGLE
+				for i := 0; i < 3; i++ {
+					sol.Rr[i] = synthBase[i] + sig*rnd.NormFloat64()
+				}
+				s.Update(sol)
+				if _, done, _ := s.Result(); done && first < 0 {
+					first = k
+				}
+			}
+			pos, done, acc := s.Result()
+
+			if first < tt.lo || first > tt.hi {
+				t.Errorf("completed at epoch %d, want %d-%d", first, tt.lo, tt.hi)
+			}
+			if done != (tt.lo >= 0) {
+				t.Errorf("done = %v", done)
+			}
+			/* standard error of 601 positions */
+			if want := math.Sqrt(3.0) * sig / math.Sqrt(601); math.Abs(acc-want) > 0.1*want {
+				t.Errorf("acc = %.3f, want %.3f", acc, want)
+			}
+			if d := synthDist(pos[:], synthBase[:]); d > 3.0*acc {
+				t.Errorf("position error = %.3f m (acc %.3f m)", d, acc)
+			}
+		})
+	}
+
+	/* solutions without position are ignored */
+	s := NewSurveyIn(0, 1.0)
+	s.Update(Sol{Time: t0, Stat: SOLQ_NONE})
+	s.Update(Sol{Time: t0, Stat: SOLQ_SINGLE, Rr: [6]float64{synthBase[0], synthBase[1], synthBase[2]}})
+	if pos, done, _ := s.Result(); done || pos != synthBase {
+		t.Errorf("pos = %v, done = %v", pos, done)
+	}
+}
@@ -0,0 +1,191 @@
+package gnssgo
+
+import (
+	"math"
+	"sort"
+)
+
+/* synthetic data generator shared by the positioning tests -------------------
+* builds a broadcast ephemeris constellation and error-free observations so the
+* positioning engines can be exercised without recorded data files.
+*-----------------------------------------------------------------------------*/
+
+var (
+	synthRover = [3]float64{-3961904.9, 3348993.8, 3698211.8} /* rover ecef (m) */
+	synthBase  = [3]float64{-3961800.0, 3349100.0, 3698300.0} /* base ecef (m) */
+)
+
+// synthEph returns a Keplerian broadcast ephemeris for sat referenced to toe.
+// The orbital plane and phase are spread by index k.
+func synthEph(sat, k int, toe Gtime) Eph {
+	var week int
+	toes := Time2GpsT(toe, &week)
+	return Eph{
+		Sat:  sat,
+		Iode: k + 1, Iodc: k + 1,
+		Week: week,
+		Toe:  toe, Toc: toe, Ttr: toe,
+		A:    26559710.0,
+		E:    0.005,
+		I0:   55.0 * D2R,
+		OMG0: float64(k%6) * 60.0 * D2R,
+		Omg:  0.0,
+		M0:   float64(k/6)*65.0*D2R + float64(k%6)*15.0*D2R,
+		Toes: toes,
+		Fit:  4.0,
+		F0:   1e-5 * float64(k%3),
+	}
+}
+
+// synthNav returns a navigation data set with nsat GPS satellites.
+func synthNav(toe Gtime, nsat int) *Nav {
+	nav := new(Nav)
+	for k := 0; k < nsat; k++ {
+		nav.Ephs = append(nav.Ephs, synthEph(SatNo(SYS_GPS, k+1), k, toe))
+	}
+	return nav
+}
+
+// synthGEph returns a GLONASS broadcast ephemeris for sat referenced to toe
+// with the state of the Keplerian orbit of synthEph at toe. The frequency
+// channel is 0.
+func synthGEph(sat, k int, toe Gtime) GEph {
+	var (
+		rs0, rs1, rs2 [3]float64
+		dts, vari     float64
+	)
+	eph := synthEph(sat, k, toe)
+	Eph2Pos(toe, &eph, rs0[:], &dts, &vari)
+	Eph2Pos(TimeAdd(toe, -0.5), &eph, rs1[:], &dts, &vari)
+	Eph2Pos(TimeAdd(toe, 0.5), &eph, rs2[:], &dts, &vari)
+	geph := GEph{Sat: sat, Iode: k + 1, Toe: toe, Tof: toe, Taun: -eph.F0}
+	for i := 0; i < 3; i++ {
+		geph.Pos[i] = rs0[i]
+		geph.Vel[i] = rs2[i] - rs1[i]
+	}
+	return geph
+}
+
+// synthSignals returns the carrier frequencies and obs codes simulated for
+// the system of sat.
+func synthSignals(sat int) ([2]float64, [2]uint8) {
+	switch SatSys(sat, nil) {
+	case SYS_IRN:
+		return [2]float64{FREQ5, FREQ9}, [2]uint8{CODE_L5A, CODE_L9A}
+	case SYS_GLO:
+		return [2]float64{FREQ1_GLO, FREQ2_GLO}, [2]uint8{CODE_L1C, CODE_L2C}
+	case SYS_GAL:
+		return [2]float64{FREQ1, FREQ7}, [2]uint8{CODE_L1C, CODE_L7Q}
+	case SYS_CMP:
+		return [2]float64{FREQ1_CMP, FREQ2_CMP}, [2]uint8{CODE_L2I, CODE_L7I}
+	case SYS_QZS:
+		return [2]float64{FREQ1, FREQ2}, [2]uint8{CODE_L1C, CODE_L2L}
+	}
+	return [2]float64{FREQ1, FREQ2}, [2]uint8{CODE_L1C, CODE_L2W}
+}
+
+// synthRange computes the pseudorange (m) of the satellite described by eph
+// observed at receiver time t (GPST) from rr with receiver clock bias dtr (m).
+// It returns 0 for satellites below elmin.
+func synthRange(eph *Eph, t Gtime, rr []float64, dtr, elmin float64) float64 {
+	return synthRangeOrb(func(ts Gtime) float64 { return Eph2Clk(ts, eph) },
+		func(ts Gtime, rs []float64) float64 {
+			var dts, vari float64
+			Eph2Pos(ts, eph, rs, &dts, &vari)
+			return dts
+		}, t, rr, dtr, elmin)
+}
+
+// synthGRange is synthRange for a GLONASS ephemeris.
+func synthGRange(geph *GEph, t Gtime, rr []float64, dtr, elmin float64) float64 {
+	return synthRangeOrb(func(ts Gtime) float64 { return GEph2Clk(ts, geph) },
+		func(ts Gtime, rs []float64) float64 {
+			var dts, vari float64
+			GEph2Pos(ts, geph, rs, &dts, &vari)
+			return dts
+		}, t, rr, dtr, elmin)
+}
+
+// synthRangeOrb computes the pseudorange by the satellite clock bias clk and
+// the satellite position and clock bias orb at transmission time ts (GPST).
+func synthRangeOrb(clk func(ts Gtime) float64, orb func(ts Gtime, rs []float64) float64,
+	t Gtime, rr []float64, dtr, elmin float64) float64 {
+	var (
+		rs           [6]float64
+		e, pos, azel [3]float64
+		dts          float64
+		P            = 0.075 * CLIGHT
+	)
+	Ecef2Pos(rr, pos[:])
+	for iter := 0; iter < 5; iter++ {
+		ts := TimeAdd(t, -P/CLIGHT)
+		ts = TimeAdd(ts, -clk(ts))
+		dts = orb(ts, rs[:])
+		r := GeoDist(rs[:], rr, e[:])
+		if r <= 0.0 {
+			return 0.0
+		}
+		P = r + dtr - CLIGHT*dts
+	}
+	if SatAzel(pos[:], e[:], azel[:]) < elmin {
+		return 0.0
+	}
+	return P
+}
+
+// synthObs simulates dual-frequency observations (see synthSignals) at time t for receiver
+// rcv located at rr. Carrier phases carry an integer ambiguity per satellite
+// and doppler is derived from the range rate.
+func synthObs(nav *Nav, t Gtime, rr [3]float64, rcv int, dtr float64) []ObsD {
+	var (
+		obs    []ObsD
+		sats   []int
+		ranges []func(t Gtime, elmin float64) float64
+	)
+	for i := range nav.Ephs {
+		eph := &nav.Ephs[i]
+		sats = append(sats, eph.Sat)
+		ranges = append(ranges, func(t Gtime, elmin float64) float64 {
+			return synthRange(eph, t, rr[:], dtr, elmin)
+		})
+	}
+	for i := range nav.Geph {
+		geph := &nav.Geph[i]
+		sats = append(sats, geph.Sat)
+		ranges = append(ranges, func(t Gtime, elmin float64) float64 {
+			return synthGRange(geph, t, rr[:], dtr, elmin)
+		})
+	}
+	for i, sat := range sats {
+		freqs, codes := synthSignals(sat)
+		P := ranges[i](t, 10.0*D2R)
+		if P == 0.0 {
+			continue
+		}
+		rate := ranges[i](TimeAdd(t, 0.5), 0.0) - ranges[i](TimeAdd(t, -0.5), 0.0)
+		d := ObsD{Time: t, Sat: sat, Rcv: rcv}
+		for f := 0; f < 2; f++ {
+			lam := CLIGHT / freqs[f]
+			d.Code[f] = codes[f]
+			d.P[f] = P
+			d.L[f] = P/lam + float64(100*sat+f)
+			d.D[f] = -rate / lam
+			d.SNR[f] = uint16(45.0 / SNR_UNIT)
+		}
+		obs = append(obs, d)
+	}
+	sort.Slice(obs, func(i, j int) bool { return obs[i].Sat < obs[j].Sat })
+	return obs
+}
+
+// synthEpoch returns the rover and base observations of one epoch in the
+// order expected by RtkPos (rover first, both sorted by satellite).
+func synthEpoch(nav *Nav, t Gtime) []ObsD {
+	obs := synthObs(nav, t, synthRover, 1, 0.0)
+	return append(obs, synthObs(nav, t, synthBase, 2, 0.0)...)
+}
+
+// synthDist returns the euclidean distance between two positions.
+func synthDist(a, b []float64) float64 {
+	return math.Sqrt(SQR(a[0]-b[0]) + SQR(a[1]-b[1]) + SQR(a[2]-b[2]))
+}
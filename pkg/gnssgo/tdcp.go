@@ -0,0 +1,119 @@
+/*------------------------------------------------------------------------------
+* tdcp.go : time-differenced carrier-phase velocity
+*
+* references :
+*     [1] F.van Graas and A.Soloviev, Precise Velocity Estimation Using a
+*         Stand-Alone GPS Receiver, Navigation, 51(4), 2004
+*-----------------------------------------------------------------------------*/
+package gnssgo
+
+import "fmt"
+
+/* time-differenced carrier-phase (tdcp) velocity ------------------------------
+* estimate the receiver velocity by the carrier-phase change between two
+* epochs. the ambiguities cancel in the time difference, so the velocity is
+* mm/s level accurate, far better than doppler.
+* args   : obs_t  *prev     I   observation data of the previous epoch
+*          obs_t  *cur      I   observation data of the current epoch
+*          nav_t  *nav      I   navigation data
+*          double *pos      I   receiver position at the current epoch {x,y,z}
+*                               (ecef) (m)
+* return : receiver velocity {vx,vy,vz} (ecef) (m/s), receiver clock drift
+*          (m/s) and error (nil: ok)
+* notes  : the velocity is the mean over the epoch interval.
+*          the first frequency carrier-phase of the satellites observed in both
+*          epochs with the same code is used. satellites with a cycle slip
+*          (LLI) in the current epoch or unhealthy are excluded.
+*          satellite positions and clocks are computed by broadcast ephemeris
+*          at both epochs. the atmospheric delay changes are neglected.
+*          an error of the receiver position enters as the change of the line
+*          of sight, a few meters are sufficient for mm/s.
+*-----------------------------------------------------------------------------*/
+func TDCPVelocity(prev, cur *Obs, nav *Nav, pos [3]float64) ([3]float64, float64, error) {
+	var (
+		vel        [3]float64
+		x, dx      [4]float64
+		Q          [16]float64
+		r1, e1, e2 [3]float64
+		dphi, ddts []float64
+		rsp, rsc   [][]float64
+		i, j, iter int
+	)
+	if prev == nil || cur == nil || prev.N() == 0 || cur.N() == 0 {
+		return vel, 0.0, fmt.Errorf("tdcp: no observation data")
+	}
+	if nav == nil || Norm(pos[:], 3) <= 0.0 {
+		return vel, 0.0, fmt.Errorf("tdcp: no navigation data or receiver position")
+	}
+	np, nc := min(prev.N(), MAXOBS), min(cur.N(), MAXOBS)
+	tt := TimeDiff(cur.Data[0].Time, prev.Data[0].Time)
+	if tt <= 0.0 {
+		return vel, 0.0, fmt.Errorf("tdcp: invalid epoch interval %.3f s", tt)
+	}
+	Trace(3, "tdcpvel : time=%s tt=%.3f np=%d nc=%d\n", TimeStr(cur.Data[0].Time, 3), tt, np, nc)
+
+	/* satellite positions and clocks of both epochs */
+	rs1, dts1, var1, svh1 := Mat(6, np), Mat(2, np), Mat(1, np), make([]int, np)
+	rs2, dts2, var2, svh2 := Mat(6, nc), Mat(2, nc), Mat(1, nc), make([]int, nc)
+	nav.SatPoss(prev.Data[0].Time, prev.Data, np, EPHOPT_BRDC, rs1, dts1, var1, svh1)
+	nav.SatPoss(cur.Data[0].Time, cur.Data, nc, EPHOPT_BRDC, rs2, dts2, var2, svh2)
+
+	/* carrier-phase changes of the satellites common to both epochs */
+	for i = 0; i < nc; i++ {
+		c := &cur.Data[i]
+		for j = 0; j < np && prev.Data[j].Sat != c.Sat; j++ {
+		}
+		if j >= np {
+			continue
+		}
+		p := &prev.Data[j]
+		freq := Sat2Freq(c.Sat, c.Code[0], nav)
+		if c.L[0] == 0.0 || p.L[0] == 0.0 || c.Code[0] != p.Code[0] || freq == 0.0 ||
+			c.LLI[0]&LLI_SLIP != 0 || svh1[j] != 0 || svh2[i] != 0 ||
+			Norm(rs1[j*6:], 3) <= 0.0 || Norm(rs2[i*6:], 3) <= 0.0 {
+			continue
+		}
+		dphi = append(dphi, (c.L[0]-p.L[0])*CLIGHT/freq)
+		rsp = append(rsp, rs1[j*6:j*6+3])
+		rsc = append(rsc, rs2[i*6:i*6+3])
+		ddts = append(ddts, CLIGHT*(dts2[i*2]-dts1[j*2]))
+	}
+	n := len(dphi)
+	if n < 4 {
+		return vel, 0.0, fmt.Errorf("tdcp: %d satellites in both epochs, 4 needed", n)
+	}
+
+	/* estimate position change and receiver clock change */
+	v, H := Mat(n, 1), Mat(4, n)
+	for iter = 0; iter < MAXITR; iter++ {
+		for j = 0; j < 3; j++ {
+			r1[j] = pos[j] - x[j]
+		}
+		for i = 0; i < n; i++ {
+			rho2 := GeoDist(rsc[i], pos[:], e2[:])
+			rho1 := GeoDist(rsp[i], r1[:], e1[:])
+			v[i] = dphi[i] - (rho2 - rho1 + x[3] - ddts[i])
+			for j = 0; j < 3; j++ {
+				H[j+i*4] = -e1[j]
+			}
+			H[3+i*4] = 1.0
+		}
+		if LSQ(H, v, 4, n, dx[:], Q[:]) != 0 {
+			return vel, 0.0, fmt.Errorf("tdcp: lsq error")
+		}
+		for j = 0; j < 4; j++ {
+			x[j] += dx[j]
+		}
+		if Norm(dx[:], 4) < 1e-6 {
+			break
+		}
+	}
+	if iter >= MAXITR {
+		return vel, 0.0, fmt.Errorf("tdcp: no convergence")
+	}
+	for j = 0; j < 3; j++ {
+		vel[j] = x[j] / tt
+	}
+	Trace(4, "tdcpvel : n=%d vel=%.4f %.4f %.4f drift=%.4f\n", n, vel[0], vel[1], vel[2], x[3]/tt)
+	return vel, x[3] / tt, nil
+}
@@ -0,0 +1,123 @@
+package gnssgo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// synthMovingObs simulates the observations at t of a receiver at rr moving
+// with velocity vel (m/s) and clock drift ddtr (m/s). The doppler includes
+// the receiver motion.
+func synthMovingObs(nav *Nav, t Gtime, rr, vel [3]float64, dtr, ddtr float64) []ObsD {
+	var rp, rm [3]float64
+	for j := 0; j < 3; j++ {
+		rp[j], rm[j] = rr[j]+0.5*vel[j], rr[j]-0.5*vel[j]
+	}
+	plus := synthObs(nav, TimeAdd(t, 0.5), rp, 1, dtr+0.5*ddtr)
+	minus := synthObs(nav, TimeAdd(t, -0.5), rm, 1, dtr-0.5*ddtr)
+
+	var obs []ObsD
+	for _, d := range synthObs(nav, t, rr, 1, dtr) {
+		i, j := 0, 0
+		for ; i < len(plus) && plus[i].Sat != d.Sat; i++ {
+		}
+		for ; j < len(minus) && minus[j].Sat != d.Sat; j++ {
+		}
+		if i >= len(plus) || j >= len(minus) {
+			continue
+		}
+		freqs, _ := synthSignals(d.Sat)
+		for f := 0; f < 2; f++ {
+			d.D[f] = -(plus[i].P[f] - minus[j].P[f]) * freqs[f] / CLIGHT
+		}
+		obs = append(obs, d)
+	}
+	return obs
+}
+
+// TestTDCPVelocity compares the TDCP and doppler velocities of a moving
+// receiver with noisy carrier-phase and doppler.
+func TestTDCPVelocity(t *testing.T) {
+	var (
+		sol  Sol
+		msg  string
+		prev Obs
+	)
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	vel := [3]float64{1.5, -2.0, 0.5}
+	const drift = 0.3 /* receiver clock drift (m/s) */
+	rng := rand.New(rand.NewSource(1))
+	opt := DefaultProcOpt()
+	opt.Elmin = 10.0 * D2R
+
+	var sumTdcp, sumDop, sumDrift float64
+	nep := 0
+	for k := 0; k <= 20; k++ {
+		var rr [3]float64
+		for j := 0; j < 3; j++ {
+			rr[j] = synthRover[j] + vel[j]*float64(k)
+		}
+		obs := synthMovingObs(nav, TimeAdd(t0, float64(k)), rr, vel, 100.0+drift*float64(k), drift)
+		for i := range obs {
+			obs[i].L[0] += 0.01 * rng.NormFloat64() /* cycle */
+			obs[i].D[0] += 0.1 * rng.NormFloat64()  /* Hz */
+		}
+		if PntPos(obs, len(obs), nav, &opt, &sol, nil, nil, &msg) == 0 {
+			t.Fatalf("pntpos failed: %s", msg)
+		}
+		cur := Obs{Data: obs}
+		if k > 0 {
+			v, d, err := TDCPVelocity(&prev, &cur, nav, [3]float64{sol.Rr[0], sol.Rr[1], sol.Rr[2]})
+			if err != nil {
+				t.Fatalf("tdcp epoch %d: %v", k, err)
+			}
+			sumTdcp += SQR(synthDist(v[:], vel[:]))
+			sumDop += SQR(synthDist(sol.Rr[3:6], vel[:]))
+			sumDrift += SQR(d - drift)
+			nep++
+		}
+		prev = cur
+	}
+	rmsTdcp, rmsDop := math.Sqrt(sumTdcp/float64(nep)), math.Sqrt(sumDop/float64(nep))
+	t.Logf("velocity error rms: tdcp=%.4f doppler=%.4f m/s", rmsTdcp, rmsDop)
+	if rmsTdcp > 0.01 {
+		t.Errorf("tdcp velocity error rms = %.4f m/s", rmsTdcp)
+	}
+	if rmsTdcp > rmsDop/3.0 {
+		t.Errorf("tdcp velocity error rms %.4f m/s not below doppler %.4f m/s", rmsTdcp, rmsDop)
+	}
+	if rms := math.Sqrt(sumDrift / float64(nep)); rms > 0.01 {
+		t.Errorf("clock drift error rms = %.4f m/s", rms)
+	}
+}
+
+// TestTDCPVelocitySlip checks that a satellite with a cycle slip is excluded
+// and that missing data is reported.
+func TestTDCPVelocitySlip(t *testing.T) {
+	t0 := Epoch2Time([]float64{2023, 6, 1, 0, 0, 0})
+	nav := synthNav(t0, 24)
+	vel := [3]float64{0.0, 3.0, -1.0}
+	rr1 := synthRover
+	rr2 := [3]float64{rr1[0] + vel[0], rr1[1] + vel[1], rr1[2] + vel[2]}
+	prev := Obs{Data: synthMovingObs(nav, t0, rr1, vel, 0.0, 0.0)}
+	cur := Obs{Data: synthMovingObs(nav, TimeAdd(t0, 1.0), rr2, vel, 0.0, 0.0)}
+
+	cur.Data[0].L[0] += 7.0
+	cur.Data[0].LLI[0] |= LLI_SLIP
+	v, _, err := TDCPVelocity(&prev, &cur, nav, rr2)
+	if err != nil {
+		t.Fatalf("tdcp: %v", err)
+	}
+	if d := synthDist(v[:], vel[:]); d > 1e-4 {
+		t.Errorf("velocity error with slip = %.6f m/s", d)
+	}
+
+	if _, _, err = TDCPVelocity(&prev, &Obs{Data: cur.Data[:3]}, nav, rr2); err == nil {
+		t.Error("no error with 3 satellites")
+	}
+	if _, _, err = TDCPVelocity(&cur, &prev, nav, rr2); err == nil {
+		t.Error("no error with reversed epochs")
+	}
+}
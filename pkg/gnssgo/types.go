@@ -479,12 +479,16 @@ type Pcv struct { /* antenna parameter type */
 	Offset    [NFREQ][3]float64  /* phase center offset e/n/u or x/y/z (m) */
 	Variation [NFREQ][19]float64 /* phase center variation (m) */
 	/* el=90,85,...,0 or nadir=0,1,2,3,... (deg) */
+	Dazi   float64              /* azimuth increment of pcv grid (deg) (0:no azimuth-dependent pcv) */
+	AziVar [NFREQ][][19]float64 /* azimuth-dependent pcv (m) az=0,dazi,...,360 (deg) */
 }
 
 type Pcvs struct { /* antenna parameters type */
 	Pcv []Pcv /* antenna parameters data */
 }
 
+type PcvList = Pcvs /* antenna parameters read by ReadANTEX */
+
 func (pcvs *Pcvs) N() int {
 	return len(pcvs.Pcv)
 }
@@ -548,8 +552,8 @@ type PEph struct { /* precise ephemeris type */
 	Std    [MAXSAT][4]float32 /* satellite position/clock std (m|s) */
 	Vel    [MAXSAT][4]float64 /* satellite velocity/clk-rate (m/s|s/s) */
 	Vst    [MAXSAT][4]float32 /* satellite velocity/clk-rate std (m/s|s/s) */
-	PosCov [MAXSAT][3]float32 /* satellite position covariance (m^2) */
-	VelCov [MAXSAT][3]float32 /* satellite velocity covariance (m^2) */
+	PosCov [MAXSAT][3]float32 /* satellite position covariance {xy,yz,zx} (m^2) */
+	VelCov [MAXSAT][3]float32 /* satellite velocity covariance {xy,yz,zx} (m^2/s^2) */
 }
 
 type PClk struct { /* precise clock type */
@@ -557,6 +561,7 @@ type PClk struct { /* precise clock type */
 	Index int                /* clock index for multiple files */
 	Clk   [MAXSAT][1]float64 /* satellite clock (s) */
 	Std   [MAXSAT][1]float32 /* satellite clock std (s) */
+	Drift [MAXSAT][1]float64 /* satellite clock drift (s/s) (0: not set) */
 }
 
 type SEph struct { /* SBAS ephemeris type */
@@ -781,6 +786,12 @@ type Sta struct { /* station parameter type */
 	Hgt          float64    /* antenna height (m) */
 	glo_cp_align int        /* GLONASS code-phase alignment (0:no,1:yes) */
 	glo_cp_bias  [4]float64 /* GLONASS code-phase biases {1C,1P,2C,2P} (m) */
+	Events       []StaEvent /* station changes by RINEX event records */
+}
+
+type StaEvent struct { /* station change event type */
+	Time Gtime /* time of the first epoch with the new parameters (gpst) */
+	Sta  Sta   /* station parameters after the change */
 }
 
 type Sol struct { /* solution type */
@@ -790,14 +801,18 @@ type Sol struct { /* solution type */
 	Qr [6]float32 /* position variance/covariance (m^2) */
 	/* {c_xx,c_yy,c_zz,c_xy,c_yz,c_zx} or */
 	/* {c_ee,c_nn,c_uu,c_en,c_nu,c_ue} */
-	Qv    [6]float32 /* velocity variance/covariance (m^2/s^2) */
-	Dtr   [6]float64 /* receiver clock bias to time systems (s) */
-	Type  uint8      /* type (0:xyz-ecef,1:enu-baseline) */
-	Stat  uint8      /* solution status (SOLQ_???) */
-	Ns    uint8      /* number of valid satellites */
-	Age   float32    /* age of differential (s) */
-	Ratio float32    /* AR ratio factor for valiation */
-	Thres float32    /* AR ratio threshold for valiation */
+	Qv       [6]float32   /* velocity variance/covariance (m^2/s^2) */
+	Dtr      [6]float64   /* receiver clock bias to time systems (s) */
+	Type     uint8        /* type (0:xyz-ecef,1:enu-baseline) */
+	Stat     uint8        /* solution status (SOLQ_???) */
+	Ns       uint8        /* number of valid satellites */
+	NsFreq   [NFREQ]uint8 /* number of valid satellites per frequency (L1,L2,...) */
+	Age      float32      /* age of differential (s) */
+	Ratio    float32      /* AR ratio factor for valiation */
+	Thres    float32      /* AR ratio threshold for valiation */
+	ARRatio  float64      /* AR ratio-test value s2/s1 (0:not resolved) */
+	NFixed   int          /* number of fixed ambiguities (0:float) */
+	Baseline [3]float64   /* baseline vector rover-base {x,y,z} (ecef) (m) (0:no base) */
 }
 
 type SolBuf struct { /* solution buffer type */
@@ -862,19 +877,25 @@ type Rtcm struct { /* RTCM control struct type */
 	Opt       string                          /* RTCM dependent options */
 }
 type TOBS [8][MAXOBSTYPE]string
+type TOBSCorr struct { /* rinex obs corrections by header (index as TOBS) */
+	scale [8][MAXOBSTYPE]float64 /* scale factor (0:none) */
+	shift [8][MAXOBSTYPE]float64 /* phase shift (cycle) */
+	ssat  [8][MAXOBSTYPE][]int   /* satellites of phase shift (nil:all) */
+}
 type RnxCtr struct { /* RINEX control struct type */
-	time     Gtime   /* message time */
-	ver      float64 /* RINEX version */
-	filetype string  /* RINEX file type ('O','N',...) */
-	sys      int     /* navigation system */
-	tsys     int     /* time system */
-	tobs     TOBS    /* rinex obs types */
-	obs      Obs     /* observation data */
-	nav      Nav     /* navigation data */
-	sta      Sta     /* station info */
-	ephsat   int     /* input ephemeris satellite number */
-	ephset   int     /* input ephemeris set (0-1) */
-	opt      string  /* rinex dependent options */
+	time     Gtime    /* message time */
+	ver      float64  /* RINEX version */
+	filetype string   /* RINEX file type ('O','N',...) */
+	sys      int      /* navigation system */
+	tsys     int      /* time system */
+	tobs     TOBS     /* rinex obs types */
+	tcor     TOBSCorr /* rinex obs corrections by header */
+	obs      Obs      /* observation data */
+	nav      Nav      /* navigation data */
+	sta      Sta      /* station info */
+	ephsat   int      /* input ephemeris satellite number */
+	ephset   int      /* input ephemeris set (0-1) */
+	opt      string   /* rinex dependent options */
 }
 
 type Url struct { /* download URL type */
@@ -904,6 +925,7 @@ type PrcOpt struct { /* processing options type */
 	SolType    int            /* solution type (0:forward,1:backward,2:combined) */
 	Nf         int            /* number of frequencies (1:L1,2:L1+L2,3:L1+L2+L5) */
 	NavSys     int            /* navigation system */
+	IrnSig     int            /* NavIC single-freq signal (0:L5,1:S) */
 	Elmin      float64        /* elevation mask angle (rad) */
 	SnrMask    SnrMask        /* SNR mask */
 	SatEph     int            /* satellite ephemeris/clock (EPHOPT_???) */
@@ -931,32 +953,40 @@ type PrcOpt struct { /* processing options type */
 	/* [0]:reserved */
 	/* [1-3]:error factor a/b/c of phase (m) */
 	/* [4]:doppler frequency (hz) */
-	Std        [3]float64         /* initial-state std [0]bias,[1]iono [2]trop */
-	Prn        [6]float64         /* process-noise std [0]bias,[1]iono [2]trop [3]acch [4]accv [5] pos */
-	SatClkStab float64            /* satellite clock stability (sec/sec) */
-	ThresAr    [8]float64         /* AR validation threshold */
-	ElMaskAr   float64            /* elevation mask of AR for rising satellite (deg) */
-	ElMaskHold float64            /* elevation mask to hold ambiguity (deg) */
-	ThresSlip  float64            /* slip threshold of geometry-free phase (m) */
-	MaxTmDiff  float64            /* max difference of time (sec) */
-	MaxInno    float64            /* reject threshold of innovation (m) */
-	MaxGdop    float64            /* reject threshold of gdop */
-	Baseline   [2]float64         /* baseline length constraint {const,sigma} (m) */
-	Ru         [3]float64         /* rover position for fixed mode {x,y,z} (ecef) (m) */
-	Rb         [3]float64         /* base position for relative mode {x,y,z} (ecef) (m) */
-	AntType    [2]string          /* antenna types {rover,base} */
-	AntDel     [2][3]float64      /* antenna delta {{rov_e,rov_n,rov_u},{ref_e,ref_n,ref_u}} */
-	Pcvr       [2]Pcv             /* receiver antenna parameters {rov,base} */
-	ExSats     [MAXSAT]uint8      /* excluded satellites (1:excluded,2:included) */
-	MaxAveEp   int                /* max averaging epoches */
-	InitRst    int                /* initialize by restart */
-	OutSingle  int                /* output single by dgps/float/fix/ppp outage */
-	RnxOpt     [2]string          /* rinex options {rover,base} */
-	PosOpt     [6]int             /* positioning options */
-	SyncSol    int                /* solution sync mode (0:off,1:on) */
-	Odisp      [2][6 * 11]float64 /* ocean tide loading parameters {rov,base} */
-	FreqOpt    int                /* disable L2-AR */
-	PPPOpt     string             /* ppp option */
+	Std         [3]float64         /* initial-state std [0]bias,[1]iono [2]trop */
+	Prn         [6]float64         /* process-noise std [0]bias,[1]iono [2]trop [3]acch [4]accv [5] pos */
+	SatClkStab  float64            /* satellite clock stability (sec/sec) */
+	ThresAr     [8]float64         /* AR validation threshold ([0]:ratio-test) */
+	ElMaskAr    float64            /* elevation mask of AR for rising satellite (deg) */
+	ElMaskHold  float64            /* elevation mask to hold ambiguity (deg) */
+	ThresSlip   float64            /* slip threshold of geometry-free phase (m) */
+	ThresSlipR  float64            /* slip threshold growth of geometry-free phase per time gap (m/s) */
+	ThresSlipMW float64            /* slip threshold of Melbourne-Wubbena combination (m) (0:off) */
+	MaxTmDiff   float64            /* max time difference between rover and base obs (s) */
+	MaxInno     float64            /* reject threshold of innovation (m) */
+	MaxPosInno  float64            /* reject threshold of position innovation (sigma) (0:off) */
+	PosInnoRst  int                /* reset filter on rejected position innovation (0:off,1:on) */
+	MaxGdop     float64            /* reject threshold of gdop */
+	SppMaxIter  int                /* max iteration of single point positioning (0:MAXITR) */
+	SppConvTol  float64            /* convergence threshold of single point positioning (m) (0:1e-4) */
+	Baseline    [2]float64         /* baseline length constraint {const,sigma} (m) */
+	Ru          [3]float64         /* rover position for fixed mode {x,y,z} (ecef) (m) */
+	Rb          [3]float64         /* base position for relative mode {x,y,z} (ecef) (m) */
+	AntType     [2]string          /* antenna types {rover,base} */
+	AntDel      [2][3]float64      /* antenna delta {{rov_e,rov_n,rov_u},{ref_e,ref_n,ref_u}} */
+	Pcvr        [2]Pcv             /* receiver antenna parameters {rov,base} */
+	PcvList     *PcvList           /* antenna parameters to set Pcvr by AntType in PntPos/RtkPos (nil:off) */
+	StaEvent    int                /* apply station changes by RINEX event records (0:off,1:on) */
+	ExSats      [MAXSAT]uint8      /* excluded satellites (1:excluded,2:included) */
+	MaxAveEp    int                /* max averaging epoches */
+	InitRst     int                /* initialize by restart */
+	OutSingle   int                /* output single by dgps/float/fix/ppp outage */
+	RnxOpt      [2]string          /* rinex options {rover,base} */
+	PosOpt      [6]int             /* positioning options */
+	SyncSol     int                /* solution sync mode (0:off,1:on) */
+	Odisp       [2][6 * 11]float64 /* ocean tide loading parameters {rov,base} */
+	FreqOpt     int                /* disable L2-AR */
+	PPPOpt      string             /* ppp option */
 }
 
 type SolOpt struct { /* solution options type */
@@ -968,6 +998,7 @@ type SolOpt struct { /* solution options type */
 	OutHead   int        /* output header (0:no,1:yes) */
 	OutOpt    int        /* output processing options (0:no,1:yes) */
 	OutVel    int        /* output velocity options (0:no,1:yes) */
+	OutNsFreq int        /* output number of valid satellites per frequency (0:no,1:yes) */
 	Datum     int        /* datum (0:WGS84,1:Tokyo) */
 	Height    int        /* height (0:ellipsoidal,1:geodetic) */
 	Geoid     int        /* geoid model (0:EGM96,1:JGD2000) */
@@ -1002,6 +1033,7 @@ type SSat struct { /* satellite status type */
 	Azel  [2]float64         /* azimuth/elevation angles {az,el} (rad) */
 	Resp  [NFREQ]float32     /* residuals of pseudorange (m) */
 	Resc  [NFREQ]float32     /* residuals of carrier-phase (m) */
+	Tgd   float64            /* broadcast group delay applied to pseudorange (m) */
 	Vsat  [NFREQ]uint8       /* valid satellite flag */
 	Snr   [NFREQ]uint16      /* signal strength (*SNR_UNIT dBHz) */
 	Fix   [NFREQ]uint8       /* ambiguity fix flag (1:fix,2:float,3:hold) */
@@ -1016,6 +1048,7 @@ type SSat struct { /* satellite status type */
 	Phw   float64            /* phase windup (cycle) */
 	Pt    [2][NFREQ]Gtime    /* previous carrier-phase time */
 	Ph    [2][NFREQ]float64  /* previous carrier-phase observable (cycle) */
+	Dp    [2][NFREQ]float64  /* previous doppler observable (Hz) */
 }
 
 type AmbC struct { /* ambiguity control type */
@@ -1028,15 +1061,16 @@ type AmbC struct { /* ambiguity control type */
 }
 
 type Rtk struct { /* RTK control/result type */
-	RtkSol Sol          /* RTK solution */
-	Rb     [6]float64   /* base position/velocity (ecef) (m|m/s) */
-	Nx, Na int          /* number of float states/fixed states */
-	Tt     float64      /* time difference between current and previous (s) */
-	X, P   []float64    /* float states and their covariance */
-	Xa, Pa []float64    /* fixed states and their covariance */
-	Nfix   int          /* number of continuous fixes of ambiguity */
-	Ambc   [MAXSAT]AmbC /* ambibuity control */
-	Ssat   [MAXSAT]SSat /* satellite status */
+	RtkSol   Sol          /* RTK solution */
+	Rb       [6]float64   /* base position/velocity (ecef) (m|m/s) */
+	Nx, Na   int          /* number of float states/fixed states */
+	Tt       float64      /* time difference between current and previous (s) */
+	X, P     []float64    /* float states and their covariance */
+	Xa, Pa   []float64    /* fixed states and their covariance */
+	Nfix     int          /* number of continuous fixes of ambiguity */
+	NInnoRej int          /* number of epochs rejected by position innovation gate */
+	Ambc     [MAXSAT]AmbC /* ambibuity control */
+	Ssat     [MAXSAT]SSat /* satellite status */
 	//neb    int             /* bytes in error message buffer, abandon in go */
 	ErrBuf string /* error message buffer */
 	Opt    PrcOpt /* processing options */
@@ -1149,6 +1183,10 @@ type RtkSvr struct { /* RTK server type */
 	BaseLenReset float64           /* baseline length to reset (km) */
 	Lock         sync.Mutex        /* lock flag */
 	Wg           sync.WaitGroup    /* thread conter is used to indicate thread exit */
+	Feed         [3][]uint8        /* data fed without input stream {rov,base,corr} */
+	FeedLock     sync.Mutex        /* lock flag of fed data */
+	SolOut       SolWriter         /* solution writer (nil: no output) */
+	SolOutOpt    SolOpt            /* solution options of solution writer */
 }
 
 type RnxOpt struct { /* RINEX options type */
@@ -1160,7 +1198,7 @@ type RnxOpt struct { /* RINEX options type */
 	NavSys      int                    /* navigation system */
 	ObsType     int                    /* observation type */
 	FreqType    int                    /* frequency type */
-	Mask        [7][64]byte            /* code mask {GPS,GLO,GAL,QZS,SBS,CMP,IRN} */
+	Mask        [7][MAXCODE]byte       /* code mask {GPS,GLO,GAL,QZS,SBS,CMP,IRN} */
 	Staid       string                 /* station id for rinex file name */
 	Prog        string                 /* program */
 	RunBy       string                 /* run-by */
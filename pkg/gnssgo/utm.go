@@ -0,0 +1,200 @@
+/*------------------------------------------------------------------------------
+* utm.go : UTM and MGRS coordinate conversion
+*
+* references :
+*     [1] C.F.F.Karney, Transverse Mercator with an accuracy of a few
+*         nanometers, J.Geodesy, 85(8), 2011
+*     [2] NGA, Universal Grids and Grid Reference Systems, NGA.STND.0037, 2014
+*-----------------------------------------------------------------------------*/
+package gnssgo
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	UTM_K0 = 0.9996     /* scale factor on central meridian */
+	UTM_FE = 500000.0   /* false easting (m) */
+	UTM_FN = 10000000.0 /* false northing in southern hemisphere (m) */
+
+	mgrsBands = "CDEFGHJKLMNPQRSTUVWX"     /* latitude bands from 80S by 8 deg */
+	mgrsRows  = "ABCDEFGHJKLMNPQRSTUV"     /* 100 km square row letters */
+	mgrsCols  = "ABCDEFGHJKLMNPQRSTUVWXYZ" /* 100 km square column letters */
+)
+
+/* krueger series coefficients [1] (35),(36) --------------------------------*/
+func tmcoef() (A float64, alp, bet [6]float64) {
+	n := FE_WGS84 / (2.0 - FE_WGS84)
+	n2 := n * n
+	n3, n4, n5, n6 := n2*n, n2*n2, n2*n2*n, n2*n2*n2
+	A = RE_WGS84 / (1.0 + n) * (1.0 + n2/4.0 + n4/64.0 + n6/256.0)
+	alp = [6]float64{
+		n/2.0 - 2.0*n2/3.0 + 5.0*n3/16.0 + 41.0*n4/180.0 - 127.0*n5/288.0 + 7891.0*n6/37800.0,
+		13.0*n2/48.0 - 3.0*n3/5.0 + 557.0*n4/1440.0 + 281.0*n5/630.0 - 1983433.0*n6/1935360.0,
+		61.0*n3/240.0 - 103.0*n4/140.0 + 15061.0*n5/26880.0 + 167603.0*n6/181440.0,
+		49561.0*n4/161280.0 - 179.0*n5/168.0 + 6601661.0*n6/7257600.0,
+		34729.0*n5/80640.0 - 3418889.0*n6/1995840.0,
+		212378941.0 * n6 / 319334400.0,
+	}
+	bet = [6]float64{
+		n/2.0 - 2.0*n2/3.0 + 37.0*n3/96.0 - n4/360.0 - 81.0*n5/512.0 + 96199.0*n6/604800.0,
+		n2/48.0 + n3/15.0 - 437.0*n4/1440.0 + 46.0*n5/105.0 - 1118711.0*n6/3870720.0,
+		17.0*n3/480.0 - 37.0*n4/840.0 - 209.0*n5/4480.0 + 5569.0*n6/90720.0,
+		4397.0*n4/161280.0 - 11.0*n5/504.0 - 830251.0*n6/7257600.0,
+		4583.0*n5/161280.0 - 108847.0*n6/3991680.0,
+		20648693.0 * n6 / 638668800.0,
+	}
+	return
+}
+
+/* utm zone of geodetic position including norway/svalbard exceptions [2] ---*/
+func utmzone(lat, lon float64) int {
+	lon = math.Mod(lon+180.0, 360.0)
+	if lon < 0.0 {
+		lon += 360.0
+	}
+	zone := int(lon/6.0) + 1
+	lon -= 180.0
+	switch {
+	case lat >= 56.0 && lat < 64.0 && lon >= 3.0 && lon < 12.0:
+		zone = 32
+	case lat >= 72.0 && lon >= 0.0 && lon < 42.0:
+		switch {
+		case lon < 9.0:
+			zone = 31
+		case lon < 21.0:
+			zone = 33
+		case lon < 33.0:
+			zone = 35
+		default:
+			zone = 37
+		}
+	}
+	return zone
+}
+
+/* geodetic position to utm ----------------------------------------------------
+* transform geodetic position to universal transverse mercator (utm)
+* args   : double *pos      I   geodetic position {lat,lon,h} (rad,m)
+* return : utm zone (1-60, 0: out of utm latitude range), northern hemisphere,
+*          easting and northing (m)
+* notes  : the latitude range of utm is 80S to 84N. the zone follows the
+*          exceptions of southwest norway (32V) and svalbard (31X-37X).
+*          transverse mercator by krueger series of 6th order [1], accuracy
+*          is better than 1 mm within the zone.
+*-----------------------------------------------------------------------------*/
+func Pos2UTM(pos [3]float64) (zone int, north bool, easting, northing float64) {
+	lat, lon := pos[0]*R2D, pos[1]*R2D
+	if lat < -80.0 || lat > 84.0 {
+		return 0, lat >= 0.0, 0.0, 0.0
+	}
+	zone = utmzone(lat, lon)
+	north = lat >= 0.0
+	easting, northing = tmfwd(pos[0], pos[1]-(float64(zone)*6.0-183.0)*D2R)
+	easting += UTM_FE
+	if !north {
+		northing += UTM_FN
+	}
+	return
+}
+
+/* utm to geodetic position ----------------------------------------------------
+* transform universal transverse mercator (utm) to geodetic position
+* args   : int    zone      I   utm zone (1-60)
+*          bool   north     I   northern hemisphere
+*          double easting   I   easting (m)
+*          double northing  I   northing (m)
+* return : geodetic position {lat,lon,h} (rad,m) with h=0 and error
+*-----------------------------------------------------------------------------*/
+func UTM2Pos(zone int, north bool, easting, northing float64) ([3]float64, error) {
+	var pos [3]float64
+	if zone < 1 || zone > 60 {
+		return pos, fmt.Errorf("invalid utm zone %d", zone)
+	}
+	if !north {
+		northing -= UTM_FN
+	}
+	lat, dlon := tminv(easting-UTM_FE, northing)
+	pos[0] = lat
+	pos[1] = dlon + (float64(zone)*6.0-183.0)*D2R
+	if pos[1] > PI {
+		pos[1] -= 2.0 * PI
+	} else if pos[1] < -PI {
+		pos[1] += 2.0 * PI
+	}
+	return pos, nil
+}
+
+/* geodetic position to mgrs ---------------------------------------------------
+* transform geodetic position to military grid reference system (mgrs) [2]
+* args   : double *pos      I   geodetic position {lat,lon,h} (rad,m)
+* return : mgrs grid reference with 1 m resolution (e.g. "38SMB4414084706")
+*          and error
+* notes  : the polar regions (ups) are not supported.
+*          the easting and northing in the 100 km square are truncated.
+*-----------------------------------------------------------------------------*/
+func Pos2MGRS(pos [3]float64) (string, error) {
+	zone, _, easting, northing := Pos2UTM(pos)
+	if zone == 0 {
+		return "", fmt.Errorf("latitude %.6f out of mgrs utm range", pos[0]*R2D)
+	}
+	band := min(int((pos[0]*R2D+80.0)/8.0), len(mgrsBands)-1)
+	e, n := int(math.Floor(easting)), int(math.Floor(northing))
+	col := (zone-1)%3*8 + e/100000 - 1
+	row := n / 100000
+	if zone%2 == 0 {
+		row += 5
+	}
+	if col < 0 || col >= len(mgrsCols) {
+		return "", fmt.Errorf("easting %.3f out of mgrs range", easting)
+	}
+	return fmt.Sprintf("%02d%c%c%c%05d%05d", zone, mgrsBands[band], mgrsCols[col],
+		mgrsRows[row%len(mgrsRows)], e%100000, n%100000), nil
+}
+
+/* transverse mercator forward [1] (6)-(9),(11) ------------------------------*/
+func tmfwd(lat, dlon float64) (x, y float64) {
+	A, alp, _ := tmcoef()
+	e := math.Sqrt(FE_WGS84 * (2.0 - FE_WGS84))
+	sinp := math.Sin(lat)
+	t := math.Sinh(math.Atanh(sinp) - e*math.Atanh(e*sinp))
+	xip := math.Atan2(t, math.Cos(dlon))
+	etap := math.Atanh(math.Sin(dlon) / math.Sqrt(1.0+t*t))
+	xi, eta := xip, etap
+	for j := 1; j <= 6; j++ {
+		xi += alp[j-1] * math.Sin(2.0*float64(j)*xip) * math.Cosh(2.0*float64(j)*etap)
+		eta += alp[j-1] * math.Cos(2.0*float64(j)*xip) * math.Sinh(2.0*float64(j)*etap)
+	}
+	return UTM_K0 * A * eta, UTM_K0 * A * xi
+}
+
+/* transverse mercator inverse [1] (11),(15),(19)-(21) -----------------------*/
+func tminv(x, y float64) (lat, dlon float64) {
+	A, _, bet := tmcoef()
+	e2 := FE_WGS84 * (2.0 - FE_WGS84)
+	e := math.Sqrt(e2)
+	xi, eta := y/(UTM_K0*A), x/(UTM_K0*A)
+	xip, etap := xi, eta
+	for j := 1; j <= 6; j++ {
+		xip -= bet[j-1] * math.Sin(2.0*float64(j)*xi) * math.Cosh(2.0*float64(j)*eta)
+		etap -= bet[j-1] * math.Cos(2.0*float64(j)*xi) * math.Sinh(2.0*float64(j)*eta)
+	}
+	sh, c := math.Sinh(etap), math.Cos(xip)
+	taup := math.Sin(xip) / math.Sqrt(sh*sh+c*c)
+	dlon = math.Atan2(sh, c)
+
+	/* solve tau from tau' by newton's method */
+	tau := taup
+	for i := 0; i < 10; i++ {
+		sig := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1.0+tau*tau)))
+		taui := tau*math.Sqrt(1.0+sig*sig) - sig*math.Sqrt(1.0+tau*tau)
+		dtau := (taup - taui) / math.Sqrt(1.0+taui*taui) *
+			(1.0 + (1.0-e2)*tau*tau) / ((1.0 - e2) * math.Sqrt(1.0+tau*tau))
+		tau += dtau
+		if math.Abs(dtau) < 1e-14 {
+			break
+		}
+	}
+	return math.Atan(tau), dlon
+}
@@ -0,0 +1,64 @@
+package gnssgo
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPos2UTM checks the UTM coordinates and MGRS references of several
+// zones and both hemispheres, including the Norway and Svalbard zones, and
+// the inverse transformation.
+func TestPos2UTM(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		zone     int
+		north    bool
+		ee, nn   float64
+		mgrs     string
+	}{
+		/* GeoConvert example of GeographicLib */
+		{33.3, 44.4, 38, true, 444140.54, 3684706.36, "38SMB4414084706"},
+		/* mirrored about the equator */
+		{-33.3, 44.4, 38, false, 444140.54, 6315293.64, "38HMJ4414015293"},
+		/* central meridian, meridian arc of 45 deg 4984944.378 m */
+		{45.0, 3.0, 31, true, 500000.0, 0.9996 * 4984944.378, "31TEK0000082950"},
+		{45.0, -93.0, 15, true, 500000.0, 0.9996 * 4984944.378, "15TWK0000082950"},
+		{0.0, 3.0, 31, true, 500000.0, 0.0, "31NEA0000000000"},
+		/* southwest norway 32V and svalbard 33X, 35X */
+		{60.0, 5.0, 32, true, 276979.93, 6658157.20, "32VKM7697958157"},
+		{78.0, 10.0, 33, true, 384085.48, 8663320.20, "33XUG8408563320"},
+		{78.0, 22.0, 35, true, 384085.48, 8663320.20, "35XLG8408563320"},
+	}
+	for _, tt := range tests {
+		pos := [3]float64{tt.lat * D2R, tt.lon * D2R, 100.0}
+		zone, north, e, n := Pos2UTM(pos)
+		if zone != tt.zone || north != tt.north || math.Abs(e-tt.ee) > 0.01 || math.Abs(n-tt.nn) > 0.01 {
+			t.Errorf("%.1f %.1f: got %d %v %.3f %.3f, want %d %v %.3f %.3f", tt.lat, tt.lon,
+				zone, north, e, n, tt.zone, tt.north, tt.ee, tt.nn)
+		}
+		if mgrs, err := Pos2MGRS(pos); err != nil || mgrs != tt.mgrs {
+			t.Errorf("%.1f %.1f: mgrs %s (%v), want %s", tt.lat, tt.lon, mgrs, err, tt.mgrs)
+		}
+		inv, err := UTM2Pos(zone, north, e, n)
+		if err != nil || math.Abs(inv[0]-pos[0])*RE_WGS84 > 1e-6 || math.Abs(inv[1]-pos[1])*RE_WGS84 > 1e-6 {
+			t.Errorf("%.1f %.1f: inverse %.10f %.10f (%v)", tt.lat, tt.lon, inv[0]*R2D, inv[1]*R2D, err)
+		}
+	}
+
+	/* zone boundaries and polar regions */
+	if zone, _, _, _ := Pos2UTM([3]float64{10.0 * D2R, 180.0 * D2R}); zone != 1 {
+		t.Errorf("lon 180: zone %d, want 1", zone)
+	}
+	if zone, _, _, _ := Pos2UTM([3]float64{10.0 * D2R, -180.0 * D2R}); zone != 1 {
+		t.Errorf("lon -180: zone %d, want 1", zone)
+	}
+	if zone, _, _, _ := Pos2UTM([3]float64{85.0 * D2R, 0.0}); zone != 0 {
+		t.Errorf("lat 85: zone %d, want 0", zone)
+	}
+	if _, err := Pos2MGRS([3]float64{-85.0 * D2R, 0.0}); err == nil {
+		t.Errorf("lat -85: want mgrs error")
+	}
+	if _, err := UTM2Pos(61, true, 500000.0, 0.0); err == nil {
+		t.Errorf("zone 61: want error")
+	}
+}
@@ -1,10 +1,12 @@
 package igs
 
 import (
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,6 +22,10 @@ const (
 	ProductTypeSP3 ProductType = "sp3"
 	// ProductTypeCLK represents precise clock files (.clk)
 	ProductTypeCLK ProductType = "clk"
+	// ProductTypeIONEX represents global ionosphere maps (IONEX)
+	ProductTypeIONEX ProductType = "ionex"
+	// ProductTypeERP represents Earth rotation parameters (.erp)
+	ProductTypeERP ProductType = "erp"
 )
 
 // AnalysisCenter represents an IGS analysis center
@@ -40,22 +46,46 @@ const (
 	AnalysisCenterJPL AnalysisCenter = "jpl"
 )
 
+// ProductLatency represents the solution type (latency) of IGS products
+type ProductLatency string
+
+const (
+	// LatencyFinal represents final products (about 2 weeks latency)
+	LatencyFinal ProductLatency = "FIN"
+	// LatencyRapid represents rapid products (about 1 day latency)
+	LatencyRapid ProductLatency = "RAP"
+	// LatencyUltraRapid represents ultra-rapid products (issued every 6 hours)
+	LatencyUltraRapid ProductLatency = "ULT"
+)
+
+// ErrNotFound is returned (wrapped) when the server has no such file
+var ErrNotFound = errors.New("file not found")
+
 // Client represents an IGS products client
 type Client struct {
 	// BaseURL is the base URL for the IGS products
 	BaseURL string
+	// DataURL is the base URL for the IGS daily data (broadcast ephemerides)
+	DataURL string
 	// HTTPClient is the HTTP client used for requests
 	HTTPClient *http.Client
 	// DownloadDir is the directory where files will be downloaded
 	DownloadDir string
+	// Latency selects final, rapid or ultra-rapid products
+	Latency ProductLatency
+	// VerifyChecksum enables the verification of DownloadProducts against
+	// sidecar checksum files
+	VerifyChecksum bool
 }
 
 // NewClient creates a new IGS client
 func NewClient(downloadDir string) *Client {
 	return &Client{
 		BaseURL:     "https://igs.ign.fr/pub/igs/products/",
+		DataURL:     "https://igs.ign.fr/pub/igs/data/",
 		HTTPClient:  &http.Client{Timeout: 60 * time.Second},
 		DownloadDir: downloadDir,
+		Latency:     LatencyFinal,
 	}
 }
 
@@ -64,6 +94,50 @@ func (c *Client) SetBaseURL(baseURL string) {
 	c.BaseURL = baseURL
 }
 
+// SetProxy sets the proxy of the HTTP client, an http://, https:// or
+// socks5:// URL or a host:port of an HTTP proxy. With "" the proxy is taken
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, as by
+// default.
+func (c *Client) SetProxy(proxyURL string) error {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != "" {
+		if !strings.Contains(proxyURL, "://") {
+			proxyURL = "http://" + proxyURL
+		}
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %q: %w", proxyURL, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("invalid proxy %q: no host", proxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	var transport *http.Transport
+	switch t := c.HTTPClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return errors.New("proxy not supported by the HTTP client transport")
+	}
+	transport.Proxy = proxy
+	c.HTTPClient.Transport = transport
+	return nil
+}
+
+// SetDataURL sets the base URL for the IGS daily data
+func (c *Client) SetDataURL(dataURL string) {
+	c.DataURL = dataURL
+}
+
 // GPSWeekAndDay calculates the GPS week and day of week from a time
 func GPSWeekAndDay(t time.Time) (int, int) {
 	// GPS time started at 00:00:00 January 6, 1980 UTC
@@ -84,23 +158,114 @@ func GPSWeekAndDay(t time.Time) (int, int) {
 	return week, dayOfWeek
 }
 
-// GetProductURL generates the URL for a specific product
-func (c *Client) GetProductURL(t time.Time, productType ProductType, ac AnalysisCenter) (string, error) {
+// latency returns the product latency of the client (final by default)
+func (c *Client) latency() ProductLatency {
+	if c.Latency == "" {
+		return LatencyFinal
+	}
+	return c.Latency
+}
+
+// ultraRapidHour returns the issue hour (0, 6, 12 or 18) of the latest
+// ultra-rapid product at or before t
+func ultraRapidHour(t time.Time) int {
+	return t.Hour() / 6 * 6
+}
+
+// ShortProductName returns the legacy filename of a product
+// Example: igs22621.sp3.Z (final), igr22621.clk.Z (rapid),
+// igu22621_06.sp3.Z (ultra-rapid), igsg1350.23i.Z (IONEX),
+// igs22627.erp.Z (weekly final ERP)
+func (c *Client) ShortProductName(t time.Time, productType ProductType, ac AnalysisCenter) string {
 	week, day := GPSWeekAndDay(t)
+	lat := c.latency()
+
+	// The IGS combined rapid and ultra-rapid products are igr and igu
+	prefix := string(ac)
+	if ac == AnalysisCenterIGS {
+		switch lat {
+		case LatencyRapid:
+			prefix = "igr"
+		case LatencyUltraRapid:
+			prefix = "igu"
+		}
+	}
+
+	switch productType {
+	case ProductTypeIONEX:
+		// Format: {ac}g{day of year}0.{yy}i.Z (rapid IGS maps are igrg)
+		if ac == AnalysisCenterIGS && lat != LatencyFinal {
+			prefix = "igr"
+		}
+		return fmt.Sprintf("%sg%03d0.%02di.Z", prefix, t.YearDay(), t.Year()%100)
+	case ProductTypeERP:
+		// Final ERP files are weekly (day 7)
+		if lat == LatencyFinal {
+			day = 7
+		}
+	}
 
-	// Format: {ac}{week}{day}.{ext}
-	// Example: igs20607.sp3.Z for IGS orbit file for GPS week 2060, day 7
-	filename := fmt.Sprintf("%s%04d%d.%s", ac, week, day, productType)
+	// Format: {ac}{week}{day}[_{hour}].{ext}.Z
+	filename := fmt.Sprintf("%s%04d%d", prefix, week, day)
+	if lat == LatencyUltraRapid {
+		filename += fmt.Sprintf("_%02d", ultraRapidHour(t))
+	}
+	return filename + "." + string(productType) + ".Z"
+}
 
-	// Add compression extension if needed
-	if productType == ProductTypeSP3 || productType == ProductTypeCLK {
-		filename += ".Z"
+// LongProductName returns the filename of a product in the long naming
+// convention used by IGS since GPS week 2238
+// Example: IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz
+func (c *Client) LongProductName(t time.Time, productType ProductType, ac AnalysisCenter) string {
+	lat := c.latency()
+
+	// Start, period and sampling interval of the product
+	start, hour, period, sampling, content := t, 0, "01D", "15M", "ORB.SP3"
+	switch productType {
+	case ProductTypeCLK:
+		content, sampling = "CLK.CLK", "05M"
+		if lat == LatencyFinal {
+			sampling = "30S"
+		}
+	case ProductTypeIONEX:
+		content, sampling = "GIM.INX", "02H"
+	case ProductTypeERP:
+		content, sampling = "ERP.ERP", "01D"
+		if lat == LatencyFinal {
+			// Final ERP files are weekly from the start of the GPS week
+			_, day := GPSWeekAndDay(t)
+			start, period = t.AddDate(0, 0, -day), "07D"
+		}
 	}
+	if lat == LatencyUltraRapid && productType != ProductTypeIONEX {
+		hour, period = ultraRapidHour(t), "02D"
+	}
+
+	// Format: {AC}0OPS{latency}_{YYYYDDDHHMM}_{period}_{sampling}_{content}.gz
+	return fmt.Sprintf("%s0OPS%s_%04d%03d%02d00_%s_%s_%s.gz", strings.ToUpper(string(ac)),
+		lat, start.Year(), start.YearDay(), hour, period, sampling, content)
+}
+
+// GetProductURL generates the URL for a specific product with the legacy
+// short filename
+func (c *Client) GetProductURL(t time.Time, productType ProductType, ac AnalysisCenter) (string, error) {
+	return c.productURL(t, productType, c.ShortProductName(t, productType, ac)), nil
+}
 
-	// Construct the full URL
-	url := fmt.Sprintf("%s/%04d/%s", c.BaseURL, week, filename)
+// GetLongProductURL generates the URL for a specific product with the long
+// filename
+func (c *Client) GetLongProductURL(t time.Time, productType ProductType, ac AnalysisCenter) (string, error) {
+	return c.productURL(t, productType, c.LongProductName(t, productType, ac)), nil
+}
 
-	return url, nil
+// productURL constructs the URL of a file in the GPS week directory of t, or
+// in the ionex/{year}/{day of year} directory for IONEX
+func (c *Client) productURL(t time.Time, productType ProductType, filename string) string {
+	if productType == ProductTypeIONEX {
+		return fmt.Sprintf("%s/ionex/%04d/%03d/%s", c.BaseURL, t.Year(), t.YearDay(), filename)
+	}
+	week, _ := GPSWeekAndDay(t)
+	return fmt.Sprintf("%s/%04d/%s", c.BaseURL, week, filename)
 }
 
 // DownloadFile downloads a file from the given URL to the specified local path
@@ -125,6 +290,9 @@ func (c *Client) DownloadFile(url, localPath string) error {
 	defer resp.Body.Close()
 
 	// Check the response status code
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("bad status: %s: %w", resp.Status, ErrNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("bad status: %s", resp.Status)
 	}
@@ -146,21 +314,65 @@ func (c *Client) DownloadFile(url, localPath string) error {
 }
 
 // DownloadProduct downloads a specific product for the given time
+// The long filename is tried first; when the server does not have it, the
+// legacy short filename is downloaded instead. The local file keeps the name
+// of the file downloaded.
 func (c *Client) DownloadProduct(t time.Time, productType ProductType, ac AnalysisCenter) (string, error) {
-	// Get the URL for the product
-	url, err := c.GetProductURL(t, productType, ac)
+	week, _ := GPSWeekAndDay(t)
+	dir := filepath.Join(c.DownloadDir, fmt.Sprintf("%04d", week))
+
+	// Try the long filename
+	filename := c.LongProductName(t, productType, ac)
+	localPath := filepath.Join(dir, filename)
+	err := c.DownloadFile(c.productURL(t, productType, filename), localPath)
+	if err == nil {
+		return localPath, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+
+	// Fall back to the legacy short filename
+	filename = c.ShortProductName(t, productType, ac)
+	localPath = filepath.Join(dir, filename)
+	if err := c.DownloadFile(c.productURL(t, productType, filename), localPath); err != nil {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return localPath, nil
+}
+
+// BRDCName returns the filename of the daily combined broadcast ephemeris
+// Example: BRDC00IGS_R_20231350000_01D_MN.rnx.gz (mixed, multi-GNSS),
+// brdc1350.23n.Z (GPS only)
+func BRDCName(t time.Time, mixed bool) string {
+	if mixed {
+		return fmt.Sprintf("BRDC00IGS_R_%04d%03d0000_01D_MN.rnx.gz", t.Year(), t.YearDay())
+	}
+	return fmt.Sprintf("brdc%03d0.%02dn.Z", t.YearDay(), t.Year()%100)
+}
+
+// GetBRDCURL generates the URL for the daily combined broadcast ephemeris
+func (c *Client) GetBRDCURL(t time.Time, mixed bool) (string, error) {
+	// Format: {data}/{year}/{day of year}/{filename}
+	return fmt.Sprintf("%s/%04d/%03d/%s", c.DataURL, t.Year(), t.YearDay(), BRDCName(t, mixed)), nil
+}
+
+// DownloadBRDC downloads the daily combined broadcast ephemeris for the given
+// time, the multi-GNSS RINEX 3 file if mixed is true or the GPS RINEX 2 file
+// otherwise. The file is saved compressed; use DecompressFile to
+// decompress it.
+func (c *Client) DownloadBRDC(t time.Time, mixed bool) (string, error) {
+	url, err := c.GetBRDCURL(t, mixed)
 	if err != nil {
-		return "", fmt.Errorf("failed to get product URL: %w", err)
+		return "", fmt.Errorf("failed to get broadcast ephemeris URL: %w", err)
 	}
 
 	// Generate the local file path
-	week, day := GPSWeekAndDay(t)
-	filename := fmt.Sprintf("%s%04d%d.%s.Z", ac, week, day, productType)
-	localPath := filepath.Join(c.DownloadDir, fmt.Sprintf("%04d", week), filename)
+	week, _ := GPSWeekAndDay(t)
+	localPath := filepath.Join(c.DownloadDir, fmt.Sprintf("%04d", week), BRDCName(t, mixed))
 
-	// Download the file
-	err = c.DownloadFile(url, localPath)
-	if err != nil {
+	if err := c.DownloadFile(url, localPath); err != nil {
 		return "", fmt.Errorf("failed to download file: %w", err)
 	}
 
@@ -177,11 +389,25 @@ func (c *Client) DownloadCLK(t time.Time, ac AnalysisCenter) (string, error) {
 	return c.DownloadProduct(t, ProductTypeCLK, ac)
 }
 
-// DecompressFile decompresses a .Z file using the uncompress command
-// This assumes that the uncompress command is available on the system
+// DownloadIONEX downloads a global ionosphere map (IONEX) for the given time
+func (c *Client) DownloadIONEX(t time.Time, ac AnalysisCenter) (string, error) {
+	return c.DownloadProduct(t, ProductTypeIONEX, ac)
+}
+
+// DownloadERP downloads an Earth rotation parameter file for the given time
+func (c *Client) DownloadERP(t time.Time, ac AnalysisCenter) (string, error) {
+	return c.DownloadProduct(t, ProductTypeERP, ac)
+}
+
+// DecompressFile decompresses a .Z or .gz file
+// .Z files are decompressed with the uncompress command, which is assumed
+// to be available on the system
 func DecompressFile(filePath string) (string, error) {
+	if strings.HasSuffix(filePath, ".gz") {
+		return decompressGzip(filePath)
+	}
 	if !strings.HasSuffix(filePath, ".Z") {
-		return "", errors.New("file is not compressed with .Z")
+		return "", errors.New("file is not compressed with .Z or .gz")
 	}
 
 	// Get the output file path (remove the .Z extension)
@@ -198,3 +424,38 @@ func DecompressFile(filePath string) (string, error) {
 
 	return outputPath, nil
 }
+
+// decompressGzip decompresses a .gz file and removes it like uncompress
+func decompressGzip(filePath string) (string, error) {
+	outputPath := strings.TrimSuffix(filePath, ".gz")
+
+	in, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress file: %w", err)
+	}
+	defer zr.Close()
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, zr); err != nil {
+		out.Close()
+		return "", fmt.Errorf("failed to decompress file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	in.Close()
+	if err := os.Remove(filePath); err != nil {
+		return "", fmt.Errorf("failed to remove file: %w", err)
+	}
+	return outputPath, nil
+}
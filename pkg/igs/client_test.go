@@ -1,10 +1,13 @@
 package igs
 
 import (
+	"bytes"
+	"compress/gzip"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,6 +81,20 @@ func TestGetProductURL(t *testing.T) {
 			ac:          AnalysisCenterIGS,
 			want:        "https://igs.ign.fr/pub/igs/products/2262/igs22621.clk.Z",
 		},
+		{
+			name:        "IGS IONEX for 2023-05-15",
+			time:        time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC),
+			productType: ProductTypeIONEX,
+			ac:          AnalysisCenterIGS,
+			want:        "https://igs.ign.fr/pub/igs/products/ionex/2023/135/igsg1350.23i.Z",
+		},
+		{
+			name:        "IGS ERP for 2023-05-15",
+			time:        time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC),
+			productType: ProductTypeERP,
+			ac:          AnalysisCenterIGS,
+			want:        "https://igs.ign.fr/pub/igs/products/2262/igs22627.erp.Z",
+		},
 		{
 			name:        "JPL SP3 for 2023-05-15",
 			time:        time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC),
@@ -150,3 +167,340 @@ func TestDownloadProduct_MockServer(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "bad status")
 }
+
+func TestProductNames(t *testing.T) {
+	testTime := time.Date(2023, 5, 15, 7, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		latency     ProductLatency
+		productType ProductType
+		ac          AnalysisCenter
+		wantLong    string
+		wantShort   string
+	}{
+		{
+			name:        "IGS final SP3",
+			latency:     LatencyFinal,
+			productType: ProductTypeSP3,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz",
+			wantShort:   "igs22621.sp3.Z",
+		},
+		{
+			name:        "IGS final CLK",
+			latency:     LatencyFinal,
+			productType: ProductTypeCLK,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSFIN_20231350000_01D_30S_CLK.CLK.gz",
+			wantShort:   "igs22621.clk.Z",
+		},
+		{
+			name:        "IGS rapid CLK",
+			latency:     LatencyRapid,
+			productType: ProductTypeCLK,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSRAP_20231350000_01D_05M_CLK.CLK.gz",
+			wantShort:   "igr22621.clk.Z",
+		},
+		{
+			name:        "IGS ultra-rapid SP3",
+			latency:     LatencyUltraRapid,
+			productType: ProductTypeSP3,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSULT_20231350600_02D_15M_ORB.SP3.gz",
+			wantShort:   "igu22621_06.sp3.Z",
+		},
+		{
+			name:        "IGS final IONEX",
+			latency:     LatencyFinal,
+			productType: ProductTypeIONEX,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSFIN_20231350000_01D_02H_GIM.INX.gz",
+			wantShort:   "igsg1350.23i.Z",
+		},
+		{
+			name:        "IGS rapid IONEX",
+			latency:     LatencyRapid,
+			productType: ProductTypeIONEX,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSRAP_20231350000_01D_02H_GIM.INX.gz",
+			wantShort:   "igrg1350.23i.Z",
+		},
+		{
+			name:        "COD final IONEX",
+			latency:     LatencyFinal,
+			productType: ProductTypeIONEX,
+			ac:          AnalysisCenterCOD,
+			wantLong:    "COD0OPSFIN_20231350000_01D_02H_GIM.INX.gz",
+			wantShort:   "codg1350.23i.Z",
+		},
+		{
+			name:        "IGS final ERP",
+			latency:     LatencyFinal,
+			productType: ProductTypeERP,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSFIN_20231340000_07D_01D_ERP.ERP.gz",
+			wantShort:   "igs22627.erp.Z",
+		},
+		{
+			name:        "IGS rapid ERP",
+			latency:     LatencyRapid,
+			productType: ProductTypeERP,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSRAP_20231350000_01D_01D_ERP.ERP.gz",
+			wantShort:   "igr22621.erp.Z",
+		},
+		{
+			name:        "IGS ultra-rapid ERP",
+			latency:     LatencyUltraRapid,
+			productType: ProductTypeERP,
+			ac:          AnalysisCenterIGS,
+			wantLong:    "IGS0OPSULT_20231350600_02D_01D_ERP.ERP.gz",
+			wantShort:   "igu22621_06.erp.Z",
+		},
+		{
+			name:        "COD final SP3",
+			latency:     LatencyFinal,
+			productType: ProductTypeSP3,
+			ac:          AnalysisCenterCOD,
+			wantLong:    "COD0OPSFIN_20231350000_01D_15M_ORB.SP3.gz",
+			wantShort:   "cod22621.sp3.Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("")
+			client.Latency = tt.latency
+			assert.Equal(t, tt.wantLong, client.LongProductName(testTime, tt.productType, tt.ac))
+			assert.Equal(t, tt.wantShort, client.ShortProductName(testTime, tt.productType, tt.ac))
+		})
+	}
+}
+
+func TestDownloadProduct_Naming(t *testing.T) {
+	testTime := time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		latency   ProductLatency
+		files     []string // files served by the mock server
+		wantFile  string
+		wantPaths []string // paths requested
+	}{
+		{
+			name:      "Long name",
+			latency:   LatencyFinal,
+			files:     []string{"/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz", "/2262/igs22621.sp3.Z"},
+			wantFile:  "IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz",
+			wantPaths: []string{"/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"},
+		},
+		{
+			name:      "Fallback to short name",
+			latency:   LatencyFinal,
+			files:     []string{"/2262/igs22621.sp3.Z"},
+			wantFile:  "igs22621.sp3.Z",
+			wantPaths: []string{"/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz", "/2262/igs22621.sp3.Z"},
+		},
+		{
+			name:      "Rapid fallback",
+			latency:   LatencyRapid,
+			files:     []string{"/2262/igr22621.sp3.Z"},
+			wantFile:  "igr22621.sp3.Z",
+			wantPaths: []string{"/2262/IGS0OPSRAP_20231350000_01D_15M_ORB.SP3.gz", "/2262/igr22621.sp3.Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				for _, f := range tt.files {
+					if r.URL.Path == f {
+						w.Write([]byte(f))
+						return
+					}
+				}
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			tempDir := t.TempDir()
+			client := NewClient(tempDir)
+			client.SetBaseURL(server.URL)
+			client.HTTPClient = server.Client()
+			client.Latency = tt.latency
+
+			localPath, err := client.DownloadSP3(testTime, AnalysisCenterIGS)
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.Join(tempDir, "2262", tt.wantFile), localPath)
+			assert.Equal(t, tt.wantPaths, paths)
+
+			content, err := os.ReadFile(localPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "/2262/"+tt.wantFile, string(content))
+		})
+	}
+}
+
+func TestDownloadProduct_ServerError(t *testing.T) {
+	// A server error other than 404 does not fall back to the short name
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(t.TempDir())
+	client.SetBaseURL(server.URL)
+	client.HTTPClient = server.Client()
+
+	_, err := client.DownloadCLK(time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC), AnalysisCenterIGS)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []string{"/2262/IGS0OPSFIN_20231350000_01D_30S_CLK.CLK.gz"}, paths)
+}
+
+func TestDecompressFile_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("#dP2023  5 15"))
+	zw.Close()
+
+	filePath := filepath.Join(t.TempDir(), "IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz")
+	assert.NoError(t, os.WriteFile(filePath, buf.Bytes(), 0644))
+
+	outputPath, err := DecompressFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSuffix(filePath, ".gz"), outputPath)
+
+	content, err := os.ReadFile(outputPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "#dP2023  5 15", string(content))
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadBRDC(t *testing.T) {
+	tests := []struct {
+		name     string
+		mixed    bool
+		wantPath string
+		wantFile string
+	}{
+		{
+			name:     "Mixed",
+			mixed:    true,
+			wantPath: "/2023/135/BRDC00IGS_R_20231350000_01D_MN.rnx.gz",
+			wantFile: "BRDC00IGS_R_20231350000_01D_MN.rnx.gz",
+		},
+		{
+			name:     "GPS",
+			mixed:    false,
+			wantPath: "/2023/135/brdc1350.23n.Z",
+			wantFile: "brdc1350.23n.Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				w.Write([]byte("brdc"))
+			}))
+			defer server.Close()
+
+			tempDir := t.TempDir()
+			client := NewClient(tempDir)
+			client.SetDataURL(server.URL)
+			client.HTTPClient = server.Client()
+
+			localPath, err := client.DownloadBRDC(time.Date(2023, 5, 15, 12, 0, 0, 0, time.UTC), tt.mixed)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.wantPath}, paths)
+			assert.Equal(t, filepath.Join(tempDir, "2262", tt.wantFile), localPath)
+
+			content, err := os.ReadFile(localPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "brdc", string(content))
+		})
+	}
+}
+
+func TestGetLongProductURL(t *testing.T) {
+	client := NewClient("")
+	client.SetBaseURL("https://igs.ign.fr/pub/igs/products")
+	testTime := time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		productType ProductType
+		want        string
+	}{
+		{ProductTypeSP3, "https://igs.ign.fr/pub/igs/products/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"},
+		{ProductTypeCLK, "https://igs.ign.fr/pub/igs/products/2262/IGS0OPSFIN_20231350000_01D_30S_CLK.CLK.gz"},
+		{ProductTypeIONEX, "https://igs.ign.fr/pub/igs/products/ionex/2023/135/IGS0OPSFIN_20231350000_01D_02H_GIM.INX.gz"},
+		{ProductTypeERP, "https://igs.ign.fr/pub/igs/products/2262/IGS0OPSFIN_20231340000_07D_01D_ERP.ERP.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.productType), func(t *testing.T) {
+			got, err := client.GetLongProductURL(testTime, tt.productType, AnalysisCenterIGS)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDownloadIONEXAndERP(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte("product"))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	client := NewClient(tempDir)
+	client.SetBaseURL(server.URL)
+	client.HTTPClient = server.Client()
+	testTime := time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	localPath, err := client.DownloadIONEX(testTime, AnalysisCenterCOD)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(tempDir, "2262", "COD0OPSFIN_20231350000_01D_02H_GIM.INX.gz"), localPath)
+
+	localPath, err = client.DownloadERP(testTime, AnalysisCenterIGS)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(tempDir, "2262", "IGS0OPSFIN_20231340000_07D_01D_ERP.ERP.gz"), localPath)
+
+	assert.Equal(t, []string{
+		"/ionex/2023/135/COD0OPSFIN_20231350000_01D_02H_GIM.INX.gz",
+		"/2262/IGS0OPSFIN_20231340000_07D_01D_ERP.ERP.gz",
+	}, paths)
+}
+
+func TestSetProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the archive
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	client := NewClient(t.TempDir())
+	client.SetBaseURL("http://igs.invalid/products")
+	assert.Error(t, client.SetProxy("ftp://proxy:21"))
+	assert.NoError(t, client.SetProxy(strings.TrimPrefix(proxy.URL, "http://")))
+
+	localPath, err := client.DownloadSP3(time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC), AnalysisCenterIGS)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://igs.invalid/products/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"}, proxied)
+	content, err := os.ReadFile(localPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "proxied", string(content))
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/bramburn/gnssgo/pkg/igs"
@@ -15,18 +14,24 @@ func main() {
 	var (
 		productType  string
 		ac           string
+		latency      string
 		date         string
 		outputDir    string
 		decompress   bool
 		listCenters  bool
 		listProducts bool
+		gpsOnly      bool
+		proxy        string
 	)
 
-	flag.StringVar(&productType, "type", "sp3", "Product type (sp3 or clk)")
+	flag.StringVar(&productType, "type", "sp3", "Product type (sp3, clk, ionex, erp or brdc)")
 	flag.StringVar(&ac, "ac", "igs", "Analysis center (igs, cod, emr, esa, gfz, jpl)")
+	flag.StringVar(&latency, "latency", "fin", "Product latency (fin, rap or ult)")
 	flag.StringVar(&date, "date", "", "Date in YYYY-MM-DD format (default: today)")
 	flag.StringVar(&outputDir, "out", ".", "Output directory")
 	flag.BoolVar(&decompress, "decompress", false, "Decompress downloaded files")
+	flag.BoolVar(&gpsOnly, "gps-only", false, "Download the GPS broadcast ephemeris instead of the mixed one (brdc)")
+	flag.StringVar(&proxy, "proxy", "", "Proxy URL (http://, https:// or socks5://, default: HTTP_PROXY)")
 	flag.BoolVar(&listCenters, "list-centers", false, "List available analysis centers")
 	flag.BoolVar(&listProducts, "list-products", false, "List available product types")
 	flag.Parse()
@@ -47,6 +52,9 @@ func main() {
 		fmt.Println("Available product types:")
 		fmt.Println("  sp3 - Precise orbit files (.sp3)")
 		fmt.Println("  clk - Precise clock files (.clk)")
+		fmt.Println("  ionex - Global ionosphere maps (IONEX)")
+		fmt.Println("  erp - Earth rotation parameters (.erp)")
+		fmt.Println("  brdc - Daily combined broadcast ephemeris (.rnx/.n)")
 		return
 	}
 
@@ -71,14 +79,31 @@ func main() {
 
 	// Create client
 	client := igs.NewClient(outputDir)
+	if err := client.SetProxy(proxy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting proxy: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Map string to product type
 	var pt igs.ProductType
 	switch productType {
+	case "brdc":
+		fmt.Printf("Downloading broadcast ephemeris for %s...\n", t.Format("2006-01-02"))
+		filePath, err := client.DownloadBRDC(t, !gpsOnly)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error downloading broadcast ephemeris: %v\n", err)
+			os.Exit(1)
+		}
+		finish(filePath, decompress)
+		return
 	case "sp3":
 		pt = igs.ProductTypeSP3
 	case "clk":
 		pt = igs.ProductTypeCLK
+	case "ionex":
+		pt = igs.ProductTypeIONEX
+	case "erp":
+		pt = igs.ProductTypeERP
 	default:
 		fmt.Fprintf(os.Stderr, "Invalid product type: %s\n", productType)
 		os.Exit(1)
@@ -104,6 +129,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Map string to product latency
+	switch latency {
+	case "fin":
+		client.Latency = igs.LatencyFinal
+	case "rap":
+		client.Latency = igs.LatencyRapid
+	case "ult":
+		client.Latency = igs.LatencyUltraRapid
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid product latency: %s\n", latency)
+		os.Exit(1)
+	}
+
 	// Download the product
 	fmt.Printf("Downloading %s product from %s for %s...\n", pt, analysisCenter, t.Format("2006-01-02"))
 	filePath, err := client.DownloadProduct(t, pt, analysisCenter)
@@ -112,6 +150,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	finish(filePath, decompress)
+}
+
+// finish reports the downloaded file and decompresses it if requested
+func finish(filePath string, decompress bool) {
 	fmt.Printf("Downloaded to: %s\n", filePath)
 
 	// Decompress if requested
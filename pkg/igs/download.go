@@ -0,0 +1,265 @@
+package igs
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResumeAttempts is the number of attempts to complete a download, each
+// resuming from the end of the partial file
+const maxResumeAttempts = 3
+
+// ErrChecksum is returned (wrapped) when a downloaded file does not match
+// the checksum published by the server
+var ErrChecksum = errors.New("checksum mismatch")
+
+// checksumExts are the sidecar checksum files tried, in order
+var checksumExts = []struct {
+	ext  string
+	hash func() hash.Hash
+}{
+	{".sha256", sha256.New},
+	{".md5", md5.New},
+}
+
+// ProductRequest identifies a product to download
+type ProductRequest struct {
+	// Time is the time of the product
+	Time time.Time
+	// Type is the product type
+	Type ProductType
+	// AC is the analysis center
+	AC AnalysisCenter
+}
+
+// Result is the outcome of the download of a product
+type Result struct {
+	// Request is the product requested
+	Request ProductRequest
+	// Path is the local path of the file (empty on error)
+	Path string
+	// Skipped is true if the file was already present and not downloaded
+	Skipped bool
+	// Err is the download error, if any
+	Err error
+}
+
+// DownloadProducts downloads products in parallel with at most concurrency
+// downloads at a time
+// Like DownloadProduct, the long filename is tried first and the legacy
+// short filename on 404. A file already present in the download directory
+// is skipped if its size matches the size reported by the server. Downloads
+// are written to a .part file and an interrupted transfer is resumed with an
+// HTTP range request. If VerifyChecksum is set, the file is checked against
+// a .sha256 or .md5 sidecar file when the server provides one.
+// The results are in the order of reqs. A failed download does not stop the
+// others; the error returned reports the number of failures.
+func (c *Client) DownloadProducts(reqs []ProductRequest, concurrency int) ([]Result, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(reqs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = c.fetchProduct(reqs[j])
+			}
+		}()
+	}
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	nerr := 0
+	for i := range results {
+		if results[i].Err != nil {
+			nerr++
+		}
+	}
+	if nerr > 0 {
+		return results, fmt.Errorf("%d of %d downloads failed", nerr, len(reqs))
+	}
+	return results, nil
+}
+
+// fetchProduct downloads a product with the long filename or, on 404, the
+// legacy short filename
+func (c *Client) fetchProduct(req ProductRequest) Result {
+	week, _ := GPSWeekAndDay(req.Time)
+	dir := filepath.Join(c.DownloadDir, fmt.Sprintf("%04d", week))
+	res := Result{Request: req}
+
+	names := []string{
+		c.LongProductName(req.Time, req.Type, req.AC),
+		c.ShortProductName(req.Time, req.Type, req.AC),
+	}
+	for i, name := range names {
+		localPath := filepath.Join(dir, name)
+		skipped, err := c.downloadResumable(c.productURL(req.Time, req.Type, name), localPath)
+		if err == nil {
+			res.Path, res.Skipped = localPath, skipped
+			return res
+		}
+		if !errors.Is(err, ErrNotFound) || i == len(names)-1 {
+			res.Err = fmt.Errorf("failed to download %s: %w", name, err)
+			return res
+		}
+	}
+	return res
+}
+
+// downloadResumable downloads a file via a .part file, resuming it if
+// interrupted, unless a file of the same size is present
+func (c *Client) downloadResumable(url, localPath string) (bool, error) {
+	if fi, err := os.Stat(localPath); err == nil && c.validLocal(url, localPath, fi.Size()) {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	partPath := localPath + ".part"
+	var err error
+	for i := 0; i < maxResumeAttempts; i++ {
+		if err = c.downloadPart(url, partPath); err == nil || errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return false, fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	if c.VerifyChecksum {
+		if err := c.verifyChecksum(url, localPath); err != nil {
+			os.Remove(localPath)
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// downloadPart downloads a file to a .part file, requesting the remainder
+// if the .part file exists
+func (c *Client) downloadPart(url, partPath string) error {
+	var offset int64
+	if fi, err := os.Stat(partPath); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flag |= os.O_APPEND
+	case http.StatusOK:
+		flag |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The .part file is already complete
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("bad status: %s: %w", resp.Status, ErrNotFound)
+	default:
+		return fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	out, err := os.OpenFile(partPath, flag, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// validLocal tests whether a local file matches the size reported by the
+// server and, if VerifyChecksum is set, the published checksum
+func (c *Client) validLocal(url, localPath string, size int64) bool {
+	resp, err := c.HTTPClient.Head(url)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || (resp.ContentLength >= 0 && resp.ContentLength != size) {
+		return false
+	}
+	return !c.VerifyChecksum || c.verifyChecksum(url, localPath) == nil
+}
+
+// verifyChecksum checks a file against the first sidecar checksum file
+// found on the server; it succeeds if the server provides none
+func (c *Client) verifyChecksum(url, localPath string) error {
+	for _, ck := range checksumExts {
+		resp, err := c.HTTPClient.Get(url + ck.ext)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read checksum: %w", err)
+		}
+
+		// Format: {hex digest}[  {filename}]
+		fields := strings.Fields(string(body))
+		if len(fields) == 0 {
+			return fmt.Errorf("empty checksum file: %s", url+ck.ext)
+		}
+		f, err := os.Open(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		h := ck.hash()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, fields[0]) {
+			return fmt.Errorf("%s: %s: %w", filepath.Base(localPath), ck.ext[1:], ErrChecksum)
+		}
+		return nil
+	}
+	return nil
+}
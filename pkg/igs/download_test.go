@@ -0,0 +1,170 @@
+package igs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockArchive is a mock product server serving files with range requests
+type mockArchive struct {
+	mu       sync.Mutex
+	files    map[string]string // path -> content
+	truncate map[string]bool   // paths whose first full GET is cut in half
+	requests []string          // "{method} {path} {range}"
+}
+
+func (m *mockArchive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.requests = append(m.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+r.Header.Get("Range")))
+	content, ok := m.files[r.URL.Path]
+	cut := r.Method == http.MethodGet && r.Header.Get("Range") == "" && m.truncate[r.URL.Path]
+	if cut {
+		delete(m.truncate, r.URL.Path)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if cut {
+		// Declare the full length and close the connection half way
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content[:len(content)/2]))
+		return
+	}
+	http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader([]byte(content)))
+}
+
+func (m *mockArchive) set(path, content string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path] = content
+}
+
+func (m *mockArchive) count(prefix string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, r := range m.requests {
+		if strings.HasPrefix(r, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func newMockClient(t *testing.T, m *mockArchive) *Client {
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+	client := NewClient(t.TempDir())
+	client.SetBaseURL(server.URL)
+	client.HTTPClient = server.Client()
+	return client
+}
+
+func TestDownloadProducts(t *testing.T) {
+	sp3 := strings.Repeat("* 2023  5 15  0  0  0.00000000\n", 100)
+	clk := strings.Repeat("AS G01  2023 05 15 00 00  0.000000  1   1.0E-04\n", 100)
+	m := &mockArchive{
+		files: map[string]string{
+			"/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz": sp3,
+			"/2262/igs22621.clk.Z":                            clk,
+		},
+		truncate: map[string]bool{"/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz": true},
+	}
+	client := newMockClient(t, m)
+	testTime := time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	reqs := []ProductRequest{
+		{Time: testTime, Type: ProductTypeSP3, AC: AnalysisCenterIGS},
+		{Time: testTime, Type: ProductTypeCLK, AC: AnalysisCenterIGS},
+		{Time: testTime, Type: ProductTypeSP3, AC: AnalysisCenterJPL},
+	}
+	results, err := client.DownloadProducts(reqs, 2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 downloads failed")
+	assert.Len(t, results, 3)
+
+	// Truncated long name file resumed with a range request
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, filepath.Join(client.DownloadDir, "2262", "IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"), results[0].Path)
+	content, err := os.ReadFile(results[0].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, sp3, string(content))
+	assert.Equal(t, 1, m.count("GET /2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz bytes="+strconv.Itoa(len(sp3)/2)+"-"))
+	_, err = os.Stat(results[0].Path + ".part")
+	assert.True(t, os.IsNotExist(err))
+
+	// Legacy short name fallback
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, filepath.Join(client.DownloadDir, "2262", "igs22621.clk.Z"), results[1].Path)
+	content, err = os.ReadFile(results[1].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, clk, string(content))
+
+	// Missing product reported without stopping the others
+	assert.ErrorIs(t, results[2].Err, ErrNotFound)
+	assert.Empty(t, results[2].Path)
+	assert.Equal(t, reqs[2], results[2].Request)
+
+	// Files present with the right size are skipped
+	m.set("/2262/igs22621.clk.Z", clk+"updated\n")
+	gets := m.count("GET ")
+	results, err = client.DownloadProducts(reqs[:2], 2)
+	assert.NoError(t, err)
+	assert.True(t, results[0].Skipped)
+	assert.False(t, results[1].Skipped)
+	assert.Equal(t, gets+2, m.count("GET ")) // long name 404 and changed clock file
+	content, err = os.ReadFile(results[1].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, clk+"updated\n", string(content))
+}
+
+func TestDownloadProducts_Checksum(t *testing.T) {
+	sp3 := "#dP2023  5 15  0  0  0.00000000\n"
+	sum := sha256.Sum256([]byte(sp3))
+	long := "/2262/IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"
+	m := &mockArchive{files: map[string]string{
+		long:             sp3,
+		long + ".sha256": hex.EncodeToString(sum[:]) + "  IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz\n",
+	}}
+	client := newMockClient(t, m)
+	client.VerifyChecksum = true
+	reqs := []ProductRequest{{Time: time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC), Type: ProductTypeSP3, AC: AnalysisCenterIGS}}
+
+	results, err := client.DownloadProducts(reqs, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+
+	// Corrupted file of the same size is downloaded again
+	assert.NoError(t, os.WriteFile(results[0].Path, []byte(strings.ToUpper(sp3)), 0644))
+	results, err = client.DownloadProducts(reqs, 1)
+	assert.NoError(t, err)
+	assert.False(t, results[0].Skipped)
+	content, err := os.ReadFile(results[0].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, sp3, string(content))
+
+	// Checksum mismatch
+	assert.NoError(t, os.Remove(results[0].Path))
+	m.set(long+".sha256", strings.Repeat("0", 64))
+	results, err = client.DownloadProducts(reqs, 1)
+	assert.Error(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrChecksum)
+	_, err = os.Stat(filepath.Join(client.DownloadDir, "2262", "IGS0OPSFIN_20231350000_01D_15M_ORB.SP3.gz"))
+	assert.True(t, os.IsNotExist(err))
+}
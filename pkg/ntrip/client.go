@@ -1,13 +1,35 @@
 package ntrip
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/bramburn/gnssgo/pkg/gnssgo/nmea"
+	"github.com/bramburn/gnssgo/pkg/gnssgo/stream"
 )
 
+// ErrNoFix is returned by SendGGA for a GGA sentence without a position fix
+var ErrNoFix = errors.New("GGA has no position fix")
+
+// proxyLock serializes setting the proxy of the streams and opening a stream,
+// as the proxy of the gnssgo streams is global
+var proxyLock sync.Mutex
+
+// clientStream is the stream used by a Client, a *gnssgo.Stream except in tests
+type clientStream interface {
+	InitStream()
+	OpenStream(ctype, mode int, path string) int
+	StreamRead(buff []byte, n int) int
+	StreamWrite(buff []byte, n int) int
+	StreamClose()
+	StreamStat(msg *string) int
+}
+
 // Client represents an NTRIP client
 type Client struct {
 	server     string
@@ -15,9 +37,13 @@ type Client struct {
 	username   string
 	password   string
 	mountpoint string
-	stream     gnssgo.Stream
+	proxy      string
+	stream     clientStream
 	mutex      sync.Mutex
 	connected  bool
+
+	ggaStop chan struct{} // Closed to stop the GGA reporter
+	ggaDone chan struct{} // Closed when the GGA reporter has stopped
 }
 
 // NewClient creates a new NTRIP client
@@ -28,9 +54,26 @@ func NewClient(server, port, username, password, mountpoint string) (*Client, er
 		username:   username,
 		password:   password,
 		mountpoint: mountpoint,
+		stream:     new(gnssgo.Stream),
 	}, nil
 }
 
+// SetProxy sets the proxy used to connect to the caster, an http://,
+// https:// or socks5:// URL or a host:port of an HTTP proxy. With "" (the
+// default) the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables. The credentials of the caster are sent through the
+// proxy. The proxy applies from the next Connect.
+func (c *Client) SetProxy(proxyURL string) error {
+	if _, err := stream.ProxyFunc(proxyURL); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.proxy = proxyURL
+	return nil
+}
+
 // Connect connects to the NTRIP server
 func (c *Client) Connect() error {
 	c.mutex.Lock()
@@ -47,20 +90,31 @@ func (c *Client) Connect() error {
 	ntripPath := fmt.Sprintf("%s:%s@%s:%s/%s",
 		c.username, c.password, c.server, c.port, c.mountpoint)
 
-	// Open the NTRIP client stream
+	// Open the NTRIP client stream, through the proxy if set
+	proxyLock.Lock()
+	if c.proxy != "" {
+		stream.StrSetProxy(c.proxy)
+	}
 	result := c.stream.OpenStream(gnssgo.STR_NTRIPCLI, gnssgo.STR_MODE_R, ntripPath)
+	if c.proxy != "" {
+		stream.StrSetProxy("")
+	}
+	proxyLock.Unlock()
 
 	// Check if the stream was opened successfully
-	if result <= 0 || c.stream.State <= 0 {
-		return fmt.Errorf("failed to connect to NTRIP server: %s", c.stream.Msg)
+	var msg string
+	if state := c.stream.StreamStat(&msg); result <= 0 || state <= 0 {
+		return fmt.Errorf("failed to connect to NTRIP server: %s", msg)
 	}
 
 	c.connected = true
 	return nil
 }
 
-// Disconnect disconnects from the NTRIP server
+// Disconnect disconnects from the NTRIP server and stops the GGA reporter
 func (c *Client) Disconnect() error {
+	c.StopGGAReporter()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
@@ -97,9 +151,10 @@ func (c *Client) IsConnected() bool {
 	return c.connected
 }
 
-// GetStream returns the underlying stream
+// GetStream returns the underlying stream (nil if not a *gnssgo.Stream)
 func (c *Client) GetStream() *gnssgo.Stream {
-	return &c.stream
+	stream, _ := c.stream.(*gnssgo.Stream)
+	return stream
 }
 
 // Write writes data to the NTRIP server
@@ -118,3 +173,71 @@ func (c *Client) Write(p []byte) (int, error) {
 
 	return n, nil
 }
+
+// SendGGA sends a GGA sentence with the rover position to the caster, as
+// required by VRS and network RTK mountpoints. The checksum is validated and a
+// sentence without a fix (quality 0) is not sent and returns ErrNoFix.
+func (c *Client) SendGGA(gga string) error {
+	gga = strings.TrimSpace(gga)
+	if !nmea.ValidateChecksum(gga) {
+		return fmt.Errorf("invalid GGA checksum: %s", gga)
+	}
+	data, err := nmea.ParseGGA(gga)
+	if err != nil {
+		return fmt.Errorf("invalid GGA sentence: %w", err)
+	}
+	if data.Quality == 0 {
+		return ErrNoFix
+	}
+
+	_, err = c.Write([]byte(gga + "\r\n"))
+	return err
+}
+
+// StartGGAReporter sends the GGA sentence returned by fn to the caster now and
+// then every interval until StopGGAReporter or Disconnect. An empty sentence,
+// one without a fix or a failed send is skipped until the next interval.
+func (c *Client) StartGGAReporter(interval time.Duration, fn func() string) error {
+	if interval <= 0 || fn == nil {
+		return fmt.Errorf("invalid GGA reporter interval or position function")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.ggaStop != nil {
+		return fmt.Errorf("GGA reporter already running")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	c.ggaStop, c.ggaDone = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if gga := fn(); gga != "" {
+				c.SendGGA(gga)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// StopGGAReporter stops the GGA reporter, if running
+func (c *Client) StopGGAReporter() {
+	c.mutex.Lock()
+	stop, done := c.ggaStop, c.ggaDone
+	c.ggaStop, c.ggaDone = nil, nil
+	c.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
@@ -1,12 +1,19 @@
 package ntrip
 
 import (
-	"errors"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/bramburn/gnssgo/pkg/gnssgo/nmea"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockStream is a mock implementation of the gnssgo.Stream type
@@ -40,10 +47,20 @@ func (m *MockStream) StreamRead(buff []byte, n int) int {
 	return args.Int(0)
 }
 
+func (m *MockStream) StreamWrite(buff []byte, n int) int {
+	args := m.Called(buff, n)
+	return args.Int(0)
+}
+
 func (m *MockStream) StreamClose() {
 	m.Called()
 }
 
+func (m *MockStream) StreamStat(msg *string) int {
+	*msg = m.Msg
+	return m.State
+}
+
 // TestNewClient tests the NewClient function
 func TestNewClient(t *testing.T) {
 	// Test with valid parameters
@@ -177,3 +194,120 @@ func TestClientIsConnected(t *testing.T) {
 	client.connected = true
 	assert.True(t, client.IsConnected())
 }
+
+// TestClientSendGGA tests the GGA validation of SendGGA
+func TestClientSendGGA(t *testing.T) {
+	client, _ := NewClient("example.com", "2101", "user", "pass", "MOUNT")
+	mockStream := new(MockStream)
+	client.stream = mockStream
+	client.connected = true
+
+	gga := "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+	mockStream.On("StreamWrite", []byte(gga+"\r\n"), len(gga)+2).Return(len(gga) + 2)
+	assert.NoError(t, client.SendGGA(gga))
+
+	// Bad checksum, no checksum, not a GGA sentence and no fix are not sent
+	assert.Error(t, client.SendGGA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48"))
+	assert.Error(t, client.SendGGA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	assert.Error(t, client.SendGGA("$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"))
+	assert.ErrorIs(t, client.SendGGA(nmea.GenerateNMEASentence("GPGGA", []string{"123519", "", "", "", "", "0", "00", "", "", "", "", "", "", ""})), ErrNoFix)
+	mockStream.AssertNumberOfCalls(t, "StreamWrite", 1)
+}
+
+// TestClientGGAReporter tests that the GGA reporter sends the position to a
+// stub caster at the configured interval
+func TestClientGGAReporter(t *testing.T) {
+	bodies := make(chan string, 100)
+	times := make(chan time.Time, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			times <- time.Now()
+			bodies <- string(body)
+			return
+		}
+		// Correction stream, kept open until the client disconnects
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	client, _ := NewClient(host, port, "user", "pass", "VRS")
+	require.NoError(t, client.Connect())
+	defer client.Disconnect()
+
+	fix := "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+	nofix := nmea.GenerateNMEASentence("GPGGA", []string{"123520", "", "", "", "", "0", "00", "", "", "", "", "", "", ""})
+	var calls atomic.Int32
+	interval := 200 * time.Millisecond
+	require.NoError(t, client.StartGGAReporter(interval, func() string {
+		// The second position has no fix and is skipped
+		if calls.Add(1) == 2 {
+			return nofix
+		}
+		return fix
+	}))
+	assert.Error(t, client.StartGGAReporter(interval, func() string { return fix }))
+
+	var sent []time.Time
+	for len(sent) < 3 {
+		select {
+		case body := <-bodies:
+			assert.Equal(t, fix+"\r\n", body)
+			sent = append(sent, <-times)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d GGA sentences received, 3 expected", len(sent))
+		}
+	}
+	client.StopGGAReporter()
+
+	// Sent at start, the second interval is skipped without fix
+	assert.InDelta(t, 2*interval, sent[1].Sub(sent[0]), float64(interval)/2)
+	assert.InDelta(t, interval, sent[2].Sub(sent[1]), float64(interval)/2)
+	assert.GreaterOrEqual(t, calls.Load(), int32(4))
+}
+
+// TestClientProxy tests that the client connects to the caster through the
+// proxy set, with the basic authentication of the caster
+func TestClientProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL of the caster
+		if r.URL.Host != "caster.invalid:2101" || r.URL.Path != "/MOUNT" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if user, passwd, ok := r.BasicAuth(); !ok || user != "user" || passwd != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxied.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("RTCM data"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("caster.invalid", "2101", "user", "pass", "MOUNT")
+	require.NoError(t, err)
+	assert.Error(t, client.SetProxy("ftp://proxy:21"))
+	require.NoError(t, client.SetProxy(proxy.URL))
+
+	require.NoError(t, client.Connect())
+	defer client.Disconnect()
+	assert.Equal(t, int32(1), proxied.Load())
+
+	buff := make([]byte, 64)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if n, err := client.Read(buff); err == nil {
+			assert.Equal(t, "RTCM data", string(buff[:n]))
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("no data received through the proxy")
+}
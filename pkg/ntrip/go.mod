@@ -1,10 +1,14 @@
 module github.com/bramburn/gnssgo/pkg/ntrip
 
-go 1.21
+go 1.23
 
 require (
+	github.com/bramburn/gnssgo/pkg/caster v0.0.0
 	github.com/bramburn/gnssgo/pkg/gnssgo v0.0.0
 	github.com/stretchr/testify v1.10.0
 )
 
-replace github.com/bramburn/gnssgo/pkg/gnssgo => ../gnssgo
+replace (
+	github.com/bramburn/gnssgo/pkg/caster => ../caster
+	github.com/bramburn/gnssgo/pkg/gnssgo => ../gnssgo
+)
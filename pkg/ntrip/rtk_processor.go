@@ -2,43 +2,100 @@ package ntrip
 
 import (
 	"fmt"
+	"io"
 	"math"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/bramburn/gnssgo/pkg/gnssgo"
 )
 
+// DefaultStatsWindow is the time window of the windowed metrics of GetStats
+const DefaultStatsWindow = time.Minute
+
+// MaxStatsWindow is the longest time window of the windowed metrics, older
+// solutions are discarded
+const MaxStatsWindow = time.Hour
+
 // RTKStats contains statistics about the RTK processing
 type RTKStats struct {
 	RoverObs  int     // Number of rover observations
 	BaseObs   int     // Number of base observations
 	Solutions int     // Number of solutions
 	FixRatio  float64 // Ratio of fixed solutions
+
+	// Metrics over the solutions of the last Window
+	Window          time.Duration // Time window
+	WindowSolutions int           // Number of solutions in the window
+	FixPercent      float64       // Fixed solutions (%)
+	FloatPercent    float64       // Float solutions (%)
+	SinglePercent   float64       // Single, DGPS and other solutions (%)
+	NonePercent     float64       // Solutions without position (%)
+	MeanRatio       float64       // Mean ambiguity ratio factor of solutions reporting one
+	MeanSats        float64       // Mean number of satellites of solutions with a position
+}
+
+// solutionRecord is a solution kept for the windowed metrics
+type solutionRecord struct {
+	time  time.Time
+	stat  int
+	ratio float64
+	ns    int
 }
 
 // RTKSolution represents an RTK solution
 type RTKSolution struct {
-	Stat int        // Solution status (SOLQ_NONE, SOLQ_SINGLE, SOLQ_FLOAT, SOLQ_FIX)
-	Pos  [3]float64 // Position (0:lat, 1:lon, 2:height)
-	Ns   uint8      // Number of valid satellites
-	Age  float32    // Age of differential (s)
+	Stat     int        // Solution status (SOLQ_NONE, SOLQ_SINGLE, SOLQ_FLOAT, SOLQ_FIX)
+	Pos      [3]float64 // Position (0:lat, 1:lon, 2:height), smoothed if enabled
+	RawPos   [3]float64 // Position before smoothing
+	Baseline [3]float64 // Rover position relative to the base station (east, north, up) (m)
+	Ns       uint8      // Number of valid satellites
+	Age      float32    // Age of differential (s)
+	Ratio    float32    // Ambiguity validation ratio factor
 }
 
-// RTKProcessor processes GNSS data using RTK
+// feedCycle is the cycle of reading the rover and base data
+const feedCycle = 10 * time.Millisecond
+
+// RTKProcessor processes GNSS data using RTK. The rover observations of the
+// receiver and the RTCM 3 corrections of the client (base observations
+// 1004/1074/1077..., base position 1005/1006 and ephemerides) are fed to an
+// RTK server solving each rover epoch by gnssgo.Rtk.RtkPos.
 type RTKProcessor struct {
-	receiver  *GNSSReceiver
-	client    *Client
-	svr       gnssgo.RtkSvr
-	mutex     sync.Mutex
-	running   bool
-	solutions int
-	fixCount  int
+	receiver    *GNSSReceiver
+	client      *Client
+	rover       io.Reader     // Rover data, the receiver except in tests
+	base        io.Reader     // Base station data, the client except in tests
+	roverFormat int           // Rover data format (STRFMT_???)
+	opt         gnssgo.PrcOpt // Processing options
+	svr         gnssgo.RtkSvr // RTK server
+	mutex       sync.Mutex
+	running     bool
+	stop        chan struct{} // Closed to stop feeding the RTK server
+	done        chan struct{} // Closed when feeding has stopped
+	solution    RTKSolution   // Last solution
+	solTime     gnssgo.Gtime  // Time of the last solution
+	solutions   int
+	fixCount    int
+	history     []solutionRecord  // Solutions of the last MaxStatsWindow
+	now         func() time.Time  // Clock for solution records
+	smoother    *PositionSmoother // Output position smoother (nil: off)
 }
 
-// NewRTKProcessor creates a new RTK processor
+// DefaultRTKOptions returns the default processing options of an RTK
+// processor: kinematic GPS + GLONASS positioning with a 15 degree elevation
+// mask and the base position of RTCM 1005/1006
+func DefaultRTKOptions() gnssgo.PrcOpt {
+	opt := gnssgo.DefaultProcOpt()
+	opt.Mode = gnssgo.PMODE_KINEMA               // Kinematic mode
+	opt.NavSys = gnssgo.SYS_GPS | gnssgo.SYS_GLO // GPS + GLONASS
+	opt.RefPos = gnssgo.POSOPT_RTCM              // Base position of RTCM 1005/1006
+	opt.Elmin = 15.0 * gnssgo.D2R                // Elevation mask (15 degrees)
+	return opt
+}
+
+// NewRTKProcessor creates a new RTK processor with DefaultRTKOptions. The
+// receiver outputs u-blox UBX raw observations unless set by SetRoverFormat.
 func NewRTKProcessor(receiver *GNSSReceiver, client *Client) (*RTKProcessor, error) {
 	if receiver == nil {
 		return nil, fmt.Errorf("receiver is nil")
@@ -48,11 +105,94 @@ func NewRTKProcessor(receiver *GNSSReceiver, client *Client) (*RTKProcessor, err
 	}
 
 	return &RTKProcessor{
-		receiver: receiver,
-		client:   client,
+		receiver:    receiver,
+		client:      client,
+		rover:       receiver,
+		base:        client,
+		roverFormat: gnssgo.STRFMT_UBX,
+		opt:         DefaultRTKOptions(),
+		now:         time.Now,
 	}, nil
 }
 
+// NewRTKProcessorWithOptions creates a new RTK processor with the processing
+// options opt (see SetOptions)
+func NewRTKProcessorWithOptions(receiver *GNSSReceiver, client *Client, opt *gnssgo.PrcOpt) (*RTKProcessor, error) {
+	p, err := NewRTKProcessor(receiver, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.SetOptions(opt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Options returns the processing options
+func (p *RTKProcessor) Options() gnssgo.PrcOpt {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.opt
+}
+
+// SetOptions validates and sets the processing options: positioning mode,
+// elevation mask, navigation systems, ... A running processor re-initializes
+// its RTK filter (gnssgo.Rtk.InitRtk) with them, so all fields take effect
+// from the next epoch but the float states and ambiguities are reset. The
+// base position is kept for RefPos POSOPT_RTCM and set to Rb otherwise.
+// InitRst only takes effect on Start.
+func (p *RTKProcessor) SetOptions(opt *gnssgo.PrcOpt) error {
+	if err := validateOptions(opt); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.opt = *opt
+	if !p.running {
+		return nil
+	}
+	p.svr.RtkSvrLock()
+	defer p.svr.RtkSvrUnlock()
+	rb := p.svr.RtkCtrl.Rb
+	p.svr.RtkCtrl.InitRtk(&p.opt)
+	switch p.opt.RefPos {
+	case gnssgo.POSOPT_RTCM:
+		p.svr.RtkCtrl.Rb = rb
+	case gnssgo.POSOPT_POS:
+		copy(p.svr.RtkCtrl.Rb[:3], p.opt.Rb[:])
+	}
+	return nil
+}
+
+// validateOptions checks the ranges of the processing options
+func validateOptions(opt *gnssgo.PrcOpt) error {
+	switch {
+	case opt == nil:
+		return fmt.Errorf("options are nil")
+	case opt.Mode < gnssgo.PMODE_SINGLE || opt.Mode > gnssgo.PMODE_PPP_FIXED:
+		return fmt.Errorf("invalid positioning mode %d", opt.Mode)
+	case opt.NavSys&gnssgo.SYS_ALL == 0 || opt.NavSys&^gnssgo.SYS_ALL != 0:
+		return fmt.Errorf("invalid navigation systems 0x%X", opt.NavSys)
+	case opt.Nf < 1 || opt.Nf > gnssgo.NFREQ:
+		return fmt.Errorf("invalid number of frequencies %d", opt.Nf)
+	case opt.Elmin < 0.0 || opt.Elmin >= math.Pi/2.0:
+		return fmt.Errorf("invalid elevation mask %.1f deg", opt.Elmin*gnssgo.R2D)
+	case opt.RefPos < gnssgo.POSOPT_POS || opt.RefPos > gnssgo.POSOPT_RTCM:
+		return fmt.Errorf("invalid base position option %d", opt.RefPos)
+	}
+	return nil
+}
+
+// SetRoverFormat sets the format of the receiver data (gnssgo.STRFMT_UBX,
+// gnssgo.STRFMT_RTCM3, ...). Call it before Start.
+func (p *RTKProcessor) SetRoverFormat(format int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.roverFormat = format
+}
+
 // Start starts the RTK processing
 func (p *RTKProcessor) Start() error {
 	p.mutex.Lock()
@@ -62,85 +202,37 @@ func (p *RTKProcessor) Start() error {
 		return fmt.Errorf("already running")
 	}
 
-	// Configure RTK processing options
-	var prcopt gnssgo.PrcOpt
-	prcopt.Mode = gnssgo.PMODE_KINEMA               // Kinematic mode
-	prcopt.NavSys = gnssgo.SYS_GPS | gnssgo.SYS_GLO // GPS + GLONASS
-	prcopt.RefPos = 1                               // Use average of single position
-	prcopt.Elmin = 15.0 * gnssgo.D2R                // Elevation mask (15 degrees)
-
 	// Configure solution options
-	var solopt [2]gnssgo.SolOpt
-	solopt[0].Posf = gnssgo.SOLF_LLH  // Latitude/Longitude/Height format
-	solopt[1].Posf = gnssgo.SOLF_NMEA // NMEA format
-
-	// The following code is commented out to avoid crashes in the simulation
-	// In a real implementation, these would be used to configure the RTK server
-
-	/*
-		// Configure stream types
-		strtype := []int{
-			gnssgo.STR_SERIAL,   // Rover input (physical GNSS receiver)
-			gnssgo.STR_NTRIPCLI, // Base station input (NTRIP)
-			gnssgo.STR_NONE,     // Ephemeris input
-			gnssgo.STR_FILE,     // Solution 1 output
-			gnssgo.STR_NONE,     // Solution 2 output
-			gnssgo.STR_NONE,     // Log rover
-			gnssgo.STR_NONE,     // Log base station
-			gnssgo.STR_NONE,     // Log ephemeris
-		}
-
-		// Configure stream paths
-		paths := []string{
-			p.receiver.port, // Rover input (physical GNSS receiver)
-			fmt.Sprintf("%s:%s@%s:%s/%s", p.client.username, p.client.password, p.client.server, p.client.port, p.client.mountpoint), // Base station input (NTRIP)
-			"",                 // Ephemeris input
-			"rtk_solution.pos", // Solution 1 output
-			"",                 // Solution 2 output
-			"",                 // Log rover
-			"",                 // Log base station
-			"",                 // Log ephemeris
-		}
-
-		// Configure stream formats
-		strfmt := []int{
-			gnssgo.STRFMT_UBX,   // Rover format (UBX)
-			gnssgo.STRFMT_RTCM3, // Base station format (RTCM3)
-			gnssgo.STRFMT_RINEX, // Ephemeris format
-			gnssgo.SOLF_LLH,     // Solution 1 format
-			gnssgo.SOLF_NMEA,    // Solution 2 format
-		}
-
-		// Start RTK server
-		var errmsg string
-		svrcycle := 10                        // Server cycle (ms)
-		buffsize := 32768                     // Buffer size (bytes)
-		navmsgsel := 0                        // Navigation message select
-		cmds := []string{"", "", ""}          // Commands for input streams
-		cmds_periodic := []string{"", "", ""} // Periodic commands
-		rcvopts := []string{"", "", ""}       // Receiver options
-		nmeacycle := 1000                     // NMEA request cycle (ms)
-		nmeareq := 0                          // NMEA request type
-		nmeapos := []float64{0, 0, 0}         // NMEA position
-	*/
-
-	// In a real implementation, we would start the RTK server
-	// For now, we'll just simulate it to avoid crashes
-	/*
-		if p.svr.RtkSvrStart(svrcycle, buffsize, strtype, paths, strfmt, navmsgsel,
-			cmds, cmds_periodic, rcvopts, nmeacycle, nmeareq, nmeapos, &prcopt,
-			solopt[:], nil, &errmsg) == 0 {
-			return fmt.Errorf("failed to start RTK server: %s", errmsg)
-		}
-	*/
+	solopt := []gnssgo.SolOpt{gnssgo.DefaultSolOpt(), gnssgo.DefaultSolOpt()}
+
+	// The data are fed by the processor, the server has no streams
+	strtype := make([]int, 8) // STR_NONE
+	paths := make([]string, 8)
+	strfmt := []int{
+		p.roverFormat,       // Rover format
+		gnssgo.STRFMT_RTCM3, // Base station format (RTCM3)
+		gnssgo.STRFMT_RTCM3, // Correction format
+	}
+	cmds := make([]string, 3)
+
+	// Start RTK server
+	var errmsg string
+	p.svr.InitRtkSvr()
+	if p.svr.RtkSvrStart(10, 32768, strtype, paths, strfmt, 0, cmds, cmds, cmds,
+		0, 0, []float64{0, 0, 0}, &p.opt, solopt, nil, &errmsg) == 0 {
+		return fmt.Errorf("failed to start RTK server: %s", errmsg)
+	}
 
-	// Just mark as running for simulation
 	p.running = true
+	p.solution = RTKSolution{Stat: gnssgo.SOLQ_NONE}
+	p.solTime = gnssgo.Gtime{}
 	p.solutions = 0
 	p.fixCount = 0
+	p.history = nil
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
 
-	// Start a goroutine to monitor solutions
-	go p.monitorSolutions()
+	// Start a goroutine to feed the data and collect the solutions
+	go p.feed(p.stop, p.done)
 
 	return nil
 }
@@ -148,184 +240,205 @@ func (p *RTKProcessor) Start() error {
 // Stop stops the RTK processing
 func (p *RTKProcessor) Stop() error {
 	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
 	if !p.running {
+		p.mutex.Unlock()
 		return nil
 	}
-
-	// In a real implementation, we would stop the RTK server
-	// For now, we'll just simulate it to avoid crashes
-	/*
-		// Stop the RTK server
-		cmds := []string{"", "", ""}
-		p.svr.RtkSvrStop(cmds)
-	*/
-
 	p.running = false
+	stop, done := p.stop, p.done
+	p.mutex.Unlock()
+
+	// Stop feeding, then the RTK server
+	close(stop)
+	<-done
+	p.svr.RtkSvrStop(make([]string, 3))
 	return nil
 }
 
-// GetStats returns statistics about the RTK processing
+// GetStats returns statistics about the RTK processing with the windowed
+// metrics of the last DefaultStatsWindow
 func (p *RTKProcessor) GetStats() RTKStats {
+	return p.StatsWindow(DefaultStatsWindow)
+}
+
+// StatsWindow returns statistics about the RTK processing with the windowed
+// metrics of the solutions of the last d, up to MaxStatsWindow
+func (p *RTKProcessor) StatsWindow(d time.Duration) RTKStats {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	// In the current implementation, we don't have access to the RtkSvrStat
-	// So we'll just return the stats we track ourselves
 	fixRatio := 0.0
 	if p.solutions > 0 {
 		fixRatio = float64(p.fixCount) / float64(p.solutions)
 	}
 
-	return RTKStats{
-		RoverObs:  0, // Not available in current implementation
-		BaseObs:   0, // Not available in current implementation
+	p.svr.RtkSvrLock()
+	roverObs, baseObs := p.svr.InputMsg[0][0], p.svr.InputMsg[1][0]
+	p.svr.RtkSvrUnlock()
+
+	stats := RTKStats{
+		RoverObs:  int(roverObs),
+		BaseObs:   int(baseObs),
 		Solutions: p.solutions,
 		FixRatio:  fixRatio,
+		Window:    d,
+	}
+
+	// Windowed metrics
+	var fix, float, none, nratio, npos int
+	var ratio, sats float64
+	start := p.now().Add(-d)
+	for _, rec := range p.history {
+		if !rec.time.After(start) {
+			continue
+		}
+		stats.WindowSolutions++
+		switch rec.stat {
+		case gnssgo.SOLQ_FIX:
+			fix++
+		case gnssgo.SOLQ_FLOAT:
+			float++
+		case gnssgo.SOLQ_NONE:
+			none++
+		}
+		if rec.ratio > 0.0 {
+			ratio += rec.ratio
+			nratio++
+		}
+		if rec.stat != gnssgo.SOLQ_NONE {
+			sats += float64(rec.ns)
+			npos++
+		}
+	}
+	if n := float64(stats.WindowSolutions); n > 0 {
+		stats.FixPercent = 100.0 * float64(fix) / n
+		stats.FloatPercent = 100.0 * float64(float) / n
+		stats.NonePercent = 100.0 * float64(none) / n
+		stats.SinglePercent = 100.0 - stats.FixPercent - stats.FloatPercent - stats.NonePercent
+	}
+	if nratio > 0 {
+		stats.MeanRatio = ratio / float64(nratio)
 	}
+	if npos > 0 {
+		stats.MeanSats = sats / float64(npos)
+	}
+	return stats
 }
 
-// GetSolution returns the current RTK solution
-func (p *RTKProcessor) GetSolution() RTKSolution {
+// recordSolution counts a solution and keeps it for the windowed metrics.
+// The caller must hold the mutex.
+func (p *RTKProcessor) recordSolution(stat int, ratio float64, ns int) {
+	now := p.now()
+	p.solutions++
+	if stat == gnssgo.SOLQ_FIX {
+		p.fixCount++
+	}
+	p.history = append(p.history, solutionRecord{time: now, stat: stat, ratio: ratio, ns: ns})
+
+	// Discard solutions older than the longest window
+	i := 0
+	for i < len(p.history) && now.Sub(p.history[i].time) > MaxStatsWindow {
+		i++
+	}
+	p.history = p.history[i:]
+}
+
+// SetSmoothing enables the smoothing of the output positions with an
+// alpha-beta filter (see PositionSmoother), alpha <= 0 disables it. The RTK
+// processing is not affected.
+func (p *RTKProcessor) SetSmoothing(alpha, beta float64) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	// Get the current solution from the RTK server
-	var sol RTKSolution
-	if p.running {
-		// Try to get actual data from the GNSS receiver
-		buffer := make([]byte, 1024)
-		n, err := p.receiver.Read(buffer)
-
-		if err == nil && n > 0 {
-			// Process the GNSS data to extract position
-			// Look for GGA sentences in the data
-			data := string(buffer[:n])
-			fmt.Println("Raw GNSS data:", data) // Debug print
-			lines := strings.Split(data, "\r\n")
-
-			for _, line := range lines {
-				if strings.HasPrefix(line, "$") && strings.Contains(line, "GGA") {
-					// Parse GGA sentence
-					fields := strings.Split(line, ",")
-					if len(fields) >= 15 {
-						// Extract fix quality
-						quality := 0
-						if fields[6] != "" {
-							quality, _ = strconv.Atoi(fields[6])
-						}
-
-						// Set solution status based on fix quality
-						switch quality {
-						case 0:
-							sol.Stat = gnssgo.SOLQ_NONE
-						case 1:
-							sol.Stat = gnssgo.SOLQ_SINGLE
-						case 2:
-							sol.Stat = gnssgo.SOLQ_DGPS
-						case 4:
-							sol.Stat = gnssgo.SOLQ_FIX
-						case 5:
-							sol.Stat = gnssgo.SOLQ_FLOAT
-						default:
-							sol.Stat = gnssgo.SOLQ_NONE
-						}
-
-						// Extract position
-						if fields[2] != "" && fields[4] != "" {
-							// Parse latitude
-							lat, _ := strconv.ParseFloat(fields[2], 64)
-							latDir := fields[3]
-							if latDir == "S" {
-								lat = -lat
-							}
-
-							// Parse longitude
-							lon, _ := strconv.ParseFloat(fields[4], 64)
-							lonDir := fields[5]
-							if lonDir == "W" {
-								lon = -lon
-							}
-
-							// Convert NMEA format (DDMM.MMMM) to decimal degrees
-							latDeg := math.Floor(lat / 100.0)
-							latMin := lat - latDeg*100.0
-							sol.Pos[0] = latDeg + latMin/60.0
-
-							lonDeg := math.Floor(lon / 100.0)
-							lonMin := lon - lonDeg*100.0
-							sol.Pos[1] = lonDeg + lonMin/60.0
-
-							// Parse altitude
-							if fields[9] != "" {
-								alt, _ := strconv.ParseFloat(fields[9], 64)
-								sol.Pos[2] = alt
-							}
-						}
-
-						// Extract number of satellites
-						if fields[7] != "" {
-							sats, _ := strconv.Atoi(fields[7])
-							sol.Ns = uint8(sats)
-						}
-
-						// Extract age of differential
-						if fields[13] != "" {
-							age, _ := strconv.ParseFloat(fields[13], 32)
-							sol.Age = float32(age)
-						}
-
-						// Found a valid GGA sentence, no need to continue
-						break
-					}
-				}
-			}
+	p.smoother = nil
+	if alpha > 0.0 {
+		p.smoother = NewPositionSmoother(alpha, beta)
+	}
+}
 
-			// If we couldn't parse any GGA sentences, just return a solution with NONE status
-			if sol.Stat == 0 {
-				sol.Stat = gnssgo.SOLQ_NONE
-				// Keep position values as 0 to indicate no valid position
-				sol.Pos[0] = 0.0 // Latitude
-				sol.Pos[1] = 0.0 // Longitude
-				sol.Pos[2] = 0.0 // Height
-				sol.Ns = 0       // No satellites
-				sol.Age = 0.0    // No age
-			}
-		} else {
-			// If we can't read from the receiver, return a default solution
-			sol.Stat = gnssgo.SOLQ_NONE
-		}
-	} else {
-		// If not running, return a solution with NONE status
-		sol.Stat = gnssgo.SOLQ_NONE
+// smoothSolution keeps the position of sol in RawPos and smooths Pos.
+// Solutions without position neither use nor update the smoother. The caller
+// must hold the mutex.
+func (p *RTKProcessor) smoothSolution(sol *RTKSolution) {
+	sol.RawPos = sol.Pos
+	if p.smoother == nil || sol.Stat == gnssgo.SOLQ_NONE {
+		return
 	}
+	sol.Pos = p.smoother.Smooth(sol.Pos)
+}
+
+// GetSolution returns the last RTK solution
+func (p *RTKProcessor) GetSolution() RTKSolution {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 
-	return sol
+	if !p.running {
+		return RTKSolution{Stat: gnssgo.SOLQ_NONE}
+	}
+	return p.solution
 }
 
-// monitorSolutions monitors the solutions produced by the RTK server
-func (p *RTKProcessor) monitorSolutions() {
-	ticker := time.NewTicker(1 * time.Second)
+// feed reads the rover and base data and feeds them to the RTK server until
+// stop is closed, collecting the solution of each rover epoch
+func (p *RTKProcessor) feed(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(feedCycle)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		p.mutex.Lock()
-		if !p.running {
-			p.mutex.Unlock()
+	buff := make([]byte, 4096)
+	for {
+		select {
+		case <-stop:
 			return
+		case <-ticker.C:
 		}
 
-		// In the current implementation, we don't have access to the RtkSvrStat
-		// So we'll just increment the solution count periodically
-		// This is a placeholder for actual solution monitoring
-		p.solutions++
-
-		// Simulate some fixed solutions (about 80% of the time)
-		if p.solutions%5 != 0 {
-			p.fixCount++
+		// The sources return an error while no data are available
+		if n, err := p.rover.Read(buff); err == nil && n > 0 {
+			p.svr.FeedRover(buff[:n])
+		}
+		if n, err := p.base.Read(buff); err == nil && n > 0 {
+			p.svr.FeedBase(buff[:n])
 		}
 
+		p.svr.RtkSvrLock()
+		sol, rb := p.svr.RtkCtrl.RtkSol, p.svr.RtkCtrl.Rb
+		p.svr.RtkSvrUnlock()
+
+		p.mutex.Lock()
+		if sol.Time.Time != 0 && gnssgo.TimeDiff(sol.Time, p.solTime) != 0.0 {
+			p.solTime = sol.Time
+			p.setSolution(&sol, rb[:3])
+		}
 		p.mutex.Unlock()
 	}
 }
+
+// setSolution converts a solution of the RTK server to the last solution and
+// records it. The caller must hold the mutex.
+func (p *RTKProcessor) setSolution(sol *gnssgo.Sol, rb []float64) {
+	s := RTKSolution{
+		Stat:  int(sol.Stat),
+		Ns:    sol.Ns,
+		Age:   sol.Age,
+		Ratio: sol.Ratio,
+	}
+	if sol.Stat != gnssgo.SOLQ_NONE {
+		var pos [3]float64
+		gnssgo.Ecef2Pos(sol.Rr[:3], pos[:])
+		s.Pos = [3]float64{pos[0] * gnssgo.R2D, pos[1] * gnssgo.R2D, pos[2]}
+
+		if gnssgo.Norm(rb, 3) > 0.0 {
+			var posb, dr [3]float64
+			for i := 0; i < 3; i++ {
+				dr[i] = sol.Rr[i] - rb[i]
+			}
+			gnssgo.Ecef2Pos(rb, posb[:])
+			gnssgo.Ecef2Enu(posb[:], dr[:], s.Baseline[:])
+		}
+	}
+	p.smoothSolution(&s)
+	p.solution = s
+	p.recordSolution(s.Stat, float64(s.Ratio), int(s.Ns))
+}
@@ -0,0 +1,294 @@
+package ntrip
+
+import (
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRTKProcessorStatsWindow tests the windowed solution metrics
+func TestRTKProcessorStatsWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	p := &RTKProcessor{now: func() time.Time { return now }}
+
+	// Two minutes of single solutions, then one minute of 30 fix, 20 float,
+	// 6 single and 4 none solutions
+	for i := 0; i < 120; i++ {
+		p.recordSolution(gnssgo.SOLQ_SINGLE, 0.0, 6)
+		now = now.Add(time.Second)
+	}
+	for i := 0; i < 60; i++ {
+		switch {
+		case i < 30:
+			p.recordSolution(gnssgo.SOLQ_FIX, 10.0, 12)
+		case i < 50:
+			p.recordSolution(gnssgo.SOLQ_FLOAT, 2.0, 10)
+		case i < 56:
+			p.recordSolution(gnssgo.SOLQ_SINGLE, 0.0, 7)
+		default:
+			p.recordSolution(gnssgo.SOLQ_NONE, 0.0, 0)
+		}
+		if i < 59 {
+			now = now.Add(time.Second)
+		}
+	}
+
+	stats := p.GetStats()
+	assert.Equal(t, 180, stats.Solutions)
+	assert.InDelta(t, 30.0/180.0, stats.FixRatio, 1e-9)
+	assert.Equal(t, DefaultStatsWindow, stats.Window)
+	assert.Equal(t, 60, stats.WindowSolutions)
+	assert.InDelta(t, 50.0, stats.FixPercent, 1e-9)
+	assert.InDelta(t, 100.0/3.0, stats.FloatPercent, 1e-9)
+	assert.InDelta(t, 10.0, stats.SinglePercent, 1e-9)
+	assert.InDelta(t, 100.0/15.0, stats.NonePercent, 1e-9)
+	assert.InDelta(t, (30*10.0+20*2.0)/50.0, stats.MeanRatio, 1e-9)
+	assert.InDelta(t, (30*12.0+20*10.0+6*7.0)/56.0, stats.MeanSats, 1e-9)
+
+	// A longer window includes the single solutions
+	stats = p.StatsWindow(2 * time.Minute)
+	assert.Equal(t, 120, stats.WindowSolutions)
+	assert.InDelta(t, 25.0, stats.FixPercent, 1e-9)
+	assert.InDelta(t, 55.0, stats.SinglePercent, 1e-9)
+
+	// No solutions in the window
+	now = now.Add(2 * time.Minute)
+	stats = p.StatsWindow(time.Minute)
+	assert.Equal(t, 0, stats.WindowSolutions)
+	assert.Equal(t, 0.0, stats.FixPercent)
+	assert.Equal(t, 0.0, stats.MeanRatio)
+
+	// Solutions older than the longest window are discarded
+	now = now.Add(MaxStatsWindow)
+	p.recordSolution(gnssgo.SOLQ_FIX, 5.0, 9)
+	assert.Len(t, p.history, 1)
+	assert.Equal(t, 181, p.GetStats().Solutions)
+}
+
+// queueReader is a data source returning the queued data and io.EOF while
+// empty, like Client and GNSSReceiver
+type queueReader struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+func (r *queueReader) Write(data []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.data = append(r.data, data...)
+}
+
+func (r *queueReader) Read(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// synthRTCM simulates a base station and a rover observing a GPS
+// constellation: it returns the RTCM 3 ephemerides (1019) and base position
+// (1005) and the base and rover observations (1077) of the epochs.
+func synthRTCM(t *testing.T, t0 gnssgo.Gtime, base, rover [3]float64, epochs int) ([]byte, [][]byte, [][]byte) {
+	var (
+		encb, encr gnssgo.Rtcm
+		ephs       []gnssgo.Eph
+		corr       []byte
+		baseObs    [][]byte
+		roverObs   [][]byte
+	)
+	gen := func(enc *gnssgo.Rtcm, ctype int) []byte {
+		require.NotZero(t, enc.GenRtcm3(ctype, 0, 0), "encode rtcm3 %d", ctype)
+		return append([]byte(nil), enc.Buff[:enc.Nbyte]...)
+	}
+	encb.InitRtcm()
+	encr.InitRtcm()
+
+	week := 0
+	toes := gnssgo.Time2GpsT(t0, &week)
+	for k := 0; k < 24; k++ {
+		eph := gnssgo.Eph{
+			Sat:  gnssgo.SatNo(gnssgo.SYS_GPS, k+1),
+			Iode: k + 1, Iodc: k + 1,
+			Week: week,
+			Toe:  t0, Toc: t0, Ttr: t0,
+			A:    26559710.0,
+			E:    0.005,
+			I0:   55.0 * gnssgo.D2R,
+			OMG0: float64(k%6) * 60.0 * gnssgo.D2R,
+			M0:   float64(k/6)*65.0*gnssgo.D2R + float64(k%6)*15.0*gnssgo.D2R,
+			Toes: toes,
+			Fit:  4.0,
+		}
+		ephs = append(ephs, eph)
+		encb.NavData.Ephs[eph.Sat-1] = eph
+		encb.EphSat = eph.Sat
+		corr = append(corr, gen(&encb, 1019)...)
+	}
+	encb.StaPara.Pos = base
+	corr = append(corr, gen(&encb, 1005)...)
+
+	// Observations of the satellites above 10 degrees, the carrier-phases
+	// carry an integer ambiguity per satellite
+	observe := func(tk gnssgo.Gtime, rr [3]float64, rcv int) []gnssgo.ObsD {
+		var (
+			obs          []gnssgo.ObsD
+			pos, e, azel [3]float64
+			rs           [6]float64
+		)
+		gnssgo.Ecef2Pos(rr[:], pos[:])
+		for i := range ephs {
+			var dts, vari float64
+			P := 0.075 * gnssgo.CLIGHT
+			for iter := 0; iter < 5; iter++ {
+				gnssgo.Eph2Pos(gnssgo.TimeAdd(tk, -P/gnssgo.CLIGHT), &ephs[i], rs[:], &dts, &vari)
+				P = gnssgo.GeoDist(rs[:], rr[:], e[:]) - gnssgo.CLIGHT*dts
+			}
+			if gnssgo.SatAzel(pos[:], e[:], azel[:]) < 10.0*gnssgo.D2R {
+				continue
+			}
+			d := gnssgo.ObsD{Time: tk, Sat: ephs[i].Sat, Rcv: rcv}
+			for f, freq := range []float64{gnssgo.FREQ1, gnssgo.FREQ2} {
+				d.Code[f] = []uint8{gnssgo.CODE_L1C, gnssgo.CODE_L2W}[f]
+				d.P[f] = P
+				d.L[f] = P*freq/gnssgo.CLIGHT + float64(100*ephs[i].Sat+f)
+				d.SNR[f] = uint16(45.0 / gnssgo.SNR_UNIT)
+			}
+			obs = append(obs, d)
+		}
+		return obs
+	}
+	for k := 0; k < epochs; k++ {
+		tk := gnssgo.TimeAdd(t0, float64(k))
+		encb.Time, encr.Time = tk, tk
+		encb.ObsData.Data = observe(tk, base, 2)
+		baseObs = append(baseObs, gen(&encb, 1077))
+		encr.ObsData.Data = observe(tk, rover, 1)
+		roverObs = append(roverObs, gen(&encr, 1077))
+	}
+	return corr, baseObs, roverObs
+}
+
+// TestRTKProcessorRtkPos tests that RTCM 3 base corrections and rover
+// observations are solved by the RTK engine into float or fixed solutions
+func TestRTKProcessorRtkPos(t *testing.T) {
+	base := [3]float64{-3961800.0, 3349100.0, 3698300.0}
+	rover := [3]float64{-3961904.9, 3348993.8, 3698211.8}
+
+	// The RTK server resolves the RTCM 3 times near the current time, the
+	// toe of 1019 has a 16 s resolution
+	t0 := gnssgo.Utc2GpsT(gnssgo.TimeGet())
+	t0.Time, t0.Sec = t0.Time-t0.Time%16, 0.0
+	corr, baseObs, roverObs := synthRTCM(t, t0, base, rover, 5)
+
+	rq, bq := new(queueReader), new(queueReader)
+	p := &RTKProcessor{rover: rq, base: bq, opt: DefaultRTKOptions(), now: time.Now}
+	p.SetRoverFormat(gnssgo.STRFMT_RTCM3)
+	assert.Equal(t, gnssgo.SOLQ_NONE, p.GetSolution().Stat)
+
+	require.NoError(t, p.Start())
+	defer p.Stop()
+	assert.Error(t, p.Start())
+
+	bq.Write(corr)
+	var sol RTKSolution
+	for k := range roverObs {
+		bq.Write(baseObs[k])
+		rq.Write(roverObs[k])
+
+		// Wait for the solution of the epoch
+		require.Eventually(t, func() bool {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			return math.Abs(gnssgo.TimeDiff(p.solTime, gnssgo.TimeAdd(t0, float64(k)))) < 1e-3
+		}, 5*time.Second, 10*time.Millisecond, "epoch %d: no solution", k)
+		sol = p.GetSolution()
+		assert.Contains(t, []int{gnssgo.SOLQ_FLOAT, gnssgo.SOLQ_FIX}, sol.Stat, "epoch %d", k)
+	}
+
+	// Position and baseline of the last solution
+	var pos, posb, dr, enu [3]float64
+	gnssgo.Ecef2Pos(rover[:], pos[:])
+	assert.InDelta(t, pos[0]*gnssgo.R2D, sol.Pos[0], 1e-6)
+	assert.InDelta(t, pos[1]*gnssgo.R2D, sol.Pos[1], 1e-6)
+	assert.InDelta(t, pos[2], sol.Pos[2], 0.1)
+	gnssgo.Ecef2Pos(base[:], posb[:])
+	for i := range dr {
+		dr[i] = rover[i] - base[i]
+	}
+	gnssgo.Ecef2Enu(posb[:], dr[:], enu[:])
+	for i := range enu {
+		assert.InDelta(t, enu[i], sol.Baseline[i], 0.1, "baseline %d", i)
+	}
+	assert.GreaterOrEqual(t, int(sol.Ns), 5)
+	if sol.Stat == gnssgo.SOLQ_FIX {
+		assert.Greater(t, sol.Ratio, float32(0.0))
+	}
+
+	stats := p.GetStats()
+	assert.Equal(t, len(roverObs), stats.Solutions)
+	assert.Equal(t, len(roverObs), stats.RoverObs)
+	assert.Equal(t, len(baseObs), stats.BaseObs)
+
+	require.NoError(t, p.Stop())
+	assert.Equal(t, gnssgo.SOLQ_NONE, p.GetSolution().Stat)
+}
+
+// TestRTKProcessorOptions tests the validation of the processing options and
+// that they are applied to the RTK filter
+func TestRTKProcessorOptions(t *testing.T) {
+	static := DefaultRTKOptions()
+	static.Mode = gnssgo.PMODE_STATIC
+	static.Elmin = 10.0 * gnssgo.D2R
+
+	p, err := NewRTKProcessor(&GNSSReceiver{}, &Client{})
+	require.NoError(t, err)
+	assert.Equal(t, gnssgo.PMODE_KINEMA, p.Options().Mode)
+
+	p, err = NewRTKProcessorWithOptions(&GNSSReceiver{}, &Client{}, &static)
+	require.NoError(t, err)
+	assert.Equal(t, gnssgo.PMODE_STATIC, p.Options().Mode)
+
+	// Invalid options are rejected
+	for name, change := range map[string]func(opt *gnssgo.PrcOpt){
+		"mode":      func(opt *gnssgo.PrcOpt) { opt.Mode = gnssgo.PMODE_PPP_FIXED + 1 },
+		"navsys":    func(opt *gnssgo.PrcOpt) { opt.NavSys = 0 },
+		"nf":        func(opt *gnssgo.PrcOpt) { opt.Nf = gnssgo.NFREQ + 1 },
+		"elevation": func(opt *gnssgo.PrcOpt) { opt.Elmin = 95.0 * gnssgo.D2R },
+		"refpos":    func(opt *gnssgo.PrcOpt) { opt.RefPos = -1 },
+	} {
+		opt := DefaultRTKOptions()
+		change(&opt)
+		_, err := NewRTKProcessorWithOptions(&GNSSReceiver{}, &Client{}, &opt)
+		assert.Error(t, err, name)
+		assert.Error(t, p.SetOptions(&opt), name)
+	}
+	assert.Error(t, p.SetOptions(nil))
+	assert.Equal(t, static, p.Options())
+
+	// The RTK filter of a running processor is re-initialized
+	p = &RTKProcessor{rover: new(queueReader), base: new(queueReader), opt: DefaultRTKOptions(), now: time.Now}
+	require.NoError(t, p.Start())
+	defer p.Stop()
+	rtkOpt := func() gnssgo.PrcOpt {
+		p.svr.RtkSvrLock()
+		defer p.svr.RtkSvrUnlock()
+		return p.svr.RtkCtrl.Opt
+	}
+	assert.Equal(t, gnssgo.PMODE_KINEMA, rtkOpt().Mode)
+	assert.InDelta(t, 15.0*gnssgo.D2R, rtkOpt().Elmin, 1e-12)
+
+	require.NoError(t, p.SetOptions(&static))
+	assert.Equal(t, gnssgo.PMODE_STATIC, rtkOpt().Mode)
+	assert.InDelta(t, 10.0*gnssgo.D2R, rtkOpt().Elmin, 1e-12)
+}
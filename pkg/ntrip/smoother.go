@@ -0,0 +1,49 @@
+package ntrip
+
+import "math"
+
+// PositionSmoother is an alpha-beta filter of the positions output for
+// display. It estimates a position and a rate per solution for latitude,
+// longitude and height, so a steady motion is followed without lag while the
+// jitter is reduced. With Beta 0 it is a simple exponential (alpha) filter.
+// The smoother only changes the output, the RTK filter is not affected.
+type PositionSmoother struct {
+	Alpha float64 // Weight of the position residual (0-1, 1: no smoothing)
+	Beta  float64 // Weight of the rate residual (0: no rate)
+
+	init bool       // First position received
+	pos  [3]float64 // Smoothed position (lat, lon (deg), height (m))
+	rate [3]float64 // Rate per solution
+}
+
+// NewPositionSmoother creates a position smoother. The alpha-beta filter is
+// stable for 0 < alpha <= 1 and 0 <= beta < 4-2*alpha.
+func NewPositionSmoother(alpha, beta float64) *PositionSmoother {
+	return &PositionSmoother{Alpha: alpha, Beta: beta}
+}
+
+// Smooth adds a position (lat, lon (deg), height (m)) and returns the
+// smoothed position. The first position after creation or Reset is returned
+// as is.
+func (s *PositionSmoother) Smooth(pos [3]float64) [3]float64 {
+	if !s.init {
+		s.init, s.pos, s.rate = true, pos, [3]float64{}
+		return pos
+	}
+	for i := 0; i < 3; i++ {
+		pred := s.pos[i] + s.rate[i]
+		res := pos[i] - pred
+		if i == 1 {
+			res = math.Remainder(res, 360.0) // Longitude across +/-180 deg
+		}
+		s.pos[i] = pred + s.Alpha*res
+		s.rate[i] += s.Beta * res
+	}
+	s.pos[1] = math.Remainder(s.pos[1], 360.0)
+	return s.pos
+}
+
+// Reset discards the smoothed position, e.g. after an outage
+func (s *PositionSmoother) Reset() {
+	s.init = false
+}
@@ -0,0 +1,115 @@
+package ntrip
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bramburn/gnssgo/pkg/gnssgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// jitterTrack returns the true and jittery positions of a vehicle moving
+// steadily north-east and climbing, with 2 cm (1-sigma) noise per axis
+func jitterTrack(n int) (truth, meas [][3]float64) {
+	const mPerDeg = 111319.49
+	rng := rand.New(rand.NewSource(1))
+	for k := 0; k < n; k++ {
+		x := [3]float64{52.0 + 0.1*float64(k)/mPerDeg, 179.99999 + 0.1*float64(k)/mPerDeg, 10.0 + 0.05*float64(k)}
+		x[1] = math.Remainder(x[1], 360.0)
+		truth = append(truth, x)
+		x[0] += 0.02 * rng.NormFloat64() / mPerDeg
+		x[1] += 0.02 * rng.NormFloat64() / mPerDeg
+		x[2] += 0.02 * rng.NormFloat64()
+		meas = append(meas, x)
+	}
+	return truth, meas
+}
+
+// trackErrors returns the mean and variance (m, m^2) of the horizontal and
+// vertical errors of pos from truth, skipping the first skip positions
+func trackErrors(truth, pos [][3]float64, skip int) (mean, variance [3]float64) {
+	const mPerDeg = 111319.49
+	n := float64(len(pos) - skip)
+	var sum, sum2 [3]float64
+	for k := skip; k < len(pos); k++ {
+		d := [3]float64{
+			(pos[k][0] - truth[k][0]) * mPerDeg,
+			math.Remainder(pos[k][1]-truth[k][1], 360.0) * mPerDeg,
+			pos[k][2] - truth[k][2],
+		}
+		for i := range d {
+			sum[i] += d[i]
+			sum2[i] += d[i] * d[i]
+		}
+	}
+	for i := range sum {
+		mean[i] = sum[i] / n
+		variance[i] = sum2[i]/n - mean[i]*mean[i]
+	}
+	return mean, variance
+}
+
+// TestPositionSmoother tests that smoothed jittery positions have a lower
+// variance and follow a steady motion, across the 180 deg meridian
+func TestPositionSmoother(t *testing.T) {
+	truth, meas := jitterTrack(600)
+	s := NewPositionSmoother(0.3, 0.03)
+	var out [][3]float64
+	for _, x := range meas {
+		out = append(out, s.Smooth(x))
+	}
+	assert.Equal(t, meas[0], out[0])
+
+	_, rawVar := trackErrors(truth, meas, 100)
+	mean, smoothVar := trackErrors(truth, out, 100)
+	for i := 0; i < 3; i++ {
+		assert.Less(t, smoothVar[i], 0.5*rawVar[i], "axis %d: variance", i)
+		assert.Less(t, math.Abs(mean[i]), 0.005, "axis %d: mean error (lag)", i)
+	}
+
+	// A plain alpha filter lags a steady motion
+	s = NewPositionSmoother(0.3, 0.0)
+	out = out[:0]
+	for _, x := range meas {
+		out = append(out, s.Smooth(x))
+	}
+	mean, _ = trackErrors(truth, out, 100)
+	assert.InDelta(t, -0.05*0.7/0.3, mean[2], 0.01)
+
+	// Reset restarts at the next position
+	s.Reset()
+	assert.Equal(t, meas[5], s.Smooth(meas[5]))
+}
+
+// TestRTKProcessorSmoothing tests that the processor smooths the output
+// position and keeps the raw position
+func TestRTKProcessorSmoothing(t *testing.T) {
+	_, meas := jitterTrack(3)
+	p := &RTKProcessor{}
+
+	sol := RTKSolution{Stat: gnssgo.SOLQ_FIX, Pos: meas[0]}
+	p.smoothSolution(&sol)
+	assert.Equal(t, meas[0], sol.Pos)
+	assert.Equal(t, meas[0], sol.RawPos)
+
+	p.SetSmoothing(0.5, 0.0)
+	for k := 0; k < 2; k++ {
+		sol = RTKSolution{Stat: gnssgo.SOLQ_FIX, Pos: meas[k]}
+		p.smoothSolution(&sol)
+	}
+	assert.Equal(t, meas[1], sol.RawPos)
+	assert.InDelta(t, (meas[0][2]+meas[1][2])/2.0, sol.Pos[2], 1e-9)
+
+	// Solutions without position do not update the smoother
+	sol = RTKSolution{Stat: gnssgo.SOLQ_NONE}
+	p.smoothSolution(&sol)
+	assert.Equal(t, [3]float64{}, sol.Pos)
+	sol = RTKSolution{Stat: gnssgo.SOLQ_FIX, Pos: meas[2]}
+	p.smoothSolution(&sol)
+	assert.InDelta(t, (meas[0][2]+meas[1][2])/4.0+meas[2][2]/2.0, sol.Pos[2], 1e-9)
+
+	p.SetSmoothing(0.0, 0.0)
+	p.smoothSolution(&sol)
+	assert.Equal(t, sol.RawPos, sol.Pos)
+}
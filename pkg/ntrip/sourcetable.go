@@ -0,0 +1,203 @@
+package ntrip
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/bramburn/gnssgo/pkg/caster"
+)
+
+// GetSourcetable requests the sourcetable at the root of an NTRIP caster and
+// parses its STR, CAS and NET records. The request asks for NTRIP 2.0, NTRIP
+// 1.0 casters answering with "SOURCETABLE 200 OK" are handled as well.
+func GetSourcetable(ctx context.Context, host string, port int) (caster.Sourcetable, error) {
+	var dialer net.Dialer
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return caster.Sourcetable{}, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	// Abort blocked reads and writes once the context is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	st, err := readSourcetable(conn, host)
+	if ctx.Err() != nil {
+		return caster.Sourcetable{}, ctx.Err()
+	}
+	return st, err
+}
+
+// readSourcetable sends the sourcetable request on conn and reads the reply
+func readSourcetable(conn io.ReadWriter, host string) (caster.Sourcetable, error) {
+	req := "GET / HTTP/1.1\r\n" +
+		"Host: " + host + "\r\n" +
+		"Ntrip-Version: Ntrip/2.0\r\n" +
+		"User-Agent: NTRIP gnssgo\r\n" +
+		"Connection: close\r\n\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		return caster.Sourcetable{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		return caster.Sourcetable{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	status = strings.TrimSpace(status)
+
+	var body io.Reader = reader
+	code := strings.Fields(status + " -")[1]
+	switch {
+	case status == "SOURCETABLE 200 OK":
+		// NTRIP 1.0: headers follow up to an empty line
+		if _, err := readHeaders(reader); err != nil {
+			return caster.Sourcetable{}, err
+		}
+	case strings.HasPrefix(status, "HTTP/1.") && code == "200":
+		// NTRIP 2.0: HTTP/1.1, the body may be chunked
+		headers, err := readHeaders(reader)
+		if err != nil {
+			return caster.Sourcetable{}, err
+		}
+		if strings.EqualFold(headers["transfer-encoding"], "chunked") {
+			body = httputil.NewChunkedReader(reader)
+		}
+	default:
+		return caster.Sourcetable{}, fmt.Errorf("unexpected response: %q", status)
+	}
+	return ParseSourcetable(body)
+}
+
+// readHeaders reads response headers up to the empty line, the names are
+// returned in lower case
+func readHeaders(reader *bufio.Reader) (map[string]string, error) {
+	headers := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response headers: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return headers, nil
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+}
+
+// ParseSourcetable parses the body of a sourcetable up to the ENDSOURCETABLE
+// line. Records of other types are ignored, missing trailing fields are left
+// empty. An error is returned if the body ends without ENDSOURCETABLE.
+func ParseSourcetable(r io.Reader) (caster.Sourcetable, error) {
+	var st caster.Sourcetable
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "ENDSOURCETABLE" {
+			return st, nil
+		}
+		fields := strings.Split(line, ";")
+		switch fields[0] {
+		case "STR":
+			st.Mounts = append(st.Mounts, parseStreamEntry(fields))
+		case "CAS":
+			st.Casters = append(st.Casters, parseCasterEntry(fields))
+		case "NET":
+			st.Networks = append(st.Networks, parseNetworkEntry(fields))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return st, fmt.Errorf("failed to read sourcetable: %w", err)
+	}
+	return st, fmt.Errorf("sourcetable without ENDSOURCETABLE: %w", io.ErrUnexpectedEOF)
+}
+
+// sourcetableFields returns n fields following the record type, the last one
+// includes any further fields
+func sourcetableFields(fields []string, n int) []string {
+	f := make([]string, n)
+	for i := 1; i < len(fields) && i <= n; i++ {
+		f[i-1] = fields[i]
+	}
+	if len(fields) > n+1 {
+		f[n-1] = strings.Join(fields[n:], ";")
+	}
+	return f
+}
+
+func parseFloat32(s string) float32 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 32)
+	return float32(v)
+}
+
+func parseInt(s string) int {
+	v, _ := strconv.Atoi(strings.TrimSpace(s))
+	return v
+}
+
+func parseStreamEntry(fields []string) caster.StreamEntry {
+	f := sourcetableFields(fields, 18)
+	return caster.StreamEntry{
+		Name:           f[0],
+		Identifier:     f[1],
+		Format:         f[2],
+		FormatDetails:  f[3],
+		Carrier:        f[4],
+		NavSystem:      f[5],
+		Network:        f[6],
+		CountryCode:    f[7],
+		Latitude:       parseFloat32(f[8]),
+		Longitude:      parseFloat32(f[9]),
+		NMEA:           f[10] == "1",
+		Solution:       f[11] == "1",
+		Generator:      f[12],
+		Compression:    f[13],
+		Authentication: f[14],
+		Fee:            f[15] == "Y",
+		Bitrate:        parseInt(f[16]),
+		Misc:           f[17],
+	}
+}
+
+func parseCasterEntry(fields []string) caster.CasterEntry {
+	f := sourcetableFields(fields, 11)
+	return caster.CasterEntry{
+		Host:                f[0],
+		Port:                parseInt(f[1]),
+		Identifier:          f[2],
+		Operator:            f[3],
+		NMEA:                f[4] == "1",
+		Country:             f[5],
+		Latitude:            parseFloat32(f[6]),
+		Longitude:           parseFloat32(f[7]),
+		FallbackHostAddress: f[8],
+		FallbackHostPort:    parseInt(f[9]),
+		Misc:                f[10],
+	}
+}
+
+func parseNetworkEntry(fields []string) caster.NetworkEntry {
+	f := sourcetableFields(fields, 8)
+	return caster.NetworkEntry{
+		Identifier:          f[0],
+		Operator:            f[1],
+		Authentication:      f[2],
+		Fee:                 f[3] == "Y",
+		NetworkInfoURL:      f[4],
+		StreamInfoURL:       f[5],
+		RegistrationAddress: f[6],
+		Misc:                f[7],
+	}
+}
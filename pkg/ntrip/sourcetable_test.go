@@ -0,0 +1,167 @@
+package ntrip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bramburn/gnssgo/pkg/caster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkSourcetable checks the records of testdata/sourcetable.txt
+func checkSourcetable(t *testing.T, st caster.Sourcetable) {
+	t.Helper()
+	require.Len(t, st.Casters, 1)
+	require.Len(t, st.Networks, 1)
+	require.Len(t, st.Mounts, 3)
+
+	assert.Equal(t, caster.CasterEntry{
+		Host: "rtk2go.com", Port: 2101, Identifier: "RTK2go", Operator: "SNIP",
+		Country: "USA", Latitude: 40.12, Longitude: -75.34,
+		FallbackHostAddress: "0.0.0.0", Misc: "http://www.rtk2go.com",
+	}, st.Casters[0])
+	assert.Equal(t, caster.NetworkEntry{
+		Identifier: "SNIP", Operator: "RTK2go", Authentication: "B",
+		NetworkInfoURL: "http://www.rtk2go.com", StreamInfoURL: "none",
+		RegistrationAddress: "none", Misc: "none",
+	}, st.Networks[0])
+	assert.Equal(t, caster.StreamEntry{
+		Name: "AMSTERDAM", Identifier: "Amsterdam", Format: "RTCM 3.2",
+		FormatDetails: "1005(10),1077(1),1087(1),1127(1)", Carrier: "2",
+		NavSystem: "GPS+GLO+BDS", Network: "SNIP", CountryCode: "NLD",
+		Latitude: 52.37, Longitude: 4.89, NMEA: true, Generator: "sNTRIP",
+		Compression: "none", Authentication: "B", Bitrate: 9600,
+	}, st.Mounts[0])
+	assert.Equal(t, "misc;with;semicolons", st.Mounts[1].Misc)
+	assert.Equal(t, float32(-40.91), st.Mounts[1].Latitude)
+	assert.Equal(t, caster.StreamEntry{Name: "SHORT", Identifier: "Short", Format: "RTCM 3"}, st.Mounts[2])
+}
+
+func TestParseSourcetable(t *testing.T) {
+	data, err := os.ReadFile("testdata/sourcetable.txt")
+	require.NoError(t, err)
+
+	st, err := ParseSourcetable(bytes.NewReader(data))
+	require.NoError(t, err)
+	checkSourcetable(t, st)
+
+	// A sourcetable cut before ENDSOURCETABLE is an error
+	end := bytes.Index(data, []byte("ENDSOURCETABLE"))
+	_, err = ParseSourcetable(bytes.NewReader(data[:end]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestSourcetableRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sourcetable.txt")
+	require.NoError(t, err)
+	st, err := ParseSourcetable(bytes.NewReader(data))
+	require.NoError(t, err)
+	st.Mounts[2].Solution, st.Mounts[2].Fee = true, true
+	st.Casters[0].NMEA, st.Casters[0].FallbackHostPort = true, 2102
+
+	// The written sourcetable parses to the same records
+	var buf bytes.Buffer
+	_, err = st.WriteTo(&buf)
+	require.NoError(t, err)
+	got, err := ParseSourcetable(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, st, got)
+
+	// Unchanged complete records are written as received
+	want := strings.Split(string(data), "\r\n")
+	lines := strings.Split(st.String(), "\r\n")
+	assert.Equal(t, want[1:4], lines[1:4])
+}
+
+// serveSourcetable accepts one connection, reads the request and writes the
+// response produced by reply
+func serveSourcetable(t *testing.T, reply func(w io.Writer, body []byte)) int {
+	t.Helper()
+	body, err := os.ReadFile("testdata/sourcetable.txt")
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || strings.TrimSpace(line) == "" {
+				break
+			}
+		}
+		reply(conn, body)
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestGetSourcetableV1(t *testing.T) {
+	port := serveSourcetable(t, func(w io.Writer, body []byte) {
+		fmt.Fprintf(w, "SOURCETABLE 200 OK\r\nServer: NTRIP Caster/1.0\r\n"+
+			"Content-Type: text/plain\r\nContent-Length: %d\r\n\r\n", len(body))
+		w.Write(body)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	st, err := GetSourcetable(ctx, "127.0.0.1", port)
+	require.NoError(t, err)
+	checkSourcetable(t, st)
+}
+
+func TestGetSourcetableV2Chunked(t *testing.T) {
+	port := serveSourcetable(t, func(w io.Writer, body []byte) {
+		fmt.Fprint(w, "HTTP/1.1 200 OK\r\nNtrip-Version: Ntrip/2.0\r\n"+
+			"Content-Type: gnss/sourcetable\r\nTransfer-Encoding: chunked\r\n\r\n")
+		cw := httputil.NewChunkedWriter(w)
+		for len(body) > 0 {
+			n := min(len(body), 100)
+			cw.Write(body[:n])
+			body = body[n:]
+		}
+		cw.Close()
+		fmt.Fprint(w, "\r\n")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	st, err := GetSourcetable(ctx, "127.0.0.1", port)
+	require.NoError(t, err)
+	checkSourcetable(t, st)
+}
+
+func TestGetSourcetableErrors(t *testing.T) {
+	port := serveSourcetable(t, func(w io.Writer, body []byte) {
+		fmt.Fprint(w, "HTTP/1.1 401 Unauthorized\r\n\r\n")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := GetSourcetable(ctx, "127.0.0.1", port)
+	assert.ErrorContains(t, err, "401")
+
+	// A caster that never answers is aborted by the context
+	port = serveSourcetable(t, func(w io.Writer, body []byte) {
+		time.Sleep(time.Second)
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = GetSourcetable(ctx, "127.0.0.1", port)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
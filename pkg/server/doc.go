@@ -26,6 +26,12 @@ Example usage:
     }
     defer server.Stop()
 
+The server reconnects to the caster after a refused or dropped connection, by default
+every DefaultReconnectInterval without limit. SetReconnect changes the interval, limits
+the consecutive failed connections or disables reconnection:
+
+    server.SetReconnect(true, 10*time.Second, 5)
+
 ## DataSource
 
 The DataSource interface defines the methods that a data source must implement.
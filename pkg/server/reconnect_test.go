@@ -0,0 +1,153 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanDataSource is a data source fed by the test
+type chanDataSource struct {
+	dataChan chan []byte
+}
+
+func (ds *chanDataSource) Start() error        { return nil }
+func (ds *chanDataSource) Stop() error         { return nil }
+func (ds *chanDataSource) Data() <-chan []byte { return ds.dataChan }
+
+// reconnectCaster is a caster handling each connection with the handler of
+// its number, refusing connections without a handler
+type reconnectCaster struct {
+	mutex    sync.Mutex
+	conns    int
+	handlers map[int]func(r *http.Request)
+	attempts chan int
+}
+
+func (c *reconnectCaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	c.conns++
+	n, handler := c.conns, c.handlers[c.conns]
+	c.mutex.Unlock()
+	c.attempts <- n
+
+	// Respond while the request body is streamed
+	http.NewResponseController(w).EnableFullDuplex()
+	if handler == nil {
+		http.Error(w, "mountpoint in use", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	handler(r)
+}
+
+// newReconnectServer returns a server publishing to the caster ts
+func newReconnectServer(t *testing.T, ts *httptest.Server, ds DataSource) *Server {
+	t.Helper()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	s := NewServer(u.Hostname(), u.Port(), "admin", "password", "TEST", logger)
+	s.SetDataSource(ds)
+	return s
+}
+
+func TestServerReconnect(t *testing.T) {
+	received := make(chan string, 10)
+	readN := func(r *http.Request, n int) {
+		buff := make([]byte, n)
+		if _, err := io.ReadFull(r.Body, buff); err == nil {
+			received <- string(buff)
+		}
+	}
+	caster := &reconnectCaster{
+		attempts: make(chan int, 10),
+		handlers: map[int]func(r *http.Request){
+			// The first connection is refused, the second one dropped after
+			// the first message
+			2: func(r *http.Request) { readN(r, 5) },
+			3: func(r *http.Request) { readN(r, 5); readN(r, 5) },
+		},
+	}
+	ts := httptest.NewServer(caster)
+	defer ts.Close()
+
+	ds := &chanDataSource{dataChan: make(chan []byte, 10)}
+	s := newReconnectServer(t, ts, ds)
+	s.SetReconnect(true, 20*time.Millisecond, 3)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	recv := func() string {
+		select {
+		case data := <-received:
+			return data
+		case <-time.After(5 * time.Second):
+			t.Fatal("no data received")
+			return ""
+		}
+	}
+	// Refused, then accepted
+	assert.Equal(t, []int{1, 2}, []int{<-caster.attempts, <-caster.attempts})
+	ds.dataChan <- []byte("hello")
+	assert.Equal(t, "hello", recv())
+
+	// Dropped after the first message, the data resumes on the next connection
+	assert.Equal(t, 3, <-caster.attempts)
+	ds.dataChan <- []byte("world")
+	assert.Equal(t, "world", recv())
+	ds.dataChan <- []byte("again")
+	assert.Equal(t, "again", recv())
+}
+
+func TestServerReconnectMaxRetries(t *testing.T) {
+	caster := &reconnectCaster{attempts: make(chan int, 10)}
+	ts := httptest.NewServer(caster)
+	defer ts.Close()
+
+	s := newReconnectServer(t, ts, &chanDataSource{dataChan: make(chan []byte)})
+	s.SetReconnect(true, time.Millisecond, 2)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// The first attempt and two retries
+	for i := 1; i <= 3; i++ {
+		select {
+		case n := <-caster.attempts:
+			assert.Equal(t, i, n)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("attempt %d not made", i)
+		}
+	}
+	select {
+	case n := <-caster.attempts:
+		t.Errorf("unexpected attempt %d", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServerReconnectStop(t *testing.T) {
+	caster := &reconnectCaster{attempts: make(chan int, 100)}
+	ts := httptest.NewServer(caster)
+	defer ts.Close()
+
+	s := newReconnectServer(t, ts, &chanDataSource{dataChan: make(chan []byte)})
+	s.SetReconnect(true, 50*time.Millisecond, 0)
+	require.NoError(t, s.Start())
+	<-caster.attempts
+	require.NoError(t, s.Stop())
+
+	// No attempt after Stop
+	time.Sleep(150 * time.Millisecond)
+	assert.Empty(t, caster.attempts)
+}
@@ -20,6 +20,10 @@ const (
 	UserAgentValue            = "GNSSGO NTRIP Server/1.0"
 )
 
+// DefaultReconnectInterval is the default wait before reconnecting to the
+// caster
+const DefaultReconnectInterval = 5 * time.Second
+
 // DataSource is an interface for providing RTCM data to the server
 type DataSource interface {
 	// Start starts the data source
@@ -32,18 +36,23 @@ type DataSource interface {
 
 // Server represents an NTRIP server
 type Server struct {
-	host        string
-	port        string
-	username    string
-	password    string
-	mountpoint  string
-	dataSource  DataSource
-	client      *http.Client
-	running     bool
-	ctx         context.Context
-	cancel      context.CancelFunc
-	mutex       sync.Mutex
-	logger      logrus.FieldLogger
+	host       string
+	port       string
+	username   string
+	password   string
+	mountpoint string
+	dataSource DataSource
+	client     *http.Client
+	running    bool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	mutex      sync.Mutex
+	logger     logrus.FieldLogger
+
+	reconnect  bool          // Reconnect after a failure or disconnection
+	interval   time.Duration // Wait before reconnecting
+	maxRetries int           // Consecutive failed connections before giving up (0: no limit)
+	pending    []byte        // Data not sent when the connection ended
 }
 
 // NewServer creates a new NTRIP server
@@ -54,13 +63,30 @@ func NewServer(host, port, username, password, mountpoint string, logger logrus.
 		username:   username,
 		password:   password,
 		mountpoint: mountpoint,
+		// The request streams until the connection ends, only the response
+		// header is time limited
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: &http.Transport{ResponseHeaderTimeout: 30 * time.Second},
 		},
-		logger: logger,
+		logger:    logger,
+		reconnect: true,
+		interval:  DefaultReconnectInterval,
 	}
 }
 
+// SetReconnect sets the reconnection to the caster after a failed connection
+// or a disconnection. The server waits interval before each attempt and
+// gives up after maxRetries consecutive failed connections (0: no limit).
+// By default the server reconnects every DefaultReconnectInterval without
+// limit. Call it before Start.
+func (s *Server) SetReconnect(enabled bool, interval time.Duration, maxRetries int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reconnect = enabled
+	s.interval = interval
+	s.maxRetries = maxRetries
+}
+
 // SetDataSource sets the data source for the server
 func (s *Server) SetDataSource(dataSource DataSource) {
 	s.mutex.Lock()
@@ -121,50 +147,61 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-// run runs the server
+// run runs the server, reconnecting to the caster as set by SetReconnect
 func (s *Server) run() {
+	s.mutex.Lock()
+	ctx, reconnect, interval, maxRetries := s.ctx, s.reconnect, s.interval, s.maxRetries
+	s.mutex.Unlock()
+
 	s.logger.Infof("Starting NTRIP server for mountpoint %s", s.mountpoint)
 
-	for {
-		// Check if the context is done
-		select {
-		case <-s.ctx.Done():
+	for attempt, failures := 1, 0; ; attempt++ {
+		s.logger.Infof("Connecting to caster %s:%s (attempt %d)", s.host, s.port, attempt)
+		connected, err := s.connect(ctx)
+		if ctx.Err() != nil {
 			s.logger.Info("Server stopped")
 			return
-		default:
 		}
-
-		// Connect to the caster
-		err := s.connect()
+		if connected {
+			failures = 0
+		} else {
+			failures++
+		}
 		if err != nil {
-			s.logger.Errorf("Failed to connect to caster: %v", err)
-			// Wait before retrying
-			select {
-			case <-s.ctx.Done():
-				return
-			case <-time.After(5 * time.Second):
-			}
-			continue
+			s.logger.Errorf("Connection to caster failed: %v", err)
+		} else {
+			s.logger.Warn("Connection to caster closed")
+		}
+
+		if !reconnect {
+			s.logger.Warn("Reconnection disabled, server stopped publishing")
+			return
+		}
+		if maxRetries > 0 && failures > maxRetries {
+			s.logger.Errorf("Giving up after %d failed connections", failures)
+			return
 		}
 
 		// Wait before reconnecting
 		select {
-		case <-s.ctx.Done():
+		case <-ctx.Done():
+			s.logger.Info("Server stopped")
 			return
-		case <-time.After(5 * time.Second):
+		case <-time.After(interval):
 		}
 	}
 }
 
-// connect connects to the caster and streams data
-func (s *Server) connect() error {
+// connect connects to the caster and streams data until the connection ends.
+// It returns whether the caster accepted the connection.
+func (s *Server) connect(ctx context.Context) (bool, error) {
 	// Create the URL
 	url := fmt.Sprintf("http://%s:%s/%s", s.host, s.port, s.mountpoint)
 
 	// Create the request
-	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -180,21 +217,39 @@ func (s *Server) connect() error {
 	pr, pw := io.Pipe()
 	req.Body = pr
 
-	// Start a goroutine to write data to the pipe
+	// Start a goroutine to write data to the pipe. It ends with the
+	// connection, so the data source is read by one connection at a time.
+	connCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		pr.Close()
+		<-done
+	}()
 	go func() {
+		defer close(done)
 		defer pw.Close()
 
+		// Data not sent on the previous connection
+		if len(s.pending) > 0 {
+			n, err := pw.Write(s.pending)
+			s.pending = s.pending[n:]
+			if err != nil {
+				return
+			}
+		}
 		for {
 			select {
-			case <-s.ctx.Done():
+			case <-connCtx.Done():
 				return
 			case data, ok := <-s.dataSource.Data():
 				if !ok {
 					return
 				}
-				_, err := pw.Write(data)
+				n, err := pw.Write(data)
 				if err != nil {
-					s.logger.Errorf("Failed to write data to pipe: %v", err)
+					s.pending = data[n:]
+					s.logger.Debugf("Failed to write data to pipe: %v", err)
 					return
 				}
 			}
@@ -204,14 +259,14 @@ func (s *Server) connect() error {
 	// Send the request
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return false, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check the response
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return false, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	s.logger.Infof("Connected to caster at %s", url)
@@ -219,8 +274,8 @@ func (s *Server) connect() error {
 	// Read the response body to keep the connection alive
 	_, err = io.Copy(io.Discard, resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return true, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return nil
+	return true, nil
 }